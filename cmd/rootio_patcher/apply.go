@@ -0,0 +1,143 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"path/filepath"
+	"strings"
+
+	"rootio_patcher/cmd/rootio_patcher/apt"
+	"rootio_patcher/cmd/rootio_patcher/common"
+	"rootio_patcher/cmd/rootio_patcher/config"
+	"rootio_patcher/cmd/rootio_patcher/gem"
+	"rootio_patcher/cmd/rootio_patcher/gomod"
+	"rootio_patcher/cmd/rootio_patcher/maven"
+	"rootio_patcher/cmd/rootio_patcher/npm"
+	"rootio_patcher/cmd/rootio_patcher/nuget"
+	"rootio_patcher/cmd/rootio_patcher/pip"
+)
+
+// ApplyCmd applies a plan written by a dry run with --plan-out
+type ApplyCmd struct {
+	Plan       string `required:"" help:"Plan file written by a dry run with --plan-out"`
+	PythonPath string `help:"Path to Python interpreter for installed pip packages (default: $VIRTUAL_ENV/bin/python, then python3, then python)"`
+	Backup     bool   `help:"Write <file>.rootio.bak before modifying each file (timestamped if a backup already exists)"`
+	Journal    string `default:".rootio_patcher.journal" help:"Append-only journal of applied pip patches, used by pip rollback"`
+}
+
+// Run applies every entry of the plan. The planned patches are used as they are, so the
+// severity and package filters of the planning run aren't applied again.
+func (cmd *ApplyCmd) Run(
+	ctx context.Context, cfg *config.Config, logger *slog.Logger, sink *resultSink, globals *Globals,
+) error {
+	logger.InfoContext(ctx, "Applying plan", slog.String("plan", cmd.Plan))
+
+	plan, err := common.ReadPlan(cmd.Plan)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(globals.report, "\nApplying %d entries from %s (planned %s)\n",
+		len(plan.Entries), cmd.Plan, plan.CreatedAt.Format("2006-01-02 15:04:05 MST"))
+
+	var results []*common.RunResult
+	var failed []string
+	for _, entry := range plan.Entries {
+		if err := ctx.Err(); err != nil {
+			return sink.collectAll(results, err)
+		}
+
+		name := planEntryName(entry)
+		fmt.Fprintf(globals.report, "\n=== %s (%s) ===\n", name, entry.Ecosystem)
+
+		app, err := cmd.app(ctx, cfg, logger, entry, globals)
+		if err != nil {
+			return sink.collectAll(results, err)
+		}
+
+		result, runErr := app.RunWithResult(ctx)
+		if runErr != nil {
+			logger.ErrorContext(ctx, "Failed to apply plan entry",
+				slog.String("entry", name),
+				slog.String("error", runErr.Error()))
+			failed = append(failed, name)
+		}
+		results = append(results, result)
+	}
+
+	if len(failed) > 0 {
+		return sink.collectAll(results, fmt.Errorf("%d of %d plan entries failed: %s",
+			len(failed), len(plan.Entries), strings.Join(failed, ", ")))
+	}
+	return sink.collectAll(results, nil)
+}
+
+// app builds the remediation app for a plan entry, answering analysis from the plan
+func (cmd *ApplyCmd) app(
+	ctx context.Context, cfg *config.Config, logger *slog.Logger, entry common.PlanEntry, globals *Globals,
+) (common.Runner, error) {
+	opts := []common.Option{
+		common.WithBackup(cmd.Backup),
+		common.WithPlanEntry(&entry),
+		common.WithConfirm(globals.confirm),
+		common.WithOutput(globals.report),
+	}
+
+	switch entry.Ecosystem {
+	case common.EcosystemPyPI:
+		if entry.File != "" {
+			return pip.NewRequirementsApp(cfg.APIKey, cfg.APIURL, entry.File, false, logger,
+				append(opts, common.WithAdditionalFiles(entry.AdditionalFiles...))...), nil
+		}
+		pythonPath, err := resolvePython(ctx, cmd.PythonPath, logger)
+		if err != nil {
+			return nil, err
+		}
+		return pip.NewApp(cfg, pythonPath, false, entry.UseAlias, logger,
+			append(opts, common.WithJournal(cmd.Journal))...), nil
+	case common.EcosystemNpm:
+		packageJSON := filepath.Join(filepath.Dir(entry.File), "package.json")
+		return npm.NewApp(cfg.APIKey, cfg.APIURL, npm.PackageManagerForLockFile(entry.File), false, logger,
+			append(opts, common.WithPackageJSON(packageJSON), common.WithUseAlias(entry.UseAlias))...), nil
+	case common.EcosystemMaven:
+		return maven.NewApp(cfg.APIKey, cfg.APIURL, entry.File, false, logger,
+			append(opts, common.WithUseAlias(entry.UseAlias))...), nil
+	case common.EcosystemGo:
+		if entry.UseAlias {
+			opts = append(opts, common.WithGoProxy(strings.TrimSuffix(cfg.PKGURL, "/")+"/go"))
+		}
+		return gomod.NewApp(cfg.APIKey, cfg.APIURL, entry.File, false, logger, opts...), nil
+	case common.EcosystemRubyGems:
+		return gem.NewApp(cfg.APIKey, cfg.APIURL, entry.File, false, logger, opts...), nil
+	case common.EcosystemNuGet:
+		return nuget.NewApp(cfg.APIKey, cfg.APIURL, entry.File, false, logger, opts...), nil
+	case common.EcosystemDebian:
+		return apt.NewApp(cfg, false, logger, opts...), nil
+	default:
+		return nil, fmt.Errorf("unsupported ecosystem %q in plan", entry.Ecosystem)
+	}
+}
+
+// planEntryName describes what a plan entry patches
+func planEntryName(entry common.PlanEntry) string {
+	if entry.File != "" {
+		return entry.File
+	}
+	return "installed packages"
+}
+
+// writePlan saves the patches collected during the run to path and tells out how to apply them
+func writePlan(out io.Writer, plan *common.Plan, path string) error {
+	if err := plan.Write(path); err != nil {
+		return err
+	}
+
+	count := 0
+	for _, entry := range plan.Entries {
+		count += len(entry.Patches)
+	}
+	fmt.Fprintf(out, "\nWrote plan with %d patches to %s\n", count, path)
+	fmt.Fprintf(out, "To apply it later, run: rootio_patcher apply --plan %s\n", path)
+	return nil
+}
@@ -0,0 +1,224 @@
+package apt
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+
+	"rootio_patcher/cmd/rootio_patcher/common"
+	"rootio_patcher/cmd/rootio_patcher/config"
+	"rootio_patcher/pkg/rootio"
+)
+
+// App handles Debian system package remediation (post-install patching with apt)
+type App struct {
+	cfg    *config.Config
+	dryRun bool
+	logger *slog.Logger
+	out    io.Writer
+
+	aptService Service
+	apiClient  common.APIClient
+	options    common.Options
+
+	result *common.RunResult
+}
+
+// NewApp creates a new apt application instance
+func NewApp(cfg *config.Config, dryRun bool, logger *slog.Logger, opts ...common.Option) *App {
+	aptService := NewService(cfg.PKGURL, cfg.APIKey, logger)
+	apiClient := common.NewAPIClient(common.EcosystemDebian, cfg.APIURL, cfg.APIKey, logger, opts...)
+
+	return NewAppWithServices(cfg, dryRun, logger, aptService, apiClient, common.NewOptions(opts...).Output(), opts...)
+}
+
+// NewAppWithServices creates a new apt application with injected services (for testing)
+func NewAppWithServices(
+	cfg *config.Config,
+	dryRun bool,
+	logger *slog.Logger,
+	aptService Service,
+	apiClient common.APIClient,
+	out io.Writer,
+	opts ...common.Option,
+) *App {
+	return &App{
+		cfg:        cfg,
+		dryRun:     dryRun,
+		logger:     logger,
+		out:        out,
+		aptService: aptService,
+		apiClient:  apiClient,
+		options:    common.NewOptions(opts...),
+	}
+}
+
+// Result returns the structured result of the last run
+func (a *App) Result() *common.RunResult {
+	return a.result
+}
+
+// RunWithResult runs the apt remediation workflow and returns its structured result
+func (a *App) RunWithResult(ctx context.Context) (*common.RunResult, error) {
+	err := a.Run(ctx)
+	a.result.SetError(err)
+	return a.result, err
+}
+
+// Run executes the apt remediation workflow
+func (a *App) Run(ctx context.Context) error {
+	a.logger.DebugContext(ctx, "Starting apt remediation", slog.Bool("dry_run", a.dryRun))
+	a.result = common.NewRunResult(common.EcosystemDebian, "", a.dryRun)
+
+	if err := a.aptService.CheckDpkg(ctx); err != nil {
+		return fmt.Errorf("failed to find dpkg: %w", err)
+	}
+
+	// 1. Collect installed packages
+	a.logger.DebugContext(ctx, "Collecting installed packages")
+	a.options.Progress.Printf("Collecting installed packages...")
+	packages, err := a.aptService.ListPackages(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to collect packages: %w", err)
+	}
+	a.logger.DebugContext(ctx, "Collected packages", slog.Int("count", len(packages)))
+	a.options.Progress.Printf("Collected %d installed packages", len(packages))
+	a.result.PackagesFound = len(packages)
+
+	// 2. Convert to SDK format
+	sdkPackages := make([]rootio.Package, len(packages))
+	for i, pkg := range packages {
+		sdkPackages[i] = rootio.Package{
+			Name:      pkg.Name,
+			Version:   pkg.Version,
+			Ecosystem: string(common.EcosystemDebian),
+		}
+	}
+
+	// 3. Call backend API to analyze vulnerabilities
+	a.logger.DebugContext(ctx, "Analyzing packages for vulnerabilities")
+	a.options.Progress.Printf("Analyzing %d packages for vulnerabilities...", len(sdkPackages))
+	response, err := a.apiClient.AnalyzePackages(ctx, sdkPackages)
+	if err != nil {
+		return fmt.Errorf("failed to analyze packages: %w", err)
+	}
+
+	a.logger.DebugContext(ctx, "Vulnerability analysis complete",
+		slog.Int("patches_available", len(response.Patches)),
+		slog.Int("packages_skipped", len(response.Skipped)))
+
+	// Drop invalid names, patches without a version to install, patches left out by
+	// --min-severity, --only, --exclude and --cve, and patches that aren't upgrades
+	patches, filterSkipped := common.FilterPatches(common.EcosystemDebian, response.Patches, false, a.options)
+	response.Patches = patches
+	response.Skipped = append(response.Skipped, filterSkipped...)
+	common.WriteCVEMatches(a.out, response.Patches, a.options.CVEs)
+	a.result.AddSkipped(response.Skipped)
+	common.WriteSkipped(a.out, response.Skipped)
+
+	if len(response.Patches) == 0 {
+		fmt.Fprintln(a.out, "\nNo patches needed - all packages are up to date!")
+		return nil
+	}
+
+	// 4. Execute or dry-run patches
+	if a.dryRun {
+		a.logger.DebugContext(ctx, "DRY-RUN MODE: No changes will be made")
+		a.options.Plan.Add(common.EcosystemDebian, "", false, sdkPackages, response.Patches)
+		a.result.AddPatches(response.Patches, false, common.PatchStatusDryRun)
+		a.reportDryRun(response.Patches)
+		return nil
+	}
+
+	// Ask before changing anything, unless --yes was given
+	if err := a.options.Confirm.Confirm(len(response.Patches), "the system packages"); err != nil {
+		a.result.AddPatches(response.Patches, false, common.PatchStatusNotApplied)
+		return err
+	}
+
+	a.result.AddPatches(response.Patches, false, common.PatchStatusPending)
+
+	fmt.Fprintln(a.out, "\nConfiguring the Root.io apt repository...")
+	if err := a.aptService.SetupRepository(ctx); err != nil {
+		a.result.SetAllPatchStatus(common.PatchStatusNotApplied, nil)
+		return fmt.Errorf("failed to configure apt repository: %w", err)
+	}
+
+	fmt.Fprintf(a.out, "\nApplying %d patches...\n\n", len(response.Patches))
+	if err := a.applyPatches(ctx, response.Patches); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(a.out, "\n✓ Successfully patched %d packages!\n", len(response.Patches))
+
+	return nil
+}
+
+// applyPatches installs patched packages one at a time. By default it exits on the first
+// failure; with KeepGoing it attempts every patch and fails if any of them failed.
+func (a *App) applyPatches(ctx context.Context, patches []rootio.PackagePatch) error {
+	var failures []string
+
+	for i, patch := range patches {
+		fmt.Fprintf(a.out, "[%d/%d] Patching %s (%s → %s)...\n",
+			i+1, len(patches),
+			patch.PackageName,
+			patch.Version,
+			common.PatchTarget(patch, false).Version)
+
+		if err := a.aptService.ApplyPatch(ctx, patch); err != nil {
+			fmt.Fprintf(a.out, "✗ Patch failed: %v\n", err)
+			a.result.SetPatchStatus(i, common.PatchStatusFailed, err)
+
+			if a.options.KeepGoing {
+				a.logger.WarnContext(ctx, "Patch failed, continuing with remaining patches",
+					slog.String("package", patch.PackageName),
+					slog.String("error", err.Error()))
+				failures = append(failures, patch.PackageName)
+				fmt.Fprintln(a.out)
+				continue
+			}
+
+			for j := i + 1; j < len(patches); j++ {
+				a.result.SetPatchStatus(j, common.PatchStatusNotApplied, nil)
+			}
+			return fmt.Errorf("patch failed: %w", err)
+		}
+
+		a.result.SetPatchStatus(i, common.PatchStatusApplied, nil)
+		fmt.Fprintf(a.out, "  ✓ Successfully patched %s\n\n", patch.PackageName)
+	}
+
+	if len(failures) > 0 {
+		fmt.Fprintf(a.out, "\nPatched %d of %d packages, %d failed:\n", len(patches)-len(failures), len(patches), len(failures))
+		for _, name := range failures {
+			fmt.Fprintf(a.out, "  ✗ %s\n", name)
+		}
+		return fmt.Errorf("%d of %d patches failed: %s", len(failures), len(patches), strings.Join(failures, ", "))
+	}
+
+	return nil
+}
+
+// reportDryRun prints the commands that configure the Root.io repository and install each patch
+func (a *App) reportDryRun(patches []rootio.PackagePatch) {
+	fmt.Fprintln(a.out, "\n=== DRY-RUN MODE ===")
+	common.WritePatchTable(a.out, patches, false, common.TerminalWidth())
+	fmt.Fprintln(a.out, "\nThe following commands would be run:")
+	fmt.Fprintln(a.out)
+
+	source, auth, err := RepositoryConfig(a.cfg.PKGURL, "<your_api_key>", `$(. /etc/os-release && echo "$VERSION_CODENAME")`)
+	if err == nil {
+		fmt.Fprintf(a.out, "  echo \"%s\" | sudo tee %s\n", strings.TrimSpace(source), SourcesFile)
+		fmt.Fprintf(a.out, "  echo \"%s\" | sudo tee %s\n", strings.TrimSpace(auth), AuthFile)
+	}
+	fmt.Fprintln(a.out, "  sudo apt-get update")
+	for _, patch := range patches {
+		target := common.PatchTarget(patch, false)
+		fmt.Fprintf(a.out, "  sudo apt-get %s\n", strings.Join(InstallArgs(target.Name, target.Version), " "))
+	}
+
+	fmt.Fprintln(a.out, "\nTo apply these patches, run as root with --dry-run=false")
+}
@@ -0,0 +1,222 @@
+package apt
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log/slog"
+	"os"
+	"strings"
+	"testing"
+
+	"rootio_patcher/cmd/rootio_patcher/common"
+	"rootio_patcher/cmd/rootio_patcher/config"
+	"rootio_patcher/pkg/rootio"
+)
+
+func newTestPatches() []rootio.PackagePatch {
+	return []rootio.PackagePatch{
+		{
+			PackageName: "openssl",
+			Version:     "3.0.11-1~deb12u1",
+			Patch:       rootio.PatchInfo{Name: "openssl", Version: "3.0.11-1~deb12u1.root.io.1"},
+			CVEIDs:      []string{"CVE-2024-0727"},
+		},
+		{
+			PackageName: "libc6",
+			Version:     "2.36-9+deb12u3",
+			Patch:       rootio.PatchInfo{Version: "2.36-9+deb12u3.root.io.1"},
+			CVEIDs:      []string{"CVE-2023-4911"},
+		},
+	}
+}
+
+func newTestServices(patches []rootio.PackagePatch) (*MockAptService, *MockAPIClient) {
+	aptService := &MockAptService{
+		ListPackagesFunc: func(ctx context.Context) ([]common.InstalledPackage, error) {
+			return []common.InstalledPackage{
+				{Name: "openssl", Version: "3.0.11-1~deb12u1"},
+				{Name: "libc6", Version: "2.36-9+deb12u3"},
+			}, nil
+		},
+	}
+	apiClient := &MockAPIClient{
+		AnalyzePackagesFunc: func(ctx context.Context, packages []rootio.Package) (*rootio.AnalyzePackagesResponse, error) {
+			return &rootio.AnalyzePackagesResponse{Patches: patches}, nil
+		},
+	}
+	return aptService, apiClient
+}
+
+func TestAptApp_Run_DpkgNotAvailable(t *testing.T) {
+	ctx := context.Background()
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+
+	expectedError := errors.New("dpkg-query not found")
+	aptService := &MockAptService{
+		CheckDpkgFunc: func(ctx context.Context) error {
+			return expectedError
+		},
+		ListPackagesFunc: func(ctx context.Context) ([]common.InstalledPackage, error) {
+			t.Error("ListPackages should not be called when dpkg is missing")
+			return nil, nil
+		},
+	}
+
+	app := NewAppWithServices(&config.Config{}, true, logger, aptService, &MockAPIClient{}, &bytes.Buffer{})
+
+	if err := app.Run(ctx); !errors.Is(err, expectedError) {
+		t.Fatalf("Expected error to wrap dpkg check error, got: %v", err)
+	}
+}
+
+func TestAptApp_Run_DryRunPrintsCommands(t *testing.T) {
+	ctx := context.Background()
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+
+	aptService, apiClient := newTestServices(newTestPatches())
+	aptService.SetupRepositoryFunc = func(ctx context.Context) error {
+		t.Error("SetupRepository should not be called in dry-run mode")
+		return nil
+	}
+	aptService.ApplyPatchFunc = func(ctx context.Context, patch rootio.PackagePatch) error {
+		t.Error("ApplyPatch should not be called in dry-run mode")
+		return nil
+	}
+
+	var out bytes.Buffer
+	cfg := &config.Config{PKGURL: "https://pkg.root.io"}
+	app := NewAppWithServices(cfg, true, logger, aptService, apiClient, &out)
+
+	result, err := app.RunWithResult(ctx)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	output := out.String()
+	for _, want := range []string{
+		"deb https://pkg.root.io/debian",
+		"sudo apt-get update",
+		"sudo apt-get install -y --no-install-recommends openssl=3.0.11-1~deb12u1.root.io.1",
+		"sudo apt-get install -y --no-install-recommends libc6=2.36-9+deb12u3.root.io.1",
+	} {
+		if !strings.Contains(output, want) {
+			t.Errorf("Expected output to contain %q, got:\n%s", want, output)
+		}
+	}
+	if strings.Contains(output, "password secret") {
+		t.Error("Dry-run output must not contain the API key")
+	}
+
+	if result.Ecosystem != common.EcosystemDebian {
+		t.Errorf("Expected ecosystem %s, got %s", common.EcosystemDebian, result.Ecosystem)
+	}
+	if len(result.Patches) != 2 || result.Patches[0].Status != common.PatchStatusDryRun {
+		t.Errorf("Expected 2 dry-run patches, got %+v", result.Patches)
+	}
+}
+
+func TestAptApp_Run_ApplyPatches(t *testing.T) {
+	ctx := context.Background()
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+
+	aptService, apiClient := newTestServices(newTestPatches())
+	var calls []string
+	aptService.SetupRepositoryFunc = func(ctx context.Context) error {
+		calls = append(calls, "setup")
+		return nil
+	}
+	aptService.ApplyPatchFunc = func(ctx context.Context, patch rootio.PackagePatch) error {
+		calls = append(calls, patch.PackageName)
+		return nil
+	}
+
+	app := NewAppWithServices(&config.Config{}, false, logger, aptService, apiClient, &bytes.Buffer{})
+
+	result, err := app.RunWithResult(ctx)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	if strings.Join(calls, ",") != "setup,openssl,libc6" {
+		t.Errorf("Expected repository setup before patches, got calls %v", calls)
+	}
+	for _, patch := range result.Patches {
+		if patch.Status != common.PatchStatusApplied {
+			t.Errorf("Expected %s to be applied, got %s", patch.PackageName, patch.Status)
+		}
+	}
+}
+
+func TestAptApp_Run_SetupRepositoryError(t *testing.T) {
+	ctx := context.Background()
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+
+	expectedError := errors.New("permission denied")
+	aptService, apiClient := newTestServices(newTestPatches())
+	aptService.SetupRepositoryFunc = func(ctx context.Context) error {
+		return expectedError
+	}
+	aptService.ApplyPatchFunc = func(ctx context.Context, patch rootio.PackagePatch) error {
+		t.Error("ApplyPatch should not be called when the repository can't be configured")
+		return nil
+	}
+
+	app := NewAppWithServices(&config.Config{}, false, logger, aptService, apiClient, &bytes.Buffer{})
+
+	result, err := app.RunWithResult(ctx)
+	if !errors.Is(err, expectedError) {
+		t.Fatalf("Expected error to wrap setup error, got: %v", err)
+	}
+	if result.Unapplied() != 2 {
+		t.Errorf("Expected 2 unapplied patches, got %d", result.Unapplied())
+	}
+}
+
+func TestAptApp_Run_KeepGoing(t *testing.T) {
+	ctx := context.Background()
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+
+	aptService, apiClient := newTestServices(newTestPatches())
+	var applied []string
+	aptService.ApplyPatchFunc = func(ctx context.Context, patch rootio.PackagePatch) error {
+		applied = append(applied, patch.PackageName)
+		if patch.PackageName == "openssl" {
+			return errors.New("unable to locate package")
+		}
+		return nil
+	}
+
+	app := NewAppWithServices(&config.Config{}, false, logger, aptService, apiClient, &bytes.Buffer{},
+		common.WithKeepGoing(true))
+
+	err := app.Run(ctx)
+	if err == nil || !strings.Contains(err.Error(), "1 of 2 patches failed: openssl") {
+		t.Fatalf("Expected a summary error naming openssl, got: %v", err)
+	}
+	if len(applied) != 2 {
+		t.Errorf("Expected both patches to be attempted, got %v", applied)
+	}
+}
+
+func TestAptApp_Run_SkipsDowngrades(t *testing.T) {
+	ctx := context.Background()
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+
+	patches := newTestPatches()
+	patches[0].Patch.Version = "3.0.11-1~deb11u1"
+	aptService, apiClient := newTestServices(patches)
+
+	app := NewAppWithServices(&config.Config{}, true, logger, aptService, apiClient, &bytes.Buffer{})
+
+	result, err := app.RunWithResult(ctx)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if len(result.Patches) != 1 || result.Patches[0].PackageName != "libc6" {
+		t.Errorf("Expected only libc6 to be patched, got %+v", result.Patches)
+	}
+	if len(result.Skipped) != 1 || result.Skipped[0].PackageName != "openssl" {
+		t.Errorf("Expected openssl to be skipped, got %+v", result.Skipped)
+	}
+}
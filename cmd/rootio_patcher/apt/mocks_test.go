@@ -0,0 +1,56 @@
+package apt
+
+import (
+	"context"
+
+	"rootio_patcher/cmd/rootio_patcher/common"
+	"rootio_patcher/pkg/rootio"
+)
+
+// MockAPIClient is a mock implementation of APIClient for testing
+type MockAPIClient struct {
+	AnalyzePackagesFunc func(ctx context.Context, packages []rootio.Package) (*rootio.AnalyzePackagesResponse, error)
+}
+
+func (m *MockAPIClient) AnalyzePackages(ctx context.Context, packages []rootio.Package) (*rootio.AnalyzePackagesResponse, error) {
+	if m.AnalyzePackagesFunc != nil {
+		return m.AnalyzePackagesFunc(ctx, packages)
+	}
+	return &rootio.AnalyzePackagesResponse{}, nil
+}
+
+// MockAptService is a mock implementation of Service for testing
+type MockAptService struct {
+	CheckDpkgFunc       func(ctx context.Context) error
+	ListPackagesFunc    func(ctx context.Context) ([]common.InstalledPackage, error)
+	SetupRepositoryFunc func(ctx context.Context) error
+	ApplyPatchFunc      func(ctx context.Context, patch rootio.PackagePatch) error
+}
+
+func (m *MockAptService) CheckDpkg(ctx context.Context) error {
+	if m.CheckDpkgFunc != nil {
+		return m.CheckDpkgFunc(ctx)
+	}
+	return nil
+}
+
+func (m *MockAptService) ListPackages(ctx context.Context) ([]common.InstalledPackage, error) {
+	if m.ListPackagesFunc != nil {
+		return m.ListPackagesFunc(ctx)
+	}
+	return []common.InstalledPackage{}, nil
+}
+
+func (m *MockAptService) SetupRepository(ctx context.Context) error {
+	if m.SetupRepositoryFunc != nil {
+		return m.SetupRepositoryFunc(ctx)
+	}
+	return nil
+}
+
+func (m *MockAptService) ApplyPatch(ctx context.Context, patch rootio.PackagePatch) error {
+	if m.ApplyPatchFunc != nil {
+		return m.ApplyPatchFunc(ctx, patch)
+	}
+	return nil
+}
@@ -0,0 +1,204 @@
+package apt
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"rootio_patcher/cmd/rootio_patcher/common"
+	"rootio_patcher/pkg/rootio"
+)
+
+// Locations of the apt configuration written for the Root.io repository
+const (
+	SourcesFile = "/etc/apt/sources.list.d/rootio.list"
+	AuthFile    = "/etc/apt/auth.conf.d/rootio.conf"
+	osRelease   = "/etc/os-release"
+)
+
+// Service defines the interface for dpkg/apt operations
+type Service interface {
+	// CheckDpkg verifies dpkg-query and apt-get are available
+	CheckDpkg(ctx context.Context) error
+
+	// ListPackages lists all installed Debian packages
+	ListPackages(ctx context.Context) ([]common.InstalledPackage, error)
+
+	// SetupRepository configures the Root.io apt repository and refreshes the package index
+	SetupRepository(ctx context.Context) error
+
+	// ApplyPatch installs the patched version of a package
+	ApplyPatch(ctx context.Context, patch rootio.PackagePatch) error
+}
+
+// AptService implements Service using dpkg-query and apt-get
+type AptService struct {
+	pkgURL string
+	apiKey string
+	logger *slog.Logger
+}
+
+// NewService creates a new apt service
+func NewService(pkgURL, apiKey string, logger *slog.Logger) *AptService {
+	return &AptService{
+		pkgURL: pkgURL,
+		apiKey: apiKey,
+		logger: logger,
+	}
+}
+
+// CheckDpkg makes sure this is a dpkg-based system with apt-get installed
+func (s *AptService) CheckDpkg(_ context.Context) error {
+	for _, tool := range []string{"dpkg-query", "apt-get"} {
+		if _, err := exec.LookPath(tool); err != nil {
+			return fmt.Errorf("%s is not available (is this a Debian-based system?): %w", tool, err)
+		}
+	}
+	return nil
+}
+
+// ListPackages collects installed packages using dpkg-query
+func (s *AptService) ListPackages(ctx context.Context) ([]common.InstalledPackage, error) {
+	cmd := exec.CommandContext(ctx, "dpkg-query", "-W", "-f=${Package}\t${Version}\n")
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("failed to run dpkg-query: %w", err)
+	}
+	return ParseDpkgOutput(output)
+}
+
+// ParseDpkgOutput parses dpkg-query output with one "package<TAB>version" line per package.
+// Packages without a version (removed but not purged) are not installed and are left out.
+func ParseDpkgOutput(output []byte) ([]common.InstalledPackage, error) {
+	var packages []common.InstalledPackage
+
+	scanner := bufio.NewScanner(bytes.NewReader(output))
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+
+		name, version, ok := strings.Cut(line, "\t")
+		if !ok {
+			return nil, fmt.Errorf("failed to parse dpkg-query output line %q", line)
+		}
+		if version = strings.TrimSpace(version); version == "" {
+			continue
+		}
+
+		packages = append(packages, common.InstalledPackage{
+			Name:    strings.TrimSpace(name),
+			Version: version,
+		})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read dpkg-query output: %w", err)
+	}
+
+	return packages, nil
+}
+
+// SetupRepository writes the Root.io source list and credentials, then runs apt-get update
+func (s *AptService) SetupRepository(ctx context.Context) error {
+	codename, err := readCodename(osRelease)
+	if err != nil {
+		return err
+	}
+
+	source, auth, err := RepositoryConfig(s.pkgURL, s.apiKey, codename)
+	if err != nil {
+		return err
+	}
+
+	s.logger.DebugContext(ctx, "Configuring Root.io apt repository",
+		slog.String("sources", SourcesFile),
+		slog.String("codename", codename))
+
+	if err := writeConfigFile(SourcesFile, source, 0644); err != nil {
+		return err
+	}
+	// The credentials file holds the API key, so it is only readable by root
+	if err := writeConfigFile(AuthFile, auth, 0600); err != nil {
+		return err
+	}
+
+	cmd := exec.CommandContext(ctx, "apt-get", "update")
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("apt-get update failed: %w (output: %s)", err, string(output))
+	}
+
+	return nil
+}
+
+// ApplyPatch installs the patched version of a single package with apt-get
+func (s *AptService) ApplyPatch(ctx context.Context, patch rootio.PackagePatch) error {
+	// Debian patches have no Root.io alias; the patch keeps the original name unless it sets one
+	target := common.PatchTarget(patch, false)
+
+	s.logger.DebugContext(ctx, "Installing patched package",
+		slog.String("package_name", target.Name),
+		slog.String("version", target.Version))
+
+	//nolint:gosec // Subprocess command is safe - using package names from our API
+	cmd := exec.CommandContext(ctx, "apt-get", InstallArgs(target.Name, target.Version)...)
+	cmd.Env = append(os.Environ(), "DEBIAN_FRONTEND=noninteractive")
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("apt-get install failed: %w (output: %s)", err, string(output))
+	}
+
+	return nil
+}
+
+// InstallArgs returns the apt-get arguments that install a specific package version
+func InstallArgs(name, version string) []string {
+	return []string{"install", "-y", "--no-install-recommends", fmt.Sprintf("%s=%s", name, version)}
+}
+
+// RepositoryConfig returns the apt source list entry and auth.conf entry for the Root.io repository
+func RepositoryConfig(pkgURL, apiKey, codename string) (string, string, error) {
+	parsedURL, err := url.Parse(pkgURL)
+	if err != nil || parsedURL.Host == "" {
+		return "", "", fmt.Errorf("invalid package URL %q", pkgURL)
+	}
+
+	repoURL := strings.TrimSuffix(pkgURL, "/") + "/debian"
+	source := fmt.Sprintf("deb %s %s main\n", repoURL, codename)
+	auth := fmt.Sprintf("machine %s/debian login root password %s\n", parsedURL.Host, apiKey)
+	return source, auth, nil
+}
+
+// readCodename returns the distribution codename (e.g. bookworm) from os-release
+func readCodename(path string) (string, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read %s: %w", path, err)
+	}
+
+	for _, line := range strings.Split(string(content), "\n") {
+		if value, ok := strings.CutPrefix(line, "VERSION_CODENAME="); ok {
+			if codename := strings.Trim(strings.TrimSpace(value), `"'`); codename != "" {
+				return codename, nil
+			}
+		}
+	}
+	return "", fmt.Errorf("failed to find VERSION_CODENAME in %s", path)
+}
+
+// writeConfigFile writes an apt configuration file, creating its directory if needed
+func writeConfigFile(path, content string, perm os.FileMode) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create %s: %w", filepath.Dir(path), err)
+	}
+	if err := os.WriteFile(path, []byte(content), perm); err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	return nil
+}
@@ -0,0 +1,63 @@
+package apt
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"rootio_patcher/cmd/rootio_patcher/common"
+)
+
+func TestParseDpkgOutput(t *testing.T) {
+	output := []byte("openssl\t3.0.11-1~deb12u2\nlibc6\t2.36-9+deb12u4\nremoved-pkg\t\n\n")
+
+	packages, err := ParseDpkgOutput(output)
+	if err != nil {
+		t.Fatalf("ParseDpkgOutput() error = %v", err)
+	}
+
+	expected := []common.InstalledPackage{
+		{Name: "openssl", Version: "3.0.11-1~deb12u2"},
+		{Name: "libc6", Version: "2.36-9+deb12u4"},
+	}
+	if !reflect.DeepEqual(packages, expected) {
+		t.Errorf("ParseDpkgOutput() = %+v, want %+v", packages, expected)
+	}
+}
+
+func TestParseDpkgOutput_Malformed(t *testing.T) {
+	if _, err := ParseDpkgOutput([]byte("openssl 3.0.11\n")); err == nil {
+		t.Fatal("Expected error for a line without a tab, got nil")
+	}
+}
+
+func TestRepositoryConfig(t *testing.T) {
+	source, auth, err := RepositoryConfig("https://pkg.root.io/", "secret", "bookworm")
+	if err != nil {
+		t.Fatalf("RepositoryConfig() error = %v", err)
+	}
+
+	if source != "deb https://pkg.root.io/debian bookworm main\n" {
+		t.Errorf("source = %q", source)
+	}
+	if auth != "machine pkg.root.io/debian login root password secret\n" {
+		t.Errorf("auth = %q", auth)
+	}
+}
+
+func TestReadCodename(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "os-release")
+	content := "PRETTY_NAME=\"Debian GNU/Linux 12 (bookworm)\"\nVERSION_CODENAME=bookworm\nID=debian\n"
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	codename, err := readCodename(path)
+	if err != nil {
+		t.Fatalf("readCodename() error = %v", err)
+	}
+	if codename != "bookworm" {
+		t.Errorf("readCodename() = %q, want bookworm", codename)
+	}
+}
@@ -0,0 +1,47 @@
+package common
+
+import (
+	"fmt"
+
+	"rootio_patcher/pkg/rootio"
+)
+
+// PatchTarget returns the package a patch installs: Root.io's aliased package with useAlias, or
+// the patched version of the original package. A patch the API returned no alias for falls back
+// to the patched version. An empty name is the original package.
+func PatchTarget(patch rootio.PackagePatch, useAlias bool) rootio.PatchInfo {
+	target := patch.Patch
+	if useAlias && (patch.PatchAlias.Version != "" || patch.Patch.Version == "") {
+		target = patch.PatchAlias
+	}
+	if target.Name == "" {
+		target.Name = patch.PackageName
+	}
+	return target
+}
+
+// ReplacesPackage reports whether the patch target is a different package than the one patched,
+// so the dependency has to be redirected to it rather than only bumped
+func ReplacesPackage(patch rootio.PackagePatch, target rootio.PatchInfo) bool {
+	return target.Name != patch.PackageName
+}
+
+// FilterMissingTargets drops patches the API returned neither the selected patched version nor a
+// fallback for, since installing them would produce malformed specs such as npm:@
+func FilterMissingTargets(
+	patches []rootio.PackagePatch, useAlias bool,
+) ([]rootio.PackagePatch, []rootio.SkippedPackage) {
+	var kept []rootio.PackagePatch
+	var skipped []rootio.SkippedPackage
+	for _, patch := range patches {
+		if PatchTarget(patch, useAlias).Version != "" {
+			kept = append(kept, patch)
+			continue
+		}
+		skipped = append(skipped, rootio.SkippedPackage{
+			PackageName: patch.PackageName,
+			Reason:      fmt.Sprintf("the API returned no patched version for %s %s", patch.PackageName, patch.Version),
+		})
+	}
+	return kept, skipped
+}
@@ -0,0 +1,75 @@
+package common
+
+import (
+	"testing"
+
+	"rootio_patcher/pkg/rootio"
+)
+
+func TestPatchTarget(t *testing.T) {
+	aliased := rootio.PackagePatch{
+		PackageName: "lodash",
+		Patch:       rootio.PatchInfo{Name: "lodash", Version: "4.17.21"},
+		PatchAlias:  rootio.PatchInfo{Name: "@rootio/lodash", Version: "4.17.21"},
+	}
+	unaliased := rootio.PackagePatch{
+		PackageName: "minimist",
+		Patch:       rootio.PatchInfo{Version: "1.2.6"},
+	}
+
+	tests := []struct {
+		name        string
+		patch       rootio.PackagePatch
+		useAlias    bool
+		want        rootio.PatchInfo
+		wantReplace bool
+	}{
+		{"alias", aliased, true, aliased.PatchAlias, true},
+		{"patched version", aliased, false, aliased.Patch, false},
+		{"missing alias falls back", unaliased, true, rootio.PatchInfo{Name: "minimist", Version: "1.2.6"}, false},
+		{"missing name is the original package", unaliased, false, rootio.PatchInfo{Name: "minimist", Version: "1.2.6"}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := PatchTarget(tt.patch, tt.useAlias)
+			if got != tt.want {
+				t.Errorf("PatchTarget() = %+v, want %+v", got, tt.want)
+			}
+			if replaces := ReplacesPackage(tt.patch, got); replaces != tt.wantReplace {
+				t.Errorf("ReplacesPackage() = %v, want %v", replaces, tt.wantReplace)
+			}
+		})
+	}
+}
+
+func TestOptions_Aliased(t *testing.T) {
+	if !NewOptions().Aliased(true) || NewOptions().Aliased(false) {
+		t.Error("Expected the ecosystem default without WithUseAlias")
+	}
+	if NewOptions(WithUseAlias(false)).Aliased(true) {
+		t.Error("Expected WithUseAlias(false) to override an aliasing default")
+	}
+	if !NewOptions(WithUseAlias(true)).Aliased(false) {
+		t.Error("Expected WithUseAlias(true) to override a non-aliasing default")
+	}
+}
+
+func TestFilterMissingTargets(t *testing.T) {
+	patches := []rootio.PackagePatch{
+		{PackageName: "lodash", Patch: rootio.PatchInfo{Version: "4.17.21"}},
+		{PackageName: "express", PatchAlias: rootio.PatchInfo{Name: "@rootio/express", Version: "4.18.2"}},
+		{PackageName: "minimist", Version: "1.2.5"},
+	}
+
+	kept, skipped := FilterMissingTargets(patches, true)
+	if len(kept) != 2 || len(skipped) != 1 || skipped[0].PackageName != "minimist" {
+		t.Errorf("Expected only minimist to be skipped with aliases, got kept %v, skipped %v", kept, skipped)
+	}
+
+	// Without aliases, an alias alone is nothing to install
+	kept, skipped = FilterMissingTargets(patches, false)
+	if len(kept) != 1 || kept[0].PackageName != "lodash" || len(skipped) != 2 {
+		t.Errorf("Expected only lodash to be kept without aliases, got kept %v, skipped %v", kept, skipped)
+	}
+}
@@ -0,0 +1,43 @@
+package common
+
+import (
+	"fmt"
+	"os"
+	"time"
+)
+
+// BackupSuffix is appended to a file path to form its backup path
+const BackupSuffix = ".rootio.bak"
+
+// BackupFile copies the file at path to <path>.rootio.bak and returns the backup path.
+// If that backup already exists it is kept, and a timestamped backup
+// (<path>.rootio.<YYYYMMDD-HHMMSS>.bak) is written instead.
+func BackupFile(path string) (string, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read %s for backup: %w", path, err)
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to stat %s: %w", path, err)
+	}
+
+	backupPath := path + BackupSuffix
+	if _, err := os.Stat(backupPath); err == nil {
+		backupPath = fmt.Sprintf("%s.rootio.%s.bak", path, time.Now().Format("20060102-150405"))
+	}
+
+	// O_EXCL guarantees an existing backup is never overwritten
+	file, err := os.OpenFile(backupPath, os.O_WRONLY|os.O_CREATE|os.O_EXCL, info.Mode().Perm())
+	if err != nil {
+		return "", fmt.Errorf("failed to create backup %s: %w", backupPath, err)
+	}
+	defer file.Close()
+
+	if _, err := file.Write(content); err != nil {
+		return "", fmt.Errorf("failed to write backup %s: %w", backupPath, err)
+	}
+
+	return backupPath, nil
+}
@@ -0,0 +1,69 @@
+package common
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestBackupFile(t *testing.T) {
+	tmpDir := t.TempDir()
+	file := filepath.Join(tmpDir, "pom.xml")
+	content := []byte("<project>\n  <!-- original -->\n</project>\n")
+	if err := os.WriteFile(file, content, 0644); err != nil {
+		t.Fatalf("Failed to create temp file: %v", err)
+	}
+
+	backupPath, err := BackupFile(file)
+	if err != nil {
+		t.Fatalf("BackupFile failed: %v", err)
+	}
+	if backupPath != file+BackupSuffix {
+		t.Errorf("Expected backup path '%s', got '%s'", file+BackupSuffix, backupPath)
+	}
+
+	backupContent, err := os.ReadFile(backupPath)
+	if err != nil {
+		t.Fatalf("Failed to read backup: %v", err)
+	}
+	if string(backupContent) != string(content) {
+		t.Error("Backup content should match the original byte-for-byte")
+	}
+}
+
+func TestBackupFile_ExistingBackupIsKept(t *testing.T) {
+	tmpDir := t.TempDir()
+	file := filepath.Join(tmpDir, "package.json")
+	if err := os.WriteFile(file, []byte(`{"version": "2"}`), 0644); err != nil {
+		t.Fatalf("Failed to create temp file: %v", err)
+	}
+	if err := os.WriteFile(file+BackupSuffix, []byte(`{"version": "1"}`), 0644); err != nil {
+		t.Fatalf("Failed to create existing backup: %v", err)
+	}
+
+	backupPath, err := BackupFile(file)
+	if err != nil {
+		t.Fatalf("BackupFile failed: %v", err)
+	}
+	if backupPath == file+BackupSuffix {
+		t.Fatal("Existing backup should not be overwritten")
+	}
+	if !strings.HasPrefix(backupPath, file+".rootio.") || !strings.HasSuffix(backupPath, ".bak") {
+		t.Errorf("Expected timestamped backup path, got '%s'", backupPath)
+	}
+
+	original, err := os.ReadFile(file + BackupSuffix)
+	if err != nil {
+		t.Fatalf("Failed to read existing backup: %v", err)
+	}
+	if string(original) != `{"version": "1"}` {
+		t.Error("Existing backup content should be unchanged")
+	}
+}
+
+func TestBackupFile_MissingFile(t *testing.T) {
+	if _, err := BackupFile("/nonexistent/pom.xml"); err == nil {
+		t.Fatal("Expected error for nonexistent file, got nil")
+	}
+}
@@ -0,0 +1,141 @@
+package common
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"rootio_patcher/pkg/rootio"
+)
+
+// DefaultCacheTTL is how long a cached analysis response is reused
+const DefaultCacheTTL = time.Hour
+
+// cacheEntry is an analysis response stored on disk
+type cacheEntry struct {
+	APIURL    string                          `json:"api_url"`
+	CreatedAt time.Time                       `json:"created_at"`
+	Response  *rootio.AnalyzePackagesResponse `json:"response"`
+}
+
+// CachingClient reuses analysis responses for an unchanged package set instead of calling the API.
+// Entries are stored per ecosystem under dir and keyed by the endpoint URL, a hash of the API key
+// and the sorted package list, so different credentials never share entries.
+type CachingClient struct {
+	client    APIClient
+	dir       string
+	ecosystem Ecosystem
+	apiURL    string
+	keyHash   string
+	ttl       time.Duration
+	now       func() time.Time
+}
+
+// NewCachingClient wraps client with an on-disk cache of its responses. Only a hash of apiKey is kept.
+func NewCachingClient(
+	client APIClient, dir string, ecosystem Ecosystem, apiURL, apiKey string, ttl time.Duration,
+) *CachingClient {
+	keyHash := sha256.Sum256([]byte(apiKey))
+	return &CachingClient{
+		client:    client,
+		dir:       dir,
+		ecosystem: ecosystem,
+		apiURL:    apiURL,
+		keyHash:   hex.EncodeToString(keyHash[:]),
+		ttl:       ttl,
+		now:       time.Now,
+	}
+}
+
+// AnalyzePackages returns the cached response for packages if it is fresh, and calls the API otherwise.
+// The cache is best effort: unreadable entries are treated as misses and write failures are ignored.
+func (c *CachingClient) AnalyzePackages(ctx context.Context, packages []rootio.Package) (*rootio.AnalyzePackagesResponse, error) {
+	path, err := c.entryPath(packages)
+	if err != nil {
+		return c.client.AnalyzePackages(ctx, packages)
+	}
+
+	if response, ok := c.load(path); ok {
+		return response, nil
+	}
+
+	response, err := c.client.AnalyzePackages(ctx, packages)
+	if err != nil {
+		return nil, err
+	}
+
+	_ = c.store(path, response)
+	return response, nil
+}
+
+// entryPath returns the cache file for a package set: <dir>/<ecosystem>/<sha256>.json
+func (c *CachingClient) entryPath(packages []rootio.Package) (string, error) {
+	encoded := make([]string, len(packages))
+	for i, pkg := range packages {
+		b, err := json.Marshal(pkg)
+		if err != nil {
+			return "", err
+		}
+		encoded[i] = string(b)
+	}
+	sort.Strings(encoded)
+
+	hash := sha256.New()
+	hash.Write([]byte(string(c.ecosystem) + "\n" + c.apiURL + "\n" + c.keyHash + "\n"))
+	for _, pkg := range encoded {
+		hash.Write([]byte(pkg + "\n"))
+	}
+
+	return filepath.Join(c.dir, string(c.ecosystem), hex.EncodeToString(hash.Sum(nil))+".json"), nil
+}
+
+// load returns the response stored at path if it was written for this API URL within the TTL
+func (c *CachingClient) load(path string) (*rootio.AnalyzePackagesResponse, bool) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, false
+	}
+
+	var entry cacheEntry
+	if err := json.Unmarshal(content, &entry); err != nil || entry.Response == nil {
+		return nil, false
+	}
+	if entry.APIURL != c.apiURL || c.now().Sub(entry.CreatedAt) >= c.ttl {
+		return nil, false
+	}
+
+	return entry.Response, true
+}
+
+// store writes response to path, replacing any previous entry atomically
+func (c *CachingClient) store(path string, response *rootio.AnalyzePackagesResponse) error {
+	content, err := json.Marshal(cacheEntry{APIURL: c.apiURL, CreatedAt: c.now(), Response: response})
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".entry-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(content); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), path)
+}
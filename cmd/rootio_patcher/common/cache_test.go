@@ -0,0 +1,117 @@
+package common
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"rootio_patcher/pkg/rootio"
+)
+
+// countingClient records how many times the API was called
+type countingClient struct {
+	calls int
+	err   error
+}
+
+func (c *countingClient) AnalyzePackages(ctx context.Context, packages []rootio.Package) (*rootio.AnalyzePackagesResponse, error) {
+	c.calls++
+	if c.err != nil {
+		return nil, c.err
+	}
+	return &rootio.AnalyzePackagesResponse{
+		Patches: []rootio.PackagePatch{
+			{PackageName: packages[0].Name, Version: packages[0].Version, Patch: rootio.PatchInfo{Name: packages[0].Name, Version: "9.9.9"}},
+		},
+	}, nil
+}
+
+func TestCachingClient_ReusesResponseForSamePackages(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	mock := &countingClient{}
+	client := NewCachingClient(mock, dir, EcosystemNpm, "https://api.root.io", "test-key", time.Hour)
+
+	packages := []rootio.Package{{Name: "lodash", Version: "4.17.20"}, {Name: "express", Version: "4.17.1"}}
+	if _, err := client.AnalyzePackages(ctx, packages); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	// The same packages in a different order hit the cache
+	reordered := []rootio.Package{packages[1], packages[0]}
+	response, err := client.AnalyzePackages(ctx, reordered)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if mock.calls != 1 {
+		t.Errorf("Expected the API to be called once, got %d calls", mock.calls)
+	}
+	if len(response.Patches) != 1 || response.Patches[0].PackageName != "lodash" {
+		t.Errorf("Expected the cached response, got %+v", response)
+	}
+
+	// A different package set misses
+	if _, err := client.AnalyzePackages(ctx, packages[:1]); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if mock.calls != 2 {
+		t.Errorf("Expected a new package set to call the API, got %d calls", mock.calls)
+	}
+}
+
+func TestCachingClient_Invalidation(t *testing.T) {
+	ctx := context.Background()
+	packages := []rootio.Package{{Name: "lodash", Version: "4.17.20"}}
+
+	tests := []struct {
+		name      string
+		ecosystem Ecosystem
+		apiURL    string
+		apiKey    string
+		elapsed   time.Duration
+	}{
+		{"other ecosystem", EcosystemMaven, "https://api.root.io", "test-key", 0},
+		{"other API URL", EcosystemNpm, "https://staging.api.root.io", "test-key", 0},
+		{"other API key", EcosystemNpm, "https://api.root.io", "other-key", 0},
+		{"expired", EcosystemNpm, "https://api.root.io", "test-key", 2 * time.Hour},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			mock := &countingClient{}
+
+			first := NewCachingClient(mock, dir, EcosystemNpm, "https://api.root.io", "test-key", time.Hour)
+			if _, err := first.AnalyzePackages(ctx, packages); err != nil {
+				t.Fatalf("Expected no error, got: %v", err)
+			}
+
+			second := NewCachingClient(mock, dir, tt.ecosystem, tt.apiURL, tt.apiKey, time.Hour)
+			second.now = func() time.Time { return time.Now().Add(tt.elapsed) }
+			if _, err := second.AnalyzePackages(ctx, packages); err != nil {
+				t.Fatalf("Expected no error, got: %v", err)
+			}
+
+			if mock.calls != 2 {
+				t.Errorf("Expected the API to be called again, got %d calls", mock.calls)
+			}
+		})
+	}
+}
+
+func TestCachingClient_DoesNotCacheErrors(t *testing.T) {
+	ctx := context.Background()
+	mock := &countingClient{err: errors.New("API error")}
+	client := NewCachingClient(mock, t.TempDir(), EcosystemPyPI, "https://api.root.io", "test-key", time.Hour)
+	packages := []rootio.Package{{Name: "django", Version: "4.2.0"}}
+
+	for i := 0; i < 2; i++ {
+		if _, err := client.AnalyzePackages(ctx, packages); !errors.Is(err, mock.err) {
+			t.Fatalf("Expected the API error, got: %v", err)
+		}
+	}
+	if mock.calls != 2 {
+		t.Errorf("Expected failed calls not to be cached, got %d calls", mock.calls)
+	}
+}
@@ -0,0 +1,60 @@
+package common
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+var (
+	// ErrConfirmationRequired is returned when patches would be applied without a terminal to
+	// confirm them on and --yes wasn't given
+	ErrConfirmationRequired = errors.New("confirmation required: pass --yes to apply patches without a terminal")
+
+	// ErrNotConfirmed is returned when the user declines to apply patches
+	ErrNotConfirmed = errors.New("aborted: patches were not confirmed")
+)
+
+// Confirmer asks before patches are applied. A nil *Confirmer applies without asking, which is
+// the default for library use and with --yes.
+type Confirmer struct {
+	in          *bufio.Reader
+	out         io.Writer
+	interactive bool
+}
+
+// NewConfirmer creates a Confirmer prompting on out and reading answers from in. When
+// interactive is false (stdin or stdout isn't a terminal) nothing is prompted and every
+// confirmation fails with ErrConfirmationRequired.
+func NewConfirmer(in io.Reader, out io.Writer, interactive bool) *Confirmer {
+	return &Confirmer{in: bufio.NewReader(in), out: out, interactive: interactive}
+}
+
+// Confirm asks whether to apply n patches to target, returning nil only if the user answers yes
+func (c *Confirmer) Confirm(n int, target string) error {
+	if c == nil {
+		return nil
+	}
+	if !c.interactive {
+		return fmt.Errorf("refusing to apply %d patches to %s: %w", n, target, ErrConfirmationRequired)
+	}
+
+	fmt.Fprintf(c.out, "\nApply %d patches to %s? [y/N] ", n, target)
+	answer, err := c.in.ReadString('\n')
+	if err != nil && !errors.Is(err, io.EOF) {
+		return fmt.Errorf("failed to read confirmation: %w", err)
+	}
+	switch strings.ToLower(strings.TrimSpace(answer)) {
+	case "y", "yes":
+		return nil
+	default:
+		return ErrNotConfirmed
+	}
+}
+
+// Prompts reports whether Confirm asks the user, which apps running concurrently can't share
+func (c *Confirmer) Prompts() bool {
+	return c != nil && c.interactive
+}
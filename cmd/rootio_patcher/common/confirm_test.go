@@ -0,0 +1,54 @@
+package common
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestConfirmer_Confirm(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		wantErr error
+	}{
+		{name: "yes", input: "y\n"},
+		{name: "full yes with spaces", input: "  YES \n"},
+		{name: "no", input: "n\n", wantErr: ErrNotConfirmed},
+		{name: "empty answer defaults to no", input: "\n", wantErr: ErrNotConfirmed},
+		{name: "end of input", input: "", wantErr: ErrNotConfirmed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out bytes.Buffer
+			err := NewConfirmer(strings.NewReader(tt.input), &out, true).Confirm(3, "package.json")
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("Expected error %v, got %v", tt.wantErr, err)
+			}
+			if !strings.Contains(out.String(), "Apply 3 patches to package.json? [y/N]") {
+				t.Errorf("Expected a prompt, got %q", out.String())
+			}
+		})
+	}
+}
+
+func TestConfirmer_Confirm_NonInteractive(t *testing.T) {
+	var out bytes.Buffer
+	err := NewConfirmer(strings.NewReader("y\n"), &out, false).Confirm(2, "pom.xml")
+	if !errors.Is(err, ErrConfirmationRequired) {
+		t.Errorf("Expected ErrConfirmationRequired without a terminal, got %v", err)
+	}
+	if out.Len() != 0 {
+		t.Errorf("Expected no prompt without a terminal, got %q", out.String())
+	}
+}
+
+func TestConfirmer_Confirm_Nil(t *testing.T) {
+	// --yes leaves the Confirmer unset, which applies without asking
+	var confirmer *Confirmer
+	if err := confirmer.Confirm(5, "go.mod"); err != nil {
+		t.Errorf("Expected a nil Confirmer to confirm, got %v", err)
+	}
+}
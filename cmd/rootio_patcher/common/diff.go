@@ -0,0 +1,168 @@
+package common
+
+import (
+	"fmt"
+	"strings"
+)
+
+// diffContext is the number of unchanged lines shown around each change
+const diffContext = 3
+
+// diffOp is one line of an edit script: ' ' kept, '-' removed or '+' added
+type diffOp struct {
+	kind byte
+	text string
+}
+
+// UnifiedDiff returns a unified diff of original and updated labelled with path,
+// or an empty string when the contents are identical
+func UnifiedDiff(path, original, updated string) string {
+	if original == updated {
+		return ""
+	}
+
+	ops := diffLines(splitLines(original), splitLines(updated))
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "--- %s\n+++ %s\n", path, path)
+
+	// Line numbers (1-based) of the next op in the original and updated files
+	oldLine, newLine := 1, 1
+	for start := 0; start < len(ops); {
+		// Find the next change
+		for start < len(ops) && ops[start].kind == ' ' {
+			start++
+			oldLine++
+			newLine++
+		}
+		if start == len(ops) {
+			break
+		}
+
+		// Extend the hunk until a run of unchanged lines is long enough to split it
+		end := start
+		for end < len(ops) {
+			if ops[end].kind != ' ' {
+				end++
+				continue
+			}
+			run := end
+			for run < len(ops) && ops[run].kind == ' ' {
+				run++
+			}
+			if run == len(ops) || run-end > 2*diffContext {
+				break
+			}
+			end = run
+		}
+
+		lead := min(diffContext, start)
+		trail := 0
+		for end+trail < len(ops) && trail < diffContext && ops[end+trail].kind == ' ' {
+			trail++
+		}
+		hunk := ops[start-lead : end+trail]
+
+		var oldCount, newCount int
+		for _, op := range hunk {
+			if op.kind != '+' {
+				oldCount++
+			}
+			if op.kind != '-' {
+				newCount++
+			}
+		}
+		fmt.Fprintf(&b, "@@ -%s +%s @@\n", hunkRange(oldLine-lead, oldCount), hunkRange(newLine-lead, newCount))
+		for _, op := range hunk {
+			fmt.Fprintf(&b, "%c%s\n", op.kind, op.text)
+		}
+
+		for _, op := range ops[start:end] {
+			if op.kind != '+' {
+				oldLine++
+			}
+			if op.kind != '-' {
+				newLine++
+			}
+		}
+		start = end
+	}
+
+	return b.String()
+}
+
+// hunkRange formats a hunk's start line and line count; an empty range starts at the line before it
+func hunkRange(start, count int) string {
+	if count == 0 {
+		return fmt.Sprintf("%d,0", start-1)
+	}
+	if count == 1 {
+		return fmt.Sprintf("%d", start)
+	}
+	return fmt.Sprintf("%d,%d", start, count)
+}
+
+// splitLines splits content into lines, ignoring the newline at the end of the last line
+func splitLines(content string) []string {
+	if content == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(content, "\n"), "\n")
+}
+
+// diffLines builds a line edit script from the longest common subsequence of a and b.
+// Version bumps touch few lines, so the shared prefix and suffix are trimmed first to
+// keep the quadratic table small.
+func diffLines(a, b []string) []diffOp {
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+
+	var ops []diffOp
+	for _, line := range a[:prefix] {
+		ops = append(ops, diffOp{' ', line})
+	}
+
+	midA, midB := a[prefix:len(a)-suffix], b[prefix:len(b)-suffix]
+
+	// lcs[i][j] is the LCS length of midA[i:] and midB[j:]
+	lcs := make([][]int, len(midA)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(midB)+1)
+	}
+	for i := len(midA) - 1; i >= 0; i-- {
+		for j := len(midB) - 1; j >= 0; j-- {
+			if midA[i] == midB[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	i, j := 0, 0
+	for i < len(midA) || j < len(midB) {
+		switch {
+		case i < len(midA) && j < len(midB) && midA[i] == midB[j]:
+			ops = append(ops, diffOp{' ', midA[i]})
+			i++
+			j++
+		case j < len(midB) && (i == len(midA) || lcs[i][j+1] > lcs[i+1][j]):
+			ops = append(ops, diffOp{'+', midB[j]})
+			j++
+		default:
+			ops = append(ops, diffOp{'-', midA[i]})
+			i++
+		}
+	}
+
+	for _, line := range a[len(a)-suffix:] {
+		ops = append(ops, diffOp{' ', line})
+	}
+	return ops
+}
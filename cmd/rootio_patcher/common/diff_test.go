@@ -0,0 +1,80 @@
+package common
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestUnifiedDiff_VersionChange(t *testing.T) {
+	original := strings.Join([]string{
+		"<project>",
+		"  <dependencies>",
+		"    <dependency>",
+		"      <groupId>org.apache.logging.log4j</groupId>",
+		"      <artifactId>log4j-core</artifactId>",
+		"      <version>2.14.1</version>",
+		"    </dependency>",
+		"  </dependencies>",
+		"</project>",
+	}, "\n") + "\n"
+	updated := strings.Replace(original, "2.14.1", "2.17.1", 1)
+
+	diff := UnifiedDiff("pom.xml", original, updated)
+
+	expected := `--- pom.xml
++++ pom.xml
+@@ -3,7 +3,7 @@
+     <dependency>
+       <groupId>org.apache.logging.log4j</groupId>
+       <artifactId>log4j-core</artifactId>
+-      <version>2.14.1</version>
++      <version>2.17.1</version>
+     </dependency>
+   </dependencies>
+ </project>
+`
+	if diff != expected {
+		t.Errorf("UnifiedDiff() =\n%s\nwant:\n%s", diff, expected)
+	}
+}
+
+func TestUnifiedDiff_AddedLines(t *testing.T) {
+	original := "{\n  \"name\": \"app\"\n}\n"
+	updated := "{\n  \"name\": \"app\",\n  \"overrides\": {\n    \"lodash\": \"npm:@rootio/lodash@4.17.21\"\n  }\n}\n"
+
+	diff := UnifiedDiff("package.json", original, updated)
+
+	for _, want := range []string{
+		"@@ -1,3 +1,6 @@",
+		"-  \"name\": \"app\"\n",
+		"+  \"name\": \"app\",\n",
+		"+    \"lodash\": \"npm:@rootio/lodash@4.17.21\"\n",
+		" }\n",
+	} {
+		if !strings.Contains(diff, want) {
+			t.Errorf("Expected diff to contain %q, got:\n%s", want, diff)
+		}
+	}
+}
+
+func TestUnifiedDiff_SeparateHunks(t *testing.T) {
+	var lines []string
+	for i := 1; i <= 20; i++ {
+		lines = append(lines, "line")
+	}
+	lines[1], lines[17] = "a=1", "b=1"
+	original := strings.Join(lines, "\n") + "\n"
+	updated := strings.Replace(strings.Replace(original, "a=1", "a=2", 1), "b=1", "b=2", 1)
+
+	diff := UnifiedDiff("requirements.txt", original, updated)
+
+	if !strings.Contains(diff, "@@ -1,5 +1,5 @@\n") || !strings.Contains(diff, "@@ -15,6 +15,6 @@\n") {
+		t.Errorf("Expected two hunks, got:\n%s", diff)
+	}
+}
+
+func TestUnifiedDiff_Identical(t *testing.T) {
+	if diff := UnifiedDiff("pom.xml", "same\n", "same\n"); diff != "" {
+		t.Errorf("Expected no diff for identical content, got %q", diff)
+	}
+}
@@ -2,6 +2,8 @@ package common
 
 import (
 	"context"
+
+	"rootio_patcher/pkg/rootio"
 )
 
 // Ecosystem represents a package ecosystem (npm, pypi, maven, etc.)
@@ -11,6 +13,16 @@ const (
 	EcosystemPyPI  Ecosystem = "pypi"
 	EcosystemNpm   Ecosystem = "npm"
 	EcosystemMaven Ecosystem = "maven"
+	EcosystemGo    Ecosystem = "go"
+
+	// EcosystemRubyGems covers gems locked by Bundler in Gemfile.lock
+	EcosystemRubyGems Ecosystem = "rubygems"
+
+	// EcosystemNuGet covers .NET packages referenced by projects, packages.config and packages.lock.json
+	EcosystemNuGet Ecosystem = "nuget"
+
+	// EcosystemDebian covers dpkg/apt system packages
+	EcosystemDebian Ecosystem = "debian"
 )
 
 // PackageInfo represents a package with its metadata
@@ -22,6 +34,28 @@ type PackageInfo struct {
 	Direct            bool      `json:"direct"`
 	Dev               bool      `json:"dev"`
 	Location          string    `json:"location,omitempty"`
+	// Path is the package's position in the dependency tree, when the lock file records one
+	// (e.g. node_modules/a/node_modules/b in package-lock.json)
+	Path string `json:"path,omitempty"`
+	// Parents are the names of the packages depending on this install, when the lock file
+	// records them (package-lock.json v2 and v3); empty for direct dependencies of the project
+	Parents []string `json:"parents,omitempty"`
+	// Profile is the Maven profile declaring the package, empty outside profiles
+	Profile string `json:"profile,omitempty"`
+	// ExcludedBy is the Maven dependency whose <exclusions> remove this managed-only artifact
+	// from the build, empty when nothing excludes it
+	ExcludedBy string `json:"excluded_by,omitempty"`
+}
+
+// SDKPackage converts the package to the form sent to the Root.io API
+func (p PackageInfo) SDKPackage() rootio.Package {
+	return rootio.Package{
+		Name:      p.Name,
+		Version:   p.Version,
+		Ecosystem: string(p.Ecosystem),
+		Direct:    p.Direct,
+		Dev:       p.Dev,
+	}
 }
 
 // Parser defines the interface for ecosystem-specific dependency parsers
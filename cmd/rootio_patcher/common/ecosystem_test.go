@@ -0,0 +1,24 @@
+package common
+
+import (
+	"testing"
+
+	"rootio_patcher/pkg/rootio"
+)
+
+func TestPackageInfo_SDKPackage(t *testing.T) {
+	pkg := PackageInfo{
+		Name:              "jest",
+		Version:           "29.0.0",
+		VersionConstraint: "^29.0.0",
+		Ecosystem:         EcosystemNpm,
+		Direct:            true,
+		Dev:               true,
+		Location:          "package-lock.json",
+	}
+
+	expected := rootio.Package{Name: "jest", Version: "29.0.0", Ecosystem: "npm", Direct: true, Dev: true}
+	if got := pkg.SDKPackage(); got != expected {
+		t.Errorf("SDKPackage() = %+v, want %+v", got, expected)
+	}
+}
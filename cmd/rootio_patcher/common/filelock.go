@@ -0,0 +1,121 @@
+package common
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"time"
+)
+
+// LockSuffix is appended to a file path to form the path of its lock file
+const LockSuffix = ".rootio.lock"
+
+// DefaultLockTimeout is how long LockFiles waits for another run to release a file
+const DefaultLockTimeout = 30 * time.Second
+
+// lockRetryInterval is how often a held lock is tried again
+const lockRetryInterval = 50 * time.Millisecond
+
+// ErrLockTimeout is returned when a file is still locked by another run after the timeout
+var ErrLockTimeout = errors.New("timed out waiting for file lock")
+
+// FileLock is an advisory lock on a dependency file, held on its <path>.rootio.lock sibling so
+// two runs patching the same file don't overwrite each other's changes
+type FileLock struct {
+	path string
+	file *os.File
+}
+
+// LockFile takes an exclusive lock on path, waiting up to timeout (DefaultLockTimeout when 0)
+// for another run holding it to finish
+func LockFile(ctx context.Context, path string, timeout time.Duration) (*FileLock, error) {
+	if timeout <= 0 {
+		timeout = DefaultLockTimeout
+	}
+	lockPath := path + LockSuffix
+	deadline := time.Now().Add(timeout)
+
+	for {
+		file, err := os.OpenFile(lockPath, os.O_RDWR|os.O_CREATE, 0644)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open lock file %s: %w", lockPath, err)
+		}
+
+		locked, err := tryLock(file)
+		if err != nil {
+			file.Close()
+			return nil, fmt.Errorf("failed to lock %s: %w", lockPath, err)
+		}
+		if locked {
+			// The previous holder removes the lock file when it's done; if that happened after we
+			// opened it, we locked a file nobody else will see, so start over
+			if sameFile(file, lockPath) {
+				return &FileLock{path: lockPath, file: file}, nil
+			}
+			unlock(file)
+		}
+		file.Close()
+
+		if time.Now().After(deadline) {
+			return nil, fmt.Errorf("%w: %s is locked by another rootio_patcher run after %s (remove %s if no other run is active)",
+				ErrLockTimeout, path, timeout, lockPath)
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, fmt.Errorf("failed to lock %s: %w", path, ctx.Err())
+		case <-time.After(lockRetryInterval):
+		}
+	}
+}
+
+// LockFiles locks each of paths in sorted order, so runs locking overlapping files can't
+// deadlock. The returned function releases every lock.
+func LockFiles(ctx context.Context, paths []string, timeout time.Duration) (func(), error) {
+	sorted := make([]string, len(paths))
+	copy(sorted, paths)
+	sort.Strings(sorted)
+
+	var locks []*FileLock
+	release := func() {
+		for i := len(locks) - 1; i >= 0; i-- {
+			locks[i].Unlock()
+		}
+	}
+
+	for i, path := range sorted {
+		if i > 0 && path == sorted[i-1] {
+			continue
+		}
+		lock, err := LockFile(ctx, path, timeout)
+		if err != nil {
+			release()
+			return nil, err
+		}
+		locks = append(locks, lock)
+	}
+	return release, nil
+}
+
+// Unlock releases the lock and removes the lock file
+func (l *FileLock) Unlock() error {
+	if err := release(l.file, l.path); err != nil {
+		return fmt.Errorf("failed to release lock %s: %w", l.path, err)
+	}
+	return nil
+}
+
+// sameFile reports whether the open file is still the one at path
+func sameFile(file *os.File, path string) bool {
+	opened, err := file.Stat()
+	if err != nil {
+		return false
+	}
+	current, err := os.Stat(path)
+	if err != nil {
+		return false
+	}
+	return os.SameFile(opened, current)
+}
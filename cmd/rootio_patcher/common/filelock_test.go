@@ -0,0 +1,96 @@
+package common
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestLockFile(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "go.mod")
+
+	lock, err := LockFile(context.Background(), file, time.Second)
+	if err != nil {
+		t.Fatalf("LockFile failed: %v", err)
+	}
+	if _, err := os.Stat(file + LockSuffix); err != nil {
+		t.Errorf("Expected lock file to exist: %v", err)
+	}
+
+	// A second lock times out while the first is held
+	if _, err := LockFile(context.Background(), file, 100*time.Millisecond); !errors.Is(err, ErrLockTimeout) {
+		t.Errorf("Expected ErrLockTimeout, got: %v", err)
+	}
+
+	if err := lock.Unlock(); err != nil {
+		t.Fatalf("Unlock failed: %v", err)
+	}
+	if _, err := os.Stat(file + LockSuffix); !os.IsNotExist(err) {
+		t.Errorf("Expected lock file to be removed, got: %v", err)
+	}
+
+	lock, err = LockFile(context.Background(), file, 100*time.Millisecond)
+	if err != nil {
+		t.Fatalf("Expected lock to be free after Unlock, got: %v", err)
+	}
+	lock.Unlock()
+}
+
+func TestLockFile_WaitsForRelease(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "pom.xml")
+
+	lock, err := LockFile(context.Background(), file, time.Second)
+	if err != nil {
+		t.Fatalf("LockFile failed: %v", err)
+	}
+	go func() {
+		time.Sleep(100 * time.Millisecond)
+		lock.Unlock()
+	}()
+
+	second, err := LockFile(context.Background(), file, 5*time.Second)
+	if err != nil {
+		t.Fatalf("Expected the lock once released, got: %v", err)
+	}
+	second.Unlock()
+}
+
+func TestLockFile_ContextCanceled(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "Gemfile.lock")
+
+	lock, err := LockFile(context.Background(), file, time.Second)
+	if err != nil {
+		t.Fatalf("LockFile failed: %v", err)
+	}
+	defer lock.Unlock()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := LockFile(ctx, file, time.Second); !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected context.Canceled, got: %v", err)
+	}
+}
+
+func TestLockFiles(t *testing.T) {
+	dir := t.TempDir()
+	a, b := filepath.Join(dir, "a.txt"), filepath.Join(dir, "b.txt")
+
+	// Duplicates are locked once rather than timing out on themselves
+	unlock, err := LockFiles(context.Background(), []string{b, a, b}, 100*time.Millisecond)
+	if err != nil {
+		t.Fatalf("LockFiles failed: %v", err)
+	}
+	if _, err := LockFile(context.Background(), a, 50*time.Millisecond); !errors.Is(err, ErrLockTimeout) {
+		t.Errorf("Expected %s to be locked, got: %v", a, err)
+	}
+
+	unlock()
+	for _, path := range []string{a, b} {
+		if _, err := os.Stat(path + LockSuffix); !os.IsNotExist(err) {
+			t.Errorf("Expected lock file of %s to be removed, got: %v", path, err)
+		}
+	}
+}
@@ -0,0 +1,37 @@
+//go:build unix
+
+package common
+
+import (
+	"errors"
+	"os"
+	"syscall"
+)
+
+// tryLock takes an exclusive flock on file without blocking, reporting false if it's held elsewhere
+func tryLock(file *os.File) (bool, error) {
+	err := syscall.Flock(int(file.Fd()), syscall.LOCK_EX|syscall.LOCK_NB)
+	if errors.Is(err, syscall.EWOULDBLOCK) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// unlock releases the flock on file
+func unlock(file *os.File) {
+	_ = syscall.Flock(int(file.Fd()), syscall.LOCK_UN)
+}
+
+// release removes the lock file, then unlocks and closes it. Removing it first means a waiting
+// run never locks a file that's about to disappear.
+func release(file *os.File, path string) error {
+	removeErr := os.Remove(path)
+	unlock(file)
+	if err := file.Close(); err != nil {
+		return err
+	}
+	if removeErr != nil && !errors.Is(removeErr, os.ErrNotExist) {
+		return removeErr
+	}
+	return nil
+}
@@ -0,0 +1,57 @@
+//go:build windows
+
+package common
+
+import (
+	"errors"
+	"os"
+	"syscall"
+	"unsafe"
+)
+
+const (
+	lockfileFailImmediately = 0x1
+	lockfileExclusiveLock   = 0x2
+
+	// errorLockViolation is returned by LockFileEx when another handle holds the lock
+	errorLockViolation syscall.Errno = 33
+)
+
+var (
+	kernel32         = syscall.NewLazyDLL("kernel32.dll")
+	procLockFileEx   = kernel32.NewProc("LockFileEx")
+	procUnlockFileEx = kernel32.NewProc("UnlockFileEx")
+)
+
+// tryLock takes an exclusive lock on the first byte of file without blocking, reporting false
+// if it's held elsewhere
+func tryLock(file *os.File) (bool, error) {
+	var overlapped syscall.Overlapped
+	r1, _, err := procLockFileEx.Call(file.Fd(), lockfileExclusiveLock|lockfileFailImmediately,
+		0, 1, 0, uintptr(unsafe.Pointer(&overlapped)))
+	if r1 != 0 {
+		return true, nil
+	}
+	if errors.Is(err, errorLockViolation) {
+		return false, nil
+	}
+	return false, err
+}
+
+// unlock releases the byte-range lock taken by tryLock with UnlockFileEx
+func unlock(file *os.File) {
+	var overlapped syscall.Overlapped
+	procUnlockFileEx.Call(file.Fd(), 0, 1, 0, uintptr(unsafe.Pointer(&overlapped)))
+}
+
+// release unlocks the lock file with UnlockFileEx, closes the handle, then removes the file.
+// Removal is best effort: it fails while another run still has the file open, and that run
+// removes it when it's done.
+func release(file *os.File, path string) error {
+	unlock(file)
+	if err := file.Close(); err != nil {
+		return err
+	}
+	_ = os.Remove(path)
+	return nil
+}
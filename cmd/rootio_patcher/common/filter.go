@@ -0,0 +1,134 @@
+package common
+
+import (
+	"path"
+	"strings"
+
+	"rootio_patcher/pkg/rootio"
+)
+
+// FilterPatches applies the filters every app runs on the API's patches, in order: names and
+// versions that aren't valid for the ecosystem, patches without a version to install (with
+// useAlias, the aliased one or its fallback), --min-severity, --only and --exclude, --cve, and
+// patches that aren't newer than the current version. It returns the patches left and a
+// skipped entry for each one dropped.
+func FilterPatches(
+	ecosystem Ecosystem, patches []rootio.PackagePatch, useAlias bool, options Options,
+) ([]rootio.PackagePatch, []rootio.SkippedPackage) {
+	var skipped, dropped []rootio.SkippedPackage
+	patches, dropped = FilterInvalidNames(ecosystem, patches)
+	skipped = append(skipped, dropped...)
+	patches, dropped = FilterMissingTargets(patches, useAlias)
+	skipped = append(skipped, dropped...)
+	patches, dropped = FilterBySeverity(patches, options.MinSeverity)
+	skipped = append(skipped, dropped...)
+	patches, dropped = FilterByName(patches, options.Only, options.Exclude)
+	skipped = append(skipped, dropped...)
+	patches, dropped = FilterByCVE(patches, options.CVEs)
+	skipped = append(skipped, dropped...)
+	patches, dropped = FilterDowngrades(ecosystem, patches, useAlias)
+	skipped = append(skipped, dropped...)
+	return patches, skipped
+}
+
+// FilterByName splits patches into those selected by the --only and --exclude
+// package lists and skipped entries for the rest. Patterns match the package
+// name (groupId:artifactId for Maven) case-insensitively and may use globs
+// such as "@babel/*". When both lists are given, only is applied first and
+// exclude narrows the result further.
+func FilterByName(
+	patches []rootio.PackagePatch, only, exclude []string,
+) ([]rootio.PackagePatch, []rootio.SkippedPackage) {
+	if len(only) == 0 && len(exclude) == 0 {
+		return patches, nil
+	}
+
+	var kept []rootio.PackagePatch
+	var skipped []rootio.SkippedPackage
+	for _, patch := range patches {
+		switch {
+		case len(only) > 0 && !MatchesAny(patch.PackageName, only):
+			skipped = append(skipped, rootio.SkippedPackage{
+				PackageName: patch.PackageName,
+				Reason:      "not selected by --only",
+			})
+		case MatchesAny(patch.PackageName, exclude):
+			skipped = append(skipped, rootio.SkippedPackage{
+				PackageName: patch.PackageName,
+				Reason:      "excluded by --exclude",
+			})
+		default:
+			kept = append(kept, patch)
+		}
+	}
+
+	return kept, skipped
+}
+
+// FilterByCVE splits patches into those fixing at least one of the CVEs selected with --cve
+// and skipped entries for the rest. IDs are compared case-insensitively; with no CVEs
+// selected every patch is kept.
+func FilterByCVE(patches []rootio.PackagePatch, cves []string) ([]rootio.PackagePatch, []rootio.SkippedPackage) {
+	if len(cves) == 0 {
+		return patches, nil
+	}
+
+	var kept []rootio.PackagePatch
+	var skipped []rootio.SkippedPackage
+	for _, patch := range patches {
+		if len(MatchedCVEs(patch, cves)) > 0 {
+			kept = append(kept, patch)
+			continue
+		}
+		skipped = append(skipped, rootio.SkippedPackage{
+			PackageName: patch.PackageName,
+			Reason:      "fixes none of the CVEs selected by --cve",
+		})
+	}
+	return kept, skipped
+}
+
+// MatchedCVEs returns the CVEs of cves that a patch fixes, in the order they were given
+func MatchedCVEs(patch rootio.PackagePatch, cves []string) []string {
+	var matched []string
+	for _, cve := range cves {
+		for _, id := range patch.CVEIDs {
+			if strings.EqualFold(strings.TrimSpace(cve), id) {
+				matched = append(matched, id)
+				break
+			}
+		}
+	}
+	return matched
+}
+
+// FilterDev drops development and test dependencies (npm devDependencies, Maven test
+// scope, Poetry dev groups) and returns the remaining packages and how many were dropped
+func FilterDev(packages []PackageInfo) ([]PackageInfo, int) {
+	var kept []PackageInfo
+	for _, pkg := range packages {
+		if !pkg.Dev {
+			kept = append(kept, pkg)
+		}
+	}
+	return kept, len(packages) - len(kept)
+}
+
+// MatchesAny reports whether name matches any of the given names or glob patterns (case-insensitive)
+func MatchesAny(name string, patterns []string) bool {
+	name = strings.ToLower(name)
+	for _, pattern := range patterns {
+		pattern = strings.ToLower(strings.TrimSpace(pattern))
+		if pattern == "" {
+			continue
+		}
+		if pattern == name {
+			return true
+		}
+		// Malformed patterns simply don't match
+		if matched, err := path.Match(pattern, name); err == nil && matched {
+			return true
+		}
+	}
+	return false
+}
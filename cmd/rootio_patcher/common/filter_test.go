@@ -0,0 +1,179 @@
+package common
+
+import (
+	"reflect"
+	"testing"
+
+	"rootio_patcher/pkg/rootio"
+)
+
+func TestMatchesAny(t *testing.T) {
+	tests := []struct {
+		name     string
+		patterns []string
+		expected bool
+	}{
+		{"django", []string{"django"}, true},
+		{"Django", []string{"django"}, true},
+		{"flask", []string{"django", " flask "}, true},
+		{"@babel/core", []string{"@babel/*"}, true},
+		{"@babel/core", []string{"@types/*"}, false},
+		{"org.apache.logging.log4j:log4j-core", []string{"org.apache.logging.log4j:*"}, true},
+		{"junit:junit", []string{"org.apache.*"}, false},
+		{"django", []string{"[invalid"}, false},
+		{"django", nil, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if matched := MatchesAny(tt.name, tt.patterns); matched != tt.expected {
+				t.Errorf("MatchesAny(%q, %v) = %v, expected %v", tt.name, tt.patterns, matched, tt.expected)
+			}
+		})
+	}
+}
+
+func TestFilterByName(t *testing.T) {
+	patches := []rootio.PackagePatch{
+		{PackageName: "@babel/core"},
+		{PackageName: "@babel/traverse"},
+		{PackageName: "lodash"},
+		{PackageName: "express"},
+	}
+
+	tests := []struct {
+		name     string
+		only     []string
+		exclude  []string
+		expected []string
+		skipped  map[string]string
+	}{
+		{
+			name:     "no filters",
+			expected: []string{"@babel/core", "@babel/traverse", "lodash", "express"},
+		},
+		{
+			name:     "exclude",
+			exclude:  []string{"lodash"},
+			expected: []string{"@babel/core", "@babel/traverse", "express"},
+			skipped:  map[string]string{"lodash": "excluded by --exclude"},
+		},
+		{
+			name:     "only with glob",
+			only:     []string{"@babel/*"},
+			expected: []string{"@babel/core", "@babel/traverse"},
+			skipped: map[string]string{
+				"lodash":  "not selected by --only",
+				"express": "not selected by --only",
+			},
+		},
+		{
+			name:     "exclude narrows only",
+			only:     []string{"@babel/*", "lodash"},
+			exclude:  []string{"@babel/traverse"},
+			expected: []string{"@babel/core", "lodash"},
+			skipped: map[string]string{
+				"@babel/traverse": "excluded by --exclude",
+				"express":         "not selected by --only",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			kept, skipped := FilterByName(patches, tt.only, tt.exclude)
+
+			if len(kept) != len(tt.expected) {
+				t.Fatalf("Expected %d patches, got %d: %+v", len(tt.expected), len(kept), kept)
+			}
+			for i, name := range tt.expected {
+				if kept[i].PackageName != name {
+					t.Errorf("Patch %d: expected %s, got %s", i, name, kept[i].PackageName)
+				}
+			}
+
+			if len(skipped) != len(tt.skipped) {
+				t.Fatalf("Expected %d skipped, got %d: %+v", len(tt.skipped), len(skipped), skipped)
+			}
+			for _, s := range skipped {
+				if reason := tt.skipped[s.PackageName]; s.Reason != reason {
+					t.Errorf("Expected %s to be skipped with %q, got %q", s.PackageName, reason, s.Reason)
+				}
+			}
+		})
+	}
+}
+
+func TestFilterByCVE(t *testing.T) {
+	patches := []rootio.PackagePatch{
+		{PackageName: "lodash", CVEIDs: []string{"CVE-2021-23337", "CVE-2020-28500"}},
+		{PackageName: "minimist", CVEIDs: []string{"CVE-2021-44906"}},
+		{PackageName: "express"},
+	}
+
+	kept, skipped := FilterByCVE(patches, []string{"cve-2021-23337"})
+	if len(kept) != 1 || kept[0].PackageName != "lodash" {
+		t.Errorf("Expected only lodash to be kept, got %+v", kept)
+	}
+	expectedSkipped := []rootio.SkippedPackage{
+		{PackageName: "minimist", Reason: "fixes none of the CVEs selected by --cve"},
+		{PackageName: "express", Reason: "fixes none of the CVEs selected by --cve"},
+	}
+	if !reflect.DeepEqual(skipped, expectedSkipped) {
+		t.Errorf("Expected skipped %+v, got %+v", expectedSkipped, skipped)
+	}
+
+	kept, _ = FilterByCVE(patches, []string{"CVE-2021-23337", "CVE-2021-44906"})
+	if len(kept) != 2 {
+		t.Errorf("Expected lodash and minimist to be kept, got %+v", kept)
+	}
+
+	if kept, skipped := FilterByCVE(patches, nil); len(kept) != 3 || skipped != nil {
+		t.Errorf("Expected every patch to be kept without --cve, got %+v, %+v", kept, skipped)
+	}
+}
+
+func TestFilterPatches(t *testing.T) {
+	patches := []rootio.PackagePatch{
+		{PackageName: "lodash", Version: "4.17.20", Patch: rootio.PatchInfo{Version: "4.17.21"},
+			CVEIDs: []string{"CVE-2021-23337"}, Severity: "high"},
+		{PackageName: "minimist", Version: "1.2.5", Patch: rootio.PatchInfo{Version: "1.2.6"},
+			CVEIDs: []string{"CVE-2021-44906"}, Severity: "critical"},
+		{PackageName: "express", Version: "4.17.1", Patch: rootio.PatchInfo{}, Severity: "high"},
+		{PackageName: "qs", Version: "6.10.3", Patch: rootio.PatchInfo{Version: "6.10.1"}, Severity: "high"},
+		{PackageName: "debug", Version: "2.6.8", Patch: rootio.PatchInfo{Version: "2.6.9"}, Severity: "low"},
+		{PackageName: "Bad Name", Version: "1.0.0", Patch: rootio.PatchInfo{Version: "1.0.1"}, Severity: "high"},
+	}
+	options := NewOptions(WithMinSeverity("medium"), WithPackageFilter(nil, []string{"minimist"}))
+
+	kept, skipped := FilterPatches(EcosystemNpm, patches, false, options)
+	if len(kept) != 1 || kept[0].PackageName != "lodash" {
+		t.Errorf("Expected only lodash to be kept, got %+v", kept)
+	}
+	var names []string
+	for _, s := range skipped {
+		names = append(names, s.PackageName)
+	}
+	// Each filter reports its own drops, in the order the filters run
+	expected := []string{"Bad Name", "express", "debug", "minimist", "qs"}
+	if !reflect.DeepEqual(names, expected) {
+		t.Errorf("Expected skipped %v, got %v", expected, names)
+	}
+}
+
+func TestFilterDev(t *testing.T) {
+	packages := []PackageInfo{
+		{Name: "express", Version: "4.17.1", Direct: true},
+		{Name: "jest", Version: "29.0.0", Direct: true, Dev: true},
+		{Name: "babel-core", Version: "6.26.3", Dev: true},
+	}
+
+	kept, skipped := FilterDev(packages)
+
+	if len(kept) != 1 || kept[0].Name != "express" {
+		t.Errorf("Expected only express to be kept, got %+v", kept)
+	}
+	if skipped != 2 {
+		t.Errorf("Expected 2 dev packages skipped, got %d", skipped)
+	}
+}
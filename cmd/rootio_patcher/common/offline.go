@@ -0,0 +1,103 @@
+package common
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"rootio_patcher/pkg/rootio"
+)
+
+// LocalDatabase is a vulnerability database read from a JSON file, used in place of the
+// Root.io API where it can't be reached
+type LocalDatabase struct {
+	Packages []LocalPackage `json:"packages"`
+}
+
+// LocalPackage is a vulnerable package version of a LocalDatabase and the patch that fixes it
+type LocalPackage struct {
+	Ecosystem  Ecosystem         `json:"ecosystem"`
+	Name       string            `json:"name"`
+	Version    string            `json:"version"`
+	Patch      rootio.PatchInfo  `json:"patch"`
+	PatchAlias *rootio.PatchInfo `json:"patch_alias,omitempty"`
+	CVEs       []rootio.CVE      `json:"cves,omitempty"`
+}
+
+// LoadLocalDatabase reads and checks a local vulnerability database
+func LoadLocalDatabase(path string) (*LocalDatabase, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read vulnerability database: %w", err)
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(content))
+	decoder.DisallowUnknownFields()
+	var db LocalDatabase
+	if err := decoder.Decode(&db); err != nil {
+		return nil, fmt.Errorf("failed to parse vulnerability database %s: %w", path, err)
+	}
+
+	for i, pkg := range db.Packages {
+		if pkg.Ecosystem == "" || pkg.Name == "" || pkg.Version == "" || pkg.Patch.Version == "" {
+			return nil, fmt.Errorf("invalid vulnerability database %s: package %d needs an ecosystem, name, version and patch version",
+				path, i)
+		}
+	}
+	return &db, nil
+}
+
+// LocalClient answers analysis requests from a local vulnerability database instead of calling the API
+type LocalClient struct {
+	ecosystem Ecosystem
+	packages  map[string]LocalPackage
+}
+
+// NewLocalClient creates a client that looks up an ecosystem's packages in db
+func NewLocalClient(db *LocalDatabase, ecosystem Ecosystem) *LocalClient {
+	packages := make(map[string]LocalPackage)
+	for _, pkg := range db.Packages {
+		if pkg.Ecosystem == ecosystem {
+			packages[pkg.Name+"@"+pkg.Version] = pkg
+		}
+	}
+	return &LocalClient{ecosystem: ecosystem, packages: packages}
+}
+
+// AnalyzePackages returns a patch for each package version listed in the database, shaped like
+// the API's response. Packages that aren't listed have no known vulnerabilities.
+func (c *LocalClient) AnalyzePackages(ctx context.Context, packages []rootio.Package) (*rootio.AnalyzePackagesResponse, error) {
+	response := &rootio.AnalyzePackagesResponse{}
+	for _, pkg := range packages {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		entry, ok := c.packages[pkg.Name+"@"+pkg.Version]
+		if !ok {
+			continue
+		}
+
+		patch := rootio.PackagePatch{
+			PackageName: pkg.Name,
+			Version:     pkg.Version,
+			Patch:       entry.Patch,
+			CVEs:        entry.CVEs,
+		}
+		if patch.Patch.Name == "" {
+			patch.Patch.Name = pkg.Name
+		}
+		if entry.PatchAlias != nil {
+			patch.PatchAlias = *entry.PatchAlias
+		}
+		for _, cve := range entry.CVEs {
+			patch.CVEIDs = append(patch.CVEIDs, cve.ID)
+			if SeverityRank(cve.Severity) > SeverityRank(patch.Severity) {
+				patch.Severity = cve.Severity
+			}
+		}
+		response.Patches = append(response.Patches, patch)
+	}
+	return response, nil
+}
@@ -0,0 +1,81 @@
+package common
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"rootio_patcher/pkg/rootio"
+)
+
+// writeDatabase writes a local vulnerability database and returns its path
+func writeDatabase(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "db.json")
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write database: %v", err)
+	}
+	return path
+}
+
+func TestLocalClient_AnalyzePackages(t *testing.T) {
+	db, err := LoadLocalDatabase(writeDatabase(t, `{"packages": [
+		{"ecosystem": "npm", "name": "lodash", "version": "4.17.20",
+		 "patch": {"version": "4.17.21"},
+		 "patch_alias": {"name": "@rootio/lodash", "version": "4.17.21-root.1"},
+		 "cves": [{"id": "CVE-2020-28500", "severity": "medium"}, {"id": "CVE-2021-23337", "severity": "high"}]},
+		{"ecosystem": "pypi", "name": "lodash", "version": "4.17.20", "patch": {"version": "9.9.9"}}
+	]}`))
+	if err != nil {
+		t.Fatalf("LoadLocalDatabase failed: %v", err)
+	}
+
+	client := NewLocalClient(db, EcosystemNpm)
+	response, err := client.AnalyzePackages(context.Background(), []rootio.Package{
+		{Name: "lodash", Version: "4.17.20"},
+		{Name: "lodash", Version: "4.17.21"},
+		{Name: "express", Version: "4.17.1"},
+	})
+	if err != nil {
+		t.Fatalf("AnalyzePackages failed: %v", err)
+	}
+
+	if len(response.Patches) != 1 {
+		t.Fatalf("Expected 1 patch, got %+v", response.Patches)
+	}
+	patch := response.Patches[0]
+	if patch.PackageName != "lodash" || patch.Version != "4.17.20" {
+		t.Errorf("Expected the patch for lodash 4.17.20, got %s %s", patch.PackageName, patch.Version)
+	}
+	if patch.Patch != (rootio.PatchInfo{Name: "lodash", Version: "4.17.21"}) {
+		t.Errorf("Expected the patch to default to the package's name, got %+v", patch.Patch)
+	}
+	if patch.PatchAlias != (rootio.PatchInfo{Name: "@rootio/lodash", Version: "4.17.21-root.1"}) {
+		t.Errorf("Unexpected alias %+v", patch.PatchAlias)
+	}
+	if strings.Join(patch.CVEIDs, ",") != "CVE-2020-28500,CVE-2021-23337" || patch.Severity != "high" {
+		t.Errorf("Expected both CVEs at high severity, got %v %q", patch.CVEIDs, patch.Severity)
+	}
+}
+
+func TestLoadLocalDatabase_Invalid(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		wantErr string
+	}{
+		{"not json", `packages:`, "failed to parse"},
+		{"unknown field", `{"packages": [], "vulns": []}`, "unknown field"},
+		{"missing patch version", `{"packages": [{"ecosystem": "npm", "name": "lodash", "version": "4.17.20", "patch": {}}]}`, "package 0 needs"},
+		{"missing ecosystem", `{"packages": [{"name": "lodash", "version": "4.17.20", "patch": {"version": "4.17.21"}}]}`, "package 0 needs"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := LoadLocalDatabase(writeDatabase(t, tt.content)); err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Expected an error containing %q, got: %v", tt.wantErr, err)
+			}
+		})
+	}
+}
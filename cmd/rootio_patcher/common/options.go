@@ -0,0 +1,404 @@
+package common
+
+import (
+	"io"
+	"os"
+	"time"
+
+	"rootio_patcher/pkg/rootio"
+)
+
+// Options holds settings shared by all remediation apps
+type Options struct {
+	// Backup writes a copy of each file before it is modified
+	Backup bool
+
+	// MinSeverity drops patches below this severity (none, low, medium, high, critical)
+	MinSeverity string
+
+	// ResolveParent loads parent POMs to resolve inherited versions (Maven only)
+	ResolveParent bool
+
+	// Recursive also remediates the module POMs listed under <modules>, with parent resolution
+	// (Maven only)
+	Recursive bool
+
+	// GroupPrefix only patches Maven coordinates whose groupId is this group or one of its
+	// subgroups (Maven only; all groups when empty)
+	GroupPrefix string
+
+	// Profiles are the Maven profiles read instead of the ones active by default (Maven only)
+	Profiles []string
+
+	// JournalPath records applied patches so they can be rolled back (pip only)
+	JournalPath string
+
+	// ConstraintsPath is a pip constraints file pinning each applied patch, so later installs
+	// with -c keep the patched versions (pip only; not written when empty)
+	ConstraintsPath string
+
+	// TargetDir is a directory of packages installed with pip install --target, or a venv's
+	// site-packages, patched instead of the interpreter's environment (pip only)
+	TargetDir string
+
+	// KeepGoing continues applying patches after a failure instead of stopping (pip only)
+	KeepGoing bool
+
+	// Plan collects the patches found by dry runs, to be written with --plan-out
+	Plan *Plan
+
+	// PlanEntry is the reviewed plan being applied, used in place of the API
+	PlanEntry *PlanEntry
+
+	// OfflineDB is the local vulnerability database packages are analyzed against instead of the API
+	OfflineDB *LocalDatabase
+
+	// Parallel is the number of patches applied concurrently; 0 or 1 applies them one at a time (pip only)
+	Parallel int
+
+	// NetrcCredentials passes index credentials to pip in a temporary netrc file instead of the
+	// index URL (pip only)
+	NetrcCredentials bool
+
+	// InstallDeps lets pip install the dependencies of patched packages instead of passing
+	// --no-deps (pip only)
+	InstallDeps bool
+
+	// ConstraintStyle is how patched versions are written to requirements files: exact (==),
+	// compatible (~=) or minimum (>=) (pip requirements only; exact when empty)
+	ConstraintStyle string
+
+	// AdditionalFiles are further requirements files remediated together with the main one
+	// (pip requirements only)
+	AdditionalFiles []string
+
+	// Only restricts patching to these package names or glob patterns
+	Only []string
+
+	// Exclude skips these package names or glob patterns
+	Exclude []string
+
+	// CVEs restricts patching to packages whose patch fixes one of these CVE IDs
+	CVEs []string
+
+	// SkipDev leaves development and test dependencies out of the analysis
+	SkipDev bool
+
+	// PackageJSONPath is the package.json to add overrides to (npm only, defaults to ./package.json)
+	PackageJSONPath string
+
+	// UpdateLockfile also rewrites patched versions in package-lock.json (npm only)
+	UpdateLockfile bool
+
+	// Registry is the registry Root.io's scoped packages are installed from, written as a scope
+	// registry to .npmrc or .yarnrc.yml (npm only; the default registry when empty)
+	Registry string
+
+	// StrictPeers skips patches whose override falls outside a dependent's peerDependencies
+	// range instead of warning about them (npm only)
+	StrictPeers bool
+
+	// UseAlias installs Root.io's aliased packages instead of the patched versions of the original
+	// packages (npm and Maven; nil keeps the default of aliases for npm and versions for Maven)
+	UseAlias *bool
+
+	// GoProxyURL redirects patched modules to Root.io's module proxy at this URL with replace
+	// directives instead of bumping required versions (Go only; disabled when empty)
+	GoProxyURL string
+
+	// Progress prints status lines while packages are collected and analyzed (silent when nil)
+	Progress *Progress
+
+	// Confirm asks before patches are applied (applies without asking when nil)
+	Confirm *Confirmer
+
+	// Out receives the human-readable report (stdout when nil)
+	Out io.Writer
+
+	// Verify analyzes the packages again after patching and fails if patches remain
+	Verify bool
+
+	// CacheDir stores analysis responses so unchanged package sets skip the API (disabled when empty)
+	CacheDir string
+
+	// CacheTTL is how long a cached analysis response is reused
+	CacheTTL time.Duration
+
+	// LockTimeout is how long to wait for another run to release a file before patching it
+	// (DefaultLockTimeout when 0)
+	LockTimeout time.Duration
+
+	// ClientOptions configure the Root.io API client built by NewApp (proxy CA, timeouts).
+	// The ecosystem is always set by the app.
+	ClientOptions []rootio.Option
+}
+
+// Option configures Options
+type Option func(*Options)
+
+// WithBackup enables writing a backup of each file before it is modified
+func WithBackup(backup bool) Option {
+	return func(o *Options) {
+		o.Backup = backup
+	}
+}
+
+// WithMinSeverity only keeps patches at or above the given severity
+func WithMinSeverity(severity string) Option {
+	return func(o *Options) {
+		o.MinSeverity = severity
+	}
+}
+
+// WithResolveParent enables parent POM resolution for Maven projects
+func WithResolveParent(resolve bool) Option {
+	return func(o *Options) {
+		o.ResolveParent = resolve
+	}
+}
+
+// WithRecursive remediates every module of a Maven multi-module build with a single analysis
+func WithRecursive(recursive bool) Option {
+	return func(o *Options) {
+		o.Recursive = recursive
+	}
+}
+
+// WithGroupPrefix only patches Maven dependencies in the given group and its subgroups
+func WithGroupPrefix(prefix string) Option {
+	return func(o *Options) {
+		o.GroupPrefix = prefix
+	}
+}
+
+// WithProfiles reads the named Maven profiles instead of the ones active by default
+func WithProfiles(ids []string) Option {
+	return func(o *Options) {
+		o.Profiles = ids
+	}
+}
+
+// WithJournal records applied patches to the journal at path
+func WithJournal(path string) Option {
+	return func(o *Options) {
+		o.JournalPath = path
+	}
+}
+
+// WithConstraints pins applied patches in the pip constraints file at path
+func WithConstraints(path string) Option {
+	return func(o *Options) {
+		o.ConstraintsPath = path
+	}
+}
+
+// WithTargetDir patches the packages installed in dir instead of the interpreter's environment
+func WithTargetDir(dir string) Option {
+	return func(o *Options) {
+		o.TargetDir = dir
+	}
+}
+
+// WithKeepGoing continues applying the remaining patches after one fails
+func WithKeepGoing(keepGoing bool) Option {
+	return func(o *Options) {
+		o.KeepGoing = keepGoing
+	}
+}
+
+// WithInstallDeps lets pip resolve the dependencies of patched packages from the Root.io index
+func WithInstallDeps(enabled bool) Option {
+	return func(o *Options) {
+		o.InstallDeps = enabled
+	}
+}
+
+// WithConstraintStyle writes patched versions to requirements files in the given style
+func WithConstraintStyle(style string) Option {
+	return func(o *Options) {
+		o.ConstraintStyle = style
+	}
+}
+
+// WithAdditionalFiles remediates further requirements files together with the main one
+func WithAdditionalFiles(files ...string) Option {
+	return func(o *Options) {
+		o.AdditionalFiles = files
+	}
+}
+
+// WithPlan records the patches found by dry runs in plan
+func WithPlan(plan *Plan) Option {
+	return func(o *Options) {
+		o.Plan = plan
+	}
+}
+
+// WithOfflineDB analyzes packages against a local vulnerability database instead of the API
+func WithOfflineDB(db *LocalDatabase) Option {
+	return func(o *Options) {
+		o.OfflineDB = db
+	}
+}
+
+// WithPlanEntry applies the patches of a reviewed plan entry instead of analyzing packages
+func WithPlanEntry(entry *PlanEntry) Option {
+	return func(o *Options) {
+		o.PlanEntry = entry
+	}
+}
+
+// WithParallel applies up to n patches concurrently
+func WithParallel(n int) Option {
+	return func(o *Options) {
+		o.Parallel = n
+	}
+}
+
+// WithNetrcCredentials keeps the API key out of pip's command line by passing it in a netrc file
+func WithNetrcCredentials(enabled bool) Option {
+	return func(o *Options) {
+		o.NetrcCredentials = enabled
+	}
+}
+
+// WithPackageFilter only patches packages matching only (if set) and not matching exclude
+func WithPackageFilter(only, exclude []string) Option {
+	return func(o *Options) {
+		o.Only = only
+		o.Exclude = exclude
+	}
+}
+
+// WithCVEFilter only patches packages whose patch fixes one of cves
+func WithCVEFilter(cves []string) Option {
+	return func(o *Options) {
+		o.CVEs = cves
+	}
+}
+
+// WithSkipDev leaves development and test dependencies out of the analysis
+func WithSkipDev(skipDev bool) Option {
+	return func(o *Options) {
+		o.SkipDev = skipDev
+	}
+}
+
+// WithPackageJSON targets a specific package.json instead of the one in the current directory
+func WithPackageJSON(path string) Option {
+	return func(o *Options) {
+		o.PackageJSONPath = path
+	}
+}
+
+// WithUpdateLockfile also rewrites patched versions in the lock file, not just package.json
+func WithUpdateLockfile(update bool) Option {
+	return func(o *Options) {
+		o.UpdateLockfile = update
+	}
+}
+
+// WithStrictPeers skips patches that would break a dependent's peerDependencies instead of warning
+func WithStrictPeers(strict bool) Option {
+	return func(o *Options) {
+		o.StrictPeers = strict
+	}
+}
+
+// WithRegistry installs the patched packages' scopes from registry instead of the default registry
+func WithRegistry(registry string) Option {
+	return func(o *Options) {
+		o.Registry = registry
+	}
+}
+
+// WithUseAlias selects Root.io's aliased packages (true) or the patched versions of the original
+// packages (false), overriding the ecosystem's default
+func WithUseAlias(enabled bool) Option {
+	return func(o *Options) {
+		o.UseAlias = &enabled
+	}
+}
+
+// WithGoProxy patches Go modules with replace directives resolved through the module proxy at url
+func WithGoProxy(url string) Option {
+	return func(o *Options) {
+		o.GoProxyURL = url
+	}
+}
+
+// WithProgress prints progress lines to p while packages are collected and analyzed
+func WithProgress(p *Progress) Option {
+	return func(o *Options) {
+		o.Progress = p
+	}
+}
+
+// WithConfirm asks c for confirmation before patches are applied
+func WithConfirm(c *Confirmer) Option {
+	return func(o *Options) {
+		o.Confirm = c
+	}
+}
+
+// WithOutput writes the human-readable report to w instead of stdout
+func WithOutput(w io.Writer) Option {
+	return func(o *Options) {
+		o.Out = w
+	}
+}
+
+// WithVerify re-runs the analysis after patches are applied to confirm nothing is left to patch
+func WithVerify(verify bool) Option {
+	return func(o *Options) {
+		o.Verify = verify
+	}
+}
+
+// WithCache reuses analysis responses stored in dir for up to ttl; an empty dir disables the cache
+func WithCache(dir string, ttl time.Duration) Option {
+	return func(o *Options) {
+		o.CacheDir = dir
+		o.CacheTTL = ttl
+	}
+}
+
+// WithLockTimeout waits up to timeout for another run to release a file before patching it
+func WithLockTimeout(timeout time.Duration) Option {
+	return func(o *Options) {
+		o.LockTimeout = timeout
+	}
+}
+
+// WithClientOptions configures the Root.io API client created by the app
+func WithClientOptions(opts ...rootio.Option) Option {
+	return func(o *Options) {
+		o.ClientOptions = append(o.ClientOptions, opts...)
+	}
+}
+
+// NewOptions builds Options from the given option functions
+func NewOptions(opts ...Option) Options {
+	var options Options
+	for _, opt := range opts {
+		opt(&options)
+	}
+	return options
+}
+
+// Output returns the writer the human-readable report goes to: Out, or stdout when unset
+func (o Options) Output() io.Writer {
+	if o.Out == nil {
+		return os.Stdout
+	}
+	return o.Out
+}
+
+// Aliased reports whether patches install Root.io's aliased packages, which is defaultAlias
+// unless WithUseAlias was given
+func (o Options) Aliased(defaultAlias bool) bool {
+	if o.UseAlias == nil {
+		return defaultAlias
+	}
+	return *o.UseAlias
+}
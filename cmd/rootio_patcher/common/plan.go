@@ -0,0 +1,155 @@
+package common
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"rootio_patcher/pkg/rootio"
+)
+
+// PlanVersion is the format version written to plan files
+const PlanVersion = 1
+
+// Plan is a reviewed set of patches written by a dry run with --plan-out and applied later
+// with "apply --plan", without contacting the API again
+type Plan struct {
+	Version   int         `json:"version"`
+	CreatedAt time.Time   `json:"created_at"`
+	Entries   []PlanEntry `json:"entries"`
+
+	// mu guards Entries while the apps of a scan add to them concurrently
+	mu sync.Mutex
+}
+
+// PlanEntry holds the patches planned for one ecosystem and dependency file
+type PlanEntry struct {
+	Ecosystem Ecosystem `json:"ecosystem"`
+	// File is the dependency file the patches apply to, empty for an installed environment
+	File string `json:"file,omitempty"`
+	// AdditionalFiles are further requirements files remediated together with File (pip only)
+	AdditionalFiles []string `json:"additional_files,omitempty"`
+	// UseAlias applies the aliased patches (pip, npm, Maven) or replace directives (Go)
+	UseAlias bool `json:"use_alias,omitempty"`
+	// Checksum identifies the analyzed package set, to detect changes since planning
+	Checksum string                `json:"checksum"`
+	Patches  []rootio.PackagePatch `json:"patches"`
+}
+
+// NewPlan creates an empty plan
+func NewPlan() *Plan {
+	return &Plan{Version: PlanVersion, CreatedAt: time.Now().UTC()}
+}
+
+// Add records the patches a dry run found for packages. It does nothing on a nil plan,
+// so apps can call it whether or not --plan-out was given.
+func (p *Plan) Add(ecosystem Ecosystem, file string, useAlias bool, packages []rootio.Package, patches []rootio.PackagePatch) {
+	p.AddFiles(ecosystem, []string{file}, useAlias, packages, patches)
+}
+
+// AddFiles records the patches a dry run found for packages declared across several
+// dependency files. The first file names the entry.
+func (p *Plan) AddFiles(ecosystem Ecosystem, files []string, useAlias bool, packages []rootio.Package, patches []rootio.PackagePatch) {
+	if p == nil {
+		return
+	}
+	entry := PlanEntry{
+		Ecosystem: ecosystem,
+		File:      files[0],
+		UseAlias:  useAlias,
+		Checksum:  PackageChecksum(packages),
+		Patches:   patches,
+	}
+	if len(files) > 1 {
+		entry.AdditionalFiles = files[1:]
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.Entries = append(p.Entries, entry)
+}
+
+// Write saves the plan to path as indented JSON, with its entries ordered by ecosystem and
+// file whichever order the apps finished in
+func (p *Plan) Write(path string) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	sort.SliceStable(p.Entries, func(i, j int) bool {
+		if p.Entries[i].Ecosystem != p.Entries[j].Ecosystem {
+			return p.Entries[i].Ecosystem < p.Entries[j].Ecosystem
+		}
+		return p.Entries[i].File < p.Entries[j].File
+	})
+	content, err := json.MarshalIndent(p, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode plan: %w", err)
+	}
+	if err := os.WriteFile(path, append(content, '\n'), 0644); err != nil {
+		return fmt.Errorf("failed to write plan: %w", err)
+	}
+	return nil
+}
+
+// ReadPlan loads a plan written by Plan.Write
+func ReadPlan(path string) (*Plan, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read plan: %w", err)
+	}
+
+	var plan Plan
+	if err := json.Unmarshal(content, &plan); err != nil {
+		return nil, fmt.Errorf("failed to parse plan %s: %w", path, err)
+	}
+	if plan.Version != PlanVersion {
+		return nil, fmt.Errorf("unsupported plan version %d in %s (expected %d)", plan.Version, path, PlanVersion)
+	}
+	return &plan, nil
+}
+
+// PackageChecksum returns a SHA-256 of the sorted name@version list, independent of package order
+func PackageChecksum(packages []rootio.Package) string {
+	keys := make([]string, len(packages))
+	for i, pkg := range packages {
+		keys[i] = pkg.Name + "@" + pkg.Version
+	}
+	sort.Strings(keys)
+
+	hash := sha256.New()
+	for _, key := range keys {
+		hash.Write([]byte(key + "\n"))
+	}
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// PlanClient answers analysis requests from a plan entry instead of calling the API
+type PlanClient struct {
+	entry PlanEntry
+	out   io.Writer
+}
+
+// NewPlanClient creates a client that returns the entry's planned patches, writing warnings to out
+func NewPlanClient(entry PlanEntry, out io.Writer) *PlanClient {
+	return &PlanClient{entry: entry, out: out}
+}
+
+// AnalyzePackages returns the planned patches, warning when packages differ from the planned package set
+func (c *PlanClient) AnalyzePackages(ctx context.Context, packages []rootio.Package) (*rootio.AnalyzePackagesResponse, error) {
+	if PackageChecksum(packages) != c.entry.Checksum {
+		location := c.entry.File
+		if location == "" {
+			location = "the environment"
+		}
+		fmt.Fprintf(c.out, "\nWarning: the packages in %s changed since the plan was made; applying the planned patches anyway\n", location)
+	}
+
+	patches := make([]rootio.PackagePatch, len(c.entry.Patches))
+	copy(patches, c.entry.Patches)
+	return &rootio.AnalyzePackagesResponse{Patches: patches}, nil
+}
@@ -0,0 +1,110 @@
+package common
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"rootio_patcher/pkg/rootio"
+)
+
+func TestPackageChecksum(t *testing.T) {
+	packages := []rootio.Package{{Name: "django", Version: "4.0.0"}, {Name: "flask", Version: "2.0.0"}}
+	reordered := []rootio.Package{{Name: "flask", Version: "2.0.0", Direct: true}, {Name: "django", Version: "4.0.0"}}
+	changed := []rootio.Package{{Name: "django", Version: "4.0.1"}, {Name: "flask", Version: "2.0.0"}}
+
+	if PackageChecksum(packages) != PackageChecksum(reordered) {
+		t.Error("Expected the checksum to ignore package order")
+	}
+	if PackageChecksum(packages) == PackageChecksum(changed) {
+		t.Error("Expected a version change to change the checksum")
+	}
+}
+
+func TestPlan_WriteAndRead(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "plan.json")
+	packages := []rootio.Package{{Name: "rack", Version: "2.2.4"}}
+	patches := []rootio.PackagePatch{
+		{PackageName: "rack", Version: "2.2.4", Patch: rootio.PatchInfo{Name: "rack", Version: "2.2.8.1"}},
+	}
+
+	plan := NewPlan()
+	plan.Add(EcosystemRubyGems, "Gemfile.lock", false, packages, patches)
+	if err := plan.Write(path); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+
+	read, err := ReadPlan(path)
+	if err != nil {
+		t.Fatalf("ReadPlan() error = %v", err)
+	}
+	if len(read.Entries) != 1 {
+		t.Fatalf("Expected 1 entry, got %d", len(read.Entries))
+	}
+	entry := read.Entries[0]
+	if entry.Ecosystem != EcosystemRubyGems || entry.File != "Gemfile.lock" {
+		t.Errorf("Unexpected entry: %+v", entry)
+	}
+	if entry.Checksum != PackageChecksum(packages) {
+		t.Errorf("Expected the checksum of the analyzed packages, got %s", entry.Checksum)
+	}
+	if len(entry.Patches) != 1 || entry.Patches[0].Patch.Version != "2.2.8.1" {
+		t.Errorf("Expected the planned patch, got %+v", entry.Patches)
+	}
+}
+
+func TestPlan_AddFiles(t *testing.T) {
+	plan := NewPlan()
+	plan.AddFiles(EcosystemPyPI, []string{"requirements.txt", "requirements-docs.txt"}, false, nil, nil)
+	plan.Add(EcosystemPyPI, "other.txt", false, nil, nil)
+
+	if entry := plan.Entries[0]; entry.File != "requirements.txt" ||
+		len(entry.AdditionalFiles) != 1 || entry.AdditionalFiles[0] != "requirements-docs.txt" {
+		t.Errorf("Expected requirements.txt with requirements-docs.txt, got %+v", entry)
+	}
+	if entry := plan.Entries[1]; entry.File != "other.txt" || entry.AdditionalFiles != nil {
+		t.Errorf("Expected other.txt alone, got %+v", entry)
+	}
+}
+
+func TestReadPlan_RejectsUnknownVersion(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "plan.json")
+	if err := os.WriteFile(path, []byte(`{"version": 99, "entries": []}`), 0644); err != nil {
+		t.Fatalf("Failed to write plan: %v", err)
+	}
+
+	if _, err := ReadPlan(path); err == nil {
+		t.Error("Expected an unknown plan version to be rejected")
+	}
+}
+
+func TestPlan_AddOnNilPlan(t *testing.T) {
+	var plan *Plan
+	plan.Add(EcosystemNpm, "package-lock.json", false, nil, nil)
+}
+
+func TestNewAPIClient_UsesPlanEntry(t *testing.T) {
+	packages := []rootio.Package{{Name: "lodash", Version: "4.17.20"}}
+	entry := &PlanEntry{
+		Ecosystem: EcosystemNpm,
+		Checksum:  PackageChecksum(packages),
+		Patches:   []rootio.PackagePatch{{PackageName: "lodash", Version: "4.17.20"}},
+	}
+
+	// The API URL is unreachable, so a response can only come from the plan
+	client := NewAPIClient(EcosystemNpm, "http://127.0.0.1:0", "test-key", nil, WithPlanEntry(entry))
+	response, err := client.AnalyzePackages(context.Background(), packages)
+	if err != nil {
+		t.Fatalf("AnalyzePackages() error = %v", err)
+	}
+	if len(response.Patches) != 1 || response.Patches[0].PackageName != "lodash" {
+		t.Errorf("Expected the planned patch, got %+v", response.Patches)
+	}
+
+	// Filtering the response must not change the plan
+	response.Patches[0].PackageName = "changed"
+	if entry.Patches[0].PackageName != "lodash" {
+		t.Error("Expected the plan's patches to be copied")
+	}
+}
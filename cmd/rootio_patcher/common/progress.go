@@ -0,0 +1,36 @@
+package common
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sync"
+)
+
+// Progress prints status lines for long-running steps, such as collecting a large environment or
+// analyzing thousands of packages. A nil *Progress prints nothing, which is the default.
+type Progress struct {
+	mu  sync.Mutex
+	out io.Writer
+}
+
+// NewProgress creates a Progress writing to out
+func NewProgress(out io.Writer) *Progress {
+	return &Progress{out: out}
+}
+
+// Printf writes a progress line
+func (p *Progress) Printf(format string, args ...any) {
+	if p == nil {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	fmt.Fprintf(p.out, format+"\n", args...)
+}
+
+// IsTerminal reports whether f is a terminal rather than a pipe or file
+func IsTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
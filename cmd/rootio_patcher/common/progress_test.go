@@ -0,0 +1,18 @@
+package common
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestProgress_Printf(t *testing.T) {
+	var buf bytes.Buffer
+	NewProgress(&buf).Printf("Analyzing %d packages...", 3)
+	if buf.String() != "Analyzing 3 packages...\n" {
+		t.Errorf("Expected a progress line, got %q", buf.String())
+	}
+
+	// Progress is off by default
+	var progress *Progress
+	progress.Printf("Analyzing %d packages...", 3)
+}
@@ -0,0 +1,19 @@
+package common
+
+import (
+	"regexp"
+	"strings"
+)
+
+// credentialsPattern matches the user:password@ part of a URL, e.g. https://root:<key>@pkg.root.io
+var credentialsPattern = regexp.MustCompile(`://[^/\s:@]+:[^/\s@]+@`)
+
+// Redact replaces every occurrence of secret, and any credentials embedded in a URL, with ***
+// so command output and errors can be logged without leaking the API key
+func Redact(text, secret string) string {
+	text = credentialsPattern.ReplaceAllString(text, "://***@")
+	if secret != "" {
+		text = strings.ReplaceAll(text, secret, "***")
+	}
+	return text
+}
@@ -2,68 +2,403 @@ package common
 
 import (
 	"fmt"
+	"io"
 	"log/slog"
 	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"unicode/utf8"
 
 	"rootio_patcher/pkg/rootio"
 )
 
-// Reporter handles terminal output and reporting
+// Reporter handles terminal output and reporting for a single ecosystem
 type Reporter struct {
-	logger *slog.Logger
-	pkgURL string
+	logger    *slog.Logger
+	ecosystem Ecosystem
+	pkgURL    string
+	out       io.Writer
+	width     int
+
+	// packageManager is npm, yarn or pnpm (npm only)
+	packageManager string
+	// file is the build file that would be modified (Maven, Go and RubyGems only)
+	file string
+	// buildCommand rebuilds the project after patching (Maven, Go and RubyGems only)
+	buildCommand string
+	// installDeps means pip installs the patched packages' dependencies (pip only)
+	installDeps bool
+}
+
+// ReporterOption configures a Reporter
+type ReporterOption func(*Reporter)
+
+// WithWriter sends reporter output to out instead of stdout
+func WithWriter(out io.Writer) ReporterOption {
+	return func(r *Reporter) {
+		r.out = out
+	}
+}
+
+// WithWidth sets the line width the patch table is fitted to (defaults to $COLUMNS, then 120)
+func WithWidth(width int) ReporterOption {
+	return func(r *Reporter) {
+		r.width = width
+	}
+}
+
+// WithPackageManager sets the npm package manager used to pick the override field
+func WithPackageManager(packageManager string) ReporterOption {
+	return func(r *Reporter) {
+		r.packageManager = packageManager
+	}
+}
+
+// WithBuildFile sets the build file and the command that rebuilds it after patching
+func WithBuildFile(file, buildCommand string) ReporterOption {
+	return func(r *Reporter) {
+		r.file = file
+		r.buildCommand = buildCommand
+	}
+}
+
+// WithPipDependencies shows pip install commands without --no-deps, as used with --install-deps
+func WithPipDependencies(installDeps bool) ReporterOption {
+	return func(r *Reporter) {
+		r.installDeps = installDeps
+	}
 }
 
-// NewReporter creates a new reporter
+// NewReporter creates a new pip reporter writing to stdout
 func NewReporter(pkgURL string, logger *slog.Logger) *Reporter {
-	return &Reporter{
-		logger: logger,
-		pkgURL: pkgURL,
+	return NewEcosystemReporter(EcosystemPyPI, pkgURL, logger)
+}
+
+// NewReporterWithWriter creates a new pip reporter writing to out (for testing)
+func NewReporterWithWriter(pkgURL string, logger *slog.Logger, out io.Writer) *Reporter {
+	return NewEcosystemReporter(EcosystemPyPI, pkgURL, logger, WithWriter(out))
+}
+
+// NewEcosystemReporter creates a new reporter that renders commands for the given ecosystem
+func NewEcosystemReporter(ecosystem Ecosystem, pkgURL string, logger *slog.Logger, opts ...ReporterOption) *Reporter {
+	r := &Reporter{
+		logger:    logger,
+		ecosystem: ecosystem,
+		pkgURL:    pkgURL,
+		out:       os.Stdout,
+		width:     TerminalWidth(),
+	}
+	for _, opt := range opts {
+		opt(r)
+	}
+	return r
+}
+
+// OverrideField returns the package.json field that holds overrides for an npm package manager.
+// Nested fields are dot-separated (pnpm keeps overrides under "pnpm.overrides").
+func OverrideField(packageManager string) string {
+	switch packageManager {
+	case "yarn":
+		return "resolutions"
+	case "pnpm":
+		return "pnpm.overrides"
+	default:
+		return "overrides"
+	}
+}
+
+// ReportSkipped lists packages that won't be patched and why
+func (r *Reporter) ReportSkipped(skipped []rootio.SkippedPackage) {
+	if len(skipped) == 0 {
+		return
+	}
+	WriteSkipped(r.out, skipped)
+}
+
+// WriteSkipped writes each skipped package and the reason it was skipped
+func WriteSkipped(w io.Writer, skipped []rootio.SkippedPackage) {
+	if len(skipped) == 0 {
+		return
+	}
+
+	fmt.Fprintf(w, "\nSkipped %d packages:\n", len(skipped))
+	for _, s := range skipped {
+		fmt.Fprintf(w, "  - %s: %s\n", s.PackageName, s.Reason)
+	}
+}
+
+// WriteCVEMatches writes the packages whose patches fix each CVE selected with --cve, and
+// the CVEs no patch fixes
+func WriteCVEMatches(w io.Writer, patches []rootio.PackagePatch, cves []string) {
+	if len(cves) == 0 {
+		return
+	}
+
+	fmt.Fprintln(w, "\nPackages fixing the selected CVEs:")
+	for _, cve := range cves {
+		var packages []string
+		for _, patch := range patches {
+			if len(MatchedCVEs(patch, []string{cve})) > 0 {
+				packages = append(packages, patch.PackageName+" "+patch.Version)
+			}
+		}
+		if len(packages) == 0 {
+			fmt.Fprintf(w, "  - %s: no vulnerable packages found\n", cve)
+			continue
+		}
+		fmt.Fprintf(w, "  - %s: %s\n", cve, strings.Join(packages, ", "))
 	}
 }
 
-// ReportDryRun shows what would be done in dry-run mode
+// defaultWidth is the line width used when $COLUMNS isn't set
+const defaultWidth = 120
+
+// minTitleWidth keeps CVE titles readable on narrow terminals; longer lines wrap instead
+const minTitleWidth = 20
+
+// TerminalWidth returns the width from $COLUMNS, or defaultWidth
+func TerminalWidth() int {
+	if columns, err := strconv.Atoi(os.Getenv("COLUMNS")); err == nil && columns > 0 {
+		return columns
+	}
+	return defaultWidth
+}
+
+// WritePatchTable writes one row per fixed CVE with the package, its current → patched version,
+// and the CVE's severity, CVSS score and title. Columns are sized to their longest cell and
+// titles are truncated to fit width. useAlias reports the aliased patch version.
+func WritePatchTable(w io.Writer, patches []rootio.PackagePatch, useAlias bool, width int) {
+	header := []string{"PACKAGE", "VERSION", "CVE", "SEVERITY", "CVSS", "TITLE"}
+	var rows [][]string
+	for _, patch := range patches {
+		target := PatchTarget(patch, useAlias)
+		pkg := patch.PackageName
+		version := fmt.Sprintf("%s → %s", patch.Version, target.Version)
+
+		cves := patch.CVEs
+		if len(cves) == 0 {
+			for _, id := range patch.CVEIDs {
+				cves = append(cves, rootio.CVE{ID: id})
+			}
+		}
+		if len(cves) == 0 {
+			severity := strings.ToLower(patch.Severity)
+			if severity == "" {
+				severity = "-"
+			}
+			rows = append(rows, []string{pkg, version, "-", severity, "-", ""})
+			continue
+		}
+
+		for _, cve := range cves {
+			severity := strings.ToLower(cve.Severity)
+			if severity == "" {
+				severity = "unknown"
+			}
+			score := "-"
+			if cve.CVSSScore > 0 {
+				score = strconv.FormatFloat(cve.CVSSScore, 'f', 1, 64)
+			}
+			rows = append(rows, []string{pkg, version, cve.ID, severity, score, cve.Title})
+			// Only the first row of a package repeats its name and versions
+			pkg, version = "", ""
+		}
+	}
+
+	widths := make([]int, len(header)-1)
+	for _, row := range append([][]string{header}, rows...) {
+		for i := range widths {
+			widths[i] = max(widths[i], utf8.RuneCountInString(row[i]))
+		}
+	}
+	titleWidth := width
+	for _, columnWidth := range widths {
+		titleWidth -= columnWidth + 2
+	}
+	titleWidth = max(titleWidth, minTitleWidth)
+
+	for _, row := range append([][]string{header}, rows...) {
+		var line strings.Builder
+		for i, cell := range row[:len(row)-1] {
+			line.WriteString(cell)
+			line.WriteString(strings.Repeat(" ", widths[i]-utf8.RuneCountInString(cell)+2))
+		}
+		line.WriteString(truncate(row[len(row)-1], titleWidth))
+		fmt.Fprintln(w, strings.TrimRight(line.String(), " "))
+	}
+}
+
+// truncate shortens s to at most width runes, marking the cut with an ellipsis
+func truncate(s string, width int) string {
+	if utf8.RuneCountInString(s) <= width {
+		return s
+	}
+	runes := []rune(s)
+	return string(runes[:width-1]) + "…"
+}
+
+// ReportDryRun shows what would be done in dry-run mode.
+// useAlias reports the aliased packages: pip installs, npm overrides, Maven coordinates or Go replace directives.
 func (r *Reporter) ReportDryRun(patches []rootio.PackagePatch, useAlias bool) {
-	fmt.Println("\n=== DRY-RUN MODE ===")
-	fmt.Println("The following operations would be performed:")
-	fmt.Println()
+	r.ReportDryRunWithDiff(patches, useAlias, "")
+}
+
+// ReportDryRunWithDiff shows what would be done in dry-run mode, followed by the unified diff
+// of the file that would be modified (npm, Maven, Go, RubyGems and NuGet only; pip changes no files)
+func (r *Reporter) ReportDryRunWithDiff(patches []rootio.PackagePatch, useAlias bool, diff string) {
+	switch r.ecosystem {
+	case EcosystemNpm:
+		r.reportNpmDryRun(patches, useAlias, diff)
+	case EcosystemMaven, EcosystemGo, EcosystemRubyGems, EcosystemNuGet:
+		r.reportBuildFileDryRun(patches, useAlias, diff)
+	default:
+		r.reportPipDryRun(patches, useAlias)
+	}
+}
+
+// reportDiff prints the proposed changes to a file
+func (r *Reporter) reportDiff(diff string) {
+	if diff == "" {
+		return
+	}
+	fmt.Fprintln(r.out, "Proposed changes:")
+	fmt.Fprintln(r.out)
+	fmt.Fprintln(r.out, diff)
+}
+
+// ReportNextSteps tells the user what to do after patches were written to the build file
+func (r *Reporter) ReportNextSteps(count int) {
+	switch r.ecosystem {
+	case EcosystemNpm:
+		fmt.Fprintf(r.out, "\n✓ Successfully updated package.json with %d overrides!\n", count)
+		fmt.Fprintln(r.out, "\nNext steps:")
+		fmt.Fprintln(r.out, "  1. Review the changes in package.json")
+		fmt.Fprintf(r.out, "  2. Run: %s install\n", r.packageManager)
+		fmt.Fprintln(r.out, "  3. Test your application")
+	case EcosystemMaven, EcosystemGo, EcosystemRubyGems, EcosystemNuGet:
+		fmt.Fprintf(r.out, "\n✓ Successfully updated %s with %d patches!\n", r.file, count)
+		fmt.Fprintln(r.out, "\nNext steps:")
+		fmt.Fprintf(r.out, "  1. Review the changes in %s\n", r.file)
+		fmt.Fprintf(r.out, "  2. Run: %s\n", r.buildCommand)
+		fmt.Fprintln(r.out, "  3. Test your application")
+	}
+}
+
+// ReportFixedCVEs lists the CVEs remediated by the applied patches
+func (r *Reporter) ReportFixedCVEs(cves []string) {
+	if len(cves) == 0 {
+		return
+	}
+	fmt.Fprintf(r.out, "\nFixed %d CVEs:\n", len(cves))
+	for _, id := range cves {
+		fmt.Fprintf(r.out, "  %s\n", id)
+	}
+}
+
+// reportNpmDryRun lists the overrides that would be added to package.json
+func (r *Reporter) reportNpmDryRun(patches []rootio.PackagePatch, useAlias bool, diff string) {
+	fmt.Fprintln(r.out, "\n=== DRY-RUN MODE ===")
+	WritePatchTable(r.out, patches, useAlias, r.width)
+	fmt.Fprintf(r.out, "\nThe following overrides would be added to package.json:\n\n")
+
+	for i, patch := range patches {
+		fmt.Fprintf(r.out, "%d. Package: %s\n", i+1, patch.PackageName)
+		fmt.Fprintf(r.out, "   Current version: %s\n", patch.Version)
+		if target := PatchTarget(patch, useAlias); ReplacesPackage(patch, target) {
+			fmt.Fprintf(r.out, "   Aliased package: npm:%s@%s\n", target.Name, target.Version)
+		} else {
+			fmt.Fprintf(r.out, "   Patched version: %s\n", target.Version)
+		}
+		fmt.Fprintln(r.out)
+	}
+
+	fmt.Fprintf(r.out, "These will be added to package.json under \"%s\" field\n\n", OverrideField(r.packageManager))
+	r.reportDiff(diff)
+
+	fmt.Fprintln(r.out, "To apply these patches, run with --dry-run=false")
+	fmt.Fprintf(r.out, "Then run: %s install\n", r.packageManager)
+}
+
+// commandName returns the rootio_patcher command that remediates the ecosystem
+func commandName(ecosystem Ecosystem) string {
+	if ecosystem == EcosystemRubyGems {
+		return "gem"
+	}
+	return string(ecosystem)
+}
+
+// reportBuildFileDryRun lists the version bumps that would be made to the build file.
+// useAlias shows the replacement packages instead (Maven aliases, Go replace directives).
+func (r *Reporter) reportBuildFileDryRun(patches []rootio.PackagePatch, useAlias bool, diff string) {
+	fmt.Fprintln(r.out, "\n=== DRY-RUN MODE ===")
+	WritePatchTable(r.out, patches, useAlias, r.width)
+	fmt.Fprintf(r.out, "\nThe following packages in %s would be updated:\n\n", r.file)
+
+	for i, patch := range patches {
+		fmt.Fprintf(r.out, "%d. Package: %s\n", i+1, patch.PackageName)
+		fmt.Fprintf(r.out, "   Current version: %s\n", patch.Version)
+		if target := PatchTarget(patch, useAlias); ReplacesPackage(patch, target) {
+			fmt.Fprintf(r.out, "   Replaced by: %s@%s\n", target.Name, target.Version)
+		} else {
+			fmt.Fprintf(r.out, "   Patched version: %s\n", target.Version)
+		}
+		fmt.Fprintln(r.out)
+	}
+	r.reportDiff(diff)
+
+	fmt.Fprintln(r.out, "To apply these patches:")
+	fmt.Fprintf(r.out, "  1. Run: rootio_patcher %s remediate --file %s --dry-run=false\n", commandName(r.ecosystem), r.file)
+	fmt.Fprintf(r.out, "  2. Then run: %s\n", r.buildCommand)
+}
+
+// reportPipDryRun lists the pip commands that would replace each package
+func (r *Reporter) reportPipDryRun(patches []rootio.PackagePatch, useAlias bool) {
+	fmt.Fprintln(r.out, "\n=== DRY-RUN MODE ===")
+	WritePatchTable(r.out, patches, useAlias, r.width)
+	fmt.Fprintln(r.out, "\nThe following operations would be performed:")
+	fmt.Fprintln(r.out)
 
 	// Parse pkgURL to get scheme and host
 	parsedURL, err := url.Parse(r.pkgURL)
 	var indexURLTemplate string
 	if err != nil {
 		// Fallback if parsing fails
-		indexURLTemplate = fmt.Sprintf("%s/pypi/simple/", r.pkgURL)
+		indexURLTemplate = fmt.Sprintf("%s/pypi/simple/", Redact(r.pkgURL, ""))
 	} else {
 		indexURLTemplate = fmt.Sprintf("%s://root:<your_api_key>@%s/pypi/simple/",
 			parsedURL.Scheme, parsedURL.Host)
 	}
 
+	installArgs := "--no-deps "
+	if r.installDeps {
+		installArgs = ""
+	}
+
 	for i, patch := range patches {
-		// Select patch based on useAlias flag
-		var patchInfo rootio.PatchInfo
-		var patchType string
-		if useAlias {
-			patchInfo = patch.PatchAlias
+		// Select patch based on useAlias flag, falling back to the direct patch without an alias
+		patchInfo := PatchTarget(patch, useAlias)
+		patchType := "Non-Aliased"
+		if ReplacesPackage(patch, patchInfo) {
 			patchType = "Aliased"
-		} else {
-			patchInfo = patch.Patch
-			patchType = "Non-Aliased"
 		}
 
-		fmt.Printf("%d. Package: %s @ %s\n", i+1, patch.PackageName, patch.Version)
-		fmt.Printf("   Patch (%s): %s @ %s\n", patchType, patchInfo.Name, patchInfo.Version)
-		fmt.Printf("   CVEs Fixed: %v\n", patch.CVEIDs)
-		fmt.Printf("   Commands:\n")
-		fmt.Printf("     pip uninstall -y %s\n", patch.PackageName)
-		fmt.Printf("     pip install --no-deps --index-url %s %s==%s\n\n",
-			indexURLTemplate, patchInfo.Name, patchInfo.Version)
+		fmt.Fprintf(r.out, "%d. Package: %s @ %s\n", i+1, patch.PackageName, patch.Version)
+		fmt.Fprintf(r.out, "   Patch (%s): %s @ %s\n", patchType, patchInfo.Name, patchInfo.Version)
+		fmt.Fprintf(r.out, "   Commands:\n")
+		fmt.Fprintf(r.out, "     pip uninstall -y %s\n", patch.PackageName)
+		fmt.Fprintf(r.out, "     pip install %s--index-url %s %s==%s\n\n",
+			installArgs, indexURLTemplate, patchInfo.Name, patchInfo.Version)
+	}
+
+	if r.installDeps {
+		fmt.Fprintln(r.out, "Dependencies of the patched packages will also be installed from the Root.io index")
 	}
 
-	fmt.Println("To apply these patches, run with --dry-run=false")
+	fmt.Fprintln(r.out, "To apply these patches, run with --dry-run=false")
 	if useAlias {
-		fmt.Println("To use original package names instead of aliases, add --use-alias=false")
+		fmt.Fprintln(r.out, "To use original package names instead of aliases, add --use-alias=false")
 	} else {
-		fmt.Println("To use aliased package names (recommended), add --use-alias=true")
+		fmt.Fprintln(r.out, "To use aliased package names (recommended), add --use-alias=true")
 	}
 }
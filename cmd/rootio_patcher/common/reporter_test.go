@@ -0,0 +1,293 @@
+package common
+
+import (
+	"bytes"
+	"log/slog"
+	"os"
+	"strings"
+	"testing"
+
+	"rootio_patcher/pkg/rootio"
+)
+
+func TestReporter_ReportSkipped(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+	reporter := NewReporterWithWriter("https://pkg.root.io", logger, &buf)
+
+	reporter.ReportSkipped([]rootio.SkippedPackage{
+		{PackageName: "numpy", Reason: "no patched version available"},
+		{PackageName: "flask", Reason: "skipped due to severity (low is below high)"},
+	})
+
+	output := buf.String()
+	for _, expected := range []string{
+		"Skipped 2 packages:",
+		"  - numpy: no patched version available",
+		"  - flask: skipped due to severity (low is below high)",
+	} {
+		if !strings.Contains(output, expected) {
+			t.Errorf("Expected output to contain %q, got:\n%s", expected, output)
+		}
+	}
+}
+
+func TestReporter_ReportSkipped_Empty(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+	reporter := NewReporterWithWriter("https://pkg.root.io", logger, &buf)
+
+	reporter.ReportSkipped(nil)
+
+	if buf.Len() != 0 {
+		t.Errorf("Expected no output when nothing was skipped, got:\n%s", buf.String())
+	}
+}
+
+func TestOverrideField(t *testing.T) {
+	tests := []struct {
+		packageManager string
+		expected       string
+	}{
+		{"npm", "overrides"},
+		{"yarn", "resolutions"},
+		{"pnpm", "pnpm.overrides"},
+		{"", "overrides"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.packageManager, func(t *testing.T) {
+			if field := OverrideField(tt.packageManager); field != tt.expected {
+				t.Errorf("OverrideField(%q) = %q, expected %q", tt.packageManager, field, tt.expected)
+			}
+		})
+	}
+}
+
+func TestReporter_ReportDryRun_Npm(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+	reporter := NewEcosystemReporter(EcosystemNpm, "https://pkg.root.io", logger,
+		WithPackageManager("yarn"), WithWriter(&buf))
+
+	reporter.ReportDryRun([]rootio.PackagePatch{
+		{
+			PackageName: "lodash",
+			Version:     "4.17.20",
+			PatchAlias:  rootio.PatchInfo{Name: "@rootio/lodash", Version: "4.17.21"},
+			CVEIDs:      []string{"CVE-2021-23337"},
+		},
+	}, true)
+
+	output := buf.String()
+	for _, expected := range []string{
+		"1. Package: lodash",
+		"Aliased package: npm:@rootio/lodash@4.17.21",
+		"lodash   4.17.20 → 4.17.21  CVE-2021-23337  unknown",
+		`under "resolutions" field`,
+		"Then run: yarn install",
+	} {
+		if !strings.Contains(output, expected) {
+			t.Errorf("Expected output to contain %q, got:\n%s", expected, output)
+		}
+	}
+}
+
+func TestReporter_ReportDryRun_PipDependencies(t *testing.T) {
+	patches := []rootio.PackagePatch{
+		{
+			PackageName: "django",
+			Version:     "4.0.0",
+			PatchAlias:  rootio.PatchInfo{Name: "rootio-django", Version: "4.0.0+root.io.1"},
+		},
+	}
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+
+	var buf bytes.Buffer
+	NewEcosystemReporter(EcosystemPyPI, "https://pkg.root.io", logger, WithWriter(&buf)).ReportDryRun(patches, true)
+	if !strings.Contains(buf.String(), "pip install --no-deps --index-url") {
+		t.Errorf("Expected --no-deps by default, got:\n%s", buf.String())
+	}
+
+	buf.Reset()
+	NewEcosystemReporter(EcosystemPyPI, "https://pkg.root.io", logger, WithWriter(&buf), WithPipDependencies(true)).
+		ReportDryRun(patches, true)
+	output := buf.String()
+	if strings.Contains(output, "--no-deps") {
+		t.Errorf("Expected no --no-deps when installing dependencies, got:\n%s", output)
+	}
+	if !strings.Contains(output, "Dependencies of the patched packages will also be installed") {
+		t.Errorf("Expected a note about dependency installs, got:\n%s", output)
+	}
+}
+
+func TestReporter_ReportDryRun_Maven(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+	reporter := NewEcosystemReporter(EcosystemMaven, "https://pkg.root.io", logger,
+		WithBuildFile("build.gradle", "./gradlew build"), WithWriter(&buf))
+
+	reporter.ReportDryRun([]rootio.PackagePatch{
+		{
+			PackageName: "org.apache.logging.log4j:log4j-core",
+			Version:     "2.14.1",
+			Patch:       rootio.PatchInfo{Name: "org.apache.logging.log4j:log4j-core", Version: "2.17.1"},
+		},
+	}, false)
+
+	output := buf.String()
+	for _, expected := range []string{
+		"The following packages in build.gradle would be updated:",
+		"Current version: 2.14.1",
+		"Patched version: 2.17.1",
+		"rootio_patcher maven remediate --file build.gradle --dry-run=false",
+		"Then run: ./gradlew build",
+	} {
+		if !strings.Contains(output, expected) {
+			t.Errorf("Expected output to contain %q, got:\n%s", expected, output)
+		}
+	}
+	if strings.Contains(output, "pip install") {
+		t.Errorf("Expected no pip commands in Maven output, got:\n%s", output)
+	}
+}
+
+func TestReporter_ReportDryRunWithDiff(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+	reporter := NewEcosystemReporter(EcosystemMaven, "https://pkg.root.io", logger,
+		WithBuildFile("pom.xml", "mvn clean install"), WithWriter(&buf))
+
+	diff := UnifiedDiff("pom.xml", "<version>2.14.1</version>\n", "<version>2.17.1</version>\n")
+	reporter.ReportDryRunWithDiff([]rootio.PackagePatch{
+		{
+			PackageName: "org.apache.logging.log4j:log4j-core",
+			Version:     "2.14.1",
+			Patch:       rootio.PatchInfo{Name: "org.apache.logging.log4j:log4j-core", Version: "2.17.1"},
+		},
+	}, false, diff)
+
+	output := buf.String()
+	diffAt := strings.Index(output, "Proposed changes:")
+	if diffAt < 0 || !strings.Contains(output[diffAt:], "-<version>2.14.1</version>\n+<version>2.17.1</version>") {
+		t.Fatalf("Expected the diff after the patch list, got:\n%s", output)
+	}
+	if applyAt := strings.Index(output, "To apply these patches:"); applyAt < diffAt {
+		t.Errorf("Expected apply instructions after the diff, got:\n%s", output)
+	}
+}
+
+func TestWriteCVEMatches(t *testing.T) {
+	patches := []rootio.PackagePatch{
+		{PackageName: "lodash", Version: "4.17.20", CVEIDs: []string{"CVE-2021-23337", "CVE-2020-28500"}},
+		{PackageName: "lodash-es", Version: "4.17.20", CVEIDs: []string{"CVE-2021-23337"}},
+	}
+
+	var buf bytes.Buffer
+	WriteCVEMatches(&buf, patches, []string{"CVE-2021-23337", "CVE-2022-0001"})
+	expected := "\nPackages fixing the selected CVEs:\n" +
+		"  - CVE-2021-23337: lodash 4.17.20, lodash-es 4.17.20\n" +
+		"  - CVE-2022-0001: no vulnerable packages found\n"
+	if buf.String() != expected {
+		t.Errorf("Expected:\n%s\ngot:\n%s", expected, buf.String())
+	}
+
+	buf.Reset()
+	WriteCVEMatches(&buf, patches, nil)
+	if buf.Len() != 0 {
+		t.Errorf("Expected no output without --cve, got %q", buf.String())
+	}
+}
+
+func TestWritePatchTable(t *testing.T) {
+	patches := []rootio.PackagePatch{
+		{
+			PackageName: "org.apache.logging.log4j:log4j-core",
+			Version:     "2.14.1",
+			Patch:       rootio.PatchInfo{Name: "org.apache.logging.log4j:log4j-core", Version: "2.17.1"},
+			CVEs: []rootio.CVE{
+				{ID: "CVE-2021-44228", Severity: "CRITICAL", CVSSScore: 10, Title: "Log4Shell: remote code execution via JNDI lookups in log messages"},
+				{ID: "CVE-2021-45046", Severity: "high", CVSSScore: 9},
+			},
+		},
+		{
+			PackageName: "junit:junit",
+			Version:     "4.12",
+			Patch:       rootio.PatchInfo{Name: "junit:junit", Version: "4.13.2"},
+			CVEIDs:      []string{"CVE-2020-15250"},
+		},
+	}
+
+	var buf bytes.Buffer
+	WritePatchTable(&buf, patches, false, 100)
+	lines := strings.Split(strings.TrimRight(buf.String(), "\n"), "\n")
+
+	expected := []string{
+		"PACKAGE                              VERSION          CVE             SEVERITY  CVSS  TITLE",
+		"org.apache.logging.log4j:log4j-core  2.14.1 → 2.17.1  CVE-2021-44228  critical  10.0  Log4Shell: remote c…",
+		"                                                      CVE-2021-45046  high      9.0",
+		"junit:junit                          4.12 → 4.13.2    CVE-2020-15250  unknown   -",
+	}
+	if len(lines) != len(expected) {
+		t.Fatalf("Expected %d lines, got %d:\n%s", len(expected), len(lines), buf.String())
+	}
+	for i := range expected {
+		if lines[i] != expected[i] {
+			t.Errorf("Line %d:\nexpected %q\ngot      %q", i, expected[i], lines[i])
+		}
+	}
+}
+
+func TestWritePatchTable_NarrowWidth(t *testing.T) {
+	patches := []rootio.PackagePatch{{
+		PackageName: "lodash",
+		Version:     "4.17.20",
+		PatchAlias:  rootio.PatchInfo{Name: "@rootio/lodash", Version: "4.17.21"},
+		CVEs:        []rootio.CVE{{ID: "CVE-2021-23337", Severity: "high", Title: strings.Repeat("x", 80)}},
+	}}
+
+	var buf bytes.Buffer
+	WritePatchTable(&buf, patches, true, 40)
+
+	// Titles keep a minimum width instead of disappearing on narrow terminals
+	if !strings.Contains(buf.String(), strings.Repeat("x", minTitleWidth-1)+"…") {
+		t.Errorf("Expected title truncated to %d characters, got:\n%s", minTitleWidth, buf.String())
+	}
+	if !strings.Contains(buf.String(), "4.17.20 → 4.17.21") {
+		t.Errorf("Expected aliased patch version, got:\n%s", buf.String())
+	}
+}
+
+func TestReporter_ReportNextSteps(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+	reporter := NewEcosystemReporter(EcosystemNpm, "https://pkg.root.io", logger,
+		WithPackageManager("pnpm"), WithWriter(&buf))
+
+	reporter.ReportNextSteps(3)
+
+	output := buf.String()
+	if !strings.Contains(output, "Successfully updated package.json with 3 overrides!") ||
+		!strings.Contains(output, "Run: pnpm install") {
+		t.Errorf("Unexpected next steps output:\n%s", output)
+	}
+}
+
+func TestReporter_ReportFixedCVEs(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+	reporter := NewReporterWithWriter("https://pkg.root.io", logger, &buf)
+
+	reporter.ReportFixedCVEs([]string{"CVE-2020-8203", "CVE-2021-23337"})
+
+	output := buf.String()
+	if !strings.Contains(output, "Fixed 2 CVEs:\n  CVE-2020-8203\n  CVE-2021-23337\n") {
+		t.Errorf("Unexpected fixed CVEs output:\n%s", output)
+	}
+
+	buf.Reset()
+	reporter.ReportFixedCVEs(nil)
+	if buf.Len() != 0 {
+		t.Errorf("Expected no output without fixed CVEs, got:\n%s", buf.String())
+	}
+}
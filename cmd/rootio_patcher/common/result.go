@@ -0,0 +1,176 @@
+package common
+
+import (
+	"context"
+	"slices"
+
+	"rootio_patcher/pkg/rootio"
+)
+
+// PatchStatus describes what happened to an available patch
+type PatchStatus string
+
+const (
+	PatchStatusDryRun     PatchStatus = "dry_run"
+	PatchStatusPending    PatchStatus = "pending"
+	PatchStatusApplied    PatchStatus = "applied"
+	PatchStatusFailed     PatchStatus = "failed"
+	PatchStatusNotApplied PatchStatus = "not_applied"
+)
+
+// PatchResult describes a single available patch and its outcome
+type PatchResult struct {
+	PackageName    string       `json:"package_name"`
+	CurrentVersion string       `json:"current_version"`
+	PatchedName    string       `json:"patched_name"`
+	PatchedVersion string       `json:"patched_version"`
+	CVEIDs         []string     `json:"cve_ids"`
+	CVEs           []rootio.CVE `json:"cves,omitempty"`
+	Severity       string       `json:"severity,omitempty"`
+	Status         PatchStatus  `json:"status"`
+	Error          string       `json:"error,omitempty"`
+}
+
+// SkippedResult describes a package the backend could not patch
+type SkippedResult struct {
+	PackageName string `json:"package_name"`
+	Reason      string `json:"reason"`
+}
+
+// RunResult is the structured outcome of a remediation run
+type RunResult struct {
+	Ecosystem     Ecosystem       `json:"ecosystem"`
+	File          string          `json:"file,omitempty"`
+	DryRun        bool            `json:"dry_run"`
+	PackagesFound int             `json:"packages_found"`
+	DevSkipped    int             `json:"dev_skipped,omitempty"`
+	Patches       []PatchResult   `json:"patches"`
+	Skipped       []SkippedResult `json:"skipped"`
+	// FixedCVEs lists the CVEs fixed by applied patches, deduplicated and sorted
+	FixedCVEs []string `json:"fixed_cves,omitempty"`
+	// Unresolved lists packages that still had patches available when --verify re-analyzed them
+	Unresolved []string `json:"unresolved,omitempty"`
+	Error      string   `json:"error,omitempty"`
+}
+
+// Runner is implemented by each ecosystem's App so callers can embed remediation as a library
+type Runner interface {
+	// Run executes the remediation workflow, printing progress and results to stdout
+	Run(ctx context.Context) error
+
+	// RunWithResult executes Run and returns its structured result; a failed run's error is recorded on it
+	RunWithResult(ctx context.Context) (*RunResult, error)
+
+	// Result returns the structured result of the last run
+	Result() *RunResult
+}
+
+// NewRunResult creates an empty result for the given ecosystem
+func NewRunResult(ecosystem Ecosystem, file string, dryRun bool) *RunResult {
+	return &RunResult{
+		Ecosystem: ecosystem,
+		File:      file,
+		DryRun:    dryRun,
+		Patches:   []PatchResult{},
+		Skipped:   []SkippedResult{},
+	}
+}
+
+// AddPatches records available patches with the given initial status.
+// useAlias selects whether the aliased or direct patch is reported as the target.
+func (r *RunResult) AddPatches(patches []rootio.PackagePatch, useAlias bool, status PatchStatus) {
+	for _, patch := range patches {
+		patchInfo := PatchTarget(patch, useAlias)
+
+		cveIDs := patch.CVEIDs
+		if cveIDs == nil {
+			cveIDs = []string{}
+		}
+
+		r.Patches = append(r.Patches, PatchResult{
+			PackageName:    patch.PackageName,
+			CurrentVersion: patch.Version,
+			PatchedName:    patchInfo.Name,
+			PatchedVersion: patchInfo.Version,
+			CVEIDs:         cveIDs,
+			CVEs:           patch.CVEs,
+			Severity:       patch.Severity,
+			Status:         status,
+		})
+	}
+}
+
+// AddSkipped records packages skipped by the backend
+func (r *RunResult) AddSkipped(skipped []rootio.SkippedPackage) {
+	for _, s := range skipped {
+		r.Skipped = append(r.Skipped, SkippedResult{
+			PackageName: s.PackageName,
+			Reason:      s.Reason,
+		})
+	}
+}
+
+// SetPatchStatus updates the status of the patch at index i
+func (r *RunResult) SetPatchStatus(i int, status PatchStatus, err error) {
+	if i < 0 || i >= len(r.Patches) {
+		return
+	}
+	r.Patches[i].Status = status
+	if err != nil {
+		r.Patches[i].Error = err.Error()
+	}
+}
+
+// SetAllPatchStatus updates the status of every recorded patch
+func (r *RunResult) SetAllPatchStatus(status PatchStatus, err error) {
+	for i := range r.Patches {
+		r.SetPatchStatus(i, status, err)
+	}
+}
+
+// Applied returns the number of patches that were applied
+func (r *RunResult) Applied() int {
+	return r.countStatus(PatchStatusApplied)
+}
+
+// Failed returns the number of patches that failed to apply
+func (r *RunResult) Failed() int {
+	return r.countStatus(PatchStatusFailed)
+}
+
+// Unapplied returns the number of available patches that were neither applied nor failed,
+// such as patches previewed in dry-run mode
+func (r *RunResult) Unapplied() int {
+	return len(r.Patches) - r.Applied() - r.Failed()
+}
+
+// RecordFixedCVEs collects the CVEs fixed by the applied patches into FixedCVEs and returns them
+func (r *RunResult) RecordFixedCVEs() []string {
+	var cves []string
+	for _, patch := range r.Patches {
+		if patch.Status == PatchStatusApplied {
+			cves = append(cves, patch.CVEIDs...)
+		}
+	}
+	slices.Sort(cves)
+	r.FixedCVEs = slices.Compact(cves)
+	return r.FixedCVEs
+}
+
+// countStatus counts patches with the given status
+func (r *RunResult) countStatus(status PatchStatus) int {
+	count := 0
+	for _, patch := range r.Patches {
+		if patch.Status == status {
+			count++
+		}
+	}
+	return count
+}
+
+// SetError records the error that ended the run, if any
+func (r *RunResult) SetError(err error) {
+	if err != nil {
+		r.Error = err.Error()
+	}
+}
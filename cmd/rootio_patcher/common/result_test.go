@@ -0,0 +1,41 @@
+package common
+
+import (
+	"slices"
+	"testing"
+
+	"rootio_patcher/pkg/rootio"
+)
+
+func TestRunResult_RecordFixedCVEs(t *testing.T) {
+	result := NewRunResult(EcosystemNpm, "package.json", false)
+	result.AddPatches([]rootio.PackagePatch{
+		{PackageName: "lodash", CVEIDs: []string{"CVE-2021-23337", "CVE-2020-8203"}},
+		{PackageName: "lodash-es", CVEIDs: []string{"CVE-2021-23337"}},
+		{PackageName: "minimist", CVEIDs: []string{"CVE-2021-44906"}},
+	}, false, PatchStatusPending)
+	result.SetPatchStatus(0, PatchStatusApplied, nil)
+	result.SetPatchStatus(1, PatchStatusApplied, nil)
+	result.SetPatchStatus(2, PatchStatusFailed, nil)
+
+	cves := result.RecordFixedCVEs()
+
+	expected := []string{"CVE-2020-8203", "CVE-2021-23337"}
+	if !slices.Equal(cves, expected) {
+		t.Errorf("Expected fixed CVEs %v, got %v", expected, cves)
+	}
+	if !slices.Equal(result.FixedCVEs, expected) {
+		t.Errorf("Expected FixedCVEs %v, got %v", expected, result.FixedCVEs)
+	}
+}
+
+func TestRunResult_RecordFixedCVEs_NoneApplied(t *testing.T) {
+	result := NewRunResult(EcosystemNpm, "package.json", true)
+	result.AddPatches([]rootio.PackagePatch{
+		{PackageName: "lodash", CVEIDs: []string{"CVE-2021-23337"}},
+	}, false, PatchStatusDryRun)
+
+	if cves := result.RecordFixedCVEs(); len(cves) != 0 {
+		t.Errorf("Expected no fixed CVEs, got %v", cves)
+	}
+}
@@ -2,6 +2,7 @@ package common
 
 import (
 	"context"
+	"log/slog"
 
 	"rootio_patcher/pkg/rootio"
 )
@@ -11,6 +12,11 @@ type InstalledPackage struct {
 	Name     string `json:"name"`
 	Version  string `json:"version"`
 	Location string `json:"location,omitempty"`
+
+	// EditableLocation is the project directory of a pip editable (-e) install
+	EditableLocation string `json:"editable_project_location,omitempty"`
+	// VCSURL is the repository a pip package was installed from, such as git+https://...
+	VCSURL string `json:"vcs_url,omitempty"`
 }
 
 // APIClient defines the interface for calling the Root.io API
@@ -18,6 +24,29 @@ type APIClient interface {
 	AnalyzePackages(ctx context.Context, packages []rootio.Package) (*rootio.AnalyzePackagesResponse, error)
 }
 
+// NewAPIClient creates a Root.io API client that analyzes packages on the ecosystem's remediate endpoint,
+// caching its responses when a cache directory is configured. Requests and responses are logged to
+// logger at debug level. When a plan entry is being applied, the planned patches are returned
+// instead and the API isn't called; in offline mode the local vulnerability database answers.
+func NewAPIClient(ecosystem Ecosystem, apiURL, apiKey string, logger *slog.Logger, opts ...Option) APIClient {
+	options := NewOptions(opts...)
+	if options.PlanEntry != nil {
+		return NewPlanClient(*options.PlanEntry, options.Output())
+	}
+	if options.OfflineDB != nil {
+		return NewLocalClient(options.OfflineDB, ecosystem)
+	}
+	clientOptions := append(options.ClientOptions, rootio.WithEcosystem(string(ecosystem)), rootio.WithLogger(logger))
+	client := rootio.NewClient(apiURL, apiKey, clientOptions...)
+
+	if options.CacheDir == "" {
+		return client
+	}
+	// Key the cache by the endpoint, so a custom remediate path doesn't reuse another path's responses,
+	// and by the API key, so another credential on the same machine doesn't either
+	return NewCachingClient(client, options.CacheDir, ecosystem, client.RemediateURL(), apiKey, options.CacheTTL)
+}
+
 // PipExecutorInterface defines the interface for executing pip commands
 type PipExecutorInterface interface {
 	ApplyPatch(ctx context.Context, patch rootio.PackagePatch) error
@@ -0,0 +1,62 @@
+package common
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+	"time"
+
+	"rootio_patcher/pkg/rootio"
+)
+
+func TestNewAPIClient_UsesEcosystemEndpoint(t *testing.T) {
+	for _, ecosystem := range []Ecosystem{EcosystemPyPI, EcosystemNpm, EcosystemMaven, EcosystemDebian} {
+		t.Run(string(ecosystem), func(t *testing.T) {
+			var gotPath string
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				gotPath = r.URL.Path
+				_ = json.NewEncoder(w).Encode(rootio.AnalyzePackagesResponse{})
+			}))
+			defer server.Close()
+
+			// A caller-supplied ecosystem option must not override the app's ecosystem
+			client := NewAPIClient(ecosystem, server.URL, "test-key", nil,
+				WithClientOptions(rootio.WithEcosystem("pypi"), rootio.WithRetry(1, 0)))
+			if _, err := client.AnalyzePackages(context.Background(), []rootio.Package{{Name: "pkg", Version: "1.0.0"}}); err != nil {
+				t.Fatalf("Expected no error, got: %v", err)
+			}
+
+			if want := "/v3/remediate/" + string(ecosystem); gotPath != want {
+				t.Errorf("Expected request to %s, got %s", want, gotPath)
+			}
+		})
+	}
+}
+
+func TestNewAPIClient_RemediatePath(t *testing.T) {
+	var paths []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		paths = append(paths, r.URL.Path)
+		_ = json.NewEncoder(w).Encode(rootio.AnalyzePackagesResponse{})
+	}))
+	defer server.Close()
+
+	// The same packages on a staging path must not be answered from the default path's cache
+	cacheDir := t.TempDir()
+	packages := []rootio.Package{{Name: "pkg", Version: "1.0.0"}}
+	for _, template := range []string{"/staging/v3/remediate/{ecosystem}", ""} {
+		client := NewAPIClient(EcosystemNpm, server.URL, "test-key", nil,
+			WithCache(cacheDir, time.Hour),
+			WithClientOptions(rootio.WithRemediatePath(template), rootio.WithRetry(1, 0)))
+		if _, err := client.AnalyzePackages(context.Background(), packages); err != nil {
+			t.Fatalf("Expected no error, got: %v", err)
+		}
+	}
+
+	if want := []string{"/staging/v3/remediate/npm", "/v3/remediate/npm"}; !slices.Equal(paths, want) {
+		t.Errorf("Expected requests to %v, got %v", want, paths)
+	}
+}
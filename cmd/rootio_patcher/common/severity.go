@@ -0,0 +1,68 @@
+package common
+
+import (
+	"fmt"
+	"strings"
+
+	"rootio_patcher/pkg/rootio"
+)
+
+// Severity levels accepted by --min-severity, lowest first
+const (
+	SeverityNone     = "none"
+	SeverityLow      = "low"
+	SeverityMedium   = "medium"
+	SeverityHigh     = "high"
+	SeverityCritical = "critical"
+)
+
+// severityRanks orders severities; unknown values rank as none
+var severityRanks = map[string]int{
+	SeverityNone:     0,
+	SeverityLow:      1,
+	SeverityMedium:   2,
+	SeverityHigh:     3,
+	SeverityCritical: 4,
+}
+
+// SeverityRank returns the numeric rank of a severity (case-insensitive)
+func SeverityRank(severity string) int {
+	return severityRanks[strings.ToLower(severity)]
+}
+
+// IsSeverity reports whether severity is one of the levels accepted by --min-severity (case-insensitive)
+func IsSeverity(severity string) bool {
+	_, ok := severityRanks[strings.ToLower(severity)]
+	return ok
+}
+
+// FilterBySeverity splits patches into those at or above minSeverity and
+// skipped entries for the rest. Patches without a severity rank as none.
+func FilterBySeverity(
+	patches []rootio.PackagePatch, minSeverity string,
+) ([]rootio.PackagePatch, []rootio.SkippedPackage) {
+	minRank := SeverityRank(minSeverity)
+	if minRank == 0 {
+		return patches, nil
+	}
+
+	var kept []rootio.PackagePatch
+	var skipped []rootio.SkippedPackage
+	for _, patch := range patches {
+		if SeverityRank(patch.Severity) >= minRank {
+			kept = append(kept, patch)
+			continue
+		}
+
+		severity := patch.Severity
+		if severity == "" {
+			severity = "unknown"
+		}
+		skipped = append(skipped, rootio.SkippedPackage{
+			PackageName: patch.PackageName,
+			Reason:      fmt.Sprintf("skipped due to severity (%s is below %s)", severity, minSeverity),
+		})
+	}
+
+	return kept, skipped
+}
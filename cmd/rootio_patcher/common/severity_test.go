@@ -0,0 +1,67 @@
+package common
+
+import (
+	"strings"
+	"testing"
+
+	"rootio_patcher/pkg/rootio"
+)
+
+func TestSeverityRank(t *testing.T) {
+	tests := []struct {
+		severity string
+		expected int
+	}{
+		{"none", 0},
+		{"low", 1},
+		{"MEDIUM", 2},
+		{"high", 3},
+		{"Critical", 4},
+		{"", 0},
+		{"bogus", 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.severity, func(t *testing.T) {
+			if rank := SeverityRank(tt.severity); rank != tt.expected {
+				t.Errorf("Expected rank %d, got %d", tt.expected, rank)
+			}
+		})
+	}
+}
+
+func TestFilterBySeverity(t *testing.T) {
+	patches := []rootio.PackagePatch{
+		{PackageName: "django", Severity: "critical"},
+		{PackageName: "flask", Severity: "high"},
+		{PackageName: "requests", Severity: "medium"},
+		{PackageName: "urllib3", Severity: ""},
+	}
+
+	kept, skipped := FilterBySeverity(patches, SeverityHigh)
+
+	if len(kept) != 2 || kept[0].PackageName != "django" || kept[1].PackageName != "flask" {
+		t.Errorf("Expected django and flask to be kept, got %+v", kept)
+	}
+	if len(skipped) != 2 {
+		t.Fatalf("Expected 2 skipped packages, got %d", len(skipped))
+	}
+	if skipped[0].PackageName != "requests" || !strings.Contains(skipped[0].Reason, "skipped due to severity") {
+		t.Errorf("Unexpected skipped entry: %+v", skipped[0])
+	}
+	if !strings.Contains(skipped[1].Reason, "unknown") {
+		t.Errorf("Expected missing severity to be reported as unknown, got: %s", skipped[1].Reason)
+	}
+}
+
+func TestFilterBySeverity_NoneKeepsAll(t *testing.T) {
+	patches := []rootio.PackagePatch{
+		{PackageName: "django", Severity: "low"},
+		{PackageName: "flask"},
+	}
+
+	kept, skipped := FilterBySeverity(patches, SeverityNone)
+	if len(kept) != 2 || len(skipped) != 0 {
+		t.Errorf("Expected all patches to be kept, got %d kept and %d skipped", len(kept), len(skipped))
+	}
+}
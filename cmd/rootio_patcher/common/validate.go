@@ -0,0 +1,103 @@
+package common
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"rootio_patcher/pkg/rootio"
+)
+
+// packageNamePatterns are the package name grammars of each ecosystem. Patch names come from
+// the API and end up in install commands and build files, so anything else is rejected.
+var packageNamePatterns = map[Ecosystem]*regexp.Regexp{
+	// PEP 508 names
+	EcosystemPyPI: regexp.MustCompile(`^[A-Za-z0-9]([A-Za-z0-9._-]*[A-Za-z0-9])?$`),
+	// Optionally scoped npm names; legacy names may contain capitals
+	EcosystemNpm: regexp.MustCompile(`^(@[A-Za-z0-9~-][A-Za-z0-9._~-]*/)?[A-Za-z0-9~-][A-Za-z0-9._~-]*$`),
+	// groupId:artifactId coordinates
+	EcosystemMaven: regexp.MustCompile(`^[A-Za-z0-9_.-]+:[A-Za-z0-9_.-]+$`),
+	// Module paths made of slash-separated elements
+	EcosystemGo:       regexp.MustCompile(`^[A-Za-z0-9_.~+-]+(/[A-Za-z0-9_.~+-]+)*$`),
+	EcosystemRubyGems: regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]*$`),
+	// NuGet package IDs
+	EcosystemNuGet: regexp.MustCompile(`^[A-Za-z0-9_][A-Za-z0-9_.-]*$`),
+	// Debian policy package names
+	EcosystemDebian: regexp.MustCompile(`^[a-z0-9][a-z0-9.+-]+$`),
+}
+
+// versionPattern covers the version characters of every ecosystem, including Debian epochs
+// (1:2.3-1), tildes and PEP 440 local versions
+var versionPattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._+~:!-]*$`)
+
+// ValidPackageName reports whether name follows the package name grammar of the ecosystem
+func ValidPackageName(ecosystem Ecosystem, name string) bool {
+	pattern, ok := packageNamePatterns[ecosystem]
+	if !ok || !pattern.MatchString(name) {
+		return false
+	}
+
+	// Module path elements can't walk out of the module cache
+	if ecosystem == EcosystemGo {
+		for _, element := range strings.Split(name, "/") {
+			if element == "." || element == ".." {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// ValidVersion reports whether version only contains characters versions are made of
+func ValidVersion(version string) bool {
+	return versionPattern.MatchString(version)
+}
+
+// FilterInvalidNames separates patches whose package names and versions are valid for the
+// ecosystem from those that aren't. Malformed values returned by the API are reported as
+// skipped and never reach an install command or build file.
+func FilterInvalidNames(
+	ecosystem Ecosystem, patches []rootio.PackagePatch,
+) ([]rootio.PackagePatch, []rootio.SkippedPackage) {
+	var kept []rootio.PackagePatch
+	var skipped []rootio.SkippedPackage
+	for _, patch := range patches {
+		if reason := invalidPatchField(ecosystem, patch); reason != "" {
+			skipped = append(skipped, rootio.SkippedPackage{
+				PackageName: patch.PackageName,
+				Reason:      reason,
+			})
+			continue
+		}
+		kept = append(kept, patch)
+	}
+
+	return kept, skipped
+}
+
+// invalidPatchField describes the first malformed name or version of a patch, or returns ""
+func invalidPatchField(ecosystem Ecosystem, patch rootio.PackagePatch) string {
+	names := []struct{ field, value string }{
+		{"package name", patch.PackageName},
+		{"patch name", patch.Patch.Name},
+		{"alias name", patch.PatchAlias.Name},
+	}
+	for _, name := range names {
+		if name.value != "" && !ValidPackageName(ecosystem, name.value) {
+			return fmt.Sprintf("rejected invalid %s %q returned by the API", name.field, name.value)
+		}
+	}
+
+	versions := []struct{ field, value string }{
+		{"version", patch.Version},
+		{"patch version", patch.Patch.Version},
+		{"alias version", patch.PatchAlias.Version},
+	}
+	for _, version := range versions {
+		if version.value != "" && !ValidVersion(version.value) {
+			return fmt.Sprintf("rejected invalid %s %q returned by the API", version.field, version.value)
+		}
+	}
+
+	return ""
+}
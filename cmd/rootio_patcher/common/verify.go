@@ -0,0 +1,59 @@
+package common
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"rootio_patcher/pkg/rootio"
+)
+
+// VerifyPackages converts re-parsed packages for VerifyPatches. Unpinned packages can't be
+// analyzed and are left out, as are dev packages with SkipDev; duplicates are sent once.
+func VerifyPackages(packages []PackageInfo, options Options) []rootio.Package {
+	if options.SkipDev {
+		packages, _ = FilterDev(packages)
+	}
+
+	var sdkPackages []rootio.Package
+	seen := make(map[string]bool)
+	for _, pkg := range packages {
+		key := pkg.Name + "@" + pkg.Version
+		if pkg.Version == "" || seen[key] {
+			continue
+		}
+		seen[key] = true
+		sdkPackages = append(sdkPackages, pkg.SDKPackage())
+	}
+	return sdkPackages
+}
+
+// VerifyPatches analyzes packages again after patches were applied and fails if the API still has
+// patches for any of them. The run's filters apply (see FilterPatches), so packages the
+// run deliberately left alone don't count. Remaining patches are printed and recorded on result.
+func VerifyPatches(
+	ctx context.Context, ecosystem Ecosystem, client APIClient, packages []rootio.Package, options Options, result *RunResult,
+) error {
+	out := options.Output()
+	fmt.Fprintln(out, "\nVerifying patches...")
+	response, err := client.AnalyzePackages(ctx, packages)
+	if err != nil {
+		return fmt.Errorf("failed to verify patches: %w", err)
+	}
+
+	remaining, _ := FilterPatches(ecosystem, response.Patches, false, options)
+	if len(remaining) == 0 {
+		fmt.Fprintln(out, "✓ Verified: no patches remain")
+		return nil
+	}
+
+	names := make([]string, len(remaining))
+	fmt.Fprintf(out, "✗ %d packages still have patches available:\n", len(remaining))
+	for i, patch := range remaining {
+		names[i] = patch.PackageName
+		result.Unresolved = append(result.Unresolved, patch.PackageName)
+		fmt.Fprintf(out, "  - %s %s\n", patch.PackageName, patch.Version)
+	}
+	return fmt.Errorf("verification failed: %d packages still have patches available: %s",
+		len(remaining), strings.Join(names, ", "))
+}
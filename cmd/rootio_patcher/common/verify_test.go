@@ -0,0 +1,61 @@
+package common
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"rootio_patcher/pkg/rootio"
+)
+
+func TestVerifyPackages(t *testing.T) {
+	packages := []PackageInfo{
+		{Name: "lodash", Version: "4.17.21", Ecosystem: EcosystemNpm},
+		{Name: "lodash", Version: "4.17.21", Ecosystem: EcosystemNpm, Path: "node_modules/a/node_modules/lodash"},
+		{Name: "express", Version: "", Ecosystem: EcosystemNpm},
+		{Name: "jest", Version: "29.0.0", Ecosystem: EcosystemNpm, Dev: true},
+	}
+
+	if sdkPackages := VerifyPackages(packages, NewOptions()); len(sdkPackages) != 2 {
+		t.Errorf("Expected lodash once and jest, got %v", sdkPackages)
+	}
+	if sdkPackages := VerifyPackages(packages, NewOptions(WithSkipDev(true))); len(sdkPackages) != 1 {
+		t.Errorf("Expected only lodash with SkipDev, got %v", sdkPackages)
+	}
+}
+
+func TestVerifyPatches_PatchesRemain(t *testing.T) {
+	result := NewRunResult(EcosystemNpm, "package-lock.json", false)
+	packages := []rootio.Package{{Name: "lodash", Version: "4.17.20"}}
+
+	err := VerifyPatches(context.Background(), EcosystemNpm, &countingClient{}, packages, NewOptions(), result)
+	if err == nil {
+		t.Fatal("Expected verification to fail while a patch remains")
+	}
+	if len(result.Unresolved) != 1 || result.Unresolved[0] != "lodash" {
+		t.Errorf("Expected lodash to be unresolved, got %v", result.Unresolved)
+	}
+}
+
+func TestVerifyPatches_FilteredPatchesIgnored(t *testing.T) {
+	result := NewRunResult(EcosystemNpm, "package-lock.json", false)
+	packages := []rootio.Package{{Name: "lodash", Version: "4.17.20"}}
+	options := NewOptions(WithPackageFilter(nil, []string{"lodash"}))
+
+	if err := VerifyPatches(context.Background(), EcosystemNpm, &countingClient{}, packages, options, result); err != nil {
+		t.Fatalf("Expected excluded packages not to fail verification, got: %v", err)
+	}
+	if len(result.Unresolved) != 0 {
+		t.Errorf("Expected nothing unresolved, got %v", result.Unresolved)
+	}
+}
+
+func TestVerifyPatches_APIError(t *testing.T) {
+	result := NewRunResult(EcosystemNpm, "package-lock.json", false)
+	client := &countingClient{err: errors.New("API error")}
+
+	err := VerifyPatches(context.Background(), EcosystemNpm, client, []rootio.Package{{Name: "lodash"}}, NewOptions(), result)
+	if !errors.Is(err, client.err) {
+		t.Errorf("Expected the API error to be wrapped, got: %v", err)
+	}
+}
@@ -0,0 +1,578 @@
+package common
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"rootio_patcher/pkg/rootio"
+)
+
+// rootBuildPattern matches the suffix Root.io adds to a rebuilt release: 4.17.21-root.1, 4.0.0+root.io.1
+var rootBuildPattern = regexp.MustCompile(`[-+]root(\.io)?\.[0-9]+$`)
+
+// FilterDowngrades splits patches into those whose patched version is newer than the current
+// version and skipped entries for the rest, so an equal or lower version returned by the API is
+// reported instead of applied. The version compared is the one that will be installed (see
+// PatchTarget); a Root.io rebuild of the current release counts as newer. Patches without a
+// comparable version are kept.
+func FilterDowngrades(
+	ecosystem Ecosystem, patches []rootio.PackagePatch, useAlias bool,
+) ([]rootio.PackagePatch, []rootio.SkippedPackage) {
+	var kept []rootio.PackagePatch
+	var skipped []rootio.SkippedPackage
+	for _, patch := range patches {
+		target := PatchTarget(patch, useAlias).Version
+		if target == "" || patch.Version == "" || isUpgrade(ecosystem, target, patch.Version) {
+			kept = append(kept, patch)
+			continue
+		}
+
+		skipped = append(skipped, rootio.SkippedPackage{
+			PackageName: patch.PackageName,
+			Reason:      fmt.Sprintf("patched version %s is not newer than %s", target, patch.Version),
+		})
+	}
+
+	return kept, skipped
+}
+
+// isUpgrade reports whether installing target replaces current with a newer build. A Root.io
+// rebuild (4.17.21-root.1) of a release at least as new as current counts, although semver sorts
+// the suffix before the release.
+func isUpgrade(ecosystem Ecosystem, target, current string) bool {
+	if rootBuildPattern.MatchString(target) && !rootBuildPattern.MatchString(current) {
+		release := rootBuildPattern.ReplaceAllString(target, "")
+		return CompareVersions(ecosystem, release, current) >= 0
+	}
+	return CompareVersions(ecosystem, target, current) > 0
+}
+
+// CompareVersions compares two versions using the ordering rules of the ecosystem:
+// semver for npm, Go modules and NuGet, PEP 440 for PyPI, dpkg ordering for Debian, Gem::Version for RubyGems
+// and Maven's ComparableVersion for Maven.
+// It returns -1, 0 or 1 when a is older than, equal to or newer than b.
+func CompareVersions(ecosystem Ecosystem, a, b string) int {
+	switch ecosystem {
+	case EcosystemNpm, EcosystemGo, EcosystemNuGet:
+		return compareSemver(a, b)
+	case EcosystemPyPI:
+		return comparePEP440(a, b)
+	case EcosystemDebian:
+		return compareDebian(a, b)
+	case EcosystemRubyGems:
+		return compareRubyGems(a, b)
+	default:
+		return compareMaven(a, b)
+	}
+}
+
+// compareInts returns -1, 0 or 1 like strings.Compare
+func compareInts(a, b int) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// compareNumbers compares dot-separated numeric release segments, padding the shorter with zeros
+func compareNumbers(a, b []int) int {
+	for i := 0; i < len(a) || i < len(b); i++ {
+		var x, y int
+		if i < len(a) {
+			x = a[i]
+		}
+		if i < len(b) {
+			y = b[i]
+		}
+		if c := compareInts(x, y); c != 0 {
+			return c
+		}
+	}
+	return 0
+}
+
+// parseNumbers parses "1.2.3" into its numeric segments; non-numeric segments count as 0
+func parseNumbers(release string) []int {
+	var numbers []int
+	for _, part := range strings.Split(release, ".") {
+		n, _ := strconv.Atoi(part)
+		numbers = append(numbers, n)
+	}
+	return numbers
+}
+
+// compareSemver compares npm versions. Build metadata is ignored and a pre-release
+// (1.0.0-beta.1) sorts before its release.
+func compareSemver(a, b string) int {
+	mainA, preA := splitSemver(a)
+	mainB, preB := splitSemver(b)
+	if c := compareNumbers(parseNumbers(mainA), parseNumbers(mainB)); c != 0 {
+		return c
+	}
+
+	switch {
+	case preA == "" && preB == "":
+		return 0
+	case preA == "":
+		return 1
+	case preB == "":
+		return -1
+	}
+
+	idsA, idsB := strings.Split(preA, "."), strings.Split(preB, ".")
+	for i := 0; i < len(idsA) && i < len(idsB); i++ {
+		if c := compareIdentifier(idsA[i], idsB[i]); c != 0 {
+			return c
+		}
+	}
+	return compareInts(len(idsA), len(idsB))
+}
+
+// rubySegmentPattern splits a gem version into numeric and alphabetic segments like Gem::Version
+var rubySegmentPattern = regexp.MustCompile(`[0-9]+|[a-zA-Z]+`)
+
+// compareRubyGems compares gem versions. A letter segment marks a pre-release, so 1.0.0.rc1 sorts
+// before 1.0.0; zeros before the pre-release and at the end are ignored (1.0 == 1.0.0).
+func compareRubyGems(a, b string) int {
+	segsA, segsB := rubySegments(a), rubySegments(b)
+	for i := 0; i < len(segsA) || i < len(segsB); i++ {
+		x, y := "0", "0"
+		if i < len(segsA) {
+			x = segsA[i]
+		}
+		if i < len(segsB) {
+			y = segsB[i]
+		}
+
+		numX, errX := strconv.Atoi(x)
+		numY, errY := strconv.Atoi(y)
+		var c int
+		switch {
+		case errX == nil && errY == nil:
+			c = compareInts(numX, numY)
+		case errX == nil:
+			c = 1
+		case errY == nil:
+			c = -1
+		default:
+			c = strings.Compare(x, y)
+		}
+		if c != 0 {
+			return c
+		}
+	}
+	return 0
+}
+
+// rubySegments returns the canonical segments of a gem version, without the zeros Gem::Version drops
+func rubySegments(version string) []string {
+	segments := rubySegmentPattern.FindAllString(version, -1)
+
+	release := len(segments)
+	for i, segment := range segments {
+		if _, err := strconv.Atoi(segment); err != nil {
+			release = i
+			break
+		}
+	}
+
+	// Drop zeros at the end of the release part and at the end of the version
+	end := release
+	for end > 0 && segments[end-1] == "0" {
+		end--
+	}
+	canonical := append(segments[:end:end], segments[release:]...)
+	for len(canonical) > 0 && canonical[len(canonical)-1] == "0" {
+		canonical = canonical[:len(canonical)-1]
+	}
+	return canonical
+}
+
+// splitSemver strips a "v" prefix and build metadata and returns the release and pre-release parts
+func splitSemver(version string) (string, string) {
+	version = strings.TrimLeft(strings.TrimSpace(version), "v=")
+	version, _, _ = strings.Cut(version, "+")
+	release, pre, _ := strings.Cut(version, "-")
+	return release, pre
+}
+
+// compareIdentifier compares semver pre-release identifiers: numeric identifiers compare
+// numerically and sort before alphanumeric ones, which compare lexically
+func compareIdentifier(a, b string) int {
+	numA, errA := strconv.Atoi(a)
+	numB, errB := strconv.Atoi(b)
+	switch {
+	case errA == nil && errB == nil:
+		return compareInts(numA, numB)
+	case errA == nil:
+		return -1
+	case errB == nil:
+		return 1
+	default:
+		return strings.Compare(a, b)
+	}
+}
+
+// pep440Pattern matches a PEP 440 version: epoch, release, pre, post, dev and local segments
+var pep440Pattern = regexp.MustCompile(`^v?(?:(\d+)!)?(\d+(?:\.\d+)*)` +
+	`(?:[-_.]?(a|b|c|rc|alpha|beta|pre|preview)[-_.]?(\d*))?` +
+	`(?:-(\d+)|[-_.]?(post|rev|r)[-_.]?(\d*))?` +
+	`(?:[-_.]?(dev)[-_.]?(\d*))?` +
+	`(?:\+([a-z0-9]+(?:[-_.][a-z0-9]+)*))?$`)
+
+// pep440Version is a parsed PEP 440 version. Missing pre, post and dev segments are
+// represented so that dev < pre < release < post, as PEP 440 orders them.
+type pep440Version struct {
+	epoch   int
+	release []int
+	pre     [2]int // phase (a=0, b=1, rc=2) and number
+	post    int
+	dev     int
+	local   []string
+}
+
+const (
+	pepBefore = -1 << 31 // sorts before any real segment
+	pepAfter  = 1<<31 - 1
+)
+
+// parsePEP440 parses a PEP 440 version, reporting false for non-conforming versions
+func parsePEP440(version string) (pep440Version, bool) {
+	m := pep440Pattern.FindStringSubmatch(strings.ToLower(strings.TrimSpace(version)))
+	if m == nil {
+		return pep440Version{}, false
+	}
+	epoch, release, preKind, preNum, implicitPost, postKind, postNum, devKind, devNum, local :=
+		m[1], m[2], m[3], m[4], m[5], m[6], m[7], m[8], m[9], m[10]
+
+	v := pep440Version{release: parseNumbers(release), post: pepBefore, dev: pepAfter}
+	v.epoch, _ = strconv.Atoi(epoch)
+
+	switch preKind {
+	case "":
+		v.pre = [2]int{pepAfter, 0}
+	case "a", "alpha":
+		v.pre[0] = 0
+	case "b", "beta":
+		v.pre[0] = 1
+	default:
+		v.pre[0] = 2
+	}
+	v.pre[1], _ = strconv.Atoi(preNum)
+
+	switch {
+	case implicitPost != "":
+		v.post, _ = strconv.Atoi(implicitPost)
+	case postKind != "":
+		v.post, _ = strconv.Atoi(postNum)
+	}
+
+	if devKind != "" {
+		v.dev, _ = strconv.Atoi(devNum)
+		// A development release with no pre or post segment sorts before pre-releases
+		if preKind == "" && v.post == pepBefore {
+			v.pre = [2]int{pepBefore, 0}
+		}
+	}
+
+	if local != "" {
+		v.local = strings.FieldsFunc(local, func(r rune) bool { return r == '.' || r == '-' || r == '_' })
+	}
+	return v, true
+}
+
+// comparePEP440 compares Python versions, falling back to Maven-style ordering for
+// versions that don't follow PEP 440
+func comparePEP440(a, b string) int {
+	va, okA := parsePEP440(a)
+	vb, okB := parsePEP440(b)
+	if !okA || !okB {
+		return compareMaven(a, b)
+	}
+
+	for _, c := range []int{
+		compareInts(va.epoch, vb.epoch),
+		compareNumbers(va.release, vb.release),
+		compareInts(va.pre[0], vb.pre[0]),
+		compareInts(va.pre[1], vb.pre[1]),
+		compareInts(va.post, vb.post),
+		compareInts(va.dev, vb.dev),
+	} {
+		if c != 0 {
+			return c
+		}
+	}
+
+	// A local version (1.0+root.1) sorts after the same public version
+	for i := 0; i < len(va.local) && i < len(vb.local); i++ {
+		numA, errA := strconv.Atoi(va.local[i])
+		numB, errB := strconv.Atoi(vb.local[i])
+		var c int
+		switch {
+		case errA == nil && errB == nil:
+			c = compareInts(numA, numB)
+		case errA == nil:
+			c = 1
+		case errB == nil:
+			c = -1
+		default:
+			c = strings.Compare(va.local[i], vb.local[i])
+		}
+		if c != 0 {
+			return c
+		}
+	}
+	return compareInts(len(va.local), len(vb.local))
+}
+
+// mavenQualifiers orders the well-known Maven qualifiers; unknown qualifiers sort after all of them
+var mavenQualifiers = map[string]int{
+	"alpha":     0,
+	"a":         0,
+	"beta":      1,
+	"b":         1,
+	"milestone": 2,
+	"m":         2,
+	"rc":        3,
+	"cr":        3,
+	"snapshot":  4,
+	"":          5,
+	"ga":        5,
+	"final":     5,
+	"release":   5,
+	"sp":        6,
+}
+
+// mavenItem is one token of a Maven version: a number or a qualifier
+type mavenItem struct {
+	number    int
+	qualifier string
+	isNumber  bool
+}
+
+// tokenizeMaven splits a Maven version on ".", "-" and transitions between digits and letters
+func tokenizeMaven(version string) []mavenItem {
+	var items []mavenItem
+	var current strings.Builder
+	flush := func() {
+		if current.Len() == 0 {
+			return
+		}
+		token := current.String()
+		current.Reset()
+		if n, err := strconv.Atoi(token); err == nil {
+			items = append(items, mavenItem{number: n, isNumber: true})
+		} else {
+			items = append(items, mavenItem{qualifier: token})
+		}
+	}
+
+	version = strings.ToLower(strings.TrimSpace(version))
+	for i, r := range version {
+		if r == '.' || r == '-' || r == '_' || r == '+' {
+			flush()
+			continue
+		}
+		if i > 0 && current.Len() > 0 {
+			prev := version[i-1]
+			if (prev >= '0' && prev <= '9') != (r >= '0' && r <= '9') {
+				flush()
+			}
+		}
+		current.WriteRune(r)
+	}
+	flush()
+
+	return trimMavenItems(items)
+}
+
+// trimMavenItems drops tokens that don't change the version: zeros at the end of a numeric run
+// and release qualifiers, so 1.0.0 == 1 == 1-final and 1.0-rc1 == 1-rc1
+func trimMavenItems(items []mavenItem) []mavenItem {
+	var trimmed []mavenItem
+	for i, item := range items {
+		switch {
+		case !item.isNumber && isReleaseQualifier(item.qualifier):
+			continue
+		case item.isNumber && item.number == 0 && endsNumericRun(items[i+1:]):
+			continue
+		}
+		trimmed = append(trimmed, item)
+	}
+	return trimmed
+}
+
+// endsNumericRun reports whether only zeros remain before the next qualifier or the end
+func endsNumericRun(rest []mavenItem) bool {
+	for _, item := range rest {
+		if !item.isNumber {
+			return true
+		}
+		if item.number != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// isReleaseQualifier reports whether a qualifier marks a plain release (ga, final, release)
+func isReleaseQualifier(qualifier string) bool {
+	rank, known := mavenQualifiers[qualifier]
+	return known && rank == mavenQualifiers[""]
+}
+
+// compareMavenItems compares two tokens; a missing token (nil) acts as 0 or the release qualifier
+func compareMavenItems(a, b *mavenItem) int {
+	switch {
+	case a == nil && b == nil:
+		return 0
+	case a == nil:
+		return -compareMavenItems(b, nil)
+	}
+
+	if a.isNumber {
+		switch {
+		case b == nil:
+			return compareInts(a.number, 0)
+		case b.isNumber:
+			return compareInts(a.number, b.number)
+		default:
+			// Numbers are newer than qualifiers: 1.1 > 1-rc
+			return 1
+		}
+	}
+
+	if b == nil {
+		return compareQualifiers(a.qualifier, "")
+	}
+	if b.isNumber {
+		return -1
+	}
+	return compareQualifiers(a.qualifier, b.qualifier)
+}
+
+// compareQualifiers orders known qualifiers by rank and unknown ones lexically after them
+func compareQualifiers(a, b string) int {
+	rankA, knownA := mavenQualifiers[a]
+	rankB, knownB := mavenQualifiers[b]
+	switch {
+	case knownA && knownB:
+		return compareInts(rankA, rankB)
+	case knownA:
+		return -1
+	case knownB:
+		return 1
+	default:
+		return strings.Compare(a, b)
+	}
+}
+
+// compareMaven compares versions like Maven's ComparableVersion (simplified):
+// numeric tokens compare numerically and qualifiers follow alpha < beta < milestone < rc <
+// snapshot < release < sp < other
+func compareMaven(a, b string) int {
+	itemsA, itemsB := tokenizeMaven(a), tokenizeMaven(b)
+	for i := 0; i < len(itemsA) || i < len(itemsB); i++ {
+		var x, y *mavenItem
+		if i < len(itemsA) {
+			x = &itemsA[i]
+		}
+		if i < len(itemsB) {
+			y = &itemsB[i]
+		}
+		if c := compareMavenItems(x, y); c != 0 {
+			return c
+		}
+	}
+	return 0
+}
+
+// compareDebian compares Debian package versions ([epoch:]upstream[-revision]) like dpkg does
+func compareDebian(a, b string) int {
+	epochA, upstreamA, revisionA := splitDebian(a)
+	epochB, upstreamB, revisionB := splitDebian(b)
+	if c := compareInts(epochA, epochB); c != 0 {
+		return c
+	}
+	if c := compareDebianPart(upstreamA, upstreamB); c != 0 {
+		return c
+	}
+	return compareDebianPart(revisionA, revisionB)
+}
+
+// splitDebian splits a Debian version into its epoch, upstream version and revision
+func splitDebian(version string) (int, string, string) {
+	version = strings.TrimSpace(version)
+	epoch := 0
+	if before, after, ok := strings.Cut(version, ":"); ok {
+		epoch, _ = strconv.Atoi(before)
+		version = after
+	}
+	if i := strings.LastIndex(version, "-"); i >= 0 {
+		return epoch, version[:i], version[i+1:]
+	}
+	return epoch, version, ""
+}
+
+// debianOrder ranks a character in the non-digit part of a version: "~" sorts before
+// everything (even the end of the string), letters before other characters
+func debianOrder(c byte) int {
+	switch {
+	case c == '~':
+		return -1
+	case c >= '0' && c <= '9':
+		return 0
+	case (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z'):
+		return int(c)
+	default:
+		return int(c) + 256
+	}
+}
+
+// compareDebianPart compares alternating non-digit and digit runs as dpkg's verrevcmp does
+func compareDebianPart(a, b string) int {
+	isDigit := func(c byte) bool { return c >= '0' && c <= '9' }
+
+	for a != "" || b != "" {
+		// Non-digit prefix, compared character by character
+		for (a != "" && !isDigit(a[0])) || (b != "" && !isDigit(b[0])) {
+			var orderA, orderB int
+			if a != "" {
+				orderA = debianOrder(a[0])
+			}
+			if b != "" {
+				orderB = debianOrder(b[0])
+			}
+			if c := compareInts(orderA, orderB); c != 0 {
+				return c
+			}
+			if a != "" {
+				a = a[1:]
+			}
+			if b != "" {
+				b = b[1:]
+			}
+		}
+
+		// Digit run, compared numerically
+		var numA, numB int
+		for a != "" && isDigit(a[0]) {
+			numA = numA*10 + int(a[0]-'0')
+			a = a[1:]
+		}
+		for b != "" && isDigit(b[0]) {
+			numB = numB*10 + int(b[0]-'0')
+			b = b[1:]
+		}
+		if c := compareInts(numA, numB); c != 0 {
+			return c
+		}
+	}
+	return 0
+}
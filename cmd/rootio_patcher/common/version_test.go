@@ -0,0 +1,147 @@
+package common
+
+import (
+	"testing"
+
+	"rootio_patcher/pkg/rootio"
+)
+
+func TestCompareVersions(t *testing.T) {
+	tests := []struct {
+		ecosystem Ecosystem
+		a, b      string
+		expected  int
+	}{
+		// npm: semver with pre-release and build metadata
+		{EcosystemNpm, "4.17.3", "4.17.1", 1},
+		{EcosystemNpm, "4.17.10", "4.17.9", 1},
+		{EcosystemNpm, "v1.2.3", "1.2.3", 0},
+		{EcosystemNpm, "1.2.3+build.5", "1.2.3", 0},
+		{EcosystemNpm, "1.0.0-beta.1", "1.0.0", -1},
+		{EcosystemNpm, "1.0.0-beta.2", "1.0.0-beta.10", -1},
+		{EcosystemNpm, "1.0.0-alpha", "1.0.0-alpha.1", -1},
+		{EcosystemNpm, "1.0.0-1", "1.0.0-alpha", -1},
+		{EcosystemNpm, "2.0.0", "10.0.0", -1},
+
+		// Go: semver with a v prefix, pseudo-versions and +incompatible
+		{EcosystemGo, "v0.17.0", "v0.15.0", 1},
+		{EcosystemGo, "v0.0.0-20230101000000-abcdef123456", "v0.1.0", -1},
+		{EcosystemGo, "v2.0.0+incompatible", "v2.0.0", 0},
+
+		// RubyGems: Gem::Version with letter pre-releases
+		{EcosystemRubyGems, "7.0.4.3", "7.0.4", 1},
+		{EcosystemRubyGems, "1.0", "1.0.0", 0},
+		{EcosystemRubyGems, "7.1.0.rc1", "7.1.0", -1},
+		{EcosystemRubyGems, "7.1.0.rc1", "7.1.0.beta2", 1},
+		{EcosystemRubyGems, "1.0.a", "1.0.0.a", 0},
+		{EcosystemRubyGems, "2.10.0", "2.9.9", 1},
+
+		// NuGet: semver with an optional fourth release segment
+		{EcosystemNuGet, "13.0.3", "13.0.1", 1},
+		{EcosystemNuGet, "4.3.0.1", "4.3.0", 1},
+		{EcosystemNuGet, "4.3", "4.3.0.0", 0},
+		{EcosystemNuGet, "8.0.0-rc.2", "8.0.0", -1},
+
+		// PyPI: PEP 440
+		{EcosystemPyPI, "4.2.7", "4.2.0", 1},
+		{EcosystemPyPI, "4.2", "4.2.0", 0},
+		{EcosystemPyPI, "4.2.0.post1", "4.2.0", 1},
+		{EcosystemPyPI, "4.2.0rc1", "4.2.0", -1},
+		{EcosystemPyPI, "4.2.0a1", "4.2.0b1", -1},
+		{EcosystemPyPI, "4.2.0.dev1", "4.2.0a1", -1},
+		{EcosystemPyPI, "4.2.0+root.1", "4.2.0", 1},
+		{EcosystemPyPI, "4.2.0+root.2", "4.2.0+root.10", -1},
+		{EcosystemPyPI, "1!1.0", "2.0", 1},
+		{EcosystemPyPI, "4.2.0-1", "4.2.0.post1", 0},
+
+		// Debian: dpkg ordering with epochs, revisions and tildes
+		{EcosystemDebian, "3.0.11-1~deb12u2", "3.0.11-1~deb12u1", 1},
+		{EcosystemDebian, "3.0.11-1~deb12u1", "3.0.11-1", -1},
+		{EcosystemDebian, "1:1.0-1", "2.0-1", 1},
+		{EcosystemDebian, "1.2.3-1ubuntu1", "1.2.3-1", 1},
+		{EcosystemDebian, "1.2.10", "1.2.9", 1},
+		{EcosystemDebian, "1.0a", "1.0+", -1},
+		{EcosystemDebian, "2.36-9+deb12u4", "2.36-9+deb12u4", 0},
+
+		// Maven: ComparableVersion ordering
+		{EcosystemMaven, "4.13.2", "4.12", 1},
+		{EcosystemMaven, "1.0", "1.0.0", 0},
+		{EcosystemMaven, "1.0-final", "1.0", 0},
+		{EcosystemMaven, "1.0-alpha-1", "1.0-beta-1", -1},
+		{EcosystemMaven, "1.0-rc1", "1.0", -1},
+		{EcosystemMaven, "1.0-SNAPSHOT", "1.0", -1},
+		{EcosystemMaven, "1.0-sp1", "1.0", 1},
+		{EcosystemMaven, "32.0.0-jre", "29.0-jre", 1},
+		{EcosystemMaven, "29.0.0-jre", "29.0-jre", 0},
+		{EcosystemMaven, "2.17.1", "2.17.0", 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.ecosystem)+"/"+tt.a+"_vs_"+tt.b, func(t *testing.T) {
+			if result := CompareVersions(tt.ecosystem, tt.a, tt.b); result != tt.expected {
+				t.Errorf("CompareVersions(%s, %q, %q) = %d, expected %d", tt.ecosystem, tt.a, tt.b, result, tt.expected)
+			}
+			if result := CompareVersions(tt.ecosystem, tt.b, tt.a); result != -tt.expected {
+				t.Errorf("CompareVersions(%s, %q, %q) = %d, expected %d", tt.ecosystem, tt.b, tt.a, result, -tt.expected)
+			}
+		})
+	}
+}
+
+func TestFilterDowngrades(t *testing.T) {
+	patches := []rootio.PackagePatch{
+		{PackageName: "django", Version: "4.0.0", Patch: rootio.PatchInfo{Name: "django", Version: "4.0.1"}},
+		{PackageName: "flask", Version: "2.0.1", Patch: rootio.PatchInfo{Name: "flask", Version: "2.0.1"}},
+		{PackageName: "requests", Version: "2.31.0", Patch: rootio.PatchInfo{Name: "requests", Version: "2.28.0"}},
+		{PackageName: "urllib3", Version: "1.26.0", PatchAlias: rootio.PatchInfo{Name: "rootio-urllib3", Version: "1.26.18"}},
+		{PackageName: "jinja2", Version: "3.0.0"},
+	}
+
+	kept, skipped := FilterDowngrades(EcosystemPyPI, patches, false)
+
+	if len(kept) != 3 || kept[0].PackageName != "django" || kept[1].PackageName != "urllib3" || kept[2].PackageName != "jinja2" {
+		t.Errorf("Expected django, urllib3 and jinja2 to be kept, got %+v", kept)
+	}
+	if len(skipped) != 2 {
+		t.Fatalf("Expected 2 skipped packages, got %+v", skipped)
+	}
+	if skipped[0].PackageName != "flask" || skipped[0].Reason != "patched version 2.0.1 is not newer than 2.0.1" {
+		t.Errorf("Unexpected skipped entry: %+v", skipped[0])
+	}
+	if skipped[1].PackageName != "requests" || skipped[1].Reason != "patched version 2.28.0 is not newer than 2.31.0" {
+		t.Errorf("Unexpected skipped entry: %+v", skipped[1])
+	}
+}
+
+func TestFilterDowngrades_ComparesInstalledVersion(t *testing.T) {
+	patches := []rootio.PackagePatch{
+		// A Root.io rebuild of the current release
+		{PackageName: "lodash", Version: "4.17.21", Patch: rootio.PatchInfo{Version: "4.17.21"},
+			PatchAlias: rootio.PatchInfo{Name: "@rootio/lodash", Version: "4.17.21-root.1"}},
+		// A newer release, but an alias built from an older one
+		{PackageName: "express", Version: "4.18.2", Patch: rootio.PatchInfo{Version: "4.18.3"},
+			PatchAlias: rootio.PatchInfo{Name: "@rootio/express", Version: "4.17.3-root.1"}},
+	}
+
+	tests := []struct {
+		name     string
+		useAlias bool
+		kept     string
+		reason   string
+	}{
+		{"patched version", false, "express", "patched version 4.17.21 is not newer than 4.17.21"},
+		{"alias", true, "lodash", "patched version 4.17.3-root.1 is not newer than 4.18.2"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			kept, skipped := FilterDowngrades(EcosystemNpm, patches, tt.useAlias)
+			if len(kept) != 1 || kept[0].PackageName != tt.kept {
+				t.Errorf("Expected only %s to be kept, got %+v", tt.kept, kept)
+			}
+			if len(skipped) != 1 || skipped[0].Reason != tt.reason {
+				t.Errorf("Expected skipped reason %q, got %+v", tt.reason, skipped)
+			}
+		})
+	}
+}
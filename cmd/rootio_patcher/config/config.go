@@ -1,22 +1,286 @@
 package config
 
 import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+
 	"github.com/caarlos0/env/v11"
+	"gopkg.in/yaml.v3"
 )
 
-// Config holds configuration loaded from environment variables
+// FileName is the config file searched for in the working directory, then the home directory
+const FileName = ".rootio.yaml"
+
+// maxAPIKeySize bounds how much is read from an API key file or stdin, so pointing
+// ROOTIO_API_KEY_FILE at the wrong file fails fast instead of reading it whole
+const maxAPIKeySize = 4096
+
+// Config holds configuration loaded from environment variables and an optional config file
 type Config struct {
-	APIKey   string `env:"ROOTIO_API_KEY,required"`
-	APIURL   string `env:"ROOTIO_API_URL" envDefault:"https://api.root.io"`
-	PKGURL   string `env:"ROOTIO_PKG_URL" envDefault:"https://pkg.root.io"`
-	LogLevel string `env:"LOG_LEVEL" envDefault:"info"`
+	APIKey      string   `env:"ROOTIO_API_KEY"`
+	APIKeyFile  string   `env:"ROOTIO_API_KEY_FILE"`
+	APIURL      string   `env:"ROOTIO_API_URL" envDefault:"https://api.root.io"`
+	PKGURL      string   `env:"ROOTIO_PKG_URL" envDefault:"https://pkg.root.io"`
+	LogLevel    string   `env:"LOG_LEVEL" envDefault:"info"`
+	CACert      string   `env:"ROOTIO_CA_CERT"`
+	MinSeverity string   `env:"ROOTIO_MIN_SEVERITY"`
+	Exclude     []string `env:"ROOTIO_EXCLUDE" envSeparator:","`
+
+	// RemediatePath overrides the path template of the analysis endpoint, e.g. for a staging
+	// backend; {ecosystem} is replaced with the ecosystem (rootio.DefaultRemediatePath when empty)
+	RemediatePath string `env:"ROOTIO_REMEDIATE_PATH"`
+
+	// AuthScheme is how the API key is sent: basic (the default), bearer or header. AuthHeader
+	// names the header carrying the key with the header scheme.
+	AuthScheme string `env:"ROOTIO_AUTH_SCHEME"`
+	AuthHeader string `env:"ROOTIO_AUTH_HEADER"`
+
+	// File is the config file that was loaded, empty if none was found
+	File string
+
+	// Warnings describe problems in the config file that didn't prevent loading it
+	Warnings []string
+}
+
+// fileConfig is the content of a .rootio.yaml file
+type fileConfig struct {
+	APIURL        string   `yaml:"api_url"`
+	PKGURL        string   `yaml:"pkg_url"`
+	LogLevel      string   `yaml:"log_level"`
+	CACert        string   `yaml:"ca_cert"`
+	MinSeverity   string   `yaml:"min_severity"`
+	Exclude       []string `yaml:"exclude"`
+	APIKey        string   `yaml:"api_key"`
+	APIKeyFile    string   `yaml:"api_key_file"`
+	RemediatePath string   `yaml:"remediate_path"`
+	AuthScheme    string   `yaml:"auth_scheme"`
+	AuthHeader    string   `yaml:"auth_header"`
 }
 
-// LoadConfig loads configuration from environment variables using caarlos0/env
-func LoadConfig() (*Config, error) {
+// environment returns the file settings as the environment variables they stand in for
+func (f *fileConfig) environment() map[string]string {
+	vars := map[string]string{
+		"ROOTIO_API_URL":        f.APIURL,
+		"ROOTIO_PKG_URL":        f.PKGURL,
+		"LOG_LEVEL":             f.LogLevel,
+		"ROOTIO_CA_CERT":        f.CACert,
+		"ROOTIO_MIN_SEVERITY":   f.MinSeverity,
+		"ROOTIO_EXCLUDE":        strings.Join(f.Exclude, ","),
+		"ROOTIO_API_KEY_FILE":   f.APIKeyFile,
+		"ROOTIO_REMEDIATE_PATH": f.RemediatePath,
+		"ROOTIO_AUTH_SCHEME":    f.AuthScheme,
+		"ROOTIO_AUTH_HEADER":    f.AuthHeader,
+	}
+	for key, value := range vars {
+		if value == "" {
+			delete(vars, key)
+		}
+	}
+	return vars
+}
+
+// LoadOption configures LoadConfig
+type LoadOption func(*loadOptions)
+
+type loadOptions struct {
+	apiKeyReader   io.Reader
+	apiKeyOptional bool
+}
+
+// WithAPIKeyReader reads the API key from r (e.g. stdin) instead of the environment
+func WithAPIKeyReader(r io.Reader) LoadOption {
+	return func(o *loadOptions) {
+		o.apiKeyReader = r
+	}
+}
+
+// WithOptionalAPIKey allows loading without an API key, for runs that never call the API
+func WithOptionalAPIKey() LoadOption {
+	return func(o *loadOptions) {
+		o.apiKeyOptional = true
+	}
+}
+
+// LoadConfig loads configuration from environment variables using caarlos0/env, merged with a
+// config file. configPath selects the file; when empty, .rootio.yaml is searched for in the
+// working directory and then the home directory. Environment variables take precedence over
+// the file. The API key comes from exactly one of ROOTIO_API_KEY, the file named by
+// ROOTIO_API_KEY_FILE (or api_key_file), or the reader given with WithAPIKeyReader; setting
+// more than one is an error rather than a silent choice.
+func LoadConfig(configPath string, opts ...LoadOption) (*Config, error) {
+	var options loadOptions
+	for _, opt := range opts {
+		opt(&options)
+	}
 	cfg := &Config{}
-	if err := env.Parse(cfg); err != nil {
+
+	filePath, err := findConfigFile(configPath)
+	if err != nil {
+		return nil, err
+	}
+
+	environment := env.ToMap(os.Environ())
+	if filePath != "" {
+		file, err := readConfigFile(filePath)
+		if err != nil {
+			return nil, err
+		}
+		cfg.File = filePath
+
+		if file.APIKey != "" {
+			cfg.Warnings = append(cfg.Warnings, fmt.Sprintf(
+				"api_key in %s is ignored; set ROOTIO_API_KEY or api_key_file instead and remove the key from the file", filePath))
+		}
+		for key, value := range file.environment() {
+			if _, set := environment[key]; !set {
+				environment[key] = value
+			}
+		}
+	}
+
+	if err := env.ParseWithOptions(cfg, env.Options{Environment: environment}); err != nil {
+		return nil, err
+	}
+	if err := cfg.resolveAPIKey(options.apiKeyReader, options.apiKeyOptional); err != nil {
 		return nil, err
 	}
 	return cfg, nil
 }
+
+// resolveAPIKey sets APIKey from its one configured source; optional allows none
+func (cfg *Config) resolveAPIKey(stdin io.Reader, optional bool) error {
+	var sources []string
+	if cfg.APIKey != "" {
+		sources = append(sources, "ROOTIO_API_KEY")
+	}
+	if cfg.APIKeyFile != "" {
+		sources = append(sources, "ROOTIO_API_KEY_FILE")
+	}
+	if stdin != nil {
+		sources = append(sources, "--api-key-stdin")
+	}
+
+	switch {
+	case len(sources) == 0 && optional:
+		return nil
+	case len(sources) == 0:
+		return errors.New("no API key: set ROOTIO_API_KEY, point ROOTIO_API_KEY_FILE at a file containing it, or pass --api-key-stdin")
+	case len(sources) > 1:
+		return fmt.Errorf("the API key is set by %s; use only one of them", strings.Join(sources, " and "))
+	case cfg.APIKeyFile != "":
+		key, warning, err := readAPIKeyFile(cfg.APIKeyFile)
+		if err != nil {
+			return err
+		}
+		if warning != "" {
+			cfg.Warnings = append(cfg.Warnings, warning)
+		}
+		cfg.APIKey = key
+	case stdin != nil:
+		key, err := readAPIKey(stdin)
+		if err != nil {
+			return fmt.Errorf("failed to read API key from stdin: %w", err)
+		}
+		cfg.APIKey = key
+	}
+	return nil
+}
+
+// readAPIKeyFile reads the API key from a regular file. It returns a warning when other users
+// can read the file.
+func readAPIKeyFile(path string) (string, string, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to read API key file: %w", err)
+	}
+	if !info.Mode().IsRegular() {
+		return "", "", fmt.Errorf("API key file %s is not a regular file", path)
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to read API key file: %w", err)
+	}
+	defer file.Close()
+
+	key, err := readAPIKey(file)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to read API key file %s: %w", path, err)
+	}
+
+	var warning string
+	// Windows doesn't report group and other permissions in the file mode
+	if runtime.GOOS != "windows" && info.Mode().Perm()&0o077 != 0 {
+		warning = fmt.Sprintf("API key file %s is readable by other users; restrict it with chmod 600", path)
+	}
+	return key, warning, nil
+}
+
+// readAPIKey reads a single-line API key, trimming surrounding whitespace and newlines
+func readAPIKey(r io.Reader) (string, error) {
+	content, err := io.ReadAll(io.LimitReader(r, maxAPIKeySize+1))
+	if err != nil {
+		return "", err
+	}
+	if len(content) > maxAPIKeySize {
+		return "", fmt.Errorf("more than %d bytes; expected just the API key", maxAPIKeySize)
+	}
+
+	key := strings.TrimSpace(string(content))
+	switch {
+	case key == "":
+		return "", errors.New("the API key is empty")
+	case strings.ContainsAny(key, " \t\r\n"):
+		return "", errors.New("expected the API key on a single line")
+	}
+	return key, nil
+}
+
+// findConfigFile returns the explicit config path, or the first .rootio.yaml in the working
+// directory or home directory. An explicit path must exist; a missing default file is not an error.
+func findConfigFile(configPath string) (string, error) {
+	if configPath != "" {
+		if _, err := os.Stat(configPath); err != nil {
+			return "", fmt.Errorf("failed to read config file %s: %w", configPath, err)
+		}
+		return configPath, nil
+	}
+
+	candidates := []string{FileName}
+	if home, err := os.UserHomeDir(); err == nil {
+		candidates = append(candidates, filepath.Join(home, FileName))
+	}
+	for _, candidate := range candidates {
+		_, err := os.Stat(candidate)
+		if err == nil {
+			return candidate, nil
+		}
+		if !errors.Is(err, fs.ErrNotExist) {
+			return "", fmt.Errorf("failed to read config file %s: %w", candidate, err)
+		}
+	}
+	return "", nil
+}
+
+// readConfigFile parses a .rootio.yaml file, rejecting unknown keys so typos don't go unnoticed
+func readConfigFile(filePath string) (*fileConfig, error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file %s: %w", filePath, err)
+	}
+	defer file.Close()
+
+	var config fileConfig
+	decoder := yaml.NewDecoder(file)
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&config); err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("failed to parse config file %s: %w", filePath, err)
+	}
+	return &config, nil
+}
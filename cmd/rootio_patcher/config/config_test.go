@@ -0,0 +1,337 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+// writeConfigFile writes a config file into a temp directory and returns its path
+func writeConfigFile(t *testing.T, content string) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), FileName)
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write config file: %v", err)
+	}
+	return path
+}
+
+// isolate runs the test in an empty working and home directory with only the API key set
+func isolate(t *testing.T) {
+	t.Helper()
+
+	for _, key := range []string{"ROOTIO_API_URL", "ROOTIO_PKG_URL", "LOG_LEVEL", "ROOTIO_CA_CERT",
+		"ROOTIO_MIN_SEVERITY", "ROOTIO_EXCLUDE", "ROOTIO_API_KEY_FILE", "ROOTIO_REMEDIATE_PATH"} {
+		t.Setenv(key, "")
+		os.Unsetenv(key)
+	}
+	t.Setenv("ROOTIO_API_KEY", "env-key")
+	t.Setenv("HOME", t.TempDir())
+	t.Chdir(t.TempDir())
+}
+
+func TestLoadConfig_Defaults(t *testing.T) {
+	isolate(t)
+
+	cfg, err := LoadConfig("")
+	if err != nil {
+		t.Fatalf("LoadConfig failed: %v", err)
+	}
+	if cfg.APIURL != "https://api.root.io" || cfg.PKGURL != "https://pkg.root.io" || cfg.LogLevel != "info" {
+		t.Errorf("Unexpected defaults: %+v", cfg)
+	}
+	if cfg.File != "" {
+		t.Errorf("Expected no config file, got %s", cfg.File)
+	}
+}
+
+func TestLoadConfig_File(t *testing.T) {
+	isolate(t)
+	path := writeConfigFile(t, `
+api_url: https://api.example.com
+log_level: debug
+min_severity: high
+exclude:
+  - "@types/*"
+  - lodash
+`)
+	t.Setenv("LOG_LEVEL", "warn")
+
+	cfg, err := LoadConfig(path)
+	if err != nil {
+		t.Fatalf("LoadConfig failed: %v", err)
+	}
+
+	if cfg.APIURL != "https://api.example.com" {
+		t.Errorf("Expected api_url from file, got %s", cfg.APIURL)
+	}
+	if cfg.PKGURL != "https://pkg.root.io" {
+		t.Errorf("Expected default pkg_url, got %s", cfg.PKGURL)
+	}
+	if cfg.LogLevel != "warn" {
+		t.Errorf("Expected LOG_LEVEL env var to take precedence, got %s", cfg.LogLevel)
+	}
+	if cfg.MinSeverity != "high" {
+		t.Errorf("Expected min_severity from file, got %s", cfg.MinSeverity)
+	}
+	if !reflect.DeepEqual(cfg.Exclude, []string{"@types/*", "lodash"}) {
+		t.Errorf("Expected excludes from file, got %v", cfg.Exclude)
+	}
+	if cfg.File != path {
+		t.Errorf("Expected file %s, got %s", path, cfg.File)
+	}
+}
+
+func TestLoadConfig_RemediatePath(t *testing.T) {
+	isolate(t)
+	path := writeConfigFile(t, "remediate_path: /staging/v3/remediate/{ecosystem}\n")
+
+	cfg, err := LoadConfig(path)
+	if err != nil {
+		t.Fatalf("LoadConfig failed: %v", err)
+	}
+	if cfg.RemediatePath != "/staging/v3/remediate/{ecosystem}" {
+		t.Errorf("Expected remediate_path from file, got %q", cfg.RemediatePath)
+	}
+
+	t.Setenv("ROOTIO_REMEDIATE_PATH", "/v4/{ecosystem}")
+	cfg, err = LoadConfig(path)
+	if err != nil {
+		t.Fatalf("LoadConfig failed: %v", err)
+	}
+	if cfg.RemediatePath != "/v4/{ecosystem}" {
+		t.Errorf("Expected ROOTIO_REMEDIATE_PATH to take precedence, got %q", cfg.RemediatePath)
+	}
+}
+
+func TestLoadConfig_Auth(t *testing.T) {
+	isolate(t)
+	path := writeConfigFile(t, "auth_scheme: header\nauth_header: X-API-Key\n")
+
+	cfg, err := LoadConfig(path)
+	if err != nil {
+		t.Fatalf("LoadConfig failed: %v", err)
+	}
+	if cfg.AuthScheme != "header" || cfg.AuthHeader != "X-API-Key" {
+		t.Errorf("Expected auth_scheme and auth_header from file, got %q and %q", cfg.AuthScheme, cfg.AuthHeader)
+	}
+
+	t.Setenv("ROOTIO_AUTH_SCHEME", "bearer")
+	cfg, err = LoadConfig(path)
+	if err != nil {
+		t.Fatalf("LoadConfig failed: %v", err)
+	}
+	if cfg.AuthScheme != "bearer" {
+		t.Errorf("Expected ROOTIO_AUTH_SCHEME to take precedence, got %q", cfg.AuthScheme)
+	}
+}
+
+func TestLoadConfig_SearchOrder(t *testing.T) {
+	isolate(t)
+	home := os.Getenv("HOME")
+	if err := os.WriteFile(filepath.Join(home, FileName), []byte("log_level: error\n"), 0644); err != nil {
+		t.Fatalf("Failed to write home config: %v", err)
+	}
+
+	cfg, err := LoadConfig("")
+	if err != nil {
+		t.Fatalf("LoadConfig failed: %v", err)
+	}
+	if cfg.LogLevel != "error" {
+		t.Errorf("Expected home config to be used, got log level %s", cfg.LogLevel)
+	}
+
+	// A config file in the working directory wins over the home directory
+	if err := os.WriteFile(FileName, []byte("log_level: debug\n"), 0644); err != nil {
+		t.Fatalf("Failed to write local config: %v", err)
+	}
+	cfg, err = LoadConfig("")
+	if err != nil {
+		t.Fatalf("LoadConfig failed: %v", err)
+	}
+	if cfg.LogLevel != "debug" || cfg.File != FileName {
+		t.Errorf("Expected working directory config to be used, got %+v", cfg)
+	}
+}
+
+func TestLoadConfig_APIKeyInFile(t *testing.T) {
+	isolate(t)
+	path := writeConfigFile(t, "api_key: file-key\n")
+
+	cfg, err := LoadConfig(path)
+	if err != nil {
+		t.Fatalf("LoadConfig failed: %v", err)
+	}
+	if cfg.APIKey != "env-key" {
+		t.Errorf("Expected API key from the environment, got %s", cfg.APIKey)
+	}
+	if len(cfg.Warnings) != 1 || !strings.Contains(cfg.Warnings[0], "api_key") {
+		t.Errorf("Expected a warning about api_key, got %v", cfg.Warnings)
+	}
+}
+
+// writeKeyFile writes an API key file with the given permissions and returns its path
+func writeKeyFile(t *testing.T, content string, perm os.FileMode) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "api-key")
+	if err := os.WriteFile(path, []byte(content), perm); err != nil {
+		t.Fatalf("Failed to write key file: %v", err)
+	}
+	if err := os.Chmod(path, perm); err != nil {
+		t.Fatalf("Failed to set key file permissions: %v", err)
+	}
+	return path
+}
+
+func TestLoadConfig_APIKeyFile(t *testing.T) {
+	isolate(t)
+	os.Unsetenv("ROOTIO_API_KEY")
+	t.Setenv("ROOTIO_API_KEY_FILE", writeKeyFile(t, "  file-key\n\n", 0600))
+
+	cfg, err := LoadConfig("")
+	if err != nil {
+		t.Fatalf("LoadConfig failed: %v", err)
+	}
+	if cfg.APIKey != "file-key" {
+		t.Errorf("Expected the trimmed key from the file, got %q", cfg.APIKey)
+	}
+	if len(cfg.Warnings) != 0 {
+		t.Errorf("Expected no warnings for a private key file, got %v", cfg.Warnings)
+	}
+}
+
+func TestLoadConfig_APIKeyFileFromConfigFile(t *testing.T) {
+	isolate(t)
+	os.Unsetenv("ROOTIO_API_KEY")
+	path := writeConfigFile(t, "api_key_file: "+writeKeyFile(t, "file-key\n", 0600)+"\n")
+
+	cfg, err := LoadConfig(path)
+	if err != nil {
+		t.Fatalf("LoadConfig failed: %v", err)
+	}
+	if cfg.APIKey != "file-key" {
+		t.Errorf("Expected the key from api_key_file, got %q", cfg.APIKey)
+	}
+}
+
+func TestLoadConfig_APIKeyFileReadableByOthers(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("Windows doesn't report group and other permissions")
+	}
+	isolate(t)
+	os.Unsetenv("ROOTIO_API_KEY")
+	t.Setenv("ROOTIO_API_KEY_FILE", writeKeyFile(t, "file-key\n", 0644))
+
+	cfg, err := LoadConfig("")
+	if err != nil {
+		t.Fatalf("LoadConfig failed: %v", err)
+	}
+	if len(cfg.Warnings) != 1 || !strings.Contains(cfg.Warnings[0], "readable by other users") {
+		t.Errorf("Expected a warning about the file permissions, got %v", cfg.Warnings)
+	}
+}
+
+func TestLoadConfig_APIKeyStdin(t *testing.T) {
+	isolate(t)
+	os.Unsetenv("ROOTIO_API_KEY")
+
+	cfg, err := LoadConfig("", WithAPIKeyReader(strings.NewReader("stdin-key\r\n")))
+	if err != nil {
+		t.Fatalf("LoadConfig failed: %v", err)
+	}
+	if cfg.APIKey != "stdin-key" {
+		t.Errorf("Expected the trimmed key from stdin, got %q", cfg.APIKey)
+	}
+}
+
+func TestLoadConfig_APIKeySources(t *testing.T) {
+	tests := []struct {
+		name    string
+		env     string
+		file    string
+		stdin   string
+		wantErr string
+	}{
+		{"env and file conflict", "env-key", "file-key", "", "ROOTIO_API_KEY and ROOTIO_API_KEY_FILE"},
+		{"env and stdin conflict", "env-key", "", "stdin-key", "ROOTIO_API_KEY and --api-key-stdin"},
+		{"file and stdin conflict", "", "file-key", "stdin-key", "ROOTIO_API_KEY_FILE and --api-key-stdin"},
+		{"empty file", "", "\n", "", "empty"},
+		{"empty stdin", "", "", " ", "empty"},
+		{"several lines", "", "key\nmore\n", "", "single line"},
+		{"too large", "", strings.Repeat("k", maxAPIKeySize+1), "", "more than"},
+		{"no source", "", "", "", "no API key"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			isolate(t)
+			os.Unsetenv("ROOTIO_API_KEY")
+			if tt.env != "" {
+				t.Setenv("ROOTIO_API_KEY", tt.env)
+			}
+			if tt.file != "" {
+				t.Setenv("ROOTIO_API_KEY_FILE", writeKeyFile(t, tt.file, 0600))
+			}
+			var opts []LoadOption
+			if tt.stdin != "" {
+				opts = append(opts, WithAPIKeyReader(strings.NewReader(tt.stdin)))
+			}
+
+			_, err := LoadConfig("", opts...)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Expected an error containing %q, got: %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestLoadConfig_OptionalAPIKey(t *testing.T) {
+	isolate(t)
+	os.Unsetenv("ROOTIO_API_KEY")
+
+	cfg, err := LoadConfig("", WithOptionalAPIKey())
+	if err != nil {
+		t.Fatalf("Expected no error without an API key, got: %v", err)
+	}
+	if cfg.APIKey != "" {
+		t.Errorf("Expected no API key, got %q", cfg.APIKey)
+	}
+
+	// A configured key is still read, and conflicting sources are still an error
+	t.Setenv("ROOTIO_API_KEY", "env-key")
+	t.Setenv("ROOTIO_API_KEY_FILE", writeKeyFile(t, "file-key", 0600))
+	if _, err := LoadConfig("", WithOptionalAPIKey()); err == nil {
+		t.Error("Expected conflicting API key sources to be rejected")
+	}
+}
+
+func TestLoadConfig_APIKeyFileNotRegular(t *testing.T) {
+	isolate(t)
+	os.Unsetenv("ROOTIO_API_KEY")
+	t.Setenv("ROOTIO_API_KEY_FILE", t.TempDir())
+
+	if _, err := LoadConfig(""); err == nil || !strings.Contains(err.Error(), "not a regular file") {
+		t.Errorf("Expected an error for a directory, got: %v", err)
+	}
+}
+
+func TestLoadConfig_Errors(t *testing.T) {
+	isolate(t)
+
+	if _, err := LoadConfig(filepath.Join(t.TempDir(), "missing.yaml")); err == nil {
+		t.Error("Expected error for a missing explicit config file")
+	}
+	if _, err := LoadConfig(writeConfigFile(t, "api_ulr: typo\n")); err == nil {
+		t.Error("Expected error for an unknown key")
+	}
+
+	os.Unsetenv("ROOTIO_API_KEY")
+	if _, err := LoadConfig(""); err == nil {
+		t.Error("Expected error when ROOTIO_API_KEY is not set")
+	}
+}
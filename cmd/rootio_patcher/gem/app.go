@@ -0,0 +1,261 @@
+package gem
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+
+	"rootio_patcher/cmd/rootio_patcher/common"
+	"rootio_patcher/pkg/rootio"
+)
+
+// buildCommand installs the patched gems after Gemfile.lock is updated
+const buildCommand = "bundle install"
+
+// App handles RubyGems remediation (pre-install patching of Gemfile.lock)
+type App struct {
+	apiKey    string
+	apiURL    string
+	filePath  string
+	dryRun    bool
+	logger    *slog.Logger
+	parser    common.Parser
+	apiClient common.APIClient
+	reporter  *common.Reporter
+	options   common.Options
+	out       io.Writer
+
+	result *common.RunResult
+}
+
+// NewApp creates a new RubyGems application instance
+func NewApp(apiKey, apiURL, filePath string, dryRun bool, logger *slog.Logger, opts ...common.Option) *App {
+	return NewAppWithServices(
+		apiKey,
+		apiURL,
+		filePath,
+		dryRun,
+		logger,
+		NewParser(),
+		common.NewAPIClient(common.EcosystemRubyGems, apiURL, apiKey, logger, opts...),
+		opts...,
+	)
+}
+
+// NewAppWithServices creates a new RubyGems app with injected services (for testing)
+func NewAppWithServices(
+	apiKey, apiURL, filePath string,
+	dryRun bool,
+	logger *slog.Logger,
+	parser common.Parser,
+	apiClient common.APIClient,
+	opts ...common.Option,
+) *App {
+	options := common.NewOptions(opts...)
+	reporter := common.NewEcosystemReporter(common.EcosystemRubyGems, apiURL, logger,
+		common.WithBuildFile(filePath, buildCommand), common.WithWriter(options.Output()))
+
+	return &App{
+		apiKey:    apiKey,
+		apiURL:    apiURL,
+		filePath:  filePath,
+		dryRun:    dryRun,
+		logger:    logger,
+		parser:    parser,
+		apiClient: apiClient,
+		reporter:  reporter,
+		options:   options,
+		out:       options.Output(),
+	}
+}
+
+// Result returns the structured result of the last run
+func (a *App) Result() *common.RunResult {
+	return a.result
+}
+
+// RunWithResult runs the RubyGems remediation workflow and returns its structured result
+func (a *App) RunWithResult(ctx context.Context) (*common.RunResult, error) {
+	err := a.Run(ctx)
+	a.result.SetError(err)
+	return a.result, err
+}
+
+// Run executes the RubyGems remediation workflow
+func (a *App) Run(ctx context.Context) error {
+	a.logger.DebugContext(ctx, "Starting RubyGems remediation",
+		slog.String("file", a.filePath),
+		slog.Bool("dry_run", a.dryRun))
+	a.result = common.NewRunResult(common.EcosystemRubyGems, a.filePath, a.dryRun)
+
+	// 1. Check if file exists
+	if _, err := os.Stat(a.filePath); err != nil {
+		return fmt.Errorf("file not found: %s", a.filePath)
+	}
+
+	// 2. Parse Gemfile.lock
+	a.logger.DebugContext(ctx, "Parsing Gemfile.lock")
+	packages, err := a.parser.Parse(ctx, a.filePath)
+	if err != nil {
+		return fmt.Errorf("failed to parse %s: %w", a.filePath, err)
+	}
+	a.logger.DebugContext(ctx, "Parsed packages", slog.Int("count", len(packages)))
+	a.options.Progress.Printf("Found %d packages in %s", len(packages), a.filePath)
+	a.result.PackagesFound = len(packages)
+
+	if len(packages) == 0 {
+		fmt.Fprintf(a.out, "\nNo packages found in %s\n", a.filePath)
+		return nil
+	}
+
+	// 3. Convert to SDK format
+	sdkPackages := make([]rootio.Package, len(packages))
+	for i, pkg := range packages {
+		sdkPackages[i] = pkg.SDKPackage()
+	}
+
+	// 4. Call backend API to analyze vulnerabilities
+	a.logger.DebugContext(ctx, "Analyzing packages for vulnerabilities")
+	a.options.Progress.Printf("Analyzing %d packages for vulnerabilities...", len(sdkPackages))
+	response, err := a.apiClient.AnalyzePackages(ctx, sdkPackages)
+	if err != nil {
+		return fmt.Errorf("failed to analyze packages: %w", err)
+	}
+
+	// 5. Log analysis results
+	a.logger.DebugContext(ctx, "Vulnerability analysis complete",
+		slog.Int("patches_available", len(response.Patches)),
+		slog.Int("packages_skipped", len(response.Skipped)))
+
+	// Drop invalid names, patches without a version to install, patches left out by
+	// --min-severity, --only, --exclude and --cve, and patches that aren't upgrades
+	patches, filterSkipped := common.FilterPatches(common.EcosystemRubyGems, response.Patches, false, a.options)
+	response.Patches = patches
+	response.Skipped = append(response.Skipped, filterSkipped...)
+	common.WriteCVEMatches(a.out, response.Patches, a.options.CVEs)
+	a.result.AddSkipped(response.Skipped)
+	a.reporter.ReportSkipped(response.Skipped)
+
+	if len(response.Patches) == 0 {
+		fmt.Fprintln(a.out, "\nNo patches needed - all packages are up to date!")
+		return nil
+	}
+
+	// 6. Execute or dry-run patches
+	if a.dryRun {
+		a.logger.DebugContext(ctx, "DRY-RUN MODE: No changes will be made")
+		a.options.Plan.Add(common.EcosystemRubyGems, a.filePath, false, sdkPackages, response.Patches)
+		a.result.AddPatches(response.Patches, false, common.PatchStatusDryRun)
+		diff, err := a.proposedDiff(ctx, response.Patches)
+		if err != nil {
+			return err
+		}
+		a.reporter.ReportDryRunWithDiff(response.Patches, false, diff)
+		return nil
+	}
+
+	// 7. Apply patches by updating Gemfile.lock
+	// Ask before changing anything, unless --yes was given
+	if err := a.options.Confirm.Confirm(len(response.Patches), a.filePath); err != nil {
+		a.result.AddPatches(response.Patches, false, common.PatchStatusNotApplied)
+		return err
+	}
+
+	fmt.Fprintf(a.out, "\nApplying %d patches to %s...\n\n", len(response.Patches), a.filePath)
+	a.result.AddPatches(response.Patches, false, common.PatchStatusPending)
+	if err := a.applyPatches(ctx, response.Patches); err != nil {
+		a.result.SetAllPatchStatus(common.PatchStatusFailed, err)
+		return err
+	}
+	a.result.SetAllPatchStatus(common.PatchStatusApplied, nil)
+
+	a.reporter.ReportNextSteps(len(response.Patches))
+	a.reporter.ReportFixedCVEs(a.result.RecordFixedCVEs())
+
+	if a.options.Verify {
+		return a.verify(ctx)
+	}
+
+	return nil
+}
+
+// verify parses the updated Gemfile.lock again and confirms no patches remain for its versions
+func (a *App) verify(ctx context.Context) error {
+	packages, err := a.parser.Parse(ctx, a.filePath)
+	if err != nil {
+		return fmt.Errorf("failed to parse %s for verification: %w", a.filePath, err)
+	}
+
+	return common.VerifyPatches(ctx, common.EcosystemRubyGems, a.apiClient,
+		common.VerifyPackages(packages, a.options), a.options, a.result)
+}
+
+// patchUpdates maps each gem name to its patched version
+func patchUpdates(patches []rootio.PackagePatch) map[string]string {
+	updates := make(map[string]string)
+	for _, patch := range patches {
+		updates[patch.PackageName] = patch.Patch.Version
+	}
+	return updates
+}
+
+// proposedDiff renders the change applyPatches would make to Gemfile.lock as a unified diff
+func (a *App) proposedDiff(ctx context.Context, patches []rootio.PackagePatch) (string, error) {
+	original, err := os.ReadFile(a.filePath)
+	if err != nil {
+		return "", fmt.Errorf("failed to read file: %w", err)
+	}
+
+	updatedContent, err := a.parser.Update(ctx, a.filePath, patchUpdates(patches))
+	if err != nil {
+		return "", fmt.Errorf("failed to update file: %w", err)
+	}
+
+	return common.UnifiedDiff(a.filePath, string(original), updatedContent), nil
+}
+
+// applyPatches updates Gemfile.lock with patched versions
+func (a *App) applyPatches(ctx context.Context, patches []rootio.PackagePatch) error {
+	updates := patchUpdates(patches)
+	for i, patch := range patches {
+		fmt.Fprintf(a.out, "[%d/%d] %s: %s → %s\n", i+1, len(patches), patch.PackageName, patch.Version, patch.Patch.Version)
+	}
+
+	// Hold the lock from reading the file until it's written, so concurrent runs don't lose updates
+	unlock, err := common.LockFiles(ctx, []string{a.filePath}, a.options.LockTimeout)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	// Update the file
+	a.logger.DebugContext(ctx, "Updating Gemfile.lock", slog.Int("updates", len(updates)))
+	updatedContent, err := a.parser.Update(ctx, a.filePath, updates)
+	if err != nil {
+		return fmt.Errorf("failed to update file: %w", err)
+	}
+
+	// Validate the updated content
+	if !a.parser.Validate(updatedContent) {
+		return fmt.Errorf("updated file content is invalid")
+	}
+
+	if a.options.Backup {
+		backupPath, err := common.BackupFile(a.filePath)
+		if err != nil {
+			return fmt.Errorf("failed to back up file: %w", err)
+		}
+		a.logger.InfoContext(ctx, "Backed up file before patching",
+			slog.String("file", a.filePath),
+			slog.String("backup", backupPath))
+	}
+
+	// Write the updated content back to the file
+	if err := os.WriteFile(a.filePath, []byte(updatedContent), 0644); err != nil {
+		return fmt.Errorf("failed to write updated file: %w", err)
+	}
+
+	return nil
+}
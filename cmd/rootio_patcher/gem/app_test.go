@@ -0,0 +1,321 @@
+package gem
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+
+	"rootio_patcher/cmd/rootio_patcher/common"
+	"rootio_patcher/pkg/rootio"
+)
+
+// rackPatchClient returns a patch for rack and records the analyzed packages
+func rackPatchClient(analyzed *[]rootio.Package) *MockAPIClient {
+	return &MockAPIClient{
+		AnalyzePackagesFunc: func(ctx context.Context, packages []rootio.Package) (*rootio.AnalyzePackagesResponse, error) {
+			*analyzed = packages
+			return &rootio.AnalyzePackagesResponse{
+				Patches: []rootio.PackagePatch{
+					{PackageName: "rack", Version: "2.2.4", Patch: rootio.PatchInfo{Name: "rack", Version: "2.2.8.1"}},
+				},
+			}, nil
+		},
+	}
+}
+
+func TestGemApp_Run_FileNotFound(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+
+	app := NewAppWithServices("test-key", "https://api.root.io", "/nonexistent/Gemfile.lock", true, logger,
+		NewParser(), &MockAPIClient{})
+	if err := app.Run(context.Background()); err == nil {
+		t.Fatal("Expected error for nonexistent file, got nil")
+	}
+}
+
+func TestGemApp_Run_APIError(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+	path := copyFixture(t)
+
+	mockAPIClient := &MockAPIClient{
+		AnalyzePackagesFunc: func(ctx context.Context, packages []rootio.Package) (*rootio.AnalyzePackagesResponse, error) {
+			return nil, errors.New("API error")
+		},
+	}
+
+	app := NewAppWithServices("test-key", "https://api.root.io", path, false, logger, NewParser(), mockAPIClient)
+	if err := app.Run(context.Background()); err == nil {
+		t.Fatal("Expected error from API, got nil")
+	}
+}
+
+func TestGemApp_Run_DryRun(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+	path := copyFixture(t)
+	original, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Failed to read Gemfile.lock: %v", err)
+	}
+
+	var analyzed []rootio.Package
+	app := NewAppWithServices("test-key", "https://api.root.io", path, true, logger, NewParser(), rackPatchClient(&analyzed))
+	if err := app.Run(context.Background()); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	if len(analyzed) != 9 {
+		t.Errorf("Expected 9 gems to be analyzed, got %v", analyzed)
+	}
+	for _, pkg := range analyzed {
+		if pkg.Ecosystem != string(common.EcosystemRubyGems) {
+			t.Errorf("Expected rubygems ecosystem, got %q", pkg.Ecosystem)
+		}
+	}
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Failed to read Gemfile.lock: %v", err)
+	}
+	if string(content) != string(original) {
+		t.Errorf("Expected dry-run to leave Gemfile.lock unchanged, got:\n%s", content)
+	}
+}
+
+func TestGemApp_Run_ApplyPatches(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+	path := copyFixture(t)
+
+	var analyzed []rootio.Package
+	app := NewAppWithServices("test-key", "https://api.root.io", path, false, logger, NewParser(), rackPatchClient(&analyzed),
+		common.WithBackup(true))
+	if err := app.Run(context.Background()); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Failed to read Gemfile.lock: %v", err)
+	}
+	if !strings.Contains(string(content), "\n    rack (2.2.8.1)\n") {
+		t.Errorf("Expected rack to be updated, got:\n%s", content)
+	}
+	if _, err := os.Stat(path + ".rootio.bak"); err != nil {
+		t.Errorf("Expected a backup of Gemfile.lock: %v", err)
+	}
+	if status := app.Result().Patches[0].Status; status != common.PatchStatusApplied {
+		t.Errorf("Expected applied status, got %s", status)
+	}
+}
+
+func TestGemApp_Run_Confirm(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+
+	tests := []struct {
+		name        string
+		confirm     *common.Confirmer
+		wantErr     error
+		wantStatus  common.PatchStatus
+		wantUpdated bool
+	}{
+		{
+			name:        "answered yes",
+			confirm:     common.NewConfirmer(strings.NewReader("y\n"), io.Discard, true),
+			wantStatus:  common.PatchStatusApplied,
+			wantUpdated: true,
+		},
+		{
+			name:       "answered no",
+			confirm:    common.NewConfirmer(strings.NewReader("n\n"), io.Discard, true),
+			wantErr:    common.ErrNotConfirmed,
+			wantStatus: common.PatchStatusNotApplied,
+		},
+		{
+			name:       "no terminal",
+			confirm:    common.NewConfirmer(strings.NewReader("y\n"), io.Discard, false),
+			wantErr:    common.ErrConfirmationRequired,
+			wantStatus: common.PatchStatusNotApplied,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := copyFixture(t)
+
+			var analyzed []rootio.Package
+			app := NewAppWithServices("test-key", "https://api.root.io", path, false, logger, NewParser(), rackPatchClient(&analyzed),
+				common.WithConfirm(tt.confirm))
+			if err := app.Run(context.Background()); !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Expected error %v, got: %v", tt.wantErr, err)
+			}
+
+			content, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatalf("Failed to read Gemfile.lock: %v", err)
+			}
+			if updated := strings.Contains(string(content), "\n    rack (2.2.8.1)\n"); updated != tt.wantUpdated {
+				t.Errorf("Expected rack updated=%v, got:\n%s", tt.wantUpdated, content)
+			}
+			if status := app.Result().Patches[0].Status; status != tt.wantStatus {
+				t.Errorf("Expected %s status, got %s", tt.wantStatus, status)
+			}
+		})
+	}
+}
+
+func TestGemApp_Run_ConcurrentRunsKeepBothPatches(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+	path := copyFixture(t)
+
+	patches := []rootio.PackagePatch{
+		{PackageName: "rack", Version: "2.2.4", Patch: rootio.PatchInfo{Name: "rack", Version: "2.2.8.1"}},
+		{PackageName: "tilt", Version: "2.0.11", Patch: rootio.PatchInfo{Name: "tilt", Version: "2.0.12"}},
+	}
+
+	// Both runs parse the original file, then patch it at the same time
+	var wg sync.WaitGroup
+	errs := make([]error, len(patches))
+	for i, patch := range patches {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			client := &MockAPIClient{
+				AnalyzePackagesFunc: func(ctx context.Context, packages []rootio.Package) (*rootio.AnalyzePackagesResponse, error) {
+					return &rootio.AnalyzePackagesResponse{Patches: []rootio.PackagePatch{patch}}, nil
+				},
+			}
+			app := NewAppWithServices("test-key", "https://api.root.io", path, false, logger, NewParser(), client)
+			errs[i] = app.Run(context.Background())
+		}()
+	}
+	wg.Wait()
+
+	for _, err := range errs {
+		if err != nil {
+			t.Fatalf("Expected no error, got: %v", err)
+		}
+	}
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Failed to read Gemfile.lock: %v", err)
+	}
+	for _, want := range []string{"\n    rack (2.2.8.1)\n", "\n    tilt (2.0.12)\n"} {
+		if !strings.Contains(string(content), want) {
+			t.Errorf("Expected %q in Gemfile.lock, got:\n%s", strings.TrimSpace(want), content)
+		}
+	}
+	if _, err := os.Stat(path + common.LockSuffix); !os.IsNotExist(err) {
+		t.Errorf("Expected the lock file to be removed, got: %v", err)
+	}
+}
+
+func TestGemApp_Run_Verify(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+	path := copyFixture(t)
+
+	// The first analysis finds rack; the second sees the updated Gemfile.lock and finds nothing
+	var analyses [][]rootio.Package
+	mockAPIClient := &MockAPIClient{
+		AnalyzePackagesFunc: func(ctx context.Context, packages []rootio.Package) (*rootio.AnalyzePackagesResponse, error) {
+			analyses = append(analyses, packages)
+			if len(analyses) > 1 {
+				return &rootio.AnalyzePackagesResponse{}, nil
+			}
+			return &rootio.AnalyzePackagesResponse{
+				Patches: []rootio.PackagePatch{
+					{PackageName: "rack", Version: "2.2.4", Patch: rootio.PatchInfo{Name: "rack", Version: "2.2.8.1"}},
+				},
+			}, nil
+		},
+	}
+
+	app := NewAppWithServices("test-key", "https://api.root.io", path, false, logger, NewParser(), mockAPIClient,
+		common.WithVerify(true))
+	if err := app.Run(context.Background()); err != nil {
+		t.Fatalf("Expected verification to pass, got: %v", err)
+	}
+
+	if len(analyses) != 2 {
+		t.Fatalf("Expected the gems to be analyzed twice, got %d", len(analyses))
+	}
+	for _, pkg := range analyses[1] {
+		if pkg.Name == "rack" && pkg.Version != "2.2.8.1" {
+			t.Errorf("Expected the patched rack version to be verified, got %s", pkg.Version)
+		}
+	}
+}
+
+func TestGemApp_Run_VerifyFailsWhenPatchesRemain(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+	path := copyFixture(t)
+
+	// rackPatchClient keeps returning the rack patch, as if the update didn't take
+	var analyzed []rootio.Package
+	app := NewAppWithServices("test-key", "https://api.root.io", path, false, logger, NewParser(), rackPatchClient(&analyzed),
+		common.WithVerify(true))
+	err := app.Run(context.Background())
+	if err == nil {
+		t.Fatal("Expected verification to fail while a patch remains")
+	}
+	if !strings.Contains(err.Error(), "rack") {
+		t.Errorf("Expected the error to name rack, got: %v", err)
+	}
+	if unresolved := app.Result().Unresolved; len(unresolved) != 1 || unresolved[0] != "rack" {
+		t.Errorf("Expected rack to be unresolved, got %v", unresolved)
+	}
+}
+
+func TestGemApp_Run_PlanThenApply(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+	path := copyFixture(t)
+
+	// The dry run records its patches in the plan
+	plan := common.NewPlan()
+	var analyzed []rootio.Package
+	dryRun := NewAppWithServices("test-key", "https://api.root.io", path, true, logger, NewParser(), rackPatchClient(&analyzed),
+		common.WithPlan(plan))
+	if err := dryRun.Run(context.Background()); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if len(plan.Entries) != 1 || plan.Entries[0].File != path || len(plan.Entries[0].Patches) != 1 {
+		t.Fatalf("Expected one plan entry for %s, got %+v", path, plan.Entries)
+	}
+
+	// Applying the plan uses the planned patches; the API URL is unreachable
+	apply := NewApp("test-key", "http://127.0.0.1:0", path, false, logger, common.WithPlanEntry(&plan.Entries[0]))
+	if err := apply.Run(context.Background()); err != nil {
+		t.Fatalf("Expected the plan to apply, got: %v", err)
+	}
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Failed to read Gemfile.lock: %v", err)
+	}
+	if !strings.Contains(string(content), "\n    rack (2.2.8.1)\n") {
+		t.Errorf("Expected rack to be updated from the plan, got:\n%s", content)
+	}
+}
+
+func TestGemApp_Run_WritesReportToOutput(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	path := copyFixture(t)
+
+	var buf strings.Builder
+	var analyzed []rootio.Package
+	app := NewAppWithServices("test-key", "https://api.root.io", path, true, logger, NewParser(), rackPatchClient(&analyzed),
+		common.WithOutput(&buf))
+	if err := app.Run(context.Background()); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	for _, want := range []string{"rack", "2.2.4", "2.2.8.1", "DRY-RUN MODE"} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("Expected %q in the report, got:\n%s", want, buf.String())
+		}
+	}
+}
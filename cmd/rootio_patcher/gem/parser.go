@@ -0,0 +1,174 @@
+package gem
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"rootio_patcher/cmd/rootio_patcher/common"
+)
+
+const gemfileLock = "Gemfile.lock"
+
+// specPattern matches a gem in the specs list, indented four spaces: "    rack (2.2.4)".
+// Dependencies of a spec are indented six spaces and are not matched.
+var specPattern = regexp.MustCompile(`^    ([^\s(]+) \(([^)]+)\)$`)
+
+// dependencyPattern matches a top-level gem under DEPENDENCIES: "  rails (~> 7.0)" or "  mylib!"
+var dependencyPattern = regexp.MustCompile(`^  ([^\s(!]+)`)
+
+// GemfileLockParser handles parsing of Bundler Gemfile.lock files
+type GemfileLockParser struct{}
+
+// NewParser creates a new Gemfile.lock parser
+func NewParser() *GemfileLockParser {
+	return &GemfileLockParser{}
+}
+
+// Ecosystem returns the ecosystem name
+func (p *GemfileLockParser) Ecosystem() common.Ecosystem {
+	return common.EcosystemRubyGems
+}
+
+// FilePatterns returns file patterns this parser handles
+func (p *GemfileLockParser) FilePatterns() []string {
+	return []string{gemfileLock}
+}
+
+// CanHandle checks if this parser can handle the given file
+func (p *GemfileLockParser) CanHandle(fileName string) bool {
+	return filepath.Base(fileName) == gemfileLock
+}
+
+// splitPlatform separates a locked version from its platform suffix (1.13.10-x86_64-linux).
+// RubyGems versions never contain a dash, so everything after the first one is the platform.
+func splitPlatform(version string) (string, string) {
+	if idx := strings.Index(version, "-"); idx >= 0 {
+		return version[:idx], version[idx:]
+	}
+	return version, ""
+}
+
+// isSection reports whether a line starts a new top-level section (GEM, GIT, PLATFORMS, ...)
+func isSection(line string) bool {
+	return line != "" && line[0] != ' '
+}
+
+// gemSpecs calls fn for every line of the GEM section's specs list that locks a gem.
+// Gems from GIT and PATH sources have their own specs lists and are not included.
+func gemSpecs(lines []string, fn func(i int, name, version string)) {
+	section := ""
+	inSpecs := false
+
+	for i, line := range lines {
+		if isSection(line) {
+			section = strings.TrimSpace(line)
+			inSpecs = false
+			continue
+		}
+		if section != "GEM" {
+			continue
+		}
+		if strings.TrimSpace(line) == "specs:" {
+			inSpecs = true
+			continue
+		}
+		if !inSpecs {
+			continue
+		}
+		if match := specPattern.FindStringSubmatch(line); match != nil {
+			fn(i, match[1], match[2])
+		}
+	}
+}
+
+// directDependencies returns the gems listed under DEPENDENCIES, i.e. declared in the Gemfile
+func directDependencies(lines []string) map[string]bool {
+	direct := make(map[string]bool)
+	section := ""
+
+	for _, line := range lines {
+		if isSection(line) {
+			section = strings.TrimSpace(line)
+			continue
+		}
+		if section != "DEPENDENCIES" {
+			continue
+		}
+		if match := dependencyPattern.FindStringSubmatch(line); match != nil {
+			direct[match[1]] = true
+		}
+	}
+	return direct
+}
+
+// Parse reads the gems locked in the GEM section. Gems listed under DEPENDENCIES are direct,
+// the rest are transitive. A gem locked for several platforms is reported once.
+func (p *GemfileLockParser) Parse(ctx context.Context, filePath string) ([]common.PackageInfo, error) {
+	content, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read file: %w", err)
+	}
+
+	lines := strings.Split(string(content), "\n")
+	direct := directDependencies(lines)
+
+	var packages []common.PackageInfo
+	seen := make(map[string]bool)
+	gemSpecs(lines, func(i int, name, version string) {
+		if seen[name] {
+			return
+		}
+		seen[name] = true
+
+		version, _ = splitPlatform(version)
+		packages = append(packages, common.PackageInfo{
+			Name:      name,
+			Version:   version,
+			Ecosystem: common.EcosystemRubyGems,
+			Direct:    direct[name],
+			Location:  filePath,
+		})
+	})
+
+	return packages, nil
+}
+
+// Update rewrites the (version) of each updated gem in the specs list, keeping the platform
+// suffix and the fixed indentation Bundler requires. Dependency constraints are left alone.
+func (p *GemfileLockParser) Update(ctx context.Context, filePath string, updates map[string]string) (string, error) {
+	content, err := os.ReadFile(filePath)
+	if err != nil {
+		return "", fmt.Errorf("failed to read file: %w", err)
+	}
+
+	lines := strings.Split(string(content), "\n")
+	gemSpecs(lines, func(i int, name, version string) {
+		newVersion, ok := updates[name]
+		if !ok {
+			return
+		}
+		_, platform := splitPlatform(version)
+		lines[i] = fmt.Sprintf("    %s (%s%s)", name, newVersion, platform)
+	})
+
+	return strings.Join(lines, "\n"), nil
+}
+
+// Validate checks that the content has a GEM section with a specs list
+func (p *GemfileLockParser) Validate(content string) bool {
+	section := ""
+	for _, line := range strings.Split(content, "\n") {
+		if isSection(line) {
+			section = strings.TrimSpace(line)
+			continue
+		}
+		if section == "GEM" && strings.TrimSpace(line) == "specs:" {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,135 @@
+package gem
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// copyFixture copies testdata/Gemfile.lock to a temporary directory and returns its path
+func copyFixture(t *testing.T) string {
+	t.Helper()
+
+	content, err := os.ReadFile(filepath.Join("testdata", "Gemfile.lock"))
+	if err != nil {
+		t.Fatalf("Failed to read fixture: %v", err)
+	}
+	path := filepath.Join(t.TempDir(), "Gemfile.lock")
+	if err := os.WriteFile(path, content, 0644); err != nil {
+		t.Fatalf("Failed to write Gemfile.lock: %v", err)
+	}
+	return path
+}
+
+func TestGemfileLockParser_CanHandle(t *testing.T) {
+	parser := NewParser()
+
+	tests := []struct {
+		fileName string
+		expected bool
+	}{
+		{"Gemfile.lock", true},
+		{"app/Gemfile.lock", true},
+		{"Gemfile", false},
+		{"gems.locked", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.fileName, func(t *testing.T) {
+			if result := parser.CanHandle(tt.fileName); result != tt.expected {
+				t.Errorf("CanHandle(%s) = %v, expected %v", tt.fileName, result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestGemfileLockParser_Parse(t *testing.T) {
+	path := filepath.Join("testdata", "Gemfile.lock")
+
+	packages, err := NewParser().Parse(context.Background(), path)
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+
+	// internal_tools comes from a GIT source and nokogiri is reported once across platforms
+	expected := []struct {
+		name    string
+		version string
+		direct  bool
+	}{
+		{"mini_portile2", "2.8.1", false},
+		{"mustermann", "3.0.0", false},
+		{"nokogiri", "1.13.10", true},
+		{"racc", "1.6.2", false},
+		{"rack", "2.2.4", false},
+		{"rack-protection", "3.0.5", false},
+		{"ruby2_keywords", "0.0.5", false},
+		{"sinatra", "3.0.5", true},
+		{"tilt", "2.0.11", false},
+	}
+
+	if len(packages) != len(expected) {
+		t.Fatalf("Expected %d packages, got %d: %+v", len(expected), len(packages), packages)
+	}
+
+	for i, exp := range expected {
+		pkg := packages[i]
+		if pkg.Name != exp.name || pkg.Version != exp.version || pkg.Direct != exp.direct || pkg.Location != path {
+			t.Errorf("Package %d: expected %+v, got %+v", i, exp, pkg)
+		}
+	}
+}
+
+func TestGemfileLockParser_Update(t *testing.T) {
+	parser := NewParser()
+	path := copyFixture(t)
+	original, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Failed to read Gemfile.lock: %v", err)
+	}
+
+	updated, err := parser.Update(context.Background(), path, map[string]string{
+		"rack":     "2.2.8.1",
+		"nokogiri": "1.16.5",
+	})
+	if err != nil {
+		t.Fatalf("Update failed: %v", err)
+	}
+	if !parser.Validate(updated) {
+		t.Fatalf("Expected updated Gemfile.lock to be valid, got:\n%s", updated)
+	}
+
+	// Only spec lines change; dependency constraints and the GIT source keep their versions
+	expected := strings.NewReplacer(
+		"    rack (2.2.4)\n", "    rack (2.2.8.1)\n",
+		"    nokogiri (1.13.10)\n", "    nokogiri (1.16.5)\n",
+		"    nokogiri (1.13.10-x86_64-linux)\n", "    nokogiri (1.16.5-x86_64-linux)\n",
+	).Replace(string(original))
+	if updated != expected {
+		t.Errorf("Expected:\n%s\ngot:\n%s", expected, updated)
+	}
+}
+
+func TestGemfileLockParser_Validate(t *testing.T) {
+	parser := NewParser()
+
+	tests := []struct {
+		name     string
+		content  string
+		expected bool
+	}{
+		{"valid", "GEM\n  remote: https://rubygems.org/\n  specs:\n    rack (2.2.4)\n", true},
+		{"no GEM section", "PLATFORMS\n  ruby\n", false},
+		{"specs outside GEM", "GIT\n  specs:\n    tools (0.1.0)\n", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if result := parser.Validate(tt.content); result != tt.expected {
+				t.Errorf("Validate() = %v, expected %v", result, tt.expected)
+			}
+		})
+	}
+}
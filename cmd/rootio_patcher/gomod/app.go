@@ -0,0 +1,301 @@
+package gomod
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+
+	"rootio_patcher/cmd/rootio_patcher/common"
+	"rootio_patcher/pkg/rootio"
+)
+
+// buildCommand refreshes go.sum and rebuilds the module after patching
+const buildCommand = "go mod tidy && go build ./..."
+
+// App handles Go module remediation (pre-install patching of go.mod)
+type App struct {
+	apiKey    string
+	apiURL    string
+	filePath  string
+	dryRun    bool
+	logger    *slog.Logger
+	parser    common.Parser
+	apiClient common.APIClient
+	reporter  *common.Reporter
+	options   common.Options
+	out       io.Writer
+
+	result *common.RunResult
+}
+
+// NewApp creates a new Go application instance
+func NewApp(apiKey, apiURL, filePath string, dryRun bool, logger *slog.Logger, opts ...common.Option) *App {
+	return NewAppWithServices(
+		apiKey,
+		apiURL,
+		filePath,
+		dryRun,
+		logger,
+		NewParser(),
+		common.NewAPIClient(common.EcosystemGo, apiURL, apiKey, logger, opts...),
+		opts...,
+	)
+}
+
+// NewAppWithServices creates a new Go app with injected services (for testing)
+func NewAppWithServices(
+	apiKey, apiURL, filePath string,
+	dryRun bool,
+	logger *slog.Logger,
+	parser common.Parser,
+	apiClient common.APIClient,
+	opts ...common.Option,
+) *App {
+	options := common.NewOptions(opts...)
+	reporter := common.NewEcosystemReporter(common.EcosystemGo, apiURL, logger,
+		common.WithBuildFile(filePath, buildCommand), common.WithWriter(options.Output()))
+
+	return &App{
+		apiKey:    apiKey,
+		apiURL:    apiURL,
+		filePath:  filePath,
+		dryRun:    dryRun,
+		logger:    logger,
+		parser:    parser,
+		apiClient: apiClient,
+		reporter:  reporter,
+		options:   options,
+		out:       options.Output(),
+	}
+}
+
+// Result returns the structured result of the last run
+func (a *App) Result() *common.RunResult {
+	return a.result
+}
+
+// RunWithResult runs the Go remediation workflow and returns its structured result
+func (a *App) RunWithResult(ctx context.Context) (*common.RunResult, error) {
+	err := a.Run(ctx)
+	a.result.SetError(err)
+	return a.result, err
+}
+
+// Run executes the Go remediation workflow
+func (a *App) Run(ctx context.Context) error {
+	a.logger.DebugContext(ctx, "Starting Go remediation",
+		slog.String("file", a.filePath),
+		slog.Bool("dry_run", a.dryRun),
+		slog.Bool("replace", a.useReplace()))
+	a.result = common.NewRunResult(common.EcosystemGo, a.filePath, a.dryRun)
+
+	// 1. Check if file exists
+	if _, err := os.Stat(a.filePath); err != nil {
+		return fmt.Errorf("file not found: %s", a.filePath)
+	}
+
+	// 2. Parse go.mod
+	a.logger.DebugContext(ctx, "Parsing go.mod")
+	packages, err := a.parser.Parse(ctx, a.filePath)
+	if err != nil {
+		return fmt.Errorf("failed to parse %s: %w", a.filePath, err)
+	}
+	a.logger.DebugContext(ctx, "Parsed packages", slog.Int("count", len(packages)))
+	a.options.Progress.Printf("Found %d packages in %s", len(packages), a.filePath)
+	a.result.PackagesFound = len(packages)
+
+	if len(packages) == 0 {
+		fmt.Fprintf(a.out, "\nNo packages found in %s\n", a.filePath)
+		return nil
+	}
+
+	// 3. Convert to SDK format
+	sdkPackages := make([]rootio.Package, len(packages))
+	for i, pkg := range packages {
+		sdkPackages[i] = pkg.SDKPackage()
+	}
+
+	// 4. Call backend API to analyze vulnerabilities
+	a.logger.DebugContext(ctx, "Analyzing packages for vulnerabilities")
+	a.options.Progress.Printf("Analyzing %d packages for vulnerabilities...", len(sdkPackages))
+	response, err := a.apiClient.AnalyzePackages(ctx, sdkPackages)
+	if err != nil {
+		return fmt.Errorf("failed to analyze packages: %w", err)
+	}
+
+	// 5. Log analysis results
+	a.logger.DebugContext(ctx, "Vulnerability analysis complete",
+		slog.Int("patches_available", len(response.Patches)),
+		slog.Int("packages_skipped", len(response.Skipped)))
+
+	// Drop invalid names, patches without a version to install, patches left out by
+	// --min-severity, --only, --exclude and --cve, and patches that aren't upgrades
+	patches, filterSkipped := common.FilterPatches(common.EcosystemGo, response.Patches, a.useReplace(), a.options)
+	response.Patches = patches
+	response.Skipped = append(response.Skipped, filterSkipped...)
+	common.WriteCVEMatches(a.out, response.Patches, a.options.CVEs)
+	a.result.AddSkipped(response.Skipped)
+	a.reporter.ReportSkipped(response.Skipped)
+
+	if len(response.Patches) == 0 {
+		fmt.Fprintln(a.out, "\nNo patches needed - all packages are up to date!")
+		return nil
+	}
+
+	// Replaced modules are fetched from the Root.io proxy, so the build needs it configured
+	if a.useReplace() {
+		common.WithBuildFile(a.filePath, a.proxyBuildCommand(response.Patches))(a.reporter)
+	}
+
+	// 6. Execute or dry-run patches
+	if a.dryRun {
+		a.logger.DebugContext(ctx, "DRY-RUN MODE: No changes will be made")
+		a.options.Plan.Add(common.EcosystemGo, a.filePath, a.useReplace(), sdkPackages, response.Patches)
+		a.result.AddPatches(response.Patches, a.useReplace(), common.PatchStatusDryRun)
+		diff, err := a.proposedDiff(ctx, response.Patches)
+		if err != nil {
+			return err
+		}
+		a.reporter.ReportDryRunWithDiff(response.Patches, a.useReplace(), diff)
+		return nil
+	}
+
+	// 7. Apply patches by updating go.mod
+	// Ask before changing anything, unless --yes was given
+	if err := a.options.Confirm.Confirm(len(response.Patches), a.filePath); err != nil {
+		a.result.AddPatches(response.Patches, a.useReplace(), common.PatchStatusNotApplied)
+		return err
+	}
+
+	fmt.Fprintf(a.out, "\nApplying %d patches to %s...\n\n", len(response.Patches), a.filePath)
+	a.result.AddPatches(response.Patches, a.useReplace(), common.PatchStatusPending)
+	if err := a.applyPatches(ctx, response.Patches); err != nil {
+		a.result.SetAllPatchStatus(common.PatchStatusFailed, err)
+		return err
+	}
+	a.result.SetAllPatchStatus(common.PatchStatusApplied, nil)
+
+	a.reporter.ReportNextSteps(len(response.Patches))
+	a.reporter.ReportFixedCVEs(a.result.RecordFixedCVEs())
+
+	if a.options.Verify {
+		return a.verify(ctx)
+	}
+
+	return nil
+}
+
+// verify parses the updated go.mod again and confirms no patches remain for its versions
+func (a *App) verify(ctx context.Context) error {
+	// Replaced modules keep their original require version, so they'd always be reported again
+	if a.useReplace() {
+		fmt.Fprintln(a.out, "\nSkipping verification: replace directives keep the original required versions")
+		return nil
+	}
+
+	packages, err := a.parser.Parse(ctx, a.filePath)
+	if err != nil {
+		return fmt.Errorf("failed to parse %s for verification: %w", a.filePath, err)
+	}
+
+	return common.VerifyPatches(ctx, common.EcosystemGo, a.apiClient,
+		common.VerifyPackages(packages, a.options), a.options, a.result)
+}
+
+// useReplace reports whether patches are applied as replace directives instead of version bumps
+func (a *App) useReplace() bool {
+	return a.options.GoProxyURL != ""
+}
+
+// proxyBuildCommand returns the build command with GOPROXY pointing at the Root.io module proxy.
+// The replacement modules aren't in the public checksum database, so they're listed in GONOSUMDB.
+func (a *App) proxyBuildCommand(patches []rootio.PackagePatch) string {
+	var modules []string
+	for _, patch := range patches {
+		if target := common.PatchTarget(patch, true); common.ReplacesPackage(patch, target) {
+			modules = append(modules, target.Name)
+		}
+	}
+	if len(modules) == 0 {
+		return fmt.Sprintf("GOPROXY=%s,direct %s", a.options.GoProxyURL, buildCommand)
+	}
+	return fmt.Sprintf("GOPROXY=%s,direct GONOSUMDB=%s %s",
+		a.options.GoProxyURL, strings.Join(modules, ","), buildCommand)
+}
+
+// patchUpdates maps each module path to its patched version, or to replacement@version when the
+// patch installs another module. In replace mode a patch without an alias falls back to a version bump.
+func (a *App) patchUpdates(patches []rootio.PackagePatch) map[string]string {
+	updates := make(map[string]string)
+	for _, patch := range patches {
+		target := common.PatchTarget(patch, a.useReplace())
+		if common.ReplacesPackage(patch, target) {
+			updates[patch.PackageName] = target.Name + "@" + target.Version
+		} else {
+			updates[patch.PackageName] = target.Version
+		}
+	}
+	return updates
+}
+
+// proposedDiff renders the change applyPatches would make to go.mod as a unified diff
+func (a *App) proposedDiff(ctx context.Context, patches []rootio.PackagePatch) (string, error) {
+	original, err := os.ReadFile(a.filePath)
+	if err != nil {
+		return "", fmt.Errorf("failed to read file: %w", err)
+	}
+
+	updatedContent, err := a.parser.Update(ctx, a.filePath, a.patchUpdates(patches))
+	if err != nil {
+		return "", fmt.Errorf("failed to update file: %w", err)
+	}
+
+	return common.UnifiedDiff(a.filePath, string(original), updatedContent), nil
+}
+
+// applyPatches updates go.mod with patched versions or replace directives
+func (a *App) applyPatches(ctx context.Context, patches []rootio.PackagePatch) error {
+	updates := a.patchUpdates(patches)
+	for i, patch := range patches {
+		fmt.Fprintf(a.out, "[%d/%d] %s: %s → %s\n", i+1, len(patches), patch.PackageName, patch.Version, updates[patch.PackageName])
+	}
+
+	// Hold the lock from reading the file until it's written, so concurrent runs don't lose updates
+	unlock, err := common.LockFiles(ctx, []string{a.filePath}, a.options.LockTimeout)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	// Update the file
+	a.logger.DebugContext(ctx, "Updating go.mod", slog.Int("updates", len(updates)))
+	updatedContent, err := a.parser.Update(ctx, a.filePath, updates)
+	if err != nil {
+		return fmt.Errorf("failed to update file: %w", err)
+	}
+
+	// Validate the updated content
+	if !a.parser.Validate(updatedContent) {
+		return fmt.Errorf("updated file content is invalid")
+	}
+
+	if a.options.Backup {
+		backupPath, err := common.BackupFile(a.filePath)
+		if err != nil {
+			return fmt.Errorf("failed to back up file: %w", err)
+		}
+		a.logger.InfoContext(ctx, "Backed up file before patching",
+			slog.String("file", a.filePath),
+			slog.String("backup", backupPath))
+	}
+
+	// Write the updated content back to the file
+	if err := os.WriteFile(a.filePath, []byte(updatedContent), 0644); err != nil {
+		return fmt.Errorf("failed to write updated file: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,249 @@
+package gomod
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"os"
+	"strings"
+	"testing"
+
+	"rootio_patcher/cmd/rootio_patcher/common"
+	"rootio_patcher/pkg/rootio"
+)
+
+// netPatchClient returns a patch for golang.org/x/net and records the analyzed packages
+func netPatchClient(analyzed *[]rootio.Package) *MockAPIClient {
+	return &MockAPIClient{
+		AnalyzePackagesFunc: func(ctx context.Context, packages []rootio.Package) (*rootio.AnalyzePackagesResponse, error) {
+			*analyzed = packages
+			return &rootio.AnalyzePackagesResponse{
+				Patches: []rootio.PackagePatch{
+					{
+						PackageName: "golang.org/x/net",
+						Version:     "v0.15.0",
+						Patch:       rootio.PatchInfo{Name: "golang.org/x/net", Version: "v0.17.0"},
+						PatchAlias:  rootio.PatchInfo{Name: "pkg.root.io/golang.org/x/net", Version: "v0.15.0-root.1"},
+					},
+				},
+			}, nil
+		},
+	}
+}
+
+func TestGoApp_Run_FileNotFound(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+
+	app := NewAppWithServices("test-key", "https://api.root.io", "/nonexistent/go.mod", true, logger,
+		NewParser(), &MockAPIClient{})
+	if err := app.Run(context.Background()); err == nil {
+		t.Fatal("Expected error for nonexistent file, got nil")
+	}
+}
+
+func TestGoApp_Run_APIError(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+	path := writeGoMod(t, goMod)
+
+	mockAPIClient := &MockAPIClient{
+		AnalyzePackagesFunc: func(ctx context.Context, packages []rootio.Package) (*rootio.AnalyzePackagesResponse, error) {
+			return nil, errors.New("API error")
+		},
+	}
+
+	app := NewAppWithServices("test-key", "https://api.root.io", path, false, logger, NewParser(), mockAPIClient)
+	if err := app.Run(context.Background()); err == nil {
+		t.Fatal("Expected error from API, got nil")
+	}
+}
+
+func TestGoApp_Run_DryRun(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+	path := writeGoMod(t, goMod)
+
+	var analyzed []rootio.Package
+	app := NewAppWithServices("test-key", "https://api.root.io", path, true, logger, NewParser(), netPatchClient(&analyzed))
+	if err := app.Run(context.Background()); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	if len(analyzed) != 4 {
+		t.Errorf("Expected 4 modules to be analyzed, got %v", analyzed)
+	}
+	for _, pkg := range analyzed {
+		if pkg.Ecosystem != string(common.EcosystemGo) {
+			t.Errorf("Expected go ecosystem, got %q", pkg.Ecosystem)
+		}
+		if pkg.Name == "golang.org/x/text" && pkg.Direct {
+			t.Errorf("Expected the indirect module to be sent as transitive")
+		}
+	}
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Failed to read go.mod: %v", err)
+	}
+	if string(content) != goMod {
+		t.Errorf("Expected dry-run to leave go.mod unchanged, got:\n%s", content)
+	}
+	if status := app.Result().Patches[0].Status; status != common.PatchStatusDryRun {
+		t.Errorf("Expected dry-run status, got %s", status)
+	}
+}
+
+func TestGoApp_Run_ApplyPatches(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+	path := writeGoMod(t, goMod)
+
+	var analyzed []rootio.Package
+	app := NewAppWithServices("test-key", "https://api.root.io", path, false, logger, NewParser(), netPatchClient(&analyzed))
+	if err := app.Run(context.Background()); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Failed to read go.mod: %v", err)
+	}
+	if !strings.Contains(string(content), "\tgolang.org/x/net v0.17.0\n") {
+		t.Errorf("Expected golang.org/x/net to be bumped, got:\n%s", content)
+	}
+	if strings.Contains(string(content), "pkg.root.io") {
+		t.Errorf("Expected no replace directive without a proxy, got:\n%s", content)
+	}
+	if status := app.Result().Patches[0].Status; status != common.PatchStatusApplied {
+		t.Errorf("Expected applied status, got %s", status)
+	}
+}
+
+func TestGoApp_Run_Replace(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+	path := writeGoMod(t, goMod)
+
+	var analyzed []rootio.Package
+	app := NewAppWithServices("test-key", "https://api.root.io", path, false, logger, NewParser(), netPatchClient(&analyzed),
+		common.WithGoProxy("https://pkg.root.io/go"))
+	if err := app.Run(context.Background()); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Failed to read go.mod: %v", err)
+	}
+	if !strings.Contains(string(content), "\tgolang.org/x/net v0.15.0\n") {
+		t.Errorf("Expected the require directive to be kept, got:\n%s", content)
+	}
+	if !strings.Contains(string(content), "replace golang.org/x/net => pkg.root.io/golang.org/x/net v0.15.0-root.1\n") {
+		t.Errorf("Expected a replace directive for golang.org/x/net, got:\n%s", content)
+	}
+
+	command := app.proxyBuildCommand([]rootio.PackagePatch{{PatchAlias: rootio.PatchInfo{Name: "pkg.root.io/golang.org/x/net"}}})
+	if command != "GOPROXY=https://pkg.root.io/go,direct GONOSUMDB=pkg.root.io/golang.org/x/net go mod tidy && go build ./..." {
+		t.Errorf("Unexpected build command: %s", command)
+	}
+}
+
+func TestGoApp_Run_ReplaceWithoutAlias(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+	path := writeGoMod(t, goMod)
+
+	// Only a patched version of the same module: nothing to replace, so the require is bumped
+	patch := rootio.PackagePatch{
+		PackageName: "golang.org/x/net",
+		Version:     "v0.15.0",
+		Patch:       rootio.PatchInfo{Version: "v0.17.0"},
+	}
+	mockAPIClient := &MockAPIClient{
+		AnalyzePackagesFunc: func(ctx context.Context, packages []rootio.Package) (*rootio.AnalyzePackagesResponse, error) {
+			return &rootio.AnalyzePackagesResponse{Patches: []rootio.PackagePatch{patch}}, nil
+		},
+	}
+	app := NewAppWithServices("test-key", "https://api.root.io", path, false, logger, NewParser(), mockAPIClient,
+		common.WithGoProxy("https://pkg.root.io/go"))
+	if err := app.Run(context.Background()); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Failed to read go.mod: %v", err)
+	}
+	if !strings.Contains(string(content), "\tgolang.org/x/net v0.17.0\n") {
+		t.Errorf("Expected golang.org/x/net to be bumped, got:\n%s", content)
+	}
+	if strings.Contains(string(content), "replace golang.org/x/net") {
+		t.Errorf("Expected no replace directive without an alias, got:\n%s", content)
+	}
+
+	command := app.proxyBuildCommand([]rootio.PackagePatch{patch})
+	if command != "GOPROXY=https://pkg.root.io/go,direct go mod tidy && go build ./..." {
+		t.Errorf("Unexpected build command: %s", command)
+	}
+}
+
+func TestGoApp_Run_ReplaceResolvesInstalledTarget(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+	path := writeGoMod(t, goMod)
+
+	mockAPIClient := &MockAPIClient{
+		AnalyzePackagesFunc: func(ctx context.Context, packages []rootio.Package) (*rootio.AnalyzePackagesResponse, error) {
+			return &rootio.AnalyzePackagesResponse{
+				Patches: []rootio.PackagePatch{
+					// An empty alias falls back to the patched version
+					{
+						PackageName: "golang.org/x/net",
+						Version:     "v0.15.0",
+						Patch:       rootio.PatchInfo{Version: "v0.17.0"},
+						PatchAlias:  rootio.PatchInfo{Name: "pkg.root.io/golang.org/x/net"},
+					},
+					// Nothing to install
+					{
+						PackageName: "golang.org/x/text",
+						Version:     "v0.13.0",
+						PatchAlias:  rootio.PatchInfo{Name: "pkg.root.io/golang.org/x/text"},
+					},
+					// The replacement is built from an older release than the one required
+					{
+						PackageName: "github.com/gin-gonic/gin",
+						Version:     "v1.9.0",
+						Patch:       rootio.PatchInfo{Version: "v1.9.1"},
+						PatchAlias:  rootio.PatchInfo{Name: "pkg.root.io/github.com/gin-gonic/gin", Version: "v1.8.2-root.1"},
+					},
+				},
+			}, nil
+		},
+	}
+	app := NewAppWithServices("test-key", "https://api.root.io", path, false, logger, NewParser(), mockAPIClient,
+		common.WithGoProxy("https://pkg.root.io/go"))
+	if err := app.Run(context.Background()); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Failed to read go.mod: %v", err)
+	}
+	for _, line := range []string{"\tgolang.org/x/net v0.17.0\n", "\tgolang.org/x/text v0.13.0 // indirect\n", "require github.com/gin-gonic/gin v1.9.0\n"} {
+		if !strings.Contains(string(content), line) {
+			t.Errorf("Expected go.mod to contain %q, got:\n%s", line, content)
+		}
+	}
+	if strings.Contains(string(content), "pkg.root.io") {
+		t.Errorf("Expected no replace directive, got:\n%s", content)
+	}
+
+	expected := map[string]string{
+		"golang.org/x/text":        "the API returned no patched version for golang.org/x/text v0.13.0",
+		"github.com/gin-gonic/gin": "patched version v1.8.2-root.1 is not newer than v1.9.0",
+	}
+	skipped := app.Result().Skipped
+	if len(skipped) != len(expected) {
+		t.Fatalf("Expected %d skipped packages, got %+v", len(expected), skipped)
+	}
+	for _, s := range skipped {
+		if expected[s.PackageName] != s.Reason {
+			t.Errorf("Expected %s to be skipped with %q, got %q", s.PackageName, expected[s.PackageName], s.Reason)
+		}
+	}
+}
@@ -0,0 +1,19 @@
+package gomod
+
+import (
+	"context"
+
+	"rootio_patcher/pkg/rootio"
+)
+
+// MockAPIClient is a mock implementation of APIClient for testing
+type MockAPIClient struct {
+	AnalyzePackagesFunc func(ctx context.Context, packages []rootio.Package) (*rootio.AnalyzePackagesResponse, error)
+}
+
+func (m *MockAPIClient) AnalyzePackages(ctx context.Context, packages []rootio.Package) (*rootio.AnalyzePackagesResponse, error) {
+	if m.AnalyzePackagesFunc != nil {
+		return m.AnalyzePackagesFunc(ctx, packages)
+	}
+	return &rootio.AnalyzePackagesResponse{}, nil
+}
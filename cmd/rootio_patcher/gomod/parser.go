@@ -0,0 +1,336 @@
+package gomod
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"rootio_patcher/cmd/rootio_patcher/common"
+)
+
+const goModFile = "go.mod"
+
+// GoModParser handles parsing of Go go.mod files
+type GoModParser struct{}
+
+// NewParser creates a new go.mod parser
+func NewParser() *GoModParser {
+	return &GoModParser{}
+}
+
+// Ecosystem returns the ecosystem name
+func (p *GoModParser) Ecosystem() common.Ecosystem {
+	return common.EcosystemGo
+}
+
+// FilePatterns returns file patterns this parser handles
+func (p *GoModParser) FilePatterns() []string {
+	return []string{goModFile}
+}
+
+// CanHandle checks if this parser can handle the given file
+func (p *GoModParser) CanHandle(fileName string) bool {
+	return filepath.Base(fileName) == goModFile
+}
+
+// directive is a require or replace line of go.mod, in either the single-line or the
+// parenthesized block form
+type directive struct {
+	verb    string   // require, replace, exclude, ...
+	fields  []string // tokens after the verb (or the whole line inside a block), comment removed
+	comment string   // trailing // comment, without the slashes
+	line    int      // index into the file's lines
+}
+
+// parseDirectives returns every directive of a go.mod file, with block entries expanded
+func parseDirectives(lines []string) ([]directive, error) {
+	var directives []directive
+	block := ""
+
+	for i, line := range lines {
+		code, comment, _ := strings.Cut(line, "//")
+		fields := strings.Fields(code)
+		if len(fields) == 0 {
+			continue
+		}
+
+		if block != "" {
+			if fields[0] == ")" {
+				block = ""
+				continue
+			}
+			directives = append(directives, directive{block, fields, strings.TrimSpace(comment), i})
+			continue
+		}
+
+		// require ( opens a block; require example.com/m v1.0.0 is a single directive
+		if len(fields) == 2 && fields[1] == "(" {
+			block = fields[0]
+			continue
+		}
+		directives = append(directives, directive{fields[0], fields[1:], strings.TrimSpace(comment), i})
+	}
+
+	if block != "" {
+		return nil, fmt.Errorf("unterminated %s block", block)
+	}
+	return directives, nil
+}
+
+// unquotePath strips the quotes Go allows around module paths
+func unquotePath(path string) string {
+	return strings.Trim(path, "\"`")
+}
+
+// isIndirect reports whether a require comment marks the module as indirect
+func isIndirect(comment string) bool {
+	for _, part := range strings.Split(comment, ";") {
+		if strings.TrimSpace(part) == "indirect" {
+			return true
+		}
+	}
+	return false
+}
+
+// localReplacements returns modules replaced by a directory on disk, whose required version isn't used
+func localReplacements(directives []directive) map[string]bool {
+	local := make(map[string]bool)
+	for _, d := range directives {
+		if d.verb != "replace" {
+			continue
+		}
+		arrow := indexOf(d.fields, "=>")
+		if arrow < 1 || arrow+1 >= len(d.fields) {
+			continue
+		}
+		if isLocalPath(d.fields[arrow+1]) {
+			local[unquotePath(d.fields[0])] = true
+		}
+	}
+	return local
+}
+
+// isLocalPath reports whether a replacement is a directory rather than a module path
+func isLocalPath(target string) bool {
+	return strings.HasPrefix(target, "./") || strings.HasPrefix(target, "../") || filepath.IsAbs(target)
+}
+
+// validReplace checks the fields of a replace directive: "old [version] => new version", or
+// "old [version] => ./dir" for a local directory
+func validReplace(fields []string) bool {
+	arrow := indexOf(fields, "=>")
+	if arrow < 1 || arrow > 2 {
+		return false
+	}
+
+	switch right := fields[arrow+1:]; len(right) {
+	case 1:
+		return isLocalPath(right[0])
+	case 2:
+		return common.ValidPackageName(common.EcosystemGo, unquotePath(right[0])) && common.ValidVersion(right[1])
+	default:
+		return false
+	}
+}
+
+// indexOf returns the position of value in fields, or -1
+func indexOf(fields []string, value string) int {
+	for i, field := range fields {
+		if field == value {
+			return i
+		}
+	}
+	return -1
+}
+
+// Parse reads the required modules from go.mod. Modules marked // indirect are transitive;
+// modules replaced by a local directory are left out since their version isn't used.
+func (p *GoModParser) Parse(ctx context.Context, filePath string) ([]common.PackageInfo, error) {
+	content, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read file: %w", err)
+	}
+
+	directives, err := parseDirectives(strings.Split(string(content), "\n"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse go.mod: %w", err)
+	}
+	local := localReplacements(directives)
+
+	var packages []common.PackageInfo
+	for _, d := range directives {
+		if d.verb != "require" || len(d.fields) < 2 {
+			continue
+		}
+		path := unquotePath(d.fields[0])
+		if local[path] {
+			continue
+		}
+
+		packages = append(packages, common.PackageInfo{
+			Name:              path,
+			Version:           d.fields[1],
+			VersionConstraint: d.fields[1], // go.mod requires exact minimum versions
+			Ecosystem:         common.EcosystemGo,
+			Direct:            !isIndirect(d.comment),
+			Location:          filePath,
+		})
+	}
+
+	return packages, nil
+}
+
+// Update rewrites go.mod. An update of a plain version (v1.2.4) bumps the module's require
+// directive; an update of module@version (example.com/patched@v1.2.4) adds or rewrites a
+// replace directive pointing the module at that replacement, like go mod edit -replace.
+func (p *GoModParser) Update(ctx context.Context, filePath string, updates map[string]string) (string, error) {
+	content, err := os.ReadFile(filePath)
+	if err != nil {
+		return "", fmt.Errorf("failed to read file: %w", err)
+	}
+
+	lines := strings.Split(string(content), "\n")
+	directives, err := parseDirectives(lines)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse go.mod: %w", err)
+	}
+
+	replacements := make(map[string]string)
+	for path, update := range updates {
+		if replacement, version, ok := strings.Cut(update, "@"); ok {
+			replacements[path] = replacement + " " + version
+		}
+	}
+
+	for _, d := range directives {
+		if len(d.fields) < 2 {
+			continue
+		}
+		path := unquotePath(d.fields[0])
+
+		switch d.verb {
+		case "require":
+			if version, ok := updates[path]; ok && !strings.Contains(version, "@") {
+				lines[d.line] = replaceToken(lines[d.line], d.fields[1], version)
+			}
+		case "replace":
+			// Rewrite the right-hand side of an existing replacement for the module
+			replacement, ok := replacements[path]
+			arrow := indexOf(d.fields, "=>")
+			if !ok || arrow < 1 {
+				continue
+			}
+			prefix := lines[d.line][:strings.Index(lines[d.line], "=>")]
+			lines[d.line] = prefix + "=> " + replacement + trailingComment(d.comment)
+			delete(replacements, path)
+		}
+	}
+
+	return addReplacements(lines, replacements), nil
+}
+
+// replaceToken replaces the first whitespace-delimited occurrence of old in line
+func replaceToken(line, old, value string) string {
+	for start := 0; start < len(line); {
+		idx := strings.Index(line[start:], old)
+		if idx < 0 {
+			break
+		}
+		idx += start
+		end := idx + len(old)
+		before := idx == 0 || line[idx-1] == ' ' || line[idx-1] == '\t'
+		after := end == len(line) || line[end] == ' ' || line[end] == '\t' || line[end] == '/'
+		if before && after {
+			return line[:idx] + value + line[end:]
+		}
+		start = end
+	}
+	return line
+}
+
+// trailingComment formats a directive comment to append to a rewritten line
+func trailingComment(comment string) string {
+	if comment == "" {
+		return ""
+	}
+	return " // " + comment
+}
+
+// addReplacements adds replace directives for the modules that don't have one yet. They join an
+// existing replace block when there is one, and are appended to the end of the file otherwise.
+func addReplacements(lines []string, replacements map[string]string) string {
+	if len(replacements) == 0 {
+		return strings.Join(lines, "\n")
+	}
+
+	paths := make([]string, 0, len(replacements))
+	for path := range replacements {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	var entries []string
+	for _, path := range paths {
+		entries = append(entries, path+" => "+replacements[path])
+	}
+
+	if closing := replaceBlockEnd(lines); closing >= 0 {
+		var block []string
+		for _, entry := range entries {
+			block = append(block, "\t"+entry)
+		}
+		lines = append(lines[:closing], append(block, lines[closing:]...)...)
+		return strings.Join(lines, "\n")
+	}
+
+	content := strings.TrimRight(strings.Join(lines, "\n"), "\n")
+	if len(entries) == 1 {
+		return content + "\n\nreplace " + entries[0] + "\n"
+	}
+	return content + "\n\nreplace (\n\t" + strings.Join(entries, "\n\t") + "\n)\n"
+}
+
+// replaceBlockEnd returns the line of the closing parenthesis of the first replace block, or -1
+func replaceBlockEnd(lines []string) int {
+	inBlock := false
+	for i, line := range lines {
+		code, _, _ := strings.Cut(line, "//")
+		fields := strings.Fields(code)
+		switch {
+		case !inBlock && len(fields) == 2 && fields[0] == "replace" && fields[1] == "(":
+			inBlock = true
+		case inBlock && len(fields) > 0 && fields[0] == ")":
+			return i
+		}
+	}
+	return -1
+}
+
+// Validate checks that the content declares a module, that every block is closed and that
+// require and replace directives are complete
+func (p *GoModParser) Validate(content string) bool {
+	directives, err := parseDirectives(strings.Split(content, "\n"))
+	if err != nil {
+		return false
+	}
+
+	hasModule := false
+	for _, d := range directives {
+		switch d.verb {
+		case "module":
+			hasModule = len(d.fields) == 1
+		case "require":
+			if len(d.fields) != 2 {
+				return false
+			}
+		case "replace":
+			if !validReplace(d.fields) {
+				return false
+			}
+		}
+	}
+	return hasModule
+}
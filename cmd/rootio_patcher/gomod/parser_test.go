@@ -0,0 +1,209 @@
+package gomod
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const goMod = `module example.com/app
+
+go 1.22
+
+require github.com/gin-gonic/gin v1.9.0
+
+require (
+	golang.org/x/net v0.15.0
+	github.com/mylib/local v1.0.0
+	golang.org/x/text v0.13.0 // indirect
+	"github.com/quoted/mod" v2.1.0+incompatible
+)
+
+replace github.com/mylib/local => ../local
+`
+
+// writeGoMod writes content to a go.mod in a temporary directory and returns its path
+func writeGoMod(t *testing.T, content string) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "go.mod")
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write go.mod: %v", err)
+	}
+	return path
+}
+
+func TestGoModParser_CanHandle(t *testing.T) {
+	parser := NewParser()
+
+	tests := []struct {
+		fileName string
+		expected bool
+	}{
+		{"go.mod", true},
+		{"services/api/go.mod", true},
+		{"go.sum", false},
+		{"go.work", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.fileName, func(t *testing.T) {
+			if result := parser.CanHandle(tt.fileName); result != tt.expected {
+				t.Errorf("CanHandle(%s) = %v, expected %v", tt.fileName, result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestGoModParser_Parse(t *testing.T) {
+	path := writeGoMod(t, goMod)
+
+	packages, err := NewParser().Parse(context.Background(), path)
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+
+	// The locally replaced module is left out
+	expected := []struct {
+		name    string
+		version string
+		direct  bool
+	}{
+		{"github.com/gin-gonic/gin", "v1.9.0", true},
+		{"golang.org/x/net", "v0.15.0", true},
+		{"golang.org/x/text", "v0.13.0", false},
+		{"github.com/quoted/mod", "v2.1.0+incompatible", true},
+	}
+
+	if len(packages) != len(expected) {
+		t.Fatalf("Expected %d packages, got %d: %+v", len(expected), len(packages), packages)
+	}
+
+	for i, exp := range expected {
+		pkg := packages[i]
+		if pkg.Name != exp.name || pkg.Version != exp.version || pkg.Direct != exp.direct || pkg.Location != path {
+			t.Errorf("Package %d: expected %+v, got %+v", i, exp, pkg)
+		}
+	}
+}
+
+func TestGoModParser_Parse_UnterminatedBlock(t *testing.T) {
+	path := writeGoMod(t, "module example.com/app\n\nrequire (\n\tgolang.org/x/net v0.15.0\n")
+
+	if _, err := NewParser().Parse(context.Background(), path); err == nil {
+		t.Fatal("Expected error for unterminated require block")
+	}
+}
+
+func TestGoModParser_Update(t *testing.T) {
+	parser := NewParser()
+	path := writeGoMod(t, goMod)
+
+	updated, err := parser.Update(context.Background(), path, map[string]string{
+		"github.com/gin-gonic/gin": "v1.9.1",
+		"golang.org/x/text":        "v0.14.0",
+	})
+	if err != nil {
+		t.Fatalf("Update failed: %v", err)
+	}
+	if !parser.Validate(updated) {
+		t.Fatalf("Expected updated go.mod to be valid, got:\n%s", updated)
+	}
+
+	expected := strings.NewReplacer(
+		"gin v1.9.0", "gin v1.9.1",
+		"text v0.13.0 // indirect", "text v0.14.0 // indirect",
+	).Replace(goMod)
+	if updated != expected {
+		t.Errorf("Expected:\n%s\ngot:\n%s", expected, updated)
+	}
+}
+
+func TestGoModParser_Update_Replace(t *testing.T) {
+	parser := NewParser()
+
+	tests := []struct {
+		name     string
+		content  string
+		updates  map[string]string
+		expected string
+	}{
+		{
+			name:    "appends a single replace directive",
+			content: "module example.com/app\n\nrequire golang.org/x/net v0.15.0\n",
+			updates: map[string]string{"golang.org/x/net": "pkg.root.io/golang.org/x/net@v0.15.0-root.1"},
+			expected: "module example.com/app\n\nrequire golang.org/x/net v0.15.0\n\n" +
+				"replace golang.org/x/net => pkg.root.io/golang.org/x/net v0.15.0-root.1\n",
+		},
+		{
+			name:    "appends a sorted replace block",
+			content: "module example.com/app\n\nrequire (\n\tgolang.org/x/text v0.13.0\n\tgolang.org/x/net v0.15.0\n)\n",
+			updates: map[string]string{
+				"golang.org/x/text": "pkg.root.io/golang.org/x/text@v0.13.0-root.1",
+				"golang.org/x/net":  "pkg.root.io/golang.org/x/net@v0.15.0-root.1",
+			},
+			expected: "module example.com/app\n\nrequire (\n\tgolang.org/x/text v0.13.0\n\tgolang.org/x/net v0.15.0\n)\n\n" +
+				"replace (\n\tgolang.org/x/net => pkg.root.io/golang.org/x/net v0.15.0-root.1\n" +
+				"\tgolang.org/x/text => pkg.root.io/golang.org/x/text v0.13.0-root.1\n)\n",
+		},
+		{
+			name: "rewrites an existing replacement and joins an existing block",
+			content: "module example.com/app\n\nrequire (\n\tgolang.org/x/text v0.13.0\n\tgolang.org/x/net v0.15.0\n)\n\n" +
+				"replace (\n\tgolang.org/x/net v0.15.0 => example.com/fork/net v0.15.1 // fork\n)\n",
+			updates: map[string]string{
+				"golang.org/x/text": "pkg.root.io/golang.org/x/text@v0.13.0-root.1",
+				"golang.org/x/net":  "pkg.root.io/golang.org/x/net@v0.15.0-root.1",
+			},
+			expected: "module example.com/app\n\nrequire (\n\tgolang.org/x/text v0.13.0\n\tgolang.org/x/net v0.15.0\n)\n\n" +
+				"replace (\n\tgolang.org/x/net v0.15.0 => pkg.root.io/golang.org/x/net v0.15.0-root.1 // fork\n" +
+				"\tgolang.org/x/text => pkg.root.io/golang.org/x/text v0.13.0-root.1\n)\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := writeGoMod(t, tt.content)
+
+			updated, err := parser.Update(context.Background(), path, tt.updates)
+			if err != nil {
+				t.Fatalf("Update failed: %v", err)
+			}
+			if updated != tt.expected {
+				t.Errorf("Expected:\n%s\ngot:\n%s", tt.expected, updated)
+			}
+			if !parser.Validate(updated) {
+				t.Errorf("Expected updated go.mod to be valid")
+			}
+		})
+	}
+}
+
+func TestGoModParser_Validate(t *testing.T) {
+	parser := NewParser()
+
+	tests := []struct {
+		name     string
+		content  string
+		expected bool
+	}{
+		{"valid", goMod, true},
+		{"missing module", "go 1.22\n\nrequire golang.org/x/net v0.15.0\n", false},
+		{"unterminated block", "module example.com/app\n\nrequire (\n", false},
+		{"require without version", "module example.com/app\n\nrequire golang.org/x/net\n", false},
+		{"replace", "module example.com/app\n\nreplace golang.org/x/net v0.15.0 => pkg.root.io/golang.org/x/net v0.15.0-root.1\n", true},
+		{"replace with local directory", "module example.com/app\n\nreplace golang.org/x/net => ../net\n", true},
+		{"replace without target", "module example.com/app\n\nreplace golang.org/x/net =>  \n", false},
+		{"replace without version", "module example.com/app\n\nreplace golang.org/x/net => pkg.root.io/golang.org/x/net\n", false},
+		{"replace with malformed target", "module example.com/app\n\nreplace golang.org/x/net => @ v1.0.0\n", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if result := parser.Validate(tt.content); result != tt.expected {
+				t.Errorf("Validate() = %v, expected %v", result, tt.expected)
+			}
+		})
+	}
+}
@@ -2,40 +2,138 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"io"
 	"log/slog"
+	"net/url"
 	"os"
 	"os/signal"
+	"path/filepath"
+	"strings"
+	"time"
 
 	"github.com/alecthomas/kong"
 
+	"rootio_patcher/cmd/rootio_patcher/apt"
+	"rootio_patcher/cmd/rootio_patcher/common"
 	"rootio_patcher/cmd/rootio_patcher/config"
+	"rootio_patcher/cmd/rootio_patcher/gem"
+	"rootio_patcher/cmd/rootio_patcher/gomod"
 	"rootio_patcher/cmd/rootio_patcher/maven"
 	"rootio_patcher/cmd/rootio_patcher/npm"
+	"rootio_patcher/cmd/rootio_patcher/nuget"
 	"rootio_patcher/cmd/rootio_patcher/pip"
+	"rootio_patcher/cmd/rootio_patcher/sbom"
+	"rootio_patcher/cmd/rootio_patcher/scan"
+	"rootio_patcher/pkg/rootio"
 )
 
 var version = "dev"
 
+// Globals defines flags shared by all commands
+type Globals struct {
+	Config      string   `help:"Path to a config file (default: ./.rootio.yaml, then ~/.rootio.yaml)"`
+	APIKeyStdin bool     `help:"Read the API key from stdin instead of ROOTIO_API_KEY (e.g. from a secret manager), keeping it out of the environment"`
+	Output      string   `default:"text" enum:"text,json,sarif" help:"Output format (text, json or sarif). In json and sarif modes progress is written to stderr"`
+	ReportFile  string   `help:"Write the report (the json or sarif document with --output) to this file instead of stdout; logs and progress go to stderr"`
+	Quiet       bool     `short:"q" help:"Only print errors, and the json or sarif document with --output; the report is still written to --report-file"`
+	MinSeverity string   `default:"none" enum:"none,low,medium,high,critical" help:"Only apply patches at or above this severity (none, low, medium, high, critical)"`
+	Only        []string `sep:"," help:"Only patch these packages (comma-separated names or globs, e.g. @babel/*; groupId:artifactId for Maven)"`
+	Exclude     []string `sep:"," help:"Never patch these packages (comma-separated names or globs); applied after --only"`
+	CVE         []string `name:"cve" sep:"," help:"Only patch packages whose patch fixes this CVE (repeatable or comma-separated, e.g. CVE-2021-23337)"`
+	SkipDev     bool     `help:"Leave dev/test dependencies out (npm devDependencies, Maven/Gradle test scope, Poetry dev groups, pipenv dev-packages, NuGet PrivateAssets=all)"`
+	Verify      bool     `help:"Analyze the packages again after patching and fail if patches remain (pip, npm, Maven, Go, RubyGems, NuGet; not apt)"`
+
+	CacheDir string        `help:"Cache analysis responses in this directory and reuse them for an unchanged package set (default: no cache, the API is always called)"`
+	CacheTTL time.Duration `default:"1h" help:"How long a cached analysis response is reused with --cache-dir"`
+
+	Offline bool   `help:"Analyze packages against the local vulnerability database given with --db instead of calling the Root.io API (no API key needed)"`
+	DB      string `name:"db" help:"Local vulnerability database (JSON) used by --offline"`
+
+	Timeout time.Duration `help:"Abort the whole run after this long, e.g. 10m (default: no limit)"`
+
+	FailOnPatches   bool `help:"Exit with --patches-exit-code when patches are available but were not applied (e.g. in dry-run mode)"`
+	PatchesExitCode int  `default:"2" help:"Exit code used by --fail-on-patches (2-255)"`
+
+	PlanOut string `help:"Write the patches found by a dry run to this plan file, to be applied later with 'apply --plan'"`
+
+	Yes bool `short:"y" help:"Apply patches without asking for confirmation; required with --dry-run=false when stdin or stdout isn't a terminal (e.g. in CI)"`
+
+	// clientOptions configure the Root.io API client from the environment (not a flag)
+	clientOptions []rootio.Option
+	// plan collects the dry-run patches written to --plan-out (not a flag)
+	plan *common.Plan
+	// progress prints status lines on a terminal in text mode (not a flag)
+	progress *common.Progress
+	// confirm asks before patches are applied, unless --yes was given (not a flag)
+	confirm *common.Confirmer
+	// offlineDB is the local vulnerability database loaded from --db (not a flag)
+	offlineDB *common.LocalDatabase
+	// report receives the human-readable report: stdout, stderr in json and sarif modes, or
+	// --report-file in text mode (not a flag)
+	report io.Writer
+}
+
+// Exit codes
+const (
+	exitOK    = 0 // No patches available, or every available patch was applied
+	exitError = 1 // The command failed or a patch failed to apply
+)
+
+// exitCodeHelp documents the exit codes in --help
+const exitCodeHelp = `Exit codes:
+  0  no patches available, or all available patches were applied
+  1  error, or at least one patch failed to apply
+  2  patches available but not applied (only with --fail-on-patches; see --patches-exit-code)`
+
 // CLI defines the command-line interface
 type CLI struct {
+	Globals
+
 	Version kong.VersionFlag `short:"v" help:"Print version information"`
 
 	Pip   PipCmd   `cmd:"" help:"Python/pip package remediation"`
 	Npm   NpmCmd   `cmd:"" help:"npm package remediation"`
 	Maven MavenCmd `cmd:"" help:"Maven package remediation"`
+	Go    GoCmd    `cmd:"" help:"Go module remediation"`
+	Gem   GemCmd   `cmd:"" help:"RubyGems remediation (Bundler)"`
+	NuGet NuGetCmd `cmd:"" name:"nuget" help:".NET package remediation (NuGet)"`
+	Apt   AptCmd   `cmd:"" help:"Debian system package remediation (dpkg/apt)"`
+	Scan  ScanCmd  `cmd:"" help:"Find every dependency file in a repository and remediate each with the matching ecosystem"`
+	Apply ApplyCmd `cmd:"" help:"Apply a plan written by a dry run with --plan-out, without contacting the Root.io API"`
 }
 
 // PipCmd handles pip-related commands
 type PipCmd struct {
 	Remediate PipRemediateCmd `cmd:"" help:"Remediate Python packages (post-install patching)"`
+	Rollback  PipRollbackCmd  `cmd:"" help:"Roll back pip patches recorded in the patch journal"`
 }
 
 // PipRemediateCmd remediates installed Python packages
 type PipRemediateCmd struct {
-	PythonPath string `default:"python" help:"Path to Python interpreter"`
+	PythonPath      string   `help:"Path to Python interpreter (default: $VIRTUAL_ENV/bin/python, then python3, then python)"`
+	DryRun          bool     `default:"true" help:"Preview changes without applying them"`
+	UseAlias        bool     `default:"true" help:"Use Root.io aliased packages"`
+	Requirements    []string `xor:"file" help:"Path to requirements.txt to remediate (pre-install patching) instead of installed packages. Repeat to remediate several files (e.g. requirements-dev.txt) together"`
+	Manifest        string   `xor:"file" help:"Path to poetry.lock, pyproject.toml, Pipfile.lock or Pipfile to remediate (pre-install patching) instead of installed packages"`
+	ConstraintStyle string   `default:"exact" enum:"exact,compatible,minimum" help:"How patched versions are written to --requirements files: exact (==), compatible (~=) or minimum (>=)"`
+	Backup          bool     `help:"Write <file>.rootio.bak before modifying requirements files (timestamped if a backup already exists)"`
+	Journal         string   `default:".rootio_patcher.journal" help:"Append-only journal of applied patches, used by pip rollback"`
+	Constraints     string   `help:"Also pin each applied patch in this pip constraints file (e.g. constraints.txt), so 'pip install -c' keeps the patched versions"`
+	KeepGoing       bool     `help:"Continue applying remaining patches after a failure (exit code is still non-zero)"`
+	Parallel        int      `default:"1" help:"Apply up to N patches concurrently. pip isn't designed for concurrent installs into one environment, so keep 1 unless patches are independent"`
+	Netrc           bool     `help:"Pass the index credentials to pip in a temporary netrc file instead of the index URL, keeping the API key out of process listings"`
+	InstallDeps     bool     `help:"Let pip install the patched packages' dependencies from the Root.io index instead of passing --no-deps"`
+	Target          string   `aliases:"site-packages" help:"Remediate the packages in this directory (a pip install --target directory or a venv's site-packages) instead of the interpreter's environment"`
+}
+
+// PipRollbackCmd reverts patches recorded by pip remediate
+type PipRollbackCmd struct {
+	PythonPath string `help:"Path to Python interpreter (default: $VIRTUAL_ENV/bin/python, then python3, then python)"`
 	DryRun     bool   `default:"true" help:"Preview changes without applying them"`
-	UseAlias   bool   `default:"true" help:"Use Root.io aliased packages"`
+	Journal    string `default:".rootio_patcher.journal" help:"Journal of applied patches written by pip remediate"`
+	Target     string `aliases:"site-packages" help:"Directory the patches were applied to with pip remediate --target"`
 }
 
 // NpmCmd handles npm-related commands
@@ -45,19 +143,89 @@ type NpmCmd struct {
 
 // NpmRemediateCmd remediates npm packages by patching lock file and package.json
 type NpmRemediateCmd struct {
-	PackageManager string `default:"npm" enum:"npm,yarn,pnpm" help:"Package manager to use (npm, yarn, or pnpm)"`
+	PackageManager string `default:"auto" enum:"auto,npm,yarn,pnpm" help:"Package manager to use (npm, yarn, or pnpm); auto detects it from the lock file next to package.json"`
 	DryRun         bool   `default:"true" help:"Preview changes without applying them"`
+	Backup         bool   `help:"Write package.json.rootio.bak before modifying package.json (timestamped if a backup already exists)"`
+	PackageJSON    string `default:"package.json" help:"package.json to add overrides to; workspace packages are redirected to their workspace root"`
+	UpdateLockfile bool   `help:"Also rewrite patched versions in package-lock.json; stale integrity hashes are removed so npm recomputes them"`
+	Registry       string `help:"Registry mirroring Root.io's packages; its scope is pointed there in .npmrc (.yarnrc.yml for Yarn Berry)"`
+	UseAlias       bool   `default:"true" help:"Override with Root.io aliased packages (npm:@rootio/...); --use-alias=false overrides with the patched versions of the original packages"`
+	Strict         bool   `help:"Skip patches whose override falls outside a dependent's peerDependencies range instead of warning (package-lock.json only)"`
 }
 
 // MavenCmd handles Maven-related commands
 type MavenCmd struct {
-	Remediate MavenRemediateCmd `cmd:"" help:"Remediate Maven packages (pre-install patching of pom.xml)"`
+	Remediate MavenRemediateCmd `cmd:"" help:"Remediate Maven/Gradle packages (pre-install patching of pom.xml or build.gradle)"`
 }
 
-// MavenRemediateCmd remediates Maven packages by patching pom.xml
+// MavenRemediateCmd remediates Maven packages by patching pom.xml or a Gradle build file
 type MavenRemediateCmd struct {
-	File   string `default:"pom.xml" help:"Path to pom.xml"`
+	File          string   `default:"pom.xml" help:"Path to pom.xml, build.gradle or build.gradle.kts"`
+	DryRun        bool     `default:"true" help:"Preview changes without applying them"`
+	Backup        bool     `help:"Write <file>.rootio.bak before modifying the build file (timestamped if a backup already exists)"`
+	ResolveParent bool     `help:"Load parent POMs via <parent><relativePath> to resolve inherited properties and managed versions"`
+	Recursive     bool     `help:"Also remediate the module POMs listed under <modules>, patching each version in the POM that declares it (implies --resolve-parent)"`
+	GroupPrefix   string   `help:"Only patch dependencies whose groupId is this group or one of its subgroups (e.g. org.springframework)"`
+	UseAlias      bool     `help:"Replace dependencies with Root.io aliased coordinates where the API provides them, instead of bumping their versions"`
+	Profile       []string `sep:"," help:"Read these POM profiles (comma-separated ids) instead of the ones with <activeByDefault>, like mvn -P"`
+}
+
+// GoCmd handles Go module commands
+type GoCmd struct {
+	Remediate GoRemediateCmd `cmd:"" help:"Remediate Go modules (pre-install patching of go.mod)"`
+}
+
+// GoRemediateCmd remediates Go modules by patching go.mod
+type GoRemediateCmd struct {
+	File    string `default:"go.mod" help:"Path to go.mod"`
+	DryRun  bool   `default:"true" help:"Preview changes without applying them"`
+	Backup  bool   `help:"Write go.mod.rootio.bak before modifying go.mod (timestamped if a backup already exists)"`
+	Replace bool   `help:"Add replace directives pointing patched modules at Root.io's module proxy instead of bumping require versions"`
+}
+
+// GemCmd handles RubyGems commands
+type GemCmd struct {
+	Remediate GemRemediateCmd `cmd:"" help:"Remediate Ruby gems (pre-install patching of Gemfile.lock)"`
+}
+
+// GemRemediateCmd remediates Ruby gems by patching Gemfile.lock
+type GemRemediateCmd struct {
+	File   string `default:"Gemfile.lock" help:"Path to Gemfile.lock"`
 	DryRun bool   `default:"true" help:"Preview changes without applying them"`
+	Backup bool   `help:"Write Gemfile.lock.rootio.bak before modifying it (timestamped if a backup already exists)"`
+}
+
+// NuGetCmd handles NuGet commands
+type NuGetCmd struct {
+	Remediate NuGetRemediateCmd `cmd:"" help:"Remediate .NET packages (pre-install patching of project files or packages.lock.json)"`
+}
+
+// NuGetRemediateCmd remediates .NET packages by patching a project, packages.config or packages.lock.json
+type NuGetRemediateCmd struct {
+	File   string `required:"" help:"Path to a .csproj, Directory.Packages.props, packages.config or packages.lock.json"`
+	DryRun bool   `default:"true" help:"Preview changes without applying them"`
+	Backup bool   `help:"Write <file>.rootio.bak before modifying each file (timestamped if a backup already exists)"`
+}
+
+// AptCmd handles apt-related commands
+type AptCmd struct {
+	Remediate AptRemediateCmd `cmd:"" help:"Remediate installed Debian packages from the Root.io apt repository (post-install patching)"`
+}
+
+// AptRemediateCmd remediates installed Debian packages
+type AptRemediateCmd struct {
+	DryRun    bool `default:"true" help:"Preview changes without applying them"`
+	KeepGoing bool `help:"Continue applying remaining patches after a failure (exit code is still non-zero)"`
+}
+
+// ScanCmd discovers dependency files under a root directory and remediates all of them
+type ScanCmd struct {
+	Path   string   `default:"." help:"Root directory to scan"`
+	DryRun bool     `default:"true" help:"Preview changes without applying them"`
+	Backup bool     `help:"Write <file>.rootio.bak before modifying each file (timestamped if a backup already exists)"`
+	Ignore []string `sep:"," help:"Extra .gitignore-style patterns to skip (comma-separated), on top of the root .gitignore"`
+	SBOM   string   `name:"sbom" help:"Analyze the components of a CycloneDX or SPDX JSON SBOM instead of scanning for dependency files (report only)"`
+	Jobs   int      `default:"4" help:"Number of ecosystems to remediate at once; 1 runs files one at a time"`
 }
 
 func main() {
@@ -68,38 +236,252 @@ func run() int {
 	// Setup context with signal handling
 	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt)
 	defer cancel()
+	// The first interrupt cancels the run; restoring the default handler lets a second one exit
+	// immediately, e.g. while a package uninstalled by an interrupted patch is reinstalled
+	context.AfterFunc(ctx, cancel)
 
 	// Parse CLI first (so --help works without env vars)
 	var cli CLI
 	kongCtx := kong.Parse(&cli,
 		kong.Name("rootio_patcher"),
-		kong.Description("Automated security patching for Python, npm, and Maven packages with Root.io"),
+		kong.Description("Automated security patching for Python, npm, Maven, Go, Ruby, .NET and Debian packages with Root.io\n\n"+exitCodeHelp),
 		kong.UsageOnError(),
 		kong.Vars{"version": version},
 		kong.BindTo(ctx, (*context.Context)(nil)), // Bind context with interface type
 	)
 
-	// Load configuration from environment variables (after parsing, before running)
-	cfg, err := config.LoadConfig()
+	// Load configuration from environment variables and the config file (after parsing, before running)
+	var loadOptions []config.LoadOption
+	if cli.APIKeyStdin {
+		loadOptions = append(loadOptions, config.WithAPIKeyReader(os.Stdin))
+	}
+	if cli.Offline {
+		loadOptions = append(loadOptions, config.WithOptionalAPIKey())
+	}
+	cfg, err := config.LoadConfig(cli.Config, loadOptions...)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "\n✗ Failed to load configuration: %v\n", err)
+		return exitError
+	}
+	if err := cli.applyConfig(cfg); err != nil {
+		fmt.Fprintf(os.Stderr, "\n✗ Invalid configuration: %v\n", err)
+		return exitError
+	}
+
+	clientOptions, err := apiClientOptions(cfg)
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "\n✗ Failed to load environment configuration: %v\n", err)
-		return 1
+		fmt.Fprintf(os.Stderr, "\n✗ Failed to configure Root.io API client: %v\n", err)
+		return exitError
+	}
+	cli.clientOptions = clientOptions
+	if cli.Offline {
+		db, err := common.LoadLocalDatabase(cli.DB)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "\n✗ Failed to load vulnerability database: %v\n", err)
+			return exitError
+		}
+		cli.offlineDB = db
+	}
+	if cli.PlanOut != "" {
+		cli.plan = common.NewPlan()
 	}
 
+	// In json and sarif modes stdout is reserved for the result document, and with --report-file
+	// the report goes to the file, so route logs and progress to stderr
+	console := os.Stdout
+	if cli.Output != outputText || cli.ReportFile != "" {
+		console = os.Stderr
+	}
+
+	// The human-readable report goes to stdout in text mode, next to progress and logs otherwise;
+	// --report-file takes the report in text mode and the result document in json and sarif modes
+	document, closeReport, err := cli.openReport(console, os.Stdout)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "\n✗ Failed to create report file: %v\n", err)
+		return exitError
+	}
+	defer closeReport()
+
+	// Progress lines only help someone watching a terminal, and would clutter logs and pipes
+	if cli.Output == outputText && !cli.Quiet && common.IsTerminal(console) {
+		cli.progress = common.NewProgress(console)
+	}
+
+	// Ask before applying patches, on stderr in json and sarif modes
+	cli.confirm = newConfirmer(cli.Yes, os.Stdin, console)
+
 	// Create logger with log level from config
-	logger := createLogger(cfg.LogLevel)
+	logger := createLogger(console, cfg.LogLevel, cli.Quiet)
+	if cfg.File != "" {
+		logger.DebugContext(ctx, "Loaded config file", slog.String("path", cfg.File))
+	}
+	for _, warning := range cfg.Warnings {
+		logger.WarnContext(ctx, warning)
+	}
 
-	// Execute the selected command, passing cfg and logger
-	if err := kongCtx.Run(cfg, logger); err != nil {
-		fmt.Fprintf(os.Stderr, "\n✗ Error: %v\n", err)
-		return 1
+	// Bound the whole workflow (package collection, API calls, patching) by --timeout
+	if cli.Timeout > 0 {
+		var cancelTimeout context.CancelFunc
+		ctx, cancelTimeout = context.WithTimeout(ctx, cli.Timeout)
+		defer cancelTimeout()
+		kongCtx.BindTo(ctx, (*context.Context)(nil))
 	}
 
-	return 0
+	// Execute the selected command, passing cfg, logger and a sink for the result
+	sink := &resultSink{}
+	runErr := kongCtx.Run(cfg, logger, sink, &cli.Globals)
+	if errors.Is(runErr, context.DeadlineExceeded) {
+		runErr = fmt.Errorf("timed out after %s: %w", cli.Timeout, runErr)
+	}
+	if runErr == nil && cli.plan != nil {
+		runErr = writePlan(cli.report, cli.plan, cli.PlanOut)
+	}
+	if runErr != nil {
+		fmt.Fprintf(os.Stderr, "\n✗ Error: %v\n", runErr)
+		if hint := errorHint(runErr); hint != "" {
+			fmt.Fprintf(os.Stderr, "  %s\n", hint)
+		}
+	}
+
+	switch cli.Output {
+	case outputJSON:
+		var err error
+		if sink.results != nil {
+			err = writeJSONResults(document, sink.results, runErr)
+		} else {
+			err = writeJSONResult(document, sink.result, runErr)
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "\n✗ Failed to write JSON output: %v\n", err)
+			return exitError
+		}
+	case outputSARIF:
+		if err := writeSARIFResults(document, sink.all(), runErr); err != nil {
+			fmt.Fprintf(os.Stderr, "\n✗ Failed to write SARIF output: %v\n", err)
+			return exitError
+		}
+	}
+
+	return exitCode(runErr, sink, &cli.Globals)
 }
 
-// createLogger creates a structured logger with the specified level
-func createLogger(logLevelStr string) *slog.Logger {
+// exitCode aggregates the outcome of everything that ran into the process exit code
+func exitCode(runErr error, sink *resultSink, globals *Globals) int {
+	if runErr != nil || sink.failed() {
+		return exitError
+	}
+	if globals.FailOnPatches && sink.unapplied() > 0 {
+		return globals.PatchesExitCode
+	}
+	return exitOK
+}
+
+// newConfirmer returns the Confirmer asking before patches are applied, or nil with --yes. The
+// prompt needs a terminal to answer on: when in or out isn't one (e.g. in CI), applying fails
+// with common.ErrConfirmationRequired instead of going ahead unconfirmed.
+func newConfirmer(yes bool, in, out *os.File) *common.Confirmer {
+	if yes {
+		return nil
+	}
+	return common.NewConfirmer(in, out, common.IsTerminal(in) && common.IsTerminal(out))
+}
+
+// errorHint suggests how to fix a failed API call, or returns "" for other errors
+func errorHint(err error) string {
+	switch {
+	case errors.Is(err, rootio.ErrUnauthorized):
+		return "The API key was rejected: check that ROOTIO_API_KEY is set to a valid, unexpired key"
+	case errors.Is(err, rootio.ErrForbidden):
+		return "The API key isn't allowed to use the remediation API: contact Root.io support"
+	case errors.Is(err, rootio.ErrRateLimited):
+		return "The Root.io API is rate limiting requests: wait a minute and try again"
+	case errors.Is(err, rootio.ErrServer):
+		return "The Root.io API failed to handle the request: try again later"
+	case errors.Is(err, rootio.ErrBadRequest):
+		return "The Root.io API rejected the request: run with LOG_LEVEL=debug and report the output"
+	default:
+		return ""
+	}
+}
+
+// applyConfig fills global flags from the config file and environment.
+// --min-severity on the command line wins over the configured one; configured
+// excludes are added to those given with --exclude.
+func (g *Globals) applyConfig(cfg *config.Config) error {
+	if cfg.MinSeverity != "" && g.MinSeverity == common.SeverityNone {
+		if !common.IsSeverity(cfg.MinSeverity) {
+			return fmt.Errorf("min_severity must be one of none, low, medium, high, critical, got %q", cfg.MinSeverity)
+		}
+		g.MinSeverity = strings.ToLower(cfg.MinSeverity)
+	}
+	g.Exclude = append(append([]string{}, cfg.Exclude...), g.Exclude...)
+	return nil
+}
+
+// Validate checks global flags after parsing
+func (g *Globals) Validate() error {
+	if g.PatchesExitCode == exitOK || g.PatchesExitCode == exitError || g.PatchesExitCode < 0 || g.PatchesExitCode > 255 {
+		return fmt.Errorf("--patches-exit-code must be between 2 and 255, got %d", g.PatchesExitCode)
+	}
+	if g.Timeout < 0 {
+		return fmt.Errorf("--timeout must not be negative, got %s", g.Timeout)
+	}
+	if g.Offline && g.DB == "" {
+		return fmt.Errorf("--offline needs a vulnerability database given with --db")
+	}
+	if g.DB != "" && !g.Offline {
+		return fmt.Errorf("--db is only used with --offline")
+	}
+	return nil
+}
+
+// Validate checks pip remediate flags after parsing
+func (cmd *PipRemediateCmd) Validate() error {
+	if cmd.Parallel < 1 {
+		return fmt.Errorf("--parallel must be at least 1, got %d", cmd.Parallel)
+	}
+	return nil
+}
+
+// Validate checks npm remediate flags after parsing
+func (cmd *NpmRemediateCmd) Validate() error {
+	if cmd.Registry == "" {
+		return nil
+	}
+	registry, err := url.Parse(cmd.Registry)
+	if err != nil || (registry.Scheme != "http" && registry.Scheme != "https") || registry.Host == "" {
+		return fmt.Errorf("--registry must be an http or https URL, got %q", cmd.Registry)
+	}
+	return nil
+}
+
+// openReport sets the writer the human-readable report goes to and returns the one the json or
+// sarif document goes to: report and document by default, or --report-file in their place
+// (the report in text mode, the document otherwise). With --quiet the report is dropped unless
+// it goes to --report-file. close closes the report file, if any.
+func (g *Globals) openReport(report, document io.Writer) (io.Writer, func() error, error) {
+	g.report = report
+	if g.Quiet {
+		g.report = io.Discard
+	}
+	if g.ReportFile == "" {
+		return document, func() error { return nil }, nil
+	}
+	file, err := os.Create(g.ReportFile)
+	if err != nil {
+		return nil, nil, err
+	}
+	if g.Output == outputText {
+		g.report = file
+	} else {
+		document = file
+	}
+	return document, file.Close, nil
+}
+
+// createLogger creates a structured logger writing to out with the specified level; quiet drops
+// everything below errors
+func createLogger(out io.Writer, logLevelStr string, quiet bool) *slog.Logger {
 	var logLevel slog.Level
 	switch logLevelStr {
 	case "debug":
@@ -113,32 +495,423 @@ func createLogger(logLevelStr string) *slog.Logger {
 	default:
 		logLevel = slog.LevelInfo
 	}
+	if quiet && logLevel < slog.LevelError {
+		logLevel = slog.LevelError
+	}
 
-	return slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{
+	return slog.New(slog.NewTextHandler(out, &slog.HandlerOptions{
 		Level: logLevel,
 	}))
 }
 
+// apiClientOptions builds Root.io API client options from the environment configuration.
+// Proxies are always taken from HTTPS_PROXY/NO_PROXY; ROOTIO_CA_CERT adds a trusted CA,
+// ROOTIO_REMEDIATE_PATH replaces the analysis endpoint's path and ROOTIO_AUTH_SCHEME changes
+// how the API key is sent.
+func apiClientOptions(cfg *config.Config) ([]rootio.Option, error) {
+	var options []rootio.Option
+	switch cfg.AuthScheme {
+	case "", rootio.AuthBasic, rootio.AuthBearer:
+		if cfg.AuthHeader != "" {
+			return nil, fmt.Errorf("ROOTIO_AUTH_HEADER is only used with ROOTIO_AUTH_SCHEME=%s", rootio.AuthHeader)
+		}
+	case rootio.AuthHeader:
+		if cfg.AuthHeader == "" || strings.ContainsAny(cfg.AuthHeader, " \t:\r\n") {
+			return nil, fmt.Errorf("ROOTIO_AUTH_SCHEME=%s needs a header name in ROOTIO_AUTH_HEADER, got %q",
+				rootio.AuthHeader, cfg.AuthHeader)
+		}
+	default:
+		return nil, fmt.Errorf("ROOTIO_AUTH_SCHEME must be %s, %s or %s, got %q",
+			rootio.AuthBasic, rootio.AuthBearer, rootio.AuthHeader, cfg.AuthScheme)
+	}
+	if cfg.AuthScheme != "" {
+		options = append(options, rootio.WithAuth(cfg.AuthScheme, cfg.AuthHeader))
+	}
+
+	if cfg.RemediatePath != "" {
+		if !strings.HasPrefix(cfg.RemediatePath, "/") || strings.ContainsAny(cfg.RemediatePath, "?#") {
+			return nil, fmt.Errorf("ROOTIO_REMEDIATE_PATH must be a URL path starting with /, got %q", cfg.RemediatePath)
+		}
+		options = append(options, rootio.WithRemediatePath(cfg.RemediatePath))
+	}
+
+	if cfg.CACert != "" {
+		tlsConfig, err := rootio.LoadTLSConfig(cfg.CACert)
+		if err != nil {
+			return nil, err
+		}
+		options = append(options, rootio.WithTLSConfig(tlsConfig))
+	}
+	return options, nil
+}
+
+// resolvePython returns the explicit --python-path, or auto-detects the interpreter when it is empty
+func resolvePython(ctx context.Context, explicit string, logger *slog.Logger) (string, error) {
+	if explicit != "" {
+		logger.DebugContext(ctx, "Using Python interpreter from --python-path", slog.String("path", explicit))
+		return explicit, nil
+	}
+
+	pythonPath, err := pip.DetectPython()
+	if err != nil {
+		return "", fmt.Errorf("failed to detect Python interpreter: %w", err)
+	}
+	logger.InfoContext(ctx, "Detected Python interpreter", slog.String("path", pythonPath))
+	return pythonPath, nil
+}
+
+// dependencyFiles returns the requirements, Poetry or pipenv files to patch, none for the live environment
+func (cmd *PipRemediateCmd) dependencyFiles() []string {
+	if cmd.Manifest != "" {
+		return []string{cmd.Manifest}
+	}
+	return cmd.Requirements
+}
+
 // Run executes the pip remediate command
-func (cmd *PipRemediateCmd) Run(ctx context.Context, cfg *config.Config, logger *slog.Logger) error {
+func (cmd *PipRemediateCmd) Run(
+	ctx context.Context, cfg *config.Config, logger *slog.Logger, sink *resultSink, globals *Globals,
+) error {
+	if files := cmd.dependencyFiles(); len(files) > 0 {
+		logger.InfoContext(ctx, "Starting pip file remediation", slog.String("file", strings.Join(files, ", ")))
+
+		app := pip.NewRequirementsApp(cfg.APIKey, cfg.APIURL, files[0], cmd.DryRun, logger,
+			common.WithAdditionalFiles(files[1:]...),
+			common.WithBackup(cmd.Backup),
+			common.WithConstraintStyle(cmd.ConstraintStyle),
+			common.WithMinSeverity(globals.MinSeverity),
+			common.WithPackageFilter(globals.Only, globals.Exclude),
+			common.WithCVEFilter(globals.CVE),
+			common.WithSkipDev(globals.SkipDev),
+			common.WithVerify(globals.Verify),
+			common.WithPlan(globals.plan),
+			common.WithProgress(globals.progress),
+			common.WithConfirm(globals.confirm),
+			common.WithOutput(globals.report),
+			common.WithCache(globals.CacheDir, globals.CacheTTL),
+			common.WithClientOptions(globals.clientOptions...),
+			common.WithOfflineDB(globals.offlineDB))
+		return sink.collect(app.RunWithResult(ctx))
+	}
+
 	logger.InfoContext(ctx, "Starting pip remediation")
 
-	app := pip.NewApp(cfg, cmd.PythonPath, cmd.DryRun, cmd.UseAlias, logger)
-	return app.Run(ctx)
+	pythonPath, err := resolvePython(ctx, cmd.PythonPath, logger)
+	if err != nil {
+		return err
+	}
+
+	app := pip.NewApp(cfg, pythonPath, cmd.DryRun, cmd.UseAlias, logger,
+		common.WithMinSeverity(globals.MinSeverity),
+		common.WithPackageFilter(globals.Only, globals.Exclude),
+		common.WithCVEFilter(globals.CVE),
+		common.WithCache(globals.CacheDir, globals.CacheTTL),
+		common.WithClientOptions(globals.clientOptions...),
+		common.WithOfflineDB(globals.offlineDB),
+		common.WithJournal(cmd.Journal),
+		common.WithConstraints(cmd.Constraints),
+		common.WithKeepGoing(cmd.KeepGoing),
+		common.WithParallel(cmd.Parallel),
+		common.WithNetrcCredentials(cmd.Netrc),
+		common.WithInstallDeps(cmd.InstallDeps),
+		common.WithTargetDir(cmd.Target),
+		common.WithVerify(globals.Verify),
+		common.WithPlan(globals.plan),
+		common.WithProgress(globals.progress),
+		common.WithConfirm(globals.confirm),
+		common.WithOutput(globals.report))
+	return sink.collect(app.RunWithResult(ctx))
+}
+
+// Run executes the pip rollback command
+func (cmd *PipRollbackCmd) Run(
+	ctx context.Context, cfg *config.Config, logger *slog.Logger, sink *resultSink, globals *Globals,
+) error {
+	logger.InfoContext(ctx, "Starting pip rollback", slog.String("journal", cmd.Journal))
+
+	pythonPath, err := resolvePython(ctx, cmd.PythonPath, logger)
+	if err != nil {
+		return err
+	}
+
+	app := pip.NewRollbackApp(cfg, pythonPath, cmd.Journal, cmd.DryRun, logger,
+		common.WithTargetDir(cmd.Target), common.WithOutput(globals.report))
+	return sink.collect(app.RunWithResult(ctx))
 }
 
 // Run executes the npm remediate command
-func (cmd *NpmRemediateCmd) Run(ctx context.Context, cfg *config.Config, logger *slog.Logger) error {
-	logger.InfoContext(ctx, "Starting npm remediation", slog.String("package_manager", cmd.PackageManager))
+func (cmd *NpmRemediateCmd) Run(
+	ctx context.Context, cfg *config.Config, logger *slog.Logger, sink *resultSink, globals *Globals,
+) error {
+	packageManager := cmd.PackageManager
+	if packageManager == "auto" {
+		detected, err := npm.DetectPackageManager(cmd.PackageJSON)
+		if err != nil {
+			return err
+		}
+		packageManager = detected
+	}
+	logger.InfoContext(ctx, "Starting npm remediation", slog.String("package_manager", packageManager))
 
-	app := npm.NewApp(cfg.APIKey, cfg.APIURL, cmd.PackageManager, cmd.DryRun, logger)
-	return app.Run(ctx)
+	app := npm.NewApp(cfg.APIKey, cfg.APIURL, packageManager, cmd.DryRun, logger,
+		common.WithBackup(cmd.Backup),
+		common.WithPackageJSON(cmd.PackageJSON),
+		common.WithUpdateLockfile(cmd.UpdateLockfile),
+		common.WithRegistry(cmd.Registry),
+		common.WithUseAlias(cmd.UseAlias),
+		common.WithStrictPeers(cmd.Strict),
+		common.WithMinSeverity(globals.MinSeverity),
+		common.WithPackageFilter(globals.Only, globals.Exclude),
+		common.WithCVEFilter(globals.CVE),
+		common.WithSkipDev(globals.SkipDev),
+		common.WithVerify(globals.Verify),
+		common.WithPlan(globals.plan),
+		common.WithProgress(globals.progress),
+		common.WithConfirm(globals.confirm),
+		common.WithOutput(globals.report),
+		common.WithCache(globals.CacheDir, globals.CacheTTL),
+		common.WithClientOptions(globals.clientOptions...),
+		common.WithOfflineDB(globals.offlineDB))
+	return sink.collect(app.RunWithResult(ctx))
 }
 
 // Run executes the maven remediate command
-func (cmd *MavenRemediateCmd) Run(ctx context.Context, cfg *config.Config, logger *slog.Logger) error {
+func (cmd *MavenRemediateCmd) Run(
+	ctx context.Context, cfg *config.Config, logger *slog.Logger, sink *resultSink, globals *Globals,
+) error {
 	logger.InfoContext(ctx, "Starting Maven remediation", slog.String("file", cmd.File))
 
-	app := maven.NewApp(cfg.APIKey, cfg.APIURL, cmd.File, cmd.DryRun, logger)
-	return app.Run(ctx)
+	app := maven.NewApp(cfg.APIKey, cfg.APIURL, cmd.File, cmd.DryRun, logger,
+		common.WithBackup(cmd.Backup),
+		common.WithResolveParent(cmd.ResolveParent),
+		common.WithRecursive(cmd.Recursive),
+		common.WithGroupPrefix(cmd.GroupPrefix),
+		common.WithProfiles(cmd.Profile),
+		common.WithUseAlias(cmd.UseAlias),
+		common.WithMinSeverity(globals.MinSeverity),
+		common.WithPackageFilter(globals.Only, globals.Exclude),
+		common.WithCVEFilter(globals.CVE),
+		common.WithSkipDev(globals.SkipDev),
+		common.WithVerify(globals.Verify),
+		common.WithPlan(globals.plan),
+		common.WithProgress(globals.progress),
+		common.WithConfirm(globals.confirm),
+		common.WithOutput(globals.report),
+		common.WithCache(globals.CacheDir, globals.CacheTTL),
+		common.WithClientOptions(globals.clientOptions...),
+		common.WithOfflineDB(globals.offlineDB))
+	return sink.collect(app.RunWithResult(ctx))
+}
+
+// Run executes the go remediate command
+func (cmd *GoRemediateCmd) Run(
+	ctx context.Context, cfg *config.Config, logger *slog.Logger, sink *resultSink, globals *Globals,
+) error {
+	logger.InfoContext(ctx, "Starting Go remediation", slog.String("file", cmd.File))
+
+	opts := []common.Option{
+		common.WithBackup(cmd.Backup),
+		common.WithMinSeverity(globals.MinSeverity),
+		common.WithPackageFilter(globals.Only, globals.Exclude),
+		common.WithCVEFilter(globals.CVE),
+		common.WithSkipDev(globals.SkipDev),
+		common.WithVerify(globals.Verify),
+		common.WithPlan(globals.plan),
+		common.WithProgress(globals.progress),
+		common.WithConfirm(globals.confirm),
+		common.WithOutput(globals.report),
+		common.WithCache(globals.CacheDir, globals.CacheTTL),
+		common.WithClientOptions(globals.clientOptions...),
+		common.WithOfflineDB(globals.offlineDB),
+	}
+	if cmd.Replace {
+		opts = append(opts, common.WithGoProxy(strings.TrimSuffix(cfg.PKGURL, "/")+"/go"))
+	}
+
+	app := gomod.NewApp(cfg.APIKey, cfg.APIURL, cmd.File, cmd.DryRun, logger, opts...)
+	return sink.collect(app.RunWithResult(ctx))
+}
+
+// Run executes the gem remediate command
+func (cmd *GemRemediateCmd) Run(
+	ctx context.Context, cfg *config.Config, logger *slog.Logger, sink *resultSink, globals *Globals,
+) error {
+	logger.InfoContext(ctx, "Starting RubyGems remediation", slog.String("file", cmd.File))
+
+	app := gem.NewApp(cfg.APIKey, cfg.APIURL, cmd.File, cmd.DryRun, logger,
+		common.WithBackup(cmd.Backup),
+		common.WithMinSeverity(globals.MinSeverity),
+		common.WithPackageFilter(globals.Only, globals.Exclude),
+		common.WithCVEFilter(globals.CVE),
+		common.WithSkipDev(globals.SkipDev),
+		common.WithVerify(globals.Verify),
+		common.WithPlan(globals.plan),
+		common.WithProgress(globals.progress),
+		common.WithConfirm(globals.confirm),
+		common.WithOutput(globals.report),
+		common.WithCache(globals.CacheDir, globals.CacheTTL),
+		common.WithClientOptions(globals.clientOptions...),
+		common.WithOfflineDB(globals.offlineDB))
+	return sink.collect(app.RunWithResult(ctx))
+}
+
+// Run executes the nuget remediate command
+func (cmd *NuGetRemediateCmd) Run(
+	ctx context.Context, cfg *config.Config, logger *slog.Logger, sink *resultSink, globals *Globals,
+) error {
+	logger.InfoContext(ctx, "Starting NuGet remediation", slog.String("file", cmd.File))
+
+	app := nuget.NewApp(cfg.APIKey, cfg.APIURL, cmd.File, cmd.DryRun, logger,
+		common.WithBackup(cmd.Backup),
+		common.WithMinSeverity(globals.MinSeverity),
+		common.WithPackageFilter(globals.Only, globals.Exclude),
+		common.WithCVEFilter(globals.CVE),
+		common.WithSkipDev(globals.SkipDev),
+		common.WithVerify(globals.Verify),
+		common.WithPlan(globals.plan),
+		common.WithProgress(globals.progress),
+		common.WithConfirm(globals.confirm),
+		common.WithOutput(globals.report),
+		common.WithCache(globals.CacheDir, globals.CacheTTL),
+		common.WithClientOptions(globals.clientOptions...),
+		common.WithOfflineDB(globals.offlineDB))
+	return sink.collect(app.RunWithResult(ctx))
+}
+
+// Run executes the apt remediate command
+func (cmd *AptRemediateCmd) Run(
+	ctx context.Context, cfg *config.Config, logger *slog.Logger, sink *resultSink, globals *Globals,
+) error {
+	logger.InfoContext(ctx, "Starting apt remediation")
+
+	app := apt.NewApp(cfg, cmd.DryRun, logger,
+		common.WithMinSeverity(globals.MinSeverity),
+		common.WithPackageFilter(globals.Only, globals.Exclude),
+		common.WithCVEFilter(globals.CVE),
+		common.WithCache(globals.CacheDir, globals.CacheTTL),
+		common.WithClientOptions(globals.clientOptions...),
+		common.WithOfflineDB(globals.offlineDB),
+		common.WithKeepGoing(cmd.KeepGoing),
+		common.WithPlan(globals.plan),
+		common.WithProgress(globals.progress),
+		common.WithConfirm(globals.confirm),
+		common.WithOutput(globals.report))
+	return sink.collect(app.RunWithResult(ctx))
+}
+
+// Run executes the scan command
+func (cmd *ScanCmd) Run(
+	ctx context.Context, cfg *config.Config, logger *slog.Logger, sink *resultSink, globals *Globals,
+) error {
+	if cmd.SBOM != "" {
+		return cmd.runSBOM(ctx, cfg, logger, sink, globals)
+	}
+
+	logger.InfoContext(ctx, "Scanning for dependency files", slog.String("path", cmd.Path))
+
+	parsers := []common.Parser{
+		npm.NewParser(),
+		maven.NewParser(),
+		maven.NewGradleParser(),
+		gomod.NewParser(),
+		gem.NewParser(),
+		nuget.NewParser(),
+		pip.NewParser(),
+		pip.NewPoetryParser(),
+		pip.NewPipenvParser(),
+	}
+	targets, err := scan.Discover(cmd.Path, parsers, cmd.Ignore)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(globals.report, "\nFound %d dependency files under %s\n", len(targets), cmd.Path)
+
+	opts := []common.Option{
+		common.WithBackup(cmd.Backup),
+		common.WithMinSeverity(globals.MinSeverity),
+		common.WithPackageFilter(globals.Only, globals.Exclude),
+		common.WithCVEFilter(globals.CVE),
+		common.WithSkipDev(globals.SkipDev),
+		common.WithVerify(globals.Verify),
+		common.WithPlan(globals.plan),
+		common.WithProgress(globals.progress),
+		common.WithConfirm(globals.confirm),
+		common.WithCache(globals.CacheDir, globals.CacheTTL),
+		common.WithClientOptions(globals.clientOptions...),
+		common.WithOfflineDB(globals.offlineDB),
+	}
+
+	newApp := func(target scan.Target, out io.Writer) common.Runner {
+		opts := append([]common.Option{common.WithOutput(out)}, opts...)
+		switch target.Ecosystem {
+		case common.EcosystemNpm:
+			packageJSON := filepath.Join(filepath.Dir(target.Path), "package.json")
+			return npm.NewApp(cfg.APIKey, cfg.APIURL, npm.PackageManagerForLockFile(target.Path), cmd.DryRun, logger,
+				append(opts, common.WithPackageJSON(packageJSON))...)
+		case common.EcosystemMaven:
+			return maven.NewApp(cfg.APIKey, cfg.APIURL, target.Path, cmd.DryRun, logger, opts...)
+		case common.EcosystemGo:
+			return gomod.NewApp(cfg.APIKey, cfg.APIURL, target.Path, cmd.DryRun, logger, opts...)
+		case common.EcosystemRubyGems:
+			return gem.NewApp(cfg.APIKey, cfg.APIURL, target.Path, cmd.DryRun, logger, opts...)
+		case common.EcosystemNuGet:
+			return nuget.NewApp(cfg.APIKey, cfg.APIURL, target.Path, cmd.DryRun, logger, opts...)
+		default:
+			return pip.NewRequirementsApp(cfg.APIKey, cfg.APIURL, target.Path, cmd.DryRun, logger, opts...)
+		}
+	}
+
+	// Confirmation prompts can't be answered for several ecosystems at once
+	jobs := cmd.Jobs
+	if !cmd.DryRun && globals.confirm.Prompts() {
+		jobs = 1
+	}
+	fileResults, runErr := scan.Run(ctx, targets, jobs, globals.report, newApp)
+
+	var results []*common.RunResult
+	var failed []string
+	for _, fr := range fileResults {
+		if fr.Err != nil {
+			logger.ErrorContext(ctx, "Failed to remediate file",
+				slog.String("file", fr.Target.Path),
+				slog.String("error", fr.Err.Error()))
+			failed = append(failed, fr.Target.Path)
+		}
+		results = append(results, fr.Result)
+	}
+	if err := ctx.Err(); err != nil {
+		return sink.collectAll(results, err)
+	}
+
+	scan.WriteSummary(globals.report, fileResults)
+
+	if runErr != nil {
+		return sink.collectAll(results, fmt.Errorf("%d of %d files failed: %v", len(failed), len(targets), failed))
+	}
+	return sink.collectAll(results, nil)
+}
+
+// runSBOM analyzes the packages listed in an SBOM, routing each ecosystem to its remediate endpoint
+func (cmd *ScanCmd) runSBOM(
+	ctx context.Context, cfg *config.Config, logger *slog.Logger, sink *resultSink, globals *Globals,
+) error {
+	// An SBOM records a build, not files that can be patched
+	if !cmd.DryRun {
+		return fmt.Errorf("--sbom only reports available patches; run each ecosystem's remediate command with --dry-run=false to apply them")
+	}
+	logger.InfoContext(ctx, "Analyzing SBOM", slog.String("file", cmd.SBOM))
+
+	app := sbom.NewApp(cfg.APIKey, cfg.APIURL, cmd.SBOM, logger,
+		common.WithMinSeverity(globals.MinSeverity),
+		common.WithPackageFilter(globals.Only, globals.Exclude),
+		common.WithCVEFilter(globals.CVE),
+		common.WithSkipDev(globals.SkipDev),
+		common.WithProgress(globals.progress),
+		common.WithOutput(globals.report),
+		common.WithCache(globals.CacheDir, globals.CacheTTL),
+		common.WithClientOptions(globals.clientOptions...),
+		common.WithOfflineDB(globals.offlineDB),
+	)
+	err := app.Run(ctx)
+	return sink.collectAll(app.Results(), err)
 }
@@ -0,0 +1,427 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"rootio_patcher/cmd/rootio_patcher/common"
+	"rootio_patcher/cmd/rootio_patcher/config"
+	"rootio_patcher/pkg/rootio"
+)
+
+// resultWithPatches returns a result with one patch per status
+func resultWithPatches(statuses ...common.PatchStatus) *common.RunResult {
+	result := common.NewRunResult(common.EcosystemNpm, "package-lock.json", false)
+	for _, status := range statuses {
+		result.AddPatches([]rootio.PackagePatch{{PackageName: "express", Version: "4.17.1"}}, true, status)
+	}
+	return result
+}
+
+func TestExitCode(t *testing.T) {
+	tests := []struct {
+		name          string
+		runErr        error
+		sink          *resultSink
+		failOnPatches bool
+		expected      int
+	}{
+		{
+			name:     "no patches",
+			sink:     &resultSink{result: resultWithPatches()},
+			expected: exitOK,
+		},
+		{
+			name:     "dry run without --fail-on-patches",
+			sink:     &resultSink{result: resultWithPatches(common.PatchStatusDryRun)},
+			expected: exitOK,
+		},
+		{
+			name:          "dry run with --fail-on-patches",
+			sink:          &resultSink{result: resultWithPatches(common.PatchStatusDryRun)},
+			failOnPatches: true,
+			expected:      7,
+		},
+		{
+			name:          "all applied",
+			sink:          &resultSink{result: resultWithPatches(common.PatchStatusApplied)},
+			failOnPatches: true,
+			expected:      exitOK,
+		},
+		{
+			name:          "failed patch wins",
+			sink:          &resultSink{result: resultWithPatches(common.PatchStatusFailed, common.PatchStatusNotApplied)},
+			failOnPatches: true,
+			expected:      exitError,
+		},
+		{
+			name:          "error",
+			runErr:        errors.New("lock file not found"),
+			sink:          &resultSink{},
+			failOnPatches: true,
+			expected:      exitError,
+		},
+		{
+			name: "aggregated across scan results",
+			sink: &resultSink{results: []*common.RunResult{
+				resultWithPatches(),
+				resultWithPatches(common.PatchStatusDryRun),
+			}},
+			failOnPatches: true,
+			expected:      7,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			globals := &Globals{FailOnPatches: tt.failOnPatches, PatchesExitCode: 7}
+			if code := exitCode(tt.runErr, tt.sink, globals); code != tt.expected {
+				t.Errorf("Expected exit code %d, got %d", tt.expected, code)
+			}
+		})
+	}
+}
+
+func TestErrorHint(t *testing.T) {
+	wrapped := func(status int) error {
+		return fmt.Errorf("failed to analyze packages: %w", &rootio.APIError{StatusCode: status})
+	}
+
+	tests := []struct {
+		name string
+		err  error
+		want string
+	}{
+		{"unauthorized", wrapped(401), "ROOTIO_API_KEY"},
+		{"forbidden", wrapped(403), "contact Root.io support"},
+		{"rate limited", wrapped(429), "wait a minute"},
+		{"server error", wrapped(502), "try again later"},
+		{"bad request", wrapped(400), "LOG_LEVEL=debug"},
+		{"other error", errors.New("lock file not found"), ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hint := errorHint(tt.err)
+			if tt.want == "" && hint != "" || !strings.Contains(hint, tt.want) {
+				t.Errorf("Expected a hint containing %q, got %q", tt.want, hint)
+			}
+		})
+	}
+}
+
+func TestGlobals_Validate(t *testing.T) {
+	for _, code := range []int{0, 1, -1, 256} {
+		if err := (&Globals{PatchesExitCode: code}).Validate(); err == nil {
+			t.Errorf("Expected --patches-exit-code=%d to be rejected", code)
+		}
+	}
+	if err := (&Globals{PatchesExitCode: 2}).Validate(); err != nil {
+		t.Errorf("Expected --patches-exit-code=2 to be valid, got: %v", err)
+	}
+	if err := (&Globals{PatchesExitCode: 2, Timeout: -time.Second}).Validate(); err == nil {
+		t.Error("Expected a negative --timeout to be rejected")
+	}
+}
+
+func TestPipRemediateCmd_Validate(t *testing.T) {
+	if err := (&PipRemediateCmd{Parallel: 0}).Validate(); err == nil {
+		t.Error("Expected --parallel=0 to be rejected")
+	}
+	if err := (&PipRemediateCmd{Parallel: 4}).Validate(); err != nil {
+		t.Errorf("Expected --parallel=4 to be valid, got: %v", err)
+	}
+}
+
+func TestNpmRemediateCmd_Validate(t *testing.T) {
+	for _, registry := range []string{"", "https://npm.example.com/", "http://localhost:4873"} {
+		if err := (&NpmRemediateCmd{Registry: registry}).Validate(); err != nil {
+			t.Errorf("Expected --registry=%q to be valid, got: %v", registry, err)
+		}
+	}
+	for _, registry := range []string{"npm.example.com", "ftp://npm.example.com", "https://"} {
+		if err := (&NpmRemediateCmd{Registry: registry}).Validate(); err == nil {
+			t.Errorf("Expected --registry=%q to be rejected", registry)
+		}
+	}
+}
+
+func TestGlobals_ApplyConfig(t *testing.T) {
+	cfg := &config.Config{MinSeverity: "High", Exclude: []string{"@types/*"}}
+
+	globals := &Globals{MinSeverity: common.SeverityNone, Exclude: []string{"lodash"}}
+	if err := globals.applyConfig(cfg); err != nil {
+		t.Fatalf("applyConfig failed: %v", err)
+	}
+	if globals.MinSeverity != common.SeverityHigh {
+		t.Errorf("Expected configured min severity, got %s", globals.MinSeverity)
+	}
+	if !reflect.DeepEqual(globals.Exclude, []string{"@types/*", "lodash"}) {
+		t.Errorf("Expected configured and flag excludes, got %v", globals.Exclude)
+	}
+
+	// --min-severity on the command line wins
+	globals = &Globals{MinSeverity: common.SeverityCritical}
+	if err := globals.applyConfig(cfg); err != nil {
+		t.Fatalf("applyConfig failed: %v", err)
+	}
+	if globals.MinSeverity != common.SeverityCritical {
+		t.Errorf("Expected flag min severity to win, got %s", globals.MinSeverity)
+	}
+
+	globals = &Globals{MinSeverity: common.SeverityNone}
+	if err := globals.applyConfig(&config.Config{MinSeverity: "urgent"}); err == nil {
+		t.Error("Expected error for an invalid min_severity")
+	}
+}
+
+func TestApplyCmd_App(t *testing.T) {
+	cmd := &ApplyCmd{}
+	cfg := &config.Config{PKGURL: "https://pkg.root.io"}
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	for _, ecosystem := range []common.Ecosystem{common.EcosystemMaven, common.EcosystemGo, common.EcosystemRubyGems, common.EcosystemNpm, common.EcosystemNuGet} {
+		if _, err := cmd.app(context.Background(), cfg, logger, common.PlanEntry{Ecosystem: ecosystem, File: "deps"}, &Globals{}); err != nil {
+			t.Errorf("Expected an app for %s, got: %v", ecosystem, err)
+		}
+	}
+	if _, err := cmd.app(context.Background(), cfg, logger, common.PlanEntry{Ecosystem: "cargo"}, &Globals{}); err == nil {
+		t.Error("Expected an unsupported ecosystem to be rejected")
+	}
+}
+
+func TestNewConfirmer(t *testing.T) {
+	// Files and pipes stand in for CI, where stdin and stdout aren't terminals
+	in, err := os.CreateTemp(t.TempDir(), "stdin")
+	if err != nil {
+		t.Fatalf("Failed to create stdin: %v", err)
+	}
+	defer in.Close()
+	if _, err := in.WriteString("y\n"); err != nil {
+		t.Fatalf("Failed to write stdin: %v", err)
+	}
+	if _, err := in.Seek(0, io.SeekStart); err != nil {
+		t.Fatalf("Failed to rewind stdin: %v", err)
+	}
+	out, err := os.CreateTemp(t.TempDir(), "stdout")
+	if err != nil {
+		t.Fatalf("Failed to create stdout: %v", err)
+	}
+	defer out.Close()
+
+	if confirm := newConfirmer(true, in, out); confirm != nil {
+		t.Errorf("Expected --yes to skip confirmation, got %+v", confirm)
+	}
+
+	// Without a terminal the "y" on stdin isn't taken as an answer: --yes is required
+	err = newConfirmer(false, in, out).Confirm(2, "pom.xml")
+	if !errors.Is(err, common.ErrConfirmationRequired) {
+		t.Errorf("Expected ErrConfirmationRequired without a terminal, got %v", err)
+	}
+	if info, _ := out.Stat(); info.Size() != 0 {
+		t.Errorf("Expected no prompt without a terminal, got %d bytes", info.Size())
+	}
+}
+
+func TestAPIClientOptions_RemediatePath(t *testing.T) {
+	options, err := apiClientOptions(&config.Config{RemediatePath: "/staging/v3/remediate/{ecosystem}"})
+	if err != nil || len(options) != 1 {
+		t.Fatalf("Expected one client option for the remediate path, got %d (%v)", len(options), err)
+	}
+	if client := rootio.NewClient("https://api.example.com", "key", options...); client.RemediateURL() !=
+		"https://api.example.com/staging/v3/remediate/pypi" {
+		t.Errorf("Expected the custom remediate path, got %s", client.RemediateURL())
+	}
+
+	for _, path := range []string{"v3/remediate/{ecosystem}", "/v3/remediate?ecosystem={ecosystem}"} {
+		if _, err := apiClientOptions(&config.Config{RemediatePath: path}); err == nil {
+			t.Errorf("Expected remediate path %q to be rejected", path)
+		}
+	}
+}
+
+func TestAPIClientOptions_Auth(t *testing.T) {
+	var header http.Header
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		header = r.Header.Clone()
+		json.NewEncoder(w).Encode(rootio.AnalyzePackagesResponse{})
+	}))
+	defer server.Close()
+
+	options, err := apiClientOptions(&config.Config{AuthScheme: "header", AuthHeader: "X-API-Key"})
+	if err != nil {
+		t.Fatalf("Expected the header scheme to be accepted, got %v", err)
+	}
+	client := rootio.NewClient(server.URL, "test-key", options...)
+	if _, err := client.AnalyzePackages(context.Background(), []rootio.Package{{Name: "pkg", Version: "1.0.0"}}); err != nil {
+		t.Fatalf("AnalyzePackages failed: %v", err)
+	}
+	if header.Get("X-API-Key") != "test-key" {
+		t.Errorf("Expected the API key in X-API-Key, got headers %v", header)
+	}
+
+	for _, cfg := range []config.Config{
+		{AuthScheme: "token"},
+		{AuthScheme: "header"},
+		{AuthScheme: "header", AuthHeader: "X-API-Key: x"},
+		{AuthScheme: "bearer", AuthHeader: "X-API-Key"},
+	} {
+		if _, err := apiClientOptions(&cfg); err == nil {
+			t.Errorf("Expected auth scheme %q with header %q to be rejected", cfg.AuthScheme, cfg.AuthHeader)
+		}
+	}
+}
+
+func TestScanCmd_SBOMReportOnly(t *testing.T) {
+	cmd := &ScanCmd{SBOM: "sbom.json", DryRun: false}
+	cfg := &config.Config{APIURL: "https://api.example.com"}
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	if err := cmd.Run(context.Background(), cfg, logger, &resultSink{}, &Globals{}); err == nil ||
+		!strings.Contains(err.Error(), "only reports") {
+		t.Errorf("Expected --sbom with --dry-run=false to be rejected, got %v", err)
+	}
+}
+
+// rackPatchServer serves an analysis response with a patch for the gem fixture's rack
+func rackPatchServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(rootio.AnalyzePackagesResponse{
+			Patches: []rootio.PackagePatch{
+				{PackageName: "rack", Version: "2.2.4", Patch: rootio.PatchInfo{Name: "rack", Version: "2.2.8.1"}},
+			},
+		})
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestGemRemediateCmd_ReportFile(t *testing.T) {
+	server := rackPatchServer(t)
+
+	path := filepath.Join(t.TempDir(), "report.txt")
+	globals := &Globals{Output: outputText, ReportFile: path}
+	var stdout strings.Builder
+	if _, closeReport, err := globals.openReport(&stdout, io.Discard); err != nil {
+		t.Fatalf("Failed to open report file: %v", err)
+	} else {
+		defer closeReport()
+	}
+
+	cmd := &GemRemediateCmd{File: "gem/testdata/Gemfile.lock", DryRun: true}
+	cfg := &config.Config{APIKey: "test-key", APIURL: server.URL}
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	if err := cmd.Run(context.Background(), cfg, logger, &resultSink{}, globals); err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+
+	report, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Failed to read report file: %v", err)
+	}
+	for _, want := range []string{"rack", "2.2.4", "2.2.8.1"} {
+		if !strings.Contains(string(report), want) {
+			t.Errorf("Expected the report file to contain %q, got:\n%s", want, report)
+		}
+	}
+	if stdout.Len() != 0 {
+		t.Errorf("Expected nothing on stdout with --report-file, got:\n%s", stdout.String())
+	}
+}
+
+func TestGemRemediateCmd_Quiet(t *testing.T) {
+	server := rackPatchServer(t)
+
+	globals := &Globals{Output: outputText, Quiet: true}
+	var stdout strings.Builder
+	if _, _, err := globals.openReport(&stdout, io.Discard); err != nil {
+		t.Fatalf("Failed to open report: %v", err)
+	}
+
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	cmd := &GemRemediateCmd{File: "gem/testdata/Gemfile.lock", DryRun: true}
+	cfg := &config.Config{APIKey: "test-key", APIURL: server.URL}
+	sink := &resultSink{}
+	if err := cmd.Run(context.Background(), cfg, logger, sink, globals); err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+
+	if stdout.Len() != 0 {
+		t.Errorf("Expected no output with --quiet, got:\n%s", stdout.String())
+	}
+	// The result is still collected for --output and the exit code
+	if sink.result == nil || len(sink.result.Patches) != 1 {
+		t.Errorf("Expected the rack patch to be collected, got %+v", sink.result)
+	}
+}
+
+func TestGemRemediateCmd_Offline(t *testing.T) {
+	globals := &Globals{Output: outputText, Offline: true, DB: "testdata/offline-db.json", PatchesExitCode: 2}
+	if err := globals.Validate(); err != nil {
+		t.Fatalf("Expected --offline with --db to be valid, got %v", err)
+	}
+	db, err := common.LoadLocalDatabase(globals.DB)
+	if err != nil {
+		t.Fatalf("Failed to load database: %v", err)
+	}
+	globals.offlineDB = db
+	var stdout strings.Builder
+	if _, _, err := globals.openReport(&stdout, io.Discard); err != nil {
+		t.Fatalf("Failed to open report: %v", err)
+	}
+
+	// No API URL or key: the API must not be called
+	cmd := &GemRemediateCmd{File: "gem/testdata/Gemfile.lock", DryRun: true}
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	sink := &resultSink{}
+	if err := cmd.Run(context.Background(), &config.Config{}, logger, sink, globals); err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+
+	if sink.result == nil || len(sink.result.Patches) != 1 {
+		t.Fatalf("Expected the rack patch from the database, got %+v", sink.result)
+	}
+	if patch := sink.result.Patches[0]; patch.PackageName != "rack" || patch.PatchedVersion != "2.2.8.1" {
+		t.Errorf("Expected rack 2.2.4 → 2.2.8.1, got %+v", patch)
+	}
+	for _, want := range []string{"rack", "2.2.8.1", "CVE-2023-27530"} {
+		if !strings.Contains(stdout.String(), want) {
+			t.Errorf("Expected %q in the report, got:\n%s", want, stdout.String())
+		}
+	}
+}
+
+func TestGlobals_ValidateOffline(t *testing.T) {
+	for _, g := range []Globals{{Offline: true}, {DB: "db.json"}} {
+		g.PatchesExitCode = 2
+		if err := g.Validate(); err == nil {
+			t.Errorf("Expected --offline=%v with --db=%q to be rejected", g.Offline, g.DB)
+		}
+	}
+}
+
+func TestCreateLogger_Quiet(t *testing.T) {
+	ctx := context.Background()
+	for _, level := range []string{"debug", "info", "warn"} {
+		logger := createLogger(io.Discard, level, true)
+		if logger.Enabled(ctx, slog.LevelWarn) || !logger.Enabled(ctx, slog.LevelError) {
+			t.Errorf("Expected --quiet with LOG_LEVEL=%s to only log errors", level)
+		}
+	}
+	if !createLogger(io.Discard, "info", false).Enabled(ctx, slog.LevelInfo) {
+		t.Error("Expected info logs without --quiet")
+	}
+}
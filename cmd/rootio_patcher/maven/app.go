@@ -3,8 +3,12 @@ package maven
 import (
 	"context"
 	"fmt"
+	"io"
 	"log/slog"
 	"os"
+	"path/filepath"
+	"sort"
+	"strings"
 
 	"rootio_patcher/cmd/rootio_patcher/common"
 	"rootio_patcher/pkg/rootio"
@@ -19,21 +23,49 @@ type App struct {
 	logger    *slog.Logger
 	parser    common.Parser
 	apiClient common.APIClient
+	reporter  *common.Reporter
+	options   common.Options
+	out       io.Writer
+
+	// files are the build files being remediated: the build file, plus its modules with Recursive
+	files []string
+
+	result *common.RunResult
+}
+
+// moduleUpdater updates versions across the POMs of a multi-module build
+type moduleUpdater interface {
+	UpdateModules(ctx context.Context, filePaths []string, updates map[string]string) (map[string]string, error)
+}
+
+// stagedParser parses build files as if updated content had been written
+type stagedParser interface {
+	ParseUpdated(ctx context.Context, filePath string, updated map[string]string) ([]common.PackageInfo, error)
 }
 
 // NewApp creates a new Maven application instance
-func NewApp(apiKey, apiURL, filePath string, dryRun bool, logger *slog.Logger) *App {
+func NewApp(apiKey, apiURL, filePath string, dryRun bool, logger *slog.Logger, opts ...common.Option) *App {
 	return NewAppWithServices(
 		apiKey,
 		apiURL,
 		filePath,
 		dryRun,
 		logger,
-		NewParser(),
-		rootio.NewClient(apiURL, apiKey),
+		newParserForFile(filePath, common.NewOptions(opts...)),
+		common.NewAPIClient(common.EcosystemMaven, apiURL, apiKey, logger, opts...),
+		opts...,
 	)
 }
 
+// newParserForFile selects the Gradle or Maven parser based on the build file name
+func newParserForFile(filePath string, options common.Options) common.Parser {
+	if gradle := NewGradleParser(); gradle.CanHandle(filePath) {
+		return gradle
+	}
+	// Modules inherit from the reactor's parent POMs, so recursive runs always resolve them
+	return NewParser(WithParentResolution(options.ResolveParent || options.Recursive), WithProfiles(options.Profiles))
+}
+
 // NewAppWithServices creates a new Maven app with injected services (for testing)
 func NewAppWithServices(
 	apiKey, apiURL, filePath string,
@@ -41,7 +73,12 @@ func NewAppWithServices(
 	logger *slog.Logger,
 	parser common.Parser,
 	apiClient common.APIClient,
+	opts ...common.Option,
 ) *App {
+	options := common.NewOptions(opts...)
+	reporter := common.NewEcosystemReporter(common.EcosystemMaven, apiURL, logger,
+		common.WithBuildFile(filePath, buildCommand(filePath)), common.WithWriter(options.Output()))
+
 	return &App{
 		apiKey:    apiKey,
 		apiURL:    apiURL,
@@ -50,44 +87,95 @@ func NewAppWithServices(
 		logger:    logger,
 		parser:    parser,
 		apiClient: apiClient,
+		reporter:  reporter,
+		options:   options,
+		out:       options.Output(),
 	}
 }
 
+// Result returns the structured result of the last run
+func (a *App) Result() *common.RunResult {
+	return a.result
+}
+
+// RunWithResult runs the Maven remediation workflow and returns its structured result
+func (a *App) RunWithResult(ctx context.Context) (*common.RunResult, error) {
+	err := a.Run(ctx)
+	a.result.SetError(err)
+	return a.result, err
+}
+
 // Run executes the Maven remediation workflow
 func (a *App) Run(ctx context.Context) error {
 	a.logger.DebugContext(ctx, "Starting Maven remediation",
 		slog.String("file", a.filePath),
 		slog.Bool("dry_run", a.dryRun))
+	a.result = common.NewRunResult(common.EcosystemMaven, a.filePath, a.dryRun)
 
 	// 1. Check if file exists
 	if _, err := os.Stat(a.filePath); err != nil {
 		return fmt.Errorf("file not found: %s", a.filePath)
 	}
 
-	// 2. Parse pom.xml
-	a.logger.DebugContext(ctx, "Parsing pom.xml")
-	packages, err := a.parser.Parse(ctx, a.filePath)
+	// Find the module POMs of a multi-module build
+	a.files = []string{a.filePath}
+	if a.options.Recursive {
+		if _, ok := a.parser.(moduleUpdater); !ok {
+			return fmt.Errorf("--recursive only supports pom.xml, not %s", filepath.Base(a.filePath))
+		}
+		files, err := discoverModules(a.filePath)
+		if err != nil {
+			return fmt.Errorf("failed to find modules of %s: %w", a.filePath, err)
+		}
+		a.files = files
+		fmt.Fprintf(a.out, "\nFound %d module POMs under %s\n", len(files)-1, a.filePath)
+	}
+
+	// 2. Parse the build files
+	a.logger.DebugContext(ctx, "Parsing build files", slog.Int("files", len(a.files)))
+	packages, err := a.parseFiles(ctx)
 	if err != nil {
-		return fmt.Errorf("failed to parse %s: %w", a.filePath, err)
+		return err
 	}
 	a.logger.DebugContext(ctx, "Parsed packages", slog.Int("count", len(packages)))
+	a.options.Progress.Printf("Found %d packages in %s", len(packages), a.filePath)
+	a.result.PackagesFound = len(packages)
+
+	// Versions picked from a range at build time can't be analyzed
+	packages, rangeSkipped := skipRanges(packages)
+
+	// Leave test-scoped dependencies out of production-only scans
+	if a.options.SkipDev {
+		packages, a.result.DevSkipped = common.FilterDev(packages)
+		if a.result.DevSkipped > 0 {
+			fmt.Fprintf(a.out, "\nSkipped %d dev dependencies (--skip-dev)\n", a.result.DevSkipped)
+		}
+	}
 
 	if len(packages) == 0 {
-		fmt.Println("\nNo packages found in pom.xml")
+		a.result.AddSkipped(rangeSkipped)
+		a.reporter.ReportSkipped(rangeSkipped)
+		fmt.Fprintf(a.out, "\nNo packages found in %s\n", a.filePath)
 		return nil
 	}
 
-	// 3. Convert to SDK format
-	sdkPackages := make([]rootio.Package, len(packages))
-	for i, pkg := range packages {
-		sdkPackages[i] = rootio.Package{
-			Name:    pkg.Name,
-			Version: pkg.Version,
+	// 3. Convert to SDK format; a version declared by several modules is analyzed once,
+	// as dev-only if every module declares it in test scope
+	var sdkPackages []rootio.Package
+	seen := make(map[string]int)
+	for _, pkg := range packages {
+		key := pkg.Name + "@" + pkg.Version
+		if i, ok := seen[key]; ok {
+			sdkPackages[i].Dev = sdkPackages[i].Dev && pkg.Dev
+			continue
 		}
+		seen[key] = len(sdkPackages)
+		sdkPackages = append(sdkPackages, pkg.SDKPackage())
 	}
 
 	// 4. Call backend API to analyze vulnerabilities
 	a.logger.DebugContext(ctx, "Analyzing packages for vulnerabilities")
+	a.options.Progress.Printf("Analyzing %d packages for vulnerabilities...", len(sdkPackages))
 	response, err := a.apiClient.AnalyzePackages(ctx, sdkPackages)
 	if err != nil {
 		return fmt.Errorf("failed to analyze packages: %w", err)
@@ -97,78 +185,351 @@ func (a *App) Run(ctx context.Context) error {
 	a.logger.DebugContext(ctx, "Vulnerability analysis complete",
 		slog.Int("patches_available", len(response.Patches)),
 		slog.Int("packages_skipped", len(response.Skipped)))
+	response.Skipped = append(response.Skipped, rangeSkipped...)
+
+	// Drop invalid names, patches without a version to install, patches left out by
+	// --min-severity, --only, --exclude and --cve, and patches that aren't upgrades
+	patches, filterSkipped := common.FilterPatches(common.EcosystemMaven, response.Patches, a.useAlias(), a.options)
+	response.Patches = patches
+	response.Skipped = append(response.Skipped, filterSkipped...)
+	common.WriteCVEMatches(a.out, response.Patches, a.options.CVEs)
+
+	// Drop patches outside the group selected by --group-prefix
+	patches, groupSkipped := filterByGroup(response.Patches, a.options.GroupPrefix)
+	response.Patches = patches
+	response.Skipped = append(response.Skipped, groupSkipped...)
+	if len(groupSkipped) > 0 {
+		fmt.Fprintf(a.out, "\nSkipped %d patches outside group %s (--group-prefix)\n", len(groupSkipped), a.options.GroupPrefix)
+	}
+
+	// Versions inherited from a parent POM outside the files being remediated can't be changed
+	patches, inheritedSkipped := a.skipInherited(response.Patches, packages)
+	response.Patches = patches
+	response.Skipped = append(response.Skipped, inheritedSkipped...)
+
+	// Artifacts removed from the build with <exclusions> aren't worth patching
+	patches, excludedSkipped := skipExcluded(response.Patches, packages)
+	response.Patches = patches
+	response.Skipped = append(response.Skipped, excludedSkipped...)
+	a.result.AddSkipped(response.Skipped)
+	a.reporter.ReportSkipped(response.Skipped)
 
 	if len(response.Patches) == 0 {
-		fmt.Println("\nNo patches needed - all packages are up to date!")
+		fmt.Fprintln(a.out, "\nNo patches needed - all packages are up to date!")
 		return nil
 	}
 
 	// 6. Execute or dry-run patches
 	if a.dryRun {
 		a.logger.DebugContext(ctx, "DRY-RUN MODE: No changes will be made")
-		a.reportDryRun(response.Patches)
+		a.options.Plan.Add(common.EcosystemMaven, a.filePath, a.useAlias(), sdkPackages, response.Patches)
+		a.result.AddPatches(response.Patches, a.useAlias(), common.PatchStatusDryRun)
+		diff, err := a.proposedDiff(ctx, response.Patches)
+		if err != nil {
+			return err
+		}
+		a.reporter.ReportDryRunWithDiff(response.Patches, a.useAlias(), diff)
 		return nil
 	}
 
 	// 7. Apply patches by updating the file
-	fmt.Printf("\nApplying %d patches to %s...\n\n", len(response.Patches), a.filePath)
+	// Ask before changing anything, unless --yes was given
+	if err := a.options.Confirm.Confirm(len(response.Patches), a.filePath); err != nil {
+		a.result.AddPatches(response.Patches, a.useAlias(), common.PatchStatusNotApplied)
+		return err
+	}
+
+	fmt.Fprintf(a.out, "\nApplying %d patches to %s...\n\n", len(response.Patches), a.filePath)
+	a.result.AddPatches(response.Patches, a.useAlias(), common.PatchStatusPending)
 	if err := a.applyPatches(ctx, response.Patches); err != nil {
+		a.result.SetAllPatchStatus(common.PatchStatusFailed, err)
 		return err
 	}
+	a.result.SetAllPatchStatus(common.PatchStatusApplied, nil)
 
-	fmt.Printf("\n✓ Successfully updated %s with %d patches!\n", a.filePath, len(response.Patches))
-	fmt.Println("\nNext steps:")
-	fmt.Println("  1. Review the changes in your pom.xml")
-	fmt.Println("  2. Run: mvn clean install")
-	fmt.Println("  3. Test your application")
+	a.reporter.ReportNextSteps(len(response.Patches))
+	a.reporter.ReportFixedCVEs(a.result.RecordFixedCVEs())
+
+	if a.options.Verify {
+		return a.verify(ctx)
+	}
 
 	return nil
 }
 
-// reportDryRun shows what would be changed without modifying files
-func (a *App) reportDryRun(patches []rootio.PackagePatch) {
-	fmt.Println("\n=== DRY-RUN MODE ===")
-	fmt.Printf("The following packages in %s would be updated:\n\n", a.filePath)
+// parseFiles parses every build file being remediated
+func (a *App) parseFiles(ctx context.Context) ([]common.PackageInfo, error) {
+	var packages []common.PackageInfo
+	for _, file := range a.files {
+		filePackages, err := a.parser.Parse(ctx, file)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %w", file, err)
+		}
+		packages = append(packages, filePackages...)
+	}
+	return packages, nil
+}
 
-	for i, patch := range patches {
-		fmt.Printf("%d. Package: %s\n", i+1, patch.PackageName)
-		fmt.Printf("   Current version: %s\n", patch.Version)
-		fmt.Printf("   Patched version: %s\n", patch.Patch.Version)
-		if len(patch.CVEIDs) > 0 {
-			fmt.Printf("   CVEs Fixed: %v\n", patch.CVEIDs)
+// verify parses the updated build files again and confirms no patches remain for their versions
+func (a *App) verify(ctx context.Context) error {
+	packages, err := a.parseFiles(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to verify patches: %w", err)
+	}
+	packages, _ = skipRanges(packages)
+
+	return common.VerifyPatches(ctx, common.EcosystemMaven, a.apiClient,
+		common.VerifyPackages(packages, a.options), a.options, a.result)
+}
+
+// filterByGroup splits patches into those whose groupId is prefix or one of its subgroups
+// (org.springframework selects org.springframework.boot but not org.springframeworkx) and
+// skipped entries for the rest. A trailing ".*" is accepted, and an empty prefix keeps everything.
+func filterByGroup(
+	patches []rootio.PackagePatch, prefix string,
+) ([]rootio.PackagePatch, []rootio.SkippedPackage) {
+	prefix = strings.TrimSuffix(strings.TrimSuffix(strings.TrimSpace(prefix), "*"), ".")
+	if prefix == "" {
+		return patches, nil
+	}
+
+	var kept []rootio.PackagePatch
+	var skipped []rootio.SkippedPackage
+	for _, patch := range patches {
+		groupID, _, _ := strings.Cut(patch.PackageName, ":")
+		if groupID == prefix || strings.HasPrefix(groupID, prefix+".") {
+			kept = append(kept, patch)
+			continue
 		}
-		fmt.Println()
+		skipped = append(skipped, rootio.SkippedPackage{
+			PackageName: patch.PackageName,
+			Reason:      fmt.Sprintf("not in group %s (--group-prefix)", prefix),
+		})
 	}
 
-	fmt.Println("To apply these patches:")
-	fmt.Printf("  1. Run: DRY_RUN=false rootio_patcher maven remediate\n")
-	fmt.Println("  2. Then run: mvn clean install")
+	return kept, skipped
 }
 
-// applyPatches updates the pom.xml file with patched versions
-func (a *App) applyPatches(ctx context.Context, patches []rootio.PackagePatch) error {
-	// Build updates map: package name -> new version
+// skipInherited separates patches whose version is declared in a file that isn't being
+// remediated (a parent POM outside the build)
+func (a *App) skipInherited(
+	patches []rootio.PackagePatch, packages []common.PackageInfo,
+) ([]rootio.PackagePatch, []rootio.SkippedPackage) {
+	remediated := make(map[string]bool)
+	for _, file := range a.files {
+		remediated[file] = true
+	}
+
+	var kept []rootio.PackagePatch
+	var skipped []rootio.SkippedPackage
+
+	for _, patch := range patches {
+		location := ""
+		for _, pkg := range packages {
+			if pkg.Name == patch.PackageName && pkg.Location != "" && !remediated[pkg.Location] {
+				location = pkg.Location
+				break
+			}
+		}
+		if location == "" {
+			kept = append(kept, patch)
+			continue
+		}
+
+		skipped = append(skipped, rootio.SkippedPackage{
+			PackageName: patch.PackageName,
+			Reason:      fmt.Sprintf("version is declared in parent POM %s", location),
+		})
+	}
+
+	return kept, skipped
+}
+
+// buildCommand returns the command that rebuilds the project after patching
+func buildCommand(filePath string) string {
+	if NewGradleParser().CanHandle(filePath) {
+		return "./gradlew build"
+	}
+	return "mvn clean install"
+}
+
+// useAlias reports whether dependencies are replaced by Root.io's aliased coordinates instead of
+// having their versions bumped, which is the default
+func (a *App) useAlias() bool {
+	return a.options.Aliased(false)
+}
+
+// patchUpdates maps each package name to its patched version, or with useAlias to the aliased
+// groupId:artifactId:version the dependency is replaced by
+func patchUpdates(patches []rootio.PackagePatch, useAlias bool) map[string]string {
 	updates := make(map[string]string)
 	for _, patch := range patches {
-		updates[patch.PackageName] = patch.Patch.Version
-		fmt.Printf("  - %s: %s → %s\n", patch.PackageName, patch.Version, patch.Patch.Version)
+		updates[patch.PackageName] = updateSpec(patch, useAlias)
+	}
+	return updates
+}
+
+// updateSpec returns the update for one patch; a patch without an alias is a plain version
+func updateSpec(patch rootio.PackagePatch, useAlias bool) string {
+	target := common.PatchTarget(patch, useAlias)
+	if common.ReplacesPackage(patch, target) {
+		return target.Name + ":" + target.Version
+	}
+	return target.Version
+}
+
+// updatedFiles returns the updated content of each build file, keyed by path. Recursive runs
+// update each version in whichever POM of the build declares it.
+func (a *App) updatedFiles(ctx context.Context, patches []rootio.PackagePatch) (map[string]string, error) {
+	updates := patchUpdates(patches, a.useAlias())
+
+	if updater, ok := a.parser.(moduleUpdater); ok && a.options.Recursive {
+		updated, err := updater.UpdateModules(ctx, a.files, updates)
+		if err != nil {
+			return nil, fmt.Errorf("failed to update files: %w", err)
+		}
+		return updated, nil
 	}
 
-	// Update the file
-	a.logger.DebugContext(ctx, "Updating pom.xml", slog.Int("updates", len(updates)))
 	updatedContent, err := a.parser.Update(ctx, a.filePath, updates)
 	if err != nil {
-		return fmt.Errorf("failed to update file: %w", err)
+		return nil, fmt.Errorf("failed to update file: %w", err)
+	}
+	return map[string]string{a.filePath: updatedContent}, nil
+}
+
+// sortedFiles returns the paths of updated files in a stable order
+func sortedFiles(updated map[string]string) []string {
+	files := make([]string, 0, len(updated))
+	for file := range updated {
+		files = append(files, file)
+	}
+	sort.Strings(files)
+	return files
+}
+
+// proposedDiff renders the change applyPatches would make to the build files as a unified diff
+func (a *App) proposedDiff(ctx context.Context, patches []rootio.PackagePatch) (string, error) {
+	updated, err := a.updatedFiles(ctx, patches)
+	if err != nil {
+		return "", err
+	}
+
+	var diff string
+	for _, file := range sortedFiles(updated) {
+		original, err := os.ReadFile(file)
+		if err != nil {
+			return "", fmt.Errorf("failed to read file: %w", err)
+		}
+		diff += common.UnifiedDiff(file, string(original), updated[file])
 	}
+	return diff, nil
+}
 
-	// Validate the updated content
-	if !a.parser.Validate(updatedContent) {
-		return fmt.Errorf("updated file content is invalid")
+// checkUpdates parses the updated build files and confirms each patched dependency now has its
+// patched version and every dependency still has a version, so a botched replacement fails
+// before anything is written. Parsers that can't read staged content only get Validate.
+func (a *App) checkUpdates(ctx context.Context, patches []rootio.PackagePatch, updated map[string]string) error {
+	staged, ok := a.parser.(stagedParser)
+	if !ok {
+		return nil
+	}
+
+	var after []common.PackageInfo
+	for _, file := range a.files {
+		before, err := a.parser.Parse(ctx, file)
+		if err != nil {
+			return fmt.Errorf("failed to parse %s: %w", file, err)
+		}
+		filePackages, err := staged.ParseUpdated(ctx, file, updated)
+		if err != nil {
+			return fmt.Errorf("updated content of %s doesn't parse: %w", file, err)
+		}
+
+		// Dependencies without a version are left out, so a version emptied by an update shows
+		// up as a missing dependency
+		if len(filePackages) != len(before) {
+			return fmt.Errorf("updated content of %s declares %d versioned dependencies instead of %d",
+				file, len(filePackages), len(before))
+		}
+		for i, pkg := range filePackages {
+			if strings.Contains(pkg.Version, "${") && !strings.Contains(before[i].Version, "${") {
+				return fmt.Errorf("updated content of %s leaves the version of %s unresolved: %s",
+					file, pkg.Name, pkg.Version)
+			}
+		}
+		after = append(after, filePackages...)
 	}
 
-	// Write the updated content back to the file
-	if err := os.WriteFile(a.filePath, []byte(updatedContent), 0644); err != nil {
-		return fmt.Errorf("failed to write updated file: %w", err)
+	for _, patch := range patches {
+		target := common.PatchTarget(patch, a.useAlias())
+		name := patch.PackageName
+		if common.ReplacesPackage(patch, target) {
+			name = target.Name
+		}
+
+		patched := false
+		for _, pkg := range after {
+			if pkg.Name == patch.PackageName && pkg.Version == patch.Version {
+				return fmt.Errorf("updated build files still declare %s %s; nothing was written", patch.PackageName, patch.Version)
+			}
+			if pkg.Name == name && pkg.Version == target.Version {
+				patched = true
+			}
+		}
+		if !patched {
+			return fmt.Errorf("updated build files don't declare %s %s; nothing was written", name, target.Version)
+		}
+	}
+	return nil
+}
+
+// applyPatches updates the build files with patched versions
+func (a *App) applyPatches(ctx context.Context, patches []rootio.PackagePatch) error {
+	for i, patch := range patches {
+		fmt.Fprintf(a.out, "[%d/%d] %s: %s → %s\n", i+1, len(patches), patch.PackageName, patch.Version, updateSpec(patch, a.useAlias()))
+	}
+
+	// Hold the locks from reading the files until they're written, so concurrent runs don't lose updates
+	unlock, err := common.LockFiles(ctx, a.files, a.options.LockTimeout)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	// Update the files
+	a.logger.DebugContext(ctx, "Updating build files", slog.Int("updates", len(patches)))
+	updated, err := a.updatedFiles(ctx, patches)
+	if err != nil {
+		return err
+	}
+
+	// Validate the updated content before writing anything
+	files := sortedFiles(updated)
+	for _, file := range files {
+		if !a.parser.Validate(updated[file]) {
+			return fmt.Errorf("updated content of %s is invalid", file)
+		}
+	}
+	if err := a.checkUpdates(ctx, patches, updated); err != nil {
+		return err
+	}
+
+	for _, file := range files {
+		if a.options.Backup {
+			backupPath, err := common.BackupFile(file)
+			if err != nil {
+				return fmt.Errorf("failed to back up file: %w", err)
+			}
+			a.logger.InfoContext(ctx, "Backed up file before patching",
+				slog.String("file", file),
+				slog.String("backup", backupPath))
+		}
+
+		// Write the updated content back to the file
+		if err := os.WriteFile(file, []byte(updated[file]), 0644); err != nil {
+			return fmt.Errorf("failed to write updated file: %w", err)
+		}
 	}
 
 	return nil
@@ -1,14 +1,17 @@
 package maven
 
 import (
+	"bytes"
 	"context"
 	"errors"
+	"io"
 	"log/slog"
 	"os"
 	"path/filepath"
 	"strings"
 	"testing"
 
+	"rootio_patcher/cmd/rootio_patcher/common"
 	"rootio_patcher/pkg/rootio"
 )
 
@@ -100,7 +103,7 @@ func TestMavenApp_Run_APIError(t *testing.T) {
 		pomFile,
 		true,
 		logger,
-		&MockParser{},
+		NewParser(),
 		mockAPIClient,
 	)
 
@@ -113,6 +116,74 @@ func TestMavenApp_Run_APIError(t *testing.T) {
 	}
 }
 
+func TestMavenApp_RunWithResult(t *testing.T) {
+	ctx := context.Background()
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+
+	tmpDir := t.TempDir()
+	pomFile := filepath.Join(tmpDir, "pom.xml")
+	content := `<?xml version="1.0"?>
+<project>
+  <dependencies>
+    <dependency>
+      <groupId>junit</groupId>
+      <artifactId>junit</artifactId>
+      <version>4.12</version>
+    </dependency>
+  </dependencies>
+</project>`
+	if err := os.WriteFile(pomFile, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to create temp file: %v", err)
+	}
+
+	mockAPIClient := &MockAPIClient{
+		AnalyzePackagesFunc: func(ctx context.Context, packages []rootio.Package) (*rootio.AnalyzePackagesResponse, error) {
+			return &rootio.AnalyzePackagesResponse{
+				Patches: []rootio.PackagePatch{
+					{
+						PackageName: "junit:junit",
+						Version:     "4.12",
+						Patch:       rootio.PatchInfo{Name: "junit:junit", Version: "4.13.2"},
+						CVEIDs:      []string{"CVE-2020-15250"},
+					},
+				},
+			}, nil
+		},
+	}
+
+	app := NewAppWithServices("test-key", "https://api.root.io", pomFile, true, logger, NewParser(), mockAPIClient)
+
+	result, err := app.RunWithResult(ctx)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if result.PackagesFound != 1 || len(result.Patches) != 1 {
+		t.Fatalf("Expected 1 package and 1 patch, got %+v", result)
+	}
+	if result.Patches[0].Status != common.PatchStatusDryRun || result.Patches[0].PatchedVersion != "4.13.2" {
+		t.Errorf("Unexpected patch result: %+v", result.Patches[0])
+	}
+	if result.Error != "" {
+		t.Errorf("Expected no error on result, got '%s'", result.Error)
+	}
+}
+
+func TestMavenApp_RunWithResult_RecordsError(t *testing.T) {
+	ctx := context.Background()
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+
+	app := NewAppWithServices("test-key", "https://api.root.io", "/nonexistent/pom.xml", true, logger,
+		NewParser(), &MockAPIClient{})
+
+	result, err := app.RunWithResult(ctx)
+	if err == nil {
+		t.Fatal("Expected error for missing file")
+	}
+	if result == nil || result.Error != err.Error() {
+		t.Errorf("Expected result to record error %q, got %+v", err, result)
+	}
+}
+
 func TestMavenApp_Run_NoPatches(t *testing.T) {
 	ctx := context.Background()
 	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
@@ -258,7 +329,7 @@ func TestMavenApp_Run_ApplyPatches(t *testing.T) {
 		pomFile,
 		false, // NOT dry-run
 		logger,
-		&MockParser{},
+		NewParser(),
 		mockAPIClient,
 	)
 
@@ -280,6 +351,79 @@ func TestMavenApp_Run_ApplyPatches(t *testing.T) {
 	}
 }
 
+func TestMavenApp_Run_UseAlias(t *testing.T) {
+	ctx := context.Background()
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+
+	tmpDir := t.TempDir()
+	pomFile := filepath.Join(tmpDir, "pom.xml")
+	content := `<?xml version="1.0"?>
+<project xmlns="http://maven.apache.org/POM/4.0.0">
+  <dependencies>
+    <dependency>
+      <groupId>org.apache.logging.log4j</groupId>
+      <artifactId>log4j-core</artifactId>
+      <version>2.14.1</version>
+    </dependency>
+    <dependency>
+      <groupId>junit</groupId>
+      <artifactId>junit</artifactId>
+      <version>4.12</version>
+    </dependency>
+  </dependencies>
+</project>`
+	if err := os.WriteFile(pomFile, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to create temp file: %v", err)
+	}
+
+	mockAPIClient := &MockAPIClient{
+		AnalyzePackagesFunc: func(ctx context.Context, packages []rootio.Package) (*rootio.AnalyzePackagesResponse, error) {
+			return &rootio.AnalyzePackagesResponse{
+				Patches: []rootio.PackagePatch{
+					{
+						PackageName: "org.apache.logging.log4j:log4j-core",
+						Version:     "2.14.1",
+						Patch:       rootio.PatchInfo{Name: "org.apache.logging.log4j:log4j-core", Version: "2.17.1"},
+						PatchAlias:  rootio.PatchInfo{Name: "io.root.log4j:log4j-core", Version: "2.14.1-root.1"},
+					},
+					{
+						// No alias coordinate: falls back to bumping the version
+						PackageName: "junit:junit",
+						Version:     "4.12",
+						Patch:       rootio.PatchInfo{Name: "junit:junit", Version: "4.13.2"},
+					},
+				},
+			}, nil
+		},
+	}
+
+	app := NewAppWithServices("test-key", "https://api.root.io", pomFile, false, logger,
+		NewParser(), mockAPIClient, common.WithUseAlias(true))
+
+	if err := app.Run(ctx); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	updatedContent, err := os.ReadFile(pomFile)
+	if err != nil {
+		t.Fatalf("Failed to read file: %v", err)
+	}
+	for _, want := range []string{
+		"<groupId>io.root.log4j</groupId>",
+		"<version>2.14.1-root.1</version>",
+		"<groupId>junit</groupId>",
+		"<version>4.13.2</version>",
+	} {
+		if !strings.Contains(string(updatedContent), want) {
+			t.Errorf("Expected the POM to contain %q, got:\n%s", want, updatedContent)
+		}
+	}
+
+	if patched := app.Result().Patches[1]; patched.PatchedName != "junit:junit" || patched.PatchedVersion != "4.13.2" {
+		t.Errorf("Expected the result to report junit 4.13.2, got %+v", patched)
+	}
+}
+
 func TestMavenApp_Run_ApplyPatchesWithProperties(t *testing.T) {
 	ctx := context.Background()
 	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
@@ -324,7 +468,7 @@ func TestMavenApp_Run_ApplyPatchesWithProperties(t *testing.T) {
 		pomFile,
 		false, // NOT dry-run
 		logger,
-		&MockParser{},
+		NewParser(),
 		mockAPIClient,
 	)
 
@@ -342,3 +486,722 @@ func TestMavenApp_Run_ApplyPatchesWithProperties(t *testing.T) {
 		t.Error("Property should be updated to 2.17.1")
 	}
 }
+
+func TestMavenApp_Run_Backup(t *testing.T) {
+	ctx := context.Background()
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+
+	tmpDir := t.TempDir()
+	pomFile := filepath.Join(tmpDir, "pom.xml")
+	content := `<?xml version="1.0"?>
+<project xmlns="http://maven.apache.org/POM/4.0.0">
+  <dependencies>
+    <dependency>
+      <groupId>junit</groupId>
+      <artifactId>junit</artifactId>
+      <version>4.12</version>
+    </dependency>
+  </dependencies>
+</project>`
+	if err := os.WriteFile(pomFile, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to create temp file: %v", err)
+	}
+
+	mockAPIClient := &MockAPIClient{
+		AnalyzePackagesFunc: func(ctx context.Context, packages []rootio.Package) (*rootio.AnalyzePackagesResponse, error) {
+			return &rootio.AnalyzePackagesResponse{
+				Patches: []rootio.PackagePatch{
+					{
+						PackageName: "junit:junit",
+						Version:     "4.12",
+						Patch:       rootio.PatchInfo{Name: "junit:junit", Version: "4.13.2"},
+					},
+				},
+			}, nil
+		},
+	}
+
+	app := NewAppWithServices(
+		"test-key",
+		"https://api.root.io",
+		pomFile,
+		false, // NOT dry-run
+		logger,
+		NewParser(),
+		mockAPIClient,
+		common.WithBackup(true),
+	)
+
+	if err := app.Run(ctx); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	backupContent, err := os.ReadFile(pomFile + common.BackupSuffix)
+	if err != nil {
+		t.Fatalf("Expected backup file to exist: %v", err)
+	}
+	if !bytes.Equal(backupContent, []byte(content)) {
+		t.Error("Backup should match the original pom.xml byte-for-byte")
+	}
+
+	updatedContent, err := os.ReadFile(pomFile)
+	if err != nil {
+		t.Fatalf("Failed to read file: %v", err)
+	}
+	if !strings.Contains(string(updatedContent), "4.13.2") {
+		t.Error("File should contain updated version 4.13.2")
+	}
+}
+
+func TestMavenApp_Run_MinSeverity(t *testing.T) {
+	ctx := context.Background()
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+
+	tmpDir := t.TempDir()
+	pomFile := filepath.Join(tmpDir, "pom.xml")
+	content := `<?xml version="1.0"?>
+<project xmlns="http://maven.apache.org/POM/4.0.0">
+  <dependencies>
+    <dependency>
+      <groupId>junit</groupId>
+      <artifactId>junit</artifactId>
+      <version>4.12</version>
+    </dependency>
+    <dependency>
+      <groupId>org.apache.logging.log4j</groupId>
+      <artifactId>log4j-core</artifactId>
+      <version>2.14.1</version>
+    </dependency>
+  </dependencies>
+</project>`
+	if err := os.WriteFile(pomFile, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to create temp file: %v", err)
+	}
+
+	mockAPIClient := &MockAPIClient{
+		AnalyzePackagesFunc: func(ctx context.Context, packages []rootio.Package) (*rootio.AnalyzePackagesResponse, error) {
+			return &rootio.AnalyzePackagesResponse{
+				Patches: []rootio.PackagePatch{
+					{
+						PackageName: "junit:junit",
+						Version:     "4.12",
+						Patch:       rootio.PatchInfo{Name: "junit:junit", Version: "4.13.2"},
+						Severity:    "medium",
+					},
+					{
+						PackageName: "org.apache.logging.log4j:log4j-core",
+						Version:     "2.14.1",
+						Patch:       rootio.PatchInfo{Name: "org.apache.logging.log4j:log4j-core", Version: "2.17.1"},
+						Severity:    "critical",
+					},
+				},
+			}, nil
+		},
+	}
+
+	app := NewAppWithServices(
+		"test-key",
+		"https://api.root.io",
+		pomFile,
+		false, // NOT dry-run
+		logger,
+		NewParser(),
+		mockAPIClient,
+		common.WithMinSeverity(common.SeverityHigh),
+	)
+
+	if err := app.Run(ctx); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	updatedContent, err := os.ReadFile(pomFile)
+	if err != nil {
+		t.Fatalf("Failed to read file: %v", err)
+	}
+	if !strings.Contains(string(updatedContent), "<version>2.17.1</version>") {
+		t.Error("Critical patch should be applied")
+	}
+	if !strings.Contains(string(updatedContent), "<version>4.12</version>") {
+		t.Error("Medium patch should not be applied")
+	}
+
+	result := app.Result()
+	if len(result.Patches) != 1 {
+		t.Fatalf("Expected 1 patch in result, got %d", len(result.Patches))
+	}
+	if len(result.Skipped) != 1 || result.Skipped[0].PackageName != "junit:junit" {
+		t.Fatalf("Expected junit to be skipped due to severity, got %+v", result.Skipped)
+	}
+}
+
+func TestMavenApp_Run_ExcludePackages(t *testing.T) {
+	ctx := context.Background()
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+
+	tmpDir := t.TempDir()
+	pomFile := filepath.Join(tmpDir, "pom.xml")
+	content := `<?xml version="1.0"?>
+<project xmlns="http://maven.apache.org/POM/4.0.0">
+  <dependencies>
+    <dependency>
+      <groupId>junit</groupId>
+      <artifactId>junit</artifactId>
+      <version>4.12</version>
+    </dependency>
+    <dependency>
+      <groupId>org.apache.logging.log4j</groupId>
+      <artifactId>log4j-core</artifactId>
+      <version>2.14.1</version>
+    </dependency>
+  </dependencies>
+</project>`
+	if err := os.WriteFile(pomFile, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to create temp file: %v", err)
+	}
+
+	mockAPIClient := &MockAPIClient{
+		AnalyzePackagesFunc: func(ctx context.Context, packages []rootio.Package) (*rootio.AnalyzePackagesResponse, error) {
+			return &rootio.AnalyzePackagesResponse{
+				Patches: []rootio.PackagePatch{
+					{
+						PackageName: "junit:junit",
+						Version:     "4.12",
+						Patch:       rootio.PatchInfo{Name: "junit:junit", Version: "4.13.2"},
+					},
+					{
+						PackageName: "org.apache.logging.log4j:log4j-core",
+						Version:     "2.14.1",
+						Patch:       rootio.PatchInfo{Name: "org.apache.logging.log4j:log4j-core", Version: "2.17.1"},
+					},
+				},
+			}, nil
+		},
+	}
+
+	app := NewAppWithServices(
+		"test-key",
+		"https://api.root.io",
+		pomFile,
+		false, // NOT dry-run
+		logger,
+		NewParser(),
+		mockAPIClient,
+		common.WithPackageFilter(nil, []string{"junit:*"}),
+	)
+
+	if err := app.Run(ctx); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	updatedContent, err := os.ReadFile(pomFile)
+	if err != nil {
+		t.Fatalf("Failed to read file: %v", err)
+	}
+	if !strings.Contains(string(updatedContent), "<version>2.17.1</version>") {
+		t.Error("log4j patch should be applied")
+	}
+	if !strings.Contains(string(updatedContent), "<version>4.12</version>") {
+		t.Error("Excluded junit patch should not be applied")
+	}
+
+	result := app.Result()
+	if len(result.Patches) != 1 {
+		t.Fatalf("Expected 1 patch in result, got %d", len(result.Patches))
+	}
+	if len(result.Skipped) != 1 || result.Skipped[0].PackageName != "junit:junit" ||
+		result.Skipped[0].Reason != "excluded by --exclude" {
+		t.Fatalf("Expected junit to be skipped as excluded, got %+v", result.Skipped)
+	}
+}
+
+func TestMavenApp_Run_GroupPrefix(t *testing.T) {
+	ctx := context.Background()
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+
+	tmpDir := t.TempDir()
+	pomFile := filepath.Join(tmpDir, "pom.xml")
+	content := `<?xml version="1.0"?>
+<project xmlns="http://maven.apache.org/POM/4.0.0">
+  <dependencies>
+    <dependency>
+      <groupId>org.springframework</groupId>
+      <artifactId>spring-core</artifactId>
+      <version>5.3.18</version>
+    </dependency>
+    <dependency>
+      <groupId>com.fasterxml.jackson.core</groupId>
+      <artifactId>jackson-databind</artifactId>
+      <version>2.13.2</version>
+    </dependency>
+  </dependencies>
+</project>`
+	if err := os.WriteFile(pomFile, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to create temp file: %v", err)
+	}
+
+	mockAPIClient := &MockAPIClient{
+		AnalyzePackagesFunc: func(ctx context.Context, packages []rootio.Package) (*rootio.AnalyzePackagesResponse, error) {
+			return &rootio.AnalyzePackagesResponse{
+				Patches: []rootio.PackagePatch{
+					{
+						PackageName: "org.springframework:spring-core",
+						Version:     "5.3.18",
+						Patch:       rootio.PatchInfo{Name: "org.springframework:spring-core", Version: "5.3.20"},
+					},
+					{
+						PackageName: "com.fasterxml.jackson.core:jackson-databind",
+						Version:     "2.13.2",
+						Patch:       rootio.PatchInfo{Name: "com.fasterxml.jackson.core:jackson-databind", Version: "2.13.4"},
+					},
+				},
+			}, nil
+		},
+	}
+
+	app := NewAppWithServices("test-key", "https://api.root.io", pomFile, false, logger, NewParser(), mockAPIClient,
+		common.WithGroupPrefix("org.springframework"))
+	if err := app.Run(ctx); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	updatedContent, err := os.ReadFile(pomFile)
+	if err != nil {
+		t.Fatalf("Failed to read file: %v", err)
+	}
+	if !strings.Contains(string(updatedContent), "<version>5.3.20</version>") {
+		t.Error("spring-core patch should be applied")
+	}
+	if !strings.Contains(string(updatedContent), "<version>2.13.2</version>") {
+		t.Error("jackson-databind is outside the group and should not be patched")
+	}
+
+	result := app.Result()
+	if len(result.Patches) != 1 || result.Patches[0].PackageName != "org.springframework:spring-core" {
+		t.Fatalf("Expected only spring-core in result, got %+v", result.Patches)
+	}
+	if len(result.Skipped) != 1 || result.Skipped[0].PackageName != "com.fasterxml.jackson.core:jackson-databind" ||
+		result.Skipped[0].Reason != "not in group org.springframework (--group-prefix)" {
+		t.Fatalf("Expected jackson-databind to be skipped by group, got %+v", result.Skipped)
+	}
+}
+
+func TestFilterByGroup(t *testing.T) {
+	patches := []rootio.PackagePatch{
+		{PackageName: "org.springframework:spring-core"},
+		{PackageName: "org.springframework.boot:spring-boot"},
+		{PackageName: "org.springframeworkx:lookalike"},
+		{PackageName: "junit:junit"},
+	}
+
+	tests := []struct {
+		prefix string
+		kept   int
+	}{
+		{"", 4},
+		{"org.springframework", 2},
+		{"org.springframework.*", 2},
+		{"org.springframework.boot", 1},
+		{"com.example", 0},
+	}
+
+	for _, tt := range tests {
+		kept, skipped := filterByGroup(patches, tt.prefix)
+		if len(kept) != tt.kept || len(kept)+len(skipped) != len(patches) {
+			t.Errorf("filterByGroup(%q) kept %d and skipped %d, want %d kept", tt.prefix, len(kept), len(skipped), tt.kept)
+		}
+	}
+}
+
+func TestMavenApp_Run_SkipsDowngrades(t *testing.T) {
+	ctx := context.Background()
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+
+	tmpDir := t.TempDir()
+	pomFile := filepath.Join(tmpDir, "pom.xml")
+	content := `<?xml version="1.0"?>
+<project xmlns="http://maven.apache.org/POM/4.0.0">
+  <dependencies>
+    <dependency>
+      <groupId>junit</groupId>
+      <artifactId>junit</artifactId>
+      <version>4.12</version>
+    </dependency>
+    <dependency>
+      <groupId>org.apache.logging.log4j</groupId>
+      <artifactId>log4j-core</artifactId>
+      <version>2.14.1</version>
+    </dependency>
+  </dependencies>
+</project>`
+	if err := os.WriteFile(pomFile, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to create temp file: %v", err)
+	}
+
+	mockAPIClient := &MockAPIClient{
+		AnalyzePackagesFunc: func(ctx context.Context, packages []rootio.Package) (*rootio.AnalyzePackagesResponse, error) {
+			return &rootio.AnalyzePackagesResponse{
+				Patches: []rootio.PackagePatch{
+					{
+						PackageName: "junit:junit",
+						Version:     "4.12",
+						Patch:       rootio.PatchInfo{Name: "junit:junit", Version: "4.12-rc1"},
+					},
+					{
+						PackageName: "org.apache.logging.log4j:log4j-core",
+						Version:     "2.14.1",
+						Patch:       rootio.PatchInfo{Name: "org.apache.logging.log4j:log4j-core", Version: "2.17.1"},
+					},
+				},
+			}, nil
+		},
+	}
+
+	app := NewAppWithServices(
+		"test-key",
+		"https://api.root.io",
+		pomFile,
+		false, // NOT dry-run
+		logger,
+		NewParser(),
+		mockAPIClient,
+	)
+
+	if err := app.Run(ctx); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	updatedContent, err := os.ReadFile(pomFile)
+	if err != nil {
+		t.Fatalf("Failed to read file: %v", err)
+	}
+	if !strings.Contains(string(updatedContent), "<version>2.17.1</version>") {
+		t.Error("log4j patch should be applied")
+	}
+	if !strings.Contains(string(updatedContent), "<version>4.12</version>") {
+		t.Error("junit downgrade should not be applied")
+	}
+
+	result := app.Result()
+	if len(result.Patches) != 1 {
+		t.Fatalf("Expected 1 patch in result, got %d", len(result.Patches))
+	}
+	if len(result.Skipped) != 1 || result.Skipped[0].PackageName != "junit:junit" ||
+		result.Skipped[0].Reason != "patched version 4.12-rc1 is not newer than 4.12" {
+		t.Fatalf("Expected junit to be skipped as a downgrade, got %+v", result.Skipped)
+	}
+}
+
+func TestMavenApp_Run_SkipsVersionsInheritedFromParent(t *testing.T) {
+	ctx := context.Background()
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+
+	childFile := writeModule(t, parentPOM, childPOM)
+
+	mockAPIClient := &MockAPIClient{
+		AnalyzePackagesFunc: func(ctx context.Context, packages []rootio.Package) (*rootio.AnalyzePackagesResponse, error) {
+			return &rootio.AnalyzePackagesResponse{
+				Patches: []rootio.PackagePatch{
+					{
+						PackageName: "org.apache.logging.log4j:log4j-core",
+						Version:     "2.14.1",
+						Patch:       rootio.PatchInfo{Name: "org.apache.logging.log4j:log4j-core", Version: "2.17.1"},
+					},
+					{
+						PackageName: "com.google.guava:guava",
+						Version:     "29.0-jre",
+						Patch:       rootio.PatchInfo{Name: "com.google.guava:guava", Version: "32.0.0-jre"},
+					},
+				},
+			}, nil
+		},
+	}
+
+	app := NewAppWithServices(
+		"test-key",
+		"https://api.root.io",
+		childFile,
+		false, // NOT dry-run
+		logger,
+		NewParser(WithParentResolution(true)),
+		mockAPIClient,
+	)
+
+	if err := app.Run(ctx); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	result := app.Result()
+	if len(result.Patches) != 1 || result.Patches[0].PackageName != "com.google.guava:guava" {
+		t.Fatalf("Expected only guava to be patched, got %+v", result.Patches)
+	}
+	if len(result.Skipped) != 1 || !strings.Contains(result.Skipped[0].Reason, "parent POM") {
+		t.Fatalf("Expected log4j-core to be skipped as inherited, got %+v", result.Skipped)
+	}
+
+	updatedContent, err := os.ReadFile(childFile)
+	if err != nil {
+		t.Fatalf("Failed to read file: %v", err)
+	}
+	if !strings.Contains(string(updatedContent), "<guava.version>32.0.0-jre</guava.version>") {
+		t.Error("Expected guava property to be updated")
+	}
+}
+
+func TestMavenApp_Run_SkipDev(t *testing.T) {
+	ctx := context.Background()
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+
+	pomFile := filepath.Join(t.TempDir(), "pom.xml")
+	if err := os.WriteFile(pomFile, []byte("<project></project>"), 0644); err != nil {
+		t.Fatalf("Failed to create temp file: %v", err)
+	}
+
+	mockParser := &MockParser{
+		ParseFunc: func(ctx context.Context, filePath string) ([]common.PackageInfo, error) {
+			return []common.PackageInfo{
+				{Name: "org.springframework:spring-core", Version: "5.3.0", Ecosystem: common.EcosystemMaven, Direct: true},
+				{Name: "junit:junit", Version: "4.12", Ecosystem: common.EcosystemMaven, Direct: true, Dev: true},
+			}, nil
+		},
+	}
+
+	var analyzed []rootio.Package
+	mockAPIClient := &MockAPIClient{
+		AnalyzePackagesFunc: func(ctx context.Context, packages []rootio.Package) (*rootio.AnalyzePackagesResponse, error) {
+			analyzed = packages
+			return &rootio.AnalyzePackagesResponse{}, nil
+		},
+	}
+
+	app := NewAppWithServices("test-key", "https://api.root.io", pomFile, true, logger,
+		mockParser, mockAPIClient, common.WithSkipDev(true))
+
+	if err := app.Run(ctx); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	if len(analyzed) != 1 || analyzed[0].Name != "org.springframework:spring-core" {
+		t.Errorf("Expected test-scoped junit to be left out, got %+v", analyzed)
+	}
+}
+
+func TestMavenApp_ProposedDiff(t *testing.T) {
+	pomFile := filepath.Join(t.TempDir(), "pom.xml")
+	content := `<project>
+  <dependencies>
+    <dependency>
+      <groupId>junit</groupId>
+      <artifactId>junit</artifactId>
+      <version>4.12</version>
+    </dependency>
+  </dependencies>
+</project>
+`
+	if err := os.WriteFile(pomFile, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to create temp file: %v", err)
+	}
+
+	app := NewAppWithServices("test-key", "https://api.root.io", pomFile, true,
+		slog.New(slog.NewTextHandler(os.Stdout, nil)), NewParser(), &MockAPIClient{})
+
+	diff, err := app.proposedDiff(context.Background(), []rootio.PackagePatch{
+		{PackageName: "junit:junit", Version: "4.12", Patch: rootio.PatchInfo{Name: "junit:junit", Version: "4.13.2"}},
+	})
+	if err != nil {
+		t.Fatalf("proposedDiff failed: %v", err)
+	}
+
+	for _, want := range []string{
+		"--- " + pomFile,
+		"-      <version>4.12</version>\n",
+		"+      <version>4.13.2</version>\n",
+	} {
+		if !strings.Contains(diff, want) {
+			t.Errorf("Expected diff to contain %q, got:\n%s", want, diff)
+		}
+	}
+
+	// Dry-run must leave the file untouched
+	if current, _ := os.ReadFile(pomFile); string(current) != content {
+		t.Error("proposedDiff should not modify the file")
+	}
+}
+
+func TestMavenApp_Run_ReportsProgress(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	pomFile := filepath.Join(t.TempDir(), "pom.xml")
+	content := `<?xml version="1.0"?>
+<project xmlns="http://maven.apache.org/POM/4.0.0">
+  <dependencies>
+    <dependency>
+      <groupId>junit</groupId>
+      <artifactId>junit</artifactId>
+      <version>4.12</version>
+    </dependency>
+    <dependency>
+      <groupId>commons-io</groupId>
+      <artifactId>commons-io</artifactId>
+      <version>2.6</version>
+    </dependency>
+  </dependencies>
+</project>`
+	if err := os.WriteFile(pomFile, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to create temp file: %v", err)
+	}
+
+	mockAPIClient := &MockAPIClient{
+		AnalyzePackagesFunc: func(ctx context.Context, packages []rootio.Package) (*rootio.AnalyzePackagesResponse, error) {
+			return &rootio.AnalyzePackagesResponse{
+				Patches: []rootio.PackagePatch{
+					{PackageName: "junit:junit", Version: "4.12", Patch: rootio.PatchInfo{Name: "junit:junit", Version: "4.13.2"}},
+					{PackageName: "commons-io:commons-io", Version: "2.6", Patch: rootio.PatchInfo{Name: "commons-io:commons-io", Version: "2.14.0"}},
+				},
+			}, nil
+		},
+	}
+
+	for _, enabled := range []bool{true, false} {
+		var buf bytes.Buffer
+		opts := []common.Option{common.WithOutput(&buf)}
+		if enabled {
+			opts = append(opts, common.WithProgress(common.NewProgress(&buf)))
+		}
+		app := NewAppWithServices("test-key", "https://api.root.io", pomFile, true, logger, NewParser(), mockAPIClient, opts...)
+		if err := app.Run(context.Background()); err != nil {
+			t.Fatalf("Expected no error, got: %v", err)
+		}
+		output := buf.String()
+
+		progressLines := []string{
+			"Found 2 packages in " + pomFile + "\n",
+			"Analyzing 2 packages for vulnerabilities...\n",
+		}
+		for _, line := range progressLines {
+			if strings.Contains(output, line) != enabled {
+				t.Errorf("Expected progress line %q to be printed = %v, got:\n%s", line, enabled, output)
+			}
+		}
+	}
+
+	// Applying prints a numbered line per patch, with or without progress
+	var buf bytes.Buffer
+	app := NewAppWithServices("test-key", "https://api.root.io", pomFile, false, logger, NewParser(), mockAPIClient,
+		common.WithOutput(&buf))
+	if err := app.Run(context.Background()); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	output := buf.String()
+	for _, line := range []string{
+		"[1/2] junit:junit: 4.12 → 4.13.2\n",
+		"[2/2] commons-io:commons-io: 2.6 → 2.14.0\n",
+	} {
+		if !strings.Contains(output, line) {
+			t.Errorf("Expected %q in output, got:\n%s", line, output)
+		}
+	}
+}
+
+// botchedParser is a Maven parser whose Update returns content made by update instead of
+// applying the versions
+type botchedParser struct {
+	*MavenParser
+	update func(content string) string
+}
+
+func (p *botchedParser) Update(ctx context.Context, filePath string, updates map[string]string) (string, error) {
+	content, err := os.ReadFile(filePath)
+	if err != nil {
+		return "", err
+	}
+	return p.update(string(content)), nil
+}
+
+func TestMavenApp_Run_BlocksInconsistentUpdate(t *testing.T) {
+	content := `<?xml version="1.0"?>
+<project xmlns="http://maven.apache.org/POM/4.0.0">
+  <properties>
+    <junit.version>4.12</junit.version>
+  </properties>
+  <dependencies>
+    <dependency>
+      <groupId>junit</groupId>
+      <artifactId>junit</artifactId>
+      <version>${junit.version}</version>
+    </dependency>
+    <dependency>
+      <groupId>commons-io</groupId>
+      <artifactId>commons-io</artifactId>
+      <version>2.6</version>
+    </dependency>
+  </dependencies>
+</project>`
+
+	tests := []struct {
+		name    string
+		update  func(content string) string
+		wantErr string
+	}{
+		{
+			name:    "version left unchanged",
+			update:  func(content string) string { return content },
+			wantErr: "still declare junit:junit 4.12",
+		},
+		{
+			name: "wrong version written",
+			update: func(content string) string {
+				return strings.Replace(content, "<junit.version>4.12</junit.version>", "<junit.version>4.13.1</junit.version>", 1)
+			},
+			wantErr: "don't declare junit:junit 4.13.2",
+		},
+		{
+			name: "another version emptied",
+			update: func(content string) string {
+				content = strings.Replace(content, "<junit.version>4.12</junit.version>", "<junit.version>4.13.2</junit.version>", 1)
+				return strings.Replace(content, "<version>2.6</version>", "<version></version>", 1)
+			},
+			wantErr: "declares 1 versioned dependencies instead of 2",
+		},
+		{
+			name: "property reference broken",
+			update: func(content string) string {
+				return strings.Replace(content, "<junit.version>4.12</junit.version>", "<junit.version.x>4.13.2</junit.version.x>", 1)
+			},
+			wantErr: "leaves the version of junit:junit unresolved",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+			pomFile := filepath.Join(t.TempDir(), "pom.xml")
+			if err := os.WriteFile(pomFile, []byte(content), 0644); err != nil {
+				t.Fatalf("Failed to create temp file: %v", err)
+			}
+
+			mockAPIClient := &MockAPIClient{
+				AnalyzePackagesFunc: func(ctx context.Context, packages []rootio.Package) (*rootio.AnalyzePackagesResponse, error) {
+					return &rootio.AnalyzePackagesResponse{Patches: []rootio.PackagePatch{
+						{PackageName: "junit:junit", Version: "4.12", Patch: rootio.PatchInfo{Name: "junit:junit", Version: "4.13.2"}},
+					}}, nil
+				},
+			}
+
+			parser := &botchedParser{MavenParser: NewParser(), update: tt.update}
+			app := NewAppWithServices("test-key", "https://api.root.io", pomFile, false, logger, parser, mockAPIClient,
+				common.WithOutput(io.Discard))
+			err := app.Run(context.Background())
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("Expected an error containing %q, got %v", tt.wantErr, err)
+			}
+
+			written, err := os.ReadFile(pomFile)
+			if err != nil {
+				t.Fatalf("Failed to read file: %v", err)
+			}
+			if string(written) != content {
+				t.Errorf("Expected pom.xml to be left alone, got:\n%s", written)
+			}
+		})
+	}
+}
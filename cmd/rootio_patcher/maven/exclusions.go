@@ -0,0 +1,98 @@
+package maven
+
+import (
+	"fmt"
+	"strings"
+
+	"rootio_patcher/cmd/rootio_patcher/common"
+	"rootio_patcher/pkg/rootio"
+)
+
+// Exclusion represents a <dependency><exclusions><exclusion> block: a transitive artifact
+// removed from the dependency's tree. Either coordinate may be the * wildcard.
+type Exclusion struct {
+	GroupID    string `xml:"groupId"`
+	ArtifactID string `xml:"artifactId"`
+}
+
+// matches reports whether the exclusion removes the groupId:artifactId coordinate
+func (e Exclusion) matches(groupID, artifactID string) bool {
+	return (e.GroupID == "*" || e.GroupID == groupID) && (e.ArtifactID == "*" || e.ArtifactID == artifactID)
+}
+
+// exclusionOwner is an exclusion and the groupId:artifactId of the dependency declaring it
+type exclusionOwner struct {
+	Exclusion
+	owner string
+}
+
+// exclusionList holds the exclusions declared by the dependencies of a project
+type exclusionList []exclusionOwner
+
+// exclusions returns the exclusions of the project's dependencies, including those of active
+// profiles, with property references resolved
+func (p *MavenParser) exclusions(project Project, properties map[string]string) exclusionList {
+	var list exclusionList
+	for _, dep := range project.Dependencies.Dependency {
+		for _, exclusion := range dep.Exclusions {
+			list = append(list, exclusionOwner{
+				Exclusion: Exclusion{
+					GroupID:    p.resolveProperty(exclusion.GroupID, properties),
+					ArtifactID: p.resolveProperty(exclusion.ArtifactID, properties),
+				},
+				owner: p.dependencyName(dep, properties),
+			})
+		}
+	}
+	return list
+}
+
+// excludedBy returns the dependency excluding the groupId:artifactId coordinate, or "" when
+// no dependency excludes it
+func (l exclusionList) excludedBy(name string) string {
+	groupID, artifactID, ok := strings.Cut(name, ":")
+	if !ok {
+		return ""
+	}
+	for _, e := range l {
+		if e.matches(groupID, artifactID) {
+			return e.owner
+		}
+	}
+	return ""
+}
+
+// skipExcluded separates patches for artifacts the build excludes: a version pinned in
+// <dependencyManagement> for an artifact every dependency bringing it in excludes is never used
+func skipExcluded(
+	patches []rootio.PackagePatch, packages []common.PackageInfo,
+) ([]rootio.PackagePatch, []rootio.SkippedPackage) {
+	var kept []rootio.PackagePatch
+	var skipped []rootio.SkippedPackage
+
+	for _, patch := range patches {
+		// The artifact stays in the build if any file declares it without an exclusion
+		excludedBy := ""
+		for _, pkg := range packages {
+			if pkg.Name != patch.PackageName {
+				continue
+			}
+			if pkg.ExcludedBy == "" {
+				excludedBy = ""
+				break
+			}
+			excludedBy = pkg.ExcludedBy
+		}
+		if excludedBy == "" {
+			kept = append(kept, patch)
+			continue
+		}
+
+		skipped = append(skipped, rootio.SkippedPackage{
+			PackageName: patch.PackageName,
+			Reason:      fmt.Sprintf("excluded from the build by the <exclusions> of %s", excludedBy),
+		})
+	}
+
+	return kept, skipped
+}
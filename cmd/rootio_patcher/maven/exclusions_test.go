@@ -0,0 +1,107 @@
+package maven
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"rootio_patcher/cmd/rootio_patcher/common"
+	"rootio_patcher/pkg/rootio"
+)
+
+// exclusionsFixture is the pom whose dependencies exclude commons-logging and every log4j artifact
+var exclusionsFixture = filepath.Join("testdata", "exclusions", "pom.xml")
+
+func TestMavenParser_Parse_Exclusions(t *testing.T) {
+	packages, err := NewParser().Parse(context.Background(), exclusionsFixture)
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+
+	excludedBy := make(map[string]string, len(packages))
+	for _, pkg := range packages {
+		excludedBy[pkg.Name] = pkg.ExcludedBy
+	}
+	expected := map[string]string{
+		"org.springframework:spring-core":             "",
+		"org.apache.zookeeper:zookeeper":              "",
+		"commons-logging:commons-logging":             "org.springframework:spring-core",
+		"log4j:log4j":                                 "org.apache.zookeeper:zookeeper",
+		"com.fasterxml.jackson.core:jackson-databind": "",
+	}
+	if !reflect.DeepEqual(excludedBy, expected) {
+		t.Errorf("Expected %v, got %v", expected, excludedBy)
+	}
+}
+
+func TestMavenApp_Run_SkipsExcludedArtifacts(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	content, err := os.ReadFile(exclusionsFixture)
+	if err != nil {
+		t.Fatalf("Failed to read fixture: %v", err)
+	}
+	pomFile := filepath.Join(t.TempDir(), "pom.xml")
+	if err := os.WriteFile(pomFile, content, 0644); err != nil {
+		t.Fatalf("Failed to copy fixture: %v", err)
+	}
+
+	patch := func(name, version, patched string) rootio.PackagePatch {
+		return rootio.PackagePatch{PackageName: name, Version: version, Patch: rootio.PatchInfo{Name: name, Version: patched}}
+	}
+	mockAPIClient := &MockAPIClient{
+		AnalyzePackagesFunc: func(ctx context.Context, packages []rootio.Package) (*rootio.AnalyzePackagesResponse, error) {
+			return &rootio.AnalyzePackagesResponse{Patches: []rootio.PackagePatch{
+				patch("commons-logging:commons-logging", "1.1.1", "1.2"),
+				patch("log4j:log4j", "1.2.17", "1.2.17.1"),
+				patch("com.fasterxml.jackson.core:jackson-databind", "2.9.10", "2.9.10.8"),
+			}}, nil
+		},
+	}
+
+	app := NewAppWithServices("test-key", "https://api.root.io", pomFile, false, logger, NewParser(), mockAPIClient)
+	result, err := app.RunWithResult(context.Background())
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+
+	if len(result.Patches) != 1 || result.Patches[0].PackageName != "com.fasterxml.jackson.core:jackson-databind" {
+		t.Errorf("Expected only jackson-databind to be patched, got %+v", result.Patches)
+	}
+	expectedSkipped := []common.SkippedResult{
+		{PackageName: "commons-logging:commons-logging", Reason: "excluded from the build by the <exclusions> of org.springframework:spring-core"},
+		{PackageName: "log4j:log4j", Reason: "excluded from the build by the <exclusions> of org.apache.zookeeper:zookeeper"},
+	}
+	if !reflect.DeepEqual(result.Skipped, expectedSkipped) {
+		t.Errorf("Expected skipped %+v, got %+v", expectedSkipped, result.Skipped)
+	}
+
+	// The excluded versions are left as they were
+	updated, err := os.ReadFile(pomFile)
+	if err != nil {
+		t.Fatalf("Failed to read pom.xml: %v", err)
+	}
+	want := strings.Replace(string(content), "<version>2.9.10</version>", "<version>2.9.10.8</version>", 1)
+	if string(updated) != want {
+		t.Errorf("Expected only jackson-databind to be bumped, got:\n%s", updated)
+	}
+}
+
+func TestSkipExcluded_DeclaredElsewhere(t *testing.T) {
+	// A module that declares commons-logging keeps it in the build despite another's exclusion
+	packages := []common.PackageInfo{
+		{Name: "commons-logging:commons-logging", ExcludedBy: "org.springframework:spring-core"},
+		{Name: "commons-logging:commons-logging"},
+	}
+	patches := []rootio.PackagePatch{{PackageName: "commons-logging:commons-logging"}}
+
+	kept, skipped := skipExcluded(patches, packages)
+	if len(kept) != 1 || len(skipped) != 0 {
+		t.Errorf("Expected the patch to be kept, got kept=%v skipped=%v", kept, skipped)
+	}
+}
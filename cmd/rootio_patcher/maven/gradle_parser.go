@@ -0,0 +1,277 @@
+package maven
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"rootio_patcher/cmd/rootio_patcher/common"
+)
+
+var (
+	// gradleDependencyPattern matches string notation in Groovy and Kotlin DSL:
+	// implementation 'group:artifact:1.0' / testImplementation("group:artifact:$version")
+	gradleDependencyPattern = regexp.MustCompile(
+		`^\s*(\w+)\s*\(?\s*["']([^"':\s]+):([^"':\s]+):([^"':@\s]+)(?::[^"'@\s]+)?(?:@\w+)?["']`)
+
+	// gradleMapDependencyPattern matches Groovy map notation:
+	// implementation group: 'group', name: 'artifact', version: '1.0'
+	gradleMapDependencyPattern = regexp.MustCompile(
+		`^\s*(\w+)\s*\(?\s*group\s*:\s*["']([^"']+)["']\s*,\s*name\s*:\s*["']([^"']+)["']\s*,\s*version\s*:\s*["']([^"']+)["']`)
+
+	// gradleVariablePattern matches simple version variables:
+	// def log4jVersion = '2.17.0' / val log4jVersion = "2.17.0" / ext.log4jVersion = '2.17.0'
+	gradleVariablePattern = regexp.MustCompile(`^\s*(?:def\s+|val\s+|var\s+|ext\.)(\w+)\s*=\s*["']([^"'$]+)["']`)
+
+	// gradleVariableRefPattern matches $name and ${name} version references
+	gradleVariableRefPattern = regexp.MustCompile(`^\$\{?(\w+)\}?$`)
+)
+
+// gradleConfigurations lists the dependency configurations that declare artifacts
+var gradleConfigurations = map[string]bool{
+	"implementation":          true,
+	"api":                     true,
+	"compileOnly":             true,
+	"runtimeOnly":             true,
+	"annotationProcessor":     true,
+	"kapt":                    true,
+	"testImplementation":      true,
+	"testCompileOnly":         true,
+	"testRuntimeOnly":         true,
+	"testAnnotationProcessor": true,
+	"compile":                 true,
+	"runtime":                 true,
+	"testCompile":             true,
+	"testRuntime":             true,
+}
+
+// GradleParser handles parsing of Gradle build.gradle and build.gradle.kts files
+type GradleParser struct{}
+
+// NewGradleParser creates a new Gradle parser
+func NewGradleParser() *GradleParser {
+	return &GradleParser{}
+}
+
+// Ecosystem returns the ecosystem name (Gradle resolves artifacts from Maven repositories)
+func (p *GradleParser) Ecosystem() common.Ecosystem {
+	return common.EcosystemMaven
+}
+
+// FilePatterns returns file patterns this parser handles
+func (p *GradleParser) FilePatterns() []string {
+	return []string{"build.gradle", "build.gradle.kts"}
+}
+
+// CanHandle checks if this parser can handle the given file
+func (p *GradleParser) CanHandle(fileName string) bool {
+	base := filepath.Base(fileName)
+	for _, pattern := range p.FilePatterns() {
+		if base == pattern {
+			return true
+		}
+	}
+	return false
+}
+
+// gradleDependency represents a single dependency declaration
+type gradleDependency struct {
+	Configuration string
+	GroupID       string
+	ArtifactID    string
+	Version       string // Literal version or variable reference
+}
+
+// Parse parses a Gradle build file and returns all declared dependencies
+func (p *GradleParser) Parse(ctx context.Context, filePath string) ([]common.PackageInfo, error) {
+	content, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read file: %w", err)
+	}
+
+	lines := strings.Split(string(content), "\n")
+	variables := parseGradleVariables(lines)
+
+	var packages []common.PackageInfo
+
+	for _, line := range lines {
+		dep, ok := parseGradleDependencyLine(line)
+		if !ok {
+			continue
+		}
+
+		version := resolveGradleVersion(dep.Version, variables)
+
+		// Skip dependencies whose version can't be resolved (platform/BOM managed, catalogs)
+		if version == "" {
+			continue
+		}
+
+		packages = append(packages, common.PackageInfo{
+			Name:              fmt.Sprintf("%s:%s", dep.GroupID, dep.ArtifactID),
+			Version:           version,
+			VersionConstraint: version,
+			Ecosystem:         common.EcosystemMaven,
+			Direct:            true, // Gradle has no lock file here, all declared deps are "direct"
+			Dev:               strings.HasPrefix(dep.Configuration, "test"),
+		})
+	}
+
+	return packages, nil
+}
+
+// parseGradleDependencyLine parses a dependency declaration in string or map notation
+func parseGradleDependencyLine(line string) (gradleDependency, bool) {
+	matches := gradleDependencyPattern.FindStringSubmatch(line)
+	if matches == nil {
+		matches = gradleMapDependencyPattern.FindStringSubmatch(line)
+	}
+	if matches == nil || !gradleConfigurations[matches[1]] {
+		return gradleDependency{}, false
+	}
+
+	return gradleDependency{
+		Configuration: matches[1],
+		GroupID:       matches[2],
+		ArtifactID:    matches[3],
+		Version:       matches[4],
+	}, true
+}
+
+// parseGradleVariables collects simple string variables that may hold versions
+func parseGradleVariables(lines []string) map[string]string {
+	variables := make(map[string]string)
+	for _, line := range lines {
+		if matches := gradleVariablePattern.FindStringSubmatch(line); matches != nil {
+			variables[matches[1]] = matches[2]
+		}
+	}
+	return variables
+}
+
+// resolveGradleVersion resolves $name / ${name} references, returning "" if unresolvable
+func resolveGradleVersion(version string, variables map[string]string) string {
+	if !strings.Contains(version, "$") {
+		return version
+	}
+
+	if matches := gradleVariableRefPattern.FindStringSubmatch(version); matches != nil {
+		return variables[matches[1]]
+	}
+
+	return ""
+}
+
+// Update updates dependency versions in a Gradle build file, preserving formatting
+func (p *GradleParser) Update(ctx context.Context, filePath string, updates map[string]string) (string, error) {
+	content, err := os.ReadFile(filePath)
+	if err != nil {
+		return "", fmt.Errorf("failed to read file: %w", err)
+	}
+
+	lines := strings.Split(string(content), "\n")
+	variableUpdates := make(map[string]string)
+
+	for i, line := range lines {
+		dep, ok := parseGradleDependencyLine(line)
+		if !ok {
+			continue
+		}
+
+		spec, ok := updates[fmt.Sprintf("%s:%s", dep.GroupID, dep.ArtifactID)]
+		if !ok {
+			continue
+		}
+
+		// An alias replaces the coordinate before the version is updated
+		groupID, artifactID, newVersion := parseUpdateSpec(spec)
+		if groupID != "" {
+			line = replaceGradleCoordinate(line, dep, groupID, artifactID)
+			lines[i] = line
+			dep.GroupID, dep.ArtifactID = groupID, artifactID
+		}
+
+		// If it's a variable reference, update the variable instead
+		if matches := gradleVariableRefPattern.FindStringSubmatch(dep.Version); matches != nil {
+			variableUpdates[matches[1]] = newVersion
+			continue
+		}
+
+		lines[i] = replaceGradleVersion(line, dep, newVersion)
+	}
+
+	for i, line := range lines {
+		matches := gradleVariablePattern.FindStringSubmatch(line)
+		if matches == nil {
+			continue
+		}
+		if newVersion, ok := variableUpdates[matches[1]]; ok {
+			lines[i] = replaceQuoted(line, matches[2], newVersion)
+		}
+	}
+
+	return strings.Join(lines, "\n"), nil
+}
+
+// replaceGradleVersion rewrites the literal version of a dependency declaration
+func replaceGradleVersion(line string, dep gradleDependency, newVersion string) string {
+	coordinate := fmt.Sprintf("%s:%s:%s", dep.GroupID, dep.ArtifactID, dep.Version)
+	if strings.Contains(line, coordinate) {
+		return strings.Replace(line, coordinate,
+			fmt.Sprintf("%s:%s:%s", dep.GroupID, dep.ArtifactID, newVersion), 1)
+	}
+
+	// Map notation: version: '1.0'
+	pattern := regexp.MustCompile(`(version\s*:\s*["'])` + regexp.QuoteMeta(dep.Version) + `(["'])`)
+	return pattern.ReplaceAllString(line, "${1}"+newVersion+"${2}")
+}
+
+// replaceGradleCoordinate points a dependency declaration at another groupId and artifactId
+func replaceGradleCoordinate(line string, dep gradleDependency, groupID, artifactID string) string {
+	prefix := fmt.Sprintf("%s:%s:", dep.GroupID, dep.ArtifactID)
+	if strings.Contains(line, prefix) {
+		return strings.Replace(line, prefix, fmt.Sprintf("%s:%s:", groupID, artifactID), 1)
+	}
+
+	// Map notation: group: 'group', name: 'artifact'
+	pattern := regexp.MustCompile(`(group\s*:\s*["'])` + regexp.QuoteMeta(dep.GroupID) +
+		`(["']\s*,\s*name\s*:\s*["'])` + regexp.QuoteMeta(dep.ArtifactID) + `(["'])`)
+	return pattern.ReplaceAllString(line, "${1}"+groupID+"${2}"+artifactID+"${3}")
+}
+
+// replaceQuoted replaces the first quoted occurrence of oldValue on a line
+func replaceQuoted(line, oldValue, newValue string) string {
+	pattern := regexp.MustCompile(`(["'])` + regexp.QuoteMeta(oldValue) + `(["'])`)
+	replaced := false
+	return pattern.ReplaceAllStringFunc(line, func(match string) string {
+		if replaced {
+			return match
+		}
+		replaced = true
+		return match[:1] + newValue + match[len(match)-1:]
+	})
+}
+
+// Validate performs a basic structural check: braces and parentheses must balance
+func (p *GradleParser) Validate(content string) bool {
+	braces, parens := 0, 0
+	for _, r := range content {
+		switch r {
+		case '{':
+			braces++
+		case '}':
+			braces--
+		case '(':
+			parens++
+		case ')':
+			parens--
+		}
+		if braces < 0 || parens < 0 {
+			return false
+		}
+	}
+	return braces == 0 && parens == 0
+}
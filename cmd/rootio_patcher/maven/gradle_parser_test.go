@@ -0,0 +1,235 @@
+package maven
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"rootio_patcher/cmd/rootio_patcher/common"
+)
+
+func TestGradleParser_CanHandle(t *testing.T) {
+	parser := NewGradleParser()
+
+	tests := []struct {
+		fileName string
+		expected bool
+	}{
+		{"build.gradle", true},
+		{"build.gradle.kts", true},
+		{"app/build.gradle", true},
+		{"settings.gradle", false},
+		{"pom.xml", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.fileName, func(t *testing.T) {
+			if result := parser.CanHandle(tt.fileName); result != tt.expected {
+				t.Errorf("CanHandle(%s) = %v, expected %v", tt.fileName, result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestGradleParser_Parse_Groovy(t *testing.T) {
+	ctx := context.Background()
+	parser := NewGradleParser()
+
+	tmpDir := t.TempDir()
+	buildFile := filepath.Join(tmpDir, "build.gradle")
+
+	content := `plugins {
+    id 'java'
+}
+
+def log4jVersion = '2.14.1'
+ext.jacksonVersion = "2.12.0"
+
+dependencies {
+    implementation "org.apache.logging.log4j:log4j-core:${log4jVersion}"
+    api "com.fasterxml.jackson.core:jackson-databind:$jacksonVersion"
+    implementation group: 'org.yaml', name: 'snakeyaml', version: '1.26'
+    implementation platform('org.springframework.boot:spring-boot-dependencies:2.5.0')
+    implementation 'org.springframework.boot:spring-boot-starter-web'
+    testImplementation 'junit:junit:4.12'
+}
+`
+
+	if err := os.WriteFile(buildFile, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to create temp file: %v", err)
+	}
+
+	packages, err := parser.Parse(ctx, buildFile)
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+
+	expected := []struct {
+		name    string
+		version string
+		dev     bool
+	}{
+		{"org.apache.logging.log4j:log4j-core", "2.14.1", false},
+		{"com.fasterxml.jackson.core:jackson-databind", "2.12.0", false},
+		{"org.yaml:snakeyaml", "1.26", false},
+		{"junit:junit", "4.12", true},
+	}
+
+	if len(packages) != len(expected) {
+		t.Fatalf("Expected %d packages, got %d: %+v", len(expected), len(packages), packages)
+	}
+
+	for i, exp := range expected {
+		pkg := packages[i]
+		if pkg.Name != exp.name || pkg.Version != exp.version || pkg.Dev != exp.dev {
+			t.Errorf("Package %d: expected %s %s (dev=%v), got %s %s (dev=%v)",
+				i, exp.name, exp.version, exp.dev, pkg.Name, pkg.Version, pkg.Dev)
+		}
+		if pkg.Ecosystem != common.EcosystemMaven {
+			t.Errorf("Expected ecosystem 'maven', got '%s'", pkg.Ecosystem)
+		}
+	}
+}
+
+func TestGradleParser_Parse_Kotlin(t *testing.T) {
+	ctx := context.Background()
+	parser := NewGradleParser()
+
+	tmpDir := t.TempDir()
+	buildFile := filepath.Join(tmpDir, "build.gradle.kts")
+
+	content := `val log4jVersion = "2.14.1"
+
+dependencies {
+    implementation("org.apache.logging.log4j:log4j-core:$log4jVersion")
+    testImplementation("junit:junit:4.12")
+}
+`
+
+	if err := os.WriteFile(buildFile, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to create temp file: %v", err)
+	}
+
+	packages, err := parser.Parse(ctx, buildFile)
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+
+	if len(packages) != 2 {
+		t.Fatalf("Expected 2 packages, got %d", len(packages))
+	}
+	if packages[0].Name != "org.apache.logging.log4j:log4j-core" || packages[0].Version != "2.14.1" {
+		t.Errorf("Unexpected first package: %+v", packages[0])
+	}
+	if packages[1].Name != "junit:junit" || !packages[1].Dev {
+		t.Errorf("Unexpected second package: %+v", packages[1])
+	}
+}
+
+func TestGradleParser_Update(t *testing.T) {
+	ctx := context.Background()
+	parser := NewGradleParser()
+
+	tmpDir := t.TempDir()
+	buildFile := filepath.Join(tmpDir, "build.gradle")
+
+	content := `def log4jVersion = '2.14.1'
+
+dependencies {
+    implementation "org.apache.logging.log4j:log4j-core:${log4jVersion}"
+    implementation 'junit:junit:4.12' // pinned for legacy tests
+    implementation group: 'org.yaml', name: 'snakeyaml', version: '1.26'
+}
+`
+
+	if err := os.WriteFile(buildFile, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to create temp file: %v", err)
+	}
+
+	updates := map[string]string{
+		"org.apache.logging.log4j:log4j-core": "2.17.1",
+		"junit:junit":                         "4.13.2",
+		"org.yaml:snakeyaml":                  "1.33",
+	}
+
+	updated, err := parser.Update(ctx, buildFile, updates)
+	if err != nil {
+		t.Fatalf("Update failed: %v", err)
+	}
+
+	expected := `def log4jVersion = '2.17.1'
+
+dependencies {
+    implementation "org.apache.logging.log4j:log4j-core:${log4jVersion}"
+    implementation 'junit:junit:4.13.2' // pinned for legacy tests
+    implementation group: 'org.yaml', name: 'snakeyaml', version: '1.33'
+}
+`
+	if updated != expected {
+		t.Errorf("Unexpected update result:\n%s", updated)
+	}
+
+	if !parser.Validate(updated) {
+		t.Error("Expected updated content to be valid")
+	}
+	if strings.Contains(updated, "2.14.1") {
+		t.Error("Expected old log4j version to be replaced")
+	}
+}
+
+func TestGradleParser_Update_Alias(t *testing.T) {
+	ctx := context.Background()
+	parser := NewGradleParser()
+
+	tmpDir := t.TempDir()
+	buildFile := filepath.Join(tmpDir, "build.gradle")
+
+	content := `def log4jVersion = '2.14.1'
+
+dependencies {
+    implementation "org.apache.logging.log4j:log4j-core:${log4jVersion}"
+    implementation 'junit:junit:4.12'
+    implementation group: 'org.yaml', name: 'snakeyaml', version: '1.26'
+}
+`
+
+	if err := os.WriteFile(buildFile, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to create temp file: %v", err)
+	}
+
+	updates := map[string]string{
+		"org.apache.logging.log4j:log4j-core": "io.root.log4j:log4j-core:2.17.1",
+		"junit:junit":                         "io.root.junit:junit:4.13.2",
+		"org.yaml:snakeyaml":                  "io.root.yaml:snakeyaml:1.33",
+	}
+
+	updated, err := parser.Update(ctx, buildFile, updates)
+	if err != nil {
+		t.Fatalf("Update failed: %v", err)
+	}
+
+	expected := `def log4jVersion = '2.17.1'
+
+dependencies {
+    implementation "io.root.log4j:log4j-core:${log4jVersion}"
+    implementation 'io.root.junit:junit:4.13.2'
+    implementation group: 'io.root.yaml', name: 'snakeyaml', version: '1.33'
+}
+`
+	if updated != expected {
+		t.Errorf("Unexpected update result:\n%s", updated)
+	}
+}
+
+func TestGradleParser_Validate(t *testing.T) {
+	parser := NewGradleParser()
+
+	if !parser.Validate("dependencies {\n    implementation('junit:junit:4.12')\n}\n") {
+		t.Error("Expected balanced content to be valid")
+	}
+	if parser.Validate("dependencies {\n    implementation('junit:junit:4.12')\n") {
+		t.Error("Expected unbalanced braces to be invalid")
+	}
+}
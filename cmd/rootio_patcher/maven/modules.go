@@ -0,0 +1,215 @@
+package maven
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// discoverModules returns the root POM followed by the module POMs listed under <modules>,
+// including modules of modules. Maven can't build the reactor without every module, so a
+// missing module POM is an error.
+func discoverModules(rootPath string) ([]string, error) {
+	var files []string
+	visited := make(map[string]bool)
+
+	var walk func(filePath string) error
+	walk = func(filePath string) error {
+		absPath, err := filepath.Abs(filePath)
+		if err != nil {
+			return fmt.Errorf("failed to resolve module POM path %s: %w", filePath, err)
+		}
+		if visited[absPath] {
+			return nil
+		}
+		visited[absPath] = true
+
+		project, err := loadProject(filePath)
+		if err != nil {
+			return fmt.Errorf("failed to load module POM %s: %w", filePath, err)
+		}
+		files = append(files, filepath.Clean(filePath))
+
+		for _, module := range project.Modules {
+			if err := walk(modulePOMPath(strings.TrimSpace(module), filePath)); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	if err := walk(rootPath); err != nil {
+		return nil, err
+	}
+	return files, nil
+}
+
+// modulePOMPath returns the POM of a <module>, which names a directory or a POM file
+// relative to the aggregating POM
+func modulePOMPath(module, aggregatorPath string) string {
+	modulePath := module
+	if !filepath.IsAbs(modulePath) {
+		modulePath = filepath.Join(filepath.Dir(aggregatorPath), module)
+	}
+
+	if info, err := os.Stat(modulePath); err == nil && info.IsDir() {
+		modulePath = filepath.Join(modulePath, "pom.xml")
+	}
+	return modulePath
+}
+
+// declaredDependency is a dependency and whether it's declared under <dependencyManagement>
+type declaredDependency struct {
+	Dependency
+	managed bool
+}
+
+// declaredDependencies returns the dependencies and managed dependencies of a project, including
+// those of profiles merged in by applyProfiles
+func declaredDependencies(project Project) []declaredDependency {
+	var deps []declaredDependency
+	for _, dep := range project.Dependencies.Dependency {
+		deps = append(deps, declaredDependency{dep, false})
+	}
+	for _, dep := range project.DependencyManagement.Dependencies.Dependency {
+		deps = append(deps, declaredDependency{dep, true})
+	}
+	return deps
+}
+
+// pomEdits collects the edits to one POM
+type pomEdits struct {
+	content    []byte
+	properties map[string]string
+	locations  pomLocations
+	active     []int // indexes of the profiles read from the POM
+	edits      []textEdit
+}
+
+// UpdateModules updates dependency versions across the POMs of a multi-module build. Each
+// version is changed where it is declared: in the module itself, or in the parent POM holding
+// the property or dependencyManagement entry the module inherits. It returns the updated
+// content of every POM that changed.
+func (p *MavenParser) UpdateModules(ctx context.Context, filePaths []string, updates map[string]string) (map[string]string, error) {
+	files := make(map[string]*pomEdits)
+	load := func(filePath string) (*pomEdits, error) {
+		filePath = filepath.Clean(filePath)
+		if file, ok := files[filePath]; ok {
+			return file, nil
+		}
+
+		content, err := os.ReadFile(filePath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read file: %w", err)
+		}
+		project, err := loadProject(filePath)
+		if err != nil {
+			return nil, err
+		}
+		project = p.applyProfiles(project)
+		locations, err := locateElements(content)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse XML: %w", err)
+		}
+
+		file := &pomEdits{
+			content:    content,
+			properties: projectProperties(project),
+			locations:  locations,
+			active:     p.activeProfiles(project),
+		}
+		files[filePath] = file
+		return file, nil
+	}
+
+	for _, filePath := range filePaths {
+		project, err := loadProject(filePath)
+		if err != nil {
+			return nil, err
+		}
+		project = p.applyProfiles(project)
+		model, err := p.buildModel(project, filePath)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, dep := range declaredDependencies(project) {
+			if dep.GroupID == "" || dep.ArtifactID == "" || dep.isPOM() {
+				continue
+			}
+
+			name := p.dependencyName(dep.Dependency, model.properties)
+			spec, ok := updates[name]
+			if !ok {
+				continue
+			}
+			groupID, artifactID, newVersion := parseUpdateSpec(spec)
+
+			// Dependencies without an inline version take it from a managed entry
+			rawVersion, source, managed := dep.Version, filePath, dep.managed
+			if rawVersion == "" {
+				rawVersion, source, managed = model.managed[name].Version, model.managed[name].Source, true
+			}
+
+			// An alias replaces the coordinate both where it's used and where its version is managed
+			if groupID != "" {
+				for _, path := range []string{filePath, source} {
+					file, err := load(path)
+					if err != nil {
+						return nil, err
+					}
+					for _, loc := range file.locations.dependencies {
+						if inActiveProfile(loc.profile, file.active) && p.dependencyName(loc.dependency, file.properties) == name {
+							file.edits = append(file.edits, loc.renameEdits(groupID, artifactID)...)
+						}
+					}
+				}
+			}
+
+			if rawVersion == "" {
+				continue
+			}
+
+			// Property references are updated in the POM that defines the property
+			if propName, ok := propertyName(rawVersion); ok {
+				propName = definingProperty(propName, model.properties)
+
+				// Built-in properties like ${project.version} are never rewritten
+				propSource, ok := model.propertySources[propName]
+				if !ok {
+					continue
+				}
+				file, err := load(propSource)
+				if err != nil {
+					return nil, err
+				}
+				if r, exists := file.locations.property(propName, file.active); exists {
+					file.edits = append(file.edits, textEdit{r, newVersion})
+				}
+				continue
+			}
+
+			// Literal versions are updated on the declaring element
+			file, err := load(source)
+			if err != nil {
+				return nil, err
+			}
+			for _, loc := range file.locations.dependencies {
+				if loc.managed == managed && loc.dependency.Version == rawVersion && inActiveProfile(loc.profile, file.active) &&
+					p.dependencyName(loc.dependency, file.properties) == name {
+					file.edits = append(file.edits, textEdit{loc.version, newVersion})
+				}
+			}
+		}
+	}
+
+	updated := make(map[string]string)
+	for filePath, file := range files {
+		if len(file.edits) > 0 {
+			updated[filePath] = applyTextEdits(string(file.content), file.edits)
+		}
+	}
+	return updated, nil
+}
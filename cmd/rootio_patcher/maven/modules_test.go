@@ -0,0 +1,185 @@
+package maven
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"rootio_patcher/cmd/rootio_patcher/common"
+	"rootio_patcher/pkg/rootio"
+)
+
+// copyMultiModule copies the multi-module fixture to a temp directory and returns its root pom.xml
+func copyMultiModule(t *testing.T) string {
+	t.Helper()
+
+	rootDir := t.TempDir()
+	if err := os.CopyFS(rootDir, os.DirFS(filepath.Join("testdata", "multi-module"))); err != nil {
+		t.Fatalf("Failed to copy fixture: %v", err)
+	}
+	return filepath.Join(rootDir, "pom.xml")
+}
+
+func TestDiscoverModules(t *testing.T) {
+	rootFile := copyMultiModule(t)
+	rootDir := filepath.Dir(rootFile)
+
+	files, err := discoverModules(rootFile)
+	if err != nil {
+		t.Fatalf("discoverModules failed: %v", err)
+	}
+
+	expected := []string{
+		rootFile,
+		filepath.Join(rootDir, "core", "pom.xml"),
+		filepath.Join(rootDir, "web", "pom.xml"),
+	}
+	if !reflect.DeepEqual(files, expected) {
+		t.Errorf("Expected %v, got %v", expected, files)
+	}
+}
+
+func TestDiscoverModules_MissingModule(t *testing.T) {
+	rootFile := copyMultiModule(t)
+	if err := os.RemoveAll(filepath.Join(filepath.Dir(rootFile), "web")); err != nil {
+		t.Fatalf("Failed to remove module: %v", err)
+	}
+
+	if _, err := discoverModules(rootFile); err == nil || !strings.Contains(err.Error(), "web") {
+		t.Errorf("Expected an error for the missing web module, got: %v", err)
+	}
+}
+
+func TestMavenParser_UpdateModules(t *testing.T) {
+	rootFile := copyMultiModule(t)
+	rootDir := filepath.Dir(rootFile)
+
+	files, err := discoverModules(rootFile)
+	if err != nil {
+		t.Fatalf("discoverModules failed: %v", err)
+	}
+
+	updated, err := NewParser(WithParentResolution(true)).UpdateModules(context.Background(), files, map[string]string{
+		"com.fasterxml.jackson.core:jackson-databind": "2.13.4.2",
+		"org.apache.logging.log4j:log4j-core":         "2.17.1",
+		"org.apache.commons:commons-text":             "1.10.0",
+	})
+	if err != nil {
+		t.Fatalf("UpdateModules failed: %v", err)
+	}
+
+	// core only inherits its versions, so the parent and web are the only files changed
+	if len(updated) != 2 {
+		t.Fatalf("Expected the parent and web POMs to change, got %v", updated)
+	}
+
+	root := updated[rootFile]
+	for _, want := range []string{
+		"<jackson.version>2.13.4.2</jackson.version>",
+		"<version>2.17.1</version>",
+	} {
+		if !strings.Contains(root, want) {
+			t.Errorf("Expected parent POM to contain %q, got:\n%s", want, root)
+		}
+	}
+
+	web := updated[filepath.Join(rootDir, "web", "pom.xml")]
+	if !strings.Contains(web, "<version>1.10.0</version>") {
+		t.Errorf("Expected web POM to update commons-text, got:\n%s", web)
+	}
+	if !strings.Contains(web, "<version>${jackson.version}</version>") {
+		t.Error("Expected web POM to keep referencing the parent's jackson.version property")
+	}
+}
+
+func TestMavenApp_Run_Recursive(t *testing.T) {
+	rootFile := copyMultiModule(t)
+	rootDir := filepath.Dir(rootFile)
+
+	calls := 0
+	var analyzed []string
+	mockAPIClient := &MockAPIClient{
+		AnalyzePackagesFunc: func(ctx context.Context, packages []rootio.Package) (*rootio.AnalyzePackagesResponse, error) {
+			calls++
+			for _, pkg := range packages {
+				analyzed = append(analyzed, pkg.Name+"@"+pkg.Version)
+			}
+			return &rootio.AnalyzePackagesResponse{
+				Patches: []rootio.PackagePatch{
+					{
+						PackageName: "com.fasterxml.jackson.core:jackson-databind",
+						Version:     "2.13.0",
+						Patch:       rootio.PatchInfo{Name: "com.fasterxml.jackson.core:jackson-databind", Version: "2.13.4.2"},
+					},
+					{
+						PackageName: "org.apache.logging.log4j:log4j-core",
+						Version:     "2.14.1",
+						Patch:       rootio.PatchInfo{Name: "org.apache.logging.log4j:log4j-core", Version: "2.17.1"},
+					},
+				},
+			}, nil
+		},
+	}
+
+	app := NewAppWithServices("test-key", "https://api.root.io", rootFile, false,
+		slog.New(slog.NewTextHandler(io.Discard, nil)), NewParser(WithParentResolution(true)), mockAPIClient,
+		common.WithRecursive(true))
+
+	if err := app.Run(context.Background()); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	// Every module is analyzed in one call, with shared versions sent once
+	if calls != 1 {
+		t.Errorf("Expected one analysis call, got %d", calls)
+	}
+	expected := []string{
+		"org.apache.logging.log4j:log4j-core@2.14.1",
+		"com.fasterxml.jackson.core:jackson-databind@2.13.0",
+		"com.example:core@1.0.0",
+		"org.apache.commons:commons-text@1.9",
+	}
+	if !reflect.DeepEqual(analyzed, expected) {
+		t.Errorf("Expected analyzed packages %v, got %v", expected, analyzed)
+	}
+
+	root, err := os.ReadFile(rootFile)
+	if err != nil {
+		t.Fatalf("Failed to read parent POM: %v", err)
+	}
+	if !strings.Contains(string(root), "<jackson.version>2.13.4.2</jackson.version>") ||
+		!strings.Contains(string(root), "<version>2.17.1</version>") {
+		t.Errorf("Expected the versions inherited by the modules to be patched in the parent, got:\n%s", root)
+	}
+
+	original, err := os.ReadFile(filepath.Join("testdata", "multi-module", "core", "pom.xml"))
+	if err != nil {
+		t.Fatalf("Failed to read fixture: %v", err)
+	}
+	core, err := os.ReadFile(filepath.Join(rootDir, "core", "pom.xml"))
+	if err != nil {
+		t.Fatalf("Failed to read core POM: %v", err)
+	}
+	if string(core) != string(original) {
+		t.Errorf("Expected core POM to be unchanged, got:\n%s", core)
+	}
+}
+
+func TestMavenApp_Run_RecursiveRejectsGradle(t *testing.T) {
+	buildFile := filepath.Join(t.TempDir(), "build.gradle")
+	if err := os.WriteFile(buildFile, []byte("dependencies {}\n"), 0644); err != nil {
+		t.Fatalf("Failed to write build file: %v", err)
+	}
+
+	app := NewApp("test-key", "https://api.root.io", buildFile, true,
+		slog.New(slog.NewTextHandler(io.Discard, nil)), common.WithRecursive(true))
+
+	if err := app.Run(context.Background()); err == nil || !strings.Contains(err.Error(), "--recursive") {
+		t.Errorf("Expected --recursive to be rejected for Gradle builds, got: %v", err)
+	}
+}
@@ -0,0 +1,154 @@
+package maven
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// pomFile is a parsed POM and the path it was loaded from
+type pomFile struct {
+	project Project
+	path    string
+}
+
+// managedDependency is a dependencyManagement entry and the POM that declares it
+type managedDependency struct {
+	Dependency
+	Source string
+}
+
+// effectiveModel holds properties and managed versions merged across the parent chain
+type effectiveModel struct {
+	properties      map[string]string
+	propertySources map[string]string // property name -> POM declaring it
+	managed         map[string]managedDependency
+}
+
+// buildModel builds the effective model of a project, merging parent POMs when enabled.
+// Children override anything they inherit.
+func (p *MavenParser) buildModel(project Project, filePath string) (effectiveModel, error) {
+	var parents []pomFile
+	if p.resolveParent {
+		var err error
+		if parents, err = loadParents(project, filePath, p.loadProject); err != nil {
+			return effectiveModel{}, err
+		}
+		for i := range parents {
+			parents[i].project = p.applyProfiles(parents[i].project)
+		}
+	}
+
+	model := effectiveModel{
+		properties:      make(map[string]string),
+		propertySources: make(map[string]string),
+		managed:         make(map[string]managedDependency),
+	}
+
+	// Apply from the root ancestor down to the project itself
+	chain := append([]pomFile{{project, filePath}}, parents...)
+	for i := len(chain) - 1; i > 0; i-- {
+		for name, value := range chain[i].project.Properties.Properties {
+			model.properties[name] = value
+			model.propertySources[name] = chain[i].path
+		}
+	}
+	for name, value := range projectProperties(project) {
+		model.properties[name] = value
+		delete(model.propertySources, name)
+	}
+	for name := range project.Properties.Properties {
+		model.propertySources[name] = filePath
+	}
+
+	// Managed entries are keyed after all properties are known, so inherited groupIds resolve
+	for i := len(chain) - 1; i >= 0; i-- {
+		for _, dep := range chain[i].project.DependencyManagement.Dependencies.Dependency {
+			// An imported BOM's own version doesn't manage a dependency of the same name
+			if dep.GroupID == "" || dep.ArtifactID == "" || dep.isPOM() {
+				continue
+			}
+			model.managed[p.dependencyName(dep, model.properties)] = managedDependency{dep, chain[i].path}
+		}
+	}
+
+	return model, nil
+}
+
+// versionSource returns the POM that holds the literal value of a raw version
+func (m effectiveModel) versionSource(rawVersion, declaredIn string) string {
+	propName, ok := propertyName(rawVersion)
+	if !ok {
+		return declaredIn
+	}
+
+	if source, ok := m.propertySources[definingProperty(propName, m.properties)]; ok {
+		return source
+	}
+	return declaredIn
+}
+
+// loadParents follows <parent><relativePath> links, reading each parent with load, and returns
+// the ancestors nearest first. Resolution stops at the first parent that isn't available locally.
+func loadParents(project Project, filePath string, load func(string) (Project, error)) ([]pomFile, error) {
+	visited := make(map[string]bool)
+	if absPath, err := filepath.Abs(filePath); err == nil {
+		visited[absPath] = true
+	}
+
+	var parents []pomFile
+	for project.Parent.ArtifactID != "" {
+		parentPath := parentPOMPath(project.Parent, filePath)
+		if parentPath == "" {
+			break
+		}
+
+		absPath, err := filepath.Abs(parentPath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to resolve parent POM path %s: %w", parentPath, err)
+		}
+		if visited[absPath] {
+			return nil, fmt.Errorf("parent POM cycle detected at %s", parentPath)
+		}
+		visited[absPath] = true
+
+		parent, err := load(parentPath)
+		if errors.Is(err, fs.ErrNotExist) {
+			// Parent is only available from a repository
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to load parent POM %s: %w", parentPath, err)
+		}
+
+		parents = append(parents, pomFile{parent, parentPath})
+		project, filePath = parent, parentPath
+	}
+
+	return parents, nil
+}
+
+// parentPOMPath returns the local path of a parent POM, or "" if local lookup is disabled
+func parentPOMPath(parent Parent, childPath string) string {
+	relativePath := "../pom.xml"
+	if parent.RelativePath != nil {
+		relativePath = *parent.RelativePath
+	}
+	if relativePath == "" {
+		return ""
+	}
+
+	parentPath := relativePath
+	if !filepath.IsAbs(parentPath) {
+		parentPath = filepath.Join(filepath.Dir(childPath), relativePath)
+	}
+
+	// relativePath may point at the parent's directory
+	if info, err := os.Stat(parentPath); err == nil && info.IsDir() {
+		parentPath = filepath.Join(parentPath, "pom.xml")
+	}
+
+	return parentPath
+}
@@ -0,0 +1,178 @@
+package maven
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const parentPOM = `<?xml version="1.0" encoding="UTF-8"?>
+<project xmlns="http://maven.apache.org/POM/4.0.0">
+    <groupId>com.example</groupId>
+    <artifactId>parent</artifactId>
+    <version>1.0.0</version>
+    <packaging>pom</packaging>
+
+    <properties>
+        <log4j.version>2.14.1</log4j.version>
+    </properties>
+
+    <dependencyManagement>
+        <dependencies>
+            <dependency>
+                <groupId>org.apache.logging.log4j</groupId>
+                <artifactId>log4j-core</artifactId>
+                <version>${log4j.version}</version>
+            </dependency>
+            <dependency>
+                <groupId>junit</groupId>
+                <artifactId>junit</artifactId>
+                <version>4.12</version>
+            </dependency>
+        </dependencies>
+    </dependencyManagement>
+</project>`
+
+const childPOM = `<?xml version="1.0" encoding="UTF-8"?>
+<project xmlns="http://maven.apache.org/POM/4.0.0">
+    <parent>
+        <groupId>com.example</groupId>
+        <artifactId>parent</artifactId>
+        <version>1.0.0</version>
+    </parent>
+    <artifactId>child</artifactId>
+
+    <properties>
+        <guava.version>29.0-jre</guava.version>
+    </properties>
+
+    <dependencies>
+        <dependency>
+            <groupId>org.apache.logging.log4j</groupId>
+            <artifactId>log4j-core</artifactId>
+        </dependency>
+        <dependency>
+            <groupId>com.google.guava</groupId>
+            <artifactId>guava</artifactId>
+            <version>${guava.version}</version>
+        </dependency>
+    </dependencies>
+</project>`
+
+// writeModule writes a parent pom.xml and a child module pom.xml, returning the child path
+func writeModule(t *testing.T, parent, child string) string {
+	t.Helper()
+
+	rootDir := t.TempDir()
+	childDir := filepath.Join(rootDir, "child")
+	if err := os.Mkdir(childDir, 0755); err != nil {
+		t.Fatalf("Failed to create module dir: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(rootDir, "pom.xml"), []byte(parent), 0644); err != nil {
+		t.Fatalf("Failed to write parent pom: %v", err)
+	}
+
+	childFile := filepath.Join(childDir, "pom.xml")
+	if err := os.WriteFile(childFile, []byte(child), 0644); err != nil {
+		t.Fatalf("Failed to write child pom: %v", err)
+	}
+	return childFile
+}
+
+func TestMavenParser_Parse_ParentDisabledByDefault(t *testing.T) {
+	childFile := writeModule(t, parentPOM, childPOM)
+
+	packages, err := NewParser().Parse(context.Background(), childFile)
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+
+	// Only guava has a version without the parent
+	if len(packages) != 1 || packages[0].Name != "com.google.guava:guava" {
+		t.Fatalf("Expected only guava without parent resolution, got %+v", packages)
+	}
+}
+
+func TestMavenParser_Parse_ResolveParent(t *testing.T) {
+	childFile := writeModule(t, parentPOM, childPOM)
+	parentFile := filepath.Join(filepath.Dir(filepath.Dir(childFile)), "pom.xml")
+
+	parser := NewParser(WithParentResolution(true))
+	packages, err := parser.Parse(context.Background(), childFile)
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+
+	if len(packages) != 2 {
+		t.Fatalf("Expected 2 packages, got %d: %+v", len(packages), packages)
+	}
+
+	log4j := packages[0]
+	if log4j.Name != "org.apache.logging.log4j:log4j-core" || log4j.Version != "2.14.1" {
+		t.Errorf("Expected log4j-core 2.14.1 from parent, got %s %s", log4j.Name, log4j.Version)
+	}
+	if log4j.Location != parentFile {
+		t.Errorf("Expected log4j-core version to be located in parent %s, got %s", parentFile, log4j.Location)
+	}
+
+	guava := packages[1]
+	if guava.Version != "29.0-jre" || guava.Location != childFile {
+		t.Errorf("Expected guava 29.0-jre declared in child, got %s at %s", guava.Version, guava.Location)
+	}
+}
+
+func TestMavenParser_Parse_ResolveParent_ChildOverridesProperty(t *testing.T) {
+	child := strings.Replace(childPOM,
+		"<guava.version>29.0-jre</guava.version>",
+		"<guava.version>29.0-jre</guava.version>\n        <log4j.version>2.17.1</log4j.version>", 1)
+	childFile := writeModule(t, parentPOM, child)
+
+	parser := NewParser(WithParentResolution(true))
+	packages, err := parser.Parse(context.Background(), childFile)
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+
+	if packages[0].Version != "2.17.1" {
+		t.Errorf("Expected child property to override parent, got %s", packages[0].Version)
+	}
+	if packages[0].Location != childFile {
+		t.Errorf("Expected overridden version to be located in child, got %s", packages[0].Location)
+	}
+}
+
+func TestMavenParser_Parse_ResolveParent_Cycle(t *testing.T) {
+	// The parent points back at the child module
+	parent := strings.Replace(parentPOM, "<packaging>pom</packaging>", `<packaging>pom</packaging>
+    <parent>
+        <groupId>com.example</groupId>
+        <artifactId>child</artifactId>
+        <version>1.0.0</version>
+        <relativePath>child/pom.xml</relativePath>
+    </parent>`, 1)
+	childFile := writeModule(t, parent, childPOM)
+
+	parser := NewParser(WithParentResolution(true))
+	_, err := parser.Parse(context.Background(), childFile)
+	if err == nil || !strings.Contains(err.Error(), "cycle") {
+		t.Fatalf("Expected parent cycle error, got: %v", err)
+	}
+}
+
+func TestMavenParser_Parse_ResolveParent_MissingParent(t *testing.T) {
+	pomFile := filepath.Join(t.TempDir(), "pom.xml")
+	if err := os.WriteFile(pomFile, []byte(childPOM), 0644); err != nil {
+		t.Fatalf("Failed to write pom: %v", err)
+	}
+
+	parser := NewParser(WithParentResolution(true))
+	packages, err := parser.Parse(context.Background(), pomFile)
+	if err != nil {
+		t.Fatalf("Expected missing parent to be ignored, got: %v", err)
+	}
+	if len(packages) != 1 {
+		t.Errorf("Expected 1 package, got %d", len(packages))
+	}
+}
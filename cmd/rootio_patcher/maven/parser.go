@@ -1,21 +1,44 @@
 package maven
 
 import (
+	"bytes"
 	"context"
 	"encoding/xml"
 	"fmt"
+	"io"
 	"os"
-	"regexp"
+	"path/filepath"
+	"sort"
 	"strings"
 	"rootio_patcher/cmd/rootio_patcher/common"
 )
 
 // Parser handles parsing of Maven pom.xml files
-type MavenParser struct{}
+type MavenParser struct {
+	resolveParent bool
+	profiles      []string // profile ids read instead of the active-by-default ones
+
+	// staged is updated content read in place of the files on disk, keyed by absolute path
+	staged map[string]string
+}
+
+// ParserOption configures a MavenParser
+type ParserOption func(*MavenParser)
+
+// WithParentResolution enables loading parent POMs to resolve inherited properties and managed versions
+func WithParentResolution(enabled bool) ParserOption {
+	return func(p *MavenParser) {
+		p.resolveParent = enabled
+	}
+}
 
 // NewParser creates a new Maven parser
-func NewParser() *MavenParser {
-	return &MavenParser{}
+func NewParser(opts ...ParserOption) *MavenParser {
+	p := &MavenParser{}
+	for _, opt := range opts {
+		opt(p)
+	}
+	return p
 }
 
 // Ecosystem returns the ecosystem name
@@ -44,8 +67,26 @@ type Project struct {
 	GroupID      string       `xml:"groupId"`
 	ArtifactID   string       `xml:"artifactId"`
 	Version      string       `xml:"version"`
+	Parent       Parent       `xml:"parent"`
+	Modules      []string     `xml:"modules>module"`
 	Properties   Properties   `xml:"properties"`
 	Dependencies Dependencies `xml:"dependencies"`
+	Profiles     []Profile    `xml:"profiles>profile"`
+
+	DependencyManagement DependencyManagement `xml:"dependencyManagement"`
+}
+
+// Parent represents the parent POM reference
+type Parent struct {
+	GroupID      string  `xml:"groupId"`
+	ArtifactID   string  `xml:"artifactId"`
+	Version      string  `xml:"version"`
+	RelativePath *string `xml:"relativePath"` // nil when absent, "" disables local lookup
+}
+
+// DependencyManagement represents the dependencyManagement section
+type DependencyManagement struct {
+	Dependencies Dependencies `xml:"dependencies"`
 }
 
 // Properties represents Maven properties
@@ -89,66 +130,238 @@ type Dependency struct {
 	ArtifactID string `xml:"artifactId"`
 	Version    string `xml:"version"`
 	Scope      string `xml:"scope"`
+	Type       string `xml:"type"`
+	Profile    string `xml:"-"` // id of the profile declaring the dependency, empty outside profiles
+
+	Exclusions []Exclusion `xml:"exclusions>exclusion"`
+}
+
+// isPOM reports whether the dependency is a POM artifact rather than a library: an imported
+// BOM (<type>pom</type><scope>import</scope>) or a POM that only aggregates other dependencies.
+// POMs contain no code, so they are never sent for analysis; the versions a BOM manages are
+// not expanded since they can only be patched by overriding them in this project.
+func (d Dependency) isPOM() bool {
+	return d.Type == "pom" || d.Scope == "import"
 }
 
 // Parse parses pom.xml and returns all dependencies
 func (p *MavenParser) Parse(ctx context.Context, filePath string) ([]common.PackageInfo, error) {
-	content, err := os.ReadFile(filePath)
+	project, err := p.loadProject(filePath)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read file: %w", err)
+		return nil, err
 	}
+	project = p.applyProfiles(project)
 
-	var project Project
-	if err := xml.Unmarshal(content, &project); err != nil {
-		return nil, fmt.Errorf("failed to parse XML: %w", err)
+	model, err := p.buildModel(project, filePath)
+	if err != nil {
+		return nil, err
 	}
+	properties := model.properties
 
 	var packages []common.PackageInfo
+	seen := make(map[string]bool)
+	exclusions := p.exclusions(project, properties)
 
 	for _, dep := range project.Dependencies.Dependency {
-		if dep.GroupID == "" || dep.ArtifactID == "" {
+		if dep.GroupID == "" || dep.ArtifactID == "" || dep.isPOM() {
 			continue
 		}
 
+		// Maven package name format: groupId:artifactId
+		name := p.dependencyName(dep, properties)
+
+		// Fall back to the version from dependencyManagement
+		rawVersion, source := dep.Version, filePath
+		if rawVersion == "" {
+			rawVersion, source = model.managed[name].Version, model.managed[name].Source
+		}
+
 		// Resolve version property references
-		version := p.resolveProperty(dep.Version, project.Properties.Properties)
+		version := p.resolveProperty(rawVersion, properties)
 
 		// Skip dependencies without version (managed by parent/BOM)
 		if version == "" {
 			continue
 		}
 
-		// Maven package name format: groupId:artifactId
-		name := fmt.Sprintf("%s:%s", dep.GroupID, dep.ArtifactID)
-
 		isDev := dep.Scope == "test"
 
+		seen[name] = true
 		packages = append(packages, common.PackageInfo{
 			Name:              name,
-			Version:           version,
+			Version:           packageVersion(version),
 			VersionConstraint: version,
 			Ecosystem:         common.EcosystemMaven,
 			Direct:            true, // Maven doesn't have lock files, all declared deps are "direct"
 			Dev:               isDev,
+			Location:          model.versionSource(rawVersion, source),
+			Profile:           dep.Profile,
+		})
+	}
+
+	// Managed dependencies pin versions for transitive deps too, so include those not declared above
+	for _, dep := range project.DependencyManagement.Dependencies.Dependency {
+		if dep.GroupID == "" || dep.ArtifactID == "" || dep.isPOM() {
+			continue
+		}
+
+		name := p.dependencyName(dep, properties)
+		if seen[name] {
+			continue
+		}
+
+		// Unresolved properties can't be analyzed
+		version := p.resolveProperty(dep.Version, properties)
+		if version == "" {
+			continue
+		}
+
+		seen[name] = true
+		packages = append(packages, common.PackageInfo{
+			Name:              name,
+			Version:           packageVersion(version),
+			VersionConstraint: version,
+			Ecosystem:         common.EcosystemMaven,
+			Direct:            true,
+			Dev:               dep.Scope == "test",
+			Location:          model.versionSource(dep.Version, filePath),
+			Profile:           dep.Profile,
+			ExcludedBy:        exclusions.excludedBy(name),
 		})
 	}
 
 	return packages, nil
 }
 
-// resolveProperty resolves Maven property references like ${log4j.version}
+// ParseUpdated parses filePath as if the updated content of each file, keyed by path, had been
+// written, so updates can be checked before anything on disk changes
+func (p *MavenParser) ParseUpdated(
+	ctx context.Context, filePath string, updated map[string]string,
+) ([]common.PackageInfo, error) {
+	staged := *p
+	staged.staged = make(map[string]string, len(updated))
+	for file, content := range updated {
+		absPath, err := filepath.Abs(file)
+		if err != nil {
+			return nil, fmt.Errorf("failed to resolve path %s: %w", file, err)
+		}
+		staged.staged[absPath] = content
+	}
+	return staged.Parse(ctx, filePath)
+}
+
+// loadProject parses a POM file, or its staged content
+func (p *MavenParser) loadProject(filePath string) (Project, error) {
+	absPath, err := filepath.Abs(filePath)
+	if err != nil {
+		return Project{}, fmt.Errorf("failed to resolve path %s: %w", filePath, err)
+	}
+	content, ok := p.staged[absPath]
+	if !ok {
+		return loadProject(filePath)
+	}
+
+	var project Project
+	if err := xml.Unmarshal([]byte(content), &project); err != nil {
+		return Project{}, fmt.Errorf("failed to parse XML: %w", err)
+	}
+	return project, nil
+}
+
+// loadProject reads and parses a POM file
+func loadProject(filePath string) (Project, error) {
+	content, err := os.ReadFile(filePath)
+	if err != nil {
+		return Project{}, fmt.Errorf("failed to read file: %w", err)
+	}
+
+	var project Project
+	if err := xml.Unmarshal(content, &project); err != nil {
+		return Project{}, fmt.Errorf("failed to parse XML: %w", err)
+	}
+
+	return project, nil
+}
+
+// dependencyName returns the groupId:artifactId name with property references resolved
+func (p *MavenParser) dependencyName(dep Dependency, properties map[string]string) string {
+	return fmt.Sprintf("%s:%s",
+		p.resolveProperty(dep.GroupID, properties),
+		p.resolveProperty(dep.ArtifactID, properties))
+}
+
+// projectProperties returns the POM properties plus the built-in project.* properties
+func projectProperties(project Project) map[string]string {
+	properties := make(map[string]string, len(project.Properties.Properties)+3)
+	for name, value := range project.Properties.Properties {
+		properties[name] = value
+	}
+
+	// Coordinates are inherited from the parent when not declared
+	builtins := map[string][2]string{
+		"project.groupId":    {project.GroupID, project.Parent.GroupID},
+		"project.artifactId": {project.ArtifactID, project.Parent.ArtifactID},
+		"project.version":    {project.Version, project.Parent.Version},
+	}
+	for name, values := range builtins {
+		if values[0] != "" {
+			properties[name] = values[0]
+		} else if values[1] != "" {
+			properties[name] = values[1]
+		}
+	}
+
+	return properties
+}
+
+// resolveProperty resolves Maven property references like ${log4j.version},
+// following chained references. Unresolvable or cyclic references are returned unchanged.
 func (p *MavenParser) resolveProperty(value string, properties map[string]string) string {
-	if value == "" || !strings.HasPrefix(value, "${") {
+	resolved := value
+	visited := make(map[string]bool)
+
+	for {
+		propName, ok := propertyName(resolved)
+		if !ok || visited[propName] {
+			break
+		}
+		visited[propName] = true
+
+		next, exists := properties[propName]
+		if !exists {
+			break
+		}
+		resolved = next
+	}
+
+	if _, ok := propertyName(resolved); ok {
 		return value
 	}
+	return resolved
+}
 
-	// Extract property name: ${foo.bar} -> foo.bar
-	propName := strings.TrimSuffix(strings.TrimPrefix(value, "${"), "}")
-	if resolved, ok := properties[propName]; ok {
-		return resolved
+// propertyName extracts the name from a property reference: ${foo.bar} -> foo.bar
+func propertyName(value string) (string, bool) {
+	if !strings.HasPrefix(value, "${") || !strings.HasSuffix(value, "}") {
+		return "", false
 	}
+	return strings.TrimSuffix(strings.TrimPrefix(value, "${"), "}"), true
+}
 
-	return value
+// definingProperty follows a chain of property references to the property that holds the literal value
+func definingProperty(propName string, properties map[string]string) string {
+	visited := map[string]bool{propName: true}
+	for {
+		next, ok := propertyName(properties[propName])
+		if !ok || visited[next] {
+			return propName
+		}
+		if _, exists := properties[next]; !exists {
+			return propName
+		}
+		visited[next] = true
+		propName = next
+	}
 }
 
 // Update updates dependency versions in pom.xml
@@ -163,51 +376,232 @@ func (p *MavenParser) Update(ctx context.Context, filePath string, updates map[s
 	if err := xml.Unmarshal(content, &project); err != nil {
 		return "", fmt.Errorf("failed to parse XML: %w", err)
 	}
+	project = p.applyProfiles(project)
+	active := p.activeProfiles(project)
 
-	// Work with raw content to preserve formatting
-	updatedContent := string(content)
+	// Locate the exact elements Parse reads, so nothing else (inactive profiles, plugins) is touched
+	locations, err := locateElements(content)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse XML: %w", err)
+	}
 
-	for _, dep := range project.Dependencies.Dependency {
-		if dep.GroupID == "" || dep.ArtifactID == "" {
+	properties := projectProperties(project)
+	var edits []textEdit
+
+	for _, loc := range locations.dependencies {
+		dep := loc.dependency
+		if dep.GroupID == "" || dep.ArtifactID == "" || !inActiveProfile(loc.profile, active) {
+			continue
+		}
+
+		spec, ok := updates[p.dependencyName(dep, properties)]
+		if !ok {
+			continue
+		}
+
+		// An alias replaces the coordinate everywhere, including where the version is managed
+		groupID, artifactID, newVersion := parseUpdateSpec(spec)
+		edits = append(edits, loc.renameEdits(groupID, artifactID)...)
+
+		// Dependencies without an inline version are updated through their managed entry
+		if dep.Version == "" {
+			continue
+		}
+
+		// If it's a property reference, update the property instead
+		if propName, ok := propertyName(dep.Version); ok {
+			propName = definingProperty(propName, project.Properties.Properties)
+
+			// Built-in properties like ${project.version} are never rewritten
+			if r, exists := locations.property(propName, active); exists {
+				edits = append(edits, textEdit{r, newVersion})
+			}
 			continue
 		}
 
-		name := fmt.Sprintf("%s:%s", dep.GroupID, dep.ArtifactID)
+		// Direct version - replace the version element of this dependency only
+		edits = append(edits, textEdit{loc.version, newVersion})
+	}
 
-		if newVersion, ok := updates[name]; ok {
-			oldVersion := dep.Version
+	return applyTextEdits(string(content), edits), nil
+}
+
+// parseUpdateSpec splits an update into the coordinate the dependency is replaced by and its
+// version. A plain version ("2.17.1") keeps the coordinate; an alias
+// ("io.root.logging:log4j-core:2.17.1") replaces the groupId and artifactId.
+func parseUpdateSpec(spec string) (groupID, artifactID, version string) {
+	parts := strings.Split(spec, ":")
+	if len(parts) != 3 {
+		return "", "", spec
+	}
+	return parts[0], parts[1], parts[2]
+}
 
-			// If it's a property reference, update the property instead
-			if strings.HasPrefix(oldVersion, "${") {
-				propName := strings.TrimSuffix(strings.TrimPrefix(oldVersion, "${"), "}")
-				if _, exists := project.Properties.Properties[propName]; exists {
-					// Replace property value in content
-					pattern := fmt.Sprintf(`(<%s>)[^<]*(</[^>]*>)`, regexp.QuoteMeta(propName))
-					re := regexp.MustCompile(pattern)
-					updatedContent = re.ReplaceAllString(updatedContent, fmt.Sprintf("${1}%s${2}", newVersion))
+// textRange is a [start, end) byte range in the file content
+type textRange struct {
+	start, end int
+}
+
+// textEdit replaces the text in a range
+type textEdit struct {
+	at   textRange
+	text string
+}
+
+// dependencyLocation records where a dependency and its coordinate and version text live in the file
+type dependencyLocation struct {
+	dependency Dependency
+	managed    bool
+	profile    int // index of the declaring profile, -1 outside profiles
+	groupID    textRange
+	artifactID textRange
+	version    textRange
+}
+
+// renameEdits replaces the groupId and artifactId of the dependency, or returns nil without a groupID
+func (loc dependencyLocation) renameEdits(groupID, artifactID string) []textEdit {
+	if groupID == "" {
+		return nil
+	}
+	return []textEdit{{loc.groupID, groupID}, {loc.artifactID, artifactID}}
+}
+
+// pomLocations holds the locations of the elements Update may rewrite
+type pomLocations struct {
+	dependencies      []dependencyLocation
+	properties        map[string]textRange         // property name -> value text
+	profileProperties map[int]map[string]textRange // profile index -> property name -> value text
+}
+
+// locateElements scans the POM for the <dependencies>, <dependencyManagement> and <properties>
+// children of <project> and of each <profile>, recording byte offsets of their text values.
+// Comments and child order don't matter since elements are located by the XML decoder.
+func locateElements(content []byte) (pomLocations, error) {
+	locations := pomLocations{
+		properties:        make(map[string]textRange),
+		profileProperties: make(map[int]map[string]textRange),
+	}
+	decoder := xml.NewDecoder(bytes.NewReader(content))
+
+	var path []string
+	var current *dependencyLocation
+	profile := -1
+
+	// Text of the innermost element, excluding surrounding whitespace and comments
+	var text strings.Builder
+	value := textRange{-1, -1}
+
+	for {
+		offset := int(decoder.InputOffset())
+		token, err := decoder.Token()
+		if err == io.EOF {
+			return locations, nil
+		}
+		if err != nil {
+			return pomLocations{}, err
+		}
+
+		switch t := token.(type) {
+		case xml.StartElement:
+			path = append(path, t.Name.Local)
+			text.Reset()
+			value = textRange{-1, -1}
+
+			switch strings.Join(path, "/") {
+			case "project/profiles/profile":
+				profile++
+				locations.profileProperties[profile] = make(map[string]textRange)
+			case "project/dependencies/dependency":
+				current = &dependencyLocation{profile: -1}
+			case "project/dependencyManagement/dependencies/dependency":
+				current = &dependencyLocation{managed: true, profile: -1}
+			case "project/profiles/profile/dependencies/dependency":
+				current = &dependencyLocation{profile: profile}
+			case "project/profiles/profile/dependencyManagement/dependencies/dependency":
+				current = &dependencyLocation{managed: true, profile: profile}
+			}
+		case xml.CharData:
+			if strings.TrimSpace(string(t)) == "" {
+				continue
+			}
+			r := trimRange(content, textRange{offset, int(decoder.InputOffset())})
+			if value.start < 0 {
+				value.start = r.start
+			}
+			value.end = r.end
+			text.Write(t)
+		case xml.EndElement:
+			// Empty elements get an insertion point just before the end tag
+			if value.start < 0 {
+				value = textRange{offset, offset}
+			}
+			elementText := strings.TrimSpace(text.String())
+
+			switch {
+			case len(path) == 3 && path[1] == "properties":
+				locations.properties[t.Name.Local] = value
+			case len(path) == 5 && path[1] == "profiles" && path[3] == "properties":
+				locations.profileProperties[profile][t.Name.Local] = value
+			case current != nil && t.Name.Local == "dependency":
+				locations.dependencies = append(locations.dependencies, *current)
+				current = nil
+			case current != nil && path[len(path)-2] == "dependency":
+				switch t.Name.Local {
+				case "groupId":
+					current.dependency.GroupID = elementText
+					current.groupID = value
+				case "artifactId":
+					current.dependency.ArtifactID = elementText
+					current.artifactID = value
+				case "version":
+					current.dependency.Version = elementText
+					current.version = value
+				case "scope":
+					current.dependency.Scope = elementText
 				}
-			} else {
-				// Direct version - replace in content
-				updatedContent = p.replaceDependencyVersion(updatedContent, dep.GroupID, dep.ArtifactID, oldVersion, newVersion)
 			}
+
+			path = path[:len(path)-1]
+			value = textRange{-1, -1}
 		}
 	}
+}
+
+// trimRange narrows a range to exclude surrounding whitespace
+func trimRange(content []byte, r textRange) textRange {
+	for r.start < r.end && isXMLSpace(content[r.start]) {
+		r.start++
+	}
+	for r.end > r.start && isXMLSpace(content[r.end-1]) {
+		r.end--
+	}
+	return r
+}
 
-	return updatedContent, nil
+// isXMLSpace reports whether b is XML whitespace
+func isXMLSpace(b byte) bool {
+	return b == ' ' || b == '\t' || b == '\n' || b == '\r'
 }
 
-// replaceDependencyVersion replaces version for a specific dependency
-func (p *MavenParser) replaceDependencyVersion(content, groupID, artifactID, oldVersion, newVersion string) string {
-	// Pattern to match a dependency block and replace its version
-	pattern := fmt.Sprintf(
-		`(<dependency>\s*<groupId>%s</groupId>\s*<artifactId>%s</artifactId>\s*<version>)%s(</version>)`,
-		regexp.QuoteMeta(groupID),
-		regexp.QuoteMeta(artifactID),
-		regexp.QuoteMeta(oldVersion),
-	)
+// applyTextEdits applies non-overlapping edits to content, ignoring duplicates of the same range
+func applyTextEdits(content string, edits []textEdit) string {
+	sort.SliceStable(edits, func(i, j int) bool {
+		return edits[i].at.start < edits[j].at.start
+	})
+
+	var b strings.Builder
+	last := 0
+	for _, edit := range edits {
+		if edit.at.start < last {
+			continue
+		}
+		b.WriteString(content[last:edit.at.start])
+		b.WriteString(edit.text)
+		last = edit.at.end
+	}
+	b.WriteString(content[last:])
 
-	re := regexp.MustCompile(pattern)
-	return re.ReplaceAllString(content, fmt.Sprintf("${1}%s${2}", newVersion))
+	return b.String()
 }
 
 // Validate validates XML syntax
@@ -326,6 +326,9 @@ func TestMavenParser_ResolveProperty(t *testing.T) {
 	properties := map[string]string{
 		"foo.version": "1.2.3",
 		"bar.version": "4.5.6",
+		"chained":     "${foo.version}",
+		"cycle.a":     "${cycle.b}",
+		"cycle.b":     "${cycle.a}",
 	}
 
 	tests := []struct {
@@ -338,6 +341,8 @@ func TestMavenParser_ResolveProperty(t *testing.T) {
 		{"direct version", "1.0.0", "1.0.0"},
 		{"empty string", "", ""},
 		{"unknown property", "${unknown}", "${unknown}"},
+		{"chained property", "${chained}", "1.2.3"},
+		{"cyclic property", "${cycle.a}", "${cycle.a}"},
 	}
 
 	for _, tt := range tests {
@@ -349,3 +354,655 @@ func TestMavenParser_ResolveProperty(t *testing.T) {
 		})
 	}
 }
+
+func TestMavenParser_Parse_DependencyManagement(t *testing.T) {
+	ctx := context.Background()
+	parser := NewParser()
+
+	tmpDir := t.TempDir()
+	pomFile := filepath.Join(tmpDir, "pom.xml")
+
+	content := `<?xml version="1.0" encoding="UTF-8"?>
+<project xmlns="http://maven.apache.org/POM/4.0.0">
+    <properties>
+        <jackson.version>2.12.0</jackson.version>
+    </properties>
+
+    <dependencyManagement>
+        <dependencies>
+            <dependency>
+                <groupId>org.apache.logging.log4j</groupId>
+                <artifactId>log4j-core</artifactId>
+                <version>2.14.1</version>
+            </dependency>
+            <dependency>
+                <groupId>com.fasterxml.jackson.core</groupId>
+                <artifactId>jackson-databind</artifactId>
+                <version>${jackson.version}</version>
+            </dependency>
+            <dependency>
+                <groupId>org.springframework</groupId>
+                <artifactId>spring-framework-bom</artifactId>
+                <version>5.3.0</version>
+                <type>pom</type>
+                <scope>import</scope>
+            </dependency>
+        </dependencies>
+    </dependencyManagement>
+
+    <dependencies>
+        <dependency>
+            <groupId>org.apache.logging.log4j</groupId>
+            <artifactId>log4j-core</artifactId>
+        </dependency>
+        <dependency>
+            <groupId>org.springframework</groupId>
+            <artifactId>spring-core</artifactId>
+        </dependency>
+    </dependencies>
+</project>`
+
+	if err := os.WriteFile(pomFile, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to create temp file: %v", err)
+	}
+
+	packages, err := parser.Parse(ctx, pomFile)
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+
+	// log4j gets its managed version, jackson is managed-only, spring-core has no resolvable version
+	if len(packages) != 2 {
+		t.Fatalf("Expected 2 packages, got %d: %+v", len(packages), packages)
+	}
+
+	if packages[0].Name != "org.apache.logging.log4j:log4j-core" || packages[0].Version != "2.14.1" {
+		t.Errorf("Expected log4j-core 2.14.1 from dependencyManagement, got %s %s", packages[0].Name, packages[0].Version)
+	}
+	if packages[1].Name != "com.fasterxml.jackson.core:jackson-databind" || packages[1].Version != "2.12.0" {
+		t.Errorf("Expected jackson-databind 2.12.0, got %s %s", packages[1].Name, packages[1].Version)
+	}
+}
+
+// bomImportPOM imports the Spring Boot BOM and declares a POM-typed aggregate dependency
+const bomImportPOM = `<?xml version="1.0" encoding="UTF-8"?>
+<project xmlns="http://maven.apache.org/POM/4.0.0">
+    <groupId>com.example</groupId>
+    <artifactId>app</artifactId>
+    <version>1.0.0</version>
+
+    <dependencyManagement>
+        <dependencies>
+            <dependency>
+                <groupId>org.springframework.boot</groupId>
+                <artifactId>spring-boot-dependencies</artifactId>
+                <version>2.7.0</version>
+                <type>pom</type>
+                <scope>import</scope>
+            </dependency>
+            <dependency>
+                <groupId>com.fasterxml.jackson.core</groupId>
+                <artifactId>jackson-databind</artifactId>
+                <version>2.13.2</version>
+            </dependency>
+        </dependencies>
+    </dependencyManagement>
+
+    <dependencies>
+        <dependency>
+            <groupId>org.springframework.boot</groupId>
+            <artifactId>spring-boot-starter-web</artifactId>
+        </dependency>
+        <dependency>
+            <groupId>org.springframework.boot</groupId>
+            <artifactId>spring-boot-dependencies</artifactId>
+        </dependency>
+        <dependency>
+            <groupId>com.example</groupId>
+            <artifactId>shared-deps</artifactId>
+            <version>3.1.0</version>
+            <type>pom</type>
+        </dependency>
+        <dependency>
+            <groupId>org.apache.logging.log4j</groupId>
+            <artifactId>log4j-core</artifactId>
+            <version>2.14.1</version>
+        </dependency>
+    </dependencies>
+</project>`
+
+func TestMavenParser_Parse_ImportedBOM(t *testing.T) {
+	pomFile := filepath.Join(t.TempDir(), "pom.xml")
+	if err := os.WriteFile(pomFile, []byte(bomImportPOM), 0644); err != nil {
+		t.Fatalf("Failed to create temp file: %v", err)
+	}
+
+	packages, err := NewParser().Parse(context.Background(), pomFile)
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+
+	// The BOM and the POM-typed dependency are not libraries, and the BOM's own version
+	// must not be used for a dependency of the same name. Starter versions come from the BOM.
+	var names []string
+	for _, pkg := range packages {
+		names = append(names, pkg.Name+"@"+pkg.Version)
+	}
+	expected := []string{
+		"org.apache.logging.log4j:log4j-core@2.14.1",
+		"com.fasterxml.jackson.core:jackson-databind@2.13.2",
+	}
+	if strings.Join(names, ",") != strings.Join(expected, ",") {
+		t.Errorf("Expected packages %v, got %v", expected, names)
+	}
+}
+
+func TestMavenParser_Update_ManagedVersion(t *testing.T) {
+	ctx := context.Background()
+	parser := NewParser()
+
+	tmpDir := t.TempDir()
+	pomFile := filepath.Join(tmpDir, "pom.xml")
+
+	content := `<?xml version="1.0" encoding="UTF-8"?>
+<project xmlns="http://maven.apache.org/POM/4.0.0">
+    <dependencyManagement>
+        <dependencies>
+            <dependency>
+                <groupId>org.apache.logging.log4j</groupId>
+                <artifactId>log4j-core</artifactId>
+                <version>2.14.1</version>
+            </dependency>
+        </dependencies>
+    </dependencyManagement>
+
+    <dependencies>
+        <dependency>
+            <groupId>org.apache.logging.log4j</groupId>
+            <artifactId>log4j-core</artifactId>
+        </dependency>
+    </dependencies>
+</project>`
+
+	if err := os.WriteFile(pomFile, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to create temp file: %v", err)
+	}
+
+	updates := map[string]string{
+		"org.apache.logging.log4j:log4j-core": "2.17.1",
+	}
+
+	updated, err := parser.Update(ctx, pomFile, updates)
+	if err != nil {
+		t.Fatalf("Update failed: %v", err)
+	}
+
+	if !strings.Contains(updated, "<version>2.17.1</version>") {
+		t.Error("Expected managed version to be updated to '2.17.1'")
+	}
+	if strings.Contains(updated, "2.14.1") {
+		t.Error("Expected old managed version '2.14.1' to be replaced")
+	}
+	if strings.Count(updated, "<version>") != 1 {
+		t.Error("Expected no inline version to be added to the dependency")
+	}
+}
+
+func TestMavenParser_Update_ManagedVersionOnly(t *testing.T) {
+	ctx := context.Background()
+	parser := NewParser()
+
+	tmpDir := t.TempDir()
+	pomFile := filepath.Join(tmpDir, "pom.xml")
+
+	// Element order inside <dependency> is not fixed and may include extras like <type>
+	content := `<?xml version="1.0" encoding="UTF-8"?>
+<project xmlns="http://maven.apache.org/POM/4.0.0">
+    <dependencyManagement>
+        <dependencies>
+            <dependency>
+                <artifactId>jackson-databind</artifactId>
+                <groupId>com.fasterxml.jackson.core</groupId>
+                <type>jar</type>
+                <version>2.12.0</version>
+            </dependency>
+        </dependencies>
+    </dependencyManagement>
+
+    <dependencies>
+        <dependency>
+            <groupId>com.fasterxml.jackson.core</groupId>
+            <artifactId>jackson-databind</artifactId>
+            <scope>compile</scope>
+        </dependency>
+    </dependencies>
+</project>`
+
+	if err := os.WriteFile(pomFile, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to create temp file: %v", err)
+	}
+
+	updates := map[string]string{
+		"com.fasterxml.jackson.core:jackson-databind": "2.12.7.1",
+	}
+
+	updated, err := parser.Update(ctx, pomFile, updates)
+	if err != nil {
+		t.Fatalf("Update failed: %v", err)
+	}
+
+	if !strings.Contains(updated, "<version>2.12.7.1</version>") {
+		t.Error("Expected managed version to be bumped to '2.12.7.1'")
+	}
+
+	// The <dependencies> entry must stay intact, without an inline version
+	expectedDependency := `        <dependency>
+            <groupId>com.fasterxml.jackson.core</groupId>
+            <artifactId>jackson-databind</artifactId>
+            <scope>compile</scope>
+        </dependency>`
+	if !strings.Contains(updated, expectedDependency) {
+		t.Errorf("Expected <dependencies> entry to be unchanged, got:\n%s", updated)
+	}
+
+	if !parser.Validate(updated) {
+		t.Error("Expected updated content to be valid XML")
+	}
+}
+
+func TestMavenParser_Parse_NestedProperties(t *testing.T) {
+	ctx := context.Background()
+	parser := NewParser()
+
+	tmpDir := t.TempDir()
+	pomFile := filepath.Join(tmpDir, "pom.xml")
+
+	content := `<?xml version="1.0" encoding="UTF-8"?>
+<project xmlns="http://maven.apache.org/POM/4.0.0">
+    <groupId>com.example</groupId>
+    <artifactId>app</artifactId>
+    <version>3.1.0</version>
+
+    <properties>
+        <jackson.version>2.12.0</jackson.version>
+        <jackson.databind.version>${jackson.version}</jackson.databind.version>
+    </properties>
+
+    <dependencies>
+        <dependency>
+            <groupId>com.fasterxml.jackson.core</groupId>
+            <artifactId>jackson-databind</artifactId>
+            <version>${jackson.databind.version}</version>
+        </dependency>
+        <dependency>
+            <groupId>com.example</groupId>
+            <artifactId>app-core</artifactId>
+            <version>${project.version}</version>
+        </dependency>
+    </dependencies>
+</project>`
+
+	if err := os.WriteFile(pomFile, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to create temp file: %v", err)
+	}
+
+	packages, err := parser.Parse(ctx, pomFile)
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+
+	if len(packages) != 2 {
+		t.Fatalf("Expected 2 packages, got %d", len(packages))
+	}
+	if packages[0].Version != "2.12.0" {
+		t.Errorf("Expected two-level property chain to resolve to '2.12.0', got '%s'", packages[0].Version)
+	}
+	if packages[1].Version != "3.1.0" {
+		t.Errorf("Expected ${project.version} to resolve to '3.1.0', got '%s'", packages[1].Version)
+	}
+}
+
+func TestMavenParser_Parse_ProjectVersionFromParent(t *testing.T) {
+	ctx := context.Background()
+	parser := NewParser()
+
+	tmpDir := t.TempDir()
+	pomFile := filepath.Join(tmpDir, "pom.xml")
+
+	content := `<?xml version="1.0" encoding="UTF-8"?>
+<project xmlns="http://maven.apache.org/POM/4.0.0">
+    <parent>
+        <groupId>com.example</groupId>
+        <artifactId>parent</artifactId>
+        <version>2.0.0</version>
+    </parent>
+    <artifactId>app</artifactId>
+
+    <dependencies>
+        <dependency>
+            <groupId>${project.groupId}</groupId>
+            <artifactId>app-core</artifactId>
+            <version>${project.version}</version>
+        </dependency>
+    </dependencies>
+</project>`
+
+	if err := os.WriteFile(pomFile, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to create temp file: %v", err)
+	}
+
+	packages, err := parser.Parse(ctx, pomFile)
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+
+	if len(packages) != 1 || packages[0].Version != "2.0.0" {
+		t.Fatalf("Expected version inherited from parent '2.0.0', got %+v", packages)
+	}
+	if packages[0].Name != "com.example:app-core" {
+		t.Errorf("Expected ${project.groupId} to resolve in the package name, got '%s'", packages[0].Name)
+	}
+}
+
+func TestMavenParser_Update_NestedProperty(t *testing.T) {
+	ctx := context.Background()
+	parser := NewParser()
+
+	tmpDir := t.TempDir()
+	pomFile := filepath.Join(tmpDir, "pom.xml")
+
+	content := `<?xml version="1.0" encoding="UTF-8"?>
+<project xmlns="http://maven.apache.org/POM/4.0.0">
+    <properties>
+        <jackson.version>2.12.0</jackson.version>
+        <jackson.databind.version>${jackson.version}</jackson.databind.version>
+    </properties>
+    <dependencies>
+        <dependency>
+            <groupId>com.fasterxml.jackson.core</groupId>
+            <artifactId>jackson-databind</artifactId>
+            <version>${jackson.databind.version}</version>
+        </dependency>
+    </dependencies>
+</project>`
+
+	if err := os.WriteFile(pomFile, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to create temp file: %v", err)
+	}
+
+	updates := map[string]string{
+		"com.fasterxml.jackson.core:jackson-databind": "2.12.7.1",
+	}
+
+	updated, err := parser.Update(ctx, pomFile, updates)
+	if err != nil {
+		t.Fatalf("Update failed: %v", err)
+	}
+
+	if !strings.Contains(updated, "<jackson.version>2.12.7.1</jackson.version>") {
+		t.Error("Expected the property holding the literal version to be updated")
+	}
+	if !strings.Contains(updated, "<jackson.databind.version>${jackson.version}</jackson.databind.version>") {
+		t.Error("Expected the chained property reference to be preserved")
+	}
+}
+
+func TestMavenParser_Update_OnlyIntendedDependency(t *testing.T) {
+	ctx := context.Background()
+	parser := NewParser()
+
+	tmpDir := t.TempDir()
+	pomFile := filepath.Join(tmpDir, "pom.xml")
+
+	content := `<?xml version="1.0" encoding="UTF-8"?>
+<project xmlns="http://maven.apache.org/POM/4.0.0">
+    <dependencies>
+        <dependency>
+            <groupId>commons-io</groupId>
+            <artifactId>commons-io</artifactId>
+            <version>2.6</version>
+        </dependency>
+        <dependency>
+            <groupId>org.example</groupId>
+            <artifactId>unrelated</artifactId>
+            <version>2.6</version>
+        </dependency>
+    </dependencies>
+
+    <profiles>
+        <profile>
+            <id>legacy</id>
+            <dependencies>
+                <dependency>
+                    <groupId>commons-io</groupId>
+                    <artifactId>commons-io</artifactId>
+                    <version>2.6</version>
+                </dependency>
+            </dependencies>
+        </profile>
+    </profiles>
+</project>`
+
+	if err := os.WriteFile(pomFile, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to create temp file: %v", err)
+	}
+
+	updates := map[string]string{
+		"commons-io:commons-io": "2.7",
+	}
+
+	updated, err := parser.Update(ctx, pomFile, updates)
+	if err != nil {
+		t.Fatalf("Update failed: %v", err)
+	}
+
+	// Only the first <version>2.6</version> (the parsed commons-io dependency) changes
+	expected := strings.Replace(content, "<version>2.6</version>", "<version>2.7</version>", 1)
+	if updated != expected {
+		t.Errorf("Expected only the parsed commons-io dependency to change, got:\n%s", updated)
+	}
+}
+
+func TestMavenParser_Update_CommentsAndReorderedChildren(t *testing.T) {
+	ctx := context.Background()
+	parser := NewParser()
+
+	tests := []struct {
+		name     string
+		content  string
+		expected string
+	}{
+		{
+			name: "commented-out version line",
+			content: `<project>
+    <dependencies>
+        <dependency>
+            <groupId>junit</groupId>
+            <!-- pinned for CI -->
+            <artifactId>junit</artifactId>
+            <!-- <version>4.11</version> -->
+            <version>4.12</version>
+        </dependency>
+    </dependencies>
+</project>`,
+			expected: `<project>
+    <dependencies>
+        <dependency>
+            <groupId>junit</groupId>
+            <!-- pinned for CI -->
+            <artifactId>junit</artifactId>
+            <!-- <version>4.11</version> -->
+            <version>4.13.2</version>
+        </dependency>
+    </dependencies>
+</project>`,
+		},
+		{
+			name: "version before artifactId",
+			content: `<project>
+    <dependencies>
+        <dependency>
+            <version>4.12</version>
+            <groupId>junit</groupId>
+            <scope>test</scope>
+            <artifactId>junit</artifactId>
+        </dependency>
+    </dependencies>
+</project>`,
+			expected: `<project>
+    <dependencies>
+        <dependency>
+            <version>4.13.2</version>
+            <groupId>junit</groupId>
+            <scope>test</scope>
+            <artifactId>junit</artifactId>
+        </dependency>
+    </dependencies>
+</project>`,
+		},
+		{
+			name: "whitespace inside version element",
+			content: `<project>
+    <dependencies>
+        <dependency>
+            <groupId>junit</groupId>
+            <artifactId>junit</artifactId>
+            <version>
+                4.12
+            </version>
+        </dependency>
+    </dependencies>
+</project>`,
+			expected: `<project>
+    <dependencies>
+        <dependency>
+            <groupId>junit</groupId>
+            <artifactId>junit</artifactId>
+            <version>
+                4.13.2
+            </version>
+        </dependency>
+    </dependencies>
+</project>`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pomFile := filepath.Join(t.TempDir(), "pom.xml")
+			if err := os.WriteFile(pomFile, []byte(tt.content), 0644); err != nil {
+				t.Fatalf("Failed to create temp file: %v", err)
+			}
+
+			updated, err := parser.Update(ctx, pomFile, map[string]string{"junit:junit": "4.13.2"})
+			if err != nil {
+				t.Fatalf("Update failed: %v", err)
+			}
+
+			if updated != tt.expected {
+				t.Errorf("Unexpected update result:\n%s", updated)
+			}
+		})
+	}
+}
+
+func TestMavenParser_Update_CommentInsideVersion(t *testing.T) {
+	ctx := context.Background()
+	parser := NewParser()
+
+	content := `<project>
+    <dependencies>
+        <dependency>
+            <groupId>junit</groupId>
+            <artifactId>junit</artifactId>
+            <version><!-- keep in sync with CI -->4.12</version>
+        </dependency>
+    </dependencies>
+</project>`
+
+	pomFile := filepath.Join(t.TempDir(), "pom.xml")
+	if err := os.WriteFile(pomFile, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to create temp file: %v", err)
+	}
+
+	updated, err := parser.Update(ctx, pomFile, map[string]string{"junit:junit": "4.13.2"})
+	if err != nil {
+		t.Fatalf("Update failed: %v", err)
+	}
+
+	if !strings.Contains(updated, "<version><!-- keep in sync with CI -->4.13.2</version>") {
+		t.Errorf("Expected comment inside <version> to be preserved, got:\n%s", updated)
+	}
+}
+
+func TestMavenParser_Update_Alias(t *testing.T) {
+	ctx := context.Background()
+	parser := NewParser()
+
+	tmpDir := t.TempDir()
+	pomFile := filepath.Join(tmpDir, "pom.xml")
+
+	content := `<?xml version="1.0" encoding="UTF-8"?>
+<project xmlns="http://maven.apache.org/POM/4.0.0">
+    <properties>
+        <log4j.version>2.14.1</log4j.version>
+    </properties>
+
+    <dependencyManagement>
+        <dependencies>
+            <dependency>
+                <groupId>com.fasterxml.jackson.core</groupId>
+                <artifactId>jackson-databind</artifactId>
+                <version>2.12.0</version>
+            </dependency>
+        </dependencies>
+    </dependencyManagement>
+
+    <dependencies>
+        <dependency>
+            <groupId>com.fasterxml.jackson.core</groupId>
+            <artifactId>jackson-databind</artifactId>
+        </dependency>
+        <dependency>
+            <groupId>org.apache.logging.log4j</groupId>
+            <artifactId>log4j-core</artifactId>
+            <version>${log4j.version}</version>
+        </dependency>
+    </dependencies>
+</project>`
+
+	if err := os.WriteFile(pomFile, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to create temp file: %v", err)
+	}
+
+	updates := map[string]string{
+		"com.fasterxml.jackson.core:jackson-databind": "io.root.jackson:jackson-databind:2.12.7.1",
+		"org.apache.logging.log4j:log4j-core":         "2.17.1",
+	}
+
+	updated, err := parser.Update(ctx, pomFile, updates)
+	if err != nil {
+		t.Fatalf("Update failed: %v", err)
+	}
+
+	// Both the managed entry and the dependency using it move to the alias
+	if strings.Contains(updated, "com.fasterxml.jackson.core") {
+		t.Errorf("Expected every jackson-databind coordinate to be replaced, got:\n%s", updated)
+	}
+	if n := strings.Count(updated, "<groupId>io.root.jackson</groupId>"); n != 2 {
+		t.Errorf("Expected 2 aliased groupIds, got %d:\n%s", n, updated)
+	}
+	if !strings.Contains(updated, "<version>2.12.7.1</version>") {
+		t.Error("Expected the managed version to be bumped to '2.12.7.1'")
+	}
+
+	// A plain version keeps the coordinate
+	if !strings.Contains(updated, "<groupId>org.apache.logging.log4j</groupId>") ||
+		!strings.Contains(updated, "<log4j.version>2.17.1</log4j.version>") {
+		t.Errorf("Expected log4j-core to keep its coordinate and get 2.17.1, got:\n%s", updated)
+	}
+
+	if !parser.Validate(updated) {
+		t.Error("Expected updated content to be valid XML")
+	}
+}
@@ -0,0 +1,96 @@
+package maven
+
+import (
+	"maps"
+	"slices"
+)
+
+// Profile represents a <profiles><profile> block
+type Profile struct {
+	ID                   string               `xml:"id"`
+	Activation           Activation           `xml:"activation"`
+	Properties           Properties           `xml:"properties"`
+	Dependencies         Dependencies         `xml:"dependencies"`
+	DependencyManagement DependencyManagement `xml:"dependencyManagement"`
+}
+
+// Activation represents the activation conditions of a profile. Only activeByDefault is
+// evaluated; profiles activated by JDK, OS, property or file conditions are read with WithProfiles.
+type Activation struct {
+	ActiveByDefault bool `xml:"activeByDefault"`
+}
+
+// WithProfiles reads the named profiles instead of the ones active by default, like mvn -P
+func WithProfiles(ids []string) ParserOption {
+	return func(p *MavenParser) {
+		p.profiles = ids
+	}
+}
+
+// activeProfiles returns the indexes of the project's profiles that are read, in document
+// order: the profiles named with WithProfiles, otherwise those marked activeByDefault
+func (p *MavenParser) activeProfiles(project Project) []int {
+	var active []int
+	for i, profile := range project.Profiles {
+		if len(p.profiles) > 0 {
+			if slices.Contains(p.profiles, profile.ID) {
+				active = append(active, i)
+			}
+		} else if profile.Activation.ActiveByDefault {
+			active = append(active, i)
+		}
+	}
+	return active
+}
+
+// applyProfiles returns the project with the properties, dependencies and managed dependencies
+// of its active profiles merged in, as Maven builds it. Profile properties override the
+// project's, and profile dependencies are tagged with the profile's id.
+func (p *MavenParser) applyProfiles(project Project) Project {
+	active := p.activeProfiles(project)
+	if len(active) == 0 {
+		return project
+	}
+
+	properties := maps.Clone(project.Properties.Properties)
+	if properties == nil {
+		properties = make(map[string]string)
+	}
+	dependencies := slices.Clone(project.Dependencies.Dependency)
+	managed := slices.Clone(project.DependencyManagement.Dependencies.Dependency)
+
+	for _, i := range active {
+		profile := project.Profiles[i]
+		maps.Copy(properties, profile.Properties.Properties)
+		for _, dep := range profile.Dependencies.Dependency {
+			dep.Profile = profile.ID
+			dependencies = append(dependencies, dep)
+		}
+		for _, dep := range profile.DependencyManagement.Dependencies.Dependency {
+			dep.Profile = profile.ID
+			managed = append(managed, dep)
+		}
+	}
+
+	project.Properties.Properties = properties
+	project.Dependencies.Dependency = dependencies
+	project.DependencyManagement.Dependencies.Dependency = managed
+	return project
+}
+
+// inActiveProfile reports whether an element in the given profile (-1 outside profiles) is read
+func inActiveProfile(profile int, active []int) bool {
+	return profile < 0 || slices.Contains(active, profile)
+}
+
+// property returns the location of a property's value, preferring the last active profile that
+// sets it, as Maven does
+func (l pomLocations) property(name string, active []int) (textRange, bool) {
+	for i := len(active) - 1; i >= 0; i-- {
+		if r, ok := l.profileProperties[active[i]][name]; ok {
+			return r, true
+		}
+	}
+	r, ok := l.properties[name]
+	return r, ok
+}
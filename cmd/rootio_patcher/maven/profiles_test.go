@@ -0,0 +1,124 @@
+package maven
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"rootio_patcher/cmd/rootio_patcher/common"
+)
+
+// copyProfiles copies the profiles fixture to a temp directory and returns its pom.xml and content
+func copyProfiles(t *testing.T) (string, string) {
+	t.Helper()
+
+	content, err := os.ReadFile(filepath.Join("testdata", "profiles", "pom.xml"))
+	if err != nil {
+		t.Fatalf("Failed to read fixture: %v", err)
+	}
+	pomFile := filepath.Join(t.TempDir(), "pom.xml")
+	if err := os.WriteFile(pomFile, content, 0644); err != nil {
+		t.Fatalf("Failed to copy fixture: %v", err)
+	}
+	return pomFile, string(content)
+}
+
+// profileVersions maps each parsed package to its version and profile
+func profileVersions(packages []common.PackageInfo) map[string][2]string {
+	versions := make(map[string][2]string, len(packages))
+	for _, pkg := range packages {
+		versions[pkg.Name] = [2]string{pkg.Version, pkg.Profile}
+	}
+	return versions
+}
+
+func TestMavenParser_Parse_ActiveByDefaultProfile(t *testing.T) {
+	pomFile, _ := copyProfiles(t)
+
+	packages, err := NewParser().Parse(context.Background(), pomFile)
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+
+	expected := map[string][2]string{
+		"org.apache.commons:commons-lang3":            {"3.12.0", ""},
+		"org.apache.logging.log4j:log4j-core":         {"2.14.1", "security"},
+		"com.fasterxml.jackson.core:jackson-databind": {"2.9.10", "security"},
+	}
+	if got := profileVersions(packages); !reflect.DeepEqual(got, expected) {
+		t.Errorf("Expected %v, got %v", expected, got)
+	}
+}
+
+func TestMavenParser_Parse_NamedProfile(t *testing.T) {
+	pomFile, _ := copyProfiles(t)
+
+	packages, err := NewParser(WithProfiles([]string{"legacy"})).Parse(context.Background(), pomFile)
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+
+	// Naming a profile deactivates the active-by-default one, so its property override is gone too
+	expected := map[string][2]string{
+		"org.apache.commons:commons-lang3":        {"3.12.0", ""},
+		"commons-collections:commons-collections": {"3.2.1", "legacy"},
+		"org.apache.logging.log4j:log4j-core":     {"2.8.2", "legacy"},
+	}
+	if got := profileVersions(packages); !reflect.DeepEqual(got, expected) {
+		t.Errorf("Expected %v, got %v", expected, got)
+	}
+}
+
+func TestMavenParser_Update_Profiles(t *testing.T) {
+	updates := map[string]string{
+		"org.apache.logging.log4j:log4j-core":         "2.17.1",
+		"com.fasterxml.jackson.core:jackson-databind": "2.9.10.8",
+		"commons-collections:commons-collections":     "3.2.2",
+	}
+
+	tests := []struct {
+		name     string
+		profiles []string
+		replace  []string // pairs of old and new text expected in the updated POM
+	}{
+		{
+			name:     "active by default",
+			profiles: nil,
+			replace: []string{
+				"<log4j.version>2.14.1</log4j.version>", "<log4j.version>2.17.1</log4j.version>",
+				"<version>2.9.10</version>", "<version>2.9.10.8</version>",
+			},
+		},
+		{
+			name:     "named profile",
+			profiles: []string{"legacy"},
+			replace: []string{
+				"<log4j.version>2.8.2</log4j.version>", "<log4j.version>2.17.1</log4j.version>",
+				"<version>3.2.1</version>", "<version>3.2.2</version>",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pomFile, original := copyProfiles(t)
+			parser := NewParser(WithProfiles(tt.profiles))
+
+			updated, err := parser.Update(context.Background(), pomFile, updates)
+			if err != nil {
+				t.Fatalf("Update failed: %v", err)
+			}
+
+			expected := strings.NewReplacer(tt.replace...).Replace(original)
+			if updated != expected {
+				t.Errorf("Unexpected updated content:\n%s", updated)
+			}
+			if !parser.Validate(updated) {
+				t.Error("Expected updated content to be valid")
+			}
+		})
+	}
+}
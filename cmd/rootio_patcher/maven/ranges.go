@@ -0,0 +1,57 @@
+package maven
+
+import (
+	"fmt"
+	"strings"
+
+	"rootio_patcher/cmd/rootio_patcher/common"
+	"rootio_patcher/pkg/rootio"
+)
+
+// isVersionRange reports whether a version is a Maven version range, such as [1.0,2.0),
+// (,1.5] or [1.0,1.2],[1.5,)
+func isVersionRange(version string) bool {
+	return strings.HasPrefix(version, "[") || strings.HasPrefix(version, "(")
+}
+
+// rangeVersion returns the version a range pins, for the exact-match range [1.2.3]. Other
+// ranges resolve to whichever version the repository offers at build time.
+func rangeVersion(version string) (string, bool) {
+	inner, ok := strings.CutPrefix(version, "[")
+	if !ok {
+		return "", false
+	}
+	inner, ok = strings.CutSuffix(inner, "]")
+	if !ok || inner == "" || strings.ContainsAny(inner, ",[]()") {
+		return "", false
+	}
+	return strings.TrimSpace(inner), true
+}
+
+// packageVersion returns the version a declared version is analyzed as: the version itself,
+// the version an exact-match range pins, or the range unchanged when it can't be resolved
+func packageVersion(version string) string {
+	if pinned, ok := rangeVersion(version); ok {
+		return pinned
+	}
+	return version
+}
+
+// skipRanges separates the packages declared with a version range that doesn't pin one
+// version. Maven picks their version at build time, so there's no installed version to
+// analyze; updating one replaces the range with the patched version.
+func skipRanges(packages []common.PackageInfo) ([]common.PackageInfo, []rootio.SkippedPackage) {
+	var kept []common.PackageInfo
+	var skipped []rootio.SkippedPackage
+	for _, pkg := range packages {
+		if !isVersionRange(pkg.Version) {
+			kept = append(kept, pkg)
+			continue
+		}
+		skipped = append(skipped, rootio.SkippedPackage{
+			PackageName: pkg.Name,
+			Reason:      fmt.Sprintf("declared with the version range %s; pin a version to analyze it", pkg.Version),
+		})
+	}
+	return kept, skipped
+}
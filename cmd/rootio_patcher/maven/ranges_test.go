@@ -0,0 +1,123 @@
+package maven
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"rootio_patcher/cmd/rootio_patcher/common"
+	"rootio_patcher/pkg/rootio"
+)
+
+// rangesFixture is the pom declaring log4j-core and jackson-databind with exact-match ranges
+// and commons-io with an open range
+var rangesFixture = filepath.Join("testdata", "ranges", "pom.xml")
+
+func TestRangeVersion(t *testing.T) {
+	tests := []struct {
+		version string
+		isRange bool
+		pinned  string
+	}{
+		{"1.2.3", false, ""},
+		{"[1.2.3]", true, "1.2.3"},
+		{"[1.0,2.0)", true, ""},
+		{"(,1.5]", true, ""},
+		{"[1.0,1.2],[1.5,)", true, ""},
+		{"${log4j.version}", false, ""},
+	}
+	for _, tt := range tests {
+		if got := isVersionRange(tt.version); got != tt.isRange {
+			t.Errorf("isVersionRange(%q) = %v, want %v", tt.version, got, tt.isRange)
+		}
+		pinned, ok := rangeVersion(tt.version)
+		if pinned != tt.pinned || ok != (tt.pinned != "") {
+			t.Errorf("rangeVersion(%q) = %q, %v, want %q", tt.version, pinned, ok, tt.pinned)
+		}
+	}
+}
+
+func TestMavenParser_Parse_VersionRanges(t *testing.T) {
+	packages, err := NewParser().Parse(context.Background(), rangesFixture)
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+
+	versions := make(map[string][2]string, len(packages))
+	for _, pkg := range packages {
+		versions[pkg.Name] = [2]string{pkg.Version, pkg.VersionConstraint}
+	}
+	expected := map[string][2]string{
+		"org.apache.logging.log4j:log4j-core":         {"2.14.1", "[2.14.1]"},
+		"commons-io:commons-io":                       {"[2.6,3.0)", "[2.6,3.0)"},
+		"com.fasterxml.jackson.core:jackson-databind": {"2.9.10", "[2.9.10]"},
+		"junit:junit":                                 {"4.12", "4.12"},
+	}
+	if !reflect.DeepEqual(versions, expected) {
+		t.Errorf("Expected %v, got %v", expected, versions)
+	}
+}
+
+func TestMavenApp_Run_VersionRanges(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	content, err := os.ReadFile(rangesFixture)
+	if err != nil {
+		t.Fatalf("Failed to read fixture: %v", err)
+	}
+	pomFile := filepath.Join(t.TempDir(), "pom.xml")
+	if err := os.WriteFile(pomFile, content, 0644); err != nil {
+		t.Fatalf("Failed to copy fixture: %v", err)
+	}
+
+	var analyzed []rootio.Package
+	mockAPIClient := &MockAPIClient{
+		AnalyzePackagesFunc: func(ctx context.Context, packages []rootio.Package) (*rootio.AnalyzePackagesResponse, error) {
+			analyzed = packages
+			return &rootio.AnalyzePackagesResponse{Patches: []rootio.PackagePatch{
+				{PackageName: "org.apache.logging.log4j:log4j-core", Version: "2.14.1",
+					Patch: rootio.PatchInfo{Name: "org.apache.logging.log4j:log4j-core", Version: "2.17.1"}},
+				{PackageName: "com.fasterxml.jackson.core:jackson-databind", Version: "2.9.10",
+					Patch: rootio.PatchInfo{Name: "com.fasterxml.jackson.core:jackson-databind", Version: "2.9.10.8"}},
+			}}, nil
+		},
+	}
+
+	app := NewAppWithServices("test-key", "https://api.root.io", pomFile, false, logger, NewParser(), mockAPIClient)
+	result, err := app.RunWithResult(context.Background())
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+
+	// Only concrete versions are sent to the API
+	for _, pkg := range analyzed {
+		if isVersionRange(pkg.Version) {
+			t.Errorf("Expected no version range to be analyzed, got %s %s", pkg.Name, pkg.Version)
+		}
+	}
+	if len(analyzed) != 3 {
+		t.Errorf("Expected 3 packages to be analyzed, got %+v", analyzed)
+	}
+	expectedSkipped := []common.SkippedResult{
+		{PackageName: "commons-io:commons-io", Reason: "declared with the version range [2.6,3.0); pin a version to analyze it"},
+	}
+	if !reflect.DeepEqual(result.Skipped, expectedSkipped) {
+		t.Errorf("Expected skipped %+v, got %+v", expectedSkipped, result.Skipped)
+	}
+
+	// The exact-match ranges are replaced by the patched versions; the open range is left alone
+	updated, err := os.ReadFile(pomFile)
+	if err != nil {
+		t.Fatalf("Failed to read pom.xml: %v", err)
+	}
+	want := strings.Replace(string(content), "<version>[2.14.1]</version>", "<version>2.17.1</version>", 1)
+	want = strings.Replace(want, "<jackson.version>[2.9.10]</jackson.version>", "<jackson.version>2.9.10.8</jackson.version>", 1)
+	if string(updated) != want {
+		t.Errorf("Unexpected pom.xml:\n%s", updated)
+	}
+}
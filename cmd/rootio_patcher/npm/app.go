@@ -3,10 +3,15 @@ package npm
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"io/fs"
 	"log/slog"
 	"os"
 	"path/filepath"
+	"slices"
+	"sort"
 	"strings"
 
 	"rootio_patcher/cmd/rootio_patcher/common"
@@ -19,14 +24,25 @@ type App struct {
 	apiURL         string
 	packageManager string
 	lockFilePath   string
+	packageJSON    string
+	yarnBerry      bool
 	dryRun         bool
 	logger         *slog.Logger
 	parser         common.Parser
 	apiClient      common.APIClient
+	reporter       *common.Reporter
+	options        common.Options
+	out            io.Writer
+
+	// scoped maps packages whose overrides are nested under their parents to those parents
+	scoped map[string][]string
+	// required holds the patched packages that other packages depend on too
+	required map[string]bool
+	result   *common.RunResult
 }
 
 // NewApp creates a new npm application instance
-func NewApp(apiKey, apiURL, packageManager string, dryRun bool, logger *slog.Logger) *App {
+func NewApp(apiKey, apiURL, packageManager string, dryRun bool, logger *slog.Logger, opts ...common.Option) *App {
 	return NewAppWithServices(
 		apiKey,
 		apiURL,
@@ -34,7 +50,8 @@ func NewApp(apiKey, apiURL, packageManager string, dryRun bool, logger *slog.Log
 		dryRun,
 		logger,
 		NewParser(),
-		rootio.NewClient(apiURL, apiKey),
+		common.NewAPIClient(common.EcosystemNpm, apiURL, apiKey, logger, opts...),
+		opts...,
 	)
 }
 
@@ -46,6 +63,7 @@ func NewAppWithServices(
 	logger *slog.Logger,
 	parser common.Parser,
 	apiClient common.APIClient,
+	opts ...common.Option,
 ) *App {
 	var packageManager string
 	var lockFilePath string
@@ -54,15 +72,7 @@ func NewAppWithServices(
 	if filepath.IsAbs(packageManagerOrPath) || strings.Contains(packageManagerOrPath, string(filepath.Separator)) {
 		// It's a file path (for testing)
 		lockFilePath = packageManagerOrPath
-		// Infer package manager from file extension
-		switch {
-		case strings.HasSuffix(lockFilePath, "yarn.lock"):
-			packageManager = "yarn"
-		case strings.HasSuffix(lockFilePath, "pnpm-lock.yaml"):
-			packageManager = "pnpm"
-		default:
-			packageManager = "npm"
-		}
+		packageManager = PackageManagerForLockFile(lockFilePath)
 	} else {
 		// It's a package manager name
 		packageManager = packageManagerOrPath
@@ -79,6 +89,10 @@ func NewAppWithServices(
 		}
 	}
 
+	options := common.NewOptions(opts...)
+	reporter := common.NewEcosystemReporter(common.EcosystemNpm, apiURL, logger,
+		common.WithPackageManager(packageManager), common.WithWriter(options.Output()))
+
 	return &App{
 		apiKey:         apiKey,
 		apiURL:         apiURL,
@@ -88,7 +102,146 @@ func NewAppWithServices(
 		logger:         logger,
 		parser:         parser,
 		apiClient:      apiClient,
+		reporter:       reporter,
+		options:        options,
+		out:            options.Output(),
+	}
+}
+
+// PackageManagerForLockFile infers the package manager (npm, yarn or pnpm) from a lock file name
+func PackageManagerForLockFile(lockFilePath string) string {
+	switch {
+	case strings.HasSuffix(lockFilePath, "yarn.lock"):
+		return "yarn"
+	case strings.HasSuffix(lockFilePath, "pnpm-lock.yaml"):
+		return "pnpm"
+	default:
+		return "npm"
+	}
+}
+
+// lockFiles lists the lock file each package manager writes
+var lockFiles = []struct{ packageManager, name string }{
+	{"npm", "package-lock.json"},
+	{"yarn", "yarn.lock"},
+	{"pnpm", "pnpm-lock.yaml"},
+}
+
+// LockFileMismatchError reports that the selected package manager's lock file is missing while
+// lock files of other package managers are present
+type LockFileMismatchError struct {
+	LockFile       string   // Lock file of the selected package manager
+	PackageManager string   // Selected package manager
+	Found          []string // Lock files present instead
+	Suggested      []string // Package managers writing the lock files found
+}
+
+func (e *LockFileMismatchError) Error() string {
+	flags := make([]string, len(e.Suggested))
+	for i, packageManager := range e.Suggested {
+		flags[i] = "--package-manager=" + packageManager
+	}
+	return fmt.Sprintf("lock file not found: %s (package manager: %s), but %s is present; pass %s or --package-manager=auto",
+		e.LockFile, e.PackageManager, strings.Join(e.Found, " and "), strings.Join(flags, " or "))
+}
+
+// otherLockFiles returns the lock files in dir written by package managers other than
+// packageManager, along with those package managers
+func otherLockFiles(dir, packageManager string) (found, packageManagers []string) {
+	for _, lockFile := range lockFiles {
+		if lockFile.packageManager == packageManager {
+			continue
+		}
+		if _, err := os.Stat(filepath.Join(dir, lockFile.name)); err == nil {
+			found = append(found, lockFile.name)
+			packageManagers = append(packageManagers, lockFile.packageManager)
+		}
+	}
+	return found, packageManagers
+}
+
+// DetectPackageManager picks the package manager from the packageManager field (Corepack) of
+// packageJSON's workspace root, or else from the lock file next to it. Without the field it
+// fails when there's no lock file, and when there are several, since any of them may be stale.
+func DetectPackageManager(packageJSON string) (string, error) {
+	if _, err := os.Stat(packageJSON); err == nil {
+		root, err := FindWorkspaceRoot(packageJSON)
+		if err != nil {
+			return "", fmt.Errorf("failed to find workspace root: %w", err)
+		}
+		packageJSON = root
+	}
+	dir := filepath.Dir(packageJSON)
+
+	// A project pinned to a package manager uses it whichever lock files are around
+	declared, err := declaredPackageManager(filepath.Join(dir, "package.json"))
+	if err != nil {
+		return "", err
+	}
+	if declared != "" {
+		return declared, nil
+	}
+
+	var packageManager string
+	var names, found []string
+	for _, lockFile := range lockFiles {
+		names = append(names, lockFile.name)
+		if _, err := os.Stat(filepath.Join(dir, lockFile.name)); err == nil {
+			packageManager = lockFile.packageManager
+			found = append(found, lockFile.name)
+		}
+	}
+
+	switch len(found) {
+	case 0:
+		return "", fmt.Errorf("no lock file found in %s (looked for %s); run an install first or pass --package-manager",
+			dir, strings.Join(names, ", "))
+	case 1:
+		return packageManager, nil
+	default:
+		return "", fmt.Errorf("several lock files found in %s (%s); pass --package-manager to choose one, "+
+			"or set the packageManager field in package.json", dir, strings.Join(found, ", "))
+	}
+}
+
+// declaredPackageManager returns the package manager named by the packageManager field of a
+// package.json, such as "pnpm@8.0.0", or "" when the file or field is missing or names a
+// package manager other than npm, yarn and pnpm
+func declaredPackageManager(packageJSON string) (string, error) {
+	content, err := os.ReadFile(packageJSON)
+	if errors.Is(err, fs.ErrNotExist) {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to read %s: %w", packageJSON, err)
+	}
+
+	var manifest struct {
+		PackageManager string `json:"packageManager"`
+	}
+	if err := json.Unmarshal(content, &manifest); err != nil {
+		return "", fmt.Errorf("failed to parse %s: %w", packageJSON, err)
 	}
+
+	name, _, _ := strings.Cut(manifest.PackageManager, "@")
+	for _, lockFile := range lockFiles {
+		if lockFile.packageManager == name {
+			return name, nil
+		}
+	}
+	return "", nil
+}
+
+// Result returns the structured result of the last run
+func (a *App) Result() *common.RunResult {
+	return a.result
+}
+
+// RunWithResult runs the npm remediation workflow and returns its structured result
+func (a *App) RunWithResult(ctx context.Context) (*common.RunResult, error) {
+	err := a.Run(ctx)
+	a.result.SetError(err)
+	return a.result, err
 }
 
 // Run executes the npm remediation workflow
@@ -97,11 +250,37 @@ func (a *App) Run(ctx context.Context) error {
 		slog.String("package_manager", a.packageManager),
 		slog.String("lock_file", a.lockFilePath),
 		slog.Bool("dry_run", a.dryRun))
+	a.result = common.NewRunResult(common.EcosystemNpm, a.lockFilePath, a.dryRun)
 
-	// 1. Check if lock file exists - crash if not found
+	// Overrides only take effect in the workspace root, which also holds the lock file
+	if err := a.resolvePackageJSON(ctx); err != nil {
+		return err
+	}
+	a.result.File = a.lockFilePath
+
+	// 1. Check if lock file exists - crash if not found, naming another package manager's lock file if there is one
 	if _, err := os.Stat(a.lockFilePath); err != nil {
+		if found, managers := otherLockFiles(filepath.Dir(a.lockFilePath), a.packageManager); len(found) > 0 {
+			return &LockFileMismatchError{
+				LockFile:       a.lockFilePath,
+				PackageManager: a.packageManager,
+				Found:          found,
+				Suggested:      managers,
+			}
+		}
 		return fmt.Errorf("lock file not found: %s (package manager: %s)", a.lockFilePath, a.packageManager)
 	}
+	a.yarnBerry = isYarnBerryLock(a.lockFilePath)
+
+	// Only package-lock.json can be rewritten; fail before anything is modified
+	if a.options.UpdateLockfile && !strings.HasSuffix(a.lockFilePath, "package-lock.json") {
+		return fmt.Errorf("--update-lockfile only supports package-lock.json, not %s", filepath.Base(a.lockFilePath))
+	}
+
+	// Overrides are written to package.json, so fail before analyzing anything without one
+	if _, err := os.Stat(a.packageJSON); err != nil {
+		return fmt.Errorf("package.json not found: %s (lock file: %s)", a.packageJSON, a.lockFilePath)
+	}
 
 	// 2. Parse lock file
 	a.logger.DebugContext(ctx, "Parsing lock file", slog.String("file", a.lockFilePath))
@@ -110,23 +289,42 @@ func (a *App) Run(ctx context.Context) error {
 		return fmt.Errorf("failed to parse %s: %w", a.lockFilePath, err)
 	}
 	a.logger.DebugContext(ctx, "Parsed packages", slog.Int("count", len(packages)))
+	a.options.Progress.Printf("Found %d packages in %s", len(packages), a.lockFilePath)
+	a.result.PackagesFound = len(packages)
+	installed := packages
+
+	// Leave devDependencies out of production-only scans
+	if a.options.SkipDev {
+		packages, a.result.DevSkipped = common.FilterDev(packages)
+		if a.result.DevSkipped > 0 {
+			fmt.Fprintf(a.out, "\nSkipped %d dev dependencies (--skip-dev)\n", a.result.DevSkipped)
+		}
+	}
 
 	if len(packages) == 0 {
-		fmt.Printf("\nNo packages found in %s\n", a.lockFilePath)
+		fmt.Fprintf(a.out, "\nNo packages found in %s\n", a.lockFilePath)
 		return nil
 	}
 
-	// 3. Convert to SDK format
-	sdkPackages := make([]rootio.Package, len(packages))
-	for i, pkg := range packages {
-		sdkPackages[i] = rootio.Package{
-			Name:    pkg.Name,
-			Version: pkg.Version,
+	// 3. Convert to SDK format; the same version installed at several paths is analyzed once,
+	// as a direct dependency if any copy is direct and as dev-only if every copy is
+	var sdkPackages []rootio.Package
+	seen := make(map[string]int)
+	for _, pkg := range packages {
+		key := pkg.Name + "@" + pkg.Version
+		if i, ok := seen[key]; ok {
+			sdkPackages[i].Direct = sdkPackages[i].Direct || pkg.Direct
+			sdkPackages[i].Dev = sdkPackages[i].Dev && pkg.Dev
+			continue
 		}
+		seen[key] = len(sdkPackages)
+
+		sdkPackages = append(sdkPackages, pkg.SDKPackage())
 	}
 
 	// 4. Call backend API to analyze vulnerabilities
 	a.logger.DebugContext(ctx, "Analyzing packages for vulnerabilities")
+	a.options.Progress.Printf("Analyzing %d packages for vulnerabilities...", len(sdkPackages))
 	response, err := a.apiClient.AnalyzePackages(ctx, sdkPackages)
 	if err != nil {
 		return fmt.Errorf("failed to analyze packages: %w", err)
@@ -137,73 +335,356 @@ func (a *App) Run(ctx context.Context) error {
 		slog.Int("patches_available", len(response.Patches)),
 		slog.Int("packages_skipped", len(response.Skipped)))
 
+	// Drop invalid names, patches without a version to install, patches left out by
+	// --min-severity, --only, --exclude and --cve, and patches that aren't upgrades
+	patches, filterSkipped := common.FilterPatches(common.EcosystemNpm, response.Patches, a.useAlias(), a.options)
+	response.Patches = patches
+	response.Skipped = append(response.Skipped, filterSkipped...)
+	common.WriteCVEMatches(a.out, response.Patches, a.options.CVEs)
+
+	// An aliased package already resolves to another package; overriding it would alias the alias
+	patches, aliasSkipped := skipAliased(response.Patches, packages)
+	response.Patches = patches
+	response.Skipped = append(response.Skipped, aliasSkipped...)
+
+	// Overrides outside a dependent's peerDependencies are rejected by npm install
+	patches, peerSkipped := a.checkPeers(ctx, response.Patches)
+	response.Patches = patches
+	response.Skipped = append(response.Skipped, peerSkipped...)
+	a.result.AddSkipped(response.Skipped)
+	a.reporter.ReportSkipped(response.Skipped)
+
 	if len(response.Patches) == 0 {
-		fmt.Println("\nNo patches needed - all packages are up to date!")
+		fmt.Fprintln(a.out, "\nNo patches needed - all packages are up to date!")
 		return nil
 	}
 
+	// Only npm's overrides can target a package under a parent; package-lock.json records them
+	if a.packageManager == "npm" {
+		a.scoped = overrideParents(response.Patches, installed)
+		a.required = requiredPackages(response.Patches, installed)
+	}
+
 	// 6. Execute or dry-run patches
 	if a.dryRun {
 		a.logger.DebugContext(ctx, "DRY-RUN MODE: No changes will be made")
-		a.reportDryRun(response.Patches)
+		a.options.Plan.Add(common.EcosystemNpm, a.lockFilePath, a.useAlias(), sdkPackages, response.Patches)
+		a.result.AddPatches(response.Patches, a.useAlias(), common.PatchStatusDryRun)
+		// The overrides are still listed when package.json can't be read
+		diff, err := a.proposedDiff(ctx, response.Patches)
+		if err != nil {
+			a.logger.WarnContext(ctx, "Failed to compute package.json changes", slog.String("error", err.Error()))
+		}
+		a.reporter.ReportDryRunWithDiff(response.Patches, a.useAlias(), diff)
 		return nil
 	}
 
 	// 7. Apply patches by updating package.json
-	fmt.Printf("\nApplying %d patches to package.json...\n\n", len(response.Patches))
+	// Ask before changing anything, unless --yes was given
+	if err := a.options.Confirm.Confirm(len(response.Patches), a.packageJSON); err != nil {
+		a.result.AddPatches(response.Patches, a.useAlias(), common.PatchStatusNotApplied)
+		return err
+	}
+
+	fmt.Fprintf(a.out, "\nApplying %d patches to %s...\n\n", len(response.Patches), a.packageJSON)
+	a.result.AddPatches(response.Patches, a.useAlias(), common.PatchStatusPending)
 	if err := a.applyPatches(ctx, response.Patches); err != nil {
+		a.result.SetAllPatchStatus(common.PatchStatusFailed, err)
 		return err
 	}
+	a.result.SetAllPatchStatus(common.PatchStatusApplied, nil)
 
-	fmt.Printf("\n✓ Successfully updated package.json with %d overrides!\n", len(response.Patches))
-	fmt.Println("\nNext steps:")
-	fmt.Println("  1. Review the changes in package.json")
-	fmt.Printf("  2. Run: %s install\n", a.packageManager)
-	fmt.Println("  3. Test your application")
+	a.reporter.ReportNextSteps(len(response.Patches))
+	a.reporter.ReportFixedCVEs(a.result.RecordFixedCVEs())
+
+	if a.options.Verify {
+		return a.verify(ctx)
+	}
 
 	return nil
 }
 
-// reportDryRun shows what would be changed without modifying files
-func (a *App) reportDryRun(patches []rootio.PackagePatch) {
-	fmt.Println("\n=== DRY-RUN MODE ===")
-	fmt.Printf("The following overrides would be added to package.json:\n\n")
+// verify parses the updated lock file again and confirms no patches remain for its versions.
+// Overrides only reach the lock file on the next install, so this needs UpdateLockfile.
+func (a *App) verify(ctx context.Context) error {
+	if !a.options.UpdateLockfile {
+		fmt.Fprintf(a.out, "\nSkipping verification: %s is only updated by the next %s install (use --update-lockfile)\n",
+			a.lockFilePath, a.packageManager)
+		return nil
+	}
+
+	packages, err := a.parser.Parse(ctx, a.lockFilePath)
+	if err != nil {
+		return fmt.Errorf("failed to parse %s for verification: %w", a.lockFilePath, err)
+	}
+
+	return common.VerifyPatches(ctx, common.EcosystemNpm, a.apiClient,
+		common.VerifyPackages(packages, a.options), a.options, a.result)
+}
+
+// resolvePackageJSON picks the package.json to update, moving up to the workspace root if needed.
+// A lock file given by name is looked up next to that package.json.
+func (a *App) resolvePackageJSON(ctx context.Context) error {
+	// Without --package-json, use the package.json next to the lock file
+	packageJSON := a.options.PackageJSONPath
+	if packageJSON == "" {
+		packageJSON = filepath.Join(filepath.Dir(a.lockFilePath), "package.json")
+	}
 
-	// Determine override field name based on package manager
-	overrideField := a.getOverrideField()
+	if _, err := os.Stat(packageJSON); err == nil {
+		root, err := FindWorkspaceRoot(packageJSON)
+		if err != nil {
+			return fmt.Errorf("failed to find workspace root: %w", err)
+		}
+		if root != packageJSON {
+			fmt.Fprintf(a.out, "\n%s is a workspace of %s; overrides will be added to the workspace root\n", packageJSON, root)
+			packageJSON = root
+		}
 
-	for i, patch := range patches {
-		fmt.Printf("%d. Package: %s\n", i+1, patch.PackageName)
-		fmt.Printf("   Current version: %s\n", patch.Version)
-		fmt.Printf("   Aliased package: npm:%s@%s\n", patch.PatchAlias.Name, patch.PatchAlias.Version)
-		if len(patch.CVEIDs) > 0 {
-			fmt.Printf("   CVEs Fixed: %v\n", patch.CVEIDs)
+		workspaces, err := ResolveWorkspaces(packageJSON)
+		if err != nil {
+			return fmt.Errorf("failed to resolve workspaces: %w", err)
+		}
+		if len(workspaces) > 0 {
+			a.logger.InfoContext(ctx, "Detected workspaces; overrides in the root apply to all of them",
+				slog.String("root", packageJSON),
+				slog.Int("workspaces", len(workspaces)))
 		}
-		fmt.Println()
 	}
 
-	// Show where overrides will be placed
-	if a.packageManager == "pnpm" {
-		fmt.Printf("These will be added to package.json under \"pnpm.overrides\" field\n\n")
-	} else {
-		fmt.Printf("These will be added to package.json under \"%s\" field\n\n", overrideField)
+	a.packageJSON = packageJSON
+	if !strings.ContainsRune(a.lockFilePath, filepath.Separator) {
+		a.lockFilePath = filepath.Join(filepath.Dir(packageJSON), a.lockFilePath)
 	}
+	return nil
+}
+
+// skipAliased drops patches for packages that are only installed through an npm alias,
+// such as @rootio/lodash installed as lodash after an earlier remediation
+func skipAliased(
+	patches []rootio.PackagePatch, packages []common.PackageInfo,
+) ([]rootio.PackagePatch, []rootio.SkippedPackage) {
+	aliases := make(map[string]string)
+	plain := make(map[string]bool)
+	for _, pkg := range packages {
+		if isAliased(pkg) {
+			aliases[pkg.Name] = extractPackageName(pkg.Path)
+		} else {
+			plain[pkg.Name] = true
+		}
+	}
+
+	var kept []rootio.PackagePatch
+	var skipped []rootio.SkippedPackage
+	for _, patch := range patches {
+		installName, ok := aliases[patch.PackageName]
+		if !ok || plain[patch.PackageName] {
+			kept = append(kept, patch)
+			continue
+		}
+		skipped = append(skipped, rootio.SkippedPackage{
+			PackageName: patch.PackageName,
+			Reason:      fmt.Sprintf("already installed through an npm alias (%s → npm:%s@%s)", installName, patch.PackageName, patch.Version),
+		})
+	}
+	return kept, skipped
+}
 
-	fmt.Println("To apply these patches, run with --dry-run=false")
-	fmt.Printf("Then run: %s install\n", a.packageManager)
+// useAlias reports whether overrides point at Root.io's aliased packages, the default
+func (a *App) useAlias() bool {
+	return a.options.Aliased(true)
 }
 
-// applyPatches updates package.json with overrides
-func (a *App) applyPatches(ctx context.Context, patches []rootio.PackagePatch) error {
-	// Build overrides map: package name -> aliased package version
-	// Always use aliased packages (e.g., express -> @rootio/express)
+// patchOverrides maps each package name to its override: the aliased package with useAlias
+// (e.g., express -> npm:@rootio/express@4.17.3), or else the patched version (express -> 4.17.3)
+func patchOverrides(patches []rootio.PackagePatch, useAlias bool) map[string]string {
 	overrides := make(map[string]string)
 	for _, patch := range patches {
-		// Use original package name as key, but aliased package name@version as value
-		overrideValue := fmt.Sprintf("npm:%s@%s", patch.PatchAlias.Name, patch.PatchAlias.Version)
-		overrides[patch.PackageName] = overrideValue
-		fmt.Printf("  - %s: %s → %s@%s\n", patch.PackageName, patch.Version, patch.PatchAlias.Name, patch.PatchAlias.Version)
+		overrides[patch.PackageName] = overrideSpec(patch, useAlias)
 	}
+	return overrides
+}
+
+// overrideSpec returns the override for one patch; a patch without an alias is a plain version
+func overrideSpec(patch rootio.PackagePatch, useAlias bool) string {
+	target := common.PatchTarget(patch, useAlias)
+	if common.ReplacesPackage(patch, target) {
+		return fmt.Sprintf("npm:%s@%s", target.Name, target.Version)
+	}
+	return target.Version
+}
+
+// overrideParents returns the parents to nest each patched package's override under. A flat
+// override replaces every copy of a package, so an override is only nested when other versions
+// of the package are installed too and every vulnerable copy is a transitive dependency with
+// known parents. Names patched at several versions stay flat, as overrides hold one per name.
+func overrideParents(patches []rootio.PackagePatch, packages []common.PackageInfo) map[string][]string {
+	patched := make(map[string]int)
+	for _, patch := range patches {
+		patched[patch.PackageName]++
+	}
+
+	scoped := make(map[string][]string)
+	for _, patch := range patches {
+		if patched[patch.PackageName] > 1 {
+			continue
+		}
+
+		var parents []string
+		otherVersions, transitive := false, true
+		for _, pkg := range packages {
+			if pkg.Name != patch.PackageName {
+				continue
+			}
+			if pkg.Version != patch.Version {
+				otherVersions = true
+				continue
+			}
+			if pkg.Direct || len(pkg.Parents) == 0 {
+				transitive = false
+				break
+			}
+			parents = append(parents, pkg.Parents...)
+		}
+
+		if otherVersions && transitive && len(parents) > 0 {
+			slices.Sort(parents)
+			scoped[patch.PackageName] = slices.Compact(parents)
+		}
+	}
+	return scoped
+}
+
+// requiredPackages returns the patched packages whose vulnerable copy is required by another
+// package, which npm resolves through overrides even when the project depends on it directly
+func requiredPackages(patches []rootio.PackagePatch, packages []common.PackageInfo) map[string]bool {
+	required := make(map[string]bool)
+	for _, patch := range patches {
+		for _, pkg := range packages {
+			if pkg.Name == patch.PackageName && pkg.Version == patch.Version && len(pkg.Parents) > 0 {
+				required[patch.PackageName] = true
+				break
+			}
+		}
+	}
+	return required
+}
+
+// directDependencies returns the names package.json declares in dependencies,
+// devDependencies and optionalDependencies
+func directDependencies(pkgJSON *orderedObject) map[string]bool {
+	direct := make(map[string]bool)
+	for _, field := range []string{"dependencies", "devDependencies", "optionalDependencies"} {
+		if deps, ok := pkgJSON.object(field); ok {
+			for _, name := range deps.keys {
+				direct[name] = true
+			}
+		}
+	}
+	return direct
+}
+
+// selfReferenced returns the overridden packages that are direct dependencies of package.json
+// and also required by other packages. npm rejects a plain override that conflicts with the
+// dependencies spec, so their override goes under "." instead.
+func (a *App) selfReferenced(pkgJSON *orderedObject, overrides map[string]string) map[string]bool {
+	direct := directDependencies(pkgJSON)
+	selfRef := make(map[string]bool)
+	for name := range overrides {
+		if direct[name] && a.required[name] {
+			selfRef[name] = true
+		}
+	}
+	return selfRef
+}
+
+// nestOverrides returns npm overrides with each scoped package nested under its parents
+// ({"foo": {"bar": "1.2.3"}}). A parent that is overridden itself, and each package in
+// selfRef, keeps its own override under ".", as npm requires.
+func nestOverrides(overrides map[string]string, scoped map[string][]string, selfRef map[string]bool) map[string]any {
+	nested := make(map[string]any, len(overrides))
+	for name, spec := range overrides {
+		if _, ok := scoped[name]; ok {
+			continue
+		}
+		if selfRef[name] {
+			nested[name] = map[string]any{".": spec}
+		} else {
+			nested[name] = spec
+		}
+	}
+
+	for name, parents := range scoped {
+		spec, ok := overrides[name]
+		if !ok {
+			continue
+		}
+		for _, parent := range parents {
+			children, ok := nested[parent].(map[string]any)
+			if !ok {
+				children = make(map[string]any)
+				if own, overridden := nested[parent].(string); overridden {
+					children["."] = own
+				}
+				nested[parent] = children
+			}
+			children[name] = spec
+		}
+	}
+	return nested
+}
+
+// proposedDiff renders the changes applyPatches would make to package.json, and to the
+// lock file with UpdateLockfile, as a unified diff
+func (a *App) proposedDiff(ctx context.Context, patches []rootio.PackagePatch) (string, error) {
+	overrides := patchOverrides(patches, a.useAlias())
+	original, updated, err := a.renderPackageJSON(overrides)
+	if err != nil {
+		return "", fmt.Errorf("failed to update package.json: %w", err)
+	}
+	diff := common.UnifiedDiff(a.packageJSON, string(original), string(updated))
+
+	if a.options.Registry != "" {
+		originalConfig, updatedConfig, err := a.renderRegistryConfig(patches)
+		if err != nil {
+			return "", err
+		}
+		diff += common.UnifiedDiff(a.registryConfigPath(), string(originalConfig), string(updatedConfig))
+	}
+
+	if a.options.UpdateLockfile {
+		originalLock, err := os.ReadFile(a.lockFilePath)
+		if err != nil {
+			return "", fmt.Errorf("failed to read %s: %w", a.lockFilePath, err)
+		}
+		updatedLock, err := a.parser.Update(ctx, a.lockFilePath, a.lockUpdates(overrides))
+		if err != nil {
+			return "", fmt.Errorf("failed to update %s: %w", a.lockFilePath, err)
+		}
+		diff += common.UnifiedDiff(a.lockFilePath, string(originalLock), updatedLock)
+	}
+
+	return diff, nil
+}
+
+// applyPatches updates package.json with overrides
+func (a *App) applyPatches(ctx context.Context, patches []rootio.PackagePatch) error {
+	overrides := patchOverrides(patches, a.useAlias())
+	for i, patch := range patches {
+		scope := ""
+		if parents := a.scoped[patch.PackageName]; len(parents) > 0 {
+			scope = fmt.Sprintf(" (under %s)", strings.Join(parents, ", "))
+		}
+		fmt.Fprintf(a.out, "[%d/%d] %s: %s → %s%s\n", i+1, len(patches),
+			patch.PackageName, patch.Version, overrides[patch.PackageName], scope)
+	}
+
+	// Hold the locks from reading the files until they're written, so concurrent runs don't lose updates
+	unlock, err := common.LockFiles(ctx, a.patchedFiles(), a.options.LockTimeout)
+	if err != nil {
+		return err
+	}
+	defer unlock()
 
 	// Update package.json with overrides
 	a.logger.DebugContext(ctx, "Updating package.json with overrides", slog.Int("count", len(overrides)))
@@ -211,71 +692,209 @@ func (a *App) applyPatches(ctx context.Context, patches []rootio.PackagePatch) e
 		return fmt.Errorf("failed to update package.json: %w", err)
 	}
 
+	// The overrides name Root.io's scoped packages, so point their scopes at the mirror
+	if a.options.Registry != "" {
+		a.logger.DebugContext(ctx, "Updating registry config",
+			slog.String("file", a.registryConfigPath()),
+			slog.String("registry", a.options.Registry))
+		if err := a.updateRegistryConfig(ctx, patches); err != nil {
+			return err
+		}
+	}
+
+	if a.options.UpdateLockfile {
+		a.logger.DebugContext(ctx, "Updating lock file", slog.String("file", a.lockFilePath))
+		if err := a.updateLockfile(ctx, a.lockUpdates(overrides)); err != nil {
+			return fmt.Errorf("failed to update %s: %w", a.lockFilePath, err)
+		}
+	}
+
 	return nil
 }
 
-// getOverrideField returns the override field name based on package manager
-func (a *App) getOverrideField() string {
-	switch a.packageManager {
-	case "yarn":
-		return "resolutions"
-	case "npm", "pnpm":
-		return "overrides"
-	default:
-		return "overrides"
+// lockUpdates returns the overrides to write to the lock file. The lock file is updated by
+// package name, which would also change the unaffected copies of a scoped package, so scoped
+// packages are left for the next npm install to resolve.
+func (a *App) lockUpdates(overrides map[string]string) map[string]string {
+	if len(a.scoped) == 0 {
+		return overrides
+	}
+	updates := make(map[string]string, len(overrides))
+	for name, spec := range overrides {
+		if _, ok := a.scoped[name]; !ok {
+			updates[name] = spec
+		}
 	}
+	return updates
+}
+
+// patchedFiles returns the files applyPatches modifies
+func (a *App) patchedFiles() []string {
+	files := []string{a.packageJSON}
+	if a.options.Registry != "" {
+		files = append(files, a.registryConfigPath())
+	}
+	if a.options.UpdateLockfile {
+		files = append(files, a.lockFilePath)
+	}
+	return files
+}
+
+// updateLockfile rewrites the patched packages in the lock file so installs don't re-resolve them
+func (a *App) updateLockfile(ctx context.Context, overrides map[string]string) error {
+	updatedContent, err := a.parser.Update(ctx, a.lockFilePath, overrides)
+	if err != nil {
+		return err
+	}
+
+	if !a.parser.Validate(updatedContent) {
+		return fmt.Errorf("updated lock file content is invalid")
+	}
+
+	if a.options.Backup {
+		backupPath, err := common.BackupFile(a.lockFilePath)
+		if err != nil {
+			return fmt.Errorf("failed to back up lock file: %w", err)
+		}
+		a.logger.InfoContext(ctx, "Backed up file before patching",
+			slog.String("file", a.lockFilePath),
+			slog.String("backup", backupPath))
+	}
+
+	if err := os.WriteFile(a.lockFilePath, []byte(updatedContent), 0644); err != nil {
+		return fmt.Errorf("failed to write lock file: %w", err)
+	}
+
+	return nil
 }
 
 // updatePackageJSON updates package.json with version overrides
 func (a *App) updatePackageJSON(overrides map[string]string) error {
-	packageJSONPath := "package.json"
+	packageJSONPath := a.packageJSON
+
+	_, updatedContent, err := a.renderPackageJSON(overrides)
+	if err != nil {
+		return err
+	}
+
+	if a.options.Backup {
+		backupPath, err := common.BackupFile(packageJSONPath)
+		if err != nil {
+			return fmt.Errorf("failed to back up package.json: %w", err)
+		}
+		a.logger.Info("Backed up file before patching",
+			slog.String("file", packageJSONPath),
+			slog.String("backup", backupPath))
+	}
+
+	// Write to file
+	if err := os.WriteFile(packageJSONPath, updatedContent, 0644); err != nil {
+		return fmt.Errorf("failed to write package.json: %w", err)
+	}
+
+	return nil
+}
+
+// renderPackageJSON returns the current package.json and its content with overrides added
+func (a *App) renderPackageJSON(overrides map[string]string) ([]byte, []byte, error) {
+	packageJSONPath := a.packageJSON
 
 	// Check if package.json exists
 	if _, err := os.Stat(packageJSONPath); err != nil {
-		return fmt.Errorf("package.json not found in current directory")
+		return nil, nil, fmt.Errorf("%s not found", packageJSONPath)
 	}
 
 	// Read package.json
 	content, err := os.ReadFile(packageJSONPath)
 	if err != nil {
-		return fmt.Errorf("failed to read package.json: %w", err)
+		return nil, nil, fmt.Errorf("failed to read package.json: %w", err)
 	}
 
-	// Parse JSON
-	var pkgJSON map[string]interface{}
+	// Parse JSON, keeping keys in file order so only the overrides change
+	var pkgJSON orderedObject
 	if err := json.Unmarshal(content, &pkgJSON); err != nil {
-		return fmt.Errorf("failed to parse package.json: %w", err)
+		return nil, nil, fmt.Errorf("failed to parse package.json: %w", err)
 	}
 
-	// Add or update overrides based on package manager
-	overrideField := a.getOverrideField()
-
+	// Add or update overrides based on package manager.
 	// pnpm requires nested structure: { "pnpm": { "overrides": { ... } } }
-	if a.packageManager == "pnpm" {
-		pnpmConfig, ok := pkgJSON["pnpm"].(map[string]interface{})
+	overrideField := common.OverrideField(a.packageManager)
+	if parent, child, nested := strings.Cut(overrideField, "."); nested {
+		parentConfig, ok := pkgJSON.object(parent)
 		if !ok {
-			pnpmConfig = make(map[string]interface{})
-			pkgJSON["pnpm"] = pnpmConfig
+			parentConfig = &orderedObject{}
+		}
+		if err := parentConfig.set(child, overrides, ""); err != nil {
+			return nil, nil, fmt.Errorf("failed to encode overrides: %w", err)
+		}
+		if err := pkgJSON.set(parent, parentConfig, ""); err != nil {
+			return nil, nil, fmt.Errorf("failed to encode %s: %w", parent, err)
+		}
+	} else if a.yarnBerry {
+		// Yarn Berry keeps its other resolutions (patch:, portal: and per-descriptor entries)
+		resolutions, ok := pkgJSON.object(overrideField)
+		if !ok {
+			resolutions = &orderedObject{}
+		}
+		if err := mergeBerryResolutions(resolutions, overrides); err != nil {
+			return nil, nil, fmt.Errorf("failed to encode overrides: %w", err)
+		}
+		if err := pkgJSON.set(overrideField, resolutions, ""); err != nil {
+			return nil, nil, fmt.Errorf("failed to encode %s: %w", overrideField, err)
+		}
+	} else if a.packageManager == "npm" {
+		// npm nests the overrides of packages scoped to their parents, and of direct
+		// dependencies other packages require too
+		nested := nestOverrides(overrides, a.scoped, a.selfReferenced(&pkgJSON, overrides))
+		if err := pkgJSON.set(overrideField, nested, ""); err != nil {
+			return nil, nil, fmt.Errorf("failed to encode overrides: %w", err)
 		}
-		pnpmConfig["overrides"] = overrides
 	} else {
-		// npm and yarn use top-level field
-		pkgJSON[overrideField] = overrides
+		// yarn uses top-level field
+		if err := pkgJSON.set(overrideField, overrides, ""); err != nil {
+			return nil, nil, fmt.Errorf("failed to encode overrides: %w", err)
+		}
 	}
 
-	// Write back to package.json with pretty formatting
-	updatedContent, err := json.MarshalIndent(pkgJSON, "", "  ")
+	// Keep the file's own indentation and end it with a newline (common convention)
+	updatedContent, err := formatJSON(pkgJSON, detectIndent(content))
 	if err != nil {
-		return fmt.Errorf("failed to marshal package.json: %w", err)
+		return nil, nil, fmt.Errorf("failed to marshal package.json: %w", err)
 	}
 
-	// Add newline at end of file (common convention)
-	updatedContent = append(updatedContent, '\n')
+	return content, updatedContent, nil
+}
 
-	// Write to file
-	if err := os.WriteFile(packageJSONPath, updatedContent, 0644); err != nil {
-		return fmt.Errorf("failed to write package.json: %w", err)
+// mergeBerryResolutions adds overrides to existing Yarn Berry resolutions. Berry also accepts
+// descriptor keys such as "lodash@npm:^4.17.0", which take precedence over a package-wide
+// resolution, so those entries are dropped for patched packages.
+func mergeBerryResolutions(resolutions *orderedObject, overrides map[string]string) error {
+	for _, key := range append([]string(nil), resolutions.keys...) {
+		if name := berryResolutionPackage(key); name != key && overrides[name] != "" {
+			resolutions.remove(key)
+		}
 	}
 
+	names := make([]string, 0, len(overrides))
+	for name := range overrides {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		if err := resolutions.set(name, overrides[name], ""); err != nil {
+			return err
+		}
+	}
 	return nil
 }
+
+// berryResolutionPackage returns the package name of a Berry resolution key, dropping the
+// range of descriptor keys ("lodash@npm:^4.17.0" → "lodash")
+func berryResolutionPackage(key string) string {
+	// Skip the leading "@" of scoped names
+	if at := strings.Index(key[min(1, len(key)):], "@") + 1; at > 0 {
+		return key[:at]
+	}
+	return key
+}
@@ -6,6 +6,8 @@ import (
 	"log/slog"
 	"os"
 	"path/filepath"
+	"reflect"
+	"strings"
 	"testing"
 
 	"rootio_patcher/cmd/rootio_patcher/common"
@@ -218,6 +220,97 @@ func TestNpmApp_UpdatePackageJSON_Yarn(t *testing.T) {
 	t.Log("Successfully updated package.json with yarn resolutions (aliased packages)")
 }
 
+// TestNpmApp_UpdatePackageJSON_YarnBerry tests that Berry projects keep their other resolutions
+func TestNpmApp_UpdatePackageJSON_YarnBerry(t *testing.T) {
+	ctx := context.Background()
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+
+	tmpDir := t.TempDir()
+	packageJSON := filepath.Join(tmpDir, "package.json")
+	initialContent := `{
+  "name": "test-project",
+  "dependencies": {
+    "express": "4.18.0"
+  },
+  "resolutions": {
+    "express@npm:^4.18.0": "npm:4.18.1",
+    "resolve": "patch:resolve@npm%3A1.22.8#./.yarn/patches/resolve.patch"
+  }
+}`
+	if err := os.WriteFile(packageJSON, []byte(initialContent), 0644); err != nil {
+		t.Fatalf("Failed to create package.json: %v", err)
+	}
+
+	lockFile := filepath.Join(tmpDir, "yarn.lock")
+	if err := os.WriteFile(lockFile, []byte("__metadata:\n  version: 8\n  cacheKey: 10c0\n"), 0644); err != nil {
+		t.Fatalf("Failed to create lock file: %v", err)
+	}
+
+	originalDir, _ := os.Getwd()
+	defer os.Chdir(originalDir)
+	os.Chdir(tmpDir)
+
+	mockAPIClient := &MockAPIClient{
+		AnalyzePackagesFunc: func(ctx context.Context, packages []rootio.Package) (*rootio.AnalyzePackagesResponse, error) {
+			return &rootio.AnalyzePackagesResponse{
+				Patches: []rootio.PackagePatch{
+					{
+						PackageName: "express",
+						Version:     "4.18.0",
+						Patch:       rootio.PatchInfo{Name: "express", Version: "4.18.2"},
+						PatchAlias:  rootio.PatchInfo{Name: "@rootio/express", Version: "4.18.2"},
+					},
+				},
+			}, nil
+		},
+	}
+
+	app := NewAppWithServices(
+		"test-key",
+		"https://api.root.io",
+		"yarn",
+		false, // not dry-run
+		logger,
+		&MockParser{
+			ParseFunc: func(ctx context.Context, filePath string) ([]common.PackageInfo, error) {
+				return []common.PackageInfo{
+					{Name: "express", Version: "4.18.0"},
+				}, nil
+			},
+		},
+		mockAPIClient,
+	)
+
+	if err := app.Run(ctx); err != nil {
+		t.Fatalf("App run failed: %v", err)
+	}
+
+	updatedContent, err := os.ReadFile(packageJSON)
+	if err != nil {
+		t.Fatalf("Failed to read updated package.json: %v", err)
+	}
+
+	var pkgJSON struct {
+		Resolutions map[string]string `json:"resolutions"`
+	}
+	if err := json.Unmarshal(updatedContent, &pkgJSON); err != nil {
+		t.Fatalf("Failed to parse updated package.json: %v", err)
+	}
+
+	expected := map[string]string{
+		"express": "npm:@rootio/express@4.18.2",
+		"resolve": "patch:resolve@npm%3A1.22.8#./.yarn/patches/resolve.patch",
+	}
+	if len(pkgJSON.Resolutions) != len(expected) {
+		t.Fatalf("Expected resolutions %v, got %v", expected, pkgJSON.Resolutions)
+	}
+	for name, value := range expected {
+		if pkgJSON.Resolutions[name] != value {
+			t.Errorf("Expected resolution %s=%q, got %q", name, value, pkgJSON.Resolutions[name])
+		}
+	}
+}
+
 // TestNpmApp_UpdatePackageJSON_Pnpm tests pnpm overrides format (nested under "pnpm")
 func TestNpmApp_UpdatePackageJSON_Pnpm(t *testing.T) {
 	ctx := context.Background()
@@ -323,3 +416,571 @@ func TestNpmApp_UpdatePackageJSON_Pnpm(t *testing.T) {
 
 	t.Log("Successfully updated package.json with pnpm overrides (nested under 'pnpm', aliased packages)")
 }
+
+// TestNpmApp_UpdatePackageJSON_Backup tests that package.json is backed up before being modified
+func TestNpmApp_UpdatePackageJSON_Backup(t *testing.T) {
+	ctx := context.Background()
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+
+	tmpDir := t.TempDir()
+
+	packageJSON := filepath.Join(tmpDir, "package.json")
+	initialContent := `{
+    "name": "test-project",
+    "dependencies": {"lodash": "4.17.20"}
+}
+`
+	if err := os.WriteFile(packageJSON, []byte(initialContent), 0644); err != nil {
+		t.Fatalf("Failed to create package.json: %v", err)
+	}
+
+	lockFile := filepath.Join(tmpDir, "package-lock.json")
+	lockContent := `{
+  "name": "test-project",
+  "lockfileVersion": 3,
+  "packages": {
+    "": {"dependencies": {"lodash": "4.17.20"}},
+    "node_modules/lodash": {"version": "4.17.20"}
+  }
+}`
+	if err := os.WriteFile(lockFile, []byte(lockContent), 0644); err != nil {
+		t.Fatalf("Failed to create lock file: %v", err)
+	}
+
+	originalDir, _ := os.Getwd()
+	defer os.Chdir(originalDir)
+	os.Chdir(tmpDir)
+
+	mockAPIClient := &MockAPIClient{
+		AnalyzePackagesFunc: func(ctx context.Context, packages []rootio.Package) (*rootio.AnalyzePackagesResponse, error) {
+			return &rootio.AnalyzePackagesResponse{
+				Patches: []rootio.PackagePatch{
+					{
+						PackageName: "lodash",
+						Version:     "4.17.20",
+						PatchAlias:  rootio.PatchInfo{Name: "@rootio/lodash", Version: "4.17.21"},
+					},
+				},
+			}, nil
+		},
+	}
+
+	app := NewAppWithServices(
+		"test-key",
+		"https://api.root.io",
+		"npm",
+		false, // not dry-run
+		logger,
+		NewParser(),
+		mockAPIClient,
+		common.WithBackup(true),
+	)
+
+	if err := app.Run(ctx); err != nil {
+		t.Fatalf("App run failed: %v", err)
+	}
+
+	backupContent, err := os.ReadFile(packageJSON + common.BackupSuffix)
+	if err != nil {
+		t.Fatalf("Expected backup file to exist: %v", err)
+	}
+	if string(backupContent) != initialContent {
+		t.Error("Backup should match the original package.json byte-for-byte")
+	}
+}
+
+// TestNpmApp_UpdateLockfile tests that --update-lockfile rewrites package-lock.json along with package.json
+func TestNpmApp_UpdateLockfile(t *testing.T) {
+	ctx := context.Background()
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+
+	tmpDir := t.TempDir()
+	packageJSON := filepath.Join(tmpDir, "package.json")
+	if err := os.WriteFile(packageJSON, []byte(`{"name": "app", "dependencies": {"lodash": "^4.17.20"}}`), 0644); err != nil {
+		t.Fatalf("Failed to create package.json: %v", err)
+	}
+	lockFile := filepath.Join(tmpDir, "package-lock.json")
+	if err := os.WriteFile(lockFile, []byte(packageLockV3), 0644); err != nil {
+		t.Fatalf("Failed to create lock file: %v", err)
+	}
+
+	mockAPIClient := &MockAPIClient{
+		AnalyzePackagesFunc: func(ctx context.Context, packages []rootio.Package) (*rootio.AnalyzePackagesResponse, error) {
+			return &rootio.AnalyzePackagesResponse{
+				Patches: []rootio.PackagePatch{
+					{
+						PackageName: "lodash",
+						Version:     "4.17.20",
+						Patch:       rootio.PatchInfo{Name: "lodash", Version: "4.17.21"},
+						PatchAlias:  rootio.PatchInfo{Name: "@rootio/lodash", Version: "4.17.21"},
+					},
+				},
+			}, nil
+		},
+	}
+
+	app := NewAppWithServices("test-key", "https://api.root.io", lockFile, false, logger,
+		NewParser(), mockAPIClient,
+		common.WithPackageJSON(packageJSON),
+		common.WithUpdateLockfile(true))
+
+	if err := app.Run(ctx); err != nil {
+		t.Fatalf("App run failed: %v", err)
+	}
+
+	content, err := os.ReadFile(lockFile)
+	if err != nil {
+		t.Fatalf("Failed to read lock file: %v", err)
+	}
+	var lockfile PackageLockJSON
+	if err := json.Unmarshal(content, &lockfile); err != nil {
+		t.Fatalf("Failed to parse updated lock file: %v", err)
+	}
+	entry := lockfile.Packages["node_modules/lodash"]
+	if entry.Version != "4.17.21" || entry.Integrity != "" {
+		t.Errorf("Expected lodash 4.17.21 without a stale integrity hash, got %+v", entry)
+	}
+
+	pkgJSON, err := os.ReadFile(packageJSON)
+	if err != nil {
+		t.Fatalf("Failed to read package.json: %v", err)
+	}
+	if !json.Valid(pkgJSON) || !strings.Contains(string(pkgJSON), "npm:@rootio/lodash@4.17.21") {
+		t.Errorf("Expected package.json overrides to be written too, got:\n%s", pkgJSON)
+	}
+}
+
+// TestNpmApp_UseAliasFalse tests that --use-alias=false overrides with plain patched versions
+func TestNpmApp_UseAliasFalse(t *testing.T) {
+	ctx := context.Background()
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+
+	tmpDir := t.TempDir()
+	packageJSON := filepath.Join(tmpDir, "package.json")
+	if err := os.WriteFile(packageJSON, []byte(`{"name": "app", "dependencies": {"lodash": "^4.17.20"}}`), 0644); err != nil {
+		t.Fatalf("Failed to create package.json: %v", err)
+	}
+	lockFile := filepath.Join(tmpDir, "package-lock.json")
+	if err := os.WriteFile(lockFile, []byte(packageLockV3), 0644); err != nil {
+		t.Fatalf("Failed to create lock file: %v", err)
+	}
+
+	mockAPIClient := &MockAPIClient{
+		AnalyzePackagesFunc: func(ctx context.Context, packages []rootio.Package) (*rootio.AnalyzePackagesResponse, error) {
+			return &rootio.AnalyzePackagesResponse{
+				Patches: []rootio.PackagePatch{
+					{
+						PackageName: "lodash",
+						Version:     "4.17.20",
+						Patch:       rootio.PatchInfo{Name: "lodash", Version: "4.17.21"},
+						PatchAlias:  rootio.PatchInfo{Name: "@rootio/lodash", Version: "4.17.21-root.1"},
+					},
+				},
+			}, nil
+		},
+	}
+
+	app := NewAppWithServices("test-key", "https://api.root.io", lockFile, false, logger,
+		NewParser(), mockAPIClient,
+		common.WithPackageJSON(packageJSON),
+		common.WithUpdateLockfile(true),
+		common.WithUseAlias(false))
+
+	if err := app.Run(ctx); err != nil {
+		t.Fatalf("App run failed: %v", err)
+	}
+
+	content, err := os.ReadFile(packageJSON)
+	if err != nil {
+		t.Fatalf("Failed to read package.json: %v", err)
+	}
+	var pkgJSON struct {
+		Overrides map[string]string `json:"overrides"`
+	}
+	if err := json.Unmarshal(content, &pkgJSON); err != nil {
+		t.Fatalf("Failed to parse updated package.json: %v", err)
+	}
+	if pkgJSON.Overrides["lodash"] != "4.17.21" {
+		t.Errorf("Expected a plain lodash override 4.17.21, got %q", pkgJSON.Overrides["lodash"])
+	}
+
+	lockContent, err := os.ReadFile(lockFile)
+	if err != nil {
+		t.Fatalf("Failed to read lock file: %v", err)
+	}
+	var lockfile PackageLockJSON
+	if err := json.Unmarshal(lockContent, &lockfile); err != nil {
+		t.Fatalf("Failed to parse updated lock file: %v", err)
+	}
+	if entry := lockfile.Packages["node_modules/lodash"]; entry.Version != "4.17.21" || entry.Name != "" {
+		t.Errorf("Expected lodash 4.17.21 under its own name, got %+v", entry)
+	}
+
+	if patched := app.Result().Patches[0]; patched.PatchedName != "lodash" || patched.PatchedVersion != "4.17.21" {
+		t.Errorf("Expected the result to report lodash 4.17.21, got %+v", patched)
+	}
+}
+
+// TestPatchOverrides_MissingAlias tests that patches without an alias fall back to plain versions
+func TestPatchOverrides_MissingAlias(t *testing.T) {
+	patches := []rootio.PackagePatch{
+		{PackageName: "lodash", Version: "4.17.20",
+			Patch:      rootio.PatchInfo{Name: "lodash", Version: "4.17.21"},
+			PatchAlias: rootio.PatchInfo{Name: "@rootio/lodash", Version: "4.17.21"}},
+		{PackageName: "minimist", Version: "1.2.5",
+			Patch: rootio.PatchInfo{Name: "minimist", Version: "1.2.6"}},
+	}
+
+	overrides := patchOverrides(patches, true)
+	if overrides["lodash"] != "npm:@rootio/lodash@4.17.21" {
+		t.Errorf("Expected the aliased lodash override, got %q", overrides["lodash"])
+	}
+	if overrides["minimist"] != "1.2.6" {
+		t.Errorf("Expected minimist without an alias to fall back to 1.2.6, got %q", overrides["minimist"])
+	}
+	if scopes := registryScopes(patches, false); len(scopes) != 0 {
+		t.Errorf("Expected no scope registries for plain versions, got %v", scopes)
+	}
+}
+
+// TestNpmApp_EmptyPatchAlias tests that a patch without an alias is overridden with its patched
+// version, and one without any patched version is skipped instead of written as npm:@
+func TestNpmApp_EmptyPatchAlias(t *testing.T) {
+	ctx := context.Background()
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+
+	tmpDir := t.TempDir()
+	packageJSON := filepath.Join(tmpDir, "package.json")
+	if err := os.WriteFile(packageJSON, []byte(`{"name": "app", "dependencies": {"lodash": "^4.17.20", "minimist": "^1.2.5"}}`), 0644); err != nil {
+		t.Fatalf("Failed to create package.json: %v", err)
+	}
+	lockFile := filepath.Join(tmpDir, "package-lock.json")
+	if err := os.WriteFile(lockFile, []byte(packageLockV3), 0644); err != nil {
+		t.Fatalf("Failed to create lock file: %v", err)
+	}
+
+	mockAPIClient := &MockAPIClient{
+		AnalyzePackagesFunc: func(ctx context.Context, packages []rootio.Package) (*rootio.AnalyzePackagesResponse, error) {
+			return &rootio.AnalyzePackagesResponse{
+				Patches: []rootio.PackagePatch{
+					{
+						PackageName: "lodash",
+						Version:     "4.17.20",
+						Patch:       rootio.PatchInfo{Name: "lodash", Version: "4.17.21"},
+					},
+					{PackageName: "minimist", Version: "1.2.5"},
+				},
+			}, nil
+		},
+	}
+
+	app := NewAppWithServices("test-key", "https://api.root.io", lockFile, false, logger,
+		NewParser(), mockAPIClient, common.WithPackageJSON(packageJSON))
+
+	if err := app.Run(ctx); err != nil {
+		t.Fatalf("App run failed: %v", err)
+	}
+
+	content, err := os.ReadFile(packageJSON)
+	if err != nil {
+		t.Fatalf("Failed to read package.json: %v", err)
+	}
+	if strings.Contains(string(content), "npm:@") {
+		t.Errorf("Expected no malformed alias overrides, got:\n%s", content)
+	}
+	var pkgJSON struct {
+		Overrides map[string]string `json:"overrides"`
+	}
+	if err := json.Unmarshal(content, &pkgJSON); err != nil {
+		t.Fatalf("Failed to parse updated package.json: %v", err)
+	}
+	if len(pkgJSON.Overrides) != 1 || pkgJSON.Overrides["lodash"] != "4.17.21" {
+		t.Errorf("Expected only a plain lodash override 4.17.21, got %v", pkgJSON.Overrides)
+	}
+
+	result := app.Result()
+	if len(result.Skipped) != 1 || result.Skipped[0].PackageName != "minimist" {
+		t.Errorf("Expected minimist to be skipped, got %+v", result.Skipped)
+	}
+}
+
+// TestNpmApp_UpdateLockfile_UnsupportedLockFile tests that yarn.lock is rejected before anything is modified
+func TestNpmApp_UpdateLockfile_UnsupportedLockFile(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+
+	tmpDir := t.TempDir()
+	lockFile := filepath.Join(tmpDir, "yarn.lock")
+	if err := os.WriteFile(lockFile, []byte("# yarn lockfile v1\n"), 0644); err != nil {
+		t.Fatalf("Failed to create lock file: %v", err)
+	}
+
+	app := NewAppWithServices("test-key", "https://api.root.io", lockFile, false, logger,
+		NewParser(), &MockAPIClient{},
+		common.WithPackageJSON(filepath.Join(tmpDir, "package.json")),
+		common.WithUpdateLockfile(true))
+
+	err := app.Run(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "only supports package-lock.json") {
+		t.Fatalf("Expected an unsupported lock file error, got: %v", err)
+	}
+}
+
+// TestNpmApp_Run_AliasedPackageIsIdempotent tests that a package aliased by an earlier run isn't aliased again
+func TestNpmApp_Run_AliasedPackageIsIdempotent(t *testing.T) {
+	ctx := context.Background()
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+
+	tmpDir := t.TempDir()
+	packageJSON := filepath.Join(tmpDir, "package.json")
+	if err := os.WriteFile(packageJSON, []byte(`{"name": "app", "dependencies": {"lodash": "^4.17.20"}}`), 0644); err != nil {
+		t.Fatalf("Failed to create package.json: %v", err)
+	}
+	lockFile := filepath.Join(tmpDir, "package-lock.json")
+	if err := os.WriteFile(lockFile, []byte(packageLockV3), 0644); err != nil {
+		t.Fatalf("Failed to create lock file: %v", err)
+	}
+
+	// Offers a patch for every analyzed lodash, including the Root.io package itself
+	var analyzed []rootio.Package
+	mockAPIClient := &MockAPIClient{
+		AnalyzePackagesFunc: func(ctx context.Context, packages []rootio.Package) (*rootio.AnalyzePackagesResponse, error) {
+			analyzed = packages
+			var patches []rootio.PackagePatch
+			for _, pkg := range packages {
+				if !strings.HasSuffix(pkg.Name, "lodash") {
+					continue
+				}
+				version := "4.17.22"
+				if pkg.Name == "@rootio/lodash" {
+					version = "4.17.23"
+				}
+				patches = append(patches, rootio.PackagePatch{
+					PackageName: pkg.Name,
+					Version:     pkg.Version,
+					PatchAlias:  rootio.PatchInfo{Name: "@rootio/lodash", Version: version},
+				})
+			}
+			return &rootio.AnalyzePackagesResponse{Patches: patches}, nil
+		},
+	}
+
+	for run := 1; run <= 2; run++ {
+		app := NewAppWithServices("test-key", "https://api.root.io", lockFile, false, logger,
+			NewParser(), mockAPIClient,
+			common.WithPackageJSON(packageJSON),
+			common.WithUpdateLockfile(true))
+		if err := app.Run(ctx); err != nil {
+			t.Fatalf("Run %d failed: %v", run, err)
+		}
+
+		if run == 2 {
+			skipped := app.Result().Skipped
+			if len(skipped) != 1 || skipped[0].PackageName != "@rootio/lodash" {
+				t.Errorf("Expected the aliased package to be skipped, got %+v", skipped)
+			}
+		}
+	}
+
+	for _, pkg := range analyzed {
+		if strings.HasPrefix(pkg.Version, "npm:") || pkg.Name == "lodash" {
+			t.Errorf("Expected the second run to analyze the aliased package, got %s@%s", pkg.Name, pkg.Version)
+		}
+	}
+
+	for _, file := range []string{packageJSON, lockFile} {
+		content, err := os.ReadFile(file)
+		if err != nil {
+			t.Fatalf("Failed to read %s: %v", file, err)
+		}
+		if strings.Contains(string(content), "@rootio/@rootio") || strings.Contains(string(content), "npm:npm:") {
+			t.Errorf("Expected no double alias in %s, got:\n%s", filepath.Base(file), content)
+		}
+	}
+
+	pkgJSON, _ := os.ReadFile(packageJSON)
+	if !strings.Contains(string(pkgJSON), `"lodash": "npm:@rootio/lodash@4.17.22"`) {
+		t.Errorf("Expected the first run's override to be kept, got:\n%s", pkgJSON)
+	}
+}
+
+// TestNpmApp_ScopedOverrides tests that a transitive package installed at other versions too is
+// overridden only under the parents of its vulnerable copy
+func TestNpmApp_ScopedOverrides(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+
+	tmpDir := t.TempDir()
+	writePackageJSON(t, tmpDir)
+	lockFile := filepath.Join(tmpDir, "package-lock.json")
+	if err := os.WriteFile(lockFile, []byte(scopedLockV3), 0644); err != nil {
+		t.Fatalf("Failed to create lock file: %v", err)
+	}
+
+	mockAPIClient := &MockAPIClient{
+		AnalyzePackagesFunc: func(ctx context.Context, packages []rootio.Package) (*rootio.AnalyzePackagesResponse, error) {
+			return &rootio.AnalyzePackagesResponse{
+				Patches: []rootio.PackagePatch{
+					{PackageName: "bar", Version: "1.0.0", Patch: rootio.PatchInfo{Name: "bar", Version: "1.0.1"}},
+					{PackageName: "foo", Version: "1.0.0", Patch: rootio.PatchInfo{Name: "foo", Version: "1.0.2"}},
+				},
+			}, nil
+		},
+	}
+
+	app := NewAppWithServices("test-key", "https://api.root.io", lockFile, false, logger,
+		NewParser(), mockAPIClient, common.WithUseAlias(false))
+	if err := app.Run(context.Background()); err != nil {
+		t.Fatalf("App run failed: %v", err)
+	}
+
+	content, err := os.ReadFile(filepath.Join(tmpDir, "package.json"))
+	if err != nil {
+		t.Fatalf("Failed to read package.json: %v", err)
+	}
+	var pkgJSON struct {
+		Overrides map[string]any `json:"overrides"`
+	}
+	if err := json.Unmarshal(content, &pkgJSON); err != nil {
+		t.Fatalf("Failed to parse updated package.json: %v", err)
+	}
+
+	// A flat bar override would also replace qux's bar 2.0.0; foo keeps its own override under "."
+	expected := map[string]any{
+		"baz": map[string]any{"bar": "1.0.1"},
+		"foo": map[string]any{".": "1.0.2", "bar": "1.0.1"},
+	}
+	if !reflect.DeepEqual(pkgJSON.Overrides, expected) {
+		t.Errorf("Expected overrides %v, got:\n%s", expected, content)
+	}
+}
+
+// TestNpmApp_DirectDependencyOverride tests that a patched package that is both a direct
+// dependency and required by another package is overridden with the "." form
+func TestNpmApp_DirectDependencyOverride(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+
+	tmpDir := t.TempDir()
+	packageJSON := filepath.Join(tmpDir, "package.json")
+	content := `{"name": "app", "dependencies": {"express": "^4.17.1", "lodash": "^4.17.20"}}`
+	if err := os.WriteFile(packageJSON, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to create package.json: %v", err)
+	}
+	lockFile := filepath.Join(tmpDir, "package-lock.json")
+	lock := `{
+  "lockfileVersion": 3,
+  "packages": {
+    "": {"dependencies": {"express": "^4.17.1", "lodash": "^4.17.20"}},
+    "node_modules/express": {"version": "4.17.1", "dependencies": {"lodash": "^4.17.0"}},
+    "node_modules/lodash": {"version": "4.17.20"}
+  }
+}`
+	if err := os.WriteFile(lockFile, []byte(lock), 0644); err != nil {
+		t.Fatalf("Failed to create lock file: %v", err)
+	}
+
+	mockAPIClient := &MockAPIClient{
+		AnalyzePackagesFunc: func(ctx context.Context, packages []rootio.Package) (*rootio.AnalyzePackagesResponse, error) {
+			return &rootio.AnalyzePackagesResponse{
+				Patches: []rootio.PackagePatch{
+					{
+						PackageName: "lodash",
+						Version:     "4.17.20",
+						Patch:       rootio.PatchInfo{Name: "lodash", Version: "4.17.21"},
+						PatchAlias:  rootio.PatchInfo{Name: "@rootio/lodash", Version: "4.17.21"},
+					},
+				},
+			}, nil
+		},
+	}
+
+	app := NewAppWithServices("test-key", "https://api.root.io", lockFile, false, logger,
+		NewParser(), mockAPIClient, common.WithPackageJSON(packageJSON))
+	if err := app.Run(context.Background()); err != nil {
+		t.Fatalf("App run failed: %v", err)
+	}
+
+	updated, err := os.ReadFile(packageJSON)
+	if err != nil {
+		t.Fatalf("Failed to read package.json: %v", err)
+	}
+	var pkgJSON struct {
+		Dependencies map[string]string `json:"dependencies"`
+		Overrides    map[string]any    `json:"overrides"`
+	}
+	if err := json.Unmarshal(updated, &pkgJSON); err != nil {
+		t.Fatalf("Failed to parse updated package.json: %v", err)
+	}
+
+	expected := map[string]any{
+		"lodash": map[string]any{".": "npm:@rootio/lodash@4.17.21"},
+	}
+	if !reflect.DeepEqual(pkgJSON.Overrides, expected) {
+		t.Errorf("Expected overrides %v, got:\n%s", expected, updated)
+	}
+	if pkgJSON.Dependencies["lodash"] != "^4.17.20" {
+		t.Errorf("Expected the dependencies spec to be kept, got:\n%s", updated)
+	}
+}
+
+func TestOverrideParents(t *testing.T) {
+	bar := rootio.PackagePatch{PackageName: "bar", Version: "1.0.0"}
+	tests := []struct {
+		name     string
+		patches  []rootio.PackagePatch
+		packages []common.PackageInfo
+		want     map[string][]string
+	}{
+		{
+			name:    "only version installed",
+			patches: []rootio.PackagePatch{bar},
+			packages: []common.PackageInfo{
+				{Name: "bar", Version: "1.0.0", Parents: []string{"foo"}},
+			},
+			want: map[string][]string{},
+		},
+		{
+			name:    "other version installed",
+			patches: []rootio.PackagePatch{bar},
+			packages: []common.PackageInfo{
+				{Name: "bar", Version: "1.0.0", Parents: []string{"foo"}},
+				{Name: "bar", Version: "1.0.0", Parents: []string{"baz", "foo"}},
+				{Name: "bar", Version: "2.0.0", Parents: []string{"qux"}},
+			},
+			want: map[string][]string{"bar": {"baz", "foo"}},
+		},
+		{
+			name:    "vulnerable copy is direct",
+			patches: []rootio.PackagePatch{bar},
+			packages: []common.PackageInfo{
+				{Name: "bar", Version: "1.0.0", Direct: true},
+				{Name: "bar", Version: "2.0.0", Parents: []string{"qux"}},
+			},
+			want: map[string][]string{},
+		},
+		{
+			name:    "parents unknown",
+			patches: []rootio.PackagePatch{bar},
+			packages: []common.PackageInfo{
+				{Name: "bar", Version: "1.0.0"},
+				{Name: "bar", Version: "2.0.0"},
+			},
+			want: map[string][]string{},
+		},
+		{
+			name:    "patched at several versions",
+			patches: []rootio.PackagePatch{bar, {PackageName: "bar", Version: "2.0.0"}},
+			packages: []common.PackageInfo{
+				{Name: "bar", Version: "1.0.0", Parents: []string{"foo"}},
+				{Name: "bar", Version: "2.0.0", Parents: []string{"qux"}},
+			},
+			want: map[string][]string{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := overrideParents(tt.patches, tt.packages); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
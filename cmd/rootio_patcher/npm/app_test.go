@@ -3,15 +3,26 @@ package npm
 import (
 	"context"
 	"errors"
+	"io"
 	"log/slog"
 	"os"
 	"path/filepath"
+	"reflect"
 	"strings"
 	"testing"
 
+	"rootio_patcher/cmd/rootio_patcher/common"
 	"rootio_patcher/pkg/rootio"
 )
 
+// writePackageJSON creates the package.json the app adds overrides to
+func writePackageJSON(t *testing.T, dir string) {
+	t.Helper()
+	if err := os.WriteFile(filepath.Join(dir, "package.json"), []byte(`{"name": "test"}`), 0644); err != nil {
+		t.Fatalf("Failed to create package.json: %v", err)
+	}
+}
+
 func TestNpmApp_Run_FileNotFound(t *testing.T) {
 	ctx := context.Background()
 	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
@@ -32,12 +43,73 @@ func TestNpmApp_Run_FileNotFound(t *testing.T) {
 	}
 }
 
+func TestNpmApp_Run_LockFileMismatch(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	tmpDir := t.TempDir()
+	writePackageJSON(t, tmpDir)
+	if err := os.WriteFile(filepath.Join(tmpDir, "package-lock.json"), []byte(`{"lockfileVersion": 3}`), 0644); err != nil {
+		t.Fatalf("Failed to create package-lock.json: %v", err)
+	}
+
+	// --package-manager=yarn in a project installed with npm
+	app := NewAppWithServices("test-key", "https://api.root.io", filepath.Join(tmpDir, "yarn.lock"), true, logger,
+		&MockParser{}, &MockAPIClient{})
+	err := app.Run(context.Background())
+
+	var mismatch *LockFileMismatchError
+	if !errors.As(err, &mismatch) {
+		t.Fatalf("Expected a LockFileMismatchError, got: %v", err)
+	}
+	if !reflect.DeepEqual(mismatch.Found, []string{"package-lock.json"}) || !reflect.DeepEqual(mismatch.Suggested, []string{"npm"}) {
+		t.Errorf("Expected package-lock.json to suggest npm, got %+v", mismatch)
+	}
+	if !strings.Contains(err.Error(), "but package-lock.json is present; pass --package-manager=npm") {
+		t.Errorf("Expected the error to suggest --package-manager=npm, got: %v", err)
+	}
+}
+
+func TestNpmApp_Run_PackageJSONNotFound(t *testing.T) {
+	ctx := context.Background()
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+
+	tmpDir := t.TempDir()
+	lockFile := filepath.Join(tmpDir, "package-lock.json")
+	if err := os.WriteFile(lockFile, []byte(`{"lockfileVersion": 3, "packages": {"": {}}}`), 0644); err != nil {
+		t.Fatalf("Failed to create temp file: %v", err)
+	}
+
+	mockParser := &MockParser{
+		ParseFunc: func(ctx context.Context, filePath string) ([]common.PackageInfo, error) {
+			return []common.PackageInfo{{Name: "lodash", Version: "4.17.20"}}, nil
+		},
+	}
+	analyzed := false
+	mockAPIClient := &MockAPIClient{
+		AnalyzePackagesFunc: func(ctx context.Context, packages []rootio.Package) (*rootio.AnalyzePackagesResponse, error) {
+			analyzed = true
+			return &rootio.AnalyzePackagesResponse{}, nil
+		},
+	}
+
+	app := NewAppWithServices("test-key", "https://api.root.io", lockFile, true, logger, mockParser, mockAPIClient)
+
+	err := app.Run(ctx)
+	if err == nil || !strings.Contains(err.Error(), "package.json not found: "+filepath.Join(tmpDir, "package.json")) {
+		t.Fatalf("Expected package.json not found error, got: %v", err)
+	}
+	if analyzed {
+		t.Error("Expected the missing package.json to be reported before calling the API")
+	}
+}
+
 func TestNpmApp_Run_NoPackages(t *testing.T) {
 	ctx := context.Background()
 	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
 
 	// Create empty lock file
 	tmpDir := t.TempDir()
+	writePackageJSON(t, tmpDir)
 	lockFile := filepath.Join(tmpDir, "package-lock.json")
 	content := `{"name": "test", "version": "1.0.0", "lockfileVersion": 3, "packages": {"": {}}}`
 	if err := os.WriteFile(lockFile, []byte(content), 0644); err != nil {
@@ -65,6 +137,7 @@ func TestNpmApp_Run_APIError(t *testing.T) {
 	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
 
 	tmpDir := t.TempDir()
+	writePackageJSON(t, tmpDir)
 	lockFile := filepath.Join(tmpDir, "package-lock.json")
 	content := `{
   "name": "test",
@@ -91,7 +164,7 @@ func TestNpmApp_Run_APIError(t *testing.T) {
 		lockFile,
 		true,
 		logger,
-		&MockParser{},
+		NewParser(),
 		mockAPIClient,
 	)
 
@@ -109,6 +182,7 @@ func TestNpmApp_Run_NoPatches(t *testing.T) {
 	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
 
 	tmpDir := t.TempDir()
+	writePackageJSON(t, tmpDir)
 	lockFile := filepath.Join(tmpDir, "package-lock.json")
 	content := `{
   "name": "test",
@@ -151,6 +225,7 @@ func TestNpmApp_Run_DryRun(t *testing.T) {
 	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
 
 	tmpDir := t.TempDir()
+	writePackageJSON(t, tmpDir)
 	lockFile := filepath.Join(tmpDir, "package-lock.json")
 	content := `{
   "name": "test",
@@ -209,6 +284,7 @@ func TestNpmApp_Run_ApplyPatches(t *testing.T) {
 	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
 
 	tmpDir := t.TempDir()
+	writePackageJSON(t, tmpDir)
 	lockFile := filepath.Join(tmpDir, "package-lock.json")
 	content := `{
   "name": "test",
@@ -246,7 +322,7 @@ func TestNpmApp_Run_ApplyPatches(t *testing.T) {
 		lockFile,
 		false, // NOT dry-run
 		logger,
-		&MockParser{},
+		NewParser(),
 		mockAPIClient,
 	)
 
@@ -255,15 +331,245 @@ func TestNpmApp_Run_ApplyPatches(t *testing.T) {
 		t.Fatalf("Expected no error, got: %v", err)
 	}
 
-	// Verify file was modified
-	updatedContent, err := os.ReadFile(lockFile)
+	// The patch is applied as an override in package.json; the lock file is left to npm install
+	packageJSON, err := os.ReadFile(filepath.Join(tmpDir, "package.json"))
+	if err != nil {
+		t.Fatalf("Failed to read package.json: %v", err)
+	}
+	if !strings.Contains(string(packageJSON), `"overrides"`) || !strings.Contains(string(packageJSON), "4.17.21") {
+		t.Errorf("package.json should override lodash with 4.17.21, got:\n%s", packageJSON)
+	}
+	lockContent, err := os.ReadFile(lockFile)
 	if err != nil {
 		t.Fatalf("Failed to read file: %v", err)
 	}
-	if !strings.Contains(string(updatedContent), "4.17.21") {
-		t.Error("File should contain updated version 4.17.21")
+	if string(lockContent) != content {
+		t.Errorf("Lock file should be unchanged without --update-lockfile, got:\n%s", lockContent)
+	}
+}
+
+func TestNpmApp_Run_SkipDev(t *testing.T) {
+	ctx := context.Background()
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+
+	tmpDir := t.TempDir()
+	writePackageJSON(t, tmpDir)
+	lockFile := filepath.Join(tmpDir, "package-lock.json")
+	if err := os.WriteFile(lockFile, []byte(`{"lockfileVersion": 3, "packages": {"": {}}}`), 0644); err != nil {
+		t.Fatalf("Failed to create temp file: %v", err)
+	}
+
+	mockParser := &MockParser{
+		ParseFunc: func(ctx context.Context, filePath string) ([]common.PackageInfo, error) {
+			return []common.PackageInfo{
+				{Name: "express", Version: "4.17.1", Ecosystem: common.EcosystemNpm, Direct: true},
+				{Name: "jest", Version: "29.0.0", Ecosystem: common.EcosystemNpm, Direct: true, Dev: true},
+			}, nil
+		},
+	}
+
+	var analyzed []rootio.Package
+	mockAPIClient := &MockAPIClient{
+		AnalyzePackagesFunc: func(ctx context.Context, packages []rootio.Package) (*rootio.AnalyzePackagesResponse, error) {
+			analyzed = packages
+			return &rootio.AnalyzePackagesResponse{}, nil
+		},
+	}
+
+	app := NewAppWithServices("test-key", "https://api.root.io", lockFile, true, logger,
+		mockParser, mockAPIClient, common.WithSkipDev(true))
+
+	result, err := app.RunWithResult(ctx)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	if len(analyzed) != 1 || analyzed[0].Name != "express" {
+		t.Errorf("Expected only express to be analyzed, got %+v", analyzed)
+	}
+	if result.PackagesFound != 2 || result.DevSkipped != 1 {
+		t.Errorf("Expected 2 packages found and 1 dev skipped, got %d and %d", result.PackagesFound, result.DevSkipped)
+	}
+}
+
+func TestNpmApp_ProposedDiff(t *testing.T) {
+	tmpDir := t.TempDir()
+	packageJSON := filepath.Join(tmpDir, "package.json")
+	content := "{\n  \"name\": \"app\"\n}\n"
+	if err := os.WriteFile(packageJSON, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to create temp file: %v", err)
+	}
+
+	app := NewAppWithServices("test-key", "https://api.root.io", filepath.Join(tmpDir, "package-lock.json"), true,
+		slog.New(slog.NewTextHandler(os.Stdout, nil)), &MockParser{}, &MockAPIClient{})
+	app.packageJSON = packageJSON
+
+	diff, err := app.proposedDiff(context.Background(), []rootio.PackagePatch{
+		{PackageName: "lodash", Version: "4.17.20", PatchAlias: rootio.PatchInfo{Name: "@rootio/lodash", Version: "4.17.21"}},
+	})
+	if err != nil {
+		t.Fatalf("proposedDiff failed: %v", err)
+	}
+
+	for _, want := range []string{
+		"+  \"overrides\": {\n",
+		"+    \"lodash\": \"npm:@rootio/lodash@4.17.21\"\n",
+	} {
+		if !strings.Contains(diff, want) {
+			t.Errorf("Expected diff to contain %q, got:\n%s", want, diff)
+		}
+	}
+
+	if current, _ := os.ReadFile(packageJSON); string(current) != content {
+		t.Error("proposedDiff should not modify package.json")
+	}
+}
+
+func TestNpmApp_RenderPackageJSON_PreservesFormatting(t *testing.T) {
+	tests := []struct {
+		name           string
+		packageManager string
+		content        string
+		want           string
+	}{
+		{
+			name:           "npm with four-space indent",
+			packageManager: "npm",
+			content:        "{\n    \"version\": \"1.0.0\",\n    \"name\": \"app\",\n    \"scripts\": {\n        \"test\": \"jest\"\n    }\n}\n",
+			want:           "{\n    \"version\": \"1.0.0\",\n    \"name\": \"app\",\n    \"scripts\": {\n        \"test\": \"jest\"\n    },\n    \"overrides\": {\n        \"lodash\": \"npm:@rootio/lodash@4.17.21\"\n    }\n}\n",
+		},
+		{
+			name:           "pnpm with tabs",
+			packageManager: "pnpm",
+			content:        "{\n\t\"name\": \"app\",\n\t\"pnpm\": {\n\t\t\"patchedDependencies\": {},\n\t\t\"overrides\": {}\n\t},\n\t\"license\": \"MIT\"\n}\n",
+			want:           "{\n\t\"name\": \"app\",\n\t\"pnpm\": {\n\t\t\"patchedDependencies\": {},\n\t\t\"overrides\": {\n\t\t\t\"lodash\": \"npm:@rootio/lodash@4.17.21\"\n\t\t}\n\t},\n\t\"license\": \"MIT\"\n}\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tmpDir := t.TempDir()
+			packageJSON := filepath.Join(tmpDir, "package.json")
+			if err := os.WriteFile(packageJSON, []byte(tt.content), 0644); err != nil {
+				t.Fatalf("Failed to create temp file: %v", err)
+			}
+
+			app := NewAppWithServices("test-key", "https://api.root.io", tt.packageManager, false,
+				slog.New(slog.NewTextHandler(os.Stdout, nil)), &MockParser{}, &MockAPIClient{})
+			app.packageJSON = packageJSON
+
+			_, updated, err := app.renderPackageJSON(map[string]string{"lodash": "npm:@rootio/lodash@4.17.21"})
+			if err != nil {
+				t.Fatalf("renderPackageJSON failed: %v", err)
+			}
+			if string(updated) != tt.want {
+				t.Errorf("Expected:\n%s\ngot:\n%s", tt.want, updated)
+			}
+		})
+	}
+}
+
+func TestDetectPackageManager(t *testing.T) {
+	tests := []struct {
+		name      string
+		lockFiles []string
+		want      string
+		wantErr   string
+	}{
+		{"npm", []string{"package-lock.json"}, "npm", ""},
+		{"yarn", []string{"yarn.lock"}, "yarn", ""},
+		{"pnpm", []string{"pnpm-lock.yaml"}, "pnpm", ""},
+		{"ambiguous", []string{"package-lock.json", "yarn.lock"}, "", "several lock files found"},
+		{"none", nil, "", "no lock file found"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			writePackageJSON(t, dir)
+			for _, name := range tt.lockFiles {
+				if err := os.WriteFile(filepath.Join(dir, name), []byte("{}"), 0644); err != nil {
+					t.Fatalf("Failed to create %s: %v", name, err)
+				}
+			}
+
+			got, err := DetectPackageManager(filepath.Join(dir, "package.json"))
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("Expected an error containing %q, got %v (package manager %q)", tt.wantErr, err, got)
+				}
+				if !strings.Contains(err.Error(), "--package-manager") {
+					t.Errorf("Expected the error to suggest --package-manager, got: %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("DetectPackageManager failed: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("Expected package manager %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestDetectPackageManager_PackageManagerField(t *testing.T) {
+	dir := t.TempDir()
+	content := `{"name": "test", "packageManager": "pnpm@8.0.0"}`
+	if err := os.WriteFile(filepath.Join(dir, "package.json"), []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to create package.json: %v", err)
+	}
+	// A stale package-lock.json next to the pnpm lock file would otherwise be ambiguous
+	for _, name := range []string{"package-lock.json", "pnpm-lock.yaml"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte("{}"), 0644); err != nil {
+			t.Fatalf("Failed to create %s: %v", name, err)
+		}
+	}
+
+	got, err := DetectPackageManager(filepath.Join(dir, "package.json"))
+	if err != nil {
+		t.Fatalf("DetectPackageManager failed: %v", err)
+	}
+	if got != "pnpm" {
+		t.Errorf("Expected pnpm from the packageManager field, got %q", got)
+	}
+	if field := common.OverrideField(got); field != "pnpm.overrides" {
+		t.Errorf("Expected pnpm's overrides format, got %q", field)
+	}
+}
+
+func TestDetectPackageManager_UnknownPackageManagerField(t *testing.T) {
+	dir := t.TempDir()
+	content := `{"name": "test", "packageManager": "bun@1.1.0"}`
+	if err := os.WriteFile(filepath.Join(dir, "package.json"), []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to create package.json: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "yarn.lock"), []byte(""), 0644); err != nil {
+		t.Fatalf("Failed to create yarn.lock: %v", err)
+	}
+
+	// Package managers without an overrides format fall back to the lock file
+	got, err := DetectPackageManager(filepath.Join(dir, "package.json"))
+	if err != nil {
+		t.Fatalf("DetectPackageManager failed: %v", err)
+	}
+	if got != "yarn" {
+		t.Errorf("Expected yarn from the lock file, got %q", got)
+	}
+}
+
+func TestDetectPackageManager_Workspace(t *testing.T) {
+	rootDir := writeMonorepo(t, `{"workspaces": ["packages/*"]}`)
+	if err := os.WriteFile(filepath.Join(rootDir, "yarn.lock"), []byte(""), 0644); err != nil {
+		t.Fatalf("Failed to create yarn.lock: %v", err)
+	}
+
+	// A workspace package has no lock file of its own; the root's decides
+	got, err := DetectPackageManager(filepath.Join(rootDir, "packages", "api", "package.json"))
+	if err != nil {
+		t.Fatalf("DetectPackageManager failed: %v", err)
 	}
-	if strings.Contains(string(updatedContent), `"version": "4.17.20"`) {
-		t.Error("File should not contain old version 4.17.20")
+	if got != "yarn" {
+		t.Errorf("Expected yarn from the workspace root's lock file, got %q", got)
 	}
 }
@@ -0,0 +1,172 @@
+package npm
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// parseUpdateSpec splits an update into the aliased package name and version.
+// A plain version ("4.17.21") has no alias; an npm alias ("npm:@rootio/lodash@4.17.21") does.
+func parseUpdateSpec(spec string) (string, string) {
+	alias, ok := strings.CutPrefix(spec, "npm:")
+	if !ok {
+		return "", spec
+	}
+	// Scoped names start with @, so the version separator is the last @
+	if at := strings.LastIndex(alias, "@"); at > 0 {
+		return alias[:at], alias[at+1:]
+	}
+	return "", spec
+}
+
+// lockSections returns which sections of a package-lock.json hold installed versions for its
+// lockfileVersion: v1 only has the nested legacy "dependencies" tree, v3 only the flat
+// "packages" map, and v2 has both, with "dependencies" mirroring "packages" for npm 6
+func lockSections(lockfileVersion int) (packages, dependencies bool, err error) {
+	switch lockfileVersion {
+	case 1:
+		return false, true, nil
+	case 2:
+		return true, true, nil
+	case 3:
+		return true, false, nil
+	default:
+		return false, false, fmt.Errorf("unsupported lockfileVersion %d", lockfileVersion)
+	}
+}
+
+// updatePackageLock rewrites the versions of updated packages in a package-lock.json, in every
+// section its lockfileVersion keeps them so the file stays consistent for every npm version.
+// Integrity hashes describe the old tarball, so they are removed and npm recomputes them on
+// the next install. An aliased package also gets its name set and its resolved URL removed,
+// since the tarball now comes from another package.
+func updatePackageLock(content []byte, updates map[string]string) (string, error) {
+	var header struct {
+		LockfileVersion int `json:"lockfileVersion"`
+	}
+	if err := json.Unmarshal(content, &header); err != nil {
+		return "", fmt.Errorf("failed to parse JSON: %w", err)
+	}
+	updatePackages, updateDependencies, err := lockSections(header.LockfileVersion)
+	if err != nil {
+		return "", err
+	}
+
+	var lockfile orderedObject
+	if err := json.Unmarshal(content, &lockfile); err != nil {
+		return "", fmt.Errorf("failed to parse JSON: %w", err)
+	}
+
+	// Flat "packages" keyed by node_modules path
+	if packages, ok := lockfile.object("packages"); ok && updatePackages {
+		for _, pkgPath := range packages.keys {
+			if pkgPath == "" {
+				continue
+			}
+			spec, ok := updates[extractPackageName(pkgPath)]
+			if !ok {
+				continue
+			}
+
+			entry, ok := packages.object(pkgPath)
+			if !ok {
+				continue
+			}
+			if err := updatePackagesEntry(entry, spec); err != nil {
+				return "", err
+			}
+			if err := packages.set(pkgPath, entry, ""); err != nil {
+				return "", err
+			}
+		}
+		if err := lockfile.set("packages", packages, ""); err != nil {
+			return "", err
+		}
+	}
+
+	// Nested legacy "dependencies"
+	if dependencies, ok := lockfile.object("dependencies"); ok && updateDependencies {
+		if err := updateLegacyDependencies(dependencies, updates); err != nil {
+			return "", err
+		}
+		if err := lockfile.set("dependencies", dependencies, ""); err != nil {
+			return "", err
+		}
+	}
+
+	updated, err := formatJSON(lockfile, detectIndent(content))
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal JSON: %w", err)
+	}
+	return string(updated), nil
+}
+
+// updatePackagesEntry updates an entry of the "packages" section
+func updatePackagesEntry(entry *orderedObject, spec string) error {
+	aliasName, version := parseUpdateSpec(spec)
+	oldVersion := entry.getString("version")
+
+	if err := entry.set("version", version, ""); err != nil {
+		return err
+	}
+	if aliasName != "" {
+		if err := entry.set("name", aliasName, "version"); err != nil {
+			return err
+		}
+		entry.remove("resolved")
+	} else if resolved := entry.getString("resolved"); resolved != "" && oldVersion != "" {
+		if err := entry.set("resolved", strings.Replace(resolved, oldVersion, version, 1), ""); err != nil {
+			return err
+		}
+	}
+	entry.remove("integrity")
+	return nil
+}
+
+// updateLegacyDependencies updates entries of a legacy "dependencies" section and the
+// dependencies nested under them. Aliased versions are written as npm:name@version there.
+func updateLegacyDependencies(dependencies *orderedObject, updates map[string]string) error {
+	for _, name := range dependencies.keys {
+		entry, ok := dependencies.object(name)
+		if !ok {
+			continue
+		}
+
+		if spec, ok := updates[name]; ok {
+			aliasName, version := parseUpdateSpec(spec)
+			oldVersion := entry.getString("version")
+
+			if aliasName != "" {
+				if err := entry.set("version", spec, ""); err != nil {
+					return err
+				}
+				entry.remove("resolved")
+			} else {
+				if err := entry.set("version", version, ""); err != nil {
+					return err
+				}
+				if resolved := entry.getString("resolved"); resolved != "" && oldVersion != "" {
+					if err := entry.set("resolved", strings.Replace(resolved, oldVersion, version, 1), ""); err != nil {
+						return err
+					}
+				}
+			}
+			entry.remove("integrity")
+		}
+
+		if nested, ok := entry.object("dependencies"); ok {
+			if err := updateLegacyDependencies(nested, updates); err != nil {
+				return err
+			}
+			if err := entry.set("dependencies", nested, ""); err != nil {
+				return err
+			}
+		}
+
+		if err := dependencies.set(name, entry, ""); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,235 @@
+package npm
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+const packageLockV3 = `{
+  "name": "app",
+  "version": "1.0.0",
+  "lockfileVersion": 3,
+  "requires": true,
+  "packages": {
+    "": {
+      "name": "app",
+      "dependencies": {
+        "express": "^4.17.0",
+        "lodash": "^4.17.20"
+      }
+    },
+    "node_modules/express": {
+      "version": "4.17.1",
+      "resolved": "https://registry.npmjs.org/express/-/express-4.17.1.tgz",
+      "integrity": "sha512-express",
+      "license": "MIT"
+    },
+    "node_modules/lodash": {
+      "version": "4.17.20",
+      "resolved": "https://registry.npmjs.org/lodash/-/lodash-4.17.20.tgz",
+      "integrity": "sha512-lodash",
+      "license": "MIT"
+    }
+  }
+}
+`
+
+func TestUpdatePackageLock_PlainVersion(t *testing.T) {
+	updated, err := updatePackageLock([]byte(packageLockV3), map[string]string{"lodash": "4.17.21"})
+	if err != nil {
+		t.Fatalf("updatePackageLock failed: %v", err)
+	}
+
+	expected := strings.Replace(packageLockV3, `      "version": "4.17.20",
+      "resolved": "https://registry.npmjs.org/lodash/-/lodash-4.17.20.tgz",
+      "integrity": "sha512-lodash",
+`, `      "version": "4.17.21",
+      "resolved": "https://registry.npmjs.org/lodash/-/lodash-4.17.21.tgz",
+`, 1)
+	if updated != expected {
+		t.Errorf("Expected only lodash to change, keeping key order and unknown fields; got:\n%s", updated)
+	}
+}
+
+func TestUpdatePackageLock_Alias(t *testing.T) {
+	updated, err := updatePackageLock([]byte(packageLockV3), map[string]string{"lodash": "npm:@rootio/lodash@4.17.21"})
+	if err != nil {
+		t.Fatalf("updatePackageLock failed: %v", err)
+	}
+
+	var lockfile PackageLockJSON
+	if err := json.Unmarshal([]byte(updated), &lockfile); err != nil {
+		t.Fatalf("Updated lock file is not valid JSON: %v", err)
+	}
+	entry := lockfile.Packages["node_modules/lodash"]
+	if entry.Version != "4.17.21" || entry.Resolved != "" || entry.Integrity != "" {
+		t.Errorf("Expected version 4.17.21 without resolved or integrity, got %+v", entry)
+	}
+	if !strings.Contains(updated, `"node_modules/lodash": {
+      "name": "@rootio/lodash",
+      "version": "4.17.21",`) {
+		t.Errorf("Expected the alias name before the version, got:\n%s", updated)
+	}
+	if lockfile.Packages["node_modules/express"].Integrity != "sha512-express" {
+		t.Error("Expected packages that weren't updated to keep their integrity")
+	}
+}
+
+func TestUpdatePackageLock_LegacyDependencies(t *testing.T) {
+	content := `{
+  "name": "app",
+  "lockfileVersion": 1,
+  "dependencies": {
+    "express": {
+      "version": "4.17.1",
+      "integrity": "sha512-express",
+      "dependencies": {
+        "lodash": {
+          "version": "4.17.20",
+          "resolved": "https://registry.npmjs.org/lodash/-/lodash-4.17.20.tgz",
+          "integrity": "sha512-nested"
+        }
+      }
+    },
+    "lodash": {
+      "version": "4.17.20",
+      "resolved": "https://registry.npmjs.org/lodash/-/lodash-4.17.20.tgz",
+      "integrity": "sha512-lodash"
+    }
+  }
+}
+`
+	updated, err := updatePackageLock([]byte(content), map[string]string{"lodash": "npm:@rootio/lodash@4.17.21"})
+	if err != nil {
+		t.Fatalf("updatePackageLock failed: %v", err)
+	}
+
+	if strings.Count(updated, `"version": "npm:@rootio/lodash@4.17.21"`) != 2 {
+		t.Errorf("Expected top-level and nested lodash to be aliased, got:\n%s", updated)
+	}
+	if strings.Contains(updated, "sha512-lodash") || strings.Contains(updated, "sha512-nested") {
+		t.Errorf("Expected stale lodash integrity hashes to be removed, got:\n%s", updated)
+	}
+	if !strings.Contains(updated, "sha512-express") {
+		t.Errorf("Expected express integrity to be kept, got:\n%s", updated)
+	}
+}
+
+// packageLockV2 keeps lodash both in "packages" and in the legacy "dependencies" mirror,
+// hoisted and nested under express
+const packageLockV2 = `{
+  "name": "app",
+  "lockfileVersion": 2,
+  "requires": true,
+  "packages": {
+    "": {
+      "name": "app",
+      "dependencies": {
+        "express": "^4.17.0",
+        "lodash": "^4.17.20"
+      }
+    },
+    "node_modules/express": {
+      "version": "4.17.1",
+      "integrity": "sha512-express"
+    },
+    "node_modules/express/node_modules/lodash": {
+      "version": "4.17.20",
+      "resolved": "https://registry.npmjs.org/lodash/-/lodash-4.17.20.tgz",
+      "integrity": "sha512-nested"
+    },
+    "node_modules/lodash": {
+      "version": "4.17.20",
+      "resolved": "https://registry.npmjs.org/lodash/-/lodash-4.17.20.tgz",
+      "integrity": "sha512-lodash"
+    }
+  },
+  "dependencies": {
+    "express": {
+      "version": "4.17.1",
+      "integrity": "sha512-express",
+      "dependencies": {
+        "lodash": {
+          "version": "4.17.20",
+          "resolved": "https://registry.npmjs.org/lodash/-/lodash-4.17.20.tgz",
+          "integrity": "sha512-nested"
+        }
+      }
+    },
+    "lodash": {
+      "version": "4.17.20",
+      "resolved": "https://registry.npmjs.org/lodash/-/lodash-4.17.20.tgz",
+      "integrity": "sha512-lodash"
+    }
+  }
+}
+`
+
+func TestUpdatePackageLock_LockfileVersions(t *testing.T) {
+	v1 := strings.Replace(packageLockV2, `"lockfileVersion": 2`, `"lockfileVersion": 1`, 1)
+	v1 = v1[:strings.Index(v1, `  "packages"`)] + v1[strings.Index(v1, `  "dependencies": {
+    "express"`):]
+
+	tests := []struct {
+		name    string
+		content string
+		// updated is how many lodash entries get the new version
+		updated int
+	}{
+		{"v1 nested dependencies", v1, 2},
+		{"v2 packages and dependencies", packageLockV2, 4},
+		{"v3 packages", packageLockV3, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			updated, err := updatePackageLock([]byte(tt.content), map[string]string{"lodash": "4.17.21"})
+			if err != nil {
+				t.Fatalf("updatePackageLock failed: %v", err)
+			}
+
+			if got := strings.Count(updated, `"version": "4.17.21"`); got != tt.updated {
+				t.Errorf("Expected %d lodash entries at 4.17.21, got %d:\n%s", tt.updated, got, updated)
+			}
+			if strings.Contains(updated, `"version": "4.17.20"`) || strings.Contains(updated, "lodash-4.17.20.tgz") {
+				t.Errorf("Expected every lodash entry to be updated, got:\n%s", updated)
+			}
+			if got := strings.Count(updated, "lodash-4.17.21.tgz"); got != tt.updated {
+				t.Errorf("Expected %d resolved URLs to be updated, got %d", tt.updated, got)
+			}
+			if !strings.Contains(updated, "sha512-express") {
+				t.Errorf("Expected express integrity to be kept, got:\n%s", updated)
+			}
+		})
+	}
+}
+
+func TestUpdatePackageLock_UnsupportedLockfileVersion(t *testing.T) {
+	for _, version := range []string{"0", "4"} {
+		content := strings.Replace(packageLockV3, `"lockfileVersion": 3`, `"lockfileVersion": `+version, 1)
+		_, err := updatePackageLock([]byte(content), map[string]string{"lodash": "4.17.21"})
+		if err == nil || !strings.Contains(err.Error(), "unsupported lockfileVersion "+version) {
+			t.Errorf("Expected an unsupported lockfileVersion %s error, got: %v", version, err)
+		}
+	}
+}
+
+func TestParseUpdateSpec(t *testing.T) {
+	tests := []struct {
+		spec    string
+		name    string
+		version string
+	}{
+		{"4.17.21", "", "4.17.21"},
+		{"npm:@rootio/lodash@4.17.21", "@rootio/lodash", "4.17.21"},
+		{"npm:lodash-patched@1.0.0", "lodash-patched", "1.0.0"},
+	}
+
+	for _, tt := range tests {
+		name, version := parseUpdateSpec(tt.spec)
+		if name != tt.name || version != tt.version {
+			t.Errorf("parseUpdateSpec(%q) = %q, %q; want %q, %q", tt.spec, name, version, tt.name, tt.version)
+		}
+	}
+}
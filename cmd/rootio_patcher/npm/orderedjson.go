@@ -0,0 +1,176 @@
+package npm
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// orderedObject is a JSON object that keeps its keys in file order, so rewriting
+// package.json or package-lock.json only changes the entries that were updated
+type orderedObject struct {
+	keys   []string
+	values map[string]json.RawMessage
+}
+
+// UnmarshalJSON decodes an object, keeping values raw
+func (o *orderedObject) UnmarshalJSON(data []byte) error {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	token, err := decoder.Token()
+	if err != nil {
+		return err
+	}
+	if delim, ok := token.(json.Delim); !ok || delim != '{' {
+		return fmt.Errorf("expected a JSON object")
+	}
+
+	o.keys = nil
+	o.values = make(map[string]json.RawMessage)
+	for decoder.More() {
+		token, err := decoder.Token()
+		if err != nil {
+			return err
+		}
+		key := token.(string)
+
+		var value json.RawMessage
+		if err := decoder.Decode(&value); err != nil {
+			return err
+		}
+		if _, exists := o.values[key]; !exists {
+			o.keys = append(o.keys, key)
+		}
+		o.values[key] = value
+	}
+
+	_, err = decoder.Token()
+	return err
+}
+
+// MarshalJSON encodes the object with its keys in their original order
+func (o orderedObject) MarshalJSON() ([]byte, error) {
+	var b bytes.Buffer
+	b.WriteByte('{')
+	for i, key := range o.keys {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		encodedKey, err := marshalNoEscape(key)
+		if err != nil {
+			return nil, err
+		}
+		b.Write(encodedKey)
+		b.WriteByte(':')
+		b.Write(o.values[key])
+	}
+	b.WriteByte('}')
+	return b.Bytes(), nil
+}
+
+// has reports whether the object contains key
+func (o *orderedObject) has(key string) bool {
+	_, ok := o.values[key]
+	return ok
+}
+
+// getString returns the string value of key, or "" when it is missing or not a string
+func (o *orderedObject) getString(key string) string {
+	var value string
+	_ = json.Unmarshal(o.values[key], &value)
+	return value
+}
+
+// set stores value under key. A new key is inserted before the key named before when it
+// exists, and appended otherwise.
+func (o *orderedObject) set(key string, value any, before string) error {
+	encoded, err := marshalNoEscape(value)
+	if err != nil {
+		return err
+	}
+
+	if o.values == nil {
+		o.values = make(map[string]json.RawMessage)
+	}
+	if !o.has(key) {
+		at := len(o.keys)
+		for i, k := range o.keys {
+			if k == before {
+				at = i
+				break
+			}
+		}
+		o.keys = append(o.keys[:at], append([]string{key}, o.keys[at:]...)...)
+	}
+	o.values[key] = encoded
+	return nil
+}
+
+// remove deletes key from the object
+func (o *orderedObject) remove(key string) {
+	if !o.has(key) {
+		return
+	}
+	delete(o.values, key)
+	for i, k := range o.keys {
+		if k == key {
+			o.keys = append(o.keys[:i], o.keys[i+1:]...)
+			break
+		}
+	}
+}
+
+// object decodes the value of key as an ordered object
+func (o *orderedObject) object(key string) (*orderedObject, bool) {
+	raw, ok := o.values[key]
+	if !ok {
+		return nil, false
+	}
+	var child orderedObject
+	if err := json.Unmarshal(raw, &child); err != nil {
+		return nil, false
+	}
+	return &child, true
+}
+
+// marshalNoEscape encodes value as JSON without escaping <, > and & the way npm writes them
+func marshalNoEscape(value any) ([]byte, error) {
+	var b bytes.Buffer
+	encoder := json.NewEncoder(&b)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(value); err != nil {
+		return nil, err
+	}
+	return bytes.TrimSuffix(b.Bytes(), []byte("\n")), nil
+}
+
+// detectIndent returns the indentation of the first indented line of a JSON file:
+// a tab or a run of spaces. npm's default of two spaces is used when there is none.
+func detectIndent(content []byte) string {
+	for _, line := range strings.Split(string(content), "\n") {
+		trimmed := strings.TrimLeft(line, " \t")
+		if trimmed == "" || len(trimmed) == len(line) {
+			continue
+		}
+		if line[0] == '\t' {
+			return "\t"
+		}
+		return line[:len(line)-len(strings.TrimLeft(line, " "))]
+	}
+	return "  "
+}
+
+// formatJSON encodes value indented with indent and a trailing newline, as npm writes its files
+func formatJSON(value any, indent string) ([]byte, error) {
+	compact, err := marshalNoEscape(value)
+	if err != nil {
+		return nil, err
+	}
+
+	var indented bytes.Buffer
+	if err := json.Indent(&indented, compact, "", indent); err != nil {
+		return nil, err
+	}
+	indented.WriteByte('\n')
+	return indented.Bytes(), nil
+}
@@ -1,11 +1,19 @@
 package npm
 
 import (
+	"bufio"
+	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
 	"os"
+	"path/filepath"
+	"slices"
+	"sort"
 	"strings"
+
+	"gopkg.in/yaml.v3"
+
 	"rootio_patcher/cmd/rootio_patcher/common"
 )
 
@@ -48,20 +56,24 @@ type PackageLockJSON struct {
 
 // PackageLockEntry represents a package entry in the "packages" section
 type PackageLockEntry struct {
-	Version         string            `json:"version,omitempty"`
-	Resolved        string            `json:"resolved,omitempty"`
-	Integrity       string            `json:"integrity,omitempty"`
-	Dev             bool              `json:"dev,omitempty"`
-	Dependencies    map[string]string `json:"dependencies,omitempty"`
-	DevDependencies map[string]string `json:"devDependencies,omitempty"`
+	Name                 string            `json:"name,omitempty"`
+	Version              string            `json:"version,omitempty"`
+	Resolved             string            `json:"resolved,omitempty"`
+	Integrity            string            `json:"integrity,omitempty"`
+	Dev                  bool              `json:"dev,omitempty"`
+	Dependencies         map[string]string `json:"dependencies,omitempty"`
+	DevDependencies      map[string]string `json:"devDependencies,omitempty"`
+	OptionalDependencies map[string]string `json:"optionalDependencies,omitempty"`
+	PeerDependencies     map[string]string `json:"peerDependencies,omitempty"`
 }
 
 // DependencyEntry represents a dependency in the legacy "dependencies" section
 type DependencyEntry struct {
-	Version  string                     `json:"version"`
-	Resolved string                     `json:"resolved,omitempty"`
-	Dev      bool                       `json:"dev,omitempty"`
-	Requires map[string]string          `json:"requires,omitempty"`
+	Version      string                     `json:"version"`
+	Resolved     string                     `json:"resolved,omitempty"`
+	Dev          bool                       `json:"dev,omitempty"`
+	Requires     map[string]string          `json:"requires,omitempty"`
+	Dependencies map[string]DependencyEntry `json:"dependencies,omitempty"`
 }
 
 // Parse parses lock files (package-lock.json, yarn.lock, pnpm-lock.yaml) and returns all packages
@@ -85,50 +97,48 @@ func (p *NpmParser) Parse(ctx context.Context, filePath string) ([]common.Packag
 		return nil, fmt.Errorf("failed to parse JSON: %w", err)
 	}
 
-	var packages []common.PackageInfo
-	seen := make(map[string]bool)
-
-	// Get direct dependencies from root package (the "" key)
-	rootPkg, hasRoot := lockfile.Packages[""]
-	directDeps := make(map[string]bool)
-	directDevDeps := make(map[string]bool)
+	// lockfileVersion 1 only has the nested "dependencies" tree
+	if len(lockfile.Packages) == 0 && len(lockfile.Dependencies) > 0 {
+		directDeps, directDevDeps := readRootDependencies(filepath.Dir(filePath))
+		return legacyDependencies(lockfile.Dependencies, "", directDeps, directDevDeps), nil
+	}
 
-	if hasRoot {
-		for dep := range rootPkg.Dependencies {
-			directDeps[dep] = true
-		}
-		for dep := range rootPkg.DevDependencies {
-			directDevDeps[dep] = true
-		}
+	// Keep the lock file's order so results are stable
+	pkgPaths, err := packagePaths(content)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse JSON: %w", err)
 	}
 
-	// Parse packages from the "packages" object (lockfile v2/v3)
+	// Importers are the root package ("") and workspace packages: entries outside node_modules
+	importers := make(map[string]PackageLockEntry)
 	for pkgPath, pkgData := range lockfile.Packages {
-		// Skip root package
-		if pkgPath == "" {
-			continue
+		if !strings.Contains(pkgPath, "node_modules/") {
+			importers[pkgPath] = pkgData
 		}
+	}
+
+	parents := packageParents(lockfile.Packages, importers)
 
-		name := extractPackageName(pkgPath)
-		if name == "" {
+	var packages []common.PackageInfo
+	for _, pkgPath := range pkgPaths {
+		if _, isImporter := importers[pkgPath]; isImporter {
 			continue
 		}
+		pkgData := lockfile.Packages[pkgPath]
 
-		version := pkgData.Version
-		if version == "" {
+		installName := extractPackageName(pkgPath)
+		if installName == "" {
 			continue
 		}
 
-		// Create unique key to avoid duplicates
-		key := fmt.Sprintf("%s@%s", name, version)
-		if seen[key] {
+		// Links to workspace packages have no version
+		if pkgData.Version == "" {
 			continue
 		}
-		seen[key] = true
+		name, version := resolveAlias(installName, pkgData.Name, pkgData.Version)
 
-		// Determine if direct or transitive
-		isDirect := directDeps[name] || directDevDeps[name]
-		isDev := directDevDeps[name] || pkgData.Dev
+		// Each path is a distinct install, so the same name can appear at several depths and versions
+		isDirect, isDevDirect := directDependency(pkgPath, installName, importers, lockfile.Packages)
 
 		packages = append(packages, common.PackageInfo{
 			Name:              name,
@@ -136,83 +146,583 @@ func (p *NpmParser) Parse(ctx context.Context, filePath string) ([]common.Packag
 			VersionConstraint: version, // Lock file has exact versions
 			Ecosystem:         common.EcosystemNpm,
 			Direct:            isDirect,
-			Dev:               isDev,
+			Dev:               isDevDirect || pkgData.Dev,
+			Path:              pkgPath,
+			Parents:           parents[pkgPath],
 		})
 	}
 
 	return packages, nil
 }
 
-// extractPackageName extracts package name from node_modules path
-func extractPackageName(pkgPath string) string {
-	if !strings.HasPrefix(pkgPath, "node_modules/") {
-		return pkgPath
+// packageParents maps each install path to the sorted names of the packages requiring it.
+// Importers (the root and workspaces) aren't parents: their dependencies are direct.
+func packageParents(lockPackages, importers map[string]PackageLockEntry) map[string][]string {
+	parents := make(map[string][]string)
+	for pkgPath, pkgData := range lockPackages {
+		if _, isImporter := importers[pkgPath]; isImporter || pkgData.Version == "" {
+			continue
+		}
+		name, _ := resolveAlias(extractPackageName(pkgPath), pkgData.Name, pkgData.Version)
+
+		for _, deps := range []map[string]string{pkgData.Dependencies, pkgData.OptionalDependencies} {
+			for dep := range deps {
+				if resolved := resolveInstall(pkgPath, dep, lockPackages); resolved != "" {
+					parents[resolved] = append(parents[resolved], name)
+				}
+			}
+		}
 	}
 
-	remainder := strings.TrimPrefix(pkgPath, "node_modules/")
+	for pkgPath, names := range parents {
+		slices.Sort(names)
+		parents[pkgPath] = slices.Compact(names)
+	}
+	return parents
+}
 
-	// Handle nested node_modules
-	if idx := strings.Index(remainder, "/node_modules/"); idx != -1 {
-		parts := strings.Split(remainder, "/node_modules/")
-		return parts[len(parts)-1]
+// resolveInstall returns the install path name resolves to when required from the package at
+// pkgPath, looking in its own node_modules and then each enclosing one as Node does, or ""
+// when it isn't installed
+func resolveInstall(pkgPath, name string, lockPackages map[string]PackageLockEntry) string {
+	dir := pkgPath
+	for {
+		candidate := "node_modules/" + name
+		if dir != "" {
+			candidate = dir + "/" + candidate
+		}
+		if _, ok := lockPackages[candidate]; ok {
+			return candidate
+		}
+		if dir == "" {
+			return ""
+		}
+
+		// Move up to the package whose node_modules holds dir
+		idx := strings.LastIndex(dir, "node_modules/")
+		if idx <= 0 {
+			dir = ""
+		} else {
+			dir = strings.TrimSuffix(dir[:idx], "/")
+		}
+	}
+}
+
+// legacyDependencies walks a lockfileVersion 1 "dependencies" tree depth first, in name order,
+// giving each package the node_modules path it would have in a v2 lock file. Only top-level
+// packages declared in package.json are direct.
+func legacyDependencies(
+	deps map[string]DependencyEntry, parentPath string, directDeps, directDevDeps map[string]bool,
+) []common.PackageInfo {
+	names := make([]string, 0, len(deps))
+	for name := range deps {
+		names = append(names, name)
 	}
+	sort.Strings(names)
+
+	var packages []common.PackageInfo
+	for _, name := range names {
+		dep := deps[name]
+		pkgPath := parentPath + "node_modules/" + name
+
+		// Links to local packages have no registry version
+		if dep.Version != "" && !strings.HasPrefix(dep.Version, "file:") {
+			topLevel := parentPath == ""
+			realName, version := resolveAlias(name, "", dep.Version)
+			packages = append(packages, common.PackageInfo{
+				Name:              realName,
+				Version:           version,
+				VersionConstraint: version, // Lock file has exact versions
+				Ecosystem:         common.EcosystemNpm,
+				Direct:            topLevel && (directDeps[name] || directDevDeps[name]),
+				Dev:               dep.Dev,
+				Path:              pkgPath,
+			})
+		}
 
-	return remainder
+		packages = append(packages, legacyDependencies(dep.Dependencies, pkgPath+"/", directDeps, directDevDeps)...)
+	}
+
+	return packages
 }
 
-// Update updates package versions in package-lock.json
+// packagePaths returns the keys of the "packages" object in the order they appear in the lock file
+func packagePaths(content []byte) ([]string, error) {
+	var raw struct {
+		Packages json.RawMessage `json:"packages"`
+	}
+	if err := json.Unmarshal(content, &raw); err != nil {
+		return nil, err
+	}
+	if len(raw.Packages) == 0 {
+		return nil, nil
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(raw.Packages))
+	if _, err := decoder.Token(); err != nil {
+		return nil, err
+	}
+
+	var paths []string
+	for decoder.More() {
+		token, err := decoder.Token()
+		if err != nil {
+			return nil, err
+		}
+		pkgPath, ok := token.(string)
+		if !ok {
+			return nil, fmt.Errorf("unexpected key %v in packages", token)
+		}
+
+		var value json.RawMessage
+		if err := decoder.Decode(&value); err != nil {
+			return nil, err
+		}
+		paths = append(paths, pkgPath)
+	}
+
+	return paths, nil
+}
+
+// directDependency reports whether the package installed at pkgPath is a direct dependency
+// (and a direct dev dependency) of the importer whose node_modules it lives in.
+// Packages hoisted to the top-level node_modules may belong to the root or to any workspace
+// without its own copy. Packages nested under another package's node_modules are always transitive.
+func directDependency(
+	pkgPath, name string, importers, lockPackages map[string]PackageLockEntry,
+) (bool, bool) {
+	owner := ""
+	if idx := strings.LastIndex(pkgPath, "node_modules/"); idx > 0 {
+		owner = strings.TrimSuffix(pkgPath[:idx], "/")
+	}
+
+	declares := func(importer PackageLockEntry) (bool, bool) {
+		_, dep := importer.Dependencies[name]
+		_, devDep := importer.DevDependencies[name]
+		return dep || devDep, devDep && !dep
+	}
+
+	if owner != "" {
+		importer, ok := importers[owner]
+		if !ok {
+			return false, false
+		}
+		return declares(importer)
+	}
+
+	isDirect, isDev := false, true
+	for importerPath, importer := range importers {
+		if importerPath != "" {
+			if _, shadowed := lockPackages[importerPath+"/node_modules/"+name]; shadowed {
+				continue
+			}
+		}
+		direct, dev := declares(importer)
+		if direct {
+			isDirect = true
+			isDev = isDev && dev
+		}
+	}
+	return isDirect, isDirect && isDev
+}
+
+// resolveAlias returns the package and version actually installed under installName. An npm
+// alias (lodash → npm:@rootio/lodash@4.17.21) is recorded as an npm: version, or in the
+// "packages" section as the real name next to the plain version.
+func resolveAlias(installName, lockName, version string) (string, string) {
+	if aliasName, aliasVersion := parseUpdateSpec(version); aliasName != "" {
+		return aliasName, aliasVersion
+	}
+	if lockName != "" {
+		return lockName, version
+	}
+	return installName, version
+}
+
+// isAliased reports whether pkg is installed through an npm alias, i.e. under another name
+func isAliased(pkg common.PackageInfo) bool {
+	return pkg.Path != "" && extractPackageName(pkg.Path) != pkg.Name
+}
+
+// extractPackageName extracts the package name from a node_modules path.
+// Nested paths (node_modules/a/node_modules/b) yield the innermost package.
+func extractPackageName(pkgPath string) string {
+	if idx := strings.LastIndex(pkgPath, "node_modules/"); idx != -1 {
+		return pkgPath[idx+len("node_modules/"):]
+	}
+	return pkgPath
+}
+
+// Update updates package versions in package-lock.json. Each update is a version or an
+// npm alias such as npm:@rootio/lodash@4.17.21.
 func (p *NpmParser) Update(ctx context.Context, filePath string, updates map[string]string) (string, error) {
+	if !strings.HasSuffix(filePath, "package-lock.json") {
+		return "", fmt.Errorf("updating %s is not supported; only package-lock.json can be rewritten", filepath.Base(filePath))
+	}
+
 	content, err := os.ReadFile(filePath)
 	if err != nil {
 		return "", fmt.Errorf("failed to read file: %w", err)
 	}
 
+	return updatePackageLock(content, updates)
+}
+
+// Validate validates JSON syntax
+func (p *NpmParser) Validate(content string) bool {
 	var lockfile PackageLockJSON
-	if err := json.Unmarshal(content, &lockfile); err != nil {
-		return "", fmt.Errorf("failed to parse JSON: %w", err)
+	return json.Unmarshal([]byte(content), &lockfile) == nil
+}
+
+// parseYarnLock parses yarn.lock files, both the v1 format and the Berry (v2+) format
+func (p *NpmParser) parseYarnLock(filePath string) ([]common.PackageInfo, error) {
+	content, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read file: %w", err)
 	}
 
-	// Update packages in the "packages" object
-	for pkgPath, pkgData := range lockfile.Packages {
-		if pkgPath == "" {
+	// yarn.lock doesn't record which packages are direct, so use package.json if available
+	directDeps, directDevDeps := readRootDependencies(filepath.Dir(filePath))
+
+	if isYarnBerry(content) {
+		return parseYarnBerryLock(content, directDeps, directDevDeps)
+	}
+
+	var packages []common.PackageInfo
+	seen := make(map[string]bool)
+
+	// Names declared by the entry header currently being read
+	var entryNames []string
+
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	for scanner.Scan() {
+		line := scanner.Text()
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || strings.HasPrefix(trimmed, "#") {
 			continue
 		}
 
-		name := extractPackageName(pkgPath)
-		if newVersion, ok := updates[name]; ok {
-			oldVersion := pkgData.Version
-			pkgData.Version = newVersion
+		// Unindented lines start a new entry: "lodash@^4.0.0, lodash@^4.17.0:"
+		if !strings.HasPrefix(line, " ") {
+			entryNames = parseYarnEntryNames(strings.TrimSuffix(trimmed, ":"))
+			continue
+		}
 
-			// Update resolved URL if present
-			if pkgData.Resolved != "" && oldVersion != "" {
-				pkgData.Resolved = strings.Replace(pkgData.Resolved, oldVersion, newVersion, 1)
+		// Only the entry's own version field is indented by exactly two spaces
+		if entryNames == nil || !strings.HasPrefix(line, "  version ") {
+			continue
+		}
+
+		version := strings.Trim(strings.TrimSpace(strings.TrimPrefix(line, "  version ")), `"`)
+		for _, name := range entryNames {
+			key := fmt.Sprintf("%s@%s", name, version)
+			if seen[key] {
+				continue
 			}
+			seen[key] = true
+
+			packages = append(packages, common.PackageInfo{
+				Name:              name,
+				Version:           version,
+				VersionConstraint: version,
+				Ecosystem:         common.EcosystemNpm,
+				Direct:            directDeps[name] || directDevDeps[name],
+				Dev:               directDevDeps[name],
+			})
+		}
+		entryNames = nil
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read yarn.lock: %w", err)
+	}
+
+	return packages, nil
+}
+
+// parseYarnEntryNames extracts the unique package names from a yarn.lock entry header
+func parseYarnEntryNames(header string) []string {
+	var names []string
+	seen := make(map[string]bool)
+
+	for _, spec := range strings.Split(header, ",") {
+		spec = strings.Trim(strings.TrimSpace(spec), `"`)
+
+		// Split on the last "@" so scoped names like @babel/core@^7.0.0 stay intact
+		idx := strings.LastIndex(spec, "@")
+		if idx <= 0 {
+			continue
+		}
 
-			lockfile.Packages[pkgPath] = pkgData
+		name := spec[:idx]
+		if !seen[name] {
+			seen[name] = true
+			names = append(names, name)
 		}
 	}
 
-	// Marshal back to JSON with indentation
-	updatedContent, err := json.MarshalIndent(lockfile, "", "  ")
+	return names
+}
+
+// yarnBerryEntry is a package entry of a Yarn Berry (v2+) yarn.lock
+type yarnBerryEntry struct {
+	Version    string `yaml:"version"`
+	Resolution string `yaml:"resolution"`
+}
+
+// isYarnBerry reports whether yarn.lock content was written by Yarn Berry (v2+).
+// Berry lock files are YAML and start with a __metadata entry holding the lock file version.
+func isYarnBerry(content []byte) bool {
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	for scanner.Scan() {
+		trimmed := strings.TrimSpace(scanner.Text())
+		if trimmed == "" || strings.HasPrefix(trimmed, "#") {
+			continue
+		}
+		return trimmed == "__metadata:"
+	}
+	return false
+}
+
+// isYarnBerryLock reports whether the lock file at filePath is a Yarn Berry yarn.lock
+func isYarnBerryLock(filePath string) bool {
+	if !strings.HasSuffix(filePath, "yarn.lock") {
+		return false
+	}
+	content, err := os.ReadFile(filePath)
+	if err != nil {
+		return false
+	}
+	return isYarnBerry(content)
+}
+
+// parseYarnBerryLock parses a Yarn Berry (v2+) yarn.lock. Package names come from each
+// entry's resolution ("@babel/core@npm:7.23.0"), since the descriptor keys may use aliases.
+// Only packages resolved from the registry are returned; workspace, patch, portal, link
+// and file entries aren't published packages.
+func parseYarnBerryLock(content []byte, directDeps, directDevDeps map[string]bool) ([]common.PackageInfo, error) {
+	var entries map[string]yarnBerryEntry
+	if err := yaml.Unmarshal(content, &entries); err != nil {
+		return nil, fmt.Errorf("failed to parse YAML: %w", err)
+	}
+
+	// Map iteration order is random, so walk the entries in key order like the file does
+	keys := make([]string, 0, len(entries))
+	for key := range entries {
+		if key != "__metadata" {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	var packages []common.PackageInfo
+	seen := make(map[string]bool)
+
+	for _, key := range keys {
+		entry := entries[key]
+		name, protocol, ok := splitBerryResolution(entry.Resolution)
+		if !ok || protocol != "npm" || entry.Version == "" {
+			continue
+		}
+
+		id := fmt.Sprintf("%s@%s", name, entry.Version)
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+
+		packages = append(packages, common.PackageInfo{
+			Name:              name,
+			Version:           entry.Version,
+			VersionConstraint: entry.Version,
+			Ecosystem:         common.EcosystemNpm,
+			Direct:            directDeps[name] || directDevDeps[name],
+			Dev:               directDevDeps[name],
+		})
+	}
+
+	return packages, nil
+}
+
+// splitBerryResolution splits a Berry resolution such as "@babel/core@npm:7.23.0" into the
+// package name and protocol
+func splitBerryResolution(resolution string) (string, string, bool) {
+	// Skip the leading "@" of scoped names
+	at := strings.Index(resolution[min(1, len(resolution)):], "@") + 1
+	if at <= 0 {
+		return "", "", false
+	}
+
+	protocol, _, ok := strings.Cut(resolution[at+1:], ":")
+	if !ok {
+		return "", "", false
+	}
+	return resolution[:at], protocol, true
+}
+
+// readRootDependencies reads direct dependencies from package.json in dir.
+// Missing or invalid package.json files yield empty maps.
+func readRootDependencies(dir string) (map[string]bool, map[string]bool) {
+	directDeps := make(map[string]bool)
+	directDevDeps := make(map[string]bool)
+
+	content, err := os.ReadFile(filepath.Join(dir, "package.json"))
 	if err != nil {
-		return "", fmt.Errorf("failed to marshal JSON: %w", err)
+		return directDeps, directDevDeps
+	}
+
+	var manifest struct {
+		Dependencies    map[string]string `json:"dependencies"`
+		DevDependencies map[string]string `json:"devDependencies"`
+	}
+	if err := json.Unmarshal(content, &manifest); err != nil {
+		return directDeps, directDevDeps
+	}
+
+	for dep := range manifest.Dependencies {
+		directDeps[dep] = true
+	}
+	for dep := range manifest.DevDependencies {
+		directDevDeps[dep] = true
 	}
 
-	return string(updatedContent) + "\n", nil
+	return directDeps, directDevDeps
 }
 
-// Validate validates JSON syntax
-func (p *NpmParser) Validate(content string) bool {
-	var lockfile PackageLockJSON
-	return json.Unmarshal([]byte(content), &lockfile) == nil
+// PnpmLock represents the structure of pnpm-lock.yaml (lockfileVersion 6.0 and 9.0)
+type PnpmLock struct {
+	LockfileVersion string                      `yaml:"lockfileVersion"`
+	Importers       map[string]PnpmImporter     `yaml:"importers,omitempty"`
+	Dependencies    map[string]PnpmDependency   `yaml:"dependencies,omitempty"`
+	DevDependencies map[string]PnpmDependency   `yaml:"devDependencies,omitempty"`
+	Packages        map[string]PnpmPackageEntry `yaml:"packages"`
 }
 
-// parseYarnLock parses yarn.lock files
-func (p *NpmParser) parseYarnLock(filePath string) ([]common.PackageInfo, error) {
-	return nil, fmt.Errorf("yarn.lock parsing not yet implemented")
+// PnpmImporter represents a workspace project in the "importers" section
+type PnpmImporter struct {
+	Dependencies    map[string]PnpmDependency `yaml:"dependencies,omitempty"`
+	DevDependencies map[string]PnpmDependency `yaml:"devDependencies,omitempty"`
+}
+
+// PnpmDependency represents a direct dependency declared by an importer
+type PnpmDependency struct {
+	Specifier string `yaml:"specifier"`
+	Version   string `yaml:"version"`
+}
+
+// PnpmPackageEntry represents a package entry in the "packages" section
+type PnpmPackageEntry struct {
+	Version string `yaml:"version,omitempty"`
+	Dev     bool   `yaml:"dev,omitempty"`
 }
 
 // parsePnpmLock parses pnpm-lock.yaml files
 func (p *NpmParser) parsePnpmLock(filePath string) ([]common.PackageInfo, error) {
-	return nil, fmt.Errorf("pnpm-lock.yaml parsing not yet implemented")
+	content, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read file: %w", err)
+	}
+
+	var lockfile PnpmLock
+	if err := yaml.Unmarshal(content, &lockfile); err != nil {
+		return nil, fmt.Errorf("failed to parse YAML: %w", err)
+	}
+
+	// Direct dependencies come from the root importer (v9, and v6 workspaces)
+	// or from top-level dependencies (v6 single-project lockfiles)
+	directDeps := make(map[string]bool)
+	directDevDeps := make(map[string]bool)
+
+	root, hasRoot := lockfile.Importers["."]
+	if !hasRoot {
+		root = PnpmImporter{
+			Dependencies:    lockfile.Dependencies,
+			DevDependencies: lockfile.DevDependencies,
+		}
+	}
+	for dep := range root.Dependencies {
+		directDeps[dep] = true
+	}
+	for dep := range root.DevDependencies {
+		directDevDeps[dep] = true
+	}
+
+	// Map iteration order is random, so walk the packages in the order the file lists them
+	pkgKeys, err := pnpmPackageKeys(content)
+	if err != nil {
+		return nil, err
+	}
+
+	var packages []common.PackageInfo
+	seen := make(map[string]bool)
+
+	for _, pkgKey := range pkgKeys {
+		pkgData := lockfile.Packages[pkgKey]
+		name, version := splitPnpmPackageKey(pkgKey)
+		if name == "" || version == "" {
+			continue
+		}
+
+		key := fmt.Sprintf("%s@%s", name, version)
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+
+		packages = append(packages, common.PackageInfo{
+			Name:              name,
+			Version:           version,
+			VersionConstraint: version,
+			Ecosystem:         common.EcosystemNpm,
+			Direct:            directDeps[name] || directDevDeps[name],
+			Dev:               directDevDeps[name] || pkgData.Dev,
+		})
+	}
+
+	return packages, nil
+}
+
+// pnpmPackageKeys returns the keys of the "packages" section in the order they appear in the file
+func pnpmPackageKeys(content []byte) ([]string, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(content, &doc); err != nil {
+		return nil, fmt.Errorf("failed to parse YAML: %w", err)
+	}
+	if len(doc.Content) == 0 || doc.Content[0].Kind != yaml.MappingNode {
+		return nil, nil
+	}
+
+	root := doc.Content[0]
+	for i := 0; i+1 < len(root.Content); i += 2 {
+		section := root.Content[i+1]
+		if root.Content[i].Value != "packages" || section.Kind != yaml.MappingNode {
+			continue
+		}
+		keys := make([]string, 0, len(section.Content)/2)
+		for j := 0; j+1 < len(section.Content); j += 2 {
+			keys = append(keys, section.Content[j].Value)
+		}
+		return keys, nil
+	}
+	return nil, nil
+}
+
+// splitPnpmPackageKey splits a pnpm package key into name and version.
+// Handles "/lodash@4.17.21" (v6), "lodash@4.17.21" (v9), scoped names and
+// peer dependency suffixes like "jest@29.0.0(@types/node@20.0.0)".
+func splitPnpmPackageKey(pkgKey string) (string, string) {
+	key := strings.TrimPrefix(pkgKey, "/")
+
+	// Drop peer dependency suffix
+	if idx := strings.Index(key, "("); idx != -1 {
+		key = key[:idx]
+	}
+
+	// Split on the last "@" so scoped names like @babel/core@7.0.0 stay intact
+	idx := strings.LastIndex(key, "@")
+	if idx <= 0 {
+		return "", ""
+	}
+
+	return key[:idx], key[idx+1:]
 }
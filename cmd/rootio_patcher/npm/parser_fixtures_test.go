@@ -3,6 +3,7 @@ package npm
 import (
 	"context"
 	"path/filepath"
+	"reflect"
 	"strings"
 	"testing"
 )
@@ -337,6 +338,28 @@ func TestPnpmParser_ParseRealLockFile(t *testing.T) {
 	}
 }
 
+// TestPnpmParser_ParseRealLockFile_Order checks packages come back in the same order on every parse
+func TestPnpmParser_ParseRealLockFile_Order(t *testing.T) {
+	ctx := context.Background()
+	parser := NewParser()
+
+	lockFile := filepath.Join("testdata", "pnpm", "pnpm-lock.yaml")
+
+	first, err := parser.Parse(ctx, lockFile)
+	if err != nil {
+		t.Fatalf("Failed to parse real pnpm-lock.yaml: %v", err)
+	}
+	for run := 0; run < 10; run++ {
+		packages, err := parser.Parse(ctx, lockFile)
+		if err != nil {
+			t.Fatalf("Failed to parse real pnpm-lock.yaml: %v", err)
+		}
+		if !reflect.DeepEqual(packages, first) {
+			t.Fatalf("Expected the same package order on every parse, run %d differed", run)
+		}
+	}
+}
+
 // TestPnpmParser_ParseRealLockFile_Count tests package count from pnpm-lock.yaml
 func TestPnpmParser_ParseRealLockFile_Count(t *testing.T) {
 	ctx := context.Background()
@@ -395,3 +418,101 @@ func TestAllLockFiles_SameBasicPackages(t *testing.T) {
 		}
 	}
 }
+
+// TestNpmParser_ParseLockfileV1 tests parsing the nested "dependencies" tree of a lockfileVersion 1 file
+func TestNpmParser_ParseLockfileV1(t *testing.T) {
+	ctx := context.Background()
+	parser := NewParser()
+
+	lockFile := filepath.Join("testdata", "npm-v1", "package-lock.json")
+
+	packages, err := parser.Parse(ctx, lockFile)
+	if err != nil {
+		t.Fatalf("Failed to parse v1 lock file: %v", err)
+	}
+
+	expected := []struct {
+		path    string
+		name    string
+		version string
+		direct  bool
+		dev     bool
+	}{
+		{"node_modules/@jest/core", "@jest/core", "29.0.0", false, true},
+		{"node_modules/@jest/core/node_modules/ms", "ms", "2.1.3", false, true},
+		{"node_modules/body-parser", "body-parser", "1.20.1", false, false},
+		{"node_modules/debug", "debug", "2.6.9", false, false},
+		{"node_modules/express", "express", "4.18.2", true, false},
+		{"node_modules/jest", "jest", "29.0.0", true, true},
+		{"node_modules/lodash", "lodash", "4.17.21", true, false},
+		{"node_modules/ms", "ms", "2.0.0", false, false},
+		{"node_modules/qs", "qs", "6.11.0", false, false},
+	}
+
+	if len(packages) != len(expected) {
+		t.Fatalf("Expected %d packages, got %d: %+v", len(expected), len(packages), packages)
+	}
+	for i, want := range expected {
+		got := packages[i]
+		if got.Path != want.path || got.Name != want.name || got.Version != want.version ||
+			got.Direct != want.direct || got.Dev != want.dev {
+			t.Errorf("Package %d: expected %+v, got %+v", i, want, got)
+		}
+	}
+}
+
+// TestYarnParser_ParseBerryLockFile tests parsing a Yarn Berry (v2+) yarn.lock
+func TestYarnParser_ParseBerryLockFile(t *testing.T) {
+	ctx := context.Background()
+	parser := NewParser()
+
+	lockFile := filepath.Join("testdata", "yarn-berry", "yarn.lock")
+
+	packages, err := parser.Parse(ctx, lockFile)
+	if err != nil {
+		t.Fatalf("Failed to parse Berry lock file: %v", err)
+	}
+
+	// Workspace, portal and patch entries are skipped; debug and ms have two versions each
+	expected := []struct {
+		name    string
+		version string
+		direct  bool
+		dev     bool
+	}{
+		{"@babel/core", "7.23.0", false, false},
+		{"body-parser", "1.20.1", false, false},
+		{"debug", "2.6.9", false, false},
+		{"debug", "4.3.4", false, false},
+		{"express", "4.18.2", true, false},
+		{"jest", "29.0.0", true, true},
+		{"lodash", "4.17.21", true, false},
+		{"ms", "2.0.0", false, false},
+		{"ms", "2.1.2", false, false},
+		{"qs", "6.11.0", false, false},
+		{"resolve", "1.22.8", false, false},
+	}
+
+	if len(packages) != len(expected) {
+		t.Fatalf("Expected %d packages, got %d: %+v", len(expected), len(packages), packages)
+	}
+	for i, want := range expected {
+		got := packages[i]
+		if got.Name != want.name || got.Version != want.version || got.Direct != want.direct || got.Dev != want.dev {
+			t.Errorf("Package %d: expected %+v, got %+v", i, want, got)
+		}
+	}
+}
+
+// TestIsYarnBerry tests telling Berry lock files apart from yarn v1 lock files
+func TestIsYarnBerry(t *testing.T) {
+	if !isYarnBerryLock(filepath.Join("testdata", "yarn-berry", "yarn.lock")) {
+		t.Error("Expected the yarn-berry fixture to be detected as Berry")
+	}
+	if isYarnBerryLock(filepath.Join("testdata", "yarn", "yarn.lock")) {
+		t.Error("Expected the yarn v1 fixture not to be detected as Berry")
+	}
+	if isYarnBerryLock(filepath.Join("testdata", "npm", "package-lock.json")) {
+		t.Error("Expected package-lock.json not to be detected as Berry")
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"os"
 	"path/filepath"
+	"reflect"
 	"testing"
 
 	"rootio_patcher/cmd/rootio_patcher/common"
@@ -20,12 +21,15 @@ func TestNpmParser_FilePatterns(t *testing.T) {
 	parser := NewParser()
 	patterns := parser.FilePatterns()
 
-	if len(patterns) != 1 {
-		t.Fatalf("Expected 1 pattern, got %d", len(patterns))
+	expected := []string{"package-lock.json", "yarn.lock", "pnpm-lock.yaml"}
+	if len(patterns) != len(expected) {
+		t.Fatalf("Expected %d patterns, got %d", len(expected), len(patterns))
 	}
 
-	if patterns[0] != "package-lock.json" {
-		t.Errorf("Expected pattern 'package-lock.json', got '%s'", patterns[0])
+	for i, pattern := range expected {
+		if patterns[i] != pattern {
+			t.Errorf("Expected pattern %d to be '%s', got '%s'", i, pattern, patterns[i])
+		}
 	}
 }
 
@@ -134,6 +138,295 @@ func TestNpmParser_Parse(t *testing.T) {
 	}
 }
 
+func TestNpmParser_Parse_NestedDependencies(t *testing.T) {
+	ctx := context.Background()
+	parser := NewParser()
+
+	tmpDir := t.TempDir()
+	lockFile := filepath.Join(tmpDir, "package-lock.json")
+
+	// debug is a direct dependency at 4.3.4 and also nested under express at 2.6.9
+	content := `{
+  "name": "test-project",
+  "lockfileVersion": 3,
+  "packages": {
+    "": {
+      "name": "test-project",
+      "dependencies": {
+        "debug": "^4.3.0",
+        "express": "^4.17.0"
+      }
+    },
+    "node_modules/debug": {
+      "version": "4.3.4"
+    },
+    "node_modules/express": {
+      "version": "4.17.1"
+    },
+    "node_modules/express/node_modules/debug": {
+      "version": "2.6.9"
+    },
+    "node_modules/ms": {
+      "version": "2.1.2"
+    },
+    "node_modules/express/node_modules/ms": {
+      "version": "2.1.2"
+    }
+  }
+}`
+
+	if err := os.WriteFile(lockFile, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to create temp file: %v", err)
+	}
+
+	packages, err := parser.Parse(ctx, lockFile)
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+
+	expected := []struct {
+		path    string
+		name    string
+		version string
+		direct  bool
+	}{
+		{"node_modules/debug", "debug", "4.3.4", true},
+		{"node_modules/express", "express", "4.17.1", true},
+		{"node_modules/express/node_modules/debug", "debug", "2.6.9", false},
+		{"node_modules/ms", "ms", "2.1.2", false},
+		{"node_modules/express/node_modules/ms", "ms", "2.1.2", false},
+	}
+
+	if len(packages) != len(expected) {
+		t.Fatalf("Expected %d packages, got %d: %+v", len(expected), len(packages), packages)
+	}
+	for i, want := range expected {
+		got := packages[i]
+		if got.Path != want.path || got.Name != want.name || got.Version != want.version || got.Direct != want.direct {
+			t.Errorf("Package %d: expected %+v, got %+v", i, want, got)
+		}
+	}
+}
+
+// scopedLockV3 has bar 1.0.0 hoisted for foo and baz, and bar 2.0.0 nested under qux
+const scopedLockV3 = `{
+  "name": "test-project",
+  "lockfileVersion": 3,
+  "packages": {
+    "": {
+      "name": "test-project",
+      "dependencies": {
+        "baz": "^1.0.0",
+        "foo": "^1.0.0",
+        "qux": "^1.0.0"
+      }
+    },
+    "node_modules/bar": {
+      "version": "1.0.0"
+    },
+    "node_modules/baz": {
+      "version": "1.0.0",
+      "dependencies": {
+        "bar": "^1.0.0"
+      }
+    },
+    "node_modules/foo": {
+      "version": "1.0.0",
+      "dependencies": {
+        "bar": "^1.0.0"
+      }
+    },
+    "node_modules/fsevents": {
+      "version": "2.3.3"
+    },
+    "node_modules/qux": {
+      "version": "1.0.0",
+      "dependencies": {
+        "bar": "^2.0.0"
+      },
+      "optionalDependencies": {
+        "fsevents": "^2.3.0"
+      }
+    },
+    "node_modules/qux/node_modules/bar": {
+      "version": "2.0.0"
+    }
+  }
+}`
+
+func TestNpmParser_Parse_Parents(t *testing.T) {
+	lockFile := filepath.Join(t.TempDir(), "package-lock.json")
+	if err := os.WriteFile(lockFile, []byte(scopedLockV3), 0644); err != nil {
+		t.Fatalf("Failed to create temp file: %v", err)
+	}
+
+	packages, err := NewParser().Parse(context.Background(), lockFile)
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+
+	// Each copy's parents are the packages that resolve to it, nearest node_modules first
+	expected := map[string][]string{
+		"node_modules/bar":                  {"baz", "foo"},
+		"node_modules/baz":                  nil,
+		"node_modules/foo":                  nil,
+		"node_modules/fsevents":             {"qux"},
+		"node_modules/qux":                  nil,
+		"node_modules/qux/node_modules/bar": {"qux"},
+	}
+	if len(packages) != len(expected) {
+		t.Fatalf("Expected %d packages, got %d: %+v", len(expected), len(packages), packages)
+	}
+	for _, pkg := range packages {
+		if want := expected[pkg.Path]; !reflect.DeepEqual(pkg.Parents, want) {
+			t.Errorf("%s: expected parents %v, got %v", pkg.Path, want, pkg.Parents)
+		}
+	}
+}
+
+func TestNpmParser_Parse_WorkspaceDependencies(t *testing.T) {
+	ctx := context.Background()
+	parser := NewParser()
+
+	tmpDir := t.TempDir()
+	lockFile := filepath.Join(tmpDir, "package-lock.json")
+
+	content := `{
+  "name": "monorepo",
+  "lockfileVersion": 3,
+  "packages": {
+    "": {
+      "name": "monorepo",
+      "workspaces": ["packages/*"],
+      "devDependencies": {
+        "jest": "^29.0.0"
+      }
+    },
+    "packages/api": {
+      "name": "api",
+      "version": "1.0.0",
+      "dependencies": {
+        "lodash": "^4.17.0",
+        "semver": "^6.0.0"
+      }
+    },
+    "node_modules/api": {
+      "resolved": "packages/api",
+      "link": true
+    },
+    "node_modules/jest": {
+      "version": "29.0.0",
+      "dev": true
+    },
+    "node_modules/lodash": {
+      "version": "4.17.20"
+    },
+    "node_modules/semver": {
+      "version": "7.5.4"
+    },
+    "packages/api/node_modules/semver": {
+      "version": "6.3.1"
+    }
+  }
+}`
+
+	if err := os.WriteFile(lockFile, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to create temp file: %v", err)
+	}
+
+	packages, err := parser.Parse(ctx, lockFile)
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+
+	expected := []struct {
+		path   string
+		direct bool
+		dev    bool
+	}{
+		{"node_modules/jest", true, true},
+		{"node_modules/lodash", true, false},
+		{"node_modules/semver", false, false}, // api uses its own nested copy
+		{"packages/api/node_modules/semver", true, false},
+	}
+
+	if len(packages) != len(expected) {
+		t.Fatalf("Expected %d packages (workspace entries and links excluded), got %d: %+v",
+			len(expected), len(packages), packages)
+	}
+	for i, want := range expected {
+		got := packages[i]
+		if got.Path != want.path || got.Direct != want.direct || got.Dev != want.dev {
+			t.Errorf("Package %d: expected %+v, got %+v", i, want, got)
+		}
+	}
+}
+
+func TestNpmParser_Parse_AliasedPackages(t *testing.T) {
+	ctx := context.Background()
+	parser := NewParser()
+
+	tests := []struct {
+		name    string
+		content string
+	}{
+		{
+			name: "npm: version",
+			content: `{
+  "lockfileVersion": 3,
+  "packages": {
+    "": {"dependencies": {"lodash": "npm:@rootio/lodash@4.17.21"}},
+    "node_modules/lodash": {"version": "npm:@rootio/lodash@4.17.21"}
+  }
+}`,
+		},
+		{
+			name: "name field",
+			content: `{
+  "lockfileVersion": 3,
+  "packages": {
+    "": {"dependencies": {"lodash": "npm:@rootio/lodash@4.17.21"}},
+    "node_modules/lodash": {"name": "@rootio/lodash", "version": "4.17.21"}
+  }
+}`,
+		},
+		{
+			name: "legacy dependencies",
+			content: `{
+  "lockfileVersion": 1,
+  "dependencies": {
+    "lodash": {"version": "npm:@rootio/lodash@4.17.21"}
+  }
+}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lockFile := filepath.Join(t.TempDir(), "package-lock.json")
+			if err := os.WriteFile(lockFile, []byte(tt.content), 0644); err != nil {
+				t.Fatalf("Failed to create temp file: %v", err)
+			}
+
+			packages, err := parser.Parse(ctx, lockFile)
+			if err != nil {
+				t.Fatalf("Parse failed: %v", err)
+			}
+			if len(packages) != 1 {
+				t.Fatalf("Expected 1 package, got %+v", packages)
+			}
+
+			pkg := packages[0]
+			if pkg.Name != "@rootio/lodash" || pkg.Version != "4.17.21" {
+				t.Errorf("Expected the aliased package @rootio/lodash@4.17.21, got %s@%s", pkg.Name, pkg.Version)
+			}
+			if pkg.Path != "node_modules/lodash" || !isAliased(pkg) {
+				t.Errorf("Expected the package to be installed as lodash, got path %q", pkg.Path)
+			}
+		})
+	}
+}
+
 func TestNpmParser_Parse_FileNotFound(t *testing.T) {
 	ctx := context.Background()
 	parser := NewParser()
@@ -243,6 +536,185 @@ func TestNpmParser_Validate(t *testing.T) {
 	}
 }
 
+func TestNpmParser_ParseYarnLock(t *testing.T) {
+	ctx := context.Background()
+	parser := NewParser()
+
+	tmpDir := t.TempDir()
+	lockFile := filepath.Join(tmpDir, "yarn.lock")
+
+	content := `# THIS IS AN AUTOGENERATED FILE. DO NOT EDIT THIS FILE DIRECTLY.
+# yarn lockfile v1
+
+
+"@babel/core@^7.0.0", "@babel/core@^7.12.3":
+  version "7.28.6"
+  resolved "https://registry.yarnpkg.com/@babel/core/-/core-7.28.6.tgz"
+  dependencies:
+    debug "^4.1.0"
+
+lodash@^4.0.0, lodash@^4.17.0:
+  version "4.17.21"
+  resolved "https://registry.yarnpkg.com/lodash/-/lodash-4.17.21.tgz"
+
+debug@^4.1.0:
+  version "4.4.3"
+`
+
+	if err := os.WriteFile(lockFile, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to create temp file: %v", err)
+	}
+
+	pkgJSON := `{"dependencies": {"lodash": "^4.17.0"}, "devDependencies": {"@babel/core": "^7.12.3"}}`
+	if err := os.WriteFile(filepath.Join(tmpDir, "package.json"), []byte(pkgJSON), 0644); err != nil {
+		t.Fatalf("Failed to create package.json: %v", err)
+	}
+
+	packages, err := parser.Parse(ctx, lockFile)
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+
+	if len(packages) != 3 {
+		t.Fatalf("Expected 3 packages, got %d", len(packages))
+	}
+
+	expected := []common.PackageInfo{
+		{Name: "@babel/core", Version: "7.28.6", Direct: true, Dev: true},
+		{Name: "lodash", Version: "4.17.21", Direct: true, Dev: false},
+		{Name: "debug", Version: "4.4.3", Direct: false, Dev: false},
+	}
+
+	for i, want := range expected {
+		got := packages[i]
+		if got.Name != want.Name || got.Version != want.Version {
+			t.Errorf("Expected %s@%s, got %s@%s", want.Name, want.Version, got.Name, got.Version)
+		}
+		if got.Direct != want.Direct {
+			t.Errorf("Expected %s Direct = %v, got %v", want.Name, want.Direct, got.Direct)
+		}
+		if got.Dev != want.Dev {
+			t.Errorf("Expected %s Dev = %v, got %v", want.Name, want.Dev, got.Dev)
+		}
+		if got.Ecosystem != common.EcosystemNpm {
+			t.Errorf("Expected ecosystem 'npm', got '%s'", got.Ecosystem)
+		}
+	}
+}
+
+func TestNpmParser_ParsePnpmLock_V6(t *testing.T) {
+	ctx := context.Background()
+	parser := NewParser()
+
+	tmpDir := t.TempDir()
+	lockFile := filepath.Join(tmpDir, "pnpm-lock.yaml")
+
+	content := `lockfileVersion: '6.0'
+
+dependencies:
+  lodash:
+    specifier: ^4.17.0
+    version: 4.17.21
+
+devDependencies:
+  jest:
+    specifier: 29.0.0
+    version: 29.0.0(@types/node@20.0.0)
+
+packages:
+
+  /lodash@4.17.21:
+    resolution: {integrity: sha512-abc}
+    dev: false
+
+  /jest@29.0.0(@types/node@20.0.0):
+    resolution: {integrity: sha512-def}
+    dev: true
+
+  /@babel/core@7.28.6:
+    resolution: {integrity: sha512-ghi}
+    dev: true
+`
+
+	if err := os.WriteFile(lockFile, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to create temp file: %v", err)
+	}
+
+	packages, err := parser.Parse(ctx, lockFile)
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+
+	if len(packages) != 3 {
+		t.Fatalf("Expected 3 packages, got %d", len(packages))
+	}
+
+	// Packages come back in the order the lock file lists them
+	for i, name := range []string{"lodash", "jest", "@babel/core"} {
+		if packages[i].Name != name {
+			t.Errorf("Expected package %d to be %s, got %s", i, name, packages[i].Name)
+		}
+	}
+
+	byName := make(map[string]common.PackageInfo)
+	for _, pkg := range packages {
+		byName[pkg.Name] = pkg
+	}
+
+	tests := []struct {
+		name    string
+		version string
+		direct  bool
+		dev     bool
+	}{
+		{"lodash", "4.17.21", true, false},
+		{"jest", "29.0.0", true, true},
+		{"@babel/core", "7.28.6", false, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pkg, ok := byName[tt.name]
+			if !ok {
+				t.Fatalf("Expected to find %s in parsed packages", tt.name)
+			}
+			if pkg.Version != tt.version {
+				t.Errorf("Expected version '%s', got '%s'", tt.version, pkg.Version)
+			}
+			if pkg.Direct != tt.direct {
+				t.Errorf("Expected Direct = %v, got %v", tt.direct, pkg.Direct)
+			}
+			if pkg.Dev != tt.dev {
+				t.Errorf("Expected Dev = %v, got %v", tt.dev, pkg.Dev)
+			}
+		})
+	}
+}
+
+func TestSplitPnpmPackageKey(t *testing.T) {
+	tests := []struct {
+		key             string
+		expectedName    string
+		expectedVersion string
+	}{
+		{"/lodash@4.17.21", "lodash", "4.17.21"},
+		{"lodash@4.17.21", "lodash", "4.17.21"},
+		{"/@babel/core@7.0.0", "@babel/core", "7.0.0"},
+		{"@babel/core@7.0.0", "@babel/core", "7.0.0"},
+		{"jest@29.0.0(@types/node@20.0.0)", "jest", "29.0.0"},
+		{"invalid", "", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.key, func(t *testing.T) {
+			name, version := splitPnpmPackageKey(tt.key)
+			if name != tt.expectedName || version != tt.expectedVersion {
+				t.Errorf("Expected '%s' '%s', got '%s' '%s'", tt.expectedName, tt.expectedVersion, name, version)
+			}
+		})
+	}
+}
+
 func TestExtractPackageName(t *testing.T) {
 	tests := []struct {
 		pkgPath  string
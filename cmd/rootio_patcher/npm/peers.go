@@ -0,0 +1,363 @@
+package npm
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"rootio_patcher/cmd/rootio_patcher/common"
+	"rootio_patcher/pkg/rootio"
+)
+
+// peerRange is a peerDependencies entry of an installed package
+type peerRange struct {
+	Dependent string // Package declaring the peer dependency
+	Version   string // Installed version of the dependent
+	Peer      string // Package the dependent expects next to it
+	Range     string // Semver range the dependent accepts for the peer
+}
+
+// peerConflict is a patch whose override falls outside a dependent's peer range
+type peerConflict struct {
+	Patch   rootio.PackagePatch
+	Target  string // Version the override installs
+	Depends peerRange
+}
+
+// readPeerRanges returns the peerDependencies declared by the packages of a package-lock.json,
+// ordered by dependent. Lock files of other package managers don't record them.
+func readPeerRanges(lockFilePath string) ([]peerRange, error) {
+	content, err := os.ReadFile(lockFilePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read lock file: %w", err)
+	}
+	var lockfile PackageLockJSON
+	if err := json.Unmarshal(content, &lockfile); err != nil {
+		return nil, fmt.Errorf("failed to parse lock file: %w", err)
+	}
+
+	var peers []peerRange
+	for pkgPath, entry := range lockfile.Packages {
+		if !strings.Contains(pkgPath, "node_modules/") {
+			continue
+		}
+		for peer, rng := range entry.PeerDependencies {
+			peers = append(peers, peerRange{
+				Dependent: extractPackageName(pkgPath),
+				Version:   entry.Version,
+				Peer:      peer,
+				Range:     rng,
+			})
+		}
+	}
+	sort.Slice(peers, func(i, j int) bool {
+		if peers[i].Dependent != peers[j].Dependent {
+			return peers[i].Dependent < peers[j].Dependent
+		}
+		if peers[i].Version != peers[j].Version {
+			return peers[i].Version < peers[j].Version
+		}
+		return peers[i].Peer < peers[j].Peer
+	})
+	return peers, nil
+}
+
+// peerConflicts returns the patches whose override version is outside a range a dependent
+// declares for the package in peerDependencies. Ranges the current version already violates
+// and ranges that can't be parsed (tags, URLs) are left alone.
+func peerConflicts(patches []rootio.PackagePatch, useAlias bool, peers []peerRange) []peerConflict {
+	var conflicts []peerConflict
+	for _, patch := range patches {
+		target := common.PatchTarget(patch, useAlias).Version
+		for _, peer := range peers {
+			if peer.Peer != patch.PackageName {
+				continue
+			}
+			current, ok := satisfiesRange(patch.Version, peer.Range)
+			if !ok || !current {
+				continue
+			}
+			if patched, _ := satisfiesRange(target, peer.Range); !patched {
+				conflicts = append(conflicts, peerConflict{Patch: patch, Target: target, Depends: peer})
+			}
+		}
+	}
+	return conflicts
+}
+
+// checkPeers warns about patches whose override falls outside a dependent's peerDependencies
+// range, which npm install rejects. With StrictPeers those patches are skipped instead.
+func (a *App) checkPeers(
+	ctx context.Context, patches []rootio.PackagePatch,
+) ([]rootio.PackagePatch, []rootio.SkippedPackage) {
+	if !strings.HasSuffix(a.lockFilePath, "package-lock.json") {
+		return patches, nil
+	}
+	peers, err := readPeerRanges(a.lockFilePath)
+	if err != nil {
+		a.logger.WarnContext(ctx, "Failed to read peer dependencies", slog.String("error", err.Error()))
+		return patches, nil
+	}
+
+	conflicts := peerConflicts(patches, a.useAlias(), peers)
+	if len(conflicts) == 0 {
+		return patches, nil
+	}
+
+	if !a.options.StrictPeers {
+		fmt.Fprintln(a.out, "\nWarning: these overrides fall outside a dependent's peerDependencies and npm install may reject them:")
+		for _, c := range conflicts {
+			fmt.Fprintf(a.out, "  %s %s → %s: %s@%s requires %s\n",
+				c.Patch.PackageName, c.Patch.Version, c.Target, c.Depends.Dependent, c.Depends.Version, c.Depends.Range)
+		}
+		fmt.Fprintln(a.out, "Use --strict to skip these patches instead")
+		return patches, nil
+	}
+
+	reasons := make(map[string]string)
+	for _, c := range conflicts {
+		if _, ok := reasons[c.Patch.PackageName+"@"+c.Patch.Version]; !ok {
+			reasons[c.Patch.PackageName+"@"+c.Patch.Version] = fmt.Sprintf(
+				"override %s is outside the peerDependencies range %s of %s@%s (--strict)",
+				c.Target, c.Depends.Range, c.Depends.Dependent, c.Depends.Version)
+		}
+	}
+	var kept []rootio.PackagePatch
+	var skipped []rootio.SkippedPackage
+	for _, patch := range patches {
+		reason, ok := reasons[patch.PackageName+"@"+patch.Version]
+		if !ok {
+			kept = append(kept, patch)
+			continue
+		}
+		skipped = append(skipped, rootio.SkippedPackage{PackageName: patch.PackageName, Reason: reason})
+	}
+	return kept, skipped
+}
+
+// comparator is one bound of an npm semver range
+type comparator struct {
+	op      string // <, <=, >, >= or =
+	version [3]int
+}
+
+// satisfiesRange reports whether version is within an npm semver range, such as ^1.2.0,
+// ">=2 <4" or "1.x || 2.x". Prerelease and build suffixes are ignored on both sides, since
+// Root.io's patched versions carry a suffix (4.17.21-root.1) that marks a rebuild of the
+// release. ok is false when the version or the range can't be parsed.
+func satisfiesRange(version, rng string) (satisfied, ok bool) {
+	parts, ok := parsePartial(version)
+	if !ok || len(parts) != 3 {
+		return false, false
+	}
+	v := [3]int{parts[0], parts[1], parts[2]}
+
+	for _, set := range strings.Split(rng, "||") {
+		comparators, ok := parseComparatorSet(set)
+		if !ok {
+			return false, false
+		}
+		if matchesAll(v, comparators) {
+			satisfied = true
+		}
+	}
+	return satisfied, true
+}
+
+// matchesAll reports whether v satisfies every comparator of a set
+func matchesAll(v [3]int, comparators []comparator) bool {
+	for _, c := range comparators {
+		cmp := compareTriples(v, c.version)
+		var ok bool
+		switch c.op {
+		case "<":
+			ok = cmp < 0
+		case "<=":
+			ok = cmp <= 0
+		case ">":
+			ok = cmp > 0
+		case ">=":
+			ok = cmp >= 0
+		default:
+			ok = cmp == 0
+		}
+		if !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// parseComparatorSet turns the space-separated comparators of a range, or a hyphen range
+// (1.2 - 2.3.4), into plain comparators. An empty set matches every version.
+func parseComparatorSet(set string) ([]comparator, bool) {
+	set = strings.TrimSpace(set)
+	if lo, hi, ok := strings.Cut(set, " - "); ok {
+		low, ok := parsePartial(strings.TrimSpace(lo))
+		if !ok {
+			return nil, false
+		}
+		high, ok := parsePartial(strings.TrimSpace(hi))
+		if !ok {
+			return nil, false
+		}
+		comparators := []comparator{{">=", fill(low)}}
+		switch {
+		case len(high) == 3:
+			comparators = append(comparators, comparator{"<=", fill(high)})
+		case len(high) > 0:
+			comparators = append(comparators, comparator{"<", increment(high)})
+		}
+		return comparators, true
+	}
+
+	// Join operators written apart from their version, as in ">= 1.2.3"
+	var tokens []string
+	pending := ""
+	for _, field := range strings.Fields(set) {
+		if strings.Trim(field, "<>=~^") == "" {
+			pending += field
+			continue
+		}
+		tokens = append(tokens, pending+field)
+		pending = ""
+	}
+	if pending != "" {
+		return nil, false
+	}
+
+	var comparators []comparator
+	for _, token := range tokens {
+		desugared, ok := parseComparator(token)
+		if !ok {
+			return nil, false
+		}
+		comparators = append(comparators, desugared...)
+	}
+	return comparators, true
+}
+
+// parseComparator turns one comparator, which may be a caret, tilde or x-range, into the
+// bounds it stands for
+func parseComparator(token string) ([]comparator, bool) {
+	op := ""
+	for _, prefix := range []string{">=", "<=", "~>", ">", "<", "=", "^", "~"} {
+		if rest, ok := strings.CutPrefix(token, prefix); ok {
+			op, token = prefix, rest
+			break
+		}
+	}
+	parts, ok := parsePartial(token)
+	if !ok {
+		return nil, false
+	}
+	low := fill(parts)
+
+	// A bare * (or >=*) matches everything; <* and >* match nothing
+	if len(parts) == 0 {
+		switch op {
+		case "<", ">":
+			return []comparator{{"<", [3]int{}}}, true
+		default:
+			return nil, true
+		}
+	}
+
+	switch op {
+	case "", "=":
+		if len(parts) == 3 {
+			return []comparator{{"=", low}}, true
+		}
+		return []comparator{{">=", low}, {"<", increment(parts)}}, true
+	case "^":
+		// Allow changes that don't modify the left-most non-zero part
+		upper := increment(parts[:1])
+		switch {
+		case parts[0] == 0 && len(parts) == 2:
+			upper = increment(parts[:2])
+		case parts[0] == 0 && len(parts) == 3 && parts[1] > 0:
+			upper = increment(parts[:2])
+		case parts[0] == 0 && len(parts) == 3:
+			upper = increment(parts)
+		}
+		return []comparator{{">=", low}, {"<", upper}}, true
+	case "~", "~>":
+		if len(parts) == 1 {
+			return []comparator{{">=", low}, {"<", increment(parts)}}, true
+		}
+		return []comparator{{">=", low}, {"<", increment(parts[:2])}}, true
+	case ">":
+		if len(parts) == 3 {
+			return []comparator{{">", low}}, true
+		}
+		return []comparator{{">=", increment(parts)}}, true
+	case "<=":
+		if len(parts) == 3 {
+			return []comparator{{"<=", low}}, true
+		}
+		return []comparator{{"<", increment(parts)}}, true
+	default:
+		return []comparator{{op, low}}, true
+	}
+}
+
+// parsePartial parses a version that may leave out trailing parts or use x or * for them
+// (1, 1.2, 1.x, 1.2.*). Only the given numeric parts are returned; a leading v, prerelease and
+// build metadata are dropped.
+func parsePartial(version string) ([]int, bool) {
+	version = strings.TrimPrefix(strings.TrimPrefix(version, "="), "v")
+	version, _, _ = strings.Cut(version, "+")
+	version, _, _ = strings.Cut(version, "-")
+	if version == "" {
+		return nil, false
+	}
+
+	var parts []int
+	for i, field := range strings.Split(version, ".") {
+		if i >= 3 {
+			return nil, false
+		}
+		if field == "x" || field == "X" || field == "*" {
+			return parts, true
+		}
+		n, err := strconv.Atoi(field)
+		if err != nil || n < 0 {
+			return nil, false
+		}
+		parts = append(parts, n)
+	}
+	return parts, true
+}
+
+// fill pads a partial version with zeros
+func fill(parts []int) [3]int {
+	var v [3]int
+	copy(v[:], parts)
+	return v
+}
+
+// increment returns the smallest version above every version matching a partial version,
+// e.g. 1.2 → 1.3.0
+func increment(parts []int) [3]int {
+	v := fill(parts)
+	v[len(parts)-1]++
+	return v
+}
+
+// compareTriples compares two major.minor.patch versions
+func compareTriples(a, b [3]int) int {
+	for i := range a {
+		if a[i] != b[i] {
+			if a[i] < b[i] {
+				return -1
+			}
+			return 1
+		}
+	}
+	return 0
+}
@@ -0,0 +1,170 @@
+package npm
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"rootio_patcher/cmd/rootio_patcher/common"
+	"rootio_patcher/pkg/rootio"
+)
+
+func TestSatisfiesRange(t *testing.T) {
+	tests := []struct {
+		version, rng string
+		want         bool
+	}{
+		{"16.8.0", "^16.8.0", true},
+		{"17.0.0", "^16.8.0", false},
+		{"0.2.5", "^0.2.3", true},
+		{"0.3.0", "^0.2.3", false},
+		{"0.0.4", "^0.0.3", false},
+		{"1.2.9", "~1.2.3", true},
+		{"1.3.0", "~1.2.3", false},
+		{"1.9.0", "~1", true},
+		{"2.1.0", "1.x || 2.x", true},
+		{"3.0.0", "1.x || 2.x", false},
+		{"3.5.0", ">=2 <4", true},
+		{"4.0.0", ">= 2 < 4", false},
+		{"2.3.4", "1.2 - 2.3.4", true},
+		{"2.3.5", "1.2 - 2.3.4", false},
+		{"2.9.9", "1.2 - 2", true},
+		{"5.0.0", "*", true},
+		{"5.0.0", "", true},
+		{"1.2.3", "1.2.3", true},
+		{"1.2.4", "=1.2.3", false},
+		{"1.3.0", ">1.2", true},
+		{"1.2.9", ">1.2", false},
+		{"1.2.9", "<=1.2", true},
+		{"4.17.21-root.1", "^4.17.0", true},
+		{"v2.0.0", "^2.0.0", true},
+	}
+	for _, tt := range tests {
+		got, ok := satisfiesRange(tt.version, tt.rng)
+		if !ok {
+			t.Errorf("satisfiesRange(%q, %q) failed to parse", tt.version, tt.rng)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("satisfiesRange(%q, %q) = %v, want %v", tt.version, tt.rng, got, tt.want)
+		}
+	}
+
+	for _, rng := range []string{"latest", "npm:react@18", "file:../react", ">="} {
+		if _, ok := satisfiesRange("1.0.0", rng); ok {
+			t.Errorf("Expected range %q to be rejected", rng)
+		}
+	}
+}
+
+func TestPeerConflicts(t *testing.T) {
+	peers := []peerRange{
+		{Dependent: "react-dom", Version: "16.14.0", Peer: "react", Range: "^16.14.0"},
+		{Dependent: "old-plugin", Version: "1.0.0", Peer: "react", Range: "^15.0.0"},
+		{Dependent: "tagged", Version: "1.0.0", Peer: "react", Range: "latest"},
+	}
+	patches := []rootio.PackagePatch{
+		{PackageName: "react", Version: "16.14.0", Patch: rootio.PatchInfo{Name: "react", Version: "17.0.2"}},
+		{PackageName: "lodash", Version: "4.17.20", Patch: rootio.PatchInfo{Name: "lodash", Version: "4.17.21"}},
+	}
+
+	conflicts := peerConflicts(patches, false, peers)
+	if len(conflicts) != 1 {
+		t.Fatalf("Expected 1 conflict, got %+v", conflicts)
+	}
+	// old-plugin's range already excludes the installed react, so only react-dom conflicts
+	if c := conflicts[0]; c.Depends.Dependent != "react-dom" || c.Target != "17.0.2" {
+		t.Errorf("Expected react-dom to conflict with react 17.0.2, got %+v", c)
+	}
+}
+
+// writePeerLockfile creates a package-lock.json where react-dom requires react ^16.14.0
+func writePeerLockfile(t *testing.T, dir string) string {
+	t.Helper()
+	lockFile := filepath.Join(dir, "package-lock.json")
+	content := `{
+  "lockfileVersion": 3,
+  "packages": {
+    "": {"dependencies": {"react": "^16.14.0", "react-dom": "^16.14.0"}},
+    "node_modules/react": {"version": "16.14.0"},
+    "node_modules/react-dom": {"version": "16.14.0", "peerDependencies": {"react": "^16.14.0"}}
+  }
+}`
+	if err := os.WriteFile(lockFile, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to create package-lock.json: %v", err)
+	}
+	return lockFile
+}
+
+// reactPatchClient returns a patch moving react outside react-dom's peer range
+func reactPatchClient() *MockAPIClient {
+	return &MockAPIClient{
+		AnalyzePackagesFunc: func(ctx context.Context, packages []rootio.Package) (*rootio.AnalyzePackagesResponse, error) {
+			return &rootio.AnalyzePackagesResponse{
+				Patches: []rootio.PackagePatch{
+					{PackageName: "react", Version: "16.14.0", Patch: rootio.PatchInfo{Name: "react", Version: "17.0.2"}},
+				},
+			}, nil
+		},
+	}
+}
+
+func TestNpmApp_Run_WarnsAboutPeerConflicts(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	tmpDir := t.TempDir()
+	writePackageJSON(t, tmpDir)
+	lockFile := writePeerLockfile(t, tmpDir)
+
+	var buf bytes.Buffer
+	app := NewAppWithServices("test-key", "https://api.root.io", lockFile, true, logger,
+		NewParser(), reactPatchClient(), common.WithUseAlias(false), common.WithOutput(&buf))
+	result, err := app.RunWithResult(context.Background())
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	output := buf.String()
+	for _, want := range []string{"peerDependencies", "react 16.14.0 → 17.0.2: react-dom@16.14.0 requires ^16.14.0"} {
+		if !strings.Contains(output, want) {
+			t.Errorf("Expected %q in the output, got:\n%s", want, output)
+		}
+	}
+	if len(result.Patches) != 1 {
+		t.Errorf("Expected the patch to be kept without --strict, got %+v", result.Patches)
+	}
+}
+
+func TestNpmApp_Run_StrictSkipsPeerConflicts(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	tmpDir := t.TempDir()
+	writePackageJSON(t, tmpDir)
+	lockFile := writePeerLockfile(t, tmpDir)
+
+	var buf bytes.Buffer
+	app := NewAppWithServices("test-key", "https://api.root.io", lockFile, false, logger,
+		NewParser(), reactPatchClient(), common.WithUseAlias(false), common.WithStrictPeers(true),
+		common.WithOutput(&buf))
+	result, err := app.RunWithResult(context.Background())
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	if len(result.Patches) != 0 {
+		t.Errorf("Expected the conflicting patch to be skipped, got %+v", result.Patches)
+	}
+	if len(result.Skipped) != 1 || !strings.Contains(result.Skipped[0].Reason, "peerDependencies range ^16.14.0 of react-dom@16.14.0") {
+		t.Errorf("Expected react to be skipped for react-dom's peer range, got %+v", result.Skipped)
+	}
+	content, err := os.ReadFile(filepath.Join(tmpDir, "package.json"))
+	if err != nil {
+		t.Fatalf("Failed to read package.json: %v", err)
+	}
+	if strings.Contains(string(content), "overrides") {
+		t.Errorf("Expected package.json to be left alone, got:\n%s", content)
+	}
+}
@@ -0,0 +1,195 @@
+package npm
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"rootio_patcher/cmd/rootio_patcher/common"
+	"rootio_patcher/pkg/rootio"
+)
+
+// registryScopes returns the scopes of the packages the patches install, such as "@rootio" for
+// aliases. Plain version bumps of unscoped packages need no scope registry.
+func registryScopes(patches []rootio.PackagePatch, useAlias bool) []string {
+	var scopes []string
+	seen := make(map[string]bool)
+	for _, patch := range patches {
+		target := common.PatchTarget(patch, useAlias)
+		if !common.ReplacesPackage(patch, target) {
+			continue
+		}
+		scope, _, ok := strings.Cut(target.Name, "/")
+		if !ok || !strings.HasPrefix(scope, "@") || seen[scope] {
+			continue
+		}
+		seen[scope] = true
+		scopes = append(scopes, scope)
+	}
+	sort.Strings(scopes)
+	return scopes
+}
+
+// registryConfigPath returns the file holding scope registries next to the root package.json:
+// .yarnrc.yml for Yarn Berry, and .npmrc for npm, pnpm and yarn v1, which all read it
+func (a *App) registryConfigPath() string {
+	name := ".npmrc"
+	if a.yarnBerry {
+		name = ".yarnrc.yml"
+	}
+	return filepath.Join(filepath.Dir(a.packageJSON), name)
+}
+
+// renderRegistryConfig returns the current registry config and its content with the patched
+// packages' scopes pointed at the configured registry. A missing config file reads as empty.
+func (a *App) renderRegistryConfig(patches []rootio.PackagePatch) ([]byte, []byte, error) {
+	path := a.registryConfigPath()
+	content, err := os.ReadFile(path)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+
+	scopes := registryScopes(patches, a.useAlias())
+	if a.yarnBerry {
+		updated, err := setYarnrcScopes(content, scopes, a.options.Registry)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to update %s: %w", path, err)
+		}
+		return content, updated, nil
+	}
+	return content, setNpmrcScopes(content, scopes, a.options.Registry), nil
+}
+
+// updateRegistryConfig points the patched packages' scopes at the configured registry
+func (a *App) updateRegistryConfig(ctx context.Context, patches []rootio.PackagePatch) error {
+	path := a.registryConfigPath()
+	original, updated, err := a.renderRegistryConfig(patches)
+	if err != nil {
+		return err
+	}
+	if bytes.Equal(original, updated) {
+		return nil
+	}
+
+	if a.options.Backup && original != nil {
+		backupPath, err := common.BackupFile(path)
+		if err != nil {
+			return fmt.Errorf("failed to back up %s: %w", path, err)
+		}
+		a.logger.InfoContext(ctx, "Backed up file before patching",
+			slog.String("file", path),
+			slog.String("backup", backupPath))
+	}
+
+	if err := os.WriteFile(path, updated, 0644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	return nil
+}
+
+// setNpmrcScopes sets "<scope>:registry=<registry>" for each scope in .npmrc content, replacing
+// existing registries for those scopes and keeping every other line
+func setNpmrcScopes(content []byte, scopes []string, registry string) []byte {
+	text := strings.TrimSuffix(string(content), "\n")
+	var lines []string
+	if text != "" {
+		lines = strings.Split(text, "\n")
+	}
+
+	for _, scope := range scopes {
+		key := scope + ":registry"
+		entry := key + "=" + registry
+
+		found := false
+		for i, line := range lines {
+			name, _, ok := strings.Cut(line, "=")
+			if ok && strings.TrimSpace(name) == key {
+				lines[i] = entry
+				found = true
+			}
+		}
+		if !found {
+			lines = append(lines, entry)
+		}
+	}
+
+	return []byte(strings.Join(lines, "\n") + "\n")
+}
+
+// setYarnrcScopes sets npmScopes.<scope>.npmRegistryServer for each scope in .yarnrc.yml
+// content, keeping the other settings and their comments
+func setYarnrcScopes(content []byte, scopes []string, registry string) ([]byte, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(content, &doc); err != nil {
+		return nil, err
+	}
+	if doc.Kind == 0 {
+		doc = yaml.Node{Kind: yaml.DocumentNode, Content: []*yaml.Node{{Kind: yaml.MappingNode}}}
+	}
+	root := doc.Content[0]
+	if root.Kind != yaml.MappingNode {
+		return nil, fmt.Errorf("expected a YAML mapping")
+	}
+
+	npmScopes, err := yamlMapping(root, "npmScopes")
+	if err != nil {
+		return nil, err
+	}
+	for _, scope := range scopes {
+		scopeConfig, err := yamlMapping(npmScopes, strings.TrimPrefix(scope, "@"))
+		if err != nil {
+			return nil, err
+		}
+		setYAMLString(scopeConfig, "npmRegistryServer", registry)
+	}
+
+	var b bytes.Buffer
+	encoder := yaml.NewEncoder(&b)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(&doc); err != nil {
+		return nil, err
+	}
+	if err := encoder.Close(); err != nil {
+		return nil, err
+	}
+	return b.Bytes(), nil
+}
+
+// yamlMapping returns the mapping stored under key in mapping, adding an empty one if missing
+func yamlMapping(mapping *yaml.Node, key string) (*yaml.Node, error) {
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		if mapping.Content[i].Value != key {
+			continue
+		}
+		value := mapping.Content[i+1]
+		if value.Kind != yaml.MappingNode {
+			return nil, fmt.Errorf("expected %s to be a mapping", key)
+		}
+		return value, nil
+	}
+
+	value := &yaml.Node{Kind: yaml.MappingNode}
+	mapping.Content = append(mapping.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: key}, value)
+	return value, nil
+}
+
+// setYAMLString stores a string value under key in mapping
+func setYAMLString(mapping *yaml.Node, key, value string) {
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		if mapping.Content[i].Value == key {
+			mapping.Content[i+1] = &yaml.Node{Kind: yaml.ScalarNode, Value: value}
+			return
+		}
+	}
+	mapping.Content = append(mapping.Content,
+		&yaml.Node{Kind: yaml.ScalarNode, Value: key},
+		&yaml.Node{Kind: yaml.ScalarNode, Value: value})
+}
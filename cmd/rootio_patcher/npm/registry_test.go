@@ -0,0 +1,166 @@
+package npm
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"rootio_patcher/cmd/rootio_patcher/common"
+	"rootio_patcher/pkg/rootio"
+)
+
+func TestRegistryScopes(t *testing.T) {
+	patches := []rootio.PackagePatch{
+		{PackageName: "lodash", PatchAlias: rootio.PatchInfo{Name: "@rootio/lodash"}},
+		{PackageName: "express", PatchAlias: rootio.PatchInfo{Name: "@rootio/express"}},
+		{PackageName: "left-pad", PatchAlias: rootio.PatchInfo{Name: "left-pad"}},
+	}
+
+	scopes := registryScopes(patches, true)
+	if len(scopes) != 1 || scopes[0] != "@rootio" {
+		t.Errorf("Expected [@rootio], got %v", scopes)
+	}
+}
+
+func TestSetNpmrcScopes(t *testing.T) {
+	content := "# team settings\nregistry=https://registry.npmjs.org/\n@rootio:registry = https://old.example.com/\n"
+
+	updated := string(setNpmrcScopes([]byte(content), []string{"@rootio"}, "https://npm.example.com/"))
+	expected := "# team settings\nregistry=https://registry.npmjs.org/\n@rootio:registry=https://npm.example.com/\n"
+	if updated != expected {
+		t.Errorf("Expected:\n%s\nGot:\n%s", expected, updated)
+	}
+
+	updated = string(setNpmrcScopes(nil, []string{"@rootio"}, "https://npm.example.com/"))
+	if updated != "@rootio:registry=https://npm.example.com/\n" {
+		t.Errorf("Expected a new scope registry line, got %q", updated)
+	}
+}
+
+func TestSetYarnrcScopes(t *testing.T) {
+	content := "# Berry settings\nnodeLinker: node-modules\nnpmScopes:\n  acme:\n    npmRegistryServer: https://acme.example.com\n"
+
+	updated, err := setYarnrcScopes([]byte(content), []string{"@rootio"}, "https://npm.example.com/")
+	if err != nil {
+		t.Fatalf("setYarnrcScopes failed: %v", err)
+	}
+	expected := "# Berry settings\nnodeLinker: node-modules\nnpmScopes:\n  acme:\n    npmRegistryServer: https://acme.example.com\n" +
+		"  rootio:\n    npmRegistryServer: https://npm.example.com/\n"
+	if string(updated) != expected {
+		t.Errorf("Expected:\n%s\nGot:\n%s", expected, updated)
+	}
+
+	if _, err := setYarnrcScopes([]byte("npmScopes: none\n"), []string{"@rootio"}, "https://npm.example.com/"); err == nil {
+		t.Error("Expected an error when npmScopes isn't a mapping")
+	}
+}
+
+// TestNpmApp_Registry tests the registry config written for each package manager
+func TestNpmApp_Registry(t *testing.T) {
+	tests := []struct {
+		name           string
+		packageManager string
+		lockFile       string
+		lockContent    string
+		configFile     string
+		expected       string
+	}{
+		{
+			name:           "npm",
+			packageManager: "npm",
+			lockFile:       "package-lock.json",
+			lockContent:    `{"lockfileVersion": 3, "packages": {}}`,
+			configFile:     ".npmrc",
+			expected:       "@rootio:registry=https://npm.example.com/\n",
+		},
+		{
+			name:           "pnpm",
+			packageManager: "pnpm",
+			lockFile:       "pnpm-lock.yaml",
+			lockContent:    "lockfileVersion: '9.0'\n",
+			configFile:     ".npmrc",
+			expected:       "@rootio:registry=https://npm.example.com/\n",
+		},
+		{
+			name:           "yarn v1",
+			packageManager: "yarn",
+			lockFile:       "yarn.lock",
+			lockContent:    "# yarn lockfile v1\n",
+			configFile:     ".npmrc",
+			expected:       "@rootio:registry=https://npm.example.com/\n",
+		},
+		{
+			name:           "yarn berry",
+			packageManager: "yarn",
+			lockFile:       "yarn.lock",
+			lockContent:    "__metadata:\n  version: 8\n",
+			configFile:     ".yarnrc.yml",
+			expected:       "npmScopes:\n  rootio:\n    npmRegistryServer: https://npm.example.com/\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tmpDir := t.TempDir()
+			packageJSON := filepath.Join(tmpDir, "package.json")
+			if err := os.WriteFile(packageJSON, []byte(`{"name": "test-project"}`), 0644); err != nil {
+				t.Fatalf("Failed to create package.json: %v", err)
+			}
+			if err := os.WriteFile(filepath.Join(tmpDir, tt.lockFile), []byte(tt.lockContent), 0644); err != nil {
+				t.Fatalf("Failed to create lock file: %v", err)
+			}
+
+			app := NewAppWithServices(
+				"test-key",
+				"https://api.root.io",
+				tt.packageManager,
+				false, // not dry-run
+				slog.New(slog.NewTextHandler(io.Discard, nil)),
+				&MockParser{
+					ParseFunc: func(ctx context.Context, filePath string) ([]common.PackageInfo, error) {
+						return []common.PackageInfo{{Name: "lodash", Version: "4.17.20"}}, nil
+					},
+				},
+				&MockAPIClient{
+					AnalyzePackagesFunc: func(ctx context.Context, packages []rootio.Package) (*rootio.AnalyzePackagesResponse, error) {
+						return &rootio.AnalyzePackagesResponse{
+							Patches: []rootio.PackagePatch{{
+								PackageName: "lodash",
+								Version:     "4.17.20",
+								Patch:       rootio.PatchInfo{Name: "lodash", Version: "4.17.21"},
+								PatchAlias:  rootio.PatchInfo{Name: "@rootio/lodash", Version: "4.17.21"},
+							}},
+						}, nil
+					},
+				},
+				common.WithPackageJSON(packageJSON),
+				common.WithRegistry("https://npm.example.com/"),
+			)
+
+			if err := app.Run(context.Background()); err != nil {
+				t.Fatalf("App run failed: %v", err)
+			}
+
+			config, err := os.ReadFile(filepath.Join(tmpDir, tt.configFile))
+			if err != nil {
+				t.Fatalf("Expected %s to be written: %v", tt.configFile, err)
+			}
+			if string(config) != tt.expected {
+				t.Errorf("Expected %s:\n%s\nGot:\n%s", tt.configFile, tt.expected, config)
+			}
+
+			// The override itself stays a plain alias; the registry comes from the scope config
+			updated, err := os.ReadFile(packageJSON)
+			if err != nil {
+				t.Fatalf("Failed to read package.json: %v", err)
+			}
+			if !strings.Contains(string(updated), `"lodash": "npm:@rootio/lodash@4.17.21"`) {
+				t.Errorf("Expected the lodash override in package.json, got:\n%s", updated)
+			}
+		})
+	}
+}
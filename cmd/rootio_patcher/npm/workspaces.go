@@ -0,0 +1,149 @@
+package npm
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// pnpmWorkspaceFile lists pnpm workspace packages instead of the package.json "workspaces" field
+const pnpmWorkspaceFile = "pnpm-workspace.yaml"
+
+// workspacePatterns returns the workspace globs declared next to a package.json, if any.
+// npm and yarn use the "workspaces" field (an array, or an object with "packages");
+// pnpm uses pnpm-workspace.yaml.
+func workspacePatterns(packageJSONPath string) ([]string, error) {
+	content, err := os.ReadFile(packageJSONPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", packageJSONPath, err)
+	}
+
+	var manifest struct {
+		Workspaces json.RawMessage `json:"workspaces"`
+	}
+	if err := json.Unmarshal(content, &manifest); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", packageJSONPath, err)
+	}
+
+	if len(manifest.Workspaces) > 0 {
+		var patterns []string
+		if err := json.Unmarshal(manifest.Workspaces, &patterns); err == nil {
+			return patterns, nil
+		}
+
+		var workspaces struct {
+			Packages []string `json:"packages"`
+		}
+		if err := json.Unmarshal(manifest.Workspaces, &workspaces); err != nil {
+			return nil, fmt.Errorf("failed to parse workspaces in %s: %w", packageJSONPath, err)
+		}
+		return workspaces.Packages, nil
+	}
+
+	pnpmPath := filepath.Join(filepath.Dir(packageJSONPath), pnpmWorkspaceFile)
+	pnpmContent, err := os.ReadFile(pnpmPath)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", pnpmPath, err)
+	}
+
+	var pnpmWorkspace struct {
+		Packages []string `yaml:"packages"`
+	}
+	if err := yaml.Unmarshal(pnpmContent, &pnpmWorkspace); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", pnpmPath, err)
+	}
+	return pnpmWorkspace.Packages, nil
+}
+
+// ResolveWorkspaces returns the package.json of every workspace declared by a root package.json,
+// sorted by path. Negated patterns ("!packages/legacy") exclude workspaces.
+func ResolveWorkspaces(rootPackageJSON string) ([]string, error) {
+	patterns, err := workspacePatterns(rootPackageJSON)
+	if err != nil {
+		return nil, err
+	}
+
+	rootDir := filepath.Dir(rootPackageJSON)
+	found := make(map[string]bool)
+	var excluded []string
+
+	for _, pattern := range patterns {
+		if negated, ok := strings.CutPrefix(pattern, "!"); ok {
+			excluded = append(excluded, filepath.Join(rootDir, filepath.FromSlash(negated)))
+			continue
+		}
+
+		// filepath.Glob has no "**"; treat it as a single directory level
+		pattern = strings.ReplaceAll(pattern, "**", "*")
+		matches, err := filepath.Glob(filepath.Join(rootDir, filepath.FromSlash(pattern), "package.json"))
+		if err != nil {
+			return nil, fmt.Errorf("invalid workspace pattern %q: %w", pattern, err)
+		}
+		for _, match := range matches {
+			found[match] = true
+		}
+	}
+
+	var workspaces []string
+	for match := range found {
+		dir := filepath.Dir(match)
+		if isExcluded(dir, excluded) {
+			continue
+		}
+		workspaces = append(workspaces, match)
+	}
+	sort.Strings(workspaces)
+
+	return workspaces, nil
+}
+
+// isExcluded reports whether dir matches any negated workspace pattern
+func isExcluded(dir string, excluded []string) bool {
+	for _, pattern := range excluded {
+		if matched, err := filepath.Match(pattern, dir); err == nil && matched {
+			return true
+		}
+	}
+	return false
+}
+
+// FindWorkspaceRoot returns the workspace root package.json that declares packageJSONPath as one
+// of its workspaces, or packageJSONPath itself when it isn't part of a workspace.
+// npm, yarn and pnpm only honor overrides in the workspace root.
+func FindWorkspaceRoot(packageJSONPath string) (string, error) {
+	absPath, err := filepath.Abs(packageJSONPath)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve path %s: %w", packageJSONPath, err)
+	}
+
+	for dir := filepath.Dir(filepath.Dir(absPath)); ; dir = filepath.Dir(dir) {
+		candidate := filepath.Join(dir, "package.json")
+		if _, err := os.Stat(candidate); err == nil {
+			workspaces, err := ResolveWorkspaces(candidate)
+			if err != nil {
+				return "", err
+			}
+			for _, workspace := range workspaces {
+				if workspace == absPath {
+					return candidate, nil
+				}
+			}
+		}
+
+		if parent := filepath.Dir(dir); parent == dir {
+			break
+		}
+	}
+
+	return packageJSONPath, nil
+}
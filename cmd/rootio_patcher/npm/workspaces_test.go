@@ -0,0 +1,203 @@
+package npm
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"rootio_patcher/cmd/rootio_patcher/common"
+	"rootio_patcher/pkg/rootio"
+)
+
+// writeMonorepo writes a root package.json with the given manifest and two workspace packages,
+// returning the root directory
+func writeMonorepo(t *testing.T, rootManifest string) string {
+	t.Helper()
+
+	rootDir := t.TempDir()
+	files := map[string]string{
+		"package.json":                rootManifest,
+		"packages/api/package.json":   `{"name": "api", "dependencies": {"express": "4.17.1"}}`,
+		"packages/web/package.json":   `{"name": "web", "dependencies": {"lodash": "4.17.20"}}`,
+		"tools/scripts/package.json":  `{"name": "scripts"}`,
+		"packages/README.md":          "not a workspace",
+		"packages/api/src/index.js":   "module.exports = {}",
+		"packages/web/public/app.css": "body {}",
+	}
+	for name, content := range files {
+		path := filepath.Join(rootDir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("Failed to create directory: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+	}
+	return rootDir
+}
+
+func TestResolveWorkspaces(t *testing.T) {
+	tests := []struct {
+		name     string
+		manifest string
+		expected []string
+	}{
+		{
+			name:     "array",
+			manifest: `{"workspaces": ["packages/*"]}`,
+			expected: []string{"packages/api/package.json", "packages/web/package.json"},
+		},
+		{
+			name:     "yarn object",
+			manifest: `{"workspaces": {"packages": ["packages/*", "tools/*"]}}`,
+			expected: []string{"packages/api/package.json", "packages/web/package.json", "tools/scripts/package.json"},
+		},
+		{
+			name:     "negated",
+			manifest: `{"workspaces": ["packages/*", "!packages/web"]}`,
+			expected: []string{"packages/api/package.json"},
+		},
+		{
+			name:     "no workspaces",
+			manifest: `{"name": "app"}`,
+			expected: nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rootDir := writeMonorepo(t, tt.manifest)
+
+			workspaces, err := ResolveWorkspaces(filepath.Join(rootDir, "package.json"))
+			if err != nil {
+				t.Fatalf("ResolveWorkspaces failed: %v", err)
+			}
+
+			var relative []string
+			for _, workspace := range workspaces {
+				rel, _ := filepath.Rel(rootDir, workspace)
+				relative = append(relative, filepath.ToSlash(rel))
+			}
+			if !reflect.DeepEqual(relative, tt.expected) {
+				t.Errorf("Expected %v, got %v", tt.expected, relative)
+			}
+		})
+	}
+}
+
+func TestResolveWorkspaces_Pnpm(t *testing.T) {
+	rootDir := writeMonorepo(t, `{"name": "root"}`)
+	pnpmWorkspace := "packages:\n  - 'packages/*'\n"
+	if err := os.WriteFile(filepath.Join(rootDir, "pnpm-workspace.yaml"), []byte(pnpmWorkspace), 0644); err != nil {
+		t.Fatalf("Failed to write pnpm-workspace.yaml: %v", err)
+	}
+
+	workspaces, err := ResolveWorkspaces(filepath.Join(rootDir, "package.json"))
+	if err != nil {
+		t.Fatalf("ResolveWorkspaces failed: %v", err)
+	}
+	if len(workspaces) != 2 {
+		t.Errorf("Expected 2 pnpm workspaces, got %v", workspaces)
+	}
+}
+
+func TestFindWorkspaceRoot(t *testing.T) {
+	rootDir := writeMonorepo(t, `{"workspaces": ["packages/*"]}`)
+	rootPackageJSON := filepath.Join(rootDir, "package.json")
+
+	root, err := FindWorkspaceRoot(filepath.Join(rootDir, "packages", "api", "package.json"))
+	if err != nil {
+		t.Fatalf("FindWorkspaceRoot failed: %v", err)
+	}
+	if root != rootPackageJSON {
+		t.Errorf("Expected workspace root %s, got %s", rootPackageJSON, root)
+	}
+
+	// tools/scripts isn't listed as a workspace, so it stands alone
+	standalone := filepath.Join(rootDir, "tools", "scripts", "package.json")
+	root, err = FindWorkspaceRoot(standalone)
+	if err != nil {
+		t.Fatalf("FindWorkspaceRoot failed: %v", err)
+	}
+	if root != standalone {
+		t.Errorf("Expected %s to be its own root, got %s", standalone, root)
+	}
+}
+
+func TestNpmApp_Run_Workspaces(t *testing.T) {
+	ctx := context.Background()
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+
+	rootManifest := `{"name": "monorepo", "private": true, "workspaces": ["packages/*"]}`
+	rootDir := writeMonorepo(t, rootManifest)
+	if err := os.WriteFile(filepath.Join(rootDir, "package-lock.json"), []byte(`{"lockfileVersion": 3}`), 0644); err != nil {
+		t.Fatalf("Failed to write lock file: %v", err)
+	}
+
+	mockParser := &MockParser{
+		ParseFunc: func(ctx context.Context, filePath string) ([]common.PackageInfo, error) {
+			if filePath != filepath.Join(rootDir, "package-lock.json") {
+				t.Errorf("Expected the root lock file to be parsed, got %s", filePath)
+			}
+			return []common.PackageInfo{
+				{Name: "express", Version: "4.17.1"},
+				{Name: "lodash", Version: "4.17.20"},
+			}, nil
+		},
+	}
+
+	mockAPIClient := &MockAPIClient{
+		AnalyzePackagesFunc: func(ctx context.Context, packages []rootio.Package) (*rootio.AnalyzePackagesResponse, error) {
+			return &rootio.AnalyzePackagesResponse{
+				Patches: []rootio.PackagePatch{
+					{PackageName: "express", Version: "4.17.1", PatchAlias: rootio.PatchInfo{Name: "@rootio/express", Version: "4.17.3"}},
+					{PackageName: "lodash", Version: "4.17.20", PatchAlias: rootio.PatchInfo{Name: "@rootio/lodash", Version: "4.17.21"}},
+				},
+			}, nil
+		},
+	}
+
+	// Target a workspace package; overrides must land in the root manifest
+	app := NewAppWithServices("test-key", "https://api.root.io", "npm", false, logger, mockParser, mockAPIClient,
+		common.WithPackageJSON(filepath.Join(rootDir, "packages", "web", "package.json")))
+	if err := app.Run(ctx); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	content, err := os.ReadFile(filepath.Join(rootDir, "package.json"))
+	if err != nil {
+		t.Fatalf("Failed to read root package.json: %v", err)
+	}
+	var pkgJSON map[string]interface{}
+	if err := json.Unmarshal(content, &pkgJSON); err != nil {
+		t.Fatalf("Failed to parse root package.json: %v", err)
+	}
+	overrides, ok := pkgJSON["overrides"].(map[string]interface{})
+	if !ok {
+		t.Fatalf("Expected overrides in root package.json, got:\n%s", content)
+	}
+	if overrides["express"] != "npm:@rootio/express@4.17.3" || overrides["lodash"] != "npm:@rootio/lodash@4.17.21" {
+		t.Errorf("Unexpected overrides: %v", overrides)
+	}
+	if pkgJSON["workspaces"] == nil {
+		t.Error("Expected workspaces field to be preserved")
+	}
+
+	// Workspace manifests are untouched
+	for name, expected := range map[string]string{
+		"packages/api/package.json": `{"name": "api", "dependencies": {"express": "4.17.1"}}`,
+		"packages/web/package.json": `{"name": "web", "dependencies": {"lodash": "4.17.20"}}`,
+	} {
+		content, err := os.ReadFile(filepath.Join(rootDir, filepath.FromSlash(name)))
+		if err != nil {
+			t.Fatalf("Failed to read %s: %v", name, err)
+		}
+		if string(content) != expected {
+			t.Errorf("Expected %s to be unchanged, got:\n%s", name, content)
+		}
+	}
+}
@@ -0,0 +1,315 @@
+package nuget
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"maps"
+	"os"
+	"slices"
+	"sort"
+	"strings"
+
+	"rootio_patcher/cmd/rootio_patcher/common"
+	"rootio_patcher/pkg/rootio"
+)
+
+// buildCommand restores the patched packages after the project files are updated
+const buildCommand = "dotnet restore"
+
+// App handles NuGet remediation (pre-install patching of project files and packages.lock.json)
+type App struct {
+	apiKey    string
+	apiURL    string
+	filePath  string
+	dryRun    bool
+	logger    *slog.Logger
+	parser    common.Parser
+	apiClient common.APIClient
+	reporter  *common.Reporter
+	options   common.Options
+	out       io.Writer
+
+	// locations maps lowercase package names to the file declaring their version
+	locations map[string]string
+	result    *common.RunResult
+}
+
+// NewApp creates a new NuGet application instance
+func NewApp(apiKey, apiURL, filePath string, dryRun bool, logger *slog.Logger, opts ...common.Option) *App {
+	return NewAppWithServices(
+		apiKey,
+		apiURL,
+		filePath,
+		dryRun,
+		logger,
+		NewParser(),
+		common.NewAPIClient(common.EcosystemNuGet, apiURL, apiKey, logger, opts...),
+		opts...,
+	)
+}
+
+// NewAppWithServices creates a new NuGet app with injected services (for testing)
+func NewAppWithServices(
+	apiKey, apiURL, filePath string,
+	dryRun bool,
+	logger *slog.Logger,
+	parser common.Parser,
+	apiClient common.APIClient,
+	opts ...common.Option,
+) *App {
+	options := common.NewOptions(opts...)
+	reporter := common.NewEcosystemReporter(common.EcosystemNuGet, apiURL, logger,
+		common.WithBuildFile(filePath, buildCommand), common.WithWriter(options.Output()))
+
+	return &App{
+		apiKey:    apiKey,
+		apiURL:    apiURL,
+		filePath:  filePath,
+		dryRun:    dryRun,
+		logger:    logger,
+		parser:    parser,
+		apiClient: apiClient,
+		reporter:  reporter,
+		options:   options,
+		out:       options.Output(),
+	}
+}
+
+// Result returns the structured result of the last run
+func (a *App) Result() *common.RunResult {
+	return a.result
+}
+
+// RunWithResult runs the NuGet remediation workflow and returns its structured result
+func (a *App) RunWithResult(ctx context.Context) (*common.RunResult, error) {
+	err := a.Run(ctx)
+	a.result.SetError(err)
+	return a.result, err
+}
+
+// Run executes the NuGet remediation workflow
+func (a *App) Run(ctx context.Context) error {
+	a.logger.DebugContext(ctx, "Starting NuGet remediation",
+		slog.String("file", a.filePath),
+		slog.Bool("dry_run", a.dryRun))
+	a.result = common.NewRunResult(common.EcosystemNuGet, a.filePath, a.dryRun)
+
+	// 1. Check if file exists
+	if _, err := os.Stat(a.filePath); err != nil {
+		return fmt.Errorf("file not found: %s", a.filePath)
+	}
+
+	// 2. Parse the project, packages.config or lock file
+	a.logger.DebugContext(ctx, "Parsing NuGet packages")
+	packages, err := a.parser.Parse(ctx, a.filePath)
+	if err != nil {
+		return fmt.Errorf("failed to parse %s: %w", a.filePath, err)
+	}
+	a.logger.DebugContext(ctx, "Parsed packages", slog.Int("count", len(packages)))
+	a.options.Progress.Printf("Found %d packages in %s", len(packages), a.filePath)
+	a.result.PackagesFound = len(packages)
+
+	// Leave development-only packages (PrivateAssets="all", developmentDependency) out
+	if a.options.SkipDev {
+		packages, a.result.DevSkipped = common.FilterDev(packages)
+		if a.result.DevSkipped > 0 {
+			fmt.Fprintf(a.out, "\nSkipped %d dev dependencies (--skip-dev)\n", a.result.DevSkipped)
+		}
+	}
+
+	if len(packages) == 0 {
+		fmt.Fprintf(a.out, "\nNo packages found in %s\n", a.filePath)
+		return nil
+	}
+
+	// 3. Convert to SDK format
+	a.locations = make(map[string]string, len(packages))
+	sdkPackages := make([]rootio.Package, len(packages))
+	for i, pkg := range packages {
+		sdkPackages[i] = pkg.SDKPackage()
+		a.locations[strings.ToLower(pkg.Name)] = pkg.Location
+	}
+
+	// 4. Call backend API to analyze vulnerabilities
+	a.logger.DebugContext(ctx, "Analyzing packages for vulnerabilities")
+	a.options.Progress.Printf("Analyzing %d packages for vulnerabilities...", len(sdkPackages))
+	response, err := a.apiClient.AnalyzePackages(ctx, sdkPackages)
+	if err != nil {
+		return fmt.Errorf("failed to analyze packages: %w", err)
+	}
+
+	// 5. Log analysis results
+	a.logger.DebugContext(ctx, "Vulnerability analysis complete",
+		slog.Int("patches_available", len(response.Patches)),
+		slog.Int("packages_skipped", len(response.Skipped)))
+
+	// Drop invalid names, patches without a version to install, patches left out by
+	// --min-severity, --only, --exclude and --cve, and patches that aren't upgrades
+	patches, filterSkipped := common.FilterPatches(common.EcosystemNuGet, response.Patches, false, a.options)
+	response.Patches = patches
+	response.Skipped = append(response.Skipped, filterSkipped...)
+	common.WriteCVEMatches(a.out, response.Patches, a.options.CVEs)
+	a.result.AddSkipped(response.Skipped)
+	a.reporter.ReportSkipped(response.Skipped)
+
+	if len(response.Patches) == 0 {
+		fmt.Fprintln(a.out, "\nNo patches needed - all packages are up to date!")
+		return nil
+	}
+
+	// 6. Execute or dry-run patches
+	if a.dryRun {
+		a.logger.DebugContext(ctx, "DRY-RUN MODE: No changes will be made")
+		a.options.Plan.Add(common.EcosystemNuGet, a.filePath, false, sdkPackages, response.Patches)
+		a.result.AddPatches(response.Patches, false, common.PatchStatusDryRun)
+		diff, err := a.proposedDiff(ctx, response.Patches)
+		if err != nil {
+			return err
+		}
+		a.reporter.ReportDryRunWithDiff(response.Patches, false, diff)
+		return nil
+	}
+
+	// 7. Apply patches by updating the files declaring the versions
+	// Ask before changing anything, unless --yes was given
+	if err := a.options.Confirm.Confirm(len(response.Patches), a.filePath); err != nil {
+		a.result.AddPatches(response.Patches, false, common.PatchStatusNotApplied)
+		return err
+	}
+
+	fmt.Fprintf(a.out, "\nApplying %d patches to %s...\n\n", len(response.Patches), a.filePath)
+	a.result.AddPatches(response.Patches, false, common.PatchStatusPending)
+	if err := a.applyPatches(ctx, response.Patches); err != nil {
+		a.result.SetAllPatchStatus(common.PatchStatusFailed, err)
+		return err
+	}
+	a.result.SetAllPatchStatus(common.PatchStatusApplied, nil)
+
+	a.reporter.ReportNextSteps(len(response.Patches))
+	a.reporter.ReportFixedCVEs(a.result.RecordFixedCVEs())
+
+	if a.options.Verify {
+		return a.verify(ctx)
+	}
+
+	return nil
+}
+
+// verify parses the updated file again and confirms no patches remain for its versions
+func (a *App) verify(ctx context.Context) error {
+	packages, err := a.parser.Parse(ctx, a.filePath)
+	if err != nil {
+		return fmt.Errorf("failed to parse %s for verification: %w", a.filePath, err)
+	}
+
+	return common.VerifyPatches(ctx, common.EcosystemNuGet, a.apiClient,
+		common.VerifyPackages(packages, a.options), a.options, a.result)
+}
+
+// fileUpdates groups the patched versions by the file declaring each package, which is
+// Directory.Packages.props for centrally managed versions
+func (a *App) fileUpdates(patches []rootio.PackagePatch) map[string]map[string]string {
+	updates := make(map[string]map[string]string)
+	for _, patch := range patches {
+		file := a.locations[strings.ToLower(patch.PackageName)]
+		if file == "" {
+			file = a.filePath
+		}
+		if updates[file] == nil {
+			updates[file] = make(map[string]string)
+		}
+		updates[file][patch.PackageName] = patch.Patch.Version
+	}
+	return updates
+}
+
+// updatedFiles returns the new content of every file that declares a patched package
+func (a *App) updatedFiles(ctx context.Context, patches []rootio.PackagePatch) (map[string]string, error) {
+	updated := make(map[string]string)
+	for file, updates := range a.fileUpdates(patches) {
+		content, err := a.parser.Update(ctx, file, updates)
+		if err != nil {
+			return nil, fmt.Errorf("failed to update %s: %w", file, err)
+		}
+		updated[file] = content
+	}
+	return updated, nil
+}
+
+// sortedFiles returns the paths of updated files in a stable order
+func sortedFiles(updated map[string]string) []string {
+	files := make([]string, 0, len(updated))
+	for file := range updated {
+		files = append(files, file)
+	}
+	sort.Strings(files)
+	return files
+}
+
+// proposedDiff renders the change applyPatches would make to the files as a unified diff
+func (a *App) proposedDiff(ctx context.Context, patches []rootio.PackagePatch) (string, error) {
+	updated, err := a.updatedFiles(ctx, patches)
+	if err != nil {
+		return "", err
+	}
+
+	var diff string
+	for _, file := range sortedFiles(updated) {
+		original, err := os.ReadFile(file)
+		if err != nil {
+			return "", fmt.Errorf("failed to read file: %w", err)
+		}
+		diff += common.UnifiedDiff(file, string(original), updated[file])
+	}
+	return diff, nil
+}
+
+// applyPatches updates the project files with patched versions
+func (a *App) applyPatches(ctx context.Context, patches []rootio.PackagePatch) error {
+	for i, patch := range patches {
+		fmt.Fprintf(a.out, "[%d/%d] %s: %s → %s\n", i+1, len(patches), patch.PackageName, patch.Version, patch.Patch.Version)
+	}
+
+	// Hold the locks from reading the files until they're written, so concurrent runs don't lose updates
+	unlock, err := common.LockFiles(ctx, slices.Collect(maps.Keys(a.fileUpdates(patches))), a.options.LockTimeout)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	// Update the files
+	a.logger.DebugContext(ctx, "Updating NuGet files", slog.Int("updates", len(patches)))
+	updated, err := a.updatedFiles(ctx, patches)
+	if err != nil {
+		return err
+	}
+
+	// Validate the updated content before writing anything
+	files := sortedFiles(updated)
+	for _, file := range files {
+		if !a.parser.Validate(updated[file]) {
+			return fmt.Errorf("updated content of %s is invalid", file)
+		}
+	}
+
+	for _, file := range files {
+		if a.options.Backup {
+			backupPath, err := common.BackupFile(file)
+			if err != nil {
+				return fmt.Errorf("failed to back up file: %w", err)
+			}
+			a.logger.InfoContext(ctx, "Backed up file before patching",
+				slog.String("file", file),
+				slog.String("backup", backupPath))
+		}
+
+		// Write the updated content back to the file
+		if err := os.WriteFile(file, []byte(updated[file]), 0644); err != nil {
+			return fmt.Errorf("failed to write updated file: %w", err)
+		}
+	}
+
+	return nil
+}
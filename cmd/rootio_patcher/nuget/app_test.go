@@ -0,0 +1,207 @@
+package nuget
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"rootio_patcher/cmd/rootio_patcher/common"
+	"rootio_patcher/pkg/rootio"
+)
+
+// newtonsoftPatchClient returns a patch for Newtonsoft.Json and records the analyzed packages
+func newtonsoftPatchClient(analyzed *[]rootio.Package) *MockAPIClient {
+	return &MockAPIClient{
+		AnalyzePackagesFunc: func(ctx context.Context, packages []rootio.Package) (*rootio.AnalyzePackagesResponse, error) {
+			*analyzed = packages
+			return &rootio.AnalyzePackagesResponse{
+				Patches: []rootio.PackagePatch{
+					{PackageName: "Newtonsoft.Json", Version: "13.0.1", Patch: rootio.PatchInfo{Name: "Newtonsoft.Json", Version: "13.0.3"}},
+				},
+			}, nil
+		},
+	}
+}
+
+func TestNuGetApp_Run_FileNotFound(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+
+	app := NewAppWithServices("test-key", "https://api.root.io", "/nonexistent/App.csproj", true, logger,
+		NewParser(), &MockAPIClient{})
+	if err := app.Run(context.Background()); err == nil {
+		t.Fatal("Expected error for nonexistent file, got nil")
+	}
+}
+
+func TestNuGetApp_Run_APIError(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+	path := copyFixture(t, "App.csproj")
+
+	mockAPIClient := &MockAPIClient{
+		AnalyzePackagesFunc: func(ctx context.Context, packages []rootio.Package) (*rootio.AnalyzePackagesResponse, error) {
+			return nil, errors.New("API error")
+		},
+	}
+
+	app := NewAppWithServices("test-key", "https://api.root.io", path, false, logger, NewParser(), mockAPIClient)
+	if err := app.Run(context.Background()); err == nil {
+		t.Fatal("Expected error from API, got nil")
+	}
+}
+
+func TestNuGetApp_Run_DryRun(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+	path := copyFixture(t, "App.csproj")
+	original, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Failed to read App.csproj: %v", err)
+	}
+
+	var analyzed []rootio.Package
+	app := NewAppWithServices("test-key", "https://api.root.io", path, true, logger, NewParser(), newtonsoftPatchClient(&analyzed))
+	if err := app.Run(context.Background()); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	if len(analyzed) != 4 {
+		t.Errorf("Expected 4 packages to be analyzed, got %v", analyzed)
+	}
+	for _, pkg := range analyzed {
+		if pkg.Ecosystem != string(common.EcosystemNuGet) {
+			t.Errorf("Expected nuget ecosystem, got %q", pkg.Ecosystem)
+		}
+	}
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Failed to read App.csproj: %v", err)
+	}
+	if string(content) != string(original) {
+		t.Errorf("Expected dry-run to leave App.csproj unchanged, got:\n%s", content)
+	}
+}
+
+func TestNuGetApp_Run_SkipDev(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+	path := copyFixture(t, "App.csproj")
+
+	var analyzed []rootio.Package
+	app := NewAppWithServices("test-key", "https://api.root.io", path, true, logger, NewParser(), newtonsoftPatchClient(&analyzed),
+		common.WithSkipDev(true))
+	if err := app.Run(context.Background()); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	// The PrivateAssets="all" analyzers are left out
+	if len(analyzed) != 2 || app.Result().DevSkipped != 2 {
+		t.Errorf("Expected 2 packages analyzed and 2 skipped, got %v (skipped %d)", analyzed, app.Result().DevSkipped)
+	}
+}
+
+func TestNuGetApp_Run_ApplyPatches(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+	path := copyFixture(t, "App.csproj")
+
+	var analyzed []rootio.Package
+	app := NewAppWithServices("test-key", "https://api.root.io", path, false, logger, NewParser(), newtonsoftPatchClient(&analyzed),
+		common.WithBackup(true))
+	if err := app.Run(context.Background()); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Failed to read App.csproj: %v", err)
+	}
+	if !strings.Contains(string(content), `<PackageReference Include="Newtonsoft.Json" Version="13.0.3" />`) {
+		t.Errorf("Expected Newtonsoft.Json to be updated, got:\n%s", content)
+	}
+	if _, err := os.Stat(path + ".rootio.bak"); err != nil {
+		t.Errorf("Expected a backup of App.csproj: %v", err)
+	}
+	if status := app.Result().Patches[0].Status; status != common.PatchStatusApplied {
+		t.Errorf("Expected applied status, got %s", status)
+	}
+}
+
+func TestNuGetApp_Run_ApplyCentralPackageManagement(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+	path := copyFixture(t, filepath.Join("central", "src", "Web", "Web.csproj"))
+	props := filepath.Join(filepath.Dir(filepath.Dir(filepath.Dir(path))), "Directory.Packages.props")
+	original, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Failed to read Web.csproj: %v", err)
+	}
+
+	// Newtonsoft.Json is managed centrally, System.Text.Json is overridden in the project
+	mockAPIClient := &MockAPIClient{
+		AnalyzePackagesFunc: func(ctx context.Context, packages []rootio.Package) (*rootio.AnalyzePackagesResponse, error) {
+			return &rootio.AnalyzePackagesResponse{
+				Patches: []rootio.PackagePatch{
+					{PackageName: "Newtonsoft.Json", Version: "13.0.1", Patch: rootio.PatchInfo{Name: "Newtonsoft.Json", Version: "13.0.3"}},
+					{PackageName: "System.Text.Json", Version: "7.0.0", Patch: rootio.PatchInfo{Name: "System.Text.Json", Version: "8.0.5"}},
+				},
+			}, nil
+		},
+	}
+
+	app := NewAppWithServices("test-key", "https://api.root.io", path, false, logger, NewParser(), mockAPIClient)
+	if err := app.Run(context.Background()); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	propsContent, err := os.ReadFile(props)
+	if err != nil {
+		t.Fatalf("Failed to read Directory.Packages.props: %v", err)
+	}
+	if !strings.Contains(string(propsContent), `<PackageVersion Include="Newtonsoft.Json" Version="13.0.3" />`) {
+		t.Errorf("Expected Newtonsoft.Json to be updated centrally, got:\n%s", propsContent)
+	}
+	if !strings.Contains(string(propsContent), `<PackageVersion Include="System.Text.Json" Version="8.0.0" />`) {
+		t.Errorf("Expected the central System.Text.Json version to be left alone, got:\n%s", propsContent)
+	}
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Failed to read Web.csproj: %v", err)
+	}
+	expected := strings.Replace(string(original), `VersionOverride="7.0.0"`, `VersionOverride="8.0.5"`, 1)
+	if string(content) != expected {
+		t.Errorf("Expected only the VersionOverride to change in Web.csproj, got:\n%s", content)
+	}
+}
+
+func TestNuGetApp_Run_PlanThenApply(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+	path := copyFixture(t, "packages.lock.json")
+
+	// The dry run records its patches in the plan
+	plan := common.NewPlan()
+	var analyzed []rootio.Package
+	dryRun := NewAppWithServices("test-key", "https://api.root.io", path, true, logger, NewParser(), newtonsoftPatchClient(&analyzed),
+		common.WithPlan(plan))
+	if err := dryRun.Run(context.Background()); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if len(plan.Entries) != 1 || plan.Entries[0].File != path || len(plan.Entries[0].Patches) != 1 {
+		t.Fatalf("Expected one plan entry for %s, got %+v", path, plan.Entries)
+	}
+
+	// Applying the plan uses the planned patches; the API URL is unreachable
+	apply := NewApp("test-key", "http://127.0.0.1:0", path, false, logger, common.WithPlanEntry(&plan.Entries[0]))
+	if err := apply.Run(context.Background()); err != nil {
+		t.Fatalf("Expected the plan to apply, got: %v", err)
+	}
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Failed to read packages.lock.json: %v", err)
+	}
+	if count := strings.Count(string(content), `"resolved": "13.0.3"`); count != 2 {
+		t.Errorf("Expected Newtonsoft.Json to be updated from the plan, got:\n%s", content)
+	}
+}
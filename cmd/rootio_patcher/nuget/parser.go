@@ -0,0 +1,385 @@
+package nuget
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"rootio_patcher/cmd/rootio_patcher/common"
+)
+
+const (
+	packagesConfig = "packages.config"
+	packagesLock   = "packages.lock.json"
+
+	// centralPackagesFile declares package versions for every project below it (central package management)
+	centralPackagesFile = "Directory.Packages.props"
+)
+
+// itemPattern matches the elements that reference a package: <PackageReference> in projects,
+// <PackageVersion> in Directory.Packages.props and <package> in packages.config
+var itemPattern = regexp.MustCompile(`<(PackageReference|PackageVersion|package)\s[^>]*>`)
+
+// attributePattern matches a double-quoted XML attribute
+var attributePattern = regexp.MustCompile(`([A-Za-z]+)\s*=\s*"([^"]*)"`)
+
+// privateAssetsPattern matches PrivateAssets given as a child element: <PrivateAssets>all</PrivateAssets>
+var privateAssetsPattern = regexp.MustCompile(`(?i)<PrivateAssets>\s*all\s*</PrivateAssets>`)
+
+// commentPattern matches XML comments, whose references are ignored
+var commentPattern = regexp.MustCompile(`(?s)<!--.*?-->`)
+
+// plainVersion matches a fixed NuGet version. Ranges, floating versions (1.*) and MSBuild
+// properties ($(SerilogVersion)) can't be bumped in place and are skipped.
+var plainVersion = regexp.MustCompile(`^[0-9]+(\.[0-9]+){0,3}(-[0-9A-Za-z.-]+)?(\+[0-9A-Za-z.-]+)?$`)
+
+// lockHeaderPattern matches the line opening an object in packages.lock.json: `    "Newtonsoft.Json": {`
+var lockHeaderPattern = regexp.MustCompile(`^\s*"([^"]+)":\s*\{$`)
+
+// lockPropertyPattern matches a string property of a locked package: `"resolved": "13.0.1",`
+var lockPropertyPattern = regexp.MustCompile(`^(\s*"(resolved|contentHash)":\s*")[^"]*(".*)$`)
+
+// NuGetParser handles SDK-style projects, packages.config, Directory.Packages.props and packages.lock.json
+type NuGetParser struct{}
+
+// NewParser creates a new NuGet parser
+func NewParser() *NuGetParser {
+	return &NuGetParser{}
+}
+
+// Ecosystem returns the ecosystem name
+func (p *NuGetParser) Ecosystem() common.Ecosystem {
+	return common.EcosystemNuGet
+}
+
+// FilePatterns returns file patterns this parser handles
+func (p *NuGetParser) FilePatterns() []string {
+	return []string{"*.csproj", packagesConfig, centralPackagesFile, packagesLock}
+}
+
+// CanHandle checks if this parser can handle the given file
+func (p *NuGetParser) CanHandle(fileName string) bool {
+	switch filepath.Base(fileName) {
+	case packagesConfig, centralPackagesFile, packagesLock:
+		return true
+	}
+	return filepath.Ext(fileName) == ".csproj"
+}
+
+// reference is a package referenced by an XML element
+type reference struct {
+	name    string
+	version string
+	dev     bool
+	// start and end delimit the version attribute's value; both are 0 without one
+	start, end int
+}
+
+// references returns the packages referenced in a project, Directory.Packages.props or
+// packages.config. <PackageReference Update="..."> items only change existing references
+// and are skipped.
+func references(content string) []reference {
+	// Blank out comments, keeping offsets, so commented-out references don't match
+	visible := commentPattern.ReplaceAllStringFunc(content, func(comment string) string {
+		return strings.Repeat(" ", len(comment))
+	})
+
+	var refs []reference
+	for _, item := range itemPattern.FindAllStringSubmatchIndex(visible, -1) {
+		tag := visible[item[0]:item[1]]
+		element := visible[item[2]:item[3]]
+
+		var ref reference
+		override := false
+		for _, attr := range attributePattern.FindAllStringSubmatchIndex(tag, -1) {
+			key, value := tag[attr[2]:attr[3]], tag[attr[4]:attr[5]]
+			start, end := item[0]+attr[4], item[0]+attr[5]
+
+			switch {
+			case strings.EqualFold(key, "Include") && element != "package",
+				strings.EqualFold(key, "id") && element == "package":
+				ref.name = value
+			case strings.EqualFold(key, "VersionOverride"):
+				// VersionOverride wins over Version and the centrally managed version
+				ref.version, ref.start, ref.end = value, start, end
+				override = true
+			case strings.EqualFold(key, "Version") && !override:
+				ref.version, ref.start, ref.end = value, start, end
+			case strings.EqualFold(key, "PrivateAssets"):
+				ref.dev = ref.dev || strings.EqualFold(value, "all")
+			case strings.EqualFold(key, "developmentDependency"):
+				ref.dev = ref.dev || strings.EqualFold(value, "true")
+			}
+		}
+
+		// Metadata can also be given as child elements of the item
+		if !strings.HasSuffix(tag, "/>") {
+			closing := "</" + element + ">"
+			if end := strings.Index(visible[item[1]:], closing); end >= 0 {
+				ref.dev = ref.dev || privateAssetsPattern.MatchString(visible[item[1]:item[1]+end])
+			}
+		}
+
+		if ref.name != "" {
+			refs = append(refs, ref)
+		}
+	}
+	return refs
+}
+
+// centralPackagesPath returns the Directory.Packages.props that manages versions for the
+// project at projectPath: the nearest one in its directory or a parent directory. A relative
+// projectPath gives a path relative to the working directory.
+func centralPackagesPath(projectPath string) string {
+	dir, err := filepath.Abs(filepath.Dir(projectPath))
+	if err != nil {
+		return ""
+	}
+
+	for {
+		candidate := filepath.Join(dir, centralPackagesFile)
+		if _, err := os.Stat(candidate); err == nil {
+			if filepath.IsAbs(projectPath) {
+				return candidate
+			}
+			wd, err := os.Getwd()
+			if err != nil {
+				return candidate
+			}
+			if rel, err := filepath.Rel(wd, candidate); err == nil {
+				return rel
+			}
+			return candidate
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return ""
+		}
+		dir = parent
+	}
+}
+
+// Parse reads the packages referenced by filePath. Project references without a version take
+// it from Directory.Packages.props, and the package's Location is the file that declares it.
+func (p *NuGetParser) Parse(ctx context.Context, filePath string) ([]common.PackageInfo, error) {
+	content, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read file: %w", err)
+	}
+
+	if filepath.Base(filePath) == packagesLock {
+		return parseLockFile(content, filePath)
+	}
+
+	var central map[string]common.PackageInfo
+	if filepath.Ext(filePath) == ".csproj" {
+		central, err = p.centralVersions(ctx, filePath)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var packages []common.PackageInfo
+	for _, ref := range references(string(content)) {
+		if ref.start == 0 {
+			pkg, ok := central[strings.ToLower(ref.name)]
+			if !ok {
+				continue
+			}
+			pkg.Dev = ref.dev
+			packages = append(packages, pkg)
+			continue
+		}
+		if !plainVersion.MatchString(ref.version) {
+			continue
+		}
+		packages = append(packages, common.PackageInfo{
+			Name:      ref.name,
+			Version:   ref.version,
+			Ecosystem: common.EcosystemNuGet,
+			Direct:    true,
+			Dev:       ref.dev,
+			Location:  filePath,
+		})
+	}
+
+	return packages, nil
+}
+
+// centralVersions returns the packages declared in the project's Directory.Packages.props,
+// keyed by lowercase name since NuGet package IDs are case-insensitive
+func (p *NuGetParser) centralVersions(ctx context.Context, projectPath string) (map[string]common.PackageInfo, error) {
+	propsPath := centralPackagesPath(projectPath)
+	if propsPath == "" {
+		return nil, nil
+	}
+
+	packages, err := p.Parse(ctx, propsPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", propsPath, err)
+	}
+
+	central := make(map[string]common.PackageInfo, len(packages))
+	for _, pkg := range packages {
+		central[strings.ToLower(pkg.Name)] = pkg
+	}
+	return central, nil
+}
+
+// lockFile is the part of packages.lock.json read by the parser
+type lockFile struct {
+	Dependencies map[string]map[string]struct {
+		Type     string `json:"type"`
+		Resolved string `json:"resolved"`
+	} `json:"dependencies"`
+}
+
+// parseLockFile reads the packages resolved for every target framework. A package resolved to
+// the same version for several frameworks is reported once; references to other projects are skipped.
+func parseLockFile(content []byte, filePath string) ([]common.PackageInfo, error) {
+	var lock lockFile
+	if err := json.Unmarshal(content, &lock); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", filePath, err)
+	}
+
+	frameworks := make([]string, 0, len(lock.Dependencies))
+	for framework := range lock.Dependencies {
+		frameworks = append(frameworks, framework)
+	}
+	sort.Strings(frameworks)
+
+	var packages []common.PackageInfo
+	index := make(map[string]int)
+	for _, framework := range frameworks {
+		names := make([]string, 0, len(lock.Dependencies[framework]))
+		for name := range lock.Dependencies[framework] {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		for _, name := range names {
+			locked := lock.Dependencies[framework][name]
+			if locked.Type == "Project" || locked.Resolved == "" {
+				continue
+			}
+
+			key := strings.ToLower(name) + "@" + locked.Resolved
+			if i, ok := index[key]; ok {
+				packages[i].Direct = packages[i].Direct || locked.Type == "Direct"
+				continue
+			}
+			index[key] = len(packages)
+			packages = append(packages, common.PackageInfo{
+				Name:      name,
+				Version:   locked.Resolved,
+				Ecosystem: common.EcosystemNuGet,
+				Direct:    locked.Type == "Direct",
+				Location:  filePath,
+			})
+		}
+	}
+
+	return packages, nil
+}
+
+// lowerKeys returns updates keyed by lowercase package name
+func lowerKeys(updates map[string]string) map[string]string {
+	lower := make(map[string]string, len(updates))
+	for name, version := range updates {
+		lower[strings.ToLower(name)] = version
+	}
+	return lower
+}
+
+// Update rewrites the version of each updated package declared in filePath: the Version (or
+// VersionOverride) attribute in XML files, or the resolved version in packages.lock.json.
+// Versions managed in Directory.Packages.props have to be updated there.
+func (p *NuGetParser) Update(ctx context.Context, filePath string, updates map[string]string) (string, error) {
+	content, err := os.ReadFile(filePath)
+	if err != nil {
+		return "", fmt.Errorf("failed to read file: %w", err)
+	}
+
+	updates = lowerKeys(updates)
+	if filepath.Base(filePath) == packagesLock {
+		return updateLockFile(string(content), updates), nil
+	}
+
+	updated := string(content)
+	refs := references(updated)
+	// Splice from the end so earlier offsets stay valid
+	for i := len(refs) - 1; i >= 0; i-- {
+		ref := refs[i]
+		newVersion, ok := updates[strings.ToLower(ref.name)]
+		if !ok || ref.start == 0 || !plainVersion.MatchString(ref.version) {
+			continue
+		}
+		updated = updated[:ref.start] + newVersion + updated[ref.end:]
+	}
+
+	return updated, nil
+}
+
+// updateLockFile rewrites the resolved version of updated packages in packages.lock.json, which
+// NuGet writes one property per line. Their contentHash no longer matches and is removed, so
+// the next restore records the patched package's hash.
+func updateLockFile(content string, updates map[string]string) string {
+	lines := strings.Split(content, "\n")
+	out := make([]string, 0, len(lines))
+
+	depth := 0
+	var newVersion string
+	for _, line := range lines {
+		// Packages are the objects at depth 3: root, "dependencies", target framework
+		if match := lockHeaderPattern.FindStringSubmatch(line); match != nil && depth == 3 {
+			newVersion = updates[strings.ToLower(match[1])]
+		}
+
+		if match := lockPropertyPattern.FindStringSubmatch(line); match != nil && depth == 4 && newVersion != "" {
+			if match[2] == "contentHash" {
+				// Drop the property, and the previous line's comma if it was the last one
+				if !strings.HasSuffix(strings.TrimSpace(line), ",") && len(out) > 0 {
+					out[len(out)-1] = strings.TrimSuffix(out[len(out)-1], ",")
+				}
+				continue
+			}
+			line = match[1] + newVersion + match[3]
+		}
+
+		depth += strings.Count(line, "{") - strings.Count(line, "}")
+		out = append(out, line)
+	}
+
+	return strings.Join(out, "\n")
+}
+
+// Validate checks that the content is well-formed JSON (packages.lock.json) or XML
+func (p *NuGetParser) Validate(content string) bool {
+	if strings.HasPrefix(strings.TrimSpace(content), "{") {
+		return json.Valid([]byte(content))
+	}
+
+	decoder := xml.NewDecoder(bytes.NewReader([]byte(content)))
+	elements := 0
+	for {
+		token, err := decoder.Token()
+		if errors.Is(err, io.EOF) {
+			return elements > 0
+		}
+		if err != nil {
+			return false
+		}
+		if _, ok := token.(xml.StartElement); ok {
+			elements++
+		}
+	}
+}
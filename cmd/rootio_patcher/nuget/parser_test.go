@@ -0,0 +1,237 @@
+package nuget
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"rootio_patcher/cmd/rootio_patcher/common"
+)
+
+// copyFixture copies testdata/<name> (a file or directory) to a temporary directory and
+// returns the copied path
+func copyFixture(t *testing.T, name string) string {
+	t.Helper()
+
+	dir := t.TempDir()
+	if err := os.CopyFS(dir, os.DirFS("testdata")); err != nil {
+		t.Fatalf("Failed to copy fixtures: %v", err)
+	}
+	return filepath.Join(dir, name)
+}
+
+// packageSummary describes a parsed package for comparisons
+type packageSummary struct {
+	name    string
+	version string
+	direct  bool
+	dev     bool
+}
+
+// assertPackages compares parsed packages with the expected ones, in order
+func assertPackages(t *testing.T, packages []common.PackageInfo, expected []packageSummary) {
+	t.Helper()
+
+	if len(packages) != len(expected) {
+		t.Fatalf("Expected %d packages, got %d: %+v", len(expected), len(packages), packages)
+	}
+	for i, exp := range expected {
+		pkg := packages[i]
+		if pkg.Name != exp.name || pkg.Version != exp.version || pkg.Direct != exp.direct || pkg.Dev != exp.dev ||
+			pkg.Ecosystem != common.EcosystemNuGet {
+			t.Errorf("Package %d: expected %+v, got %+v", i, exp, pkg)
+		}
+	}
+}
+
+func TestNuGetParser_CanHandle(t *testing.T) {
+	parser := NewParser()
+
+	tests := []struct {
+		fileName string
+		expected bool
+	}{
+		{"App.csproj", true},
+		{"src/Web/Web.csproj", true},
+		{"packages.config", true},
+		{"packages.lock.json", true},
+		{"Directory.Packages.props", true},
+		{"Directory.Build.props", false},
+		{"App.sln", false},
+		{"package-lock.json", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.fileName, func(t *testing.T) {
+			if result := parser.CanHandle(tt.fileName); result != tt.expected {
+				t.Errorf("CanHandle(%s) = %v, expected %v", tt.fileName, result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestNuGetParser_ParseProject(t *testing.T) {
+	path := filepath.Join("testdata", "App.csproj")
+
+	packages, err := NewParser().Parse(context.Background(), path)
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+
+	// Serilog's property, the version range and the commented-out log4net are skipped
+	assertPackages(t, packages, []packageSummary{
+		{"Newtonsoft.Json", "13.0.1", true, false},
+		{"System.Text.Json", "8.0.0", true, false},
+		{"StyleCop.Analyzers", "1.1.118", true, true},
+		{"Microsoft.SourceLink.GitHub", "8.0.0", true, true},
+	})
+	for _, pkg := range packages {
+		if pkg.Location != path {
+			t.Errorf("Expected %s to be located in %s, got %s", pkg.Name, path, pkg.Location)
+		}
+	}
+}
+
+func TestNuGetParser_ParsePackagesConfig(t *testing.T) {
+	packages, err := NewParser().Parse(context.Background(), filepath.Join("testdata", "packages.config"))
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+
+	assertPackages(t, packages, []packageSummary{
+		{"Newtonsoft.Json", "12.0.3", true, false},
+		{"log4net", "2.0.8", true, false},
+		{"NUnit", "3.13.2", true, true},
+	})
+}
+
+func TestNuGetParser_ParseLockFile(t *testing.T) {
+	packages, err := NewParser().Parse(context.Background(), filepath.Join("testdata", "packages.lock.json"))
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+
+	// Both target frameworks resolve the same versions; the Shared project is skipped
+	assertPackages(t, packages, []packageSummary{
+		{"Newtonsoft.Json", "13.0.1", true, false},
+		{"System.Text.Encodings.Web", "4.7.1", false, false},
+	})
+}
+
+func TestNuGetParser_ParseCentralPackageManagement(t *testing.T) {
+	path := copyFixture(t, filepath.Join("central", "src", "Web", "Web.csproj"))
+	props := filepath.Join(filepath.Dir(filepath.Dir(filepath.Dir(path))), "Directory.Packages.props")
+
+	packages, err := NewParser().Parse(context.Background(), path)
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+
+	assertPackages(t, packages, []packageSummary{
+		{"Newtonsoft.Json", "13.0.1", true, false},
+		{"Serilog", "3.1.1", true, false},
+		{"System.Text.Json", "7.0.0", true, false},
+	})
+
+	// Central versions are declared in Directory.Packages.props, overrides in the project
+	expectedLocations := []string{props, props, path}
+	for i, pkg := range packages {
+		if pkg.Location != expectedLocations[i] {
+			t.Errorf("Expected %s to be located in %s, got %s", pkg.Name, expectedLocations[i], pkg.Location)
+		}
+	}
+}
+
+func TestNuGetParser_UpdateProject(t *testing.T) {
+	parser := NewParser()
+	path := copyFixture(t, "App.csproj")
+	original, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Failed to read App.csproj: %v", err)
+	}
+
+	// NuGet package IDs are case-insensitive; Serilog's version comes from a property and is left alone
+	updated, err := parser.Update(context.Background(), path, map[string]string{
+		"newtonsoft.json": "13.0.3",
+		"Serilog":         "3.1.2",
+		"log4net":         "2.0.17",
+	})
+	if err != nil {
+		t.Fatalf("Update failed: %v", err)
+	}
+	if !parser.Validate(updated) {
+		t.Fatalf("Expected updated project to be valid, got:\n%s", updated)
+	}
+
+	expected := strings.Replace(string(original),
+		`<PackageReference Include="Newtonsoft.Json" Version="13.0.1" />`,
+		`<PackageReference Include="Newtonsoft.Json" Version="13.0.3" />`, 1)
+	if updated != expected {
+		t.Errorf("Expected:\n%s\ngot:\n%s", expected, updated)
+	}
+}
+
+func TestNuGetParser_UpdateVersionOverride(t *testing.T) {
+	path := copyFixture(t, filepath.Join("central", "src", "Web", "Web.csproj"))
+
+	updated, err := NewParser().Update(context.Background(), path, map[string]string{"System.Text.Json": "8.0.5"})
+	if err != nil {
+		t.Fatalf("Update failed: %v", err)
+	}
+	if !strings.Contains(updated, `<PackageReference Include="System.Text.Json" VersionOverride="8.0.5" />`) {
+		t.Errorf("Expected the VersionOverride to be updated, got:\n%s", updated)
+	}
+}
+
+func TestNuGetParser_UpdateLockFile(t *testing.T) {
+	parser := NewParser()
+	path := copyFixture(t, "packages.lock.json")
+
+	updated, err := parser.Update(context.Background(), path, map[string]string{"System.Text.Encodings.Web": "4.7.2"})
+	if err != nil {
+		t.Fatalf("Update failed: %v", err)
+	}
+	if !parser.Validate(updated) {
+		t.Fatalf("Expected updated lock file to be valid, got:\n%s", updated)
+	}
+
+	// Both frameworks are updated and the stale hashes removed; requested ranges stay
+	if count := strings.Count(updated, `"resolved": "4.7.2"`); count != 2 {
+		t.Errorf("Expected both resolved versions to be updated, got %d:\n%s", count, updated)
+	}
+	if strings.Contains(updated, "ECxt7Ebl") {
+		t.Errorf("Expected the stale contentHash to be removed, got:\n%s", updated)
+	}
+	if !strings.Contains(updated, `"System.Text.Encodings.Web": "[4.7.1, )"`) {
+		t.Errorf("Expected project dependency ranges to be left alone, got:\n%s", updated)
+	}
+	if count := strings.Count(updated, "ppPFpBcv"); count != 2 {
+		t.Errorf("Expected Newtonsoft.Json's hashes to be kept, got %d", count)
+	}
+}
+
+func TestNuGetParser_Validate(t *testing.T) {
+	parser := NewParser()
+
+	tests := []struct {
+		name     string
+		content  string
+		expected bool
+	}{
+		{"project", `<Project Sdk="Microsoft.NET.Sdk"><ItemGroup /></Project>`, true},
+		{"lock file", `{"version": 1, "dependencies": {}}`, true},
+		{"unclosed element", `<Project><ItemGroup></Project>`, false},
+		{"invalid json", `{"version": 1,}`, false},
+		{"empty", "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if result := parser.Validate(tt.content); result != tt.expected {
+				t.Errorf("Validate() = %v, expected %v", result, tt.expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+
+	"rootio_patcher/cmd/rootio_patcher/common"
+)
+
+const (
+	// outputText selects human-readable output
+	outputText = "text"
+
+	// outputJSON selects the machine-readable JSON output format
+	outputJSON = "json"
+)
+
+// resultSink receives the structured result of the command that ran
+type resultSink struct {
+	result *common.RunResult
+
+	// results holds one result per file for commands that remediate several files (scan)
+	results []*common.RunResult
+}
+
+// collect stores the command's result and passes its error through
+func (s *resultSink) collect(result *common.RunResult, err error) error {
+	s.result = result
+	return err
+}
+
+// collectAll stores the results of a multi-file command and passes its error through
+func (s *resultSink) collectAll(results []*common.RunResult, err error) error {
+	s.results = results
+	return err
+}
+
+// all returns every collected result
+func (s *resultSink) all() []*common.RunResult {
+	if s.result != nil {
+		return append([]*common.RunResult{s.result}, s.results...)
+	}
+	return s.results
+}
+
+// failed reports whether any collected result has failed patches
+func (s *resultSink) failed() bool {
+	for _, result := range s.all() {
+		if result.Failed() > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// unapplied returns the number of available patches that were not applied across all results
+func (s *resultSink) unapplied() int {
+	count := 0
+	for _, result := range s.all() {
+		count += result.Unapplied()
+	}
+	return count
+}
+
+// scanDocument is the JSON output of a multi-file command
+type scanDocument struct {
+	Results []*common.RunResult `json:"results"`
+	Error   string              `json:"error,omitempty"`
+}
+
+// writeJSONResults writes the per-file results of a multi-file command as one JSON document
+func writeJSONResults(w io.Writer, results []*common.RunResult, runErr error) error {
+	doc := scanDocument{Results: results}
+	if doc.Results == nil {
+		doc.Results = []*common.RunResult{}
+	}
+	if runErr != nil {
+		doc.Error = runErr.Error()
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(doc)
+}
+
+// writeJSONResult writes the run result as an indented JSON document.
+// Errors are included in the document so consumers always get valid JSON.
+func writeJSONResult(w io.Writer, result *common.RunResult, runErr error) error {
+	if result == nil {
+		result = &common.RunResult{
+			Patches: []common.PatchResult{},
+			Skipped: []common.SkippedResult{},
+		}
+	}
+	result.SetError(runErr)
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(result)
+}
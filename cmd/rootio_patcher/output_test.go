@@ -0,0 +1,115 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"rootio_patcher/cmd/rootio_patcher/common"
+	"rootio_patcher/pkg/rootio"
+)
+
+func TestWriteJSONResult_PartialFailure(t *testing.T) {
+	result := common.NewRunResult(common.EcosystemPyPI, "", false)
+	result.PackagesFound = 3
+	result.AddPatches([]rootio.PackagePatch{
+		{
+			PackageName: "django",
+			Version:     "4.0.0",
+			PatchAlias:  rootio.PatchInfo{Name: "rootio-django", Version: "4.0.1"},
+			CVEIDs:      []string{"CVE-2023-1234"},
+		},
+		{
+			PackageName: "flask",
+			Version:     "2.0.0",
+			PatchAlias:  rootio.PatchInfo{Name: "rootio-flask", Version: "2.0.1"},
+		},
+	}, true, common.PatchStatusPending)
+	result.AddSkipped([]rootio.SkippedPackage{{PackageName: "requests", Reason: "no patch available"}})
+	result.SetPatchStatus(0, common.PatchStatusApplied, nil)
+	result.SetPatchStatus(1, common.PatchStatusFailed, errors.New("install failed"))
+
+	var buf bytes.Buffer
+	if err := writeJSONResult(&buf, result, errors.New("patch failed: install failed")); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	var decoded common.RunResult
+	if err := json.Unmarshal(buf.Bytes(), &decoded); err != nil {
+		t.Fatalf("Output is not valid JSON: %v\n%s", err, buf.String())
+	}
+
+	if decoded.Ecosystem != common.EcosystemPyPI {
+		t.Errorf("Expected ecosystem 'pypi', got '%s'", decoded.Ecosystem)
+	}
+	if decoded.PackagesFound != 3 {
+		t.Errorf("Expected 3 packages found, got %d", decoded.PackagesFound)
+	}
+	if len(decoded.Patches) != 2 {
+		t.Fatalf("Expected 2 patches, got %d", len(decoded.Patches))
+	}
+	if decoded.Patches[0].Status != common.PatchStatusApplied || decoded.Patches[0].PatchedName != "rootio-django" {
+		t.Errorf("Unexpected first patch: %+v", decoded.Patches[0])
+	}
+	if decoded.Patches[1].Status != common.PatchStatusFailed || decoded.Patches[1].Error != "install failed" {
+		t.Errorf("Unexpected second patch: %+v", decoded.Patches[1])
+	}
+	if len(decoded.Skipped) != 1 || decoded.Skipped[0].Reason != "no patch available" {
+		t.Errorf("Unexpected skipped packages: %+v", decoded.Skipped)
+	}
+	if decoded.Error == "" {
+		t.Error("Expected error to be included in JSON output")
+	}
+	if decoded.Failed() != 1 || decoded.Applied() != 1 {
+		t.Errorf("Expected 1 applied and 1 failed, got %d and %d", decoded.Applied(), decoded.Failed())
+	}
+}
+
+func TestWriteJSONResult_NoResult(t *testing.T) {
+	var buf bytes.Buffer
+	if err := writeJSONResult(&buf, nil, errors.New("lock file not found")); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	var decoded map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &decoded); err != nil {
+		t.Fatalf("Output is not valid JSON: %v", err)
+	}
+	if decoded["error"] != "lock file not found" {
+		t.Errorf("Expected error field, got: %v", decoded["error"])
+	}
+	if _, ok := decoded["patches"].([]interface{}); !ok {
+		t.Error("Expected patches to be an empty array")
+	}
+}
+
+func TestWriteJSONResults(t *testing.T) {
+	npmResult := common.NewRunResult(common.EcosystemNpm, "web/package-lock.json", true)
+	npmResult.PackagesFound = 10
+	mavenResult := common.NewRunResult(common.EcosystemMaven, "api/pom.xml", true)
+	mavenResult.PackagesFound = 4
+
+	var buf bytes.Buffer
+	if err := writeJSONResults(&buf, []*common.RunResult{npmResult, mavenResult}, errors.New("1 of 3 files failed")); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	var decoded struct {
+		Results []common.RunResult `json:"results"`
+		Error   string             `json:"error"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &decoded); err != nil {
+		t.Fatalf("Output is not valid JSON: %v\n%s", err, buf.String())
+	}
+
+	if len(decoded.Results) != 2 {
+		t.Fatalf("Expected 2 results, got %d", len(decoded.Results))
+	}
+	if decoded.Results[0].Ecosystem != common.EcosystemNpm || decoded.Results[1].File != "api/pom.xml" {
+		t.Errorf("Unexpected results: %+v", decoded.Results)
+	}
+	if decoded.Error != "1 of 3 files failed" {
+		t.Errorf("Expected error to be included, got '%s'", decoded.Error)
+	}
+}
@@ -2,15 +2,22 @@ package pip
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"io"
 	"log/slog"
 	"strings"
+	"sync"
+	"time"
 
 	"rootio_patcher/cmd/rootio_patcher/common"
 	"rootio_patcher/cmd/rootio_patcher/config"
 	"rootio_patcher/pkg/rootio"
 )
 
+// restoreTimeout bounds reinstalling a package that a failed or interrupted patch left uninstalled
+const restoreTimeout = 2 * time.Minute
+
 // App handles pip package remediation (post-install patching)
 type App struct {
 	cfg        *config.Config
@@ -22,15 +29,34 @@ type App struct {
 	pipService Service
 	apiClient  common.APIClient
 	reporter   *common.Reporter
+	options    common.Options
+	out        io.Writer
+
+	result *common.RunResult
+
+	// dependencies lists the requirements of each patched package, used to order patches
+	dependencies map[string][]string
+
+	// removed lists the packages a failed patch uninstalled that couldn't be reinstalled,
+	// guarded by removedMu since parallel patches append to it
+	removedMu sync.Mutex
+	removed   []string
 }
 
 // NewApp creates a new pip application instance
-func NewApp(cfg *config.Config, pythonPath string, dryRun, useAlias bool, logger *slog.Logger) *App {
-	pipService := NewService(pythonPath, cfg.PKGURL, cfg.APIKey, useAlias, logger)
-	apiClient := rootio.NewClient(cfg.APIURL, cfg.APIKey)
-	reporter := common.NewReporter(cfg.PKGURL, logger)
+func NewApp(
+	cfg *config.Config, pythonPath string, dryRun, useAlias bool, logger *slog.Logger, opts ...common.Option,
+) *App {
+	options := common.NewOptions(opts...)
+	pipService := NewService(pythonPath, cfg.PKGURL, cfg.APIKey, useAlias, logger,
+		WithNetrc(options.NetrcCredentials),
+		WithInstallDeps(options.InstallDeps),
+		WithTarget(options.TargetDir))
+	apiClient := common.NewAPIClient(common.EcosystemPyPI, cfg.APIURL, cfg.APIKey, logger, opts...)
+	reporter := common.NewEcosystemReporter(common.EcosystemPyPI, cfg.PKGURL, logger,
+		common.WithPipDependencies(options.InstallDeps), common.WithWriter(options.Output()))
 
-	return NewAppWithServices(cfg, pythonPath, dryRun, useAlias, logger, pipService, apiClient, reporter)
+	return NewAppWithServices(cfg, pythonPath, dryRun, useAlias, logger, pipService, apiClient, reporter, opts...)
 }
 
 // NewAppWithServices creates a new pip application with injected services (for testing)
@@ -42,7 +68,9 @@ func NewAppWithServices(
 	pipService Service,
 	apiClient common.APIClient,
 	reporter *common.Reporter,
+	opts ...common.Option,
 ) *App {
+	options := common.NewOptions(opts...)
 	return &App{
 		cfg:        cfg,
 		pythonPath: pythonPath,
@@ -52,32 +80,61 @@ func NewAppWithServices(
 		pipService: pipService,
 		apiClient:  apiClient,
 		reporter:   reporter,
+		options:    options,
+		out:        options.Output(),
 	}
 }
 
+// Result returns the structured result of the last run
+func (a *App) Result() *common.RunResult {
+	return a.result
+}
+
+// RunWithResult runs the pip remediation workflow and returns its structured result
+func (a *App) RunWithResult(ctx context.Context) (*common.RunResult, error) {
+	err := a.Run(ctx)
+	a.result.SetError(err)
+	return a.result, err
+}
+
 // Run executes the pip remediation workflow
 func (a *App) Run(ctx context.Context) error {
 	a.logger.DebugContext(ctx, "Starting pip remediation", slog.Bool("dry_run", a.dryRun))
+	a.result = common.NewRunResult(common.EcosystemPyPI, "", a.dryRun)
+	a.removed = nil
+
+	// Make sure the interpreter has pip before collecting packages
+	if err := a.pipService.CheckPip(ctx); err != nil {
+		return fmt.Errorf("failed to find pip: %w", err)
+	}
 
 	// 1. Collect installed packages
 	a.logger.DebugContext(ctx, "Collecting installed packages")
+	a.options.Progress.Printf("Collecting installed packages...")
 	packages, err := a.pipService.ListPackages(ctx)
 	if err != nil {
 		return fmt.Errorf("failed to collect packages: %w", err)
 	}
 	a.logger.DebugContext(ctx, "Collected packages", slog.Int("count", len(packages)))
+	a.options.Progress.Printf("Collected %d installed packages", len(packages))
+	a.result.PackagesFound = len(packages)
+
+	// Editable and VCS installs aren't from an index, so there's nothing to analyze or reinstall
+	packages, localSkipped := skipLocalInstalls(packages)
 
 	// 2. Convert to SDK format
 	sdkPackages := make([]rootio.Package, len(packages))
 	for i, pkg := range packages {
 		sdkPackages[i] = rootio.Package{
-			Name:    pkg.Name,
-			Version: pkg.Version,
+			Name:      pkg.Name,
+			Version:   pkg.Version,
+			Ecosystem: string(common.EcosystemPyPI),
 		}
 	}
 
 	// 3. Call backend API to analyze vulnerabilities
 	a.logger.DebugContext(ctx, "Analyzing packages for vulnerabilities")
+	a.options.Progress.Printf("Analyzing %d packages for vulnerabilities...", len(sdkPackages))
 	response, err := a.apiClient.AnalyzePackages(ctx, sdkPackages)
 	if err != nil {
 		return fmt.Errorf("failed to analyze packages: %w", err)
@@ -88,43 +145,177 @@ func (a *App) Run(ctx context.Context) error {
 		slog.Int("patches_available", len(response.Patches)),
 		slog.Int("packages_skipped", len(response.Skipped)))
 
+	// Refer to each patched package by its installed name, whatever spelling the API used
+	response.Patches = matchInstalled(response.Patches, packages)
+
+	// Drop invalid names, patches without a version to install, patches left out by
+	// --min-severity, --only, --exclude and --cve, and patches that aren't upgrades
+	patches, filterSkipped := common.FilterPatches(common.EcosystemPyPI, response.Patches, a.useAlias, a.options)
+	response.Patches = patches
+	response.Skipped = append(response.Skipped, filterSkipped...)
+	common.WriteCVEMatches(a.out, response.Patches, a.options.CVEs)
+	response.Skipped = append(response.Skipped, localSkipped...)
+	a.result.AddSkipped(response.Skipped)
+	a.reporter.ReportSkipped(response.Skipped)
+
 	if len(response.Patches) == 0 {
-		fmt.Println("\nNo patches needed - all packages are up to date!")
+		fmt.Fprintln(a.out, "\nNo patches needed - all packages are up to date!")
 		return nil
 	}
 
 	// 5. Execute or dry-run patches
 	if a.dryRun {
 		a.logger.DebugContext(ctx, "DRY-RUN MODE: No changes will be made")
+		a.options.Plan.Add(common.EcosystemPyPI, "", a.useAlias, sdkPackages, response.Patches)
+		a.result.AddPatches(response.Patches, a.useAlias, common.PatchStatusDryRun)
 		a.reporter.ReportDryRun(response.Patches, a.useAlias)
 		return nil
 	}
 
-	// 6. Execute patches
-	fmt.Printf("\nApplying %d patches...\n\n", len(response.Patches))
+	// Ask before changing anything, unless --yes was given
+	if err := a.options.Confirm.Confirm(len(response.Patches), "the Python environment"); err != nil {
+		a.result.AddPatches(response.Patches, a.useAlias, common.PatchStatusNotApplied)
+		return err
+	}
+
+	// 6. Execute patches, dependencies before the packages that require them
+	response.Patches = a.orderByDependencies(ctx, response.Patches)
+	a.result.AddPatches(response.Patches, a.useAlias, common.PatchStatusPending)
+	fmt.Fprintf(a.out, "\nApplying %d patches...\n\n", len(response.Patches))
 	if err := a.applyPatches(ctx, response.Patches); err != nil {
+		err = a.reportRemoved(err)
+
+		// Pin whatever was applied before the failure
+		if constraintsErr := a.writeConstraints(response.Patches); constraintsErr != nil {
+			return errors.Join(err, constraintsErr)
+		}
 		return err
 	}
 
-	fmt.Printf("\n✓ Successfully patched %d packages!\n", len(response.Patches))
+	fmt.Fprintf(a.out, "\n✓ Successfully patched %d packages!\n", len(response.Patches))
+	a.reporter.ReportFixedCVEs(a.result.RecordFixedCVEs())
+
+	if err := a.writeConstraints(response.Patches); err != nil {
+		return err
+	}
+
+	// --no-deps installs can leave vulnerable versions behind, so check the environment again
+	if a.options.Verify {
+		return a.verify(ctx)
+	}
 
 	return nil
 }
 
-// applyPatches applies patches sequentially, exits on first failure
+// verify lists the installed packages again and confirms no patches remain for them
+func (a *App) verify(ctx context.Context) error {
+	packages, err := a.pipService.ListPackages(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to collect packages for verification: %w", err)
+	}
+	packages, _ = skipLocalInstalls(packages)
+
+	sdkPackages := make([]rootio.Package, len(packages))
+	for i, pkg := range packages {
+		sdkPackages[i] = rootio.Package{
+			Name:      pkg.Name,
+			Version:   pkg.Version,
+			Ecosystem: string(common.EcosystemPyPI),
+		}
+	}
+
+	return common.VerifyPatches(ctx, common.EcosystemPyPI, a.apiClient, sdkPackages, a.options, a.result)
+}
+
+// matchInstalled renames each patch to the installed package it refers to, comparing names
+// after PEP 503 normalization so Flask, flask and zope.interface/zope-interface match.
+// Patches for packages that aren't installed under any spelling are kept as returned.
+func matchInstalled(patches []rootio.PackagePatch, installed []common.InstalledPackage) []rootio.PackagePatch {
+	installedNames := make(map[string]string, len(installed))
+	for _, pkg := range installed {
+		installedNames[normalizeName(pkg.Name)] = pkg.Name
+	}
+
+	matched := make([]rootio.PackagePatch, len(patches))
+	for i, patch := range patches {
+		if name, ok := installedNames[normalizeName(patch.PackageName)]; ok {
+			patch.PackageName = name
+		}
+		matched[i] = patch
+	}
+	return matched
+}
+
+// patchTarget returns the package and version a patch installs, based on useAlias
+func (a *App) patchTarget(patch rootio.PackagePatch) (string, string) {
+	target := common.PatchTarget(patch, a.useAlias)
+	return target.Name, target.Version
+}
+
+// applyPatch installs a single patch. pip itself is upgraded in place instead of uninstalled.
+func (a *App) applyPatch(ctx context.Context, patch rootio.PackagePatch) error {
+	if normalizeName(patch.PackageName) == "pip" {
+		return a.pipService.ApplyPatchForPip(ctx, patch)
+	}
+
+	err := a.pipService.ApplyPatch(ctx, patch)
+	if errors.Is(err, ErrPackageRemoved) {
+		a.restoreOriginal(ctx, patch)
+	}
+	return err
+}
+
+// restoreOriginal reinstalls the original version of a package that its failed patch left
+// uninstalled. It runs even after the run is cancelled, since stopping there would leave the
+// package missing; packages it can't restore are reported by reportRemoved.
+func (a *App) restoreOriginal(ctx context.Context, patch rootio.PackagePatch) {
+	a.logger.WarnContext(ctx, "Patch left the package uninstalled, reinstalling the original version",
+		slog.String("package", patch.PackageName),
+		slog.String("version", patch.Version))
+	fmt.Fprintf(a.out, "  ↺ Reinstalling %s %s, which the failed patch uninstalled...\n", patch.PackageName, patch.Version)
+
+	restoreCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), restoreTimeout)
+	defer cancel()
+	if err := a.pipService.RestorePackage(restoreCtx, patch.PackageName, patch.Version); err != nil {
+		fmt.Fprintf(a.out, "  ✗ Failed to reinstall %s %s: %v\n", patch.PackageName, patch.Version, err)
+		a.removedMu.Lock()
+		defer a.removedMu.Unlock()
+		a.removed = append(a.removed, patch.PackageName+"=="+patch.Version)
+		return
+	}
+	fmt.Fprintf(a.out, "  ✓ Reinstalled %s %s\n", patch.PackageName, patch.Version)
+}
+
+// reportRemoved warns about the packages left uninstalled by failed patches and adds them to err
+func (a *App) reportRemoved(err error) error {
+	if len(a.removed) == 0 {
+		return err
+	}
+
+	fmt.Fprintf(a.out, "\n⚠ %d packages were uninstalled and could not be reinstalled. Install them again with:\n", len(a.removed))
+	fmt.Fprintf(a.out, "  %s -m pip install %s\n", a.pythonPath, strings.Join(a.removed, " "))
+	return errors.Join(err, fmt.Errorf("packages left uninstalled: %s", strings.Join(a.removed, ", ")))
+}
+
+// applyPatches applies patches sequentially, or concurrently with Parallel. By default it exits on
+// the first failure; with KeepGoing it attempts every patch and fails if any of them failed.
 func (a *App) applyPatches(ctx context.Context, patches []rootio.PackagePatch) error {
+	if a.options.Parallel > 1 {
+		return a.applyPatchesParallel(ctx, patches)
+	}
+
+	var failures []string
+
 	for i, patch := range patches {
-		// Select patch info based on config
-		var patchName, patchVersion string
-		if a.useAlias {
-			patchName = patch.PatchAlias.Name
-			patchVersion = patch.PatchAlias.Version
-		} else {
-			patchName = patch.Patch.Name
-			patchVersion = patch.Patch.Version
+		// Stop once the run is cancelled or --timeout expires
+		if err := ctx.Err(); err != nil {
+			return a.stopPatching(patches, i, err)
 		}
 
-		fmt.Printf("[%d/%d] Patching %s (%s → %s)...\n",
+		// Select patch info based on config
+		patchName, patchVersion := a.patchTarget(patch)
+
+		fmt.Fprintf(a.out, "[%d/%d] Patching %s (%s → %s)...\n",
 			i+1, len(patches),
 			patch.PackageName,
 			patch.Version,
@@ -134,21 +325,122 @@ func (a *App) applyPatches(ctx context.Context, patches []rootio.PackagePatch) e
 			slog.String("patch_name", patchName),
 			slog.Bool("use_alias", a.useAlias))
 
-		// Use special handling for pip package - upgrade instead of uninstall+install
-		var err error
-		if strings.EqualFold(patch.PackageName, "pip") {
-			err = a.pipService.ApplyPatchForPip(ctx, patch)
-		} else {
-			err = a.pipService.ApplyPatch(ctx, patch)
-		}
+		if err := a.applyPatch(ctx, patch); err != nil {
+			fmt.Fprintf(a.out, "✗ Patch failed: %v\n", err)
+			a.result.SetPatchStatus(i, common.PatchStatusFailed, err)
+
+			// Out of time: the remaining patches would fail the same way
+			if ctx.Err() != nil {
+				return a.stopPatching(patches, i+1, ctx.Err())
+			}
 
-		if err != nil {
-			fmt.Printf("✗ Patch failed: %v\n", err)
+			if a.options.KeepGoing {
+				a.logger.WarnContext(ctx, "Patch failed, continuing with remaining patches",
+					slog.String("package", patch.PackageName),
+					slog.String("error", err.Error()))
+				failures = append(failures, patch.PackageName)
+				fmt.Fprintln(a.out)
+				continue
+			}
+
+			for j := i + 1; j < len(patches); j++ {
+				a.result.SetPatchStatus(j, common.PatchStatusNotApplied, nil)
+			}
 			return fmt.Errorf("patch failed: %w", err)
 		}
 
-		fmt.Printf("  ✓ Successfully patched %s\n\n", patch.PackageName)
+		a.result.SetPatchStatus(i, common.PatchStatusApplied, nil)
+		a.recordPatch(ctx, patch, patchName, patchVersion)
+		fmt.Fprintf(a.out, "  ✓ Successfully patched %s\n\n", patch.PackageName)
+	}
+
+	if len(failures) > 0 {
+		fmt.Fprintf(a.out, "\nPatched %d of %d packages, %d failed:\n", len(patches)-len(failures), len(patches), len(failures))
+		for _, name := range failures {
+			fmt.Fprintf(a.out, "  ✗ %s\n", name)
+		}
+		return fmt.Errorf("%d of %d patches failed: %s", len(failures), len(patches), strings.Join(failures, ", "))
 	}
 
 	return nil
 }
+
+// stopPatching marks the patches from next on as not applied after the run was cancelled or
+// timed out, and reports the ones that were already applied
+func (a *App) stopPatching(patches []rootio.PackagePatch, next int, cause error) error {
+	for j := next; j < len(patches); j++ {
+		a.result.SetPatchStatus(j, common.PatchStatusNotApplied, nil)
+	}
+
+	var applied []string
+	for i, patch := range patches {
+		if a.result.Patches[i].Status == common.PatchStatusApplied {
+			applied = append(applied, patch.PackageName)
+		}
+	}
+
+	fmt.Fprintf(a.out, "\nStopped early: patched %d of %d packages before the run was interrupted\n", len(applied), len(patches))
+	for _, name := range applied {
+		fmt.Fprintf(a.out, "  ✓ %s\n", name)
+	}
+	return fmt.Errorf("stopped after patching %d of %d packages: %w", len(applied), len(patches), cause)
+}
+
+// writeConstraints pins every applied patch in the constraints file and explains how to use it.
+// The pins name the installed distribution, which is the Root.io alias in alias mode; pins of
+// the original packages would hold them at the vulnerable version and are removed.
+func (a *App) writeConstraints(patches []rootio.PackagePatch) error {
+	if a.options.ConstraintsPath == "" {
+		return nil
+	}
+
+	pins := make(map[string]string)
+	var replaced []string
+	for i, patch := range patches {
+		if a.result.Patches[i].Status != common.PatchStatusApplied {
+			continue
+		}
+		name, version := a.patchTarget(patch)
+		pins[name] = version
+		replaced = append(replaced, patch.PackageName)
+	}
+	if len(pins) == 0 {
+		return nil
+	}
+
+	if err := WriteConstraints(a.options.ConstraintsPath, pins, replaced); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(a.out, "\nPinned %d patched packages in %s\n", len(pins), a.options.ConstraintsPath)
+	fmt.Fprintln(a.out, "To keep them patched when reinstalling, pass the constraints file to pip:")
+	fmt.Fprintf(a.out, "  pip install -r requirements.txt -c %s\n", a.options.ConstraintsPath)
+	if a.useAlias {
+		fmt.Fprintln(a.out, "The pins name the Root.io aliases. Replace the original packages in your requirements with them,")
+		fmt.Fprintln(a.out, "since a constraint can't stop pip from installing the original package next to its alias.")
+	}
+	return nil
+}
+
+// recordPatch appends an applied patch to the journal so it can be rolled back later
+func (a *App) recordPatch(ctx context.Context, patch rootio.PackagePatch, patchName, patchVersion string) {
+	if a.options.JournalPath == "" {
+		return
+	}
+
+	entry := JournalEntry{
+		Action:          JournalActionApplied,
+		PackageName:     patch.PackageName,
+		OriginalVersion: patch.Version,
+		PatchedName:     patchName,
+		PatchedVersion:  patchVersion,
+	}
+
+	// The patch is already installed, so a journal failure is reported but doesn't abort the run
+	if err := AppendJournal(a.options.JournalPath, entry); err != nil {
+		a.logger.WarnContext(ctx, "Failed to record patch in journal",
+			slog.String("package", patch.PackageName),
+			slog.String("journal", a.options.JournalPath),
+			slog.String("error", err.Error()))
+	}
+}
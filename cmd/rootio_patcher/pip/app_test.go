@@ -1,11 +1,17 @@
 package pip
 
 import (
+	"bytes"
 	"context"
 	"errors"
+	"fmt"
 	"log/slog"
 	"os"
+	"path/filepath"
+	"slices"
+	"strings"
 	"testing"
+	"time"
 
 	"rootio_patcher/cmd/rootio_patcher/common"
 	"rootio_patcher/cmd/rootio_patcher/config"
@@ -58,6 +64,30 @@ func TestPipApp_Run_CollectorError(t *testing.T) {
 	}
 }
 
+func TestPipApp_Run_PipNotAvailable(t *testing.T) {
+	ctx := context.Background()
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+
+	expectedError := errors.New("No module named pip")
+	mockPipService := &MockPipService{
+		CheckPipFunc: func(ctx context.Context) error {
+			return expectedError
+		},
+		ListPackagesFunc: func(ctx context.Context) ([]common.InstalledPackage, error) {
+			t.Error("ListPackages should not be called when pip is missing")
+			return nil, nil
+		},
+	}
+
+	cfg := &config.Config{}
+	app := NewAppWithServices(cfg, "python", true, true, logger, mockPipService, &MockAPIClient{}, nil)
+
+	err := app.Run(ctx)
+	if !errors.Is(err, expectedError) {
+		t.Fatalf("Expected error to wrap pip check error, got: %v", err)
+	}
+}
+
 func TestPipApp_Run_APIError(t *testing.T) {
 	ctx := context.Background()
 	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
@@ -213,6 +243,203 @@ func TestPipApp_Run_ApplyPatches(t *testing.T) {
 	}
 }
 
+func TestPipApp_Run_EmptyPatchAlias(t *testing.T) {
+	ctx := context.Background()
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+
+	var applied []string
+	mockPipService := &MockPipService{
+		ListPackagesFunc: func(ctx context.Context) ([]common.InstalledPackage, error) {
+			return []common.InstalledPackage{
+				{Name: "django", Version: "4.0.0"},
+				{Name: "flask", Version: "2.0.0"},
+			}, nil
+		},
+		ApplyPatchFunc: func(ctx context.Context, patch rootio.PackagePatch) error {
+			applied = append(applied, patch.PackageName)
+			return nil
+		},
+	}
+
+	mockAPIClient := &MockAPIClient{
+		AnalyzePackagesFunc: func(ctx context.Context, packages []rootio.Package) (*rootio.AnalyzePackagesResponse, error) {
+			return &rootio.AnalyzePackagesResponse{
+				Patches: []rootio.PackagePatch{
+					{PackageName: "django", Version: "4.0.0", Patch: rootio.PatchInfo{Name: "django", Version: "4.0.1"}},
+					{PackageName: "flask", Version: "2.0.0"},
+				},
+			}, nil
+		},
+	}
+
+	mockReporter := common.NewReporter("https://pkg.root.io", logger)
+	cfg := &config.Config{}
+	app := NewAppWithServices(cfg, "python", false, true, logger, mockPipService, mockAPIClient, mockReporter)
+
+	if err := app.Run(ctx); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	// flask has nothing to install, so it's skipped rather than uninstalled
+	if !slices.Equal(applied, []string{"django"}) {
+		t.Errorf("Expected only django to be patched, got %v", applied)
+	}
+	result := app.Result()
+	if len(result.Skipped) != 1 || result.Skipped[0].PackageName != "flask" {
+		t.Errorf("Expected flask to be skipped, got %+v", result.Skipped)
+	}
+	if patched := result.Patches[0]; patched.PatchedName != "django" || patched.PatchedVersion != "4.0.1" {
+		t.Errorf("Expected django without an alias to fall back to django 4.0.1, got %+v", patched)
+	}
+}
+
+func TestPipApp_Run_WritesConstraints(t *testing.T) {
+	tests := []struct {
+		name     string
+		useAlias bool
+		expected string
+	}{
+		{
+			name:     "direct patches",
+			expected: "django==4.0.1\nflask==2.0.1\n",
+		},
+		{
+			name:     "aliased patches pin the alias",
+			useAlias: true,
+			expected: "rootio-django==4.0.1\nrootio-flask==2.0.1\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+
+			// Existing constraints are kept, except the stale pin of a patched package
+			path := filepath.Join(t.TempDir(), "constraints.txt")
+			if err := os.WriteFile(path, []byte("numpy<2\nDjango==4.0.0\n"), 0644); err != nil {
+				t.Fatalf("Failed to write constraints file: %v", err)
+			}
+
+			mockPipService := &MockPipService{
+				ListPackagesFunc: func(ctx context.Context) ([]common.InstalledPackage, error) {
+					return []common.InstalledPackage{
+						{Name: "django", Version: "4.0.0"},
+						{Name: "flask", Version: "2.0.0"},
+						{Name: "requests", Version: "2.25.0"},
+					}, nil
+				},
+				ApplyPatchFunc: func(ctx context.Context, patch rootio.PackagePatch) error {
+					if patch.PackageName == "requests" {
+						return errors.New("install failed")
+					}
+					return nil
+				},
+			}
+
+			mockAPIClient := &MockAPIClient{
+				AnalyzePackagesFunc: func(ctx context.Context, packages []rootio.Package) (*rootio.AnalyzePackagesResponse, error) {
+					return &rootio.AnalyzePackagesResponse{
+						Patches: []rootio.PackagePatch{
+							{
+								PackageName: "django",
+								Version:     "4.0.0",
+								Patch:       rootio.PatchInfo{Name: "django", Version: "4.0.1"},
+								PatchAlias:  rootio.PatchInfo{Name: "rootio-django", Version: "4.0.1"},
+							},
+							{
+								PackageName: "requests",
+								Version:     "2.25.0",
+								Patch:       rootio.PatchInfo{Name: "requests", Version: "2.25.1"},
+								PatchAlias:  rootio.PatchInfo{Name: "rootio-requests", Version: "2.25.1"},
+							},
+							{
+								PackageName: "flask",
+								Version:     "2.0.0",
+								Patch:       rootio.PatchInfo{Name: "flask", Version: "2.0.1"},
+								PatchAlias:  rootio.PatchInfo{Name: "rootio-flask", Version: "2.0.1"},
+							},
+						},
+					}, nil
+				},
+			}
+
+			mockReporter := common.NewReporter("https://pkg.root.io", logger)
+			cfg := &config.Config{}
+			app := NewAppWithServices(cfg, "python", false, tt.useAlias, logger, mockPipService, mockAPIClient, mockReporter,
+				common.WithConstraints(path), common.WithKeepGoing(true))
+
+			// requests fails, so only the applied patches are pinned
+			if err := app.Run(ctx); err == nil {
+				t.Fatal("Expected the failed patch to be reported")
+			}
+
+			content, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatalf("Failed to read constraints file: %v", err)
+			}
+			expected := constraintsHeader + "\nnumpy<2\n" + tt.expected
+			if string(content) != expected {
+				t.Errorf("Expected constraints:\n%s\ngot:\n%s", expected, content)
+			}
+		})
+	}
+}
+
+func TestPipApp_Run_RejectsInvalidPatchNames(t *testing.T) {
+	ctx := context.Background()
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+
+	var applied []string
+	mockPipService := &MockPipService{
+		ListPackagesFunc: func(ctx context.Context) ([]common.InstalledPackage, error) {
+			return []common.InstalledPackage{
+				{Name: "django", Version: "4.0.0"},
+				{Name: "flask", Version: "2.0.0"},
+			}, nil
+		},
+		ApplyPatchFunc: func(ctx context.Context, patch rootio.PackagePatch) error {
+			applied = append(applied, patch.PackageName)
+			return nil
+		},
+	}
+
+	mockAPIClient := &MockAPIClient{
+		AnalyzePackagesFunc: func(ctx context.Context, packages []rootio.Package) (*rootio.AnalyzePackagesResponse, error) {
+			return &rootio.AnalyzePackagesResponse{
+				Patches: []rootio.PackagePatch{
+					{
+						PackageName: "django",
+						Version:     "4.0.0",
+						PatchAlias:  rootio.PatchInfo{Name: "rootio-django", Version: "4.0.1"},
+					},
+					{
+						PackageName: "flask",
+						Version:     "2.0.0",
+						PatchAlias:  rootio.PatchInfo{Name: "../../rootio-flask $(id)", Version: "2.0.1"},
+					},
+				},
+			}, nil
+		},
+	}
+
+	mockReporter := common.NewReporter("https://pkg.root.io", logger)
+	app := NewAppWithServices(&config.Config{}, "python", false, true, logger, mockPipService, mockAPIClient, mockReporter)
+
+	result, err := app.RunWithResult(ctx)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	if len(applied) != 1 || applied[0] != "django" {
+		t.Errorf("Expected only django to be installed, got %v", applied)
+	}
+	if len(result.Skipped) != 1 || result.Skipped[0].PackageName != "flask" ||
+		!strings.Contains(result.Skipped[0].Reason, "invalid alias name") {
+		t.Errorf("Expected flask to be rejected for its alias name, got %+v", result.Skipped)
+	}
+}
+
 func TestPipApp_Run_ApplyPatchError(t *testing.T) {
 	ctx := context.Background()
 	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
@@ -356,3 +583,430 @@ func TestPipApp_Run_MultiplePatches(t *testing.T) {
 		t.Fatalf("Expected 2 patches to be applied, got %d", patchCount)
 	}
 }
+
+func TestPipApp_Run_PatchesDependenciesFirst(t *testing.T) {
+	ctx := context.Background()
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+
+	var applied []string
+	mockPipService := &MockPipService{
+		ListPackagesFunc: func(ctx context.Context) ([]common.InstalledPackage, error) {
+			return []common.InstalledPackage{
+				{Name: "requests", Version: "2.25.0"},
+				{Name: "urllib3", Version: "1.26.0"},
+			}, nil
+		},
+		PackageDependenciesFunc: func(ctx context.Context, names []string) (map[string][]string, error) {
+			return map[string][]string{
+				"requests": {"certifi", "urllib3"},
+				"urllib3":  nil,
+			}, nil
+		},
+		ApplyPatchFunc: func(ctx context.Context, patch rootio.PackagePatch) error {
+			applied = append(applied, patch.PackageName)
+			return nil
+		},
+	}
+
+	// requests (B) depends on urllib3 (A) but comes first in the API response
+	mockAPIClient := &MockAPIClient{
+		AnalyzePackagesFunc: func(ctx context.Context, packages []rootio.Package) (*rootio.AnalyzePackagesResponse, error) {
+			return &rootio.AnalyzePackagesResponse{
+				Patches: []rootio.PackagePatch{
+					{
+						PackageName: "requests",
+						Version:     "2.25.0",
+						Patch:       rootio.PatchInfo{Name: "requests", Version: "2.25.1"},
+						PatchAlias:  rootio.PatchInfo{Name: "rootio-requests", Version: "2.25.1"},
+					},
+					{
+						PackageName: "urllib3",
+						Version:     "1.26.0",
+						Patch:       rootio.PatchInfo{Name: "urllib3", Version: "1.26.5"},
+						PatchAlias:  rootio.PatchInfo{Name: "rootio-urllib3", Version: "1.26.5"},
+					},
+				},
+			}, nil
+		},
+	}
+
+	mockReporter := common.NewReporter("https://pkg.root.io", logger)
+	cfg := &config.Config{}
+	app := NewAppWithServices(cfg, "python", false, true, logger, mockPipService, mockAPIClient, mockReporter)
+
+	if err := app.Run(ctx); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	if !slices.Equal(applied, []string{"urllib3", "requests"}) {
+		t.Errorf("Expected urllib3 to be patched before requests, got %v", applied)
+	}
+	if name := app.Result().Patches[0].PackageName; name != "urllib3" {
+		t.Errorf("Expected the result to list urllib3 first, got %s", name)
+	}
+}
+
+func TestPipApp_Run_ResultRecordsPartialFailure(t *testing.T) {
+	ctx := context.Background()
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+
+	mockPipService := &MockPipService{
+		ListPackagesFunc: func(ctx context.Context) ([]common.InstalledPackage, error) {
+			return []common.InstalledPackage{
+				{Name: "django", Version: "4.0.0"},
+				{Name: "flask", Version: "2.0.0"},
+				{Name: "requests", Version: "2.6.0"},
+			}, nil
+		},
+		ApplyPatchFunc: func(ctx context.Context, patch rootio.PackagePatch) error {
+			if patch.PackageName == "flask" {
+				return errors.New("install failed")
+			}
+			return nil
+		},
+	}
+
+	mockAPIClient := &MockAPIClient{
+		AnalyzePackagesFunc: func(ctx context.Context, packages []rootio.Package) (*rootio.AnalyzePackagesResponse, error) {
+			return &rootio.AnalyzePackagesResponse{
+				Patches: []rootio.PackagePatch{
+					{PackageName: "django", Version: "4.0.0", PatchAlias: rootio.PatchInfo{Name: "rootio-django", Version: "4.0.1"}},
+					{PackageName: "flask", Version: "2.0.0", PatchAlias: rootio.PatchInfo{Name: "rootio-flask", Version: "2.0.1"}},
+					{PackageName: "requests", Version: "2.6.0", PatchAlias: rootio.PatchInfo{Name: "rootio-requests", Version: "2.6.1"}},
+				},
+			}, nil
+		},
+	}
+
+	mockReporter := common.NewReporter("https://pkg.root.io", logger)
+	cfg := &config.Config{}
+	app := NewAppWithServices(cfg, "python", false, true, logger, mockPipService, mockAPIClient, mockReporter)
+
+	if err := app.Run(ctx); err == nil {
+		t.Fatal("Expected error, got nil")
+	}
+
+	result := app.Result()
+	if result.PackagesFound != 3 {
+		t.Errorf("Expected 3 packages found, got %d", result.PackagesFound)
+	}
+
+	expected := []common.PatchStatus{common.PatchStatusApplied, common.PatchStatusFailed, common.PatchStatusNotApplied}
+	for i, status := range expected {
+		if result.Patches[i].Status != status {
+			t.Errorf("Expected patch %d status '%s', got '%s'", i, status, result.Patches[i].Status)
+		}
+	}
+}
+
+func TestPipApp_Run_KeepGoing(t *testing.T) {
+	ctx := context.Background()
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+
+	var attempted []string
+	mockPipService := &MockPipService{
+		ListPackagesFunc: func(ctx context.Context) ([]common.InstalledPackage, error) {
+			return []common.InstalledPackage{
+				{Name: "django", Version: "4.0.0"},
+				{Name: "flask", Version: "2.0.0"},
+				{Name: "requests", Version: "2.6.0"},
+			}, nil
+		},
+		ApplyPatchFunc: func(ctx context.Context, patch rootio.PackagePatch) error {
+			attempted = append(attempted, patch.PackageName)
+			if patch.PackageName == "flask" {
+				return errors.New("install failed")
+			}
+			return nil
+		},
+	}
+
+	mockAPIClient := &MockAPIClient{
+		AnalyzePackagesFunc: func(ctx context.Context, packages []rootio.Package) (*rootio.AnalyzePackagesResponse, error) {
+			return &rootio.AnalyzePackagesResponse{
+				Patches: []rootio.PackagePatch{
+					{PackageName: "django", Version: "4.0.0", PatchAlias: rootio.PatchInfo{Name: "rootio-django", Version: "4.0.1"}},
+					{PackageName: "flask", Version: "2.0.0", PatchAlias: rootio.PatchInfo{Name: "rootio-flask", Version: "2.0.1"}},
+					{PackageName: "requests", Version: "2.6.0", PatchAlias: rootio.PatchInfo{Name: "rootio-requests", Version: "2.6.1"}},
+				},
+			}, nil
+		},
+	}
+
+	mockReporter := common.NewReporter("https://pkg.root.io", logger)
+	cfg := &config.Config{}
+	app := NewAppWithServices(cfg, "python", false, true, logger, mockPipService, mockAPIClient, mockReporter,
+		common.WithKeepGoing(true))
+
+	err := app.Run(ctx)
+	if err == nil {
+		t.Fatal("Expected error when a patch fails, got nil")
+	}
+	if !strings.Contains(err.Error(), "1 of 3 patches failed") {
+		t.Errorf("Expected failure summary in error, got: %v", err)
+	}
+
+	if len(attempted) != 3 {
+		t.Errorf("Expected all 3 patches to be attempted, got %v", attempted)
+	}
+
+	expected := []common.PatchStatus{common.PatchStatusApplied, common.PatchStatusFailed, common.PatchStatusApplied}
+	for i, status := range expected {
+		if app.Result().Patches[i].Status != status {
+			t.Errorf("Expected patch %d status '%s', got '%s'", i, status, app.Result().Patches[i].Status)
+		}
+	}
+}
+
+func TestPipApp_Run_TimeoutReportsPartialPatches(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+
+	mockPipService := &MockPipService{
+		ListPackagesFunc: func(ctx context.Context) ([]common.InstalledPackage, error) {
+			return []common.InstalledPackage{
+				{Name: "django", Version: "4.0.0"},
+				{Name: "flask", Version: "2.0.0"},
+				{Name: "requests", Version: "2.6.0"},
+			}, nil
+		},
+		ApplyPatchFunc: func(ctx context.Context, patch rootio.PackagePatch) error {
+			if patch.PackageName == "flask" {
+				// A hung install only returns once the deadline cancels it
+				<-ctx.Done()
+				return ctx.Err()
+			}
+			return nil
+		},
+	}
+
+	mockAPIClient := &MockAPIClient{
+		AnalyzePackagesFunc: func(ctx context.Context, packages []rootio.Package) (*rootio.AnalyzePackagesResponse, error) {
+			return &rootio.AnalyzePackagesResponse{
+				Patches: []rootio.PackagePatch{
+					{PackageName: "django", Version: "4.0.0", PatchAlias: rootio.PatchInfo{Name: "rootio-django", Version: "4.0.1"}},
+					{PackageName: "flask", Version: "2.0.0", PatchAlias: rootio.PatchInfo{Name: "rootio-flask", Version: "2.0.1"}},
+					{PackageName: "requests", Version: "2.6.0", PatchAlias: rootio.PatchInfo{Name: "rootio-requests", Version: "2.6.1"}},
+				},
+			}, nil
+		},
+	}
+
+	mockReporter := common.NewReporter("https://pkg.root.io", logger)
+	cfg := &config.Config{}
+	app := NewAppWithServices(cfg, "python", false, true, logger, mockPipService, mockAPIClient, mockReporter,
+		common.WithKeepGoing(true))
+
+	err := app.Run(ctx)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Expected a deadline error, got: %v", err)
+	}
+	if !strings.Contains(err.Error(), "stopped after patching 1 of 3 packages") {
+		t.Errorf("Expected the partial progress in the error, got: %v", err)
+	}
+
+	// --keep-going doesn't keep trying once the run is out of time
+	expected := []common.PatchStatus{common.PatchStatusApplied, common.PatchStatusFailed, common.PatchStatusNotApplied}
+	for i, status := range expected {
+		if app.Result().Patches[i].Status != status {
+			t.Errorf("Expected patch %d status '%s', got '%s'", i, status, app.Result().Patches[i].Status)
+		}
+	}
+}
+
+func TestPipApp_Run_InterruptAfterUninstallRestoresPackage(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+
+	var restored []string
+	mockPipService := &MockPipService{
+		ListPackagesFunc: func(ctx context.Context) ([]common.InstalledPackage, error) {
+			return []common.InstalledPackage{
+				{Name: "django", Version: "4.0.0"},
+				{Name: "flask", Version: "2.0.0"},
+			}, nil
+		},
+		ApplyPatchFunc: func(ctx context.Context, patch rootio.PackagePatch) error {
+			// Ctrl-C arrives after django was uninstalled, before the patch is installed
+			cancel()
+			return fmt.Errorf("%w: %w", ErrPackageRemoved, ctx.Err())
+		},
+		RestorePackageFunc: func(ctx context.Context, name, version string) error {
+			if ctx.Err() != nil {
+				t.Errorf("Expected the restore to run on a live context, got: %v", ctx.Err())
+			}
+			restored = append(restored, name+"=="+version)
+			return nil
+		},
+	}
+
+	mockAPIClient := &MockAPIClient{
+		AnalyzePackagesFunc: func(ctx context.Context, packages []rootio.Package) (*rootio.AnalyzePackagesResponse, error) {
+			return &rootio.AnalyzePackagesResponse{
+				Patches: []rootio.PackagePatch{
+					{PackageName: "django", Version: "4.0.0", PatchAlias: rootio.PatchInfo{Name: "rootio-django", Version: "4.0.1"}},
+					{PackageName: "flask", Version: "2.0.0", PatchAlias: rootio.PatchInfo{Name: "rootio-flask", Version: "2.0.1"}},
+				},
+			}, nil
+		},
+	}
+
+	mockReporter := common.NewReporter("https://pkg.root.io", logger)
+	cfg := &config.Config{}
+	app := NewAppWithServices(cfg, "python", false, true, logger, mockPipService, mockAPIClient, mockReporter)
+
+	err := app.Run(ctx)
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("Expected a cancellation error, got: %v", err)
+	}
+	if strings.Contains(err.Error(), "left uninstalled") {
+		t.Errorf("Expected no packages reported as uninstalled after a successful restore, got: %v", err)
+	}
+	if !slices.Equal(restored, []string{"django==4.0.0"}) {
+		t.Errorf("Expected django 4.0.0 to be reinstalled, got %v", restored)
+	}
+
+	expected := []common.PatchStatus{common.PatchStatusFailed, common.PatchStatusNotApplied}
+	for i, status := range expected {
+		if app.Result().Patches[i].Status != status {
+			t.Errorf("Expected patch %d status '%s', got '%s'", i, status, app.Result().Patches[i].Status)
+		}
+	}
+}
+
+func TestPipApp_Run_ReportsPackagesLeftUninstalled(t *testing.T) {
+	ctx := context.Background()
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+
+	mockPipService := &MockPipService{
+		ListPackagesFunc: func(ctx context.Context) ([]common.InstalledPackage, error) {
+			return []common.InstalledPackage{{Name: "django", Version: "4.0.0"}}, nil
+		},
+		ApplyPatchFunc: func(ctx context.Context, patch rootio.PackagePatch) error {
+			return fmt.Errorf("%w: install failed: network unreachable", ErrPackageRemoved)
+		},
+		RestorePackageFunc: func(ctx context.Context, name, version string) error {
+			return errors.New("network unreachable")
+		},
+	}
+
+	mockAPIClient := &MockAPIClient{
+		AnalyzePackagesFunc: func(ctx context.Context, packages []rootio.Package) (*rootio.AnalyzePackagesResponse, error) {
+			return &rootio.AnalyzePackagesResponse{
+				Patches: []rootio.PackagePatch{
+					{PackageName: "django", Version: "4.0.0", PatchAlias: rootio.PatchInfo{Name: "rootio-django", Version: "4.0.1"}},
+				},
+			}, nil
+		},
+	}
+
+	mockReporter := common.NewReporter("https://pkg.root.io", logger)
+	cfg := &config.Config{}
+	app := NewAppWithServices(cfg, "python", false, true, logger, mockPipService, mockAPIClient, mockReporter)
+
+	err := app.Run(ctx)
+	if err == nil || !strings.Contains(err.Error(), "packages left uninstalled: django==4.0.0") {
+		t.Errorf("Expected django to be reported as left uninstalled, got: %v", err)
+	}
+}
+
+func TestPipApp_Run_ReportsSkippedPackages(t *testing.T) {
+	ctx := context.Background()
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+
+	mockPipService := &MockPipService{
+		ListPackagesFunc: func(ctx context.Context) ([]common.InstalledPackage, error) {
+			return []common.InstalledPackage{
+				{Name: "django", Version: "4.0.0"},
+				{Name: "numpy", Version: "1.20.0"},
+			}, nil
+		},
+	}
+
+	mockAPIClient := &MockAPIClient{
+		AnalyzePackagesFunc: func(ctx context.Context, packages []rootio.Package) (*rootio.AnalyzePackagesResponse, error) {
+			return &rootio.AnalyzePackagesResponse{
+				Patches: []rootio.PackagePatch{
+					{PackageName: "django", Version: "4.0.0", PatchAlias: rootio.PatchInfo{Name: "rootio-django", Version: "4.0.1"}},
+				},
+				Skipped: []rootio.SkippedPackage{
+					{PackageName: "numpy", Reason: "no patched version available"},
+				},
+			}, nil
+		},
+	}
+
+	var buf bytes.Buffer
+	reporter := common.NewReporterWithWriter("https://pkg.root.io", logger, &buf)
+	cfg := &config.Config{}
+	app := NewAppWithServices(cfg, "python", true, true, logger, mockPipService, mockAPIClient, reporter)
+
+	if err := app.Run(ctx); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	if !strings.Contains(buf.String(), "  - numpy: no patched version available") {
+		t.Errorf("Expected skipped reason in output, got:\n%s", buf.String())
+	}
+}
+
+func TestPipApp_Run_MatchesNormalizedNames(t *testing.T) {
+	ctx := context.Background()
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+
+	var uninstalled []string
+	pipUpgraded := false
+	mockPipService := &MockPipService{
+		ListPackagesFunc: func(ctx context.Context) ([]common.InstalledPackage, error) {
+			return []common.InstalledPackage{
+				{Name: "Flask", Version: "2.0.0"},
+				{Name: "zope.interface", Version: "5.4.0"},
+				{Name: "typing_extensions", Version: "4.0.0"},
+				{Name: "PIP", Version: "22.0"},
+			}, nil
+		},
+		ApplyPatchFunc: func(ctx context.Context, patch rootio.PackagePatch) error {
+			uninstalled = append(uninstalled, patch.PackageName)
+			return nil
+		},
+		ApplyPatchForPipFunc: func(ctx context.Context, patch rootio.PackagePatch) error {
+			pipUpgraded = true
+			return nil
+		},
+	}
+
+	// The API answers with normalized names
+	mockAPIClient := &MockAPIClient{
+		AnalyzePackagesFunc: func(ctx context.Context, packages []rootio.Package) (*rootio.AnalyzePackagesResponse, error) {
+			return &rootio.AnalyzePackagesResponse{
+				Patches: []rootio.PackagePatch{
+					{PackageName: "flask", Version: "2.0.0", PatchAlias: rootio.PatchInfo{Name: "rootio-flask", Version: "2.0.1"}},
+					{PackageName: "zope-interface", Version: "5.4.0", PatchAlias: rootio.PatchInfo{Name: "rootio-zope-interface", Version: "5.4.1"}},
+					{PackageName: "Typing-Extensions", Version: "4.0.0", PatchAlias: rootio.PatchInfo{Name: "rootio-typing-extensions", Version: "4.0.1"}},
+					{PackageName: "pip", Version: "22.0", PatchAlias: rootio.PatchInfo{Name: "pip", Version: "22.0.1"}},
+				},
+			}, nil
+		},
+	}
+
+	cfg := &config.Config{}
+	app := NewAppWithServices(cfg, "python", false, true, logger, mockPipService, mockAPIClient,
+		common.NewReporter("https://pkg.root.io", logger))
+
+	if err := app.Run(ctx); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	expected := []string{"Flask", "zope.interface", "typing_extensions"}
+	if strings.Join(uninstalled, ",") != strings.Join(expected, ",") {
+		t.Errorf("Expected patches to use the installed names %v, got %v", expected, uninstalled)
+	}
+	if !pipUpgraded {
+		t.Error("Expected PIP to be upgraded in place like pip")
+	}
+	if name := app.Result().Patches[0].PackageName; name != "Flask" {
+		t.Errorf("Expected the result to use the installed name Flask, got %s", name)
+	}
+}
@@ -0,0 +1,62 @@
+package pip
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// constraintsHeader is the first line of a constraints file written by pip remediate
+const constraintsHeader = "# Patched versions pinned by rootio_patcher. Use with: pip install -c <this file>"
+
+// constraintNamePattern matches the package name at the start of a constraint line
+var constraintNamePattern = regexp.MustCompile(`^\s*([A-Za-z0-9][A-Za-z0-9._-]*)`)
+
+// WriteConstraints pins each package in pins to its version in the pip constraints file at path,
+// creating it if needed. Constraints for the pinned packages and the replaced ones (the originals
+// of aliased packages), compared after PEP 503 normalization, are removed; all others are kept.
+func WriteConstraints(path string, pins map[string]string, replaced []string) error {
+	//nolint:gosec // Constraints path is provided by the user
+	existing, err := os.ReadFile(path)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("failed to read constraints file: %w", err)
+	}
+
+	pinned := make(map[string]bool, len(pins)+len(replaced))
+	for name := range pins {
+		pinned[normalizeName(name)] = true
+	}
+	for _, name := range replaced {
+		pinned[normalizeName(name)] = true
+	}
+
+	lines := []string{constraintsHeader}
+	for _, line := range strings.Split(strings.TrimRight(string(existing), "\n"), "\n") {
+		if line == "" || line == constraintsHeader {
+			continue
+		}
+		if match := constraintNamePattern.FindStringSubmatch(line); match != nil && pinned[normalizeName(match[1])] {
+			continue
+		}
+		lines = append(lines, line)
+	}
+
+	names := make([]string, 0, len(pins))
+	for name := range pins {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		lines = append(lines, fmt.Sprintf("%s==%s", name, pins[name]))
+	}
+
+	//nolint:gosec // Constraints path is provided by the user
+	if err := os.WriteFile(path, []byte(strings.Join(lines, "\n")+"\n"), 0644); err != nil {
+		return fmt.Errorf("failed to write constraints file: %w", err)
+	}
+	return nil
+}
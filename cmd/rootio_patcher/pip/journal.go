@@ -0,0 +1,123 @@
+package pip
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"strings"
+	"time"
+)
+
+// DefaultJournalPath is the default location of the patch journal
+const DefaultJournalPath = ".rootio_patcher.journal"
+
+// JournalAction describes what a journal entry records
+type JournalAction string
+
+const (
+	JournalActionApplied    JournalAction = "applied"
+	JournalActionRolledBack JournalAction = "rolled_back"
+)
+
+// JournalEntry records a single applied or rolled back patch
+type JournalEntry struct {
+	Timestamp       time.Time     `json:"timestamp"`
+	Action          JournalAction `json:"action"`
+	PackageName     string        `json:"package_name"`
+	OriginalVersion string        `json:"original_version"`
+	PatchedName     string        `json:"patched_name"`
+	PatchedVersion  string        `json:"patched_version"`
+}
+
+// AppendJournal appends an entry to the journal file, creating it if needed.
+// Each entry is written and synced on its own so a crash never loses earlier entries.
+func AppendJournal(path string, entry JournalEntry) error {
+	if entry.Timestamp.IsZero() {
+		entry.Timestamp = time.Now().UTC()
+	}
+
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("failed to marshal journal entry: %w", err)
+	}
+
+	//nolint:gosec // Journal path is provided by the user
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open journal: %w", err)
+	}
+	defer file.Close()
+
+	if _, err := file.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("failed to write journal: %w", err)
+	}
+
+	if err := file.Sync(); err != nil {
+		return fmt.Errorf("failed to sync journal: %w", err)
+	}
+
+	return nil
+}
+
+// ReadJournal reads all entries from the journal file. A missing journal has no entries.
+func ReadJournal(path string) ([]JournalEntry, error) {
+	//nolint:gosec // Journal path is provided by the user
+	file, err := os.Open(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open journal: %w", err)
+	}
+	defer file.Close()
+
+	var entries []JournalEntry
+	scanner := bufio.NewScanner(file)
+	for lineNum := 1; scanner.Scan(); lineNum++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+
+		var entry JournalEntry
+		if err := json.Unmarshal([]byte(line), &entry); err != nil {
+			return nil, fmt.Errorf("failed to parse journal line %d: %w", lineNum, err)
+		}
+		entries = append(entries, entry)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read journal: %w", err)
+	}
+
+	return entries, nil
+}
+
+// PendingRollbacks returns applied patches that have not been rolled back yet, most recent first
+func PendingRollbacks(entries []JournalEntry) []JournalEntry {
+	var pending []JournalEntry
+	for _, entry := range entries {
+		switch entry.Action {
+		case JournalActionApplied:
+			pending = append(pending, entry)
+		case JournalActionRolledBack:
+			// Drop the most recent matching applied entry
+			for i := len(pending) - 1; i >= 0; i-- {
+				if pending[i].PackageName == entry.PackageName && pending[i].PatchedName == entry.PatchedName &&
+					pending[i].PatchedVersion == entry.PatchedVersion {
+					pending = append(pending[:i], pending[i+1:]...)
+					break
+				}
+			}
+		}
+	}
+
+	// Undo in reverse order of application
+	for i, j := 0, len(pending)-1; i < j; i, j = i+1, j-1 {
+		pending[i], pending[j] = pending[j], pending[i]
+	}
+
+	return pending
+}
@@ -0,0 +1,93 @@
+package pip
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"rootio_patcher/cmd/rootio_patcher/common"
+	"rootio_patcher/pkg/rootio"
+)
+
+// directURL is the direct_url.json pip records for a package installed from a URL or a local
+// directory instead of an index (PEP 610)
+type directURL struct {
+	URL     string `json:"url"`
+	VCSInfo *struct {
+		VCS string `json:"vcs"`
+	} `json:"vcs_info,omitempty"`
+	DirInfo *struct {
+		Editable bool `json:"editable"`
+	} `json:"dir_info,omitempty"`
+}
+
+// markDirectURLs fills in the editable location and VCS URL of packages installed from a
+// repository or a local project, read from the direct_url.json in their .dist-info directory.
+// Packages without a location or an install record are left as they are.
+func markDirectURLs(packages []common.InstalledPackage) {
+	for i, pkg := range packages {
+		if pkg.Location == "" {
+			continue
+		}
+		record, ok := readDirectURL(pkg.Location, pkg.Name, pkg.Version)
+		if !ok {
+			continue
+		}
+		switch {
+		case record.VCSInfo != nil:
+			packages[i].VCSURL = record.VCSInfo.VCS + "+" + record.URL
+		case record.DirInfo != nil && record.DirInfo.Editable && pkg.EditableLocation == "":
+			packages[i].EditableLocation = strings.TrimPrefix(record.URL, "file://")
+		}
+	}
+}
+
+// readDirectURL reads the direct_url.json of an installed distribution. Installers write the
+// .dist-info directory with the name normalized or as published, so names are compared after
+// PEP 503 normalization.
+func readDirectURL(location, name, version string) (*directURL, bool) {
+	dirs, err := filepath.Glob(filepath.Join(location, "*.dist-info"))
+	if err != nil {
+		return nil, false
+	}
+	for _, dir := range dirs {
+		distName, distVersion, ok := strings.Cut(strings.TrimSuffix(filepath.Base(dir), ".dist-info"), "-")
+		if !ok || distVersion != version || normalizeName(distName) != normalizeName(name) {
+			continue
+		}
+		content, err := os.ReadFile(filepath.Join(dir, "direct_url.json"))
+		if err != nil {
+			return nil, false
+		}
+		var record directURL
+		if err := json.Unmarshal(content, &record); err != nil {
+			return nil, false
+		}
+		return &record, true
+	}
+	return nil, false
+}
+
+// skipLocalInstalls separates editable and VCS installs. They're built from source the
+// project controls, so the API has nothing to match them against, and reinstalling them from
+// the index would replace a developer's checkout.
+func skipLocalInstalls(packages []common.InstalledPackage) ([]common.InstalledPackage, []rootio.SkippedPackage) {
+	var kept []common.InstalledPackage
+	var skipped []rootio.SkippedPackage
+	for _, pkg := range packages {
+		var reason string
+		switch {
+		case pkg.EditableLocation != "":
+			reason = fmt.Sprintf("editable install from %s; not installed from a package index", pkg.EditableLocation)
+		case pkg.VCSURL != "":
+			reason = fmt.Sprintf("installed from %s; not installed from a package index", pkg.VCSURL)
+		default:
+			kept = append(kept, pkg)
+			continue
+		}
+		skipped = append(skipped, rootio.SkippedPackage{PackageName: pkg.Name, Reason: reason})
+	}
+	return kept, skipped
+}
@@ -0,0 +1,97 @@
+package pip
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"rootio_patcher/cmd/rootio_patcher/common"
+	"rootio_patcher/cmd/rootio_patcher/config"
+	"rootio_patcher/pkg/rootio"
+)
+
+func TestMarkDirectURLs(t *testing.T) {
+	sitePackages := t.TempDir()
+	records := map[string]string{
+		"my_tool-0.0.0.dist-info":   `{"url": "https://github.com/acme/my-tool.git", "vcs_info": {"vcs": "git", "commit_id": "4f2a9c1"}}`,
+		"local_lib-1.0.0.dist-info": `{"url": "file:///home/dev/local-lib", "dir_info": {"editable": true}}`,
+		"requests-2.25.0.dist-info": "",
+	}
+	for dir, record := range records {
+		if err := os.MkdirAll(filepath.Join(sitePackages, dir), 0755); err != nil {
+			t.Fatalf("Failed to create %s: %v", dir, err)
+		}
+		if record == "" {
+			continue
+		}
+		if err := os.WriteFile(filepath.Join(sitePackages, dir, "direct_url.json"), []byte(record), 0644); err != nil {
+			t.Fatalf("Failed to write direct_url.json: %v", err)
+		}
+	}
+
+	packages := []common.InstalledPackage{
+		{Name: "my-tool", Version: "0.0.0", Location: sitePackages},
+		{Name: "local-lib", Version: "1.0.0", Location: sitePackages},
+		{Name: "requests", Version: "2.25.0", Location: sitePackages},
+		{Name: "django", Version: "4.0.0"},
+	}
+	markDirectURLs(packages)
+
+	expected := []common.InstalledPackage{
+		{Name: "my-tool", Version: "0.0.0", Location: sitePackages, VCSURL: "git+https://github.com/acme/my-tool.git"},
+		{Name: "local-lib", Version: "1.0.0", Location: sitePackages, EditableLocation: "/home/dev/local-lib"},
+		{Name: "requests", Version: "2.25.0", Location: sitePackages},
+		{Name: "django", Version: "4.0.0"},
+	}
+	if !reflect.DeepEqual(packages, expected) {
+		t.Errorf("Expected %+v, got %+v", expected, packages)
+	}
+}
+
+func TestPipApp_Run_SkipsLocalInstalls(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	mockPipService := &MockPipService{
+		ListPackagesFunc: func(ctx context.Context) ([]common.InstalledPackage, error) {
+			return []common.InstalledPackage{
+				{Name: "django", Version: "4.0.0"},
+				{Name: "my-app", Version: "0.1.0", EditableLocation: "/home/dev/my-app"},
+				{Name: "my-tool", Version: "0.0.0", VCSURL: "git+https://github.com/acme/my-tool.git"},
+			}, nil
+		},
+	}
+
+	var analyzed []rootio.Package
+	mockAPIClient := &MockAPIClient{
+		AnalyzePackagesFunc: func(ctx context.Context, packages []rootio.Package) (*rootio.AnalyzePackagesResponse, error) {
+			analyzed = packages
+			return &rootio.AnalyzePackagesResponse{}, nil
+		},
+	}
+
+	reporter := common.NewReporterWithWriter("https://pkg.root.io", logger, io.Discard)
+	app := NewAppWithServices(&config.Config{}, "python", true, true, logger, mockPipService, mockAPIClient, reporter,
+		common.WithOutput(io.Discard))
+	result, err := app.RunWithResult(context.Background())
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	if len(analyzed) != 1 || analyzed[0].Name != "django" {
+		t.Errorf("Expected only django to be analyzed, got %+v", analyzed)
+	}
+	expectedSkipped := []common.SkippedResult{
+		{PackageName: "my-app", Reason: "editable install from /home/dev/my-app; not installed from a package index"},
+		{PackageName: "my-tool", Reason: "installed from git+https://github.com/acme/my-tool.git; not installed from a package index"},
+	}
+	if !reflect.DeepEqual(result.Skipped, expectedSkipped) {
+		t.Errorf("Expected skipped %+v, got %+v", expectedSkipped, result.Skipped)
+	}
+	if result.PackagesFound != 3 {
+		t.Errorf("Expected 3 packages found, got %d", result.PackagesFound)
+	}
+}
@@ -21,9 +21,21 @@ func (m *MockAPIClient) AnalyzePackages(ctx context.Context, packages []rootio.P
 
 // MockPipService is a mock implementation of Service for testing
 type MockPipService struct {
+	CheckPipFunc         func(ctx context.Context) error
 	ListPackagesFunc     func(ctx context.Context) ([]common.InstalledPackage, error)
 	ApplyPatchFunc       func(ctx context.Context, patch rootio.PackagePatch) error
 	ApplyPatchForPipFunc func(ctx context.Context, patch rootio.PackagePatch) error
+	RevertPatchFunc      func(ctx context.Context, entry JournalEntry) error
+	RestorePackageFunc   func(ctx context.Context, name, version string) error
+
+	PackageDependenciesFunc func(ctx context.Context, names []string) (map[string][]string, error)
+}
+
+func (m *MockPipService) CheckPip(ctx context.Context) error {
+	if m.CheckPipFunc != nil {
+		return m.CheckPipFunc(ctx)
+	}
+	return nil
 }
 
 func (m *MockPipService) ListPackages(ctx context.Context) ([]common.InstalledPackage, error) {
@@ -46,3 +58,24 @@ func (m *MockPipService) ApplyPatchForPip(ctx context.Context, patch rootio.Pack
 	}
 	return nil
 }
+
+func (m *MockPipService) RevertPatch(ctx context.Context, entry JournalEntry) error {
+	if m.RevertPatchFunc != nil {
+		return m.RevertPatchFunc(ctx, entry)
+	}
+	return nil
+}
+
+func (m *MockPipService) RestorePackage(ctx context.Context, name, version string) error {
+	if m.RestorePackageFunc != nil {
+		return m.RestorePackageFunc(ctx, name, version)
+	}
+	return nil
+}
+
+func (m *MockPipService) PackageDependencies(ctx context.Context, names []string) (map[string][]string, error) {
+	if m.PackageDependenciesFunc != nil {
+		return m.PackageDependenciesFunc(ctx, names)
+	}
+	return map[string][]string{}, nil
+}
@@ -0,0 +1,97 @@
+package pip
+
+import (
+	"context"
+	"log/slog"
+
+	"rootio_patcher/pkg/rootio"
+)
+
+// orderByDependencies sorts patches so each package is patched after the patched packages it
+// depends on. Patches are installed with --no-deps, so patching a dependent first would leave it
+// installed next to a dependency that is about to be uninstalled. Independent packages keep the
+// API order, which is also used if the dependencies can't be listed.
+func (a *App) orderByDependencies(ctx context.Context, patches []rootio.PackagePatch) []rootio.PackagePatch {
+	if len(patches) < 2 {
+		return patches
+	}
+
+	names := make([]string, len(patches))
+	for i, patch := range patches {
+		names[i] = patch.PackageName
+	}
+
+	dependencies, err := a.pipService.PackageDependencies(ctx, names)
+	if err != nil {
+		a.logger.WarnContext(ctx, "Failed to list dependencies of patched packages; patching in API order",
+			slog.String("error", err.Error()))
+		return patches
+	}
+
+	a.dependencies = dependencies
+	return orderPatches(patches, dependencies)
+}
+
+// orderPatches topologically sorts patches by the dependencies between them, always taking the
+// earliest patch whose dependencies are done. Patches in a dependency cycle keep their order.
+func orderPatches(patches []rootio.PackagePatch, dependencies map[string][]string) []rootio.PackagePatch {
+	requires := patchRequirements(patches, dependencies)
+	ordered := make([]rootio.PackagePatch, 0, len(patches))
+	done := make([]bool, len(patches))
+	for len(ordered) < len(patches) {
+		next := -1
+		for i := range patches {
+			if !done[i] && allDone(requires[i], done) {
+				next = i
+				break
+			}
+		}
+
+		// Every remaining patch waits on another one: break the cycle in the original order
+		if next < 0 {
+			for i := range patches {
+				if !done[i] {
+					next = i
+					break
+				}
+			}
+		}
+
+		done[next] = true
+		ordered = append(ordered, patches[next])
+	}
+
+	return ordered
+}
+
+// patchRequirements returns, for each patch, the indexes of the other patches it depends on
+func patchRequirements(patches []rootio.PackagePatch, dependencies map[string][]string) [][]int {
+	index := make(map[string]int, len(patches))
+	for i, patch := range patches {
+		index[normalizeName(patch.PackageName)] = i
+	}
+
+	requires := make([][]int, len(patches))
+	for name, requirements := range dependencies {
+		i, ok := index[normalizeName(name)]
+		if !ok {
+			continue
+		}
+		for _, requirement := range requirements {
+			if j, ok := index[normalizeName(requirement)]; ok && j != i {
+				requires[i] = append(requires[i], j)
+			}
+		}
+	}
+	return requires
+}
+
+// allDone reports whether every patch in indexes is done
+func allDone(indexes []int, done []bool) bool {
+	for _, i := range indexes {
+		if !done[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,134 @@
+package pip
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"rootio_patcher/cmd/rootio_patcher/common"
+	"rootio_patcher/pkg/rootio"
+)
+
+// applyPatchesParallel applies patches with up to Parallel pip processes at once. A patch for pip
+// itself runs first and on its own, so pip is never replaced while other installs use it, and a
+// patch starts only once the patches it depends on have finished. Patches arrive in dependency
+// order, so only earlier patches are waited for, which also breaks dependency cycles.
+// After a failure no new patches are started unless KeepGoing is set; running ones finish.
+func (a *App) applyPatchesParallel(ctx context.Context, patches []rootio.PackagePatch) error {
+	var pipPatches, otherPatches []int
+	for i, patch := range patches {
+		if normalizeName(patch.PackageName) == "pip" {
+			pipPatches = append(pipPatches, i)
+		} else {
+			otherPatches = append(otherPatches, i)
+		}
+	}
+
+	// mu guards failures, the result and the journal, which the workers share
+	var mu sync.Mutex
+	var failures []error
+
+	apply := func(i int) {
+		patch := patches[i]
+		patchName, patchVersion := a.patchTarget(patch)
+		fmt.Fprintf(a.out, "Patching %s (%s → %s)...\n", patch.PackageName, patch.Version, patchVersion)
+
+		err := a.applyPatch(ctx, patch)
+
+		mu.Lock()
+		defer mu.Unlock()
+		if err != nil {
+			fmt.Fprintf(a.out, "  ✗ Patch failed for %s: %v\n", patch.PackageName, err)
+			a.result.SetPatchStatus(i, common.PatchStatusFailed, err)
+			failures = append(failures, fmt.Errorf("%s: %w", patch.PackageName, err))
+			return
+		}
+		a.result.SetPatchStatus(i, common.PatchStatusApplied, nil)
+		a.recordPatch(ctx, patch, patchName, patchVersion)
+		fmt.Fprintf(a.out, "  ✓ Successfully patched %s\n", patch.PackageName)
+	}
+
+	// stopped reports whether no more patches should be started
+	stopped := func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return ctx.Err() != nil || (len(failures) > 0 && !a.options.KeepGoing)
+	}
+
+	// done[i] is closed once patch i has finished or won't be started
+	requires := patchRequirements(patches, a.dependencies)
+	done := make([]chan struct{}, len(patches))
+	for i := range done {
+		done[i] = make(chan struct{})
+	}
+
+	for _, i := range pipPatches {
+		if !stopped() {
+			apply(i)
+		}
+		close(done[i])
+	}
+
+	workers := make(chan struct{}, a.options.Parallel)
+	var wg sync.WaitGroup
+	for _, i := range otherPatches {
+		// Wait for the earlier patches this one depends on
+		for _, j := range requires[i] {
+			if j < i {
+				<-done[j]
+			}
+		}
+		if stopped() {
+			break
+		}
+		workers <- struct{}{}
+		// A patch may have failed while waiting for a free worker
+		if stopped() {
+			<-workers
+			break
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer close(done[i])
+			defer func() { <-workers }()
+			apply(i)
+		}()
+	}
+	wg.Wait()
+
+	// Patches that were never started
+	for i := range patches {
+		if a.result.Patches[i].Status == common.PatchStatusPending {
+			a.result.SetPatchStatus(i, common.PatchStatusNotApplied, nil)
+		}
+	}
+
+	// Stop early once the run is cancelled or --timeout expires
+	if err := ctx.Err(); err != nil {
+		return a.stopPatching(patches, len(patches), err)
+	}
+
+	a.reportStatus(patches)
+	if len(failures) > 0 {
+		return fmt.Errorf("%d of %d patches failed: %w", len(failures), len(patches), errors.Join(failures...))
+	}
+	return nil
+}
+
+// reportStatus prints the outcome of every patch, since parallel output interleaves
+func (a *App) reportStatus(patches []rootio.PackagePatch) {
+	fmt.Fprintln(a.out, "\nPatch status:")
+	for i, patch := range patches {
+		switch a.result.Patches[i].Status {
+		case common.PatchStatusApplied:
+			fmt.Fprintf(a.out, "  ✓ %s\n", patch.PackageName)
+		case common.PatchStatusFailed:
+			fmt.Fprintf(a.out, "  ✗ %s\n", patch.PackageName)
+		default:
+			fmt.Fprintf(a.out, "  - %s (not applied)\n", patch.PackageName)
+		}
+	}
+}
@@ -0,0 +1,278 @@
+package pip
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"rootio_patcher/cmd/rootio_patcher/common"
+	"rootio_patcher/cmd/rootio_patcher/config"
+	"rootio_patcher/pkg/rootio"
+)
+
+// parallelPatches returns patches for count packages, pkg0 to pkgN
+func parallelPatches(count int) ([]common.InstalledPackage, []rootio.PackagePatch) {
+	var installed []common.InstalledPackage
+	var patches []rootio.PackagePatch
+	for i := range count {
+		name := fmt.Sprintf("pkg%d", i)
+		installed = append(installed, common.InstalledPackage{Name: name, Version: "1.0.0"})
+		patches = append(patches, rootio.PackagePatch{
+			PackageName: name,
+			Version:     "1.0.0",
+			PatchAlias:  rootio.PatchInfo{Name: "rootio-" + name, Version: "1.0.1"},
+		})
+	}
+	return installed, patches
+}
+
+// concurrencyTracker records how many patches run at the same time
+type concurrencyTracker struct {
+	mu      sync.Mutex
+	active  int
+	max     int
+	started []string
+}
+
+func (c *concurrencyTracker) start(name string) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.active++
+	c.max = max(c.max, c.active)
+	c.started = append(c.started, name)
+	return c.active
+}
+
+func (c *concurrencyTracker) done() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.active--
+}
+
+func TestPipApp_Run_ParallelBoundsConcurrency(t *testing.T) {
+	ctx := context.Background()
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+
+	installed, patches := parallelPatches(8)
+	installed = append(installed, common.InstalledPackage{Name: "pip", Version: "23.0"})
+	patches = append(patches, rootio.PackagePatch{
+		PackageName: "pip",
+		Version:     "23.0",
+		PatchAlias:  rootio.PatchInfo{Name: "pip", Version: "23.0.1"},
+	})
+
+	tracker := &concurrencyTracker{}
+	mockPipService := &MockPipService{
+		ListPackagesFunc: func(ctx context.Context) ([]common.InstalledPackage, error) {
+			return installed, nil
+		},
+		ApplyPatchFunc: func(ctx context.Context, patch rootio.PackagePatch) error {
+			tracker.start(patch.PackageName)
+			defer tracker.done()
+			time.Sleep(20 * time.Millisecond)
+			return nil
+		},
+		ApplyPatchForPipFunc: func(ctx context.Context, patch rootio.PackagePatch) error {
+			if active := tracker.start(patch.PackageName); active != 1 {
+				t.Errorf("Expected pip to be patched on its own, %d patches were running", active)
+			}
+			defer tracker.done()
+			time.Sleep(20 * time.Millisecond)
+			return nil
+		},
+	}
+
+	mockAPIClient := &MockAPIClient{
+		AnalyzePackagesFunc: func(ctx context.Context, packages []rootio.Package) (*rootio.AnalyzePackagesResponse, error) {
+			return &rootio.AnalyzePackagesResponse{Patches: patches}, nil
+		},
+	}
+
+	mockReporter := common.NewReporter("https://pkg.root.io", logger)
+	app := NewAppWithServices(&config.Config{}, "python", false, true, logger, mockPipService, mockAPIClient, mockReporter,
+		common.WithParallel(3))
+
+	if err := app.Run(ctx); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	if tracker.max > 3 {
+		t.Errorf("Expected at most 3 concurrent patches, got %d", tracker.max)
+	}
+	if tracker.max < 2 {
+		t.Errorf("Expected patches to run concurrently, max concurrency was %d", tracker.max)
+	}
+	if tracker.started[0] != "pip" {
+		t.Errorf("Expected pip to be patched first, got order %v", tracker.started)
+	}
+	if applied := app.Result().Applied(); applied != len(patches) {
+		t.Errorf("Expected %d patches applied, got %d", len(patches), applied)
+	}
+}
+
+func TestPipApp_Run_ParallelStopsAfterFailure(t *testing.T) {
+	ctx := context.Background()
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+
+	installed, patches := parallelPatches(5)
+
+	tracker := &concurrencyTracker{}
+	mockPipService := &MockPipService{
+		ListPackagesFunc: func(ctx context.Context) ([]common.InstalledPackage, error) {
+			return installed, nil
+		},
+		ApplyPatchFunc: func(ctx context.Context, patch rootio.PackagePatch) error {
+			tracker.start(patch.PackageName)
+			defer tracker.done()
+			if patch.PackageName == "pkg0" {
+				return errors.New("install failed")
+			}
+			time.Sleep(50 * time.Millisecond)
+			return nil
+		},
+	}
+
+	mockAPIClient := &MockAPIClient{
+		AnalyzePackagesFunc: func(ctx context.Context, packages []rootio.Package) (*rootio.AnalyzePackagesResponse, error) {
+			return &rootio.AnalyzePackagesResponse{Patches: patches}, nil
+		},
+	}
+
+	mockReporter := common.NewReporter("https://pkg.root.io", logger)
+	app := NewAppWithServices(&config.Config{}, "python", false, true, logger, mockPipService, mockAPIClient, mockReporter,
+		common.WithParallel(2))
+
+	err := app.Run(ctx)
+	if err == nil {
+		t.Fatal("Expected error when a patch fails, got nil")
+	}
+	if !strings.Contains(err.Error(), "1 of 5 patches failed") || !strings.Contains(err.Error(), "pkg0: install failed") {
+		t.Errorf("Expected the failure to be aggregated, got: %v", err)
+	}
+
+	// pkg1 was already running when pkg0 failed and finishes; nothing else starts
+	if len(tracker.started) != 2 {
+		t.Errorf("Expected no patches to start after the failure, started %v", tracker.started)
+	}
+	expected := []common.PatchStatus{
+		common.PatchStatusFailed,
+		common.PatchStatusApplied,
+		common.PatchStatusNotApplied,
+		common.PatchStatusNotApplied,
+		common.PatchStatusNotApplied,
+	}
+	for i, status := range expected {
+		if app.Result().Patches[i].Status != status {
+			t.Errorf("Expected patch %d status '%s', got '%s'", i, status, app.Result().Patches[i].Status)
+		}
+	}
+}
+
+func TestPipApp_Run_ParallelKeepGoing(t *testing.T) {
+	ctx := context.Background()
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+
+	installed, patches := parallelPatches(5)
+
+	tracker := &concurrencyTracker{}
+	mockPipService := &MockPipService{
+		ListPackagesFunc: func(ctx context.Context) ([]common.InstalledPackage, error) {
+			return installed, nil
+		},
+		ApplyPatchFunc: func(ctx context.Context, patch rootio.PackagePatch) error {
+			tracker.start(patch.PackageName)
+			defer tracker.done()
+			if patch.PackageName == "pkg1" || patch.PackageName == "pkg3" {
+				return errors.New("install failed")
+			}
+			return nil
+		},
+	}
+
+	mockAPIClient := &MockAPIClient{
+		AnalyzePackagesFunc: func(ctx context.Context, packages []rootio.Package) (*rootio.AnalyzePackagesResponse, error) {
+			return &rootio.AnalyzePackagesResponse{Patches: patches}, nil
+		},
+	}
+
+	mockReporter := common.NewReporter("https://pkg.root.io", logger)
+	app := NewAppWithServices(&config.Config{}, "python", false, true, logger, mockPipService, mockAPIClient, mockReporter,
+		common.WithParallel(2), common.WithKeepGoing(true))
+
+	err := app.Run(ctx)
+	if err == nil || !strings.Contains(err.Error(), "2 of 5 patches failed") {
+		t.Errorf("Expected 2 failures to be reported, got: %v", err)
+	}
+	if len(tracker.started) != 5 {
+		t.Errorf("Expected all 5 patches to be attempted, got %v", tracker.started)
+	}
+	if applied := app.Result().Applied(); applied != 3 {
+		t.Errorf("Expected 3 patches applied, got %d", applied)
+	}
+}
+
+func TestPipApp_Run_ParallelWaitsForDependencies(t *testing.T) {
+	ctx := context.Background()
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	// The API lists requests before urllib3, which it depends on
+	installed, patches := parallelPatches(3)
+	patches[0].PackageName, installed[0].Name = "requests", "requests"
+	patches[1].PackageName, installed[1].Name = "urllib3", "urllib3"
+
+	var mu sync.Mutex
+	var events []string
+	mockPipService := &MockPipService{
+		ListPackagesFunc: func(ctx context.Context) ([]common.InstalledPackage, error) {
+			return installed, nil
+		},
+		PackageDependenciesFunc: func(ctx context.Context, names []string) (map[string][]string, error) {
+			return map[string][]string{"requests": {"urllib3", "idna"}}, nil
+		},
+		ApplyPatchFunc: func(ctx context.Context, patch rootio.PackagePatch) error {
+			mu.Lock()
+			events = append(events, "start "+patch.PackageName)
+			mu.Unlock()
+			time.Sleep(20 * time.Millisecond)
+			mu.Lock()
+			events = append(events, "end "+patch.PackageName)
+			mu.Unlock()
+			return nil
+		},
+	}
+
+	mockAPIClient := &MockAPIClient{
+		AnalyzePackagesFunc: func(ctx context.Context, packages []rootio.Package) (*rootio.AnalyzePackagesResponse, error) {
+			return &rootio.AnalyzePackagesResponse{Patches: patches}, nil
+		},
+	}
+
+	mockReporter := common.NewReporterWithWriter("https://pkg.root.io", logger, io.Discard)
+	app := NewAppWithServices(&config.Config{}, "python", false, true, logger, mockPipService, mockAPIClient, mockReporter,
+		common.WithParallel(2), common.WithOutput(io.Discard))
+
+	if err := app.Run(ctx); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	position := make(map[string]int, len(events))
+	for i, event := range events {
+		position[event] = i
+	}
+	if len(events) != 6 {
+		t.Fatalf("Expected 3 patches to run, got %v", events)
+	}
+	if position["end urllib3"] > position["start requests"] {
+		t.Errorf("Expected urllib3 to be applied before requests starts, got %v", events)
+	}
+	if applied := app.Result().Applied(); applied != 3 {
+		t.Errorf("Expected 3 patches applied, got %d", applied)
+	}
+}
@@ -0,0 +1,351 @@
+package pip
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"rootio_patcher/cmd/rootio_patcher/common"
+)
+
+var (
+	// requirementPattern matches a requirement: name, optional extras, and the version specifier
+	requirementPattern = regexp.MustCompile(`^([A-Za-z0-9][A-Za-z0-9._-]*)\s*(\[[^\]]*\])?\s*(.*)$`)
+
+	// pinnedVersionPattern matches a single exact "==" pin
+	pinnedVersionPattern = regexp.MustCompile(`^==\s*([^\s,;*]+)$`)
+
+	// compatibleVersionPattern matches versions ~= accepts: two or more release segments and no local label
+	compatibleVersionPattern = regexp.MustCompile(`^([0-9]+!)?[0-9]+(\.[0-9]+)+[^+]*$`)
+)
+
+// Constraint styles for the patched versions written to requirements files
+const (
+	ConstraintExact      = "exact"      // package==X
+	ConstraintCompatible = "compatible" // package~=X
+	ConstraintMinimum    = "minimum"    // package>=X
+)
+
+// RequirementsParser handles parsing of pip requirements.txt files
+type RequirementsParser struct {
+	constraintStyle string
+}
+
+// ParserOption configures a RequirementsParser
+type ParserOption func(*RequirementsParser)
+
+// WithConstraintStyle sets how Update writes patched versions (ConstraintExact when empty)
+func WithConstraintStyle(style string) ParserOption {
+	return func(p *RequirementsParser) {
+		p.constraintStyle = style
+	}
+}
+
+// NewParser creates a new requirements.txt parser
+func NewParser(opts ...ParserOption) *RequirementsParser {
+	p := &RequirementsParser{}
+	for _, opt := range opts {
+		opt(p)
+	}
+	return p
+}
+
+// constraintOperator returns the operator that writes version in the given style. PEP 440 only
+// allows local versions (4.2.0+root.io.1) after ==, and ~= needs two release segments, so other
+// versions are still pinned exactly.
+func constraintOperator(style, version string) string {
+	switch {
+	case style == ConstraintCompatible && compatibleVersionPattern.MatchString(version):
+		return "~="
+	case style == ConstraintMinimum && !strings.Contains(version, "+"):
+		return ">="
+	}
+	return "=="
+}
+
+// Ecosystem returns the ecosystem name
+func (p *RequirementsParser) Ecosystem() common.Ecosystem {
+	return common.EcosystemPyPI
+}
+
+// FilePatterns returns file patterns this parser handles
+func (p *RequirementsParser) FilePatterns() []string {
+	return []string{"requirements.txt"}
+}
+
+// CanHandle checks if this parser can handle the given file
+func (p *RequirementsParser) CanHandle(fileName string) bool {
+	base := filepath.Base(fileName)
+	return strings.HasPrefix(base, "requirements") && strings.HasSuffix(base, ".txt")
+}
+
+// requirement represents a single parsed requirement line
+type requirement struct {
+	Name      string
+	Extras    string
+	Specifier string
+	Version   string // Pinned version, empty if not pinned with ==
+}
+
+// Parse parses a requirements file (following -r includes) and returns all packages
+func (p *RequirementsParser) Parse(ctx context.Context, filePath string) ([]common.PackageInfo, error) {
+	return p.ParseFiles(ctx, []string{filePath})
+}
+
+// ParseFiles parses several requirements files (following -r includes) and returns all
+// packages. A file included by more than one of them, or listed as well as included, is
+// read once.
+func (p *RequirementsParser) ParseFiles(ctx context.Context, filePaths []string) ([]common.PackageInfo, error) {
+	visited := make(map[string]bool)
+	var packages []common.PackageInfo
+	for _, filePath := range filePaths {
+		parsed, err := p.parseFile(filePath, visited)
+		if err != nil {
+			return nil, err
+		}
+		packages = append(packages, parsed...)
+	}
+	return packages, nil
+}
+
+// isDevRequirements reports whether a requirements file holds development or test
+// dependencies by its name, as requirements-dev.txt, dev-requirements.txt or requirements/test.txt
+func isDevRequirements(filePath string) bool {
+	name := strings.ToLower(strings.TrimSuffix(filepath.Base(filePath), filepath.Ext(filePath)))
+	for _, token := range strings.FieldsFunc(name, func(r rune) bool { return r == '-' || r == '_' || r == '.' }) {
+		switch token {
+		case "dev", "develop", "development", "test", "tests":
+			return true
+		}
+	}
+	return false
+}
+
+// parseFile parses a single requirements file, recursing into -r includes
+func (p *RequirementsParser) parseFile(filePath string, visited map[string]bool) ([]common.PackageInfo, error) {
+	absPath, err := filepath.Abs(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve path %s: %w", filePath, err)
+	}
+	if visited[absPath] {
+		return nil, nil
+	}
+	visited[absPath] = true
+
+	content, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read file: %w", err)
+	}
+
+	var packages []common.PackageInfo
+	dev := isDevRequirements(filePath)
+	continued := false
+
+	for _, line := range strings.Split(string(content), "\n") {
+		// Lines following a trailing backslash belong to the previous requirement
+		isContinuation := continued
+		continued = strings.HasSuffix(strings.TrimRight(line, " \t\r"), `\`)
+		if isContinuation {
+			continue
+		}
+
+		if include, ok := parseIncludeLine(line); ok {
+			includePath := include
+			if !filepath.IsAbs(includePath) {
+				includePath = filepath.Join(filepath.Dir(filePath), include)
+			}
+			included, err := p.parseFile(includePath, visited)
+			if err != nil {
+				return nil, fmt.Errorf("failed to parse included file %s: %w", include, err)
+			}
+			packages = append(packages, included...)
+			continue
+		}
+
+		req, ok := parseRequirementLine(line)
+		if !ok {
+			continue
+		}
+
+		packages = append(packages, common.PackageInfo{
+			Name:              req.Name,
+			Version:           req.Version,
+			VersionConstraint: req.Specifier,
+			Ecosystem:         common.EcosystemPyPI,
+			Direct:            true, // Requirements files only list declared dependencies
+			Dev:               dev,
+			Location:          filePath,
+		})
+	}
+
+	return packages, nil
+}
+
+// parseIncludeLine returns the target of a "-r" / "--requirement" line
+func parseIncludeLine(line string) (string, bool) {
+	fields := strings.Fields(stripComment(line))
+	if len(fields) == 0 {
+		return "", false
+	}
+
+	switch {
+	case fields[0] == "-r" || fields[0] == "--requirement":
+		if len(fields) < 2 {
+			return "", false
+		}
+		return fields[1], true
+	case strings.HasPrefix(fields[0], "--requirement="):
+		return strings.TrimPrefix(fields[0], "--requirement="), true
+	case strings.HasPrefix(fields[0], "-r") && len(fields[0]) > 2:
+		return strings.TrimPrefix(fields[0], "-r"), true
+	}
+
+	return "", false
+}
+
+// parseRequirementLine parses a requirement line, ignoring comments, options and markers
+func parseRequirementLine(line string) (requirement, bool) {
+	line = strings.TrimSpace(stripComment(line))
+	line = strings.TrimSpace(strings.TrimSuffix(line, `\`))
+
+	// Skip blank lines and options (-r, -c, -e, --index-url, ...)
+	if line == "" || strings.HasPrefix(line, "-") {
+		return requirement{}, false
+	}
+
+	// Drop environment markers: django==4.2; python_version >= "3.8"
+	if idx := strings.Index(line, ";"); idx != -1 {
+		line = strings.TrimSpace(line[:idx])
+	}
+
+	// Drop per-requirement options: django==4.2 --hash=sha256:...
+	if idx := strings.Index(line, " --"); idx != -1 {
+		line = strings.TrimSpace(line[:idx])
+	}
+
+	matches := requirementPattern.FindStringSubmatch(line)
+	if matches == nil {
+		return requirement{}, false
+	}
+
+	req := requirement{
+		Name:      matches[1],
+		Extras:    matches[2],
+		Specifier: strings.TrimSpace(matches[3]),
+	}
+
+	// Direct references (name @ url) have no version
+	if strings.HasPrefix(req.Specifier, "@") {
+		req.Specifier = ""
+		return req, true
+	}
+
+	if pinned := pinnedVersionPattern.FindStringSubmatch(req.Specifier); pinned != nil {
+		req.Version = pinned[1]
+	}
+
+	return req, true
+}
+
+// stripComment removes a trailing "# comment" from a requirements line
+func stripComment(line string) string {
+	if strings.HasPrefix(strings.TrimSpace(line), "#") {
+		return ""
+	}
+	if idx := strings.Index(line, " #"); idx != -1 {
+		return line[:idx]
+	}
+	if idx := strings.Index(line, "\t#"); idx != -1 {
+		return line[:idx]
+	}
+	return line
+}
+
+// keepsExactPin reports whether a range was requested but version can only be pinned exactly
+func (p *RequirementsParser) keepsExactPin(version string) bool {
+	if p.constraintStyle == "" || p.constraintStyle == ConstraintExact {
+		return false
+	}
+	return constraintOperator(p.constraintStyle, version) == "=="
+}
+
+// Update updates pinned versions in a single requirements file, preserving extras, markers,
+// comments and ordering. Patched versions are written in the parser's constraint style.
+// Included files are not modified; call Update on each file separately.
+func (p *RequirementsParser) Update(ctx context.Context, filePath string, updates map[string]string) (string, error) {
+	content, err := os.ReadFile(filePath)
+	if err != nil {
+		return "", fmt.Errorf("failed to read file: %w", err)
+	}
+
+	// Package names are case-insensitive in pip, and -, _ and . are equivalent
+	normalizedUpdates := make(map[string]string, len(updates))
+	for name, version := range updates {
+		normalizedUpdates[normalizeName(name)] = version
+	}
+
+	lines := strings.Split(string(content), "\n")
+	continued := false
+
+	for i, line := range lines {
+		isContinuation := continued
+		continued = strings.HasSuffix(strings.TrimRight(line, " \t\r"), `\`)
+		if isContinuation {
+			continue
+		}
+
+		req, ok := parseRequirementLine(line)
+		if !ok || req.Version == "" {
+			continue
+		}
+
+		newVersion, ok := normalizedUpdates[normalizeName(req.Name)]
+		if !ok {
+			continue
+		}
+
+		// Replace only the pin, leaving extras, markers and comments intact
+		operator := constraintOperator(p.constraintStyle, newVersion)
+		pattern := regexp.MustCompile(`(==\s*)` + regexp.QuoteMeta(req.Version))
+		replaced := false
+		lines[i] = pattern.ReplaceAllStringFunc(line, func(match string) string {
+			if replaced {
+				return match
+			}
+			replaced = true
+			if operator == "==" {
+				return strings.TrimSuffix(match, req.Version) + newVersion
+			}
+			return operator + newVersion
+		})
+	}
+
+	return strings.Join(lines, "\n"), nil
+}
+
+// Validate checks that every requirement line in the content can be parsed
+func (p *RequirementsParser) Validate(content string) bool {
+	continued := false
+
+	for _, line := range strings.Split(content, "\n") {
+		isContinuation := continued
+		continued = strings.HasSuffix(strings.TrimRight(line, " \t\r"), `\`)
+		if isContinuation {
+			continue
+		}
+
+		trimmed := strings.TrimSpace(stripComment(line))
+		if trimmed == "" || strings.HasPrefix(trimmed, "-") {
+			continue
+		}
+
+		if _, ok := parseRequirementLine(line); !ok {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,364 @@
+package pip
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"rootio_patcher/cmd/rootio_patcher/common"
+)
+
+func TestRequirementsParser_Ecosystem(t *testing.T) {
+	parser := NewParser()
+	if parser.Ecosystem() != common.EcosystemPyPI {
+		t.Errorf("Expected ecosystem 'pypi', got '%s'", parser.Ecosystem())
+	}
+}
+
+func TestRequirementsParser_CanHandle(t *testing.T) {
+	parser := NewParser()
+
+	tests := []struct {
+		fileName string
+		expected bool
+	}{
+		{"requirements.txt", true},
+		{"requirements-dev.txt", true},
+		{"app/requirements.txt", true},
+		{"package-lock.json", false},
+		{"pom.xml", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.fileName, func(t *testing.T) {
+			result := parser.CanHandle(tt.fileName)
+			if result != tt.expected {
+				t.Errorf("Expected CanHandle('%s') = %v, got %v", tt.fileName, tt.expected, result)
+			}
+		})
+	}
+}
+
+func TestParseRequirementLine(t *testing.T) {
+	tests := []struct {
+		line            string
+		expectedOK      bool
+		expectedName    string
+		expectedVersion string
+		expectedSpec    string
+	}{
+		{"django==4.2.0", true, "django", "4.2.0", "==4.2.0"},
+		{"Django == 4.2.0  # pinned for CVE", true, "Django", "4.2.0", "== 4.2.0"},
+		{"requests[security,socks]==2.28.0", true, "requests", "2.28.0", "==2.28.0"},
+		{`flask==2.1.2; python_version >= "3.8"`, true, "flask", "2.1.2", "==2.1.2"},
+		{"urllib3>=1.26", true, "urllib3", "", ">=1.26"},
+		{"numpy>=1.0,<2.0", true, "numpy", "", ">=1.0,<2.0"},
+		{"django==4.*", true, "django", "", "==4.*"},
+		{"six", true, "six", "", ""},
+		{"certifi==2020.12.5 --hash=sha256:abc", true, "certifi", "2020.12.5", "==2020.12.5"},
+		{"mypkg @ https://example.com/mypkg.tar.gz", true, "mypkg", "", ""},
+		{"# comment", false, "", "", ""},
+		{"", false, "", "", ""},
+		{"-r other.txt", false, "", "", ""},
+		{"--index-url https://pypi.org/simple", false, "", "", ""},
+		{"-e git+https://github.com/org/repo.git#egg=repo", false, "", "", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.line, func(t *testing.T) {
+			req, ok := parseRequirementLine(tt.line)
+			if ok != tt.expectedOK {
+				t.Fatalf("Expected ok = %v, got %v", tt.expectedOK, ok)
+			}
+			if !ok {
+				return
+			}
+			if req.Name != tt.expectedName {
+				t.Errorf("Expected name '%s', got '%s'", tt.expectedName, req.Name)
+			}
+			if req.Version != tt.expectedVersion {
+				t.Errorf("Expected version '%s', got '%s'", tt.expectedVersion, req.Version)
+			}
+			if req.Specifier != tt.expectedSpec {
+				t.Errorf("Expected specifier '%s', got '%s'", tt.expectedSpec, req.Specifier)
+			}
+		})
+	}
+}
+
+func TestRequirementsParser_Parse_WithIncludes(t *testing.T) {
+	ctx := context.Background()
+	parser := NewParser()
+
+	tmpDir := t.TempDir()
+	mainFile := filepath.Join(tmpDir, "requirements.txt")
+	baseFile := filepath.Join(tmpDir, "base.txt")
+
+	mainContent := `# Application requirements
+-r base.txt
+django==4.2.0
+requests[security]>=2.28
+`
+	baseContent := `certifi==2020.12.5 \
+    --hash=sha256:abc
+-r requirements.txt
+`
+
+	if err := os.WriteFile(mainFile, []byte(mainContent), 0644); err != nil {
+		t.Fatalf("Failed to create temp file: %v", err)
+	}
+	if err := os.WriteFile(baseFile, []byte(baseContent), 0644); err != nil {
+		t.Fatalf("Failed to create temp file: %v", err)
+	}
+
+	packages, err := parser.Parse(ctx, mainFile)
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+
+	if len(packages) != 3 {
+		t.Fatalf("Expected 3 packages, got %d", len(packages))
+	}
+
+	if packages[0].Name != "certifi" || packages[0].Version != "2020.12.5" {
+		t.Errorf("Expected certifi 2020.12.5, got %s %s", packages[0].Name, packages[0].Version)
+	}
+	if packages[0].Location != baseFile {
+		t.Errorf("Expected certifi location '%s', got '%s'", baseFile, packages[0].Location)
+	}
+	if packages[1].Name != "django" || packages[1].Version != "4.2.0" {
+		t.Errorf("Expected django 4.2.0, got %s %s", packages[1].Name, packages[1].Version)
+	}
+	if packages[2].Name != "requests" || packages[2].Version != "" {
+		t.Errorf("Expected unpinned requests, got %s %s", packages[2].Name, packages[2].Version)
+	}
+	for _, pkg := range packages {
+		if pkg.Ecosystem != common.EcosystemPyPI {
+			t.Errorf("Expected ecosystem 'pypi', got '%s'", pkg.Ecosystem)
+		}
+	}
+}
+
+// includesFixture is a requirements.txt that includes requirements-dev.txt, which includes it back
+var includesFixture = filepath.Join("testdata", "includes", "requirements.txt")
+
+func TestRequirementsParser_Parse_DevIncludes(t *testing.T) {
+	packages, err := NewParser().Parse(context.Background(), includesFixture)
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+
+	devFile := filepath.Join("testdata", "includes", "requirements-dev.txt")
+	expected := []struct {
+		name     string
+		dev      bool
+		location string
+	}{
+		{"django", false, includesFixture},
+		{"requests", false, includesFixture},
+		{"pytest", true, devFile},
+		{"black", true, devFile},
+	}
+	if len(packages) != len(expected) {
+		t.Fatalf("Expected %d packages, got %+v", len(expected), packages)
+	}
+	for i, want := range expected {
+		pkg := packages[i]
+		if pkg.Name != want.name || pkg.Dev != want.dev || pkg.Location != want.location {
+			t.Errorf("Expected %s (dev %v) from %s, got %s (dev %v) from %s",
+				want.name, want.dev, want.location, pkg.Name, pkg.Dev, pkg.Location)
+		}
+	}
+}
+
+func TestRequirementsParser_ParseFiles(t *testing.T) {
+	docsFile := filepath.Join("testdata", "includes", "requirements-docs.txt")
+	devFile := filepath.Join("testdata", "includes", "requirements-dev.txt")
+
+	// requirements.txt is both listed and included by requirements-dev.txt, so it's read once
+	packages, err := NewParser().ParseFiles(context.Background(), []string{devFile, includesFixture, docsFile})
+	if err != nil {
+		t.Fatalf("ParseFiles failed: %v", err)
+	}
+
+	var names []string
+	for _, pkg := range packages {
+		names = append(names, pkg.Name+"@"+filepath.Base(pkg.Location))
+	}
+	expected := []string{
+		"django@requirements.txt",
+		"requests@requirements.txt",
+		"pytest@requirements-dev.txt",
+		"black@requirements-dev.txt",
+		"sphinx@requirements-docs.txt",
+		"requests@requirements-docs.txt",
+	}
+	if strings.Join(names, " ") != strings.Join(expected, " ") {
+		t.Errorf("Expected %v, got %v", expected, names)
+	}
+}
+
+func TestIsDevRequirements(t *testing.T) {
+	tests := map[string]bool{
+		"requirements.txt":            false,
+		"requirements-dev.txt":        true,
+		"dev-requirements.txt":        true,
+		"requirements_test.txt":       true,
+		"requirements/dev.txt":        true,
+		"requirements/devices.txt":    false,
+		"requirements-docs.txt":       false,
+		"requirements.development.in": true,
+	}
+	for file, want := range tests {
+		if got := isDevRequirements(file); got != want {
+			t.Errorf("isDevRequirements(%q) = %v, want %v", file, got, want)
+		}
+	}
+}
+
+func TestRequirementsParser_Parse_FileNotFound(t *testing.T) {
+	ctx := context.Background()
+	parser := NewParser()
+
+	_, err := parser.Parse(ctx, "/nonexistent/requirements.txt")
+	if err == nil {
+		t.Fatal("Expected error for nonexistent file, got nil")
+	}
+}
+
+func TestRequirementsParser_Update(t *testing.T) {
+	ctx := context.Background()
+	parser := NewParser()
+
+	tmpDir := t.TempDir()
+	reqFile := filepath.Join(tmpDir, "requirements.txt")
+
+	content := `# Pinned dependencies
+Django==1.11.29  # LTS
+requests[security]==2.6.0; python_version >= "3.6"
+cryptography>=36.0.2
+certifi==2020.12.5 \
+    --hash=sha256:2020.12.5abc
+`
+
+	if err := os.WriteFile(reqFile, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to create temp file: %v", err)
+	}
+
+	updates := map[string]string{
+		"django":       "1.11.29.post1",
+		"requests":     "2.6.0.post1",
+		"cryptography": "36.0.2.post1",
+		"certifi":      "2020.12.5.post1",
+	}
+
+	updated, err := parser.Update(ctx, reqFile, updates)
+	if err != nil {
+		t.Fatalf("Update failed: %v", err)
+	}
+
+	expected := `# Pinned dependencies
+Django==1.11.29.post1  # LTS
+requests[security]==2.6.0.post1; python_version >= "3.6"
+cryptography>=36.0.2
+certifi==2020.12.5.post1 \
+    --hash=sha256:2020.12.5abc
+`
+
+	if updated != expected {
+		t.Errorf("Unexpected updated content:\n%s", updated)
+	}
+
+	if !parser.Validate(updated) {
+		t.Error("Expected updated content to be valid")
+	}
+}
+
+func TestRequirementsParser_Update_ConstraintStyle(t *testing.T) {
+	ctx := context.Background()
+
+	reqFile := filepath.Join(t.TempDir(), "requirements.txt")
+	content := `Django==1.11.29  # LTS
+requests[security,socks] == 2.6.0 ; python_version >= "3.6"
+certifi==2020.12.5
+`
+	if err := os.WriteFile(reqFile, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to create temp file: %v", err)
+	}
+
+	updates := map[string]string{
+		"django":   "1.11.30",
+		"requests": "2.6.1",
+		"certifi":  "2020.12.5+root.io.1",
+	}
+
+	tests := []struct {
+		style    string
+		expected string
+	}{
+		{ConstraintExact, `Django==1.11.30  # LTS
+requests[security,socks] == 2.6.1 ; python_version >= "3.6"
+certifi==2020.12.5+root.io.1
+`},
+		{ConstraintCompatible, `Django~=1.11.30  # LTS
+requests[security,socks] ~=2.6.1 ; python_version >= "3.6"
+certifi==2020.12.5+root.io.1
+`},
+		{ConstraintMinimum, `Django>=1.11.30  # LTS
+requests[security,socks] >=2.6.1 ; python_version >= "3.6"
+certifi==2020.12.5+root.io.1
+`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.style, func(t *testing.T) {
+			parser := NewParser(WithConstraintStyle(tt.style))
+			updated, err := parser.Update(ctx, reqFile, updates)
+			if err != nil {
+				t.Fatalf("Update failed: %v", err)
+			}
+			if updated != tt.expected {
+				t.Errorf("Unexpected updated content:\n%s", updated)
+			}
+			if !parser.Validate(updated) {
+				t.Error("Expected updated content to be valid")
+			}
+		})
+	}
+}
+
+func TestConstraintOperator(t *testing.T) {
+	tests := []struct {
+		style    string
+		version  string
+		expected string
+	}{
+		{"", "2.6.1", "=="},
+		{ConstraintExact, "2.6.1", "=="},
+		{ConstraintCompatible, "2.6.1", "~="},
+		{ConstraintCompatible, "1!2.0rc1", "~="},
+		{ConstraintCompatible, "2", "=="},
+		{ConstraintCompatible, "2.6.1+root.io.1", "=="},
+		{ConstraintMinimum, "2", ">="},
+		{ConstraintMinimum, "2.6.1+root.io.1", "=="},
+	}
+
+	for _, tt := range tests {
+		if got := constraintOperator(tt.style, tt.version); got != tt.expected {
+			t.Errorf("constraintOperator(%q, %q) = %q, expected %q", tt.style, tt.version, got, tt.expected)
+		}
+	}
+}
+
+func TestRequirementsParser_Validate(t *testing.T) {
+	parser := NewParser()
+
+	if !parser.Validate("django==4.2.0\n# comment\n-r base.txt\n") {
+		t.Error("Expected valid requirements to pass validation")
+	}
+	if parser.Validate("django==4.2.0\n!!!invalid\n") {
+		t.Error("Expected invalid requirement to fail validation")
+	}
+}
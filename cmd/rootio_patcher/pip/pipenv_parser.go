@@ -0,0 +1,264 @@
+package pip
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"rootio_patcher/cmd/rootio_patcher/common"
+)
+
+const (
+	pipfileLockFile = "Pipfile.lock"
+	pipfileFile     = "Pipfile"
+)
+
+// pipfileLockSections are the Pipfile.lock sections holding packages, in install order
+var pipfileLockSections = []string{"default", "develop"}
+
+// PipenvParser handles parsing of pipenv projects (Pipfile.lock and Pipfile)
+type PipenvParser struct{}
+
+// NewPipenvParser creates a new pipenv parser
+func NewPipenvParser() *PipenvParser {
+	return &PipenvParser{}
+}
+
+// Ecosystem returns the ecosystem name
+func (p *PipenvParser) Ecosystem() common.Ecosystem {
+	return common.EcosystemPyPI
+}
+
+// FilePatterns returns file patterns this parser handles
+func (p *PipenvParser) FilePatterns() []string {
+	return []string{pipfileLockFile, pipfileFile}
+}
+
+// CanHandle checks if this parser can handle the given file
+func (p *PipenvParser) CanHandle(fileName string) bool {
+	base := filepath.Base(fileName)
+	return base == pipfileLockFile || base == pipfileFile
+}
+
+// Parse reads package versions from Pipfile.lock next to filePath. Packages in the develop
+// section are dev dependencies. Dependencies declared in the Pipfile are marked direct and
+// also returned, unpinned, located in the Pipfile so their constraints are rewritten along
+// with the lock file.
+func (p *PipenvParser) Parse(ctx context.Context, filePath string) ([]common.PackageInfo, error) {
+	dir := filepath.Dir(filePath)
+	lockPath := filepath.Join(dir, pipfileLockFile)
+	pipfilePath := filepath.Join(dir, pipfileFile)
+
+	lockContent, err := os.ReadFile(lockPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", lockPath, err)
+	}
+
+	locked, err := parsePipfileLock(lockContent)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse JSON: %w", err)
+	}
+
+	var declared []poetryDependency
+	pipfileContent, err := os.ReadFile(pipfilePath)
+	switch {
+	case err == nil:
+		declared = parsePipfileDependencies(string(pipfileContent))
+	case !errors.Is(err, fs.ErrNotExist):
+		return nil, fmt.Errorf("failed to read %s: %w", pipfilePath, err)
+	}
+
+	declaredByName := make(map[string]poetryDependency, len(declared))
+	for _, dep := range declared {
+		declaredByName[normalizeName(dep.Name)] = dep
+	}
+
+	var packages []common.PackageInfo
+	for _, pkg := range locked {
+		dep, direct := declaredByName[normalizeName(pkg.Name)]
+		packages = append(packages, common.PackageInfo{
+			Name:              pkg.Name,
+			Version:           pkg.Version,
+			VersionConstraint: dep.Constraint,
+			Ecosystem:         common.EcosystemPyPI,
+			Direct:            direct,
+			Dev:               pkg.Category == "develop",
+			Location:          lockPath,
+		})
+	}
+
+	for _, dep := range declared {
+		packages = append(packages, common.PackageInfo{
+			Name:              dep.Name,
+			VersionConstraint: dep.Constraint,
+			Ecosystem:         common.EcosystemPyPI,
+			Direct:            true,
+			Dev:               dep.Dev,
+			Location:          pipfilePath,
+		})
+	}
+
+	return packages, nil
+}
+
+// parsePipfileLock returns the pinned packages of each section, sorted by name within a section.
+// A package locked in both sections is only a dev dependency if default doesn't need it.
+func parsePipfileLock(content []byte) ([]lockPackage, error) {
+	var lock map[string]json.RawMessage
+	if err := json.Unmarshal(content, &lock); err != nil {
+		return nil, err
+	}
+
+	var packages []lockPackage
+	seen := make(map[string]bool)
+	for _, section := range pipfileLockSections {
+		raw, ok := lock[section]
+		if !ok {
+			continue
+		}
+
+		var entries map[string]struct {
+			Version string `json:"version"`
+		}
+		if err := json.Unmarshal(raw, &entries); err != nil {
+			return nil, fmt.Errorf("%s section: %w", section, err)
+		}
+
+		names := make([]string, 0, len(entries))
+		for name := range entries {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		for _, name := range names {
+			// Editable, path and VCS entries have no pinned version
+			version := strings.TrimPrefix(entries[name].Version, "==")
+			if version == "" || seen[normalizeName(name)] {
+				continue
+			}
+			seen[normalizeName(name)] = true
+			packages = append(packages, lockPackage{Name: name, Version: version, Category: section})
+		}
+	}
+
+	return packages, nil
+}
+
+// parsePipfileDependencies returns dependencies from the [packages] and [dev-packages] tables
+func parsePipfileDependencies(content string) []poetryDependency {
+	return parseDeclaredDependencies(content, pipfileDependencyTable)
+}
+
+// pipfileDependencyTable reports whether a Pipfile table declares dependencies and whether they are dev-only
+func pipfileDependencyTable(table string) (bool, bool) {
+	switch table {
+	case "packages":
+		return true, false
+	case "dev-packages":
+		return true, true
+	}
+	return false, false
+}
+
+// Update rewrites versions in Pipfile.lock or constraints in the Pipfile, depending on filePath
+func (p *PipenvParser) Update(ctx context.Context, filePath string, updates map[string]string) (string, error) {
+	content, err := os.ReadFile(filePath)
+	if err != nil {
+		return "", fmt.Errorf("failed to read file: %w", err)
+	}
+
+	normalizedUpdates := make(map[string]string, len(updates))
+	for name, version := range updates {
+		normalizedUpdates[normalizeName(name)] = version
+	}
+
+	if filepath.Base(filePath) == pipfileFile {
+		return updatePipfile(string(content), normalizedUpdates), nil
+	}
+	return updatePipfileLock(content, normalizedUpdates)
+}
+
+// updatePipfileLock rewrites the pinned version of each updated package in every section.
+// Package hashes still describe the old version; pipenv lock refreshes them. The file is
+// written the way pipenv writes it: sorted keys and four-space indentation.
+func updatePipfileLock(content []byte, updates map[string]string) (string, error) {
+	var lock map[string]json.RawMessage
+	if err := json.Unmarshal(content, &lock); err != nil {
+		return "", fmt.Errorf("failed to parse JSON: %w", err)
+	}
+
+	for _, section := range pipfileLockSections {
+		raw, ok := lock[section]
+		if !ok {
+			continue
+		}
+
+		var packages map[string]map[string]json.RawMessage
+		if err := json.Unmarshal(raw, &packages); err != nil {
+			return "", fmt.Errorf("failed to parse %s section: %w", section, err)
+		}
+
+		for name, entry := range packages {
+			newVersion, ok := updates[normalizeName(name)]
+			if !ok || entry["version"] == nil {
+				continue
+			}
+			version, err := json.Marshal("==" + newVersion)
+			if err != nil {
+				return "", fmt.Errorf("failed to encode version of %s: %w", name, err)
+			}
+			entry["version"] = version
+		}
+
+		updated, err := marshalPipfileJSON(packages)
+		if err != nil {
+			return "", fmt.Errorf("failed to encode %s section: %w", section, err)
+		}
+		lock[section] = updated
+	}
+
+	compact, err := marshalPipfileJSON(lock)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal JSON: %w", err)
+	}
+
+	var indented bytes.Buffer
+	if err := json.Indent(&indented, compact, "", "    "); err != nil {
+		return "", fmt.Errorf("failed to format JSON: %w", err)
+	}
+	indented.WriteByte('\n')
+
+	return indented.String(), nil
+}
+
+// marshalPipfileJSON encodes value without escaping the <, > and & of environment markers
+func marshalPipfileJSON(value any) ([]byte, error) {
+	var b bytes.Buffer
+	encoder := json.NewEncoder(&b)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(value); err != nil {
+		return nil, err
+	}
+	return bytes.TrimSuffix(b.Bytes(), []byte("\n")), nil
+}
+
+// updatePipfile rewrites single-version constraints, keeping the operator (==2.28.1 → ==2.31.0).
+// Wildcards and ranges are left alone since the lock file pins the exact version.
+func updatePipfile(content string, updates map[string]string) string {
+	return updateConstraints(content, updates, pipfileDependencyTable)
+}
+
+// Validate checks Pipfile.lock content is valid JSON and Pipfile content is balanced TOML
+func (p *PipenvParser) Validate(content string) bool {
+	if strings.HasPrefix(strings.TrimSpace(content), "{") {
+		return json.Valid([]byte(content))
+	}
+	return NewPoetryParser().Validate(content)
+}
@@ -0,0 +1,281 @@
+package pip
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"rootio_patcher/cmd/rootio_patcher/common"
+	"rootio_patcher/pkg/rootio"
+)
+
+const pipfileLock = `{
+    "_meta": {
+        "hash": {
+            "sha256": "deadbeef"
+        },
+        "pipfile-spec": 6,
+        "requires": {
+            "python_version": "3.11"
+        },
+        "sources": [
+            {
+                "name": "pypi",
+                "url": "https://pypi.org/simple",
+                "verify_ssl": true
+            }
+        ]
+    },
+    "default": {
+        "certifi": {
+            "hashes": [
+                "sha256:abc"
+            ],
+            "markers": "python_version >= '3.6'",
+            "version": "==2022.12.7"
+        },
+        "requests": {
+            "hashes": [
+                "sha256:def"
+            ],
+            "index": "pypi",
+            "version": "==2.28.1"
+        },
+        "mylib": {
+            "editable": true,
+            "path": "./mylib"
+        }
+    },
+    "develop": {
+        "pytest": {
+            "hashes": [
+                "sha256:123"
+            ],
+            "index": "pypi",
+            "version": "==7.0.0"
+        }
+    }
+}
+`
+
+const pipfile = `[[source]]
+url = "https://pypi.org/simple"
+verify_ssl = true
+name = "pypi"
+
+[packages]
+requests = "==2.28.1" # http client
+mylib = {path = "./mylib", editable = true}
+
+[dev-packages]
+pytest = "*"
+
+[requires]
+python_version = "3.11"
+`
+
+// writePipenvProject writes Pipfile.lock and Pipfile and returns the lock path
+func writePipenvProject(t *testing.T) string {
+	t.Helper()
+
+	dir := t.TempDir()
+	lockFile := filepath.Join(dir, "Pipfile.lock")
+	if err := os.WriteFile(lockFile, []byte(pipfileLock), 0644); err != nil {
+		t.Fatalf("Failed to write Pipfile.lock: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "Pipfile"), []byte(pipfile), 0644); err != nil {
+		t.Fatalf("Failed to write Pipfile: %v", err)
+	}
+	return lockFile
+}
+
+func TestPipenvParser_CanHandle(t *testing.T) {
+	parser := NewPipenvParser()
+
+	tests := []struct {
+		fileName string
+		expected bool
+	}{
+		{"Pipfile.lock", true},
+		{"app/Pipfile", true},
+		{"poetry.lock", false},
+		{"requirements.txt", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.fileName, func(t *testing.T) {
+			if result := parser.CanHandle(tt.fileName); result != tt.expected {
+				t.Errorf("CanHandle(%s) = %v, expected %v", tt.fileName, result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestPipenvParser_Parse(t *testing.T) {
+	lockFile := writePipenvProject(t)
+	pipfilePath := filepath.Join(filepath.Dir(lockFile), "Pipfile")
+
+	packages, err := NewPipenvParser().Parse(context.Background(), lockFile)
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+
+	expected := []struct {
+		name       string
+		version    string
+		constraint string
+		direct     bool
+		dev        bool
+		location   string
+	}{
+		{"certifi", "2022.12.7", "", false, false, lockFile},
+		{"requests", "2.28.1", "==2.28.1", true, false, lockFile},
+		{"pytest", "7.0.0", "*", true, true, lockFile},
+		{"requests", "", "==2.28.1", true, false, pipfilePath},
+		{"mylib", "", "", true, false, pipfilePath},
+		{"pytest", "", "*", true, true, pipfilePath},
+	}
+
+	if len(packages) != len(expected) {
+		t.Fatalf("Expected %d packages, got %d: %+v", len(expected), len(packages), packages)
+	}
+
+	for i, exp := range expected {
+		pkg := packages[i]
+		if pkg.Name != exp.name || pkg.Version != exp.version || pkg.VersionConstraint != exp.constraint ||
+			pkg.Direct != exp.direct || pkg.Dev != exp.dev || pkg.Location != exp.location {
+			t.Errorf("Package %d: expected %+v, got %+v", i, exp, pkg)
+		}
+	}
+}
+
+func TestPipenvParser_Parse_MissingLock(t *testing.T) {
+	pipfilePath := filepath.Join(t.TempDir(), "Pipfile")
+	if err := os.WriteFile(pipfilePath, []byte(pipfile), 0644); err != nil {
+		t.Fatalf("Failed to write Pipfile: %v", err)
+	}
+
+	if _, err := NewPipenvParser().Parse(context.Background(), pipfilePath); err == nil {
+		t.Fatal("Expected error when Pipfile.lock is missing")
+	}
+}
+
+func TestPipenvParser_Update(t *testing.T) {
+	ctx := context.Background()
+	parser := NewPipenvParser()
+	lockFile := writePipenvProject(t)
+	pipfilePath := filepath.Join(filepath.Dir(lockFile), "Pipfile")
+	updates := map[string]string{"requests": "2.31.0", "pytest": "7.4.0"}
+
+	updatedLock, err := parser.Update(ctx, lockFile, updates)
+	if err != nil {
+		t.Fatalf("Update failed: %v", err)
+	}
+	if !parser.Validate(updatedLock) {
+		t.Fatalf("Expected updated lock to be valid JSON, got:\n%s", updatedLock)
+	}
+
+	var lock struct {
+		Default map[string]struct {
+			Version string `json:"version"`
+		} `json:"default"`
+		Develop map[string]struct {
+			Version string `json:"version"`
+		} `json:"develop"`
+	}
+	if err := json.Unmarshal([]byte(updatedLock), &lock); err != nil {
+		t.Fatalf("Failed to parse updated lock: %v", err)
+	}
+	for name, version := range map[string]string{"requests": "==2.31.0", "certifi": "==2022.12.7", "mylib": ""} {
+		if got := lock.Default[name].Version; got != version {
+			t.Errorf("Expected default %s version %q, got %q", name, version, got)
+		}
+	}
+	if got := lock.Develop["pytest"].Version; got != "==7.4.0" {
+		t.Errorf("Expected develop pytest version ==7.4.0, got %q", got)
+	}
+	if !strings.Contains(updatedLock, `"markers": "python_version >= '3.6'"`) {
+		t.Errorf("Expected markers to be written unescaped, got:\n%s", updatedLock)
+	}
+
+	updatedPipfile, err := parser.Update(ctx, pipfilePath, updates)
+	if err != nil {
+		t.Fatalf("Update failed: %v", err)
+	}
+	for _, expected := range []string{
+		`requests = "==2.31.0" # http client`,
+		`pytest = "*"`,
+		`python_version = "3.11"`,
+	} {
+		if !strings.Contains(updatedPipfile, expected) {
+			t.Errorf("Expected updated Pipfile to contain %q, got:\n%s", expected, updatedPipfile)
+		}
+	}
+}
+
+func TestUpdatePipfileLock_KeepsPipenvFormatting(t *testing.T) {
+	updated, err := updatePipfileLock([]byte(pipfileLock), map[string]string{})
+	if err != nil {
+		t.Fatalf("updatePipfileLock failed: %v", err)
+	}
+
+	// pipenv writes sorted keys with four-space indentation; the fixture's default section isn't sorted
+	if !strings.HasPrefix(updated, "{\n    \"_meta\": {\n        \"hash\": {\n") {
+		t.Errorf("Expected four-space indentation, got:\n%s", updated)
+	}
+	if !strings.Contains(updated, "        \"mylib\": {\n            \"editable\": true,\n            \"path\": \"./mylib\"\n        },\n        \"requests\": {") {
+		t.Errorf("Expected sorted package keys, got:\n%s", updated)
+	}
+}
+
+func TestRequirementsApp_Run_Pipenv(t *testing.T) {
+	ctx := context.Background()
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+	lockFile := writePipenvProject(t)
+	pipfilePath := filepath.Join(filepath.Dir(lockFile), "Pipfile")
+
+	var analyzed []rootio.Package
+	mockAPIClient := &MockAPIClient{
+		AnalyzePackagesFunc: func(ctx context.Context, packages []rootio.Package) (*rootio.AnalyzePackagesResponse, error) {
+			analyzed = packages
+			return &rootio.AnalyzePackagesResponse{
+				Patches: []rootio.PackagePatch{
+					{PackageName: "requests", Version: "2.28.1", Patch: rootio.PatchInfo{Name: "requests", Version: "2.31.0"}},
+				},
+			}, nil
+		},
+	}
+
+	app := NewRequirementsAppWithServices("test-key", "https://api.root.io", lockFile, false, logger,
+		NewPipenvParser(), mockAPIClient, common.WithSkipDev(true))
+	if err := app.Run(ctx); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	if len(analyzed) != 2 {
+		t.Errorf("Expected the 2 locked default packages to be analyzed, got %v", analyzed)
+	}
+	if app.Result().DevSkipped != 2 {
+		t.Errorf("Expected pytest to be skipped from both files, got %d", app.Result().DevSkipped)
+	}
+
+	lockContent, err := os.ReadFile(lockFile)
+	if err != nil {
+		t.Fatalf("Failed to read Pipfile.lock: %v", err)
+	}
+	if !strings.Contains(string(lockContent), `"version": "==2.31.0"`) {
+		t.Errorf("Expected requests to be updated in Pipfile.lock, got:\n%s", lockContent)
+	}
+
+	pipfileContent, err := os.ReadFile(pipfilePath)
+	if err != nil {
+		t.Fatalf("Failed to read Pipfile: %v", err)
+	}
+	if !strings.Contains(string(pipfileContent), `requests = "==2.31.0"`) {
+		t.Errorf("Expected requests constraint to be updated in Pipfile, got:\n%s", pipfileContent)
+	}
+}
@@ -0,0 +1,447 @@
+package pip
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"rootio_patcher/cmd/rootio_patcher/common"
+)
+
+const (
+	poetryLockFile = "poetry.lock"
+	pyprojectFile  = "pyproject.toml"
+)
+
+var (
+	// tomlHeaderPattern matches a table header: [table] or [[array.of.tables]]
+	tomlHeaderPattern = regexp.MustCompile(`^\s*\[\[?\s*([^\]]+?)\s*\]\]?\s*(#.*)?$`)
+
+	// tomlKeyValuePattern matches key = value, with a bare or quoted key
+	tomlKeyValuePattern = regexp.MustCompile(`^\s*"?([A-Za-z0-9._-]+)"?\s*=\s*(.*)$`)
+
+	// tomlStringPattern matches the first basic or literal string in a value
+	tomlStringPattern = regexp.MustCompile(`"([^"]*)"|'([^']*)'`)
+
+	// inlineVersionPattern matches version = "..." inside an inline table
+	inlineVersionPattern = regexp.MustCompile(`(?:^|[{,\s])version\s*=\s*("[^"]*"|'[^']*')`)
+
+	// groupDependenciesPattern matches [tool.poetry.group.<name>.dependencies]
+	groupDependenciesPattern = regexp.MustCompile(`^tool\.poetry\.group\.([^.]+)\.dependencies$`)
+
+	// simpleConstraintPattern matches a single-version constraint such as ^4.2, ~=4.2.0, >=4.2 or 4.2.0
+	simpleConstraintPattern = regexp.MustCompile(`^(\^|~=|~|>=|==|=)?\s*[0-9][^\s,|<>!]*$`)
+)
+
+// PoetryParser handles parsing of Poetry projects (poetry.lock and pyproject.toml)
+type PoetryParser struct{}
+
+// NewPoetryParser creates a new Poetry parser
+func NewPoetryParser() *PoetryParser {
+	return &PoetryParser{}
+}
+
+// Ecosystem returns the ecosystem name
+func (p *PoetryParser) Ecosystem() common.Ecosystem {
+	return common.EcosystemPyPI
+}
+
+// FilePatterns returns file patterns this parser handles
+func (p *PoetryParser) FilePatterns() []string {
+	return []string{poetryLockFile, pyprojectFile}
+}
+
+// CanHandle checks if this parser can handle the given file
+func (p *PoetryParser) CanHandle(fileName string) bool {
+	base := filepath.Base(fileName)
+	return base == poetryLockFile || base == pyprojectFile
+}
+
+// lockPackage is a [[package]] entry in poetry.lock
+type lockPackage struct {
+	Name     string
+	Version  string
+	Category string
+}
+
+// poetryDependency is a dependency declared in pyproject.toml
+type poetryDependency struct {
+	Name       string
+	Constraint string
+	Dev        bool
+}
+
+// Parse reads package versions from poetry.lock next to filePath. Dependencies declared in
+// pyproject.toml are marked direct and also returned, unpinned, located in pyproject.toml
+// so their constraints are rewritten along with the lock file.
+func (p *PoetryParser) Parse(ctx context.Context, filePath string) ([]common.PackageInfo, error) {
+	dir := filepath.Dir(filePath)
+	lockPath := filepath.Join(dir, poetryLockFile)
+	pyprojectPath := filepath.Join(dir, pyprojectFile)
+
+	lockContent, err := os.ReadFile(lockPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", lockPath, err)
+	}
+
+	var declared []poetryDependency
+	pyprojectContent, err := os.ReadFile(pyprojectPath)
+	switch {
+	case err == nil:
+		declared = parsePyprojectDependencies(string(pyprojectContent))
+	case !errors.Is(err, fs.ErrNotExist):
+		return nil, fmt.Errorf("failed to read %s: %w", pyprojectPath, err)
+	}
+
+	declaredByName := make(map[string]poetryDependency, len(declared))
+	for _, dep := range declared {
+		declaredByName[normalizeName(dep.Name)] = dep
+	}
+
+	var packages []common.PackageInfo
+	for _, pkg := range parseLockPackages(string(lockContent)) {
+		dep, direct := declaredByName[normalizeName(pkg.Name)]
+		packages = append(packages, common.PackageInfo{
+			Name:              pkg.Name,
+			Version:           pkg.Version,
+			VersionConstraint: dep.Constraint,
+			Ecosystem:         common.EcosystemPyPI,
+			Direct:            direct,
+			Dev:               pkg.Category == "dev" || (direct && dep.Dev),
+			Location:          lockPath,
+		})
+	}
+
+	for _, dep := range declared {
+		packages = append(packages, common.PackageInfo{
+			Name:              dep.Name,
+			VersionConstraint: dep.Constraint,
+			Ecosystem:         common.EcosystemPyPI,
+			Direct:            true,
+			Dev:               dep.Dev,
+			Location:          pyprojectPath,
+		})
+	}
+
+	return packages, nil
+}
+
+// parseLockPackages returns the name, version and category of each [[package]] entry
+func parseLockPackages(content string) []lockPackage {
+	var packages []lockPackage
+	var current *lockPackage
+
+	for _, line := range strings.Split(content, "\n") {
+		if header := tomlHeaderPattern.FindStringSubmatch(line); header != nil {
+			current = nil
+			if strings.HasPrefix(strings.TrimSpace(line), "[[") && header[1] == "package" {
+				packages = append(packages, lockPackage{})
+				current = &packages[len(packages)-1]
+			}
+			continue
+		}
+
+		if current == nil {
+			continue
+		}
+
+		key, value, ok := parseTOMLString(line)
+		if !ok {
+			continue
+		}
+		switch key {
+		case "name":
+			current.Name = value
+		case "version":
+			current.Version = value
+		case "category":
+			current.Category = value
+		}
+	}
+
+	return packages
+}
+
+// parsePyprojectDependencies returns dependencies from the Poetry dependency tables.
+// Only the legacy dev-dependencies table and groups other than main are dev dependencies.
+func parsePyprojectDependencies(content string) []poetryDependency {
+	return parseDeclaredDependencies(content, poetryDependencyTable)
+}
+
+// parseDeclaredDependencies returns the dependencies in the TOML tables that table reports as
+// dependency tables, and whether each is dev-only
+func parseDeclaredDependencies(content string, table func(string) (bool, bool)) []poetryDependency {
+	var deps []poetryDependency
+	inDependencies, dev := false, false
+
+	for _, line := range strings.Split(content, "\n") {
+		if header := tomlHeaderPattern.FindStringSubmatch(line); header != nil {
+			inDependencies, dev = table(header[1])
+			continue
+		}
+
+		if !inDependencies {
+			continue
+		}
+
+		name, constraint, ok := parseDependencyLine(line)
+		if !ok || strings.EqualFold(name, "python") {
+			continue
+		}
+		deps = append(deps, poetryDependency{Name: name, Constraint: constraint, Dev: dev})
+	}
+
+	return deps
+}
+
+// poetryDependencyTable reports whether a table declares dependencies and whether they are dev-only
+func poetryDependencyTable(table string) (bool, bool) {
+	switch table {
+	case "tool.poetry.dependencies":
+		return true, false
+	case "tool.poetry.dev-dependencies":
+		return true, true
+	}
+
+	if group := groupDependenciesPattern.FindStringSubmatch(table); group != nil {
+		return true, group[1] != "main"
+	}
+	return false, false
+}
+
+// parseDependencyLine parses name = "constraint" or name = { version = "constraint", ... }
+func parseDependencyLine(line string) (string, string, bool) {
+	matches := tomlKeyValuePattern.FindStringSubmatch(stripTOMLComment(line))
+	if matches == nil {
+		return "", "", false
+	}
+
+	name, value := matches[1], strings.TrimSpace(matches[2])
+	if strings.HasPrefix(value, "[") {
+		// Multiple constraints (one per Python version or platform) are left to the lock file
+		return name, "", true
+	}
+	if strings.HasPrefix(value, "{") {
+		version := inlineVersionPattern.FindStringSubmatch(value)
+		if version == nil {
+			// Path, git and url dependencies have no version
+			return name, "", true
+		}
+		return name, unquote(version[1]), true
+	}
+
+	str := tomlStringPattern.FindStringSubmatch(value)
+	if str == nil {
+		return "", "", false
+	}
+	return name, str[1] + str[2], true
+}
+
+// parseTOMLString parses a key = "string" line
+func parseTOMLString(line string) (string, string, bool) {
+	matches := tomlKeyValuePattern.FindStringSubmatch(stripTOMLComment(line))
+	if matches == nil {
+		return "", "", false
+	}
+
+	str := tomlStringPattern.FindStringSubmatch(matches[2])
+	if str == nil {
+		return "", "", false
+	}
+	return matches[1], str[1] + str[2], true
+}
+
+// stripTOMLComment removes a trailing # comment that isn't inside a string
+func stripTOMLComment(line string) string {
+	var quote rune
+	for i, r := range line {
+		switch {
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			}
+		case r == '"' || r == '\'':
+			quote = r
+		case r == '#':
+			return line[:i]
+		}
+	}
+	return line
+}
+
+// unquote strips the surrounding quotes from a TOML string
+func unquote(value string) string {
+	if len(value) >= 2 {
+		return value[1 : len(value)-1]
+	}
+	return value
+}
+
+// Update rewrites versions in poetry.lock or constraints in pyproject.toml, depending on filePath
+func (p *PoetryParser) Update(ctx context.Context, filePath string, updates map[string]string) (string, error) {
+	content, err := os.ReadFile(filePath)
+	if err != nil {
+		return "", fmt.Errorf("failed to read file: %w", err)
+	}
+
+	normalizedUpdates := make(map[string]string, len(updates))
+	for name, version := range updates {
+		normalizedUpdates[normalizeName(name)] = version
+	}
+
+	if filepath.Base(filePath) == pyprojectFile {
+		return updatePyproject(string(content), normalizedUpdates), nil
+	}
+	return updateLock(string(content), normalizedUpdates), nil
+}
+
+// updateLock rewrites the version of each updated [[package]] entry.
+// File hashes still describe the old version; poetry lock --no-update refreshes them.
+func updateLock(content string, updates map[string]string) string {
+	lines := strings.Split(content, "\n")
+	inPackage := false
+	versionLine := -1
+	name := ""
+
+	flush := func() {
+		if newVersion, ok := updates[normalizeName(name)]; ok && versionLine >= 0 {
+			lines[versionLine] = replaceFirstString(lines[versionLine], newVersion)
+		}
+		versionLine, name = -1, ""
+	}
+
+	for i, line := range lines {
+		if header := tomlHeaderPattern.FindStringSubmatch(line); header != nil {
+			flush()
+			inPackage = strings.HasPrefix(strings.TrimSpace(line), "[[") && header[1] == "package"
+			continue
+		}
+
+		if !inPackage {
+			continue
+		}
+
+		key, value, ok := parseTOMLString(line)
+		if !ok {
+			continue
+		}
+		switch key {
+		case "name":
+			name = value
+		case "version":
+			versionLine = i
+		}
+	}
+	flush()
+
+	return strings.Join(lines, "\n")
+}
+
+// updatePyproject rewrites single-version constraints, keeping the operator (^4.2.0 → ^4.2.7).
+// Ranges and wildcards are left alone since the lock file pins the exact version.
+func updatePyproject(content string, updates map[string]string) string {
+	return updateConstraints(content, updates, poetryDependencyTable)
+}
+
+// updateConstraints rewrites single-version constraints in the TOML tables that table reports
+// as dependency tables
+func updateConstraints(content string, updates map[string]string, table func(string) (bool, bool)) string {
+	lines := strings.Split(content, "\n")
+	inDependencies := false
+
+	for i, line := range lines {
+		if header := tomlHeaderPattern.FindStringSubmatch(line); header != nil {
+			inDependencies, _ = table(header[1])
+			continue
+		}
+
+		if !inDependencies {
+			continue
+		}
+
+		name, constraint, ok := parseDependencyLine(line)
+		if !ok {
+			continue
+		}
+		newVersion, ok := updates[normalizeName(name)]
+		if !ok {
+			continue
+		}
+
+		newConstraint, ok := bumpConstraint(constraint, newVersion)
+		if !ok {
+			continue
+		}
+
+		// Replace the constraint string itself, leaving other inline table keys untouched
+		if loc := inlineVersionPattern.FindStringSubmatchIndex(line); loc != nil {
+			lines[i] = line[:loc[2]] + replaceFirstString(line[loc[2]:loc[3]], newConstraint) + line[loc[3]:]
+			continue
+		}
+		eq := strings.Index(line, "=")
+		lines[i] = line[:eq] + replaceFirstString(line[eq:], newConstraint)
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+// bumpConstraint returns constraint with its version replaced, if it is a single-version constraint
+func bumpConstraint(constraint, newVersion string) (string, bool) {
+	matches := simpleConstraintPattern.FindStringSubmatch(strings.TrimSpace(constraint))
+	if matches == nil {
+		return "", false
+	}
+	return matches[1] + newVersion, true
+}
+
+// replaceFirstString replaces the contents of the first quoted string in line
+func replaceFirstString(line, value string) string {
+	loc := tomlStringPattern.FindStringIndex(line)
+	if loc == nil {
+		return line
+	}
+	quote := line[loc[0] : loc[0]+1]
+	return line[:loc[0]] + quote + value + quote + line[loc[1]:]
+}
+
+// Validate checks that strings are closed and brackets and braces outside strings are balanced
+func (p *PoetryParser) Validate(content string) bool {
+	depth := 0
+	var quote rune
+	for _, line := range strings.Split(content, "\n") {
+		if quote == 0 {
+			line = stripTOMLComment(line)
+		}
+		for _, r := range line {
+			switch {
+			case quote != 0:
+				if r == quote {
+					quote = 0
+				}
+			case r == '"' || r == '\'':
+				quote = r
+			case r == '[' || r == '{':
+				depth++
+			case r == ']' || r == '}':
+				depth--
+				if depth < 0 {
+					return false
+				}
+			}
+		}
+	}
+	return depth == 0 && quote == 0
+}
+
+// separatorPattern matches runs of characters that are equivalent in package names
+var separatorPattern = regexp.MustCompile(`[-_.]+`)
+
+// normalizeName normalizes a Python package name (PEP 503): lowercase, with -, _ and . runs as -
+func normalizeName(name string) string {
+	return separatorPattern.ReplaceAllString(strings.ToLower(name), "-")
+}
@@ -0,0 +1,319 @@
+package pip
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"rootio_patcher/pkg/rootio"
+)
+
+const poetryLock = `# This file is automatically @generated by Poetry and should not be changed by hand.
+
+[[package]]
+name = "django"
+version = "4.2.0"
+description = "A high-level Python web framework."
+optional = false
+python-versions = ">=3.8"
+files = [
+    {file = "Django-4.2-py3-none-any.whl", hash = "sha256:abc"},
+]
+
+[package.dependencies]
+sqlparse = ">=0.3.1"
+
+[[package]]
+name = "sqlparse"
+version = "0.4.3"
+description = "A non-validating SQL parser."
+optional = false
+python-versions = ">=3.5"
+files = []
+
+[[package]]
+name = "pytest"
+version = "7.0.0"
+description = "pytest: simple powerful testing with Python"
+category = "dev"
+optional = false
+python-versions = ">=3.7"
+files = []
+
+[metadata]
+lock-version = "2.0"
+python-versions = "^3.10"
+content-hash = "deadbeef"
+`
+
+const pyproject = `[tool.poetry]
+name = "example"
+version = "0.1.0"
+
+[tool.poetry.dependencies]
+python = "^3.10"
+Django = "^4.2.0" # web framework
+
+[tool.poetry.group.test.dependencies]
+pytest = {version = "7.0.0", optional = true}
+
+[build-system]
+requires = ["poetry-core"]
+build-backend = "poetry.core.masonry.api"
+`
+
+// writePoetryProject writes poetry.lock and pyproject.toml and returns the lock path
+func writePoetryProject(t *testing.T) string {
+	t.Helper()
+
+	dir := t.TempDir()
+	lockFile := filepath.Join(dir, "poetry.lock")
+	if err := os.WriteFile(lockFile, []byte(poetryLock), 0644); err != nil {
+		t.Fatalf("Failed to write poetry.lock: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "pyproject.toml"), []byte(pyproject), 0644); err != nil {
+		t.Fatalf("Failed to write pyproject.toml: %v", err)
+	}
+	return lockFile
+}
+
+func TestPoetryParser_CanHandle(t *testing.T) {
+	parser := NewPoetryParser()
+
+	tests := []struct {
+		fileName string
+		expected bool
+	}{
+		{"poetry.lock", true},
+		{"app/pyproject.toml", true},
+		{"requirements.txt", false},
+		{"Pipfile.lock", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.fileName, func(t *testing.T) {
+			if result := parser.CanHandle(tt.fileName); result != tt.expected {
+				t.Errorf("CanHandle(%s) = %v, expected %v", tt.fileName, result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestPoetryParser_Parse(t *testing.T) {
+	lockFile := writePoetryProject(t)
+	pyprojectPath := filepath.Join(filepath.Dir(lockFile), "pyproject.toml")
+
+	packages, err := NewPoetryParser().Parse(context.Background(), lockFile)
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+
+	expected := []struct {
+		name       string
+		version    string
+		constraint string
+		direct     bool
+		dev        bool
+		location   string
+	}{
+		{"django", "4.2.0", "^4.2.0", true, false, lockFile},
+		{"sqlparse", "0.4.3", "", false, false, lockFile},
+		{"pytest", "7.0.0", "7.0.0", true, true, lockFile},
+		{"Django", "", "^4.2.0", true, false, pyprojectPath},
+		{"pytest", "", "7.0.0", true, true, pyprojectPath},
+	}
+
+	if len(packages) != len(expected) {
+		t.Fatalf("Expected %d packages, got %d: %+v", len(expected), len(packages), packages)
+	}
+
+	for i, exp := range expected {
+		pkg := packages[i]
+		if pkg.Name != exp.name || pkg.Version != exp.version || pkg.VersionConstraint != exp.constraint ||
+			pkg.Direct != exp.direct || pkg.Dev != exp.dev || pkg.Location != exp.location {
+			t.Errorf("Package %d: expected %+v, got %+v", i, exp, pkg)
+		}
+	}
+}
+
+func TestPoetryParser_Parse_WithoutPyproject(t *testing.T) {
+	lockFile := filepath.Join(t.TempDir(), "poetry.lock")
+	if err := os.WriteFile(lockFile, []byte(poetryLock), 0644); err != nil {
+		t.Fatalf("Failed to write poetry.lock: %v", err)
+	}
+
+	packages, err := NewPoetryParser().Parse(context.Background(), lockFile)
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	if len(packages) != 3 {
+		t.Fatalf("Expected 3 packages from the lock file, got %d", len(packages))
+	}
+	if packages[0].Direct {
+		t.Error("Expected packages to be indirect without pyproject.toml")
+	}
+}
+
+func TestPoetryParser_Parse_MissingLock(t *testing.T) {
+	pyprojectPath := filepath.Join(t.TempDir(), "pyproject.toml")
+	if err := os.WriteFile(pyprojectPath, []byte(pyproject), 0644); err != nil {
+		t.Fatalf("Failed to write pyproject.toml: %v", err)
+	}
+
+	if _, err := NewPoetryParser().Parse(context.Background(), pyprojectPath); err == nil {
+		t.Fatal("Expected error when poetry.lock is missing")
+	}
+}
+
+func TestPoetryParser_Update(t *testing.T) {
+	ctx := context.Background()
+	parser := NewPoetryParser()
+	lockFile := writePoetryProject(t)
+	pyprojectPath := filepath.Join(filepath.Dir(lockFile), "pyproject.toml")
+	updates := map[string]string{"django": "4.2.7", "pytest": "7.4.0"}
+
+	updatedLock, err := parser.Update(ctx, lockFile, updates)
+	if err != nil {
+		t.Fatalf("Update failed: %v", err)
+	}
+	for _, expected := range []string{
+		"name = \"django\"\nversion = \"4.2.7\"",
+		"name = \"sqlparse\"\nversion = \"0.4.3\"",
+		"name = \"pytest\"\nversion = \"7.4.0\"",
+		`sqlparse = ">=0.3.1"`,
+		`lock-version = "2.0"`,
+	} {
+		if !strings.Contains(updatedLock, expected) {
+			t.Errorf("Expected updated lock to contain %q, got:\n%s", expected, updatedLock)
+		}
+	}
+	if !parser.Validate(updatedLock) {
+		t.Error("Expected updated lock to be valid")
+	}
+
+	updatedPyproject, err := parser.Update(ctx, pyprojectPath, updates)
+	if err != nil {
+		t.Fatalf("Update failed: %v", err)
+	}
+	for _, expected := range []string{
+		`Django = "^4.2.7" # web framework`,
+		`pytest = {version = "7.4.0", optional = true}`,
+		`python = "^3.10"`,
+		`version = "0.1.0"`,
+	} {
+		if !strings.Contains(updatedPyproject, expected) {
+			t.Errorf("Expected updated pyproject to contain %q, got:\n%s", expected, updatedPyproject)
+		}
+	}
+	if !parser.Validate(updatedPyproject) {
+		t.Error("Expected updated pyproject to be valid")
+	}
+}
+
+func TestBumpConstraint(t *testing.T) {
+	tests := []struct {
+		constraint string
+		expected   string
+		ok         bool
+	}{
+		{"^4.2.0", "^4.2.7", true},
+		{"~4.2", "~4.2.7", true},
+		{"~=4.2.0", "~=4.2.7", true},
+		{">=4.2", ">=4.2.7", true},
+		{"4.2.0", "4.2.7", true},
+		{"*", "", false},
+		{">=4.2,<5.0", "", false},
+		{"", "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.constraint, func(t *testing.T) {
+			result, ok := bumpConstraint(tt.constraint, "4.2.7")
+			if result != tt.expected || ok != tt.ok {
+				t.Errorf("bumpConstraint(%q) = %q, %v; expected %q, %v", tt.constraint, result, ok, tt.expected, tt.ok)
+			}
+		})
+	}
+}
+
+func TestNormalizeName(t *testing.T) {
+	tests := map[string]string{
+		"Flask":              "flask",
+		"zope.interface":     "zope-interface",
+		"typing_extensions":  "typing-extensions",
+		"Foo__Bar-.baz":      "foo-bar-baz",
+		"ruamel.yaml.clib":   "ruamel-yaml-clib",
+		"already-normalized": "already-normalized",
+	}
+
+	for name, expected := range tests {
+		if got := normalizeName(name); got != expected {
+			t.Errorf("normalizeName(%q) = %q, expected %q", name, got, expected)
+		}
+	}
+}
+
+func TestPoetryParser_Validate(t *testing.T) {
+	parser := NewPoetryParser()
+
+	if !parser.Validate(poetryLock) {
+		t.Error("Expected lock file to be valid")
+	}
+	if parser.Validate("[[package]\nname = \"django\"\n") {
+		t.Error("Expected unbalanced brackets to be invalid")
+	}
+	if parser.Validate("name = \"django\n") {
+		t.Error("Expected unterminated string to be invalid")
+	}
+}
+
+func TestRequirementsApp_Run_Poetry(t *testing.T) {
+	ctx := context.Background()
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+	lockFile := writePoetryProject(t)
+	pyprojectPath := filepath.Join(filepath.Dir(lockFile), "pyproject.toml")
+
+	var analyzed []rootio.Package
+	mockAPIClient := &MockAPIClient{
+		AnalyzePackagesFunc: func(ctx context.Context, packages []rootio.Package) (*rootio.AnalyzePackagesResponse, error) {
+			analyzed = packages
+			return &rootio.AnalyzePackagesResponse{
+				Patches: []rootio.PackagePatch{
+					{PackageName: "django", Version: "4.2.0", Patch: rootio.PatchInfo{Name: "django", Version: "4.2.7"}},
+				},
+			}, nil
+		},
+	}
+
+	app := NewRequirementsAppWithServices("test-key", "https://api.root.io", lockFile, false, logger,
+		NewPoetryParser(), mockAPIClient)
+	if err := app.Run(ctx); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	if len(analyzed) != 3 {
+		t.Errorf("Expected the 3 locked packages to be analyzed, got %v", analyzed)
+	}
+	if app.Result().PackagesFound != 3 {
+		t.Errorf("Expected 3 packages found, got %d", app.Result().PackagesFound)
+	}
+
+	lockContent, err := os.ReadFile(lockFile)
+	if err != nil {
+		t.Fatalf("Failed to read poetry.lock: %v", err)
+	}
+	if !strings.Contains(string(lockContent), "name = \"django\"\nversion = \"4.2.7\"") {
+		t.Errorf("Expected django to be updated in poetry.lock, got:\n%s", lockContent)
+	}
+
+	pyprojectContent, err := os.ReadFile(pyprojectPath)
+	if err != nil {
+		t.Fatalf("Failed to read pyproject.toml: %v", err)
+	}
+	if !strings.Contains(string(pyprojectContent), `Django = "^4.2.7"`) {
+		t.Errorf("Expected Django constraint to be updated in pyproject.toml, got:\n%s", pyprojectContent)
+	}
+}
@@ -0,0 +1,36 @@
+package pip
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+)
+
+// DetectPython picks the Python interpreter to patch: the active virtualenv first,
+// then python3, then python
+func DetectPython() (string, error) {
+	return detectPython(os.Getenv, exec.LookPath)
+}
+
+// detectPython implements DetectPython with injectable environment and PATH lookup (for testing)
+func detectPython(getenv func(string) string, lookPath func(string) (string, error)) (string, error) {
+	if venv := getenv("VIRTUAL_ENV"); venv != "" {
+		venvPython := filepath.Join(venv, "bin", "python")
+		if runtime.GOOS == "windows" {
+			venvPython = filepath.Join(venv, "Scripts", "python.exe")
+		}
+		if _, err := lookPath(venvPython); err == nil {
+			return venvPython, nil
+		}
+	}
+
+	for _, name := range []string{"python3", "python"} {
+		if path, err := lookPath(name); err == nil {
+			return path, nil
+		}
+	}
+
+	return "", fmt.Errorf("no Python interpreter found (tried $VIRTUAL_ENV, python3, python); set --python-path")
+}
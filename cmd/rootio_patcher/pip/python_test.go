@@ -0,0 +1,67 @@
+package pip
+
+import (
+	"errors"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+func TestDetectPython(t *testing.T) {
+	venvPython := filepath.Join("/venv", "bin", "python")
+	if runtime.GOOS == "windows" {
+		venvPython = filepath.Join("/venv", "Scripts", "python.exe")
+	}
+
+	tests := []struct {
+		name      string
+		venv      string
+		available []string
+		expected  string
+	}{
+		{"virtualenv", "/venv", []string{venvPython, "python3", "python"}, venvPython},
+		{"broken virtualenv", "/venv", []string{"python3"}, "/usr/bin/python3"},
+		{"python3", "", []string{"python3", "python"}, "/usr/bin/python3"},
+		{"python", "", []string{"python"}, "/usr/bin/python"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			getenv := func(key string) string {
+				if key == "VIRTUAL_ENV" {
+					return tt.venv
+				}
+				return ""
+			}
+			lookPath := func(name string) (string, error) {
+				for _, available := range tt.available {
+					if available != name {
+						continue
+					}
+					if filepath.IsAbs(name) {
+						return name, nil
+					}
+					return "/usr/bin/" + name, nil
+				}
+				return "", errors.New("not found")
+			}
+
+			path, err := detectPython(getenv, lookPath)
+			if err != nil {
+				t.Fatalf("Expected no error, got: %v", err)
+			}
+			if path != tt.expected {
+				t.Errorf("Expected %s, got %s", tt.expected, path)
+			}
+		})
+	}
+}
+
+func TestDetectPython_NotFound(t *testing.T) {
+	getenv := func(string) string { return "" }
+	lookPath := func(string) (string, error) { return "", errors.New("not found") }
+
+	if _, err := detectPython(getenv, lookPath); err == nil {
+		t.Fatal("Expected error when no interpreter is available")
+	}
+}
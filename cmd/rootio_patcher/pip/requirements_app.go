@@ -0,0 +1,393 @@
+package pip
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+
+	"rootio_patcher/cmd/rootio_patcher/common"
+	"rootio_patcher/pkg/rootio"
+)
+
+// RequirementsApp handles requirements.txt, Poetry and pipenv remediation (pre-install file patching)
+type RequirementsApp struct {
+	apiKey    string
+	apiURL    string
+	filePath  string
+	dryRun    bool
+	logger    *slog.Logger
+	parser    common.Parser
+	apiClient common.APIClient
+	options   common.Options
+	out       io.Writer
+
+	result *common.RunResult
+}
+
+// NewRequirementsApp creates a new requirements.txt application instance
+func NewRequirementsApp(
+	apiKey, apiURL, filePath string, dryRun bool, logger *slog.Logger, opts ...common.Option,
+) *RequirementsApp {
+	return NewRequirementsAppWithServices(
+		apiKey,
+		apiURL,
+		filePath,
+		dryRun,
+		logger,
+		newParserForFile(filePath, common.NewOptions(opts...)),
+		common.NewAPIClient(common.EcosystemPyPI, apiURL, apiKey, logger, opts...),
+		opts...,
+	)
+}
+
+// multiFileParser is implemented by parsers that read several files as one set of requirements
+type multiFileParser interface {
+	ParseFiles(ctx context.Context, filePaths []string) ([]common.PackageInfo, error)
+}
+
+// newParserForFile selects the Poetry, pipenv or requirements.txt parser based on the file name
+func newParserForFile(filePath string, options common.Options) common.Parser {
+	if poetry := NewPoetryParser(); poetry.CanHandle(filePath) {
+		return poetry
+	}
+	if pipenv := NewPipenvParser(); pipenv.CanHandle(filePath) {
+		return pipenv
+	}
+	return NewParser(WithConstraintStyle(options.ConstraintStyle))
+}
+
+// NewRequirementsAppWithServices creates a new requirements.txt app with injected services (for testing)
+func NewRequirementsAppWithServices(
+	apiKey, apiURL, filePath string,
+	dryRun bool,
+	logger *slog.Logger,
+	parser common.Parser,
+	apiClient common.APIClient,
+	opts ...common.Option,
+) *RequirementsApp {
+	options := common.NewOptions(opts...)
+	return &RequirementsApp{
+		apiKey:    apiKey,
+		apiURL:    apiURL,
+		filePath:  filePath,
+		dryRun:    dryRun,
+		logger:    logger,
+		parser:    parser,
+		apiClient: apiClient,
+		options:   options,
+		out:       options.Output(),
+	}
+}
+
+// Result returns the structured result of the last run
+func (a *RequirementsApp) Result() *common.RunResult {
+	return a.result
+}
+
+// RunWithResult runs the requirements file remediation workflow and returns its structured result
+func (a *RequirementsApp) RunWithResult(ctx context.Context) (*common.RunResult, error) {
+	err := a.Run(ctx)
+	a.result.SetError(err)
+	return a.result, err
+}
+
+// Run executes the requirements.txt remediation workflow
+func (a *RequirementsApp) Run(ctx context.Context) error {
+	a.logger.DebugContext(ctx, "Starting requirements remediation",
+		slog.String("file", a.filePath),
+		slog.Bool("dry_run", a.dryRun))
+	a.result = common.NewRunResult(common.EcosystemPyPI, a.filePath, a.dryRun)
+
+	// 1. Check if the files exist
+	for _, file := range a.files() {
+		if _, err := os.Stat(file); err != nil {
+			return fmt.Errorf("file not found: %s", file)
+		}
+	}
+
+	// 2. Parse requirements (including -r includes)
+	a.logger.DebugContext(ctx, "Parsing requirements file")
+	packages, err := a.parse(ctx)
+	if err != nil {
+		return err
+	}
+	a.logger.DebugContext(ctx, "Parsed packages", slog.Int("count", len(packages)))
+	a.options.Progress.Printf("Found %d packages in %s", len(packages), a.fileNames())
+
+	// Leave Poetry dev groups and pipenv dev-packages out of production-only scans
+	if a.options.SkipDev {
+		packages, a.result.DevSkipped = common.FilterDev(packages)
+		if a.result.DevSkipped > 0 {
+			fmt.Fprintf(a.out, "\nSkipped %d dev dependencies (--skip-dev)\n", a.result.DevSkipped)
+		}
+	}
+
+	// 3. Convert pinned requirements to SDK format, remembering every file that declares each.
+	// Unpinned entries are still updated (e.g. pyproject.toml constraints) when the pinned one is patched.
+	var sdkPackages []rootio.Package
+	locations := make(map[string][]string)
+	for _, pkg := range packages {
+		location := pkg.Location
+		if location == "" {
+			location = a.filePath
+		}
+		name := normalizeName(pkg.Name)
+		if _, seen := locations[name]; !seen {
+			a.result.PackagesFound++
+		}
+		locations[name] = append(locations[name], location)
+
+		// Unpinned requirements can't be analyzed
+		if pkg.Version == "" {
+			a.logger.DebugContext(ctx, "Skipping unpinned requirement",
+				slog.String("package", pkg.Name),
+				slog.String("constraint", pkg.VersionConstraint))
+			continue
+		}
+
+		sdkPackages = append(sdkPackages, pkg.SDKPackage())
+	}
+
+	if len(sdkPackages) == 0 {
+		fmt.Fprintf(a.out, "\nNo pinned packages found in %s\n", a.fileNames())
+		return nil
+	}
+
+	// 4. Call backend API to analyze vulnerabilities
+	a.logger.DebugContext(ctx, "Analyzing packages for vulnerabilities")
+	a.options.Progress.Printf("Analyzing %d packages for vulnerabilities...", len(sdkPackages))
+	response, err := a.apiClient.AnalyzePackages(ctx, sdkPackages)
+	if err != nil {
+		return fmt.Errorf("failed to analyze packages: %w", err)
+	}
+
+	// 5. Log analysis results
+	a.logger.DebugContext(ctx, "Vulnerability analysis complete",
+		slog.Int("patches_available", len(response.Patches)),
+		slog.Int("packages_skipped", len(response.Skipped)))
+
+	// Drop invalid names, patches without a version to install, patches left out by
+	// --min-severity, --only, --exclude and --cve, and patches that aren't upgrades
+	patches, filterSkipped := common.FilterPatches(common.EcosystemPyPI, response.Patches, false, a.options)
+	response.Patches = patches
+	response.Skipped = append(response.Skipped, filterSkipped...)
+	common.WriteCVEMatches(a.out, response.Patches, a.options.CVEs)
+	a.result.AddSkipped(response.Skipped)
+	common.WriteSkipped(a.out, response.Skipped)
+
+	if len(response.Patches) == 0 {
+		fmt.Fprintln(a.out, "\nNo patches needed - all packages are up to date!")
+		return nil
+	}
+
+	// 6. Group updates by the file that declares each package
+	fileUpdates, files := a.groupUpdates(response.Patches, locations)
+	a.warnExactPins(ctx, response.Patches)
+
+	// 7. Execute or dry-run patches
+	if a.dryRun {
+		a.logger.DebugContext(ctx, "DRY-RUN MODE: No changes will be made")
+		a.options.Plan.AddFiles(common.EcosystemPyPI, a.files(), false, sdkPackages, response.Patches)
+		a.result.AddPatches(response.Patches, false, common.PatchStatusDryRun)
+		return a.reportDryRun(ctx, response.Patches, fileUpdates, files)
+	}
+
+	// Ask before changing anything, unless --yes was given
+	if err := a.options.Confirm.Confirm(len(response.Patches), a.fileNames()); err != nil {
+		a.result.AddPatches(response.Patches, false, common.PatchStatusNotApplied)
+		return err
+	}
+
+	fmt.Fprintf(a.out, "\nApplying %d patches to %s...\n\n", len(response.Patches), a.fileNames())
+	for i, patch := range response.Patches {
+		fmt.Fprintf(a.out, "[%d/%d] %s: %s → %s\n", i+1, len(response.Patches), patch.PackageName, patch.Version, patch.Patch.Version)
+	}
+
+	a.result.AddPatches(response.Patches, false, common.PatchStatusPending)
+	if err := a.applyPatches(ctx, fileUpdates, files); err != nil {
+		a.result.SetAllPatchStatus(common.PatchStatusFailed, err)
+		return err
+	}
+	a.result.SetAllPatchStatus(common.PatchStatusApplied, nil)
+
+	fmt.Fprintf(a.out, "\n✓ Successfully updated %s with %d patches!\n", a.fileNames(), len(response.Patches))
+	fmt.Fprintln(a.out, "\nNext steps:")
+	fmt.Fprintln(a.out, "  1. Review the changes in your dependency files")
+	fmt.Fprintf(a.out, "  2. Run: %s\n", a.installCommand())
+	fmt.Fprintln(a.out, "  3. Test your application")
+
+	if a.options.Verify {
+		return a.verify(ctx)
+	}
+
+	return nil
+}
+
+// verify parses the updated files again and confirms no patches remain for the pinned versions
+func (a *RequirementsApp) verify(ctx context.Context) error {
+	packages, err := a.parse(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to parse for verification: %w", err)
+	}
+
+	return common.VerifyPatches(ctx, common.EcosystemPyPI, a.apiClient,
+		common.VerifyPackages(packages, a.options), a.options, a.result)
+}
+
+// files returns the requirements files being remediated, the main file first
+func (a *RequirementsApp) files() []string {
+	return append([]string{a.filePath}, a.options.AdditionalFiles...)
+}
+
+// fileNames lists the requirements files being remediated for messages
+func (a *RequirementsApp) fileNames() string {
+	return strings.Join(a.files(), ", ")
+}
+
+// parse reads the packages of every requirements file, each included file once
+func (a *RequirementsApp) parse(ctx context.Context) ([]common.PackageInfo, error) {
+	if parser, ok := a.parser.(multiFileParser); ok {
+		packages, err := parser.ParseFiles(ctx, a.files())
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %w", a.fileNames(), err)
+		}
+		return packages, nil
+	}
+
+	var packages []common.PackageInfo
+	for _, file := range a.files() {
+		parsed, err := a.parser.Parse(ctx, file)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %w", file, err)
+		}
+		packages = append(packages, parsed...)
+	}
+	return packages, nil
+}
+
+// groupUpdates builds per-file update maps and returns the files in first-seen order
+func (a *RequirementsApp) groupUpdates(
+	patches []rootio.PackagePatch, locations map[string][]string,
+) (map[string]map[string]string, []string) {
+	fileUpdates := make(map[string]map[string]string)
+	var files []string
+
+	for _, patch := range patches {
+		for _, location := range locations[normalizeName(patch.PackageName)] {
+			if _, ok := fileUpdates[location]; !ok {
+				fileUpdates[location] = make(map[string]string)
+				files = append(files, location)
+			}
+			fileUpdates[location][patch.PackageName] = patch.Patch.Version
+		}
+	}
+
+	return fileUpdates, files
+}
+
+// warnExactPins warns about patched versions that --constraint-style can't write as a range
+func (a *RequirementsApp) warnExactPins(ctx context.Context, patches []rootio.PackagePatch) {
+	parser, ok := a.parser.(*RequirementsParser)
+	if !ok {
+		return
+	}
+	for _, patch := range patches {
+		if parser.keepsExactPin(patch.Patch.Version) {
+			a.logger.WarnContext(ctx, "Pinning the patched version exactly, PEP 440 doesn't allow it in a range",
+				slog.String("package", patch.PackageName),
+				slog.String("version", patch.Patch.Version),
+				slog.String("constraint_style", a.options.ConstraintStyle))
+		}
+	}
+}
+
+// reportDryRun shows the proposed diff for each requirements file without modifying it
+func (a *RequirementsApp) reportDryRun(
+	ctx context.Context,
+	patches []rootio.PackagePatch,
+	fileUpdates map[string]map[string]string,
+	files []string,
+) error {
+	fmt.Fprintln(a.out, "\n=== DRY-RUN MODE ===")
+	fmt.Fprintf(a.out, "The following packages in %s would be updated:\n\n", a.fileNames())
+	common.WritePatchTable(a.out, patches, false, common.TerminalWidth())
+
+	fmt.Fprintln(a.out, "\nProposed changes:")
+	for _, file := range files {
+		original, err := os.ReadFile(file)
+		if err != nil {
+			return fmt.Errorf("failed to read %s: %w", file, err)
+		}
+
+		updated, err := a.parser.Update(ctx, file, fileUpdates[file])
+		if err != nil {
+			return fmt.Errorf("failed to update %s: %w", file, err)
+		}
+
+		fmt.Fprintf(a.out, "\n%s", common.UnifiedDiff(file, string(original), updated))
+	}
+
+	fmt.Fprintln(a.out, "\nTo apply these patches, run with --dry-run=false")
+	fmt.Fprintf(a.out, "Then run: %s\n", a.installCommand())
+
+	return nil
+}
+
+// installCommand returns the command that installs the patched dependencies
+func (a *RequirementsApp) installCommand() string {
+	if NewPoetryParser().CanHandle(a.filePath) {
+		// Lock file hashes still describe the old versions until the lock is refreshed
+		return "poetry lock --no-update && poetry install"
+	}
+	if NewPipenvParser().CanHandle(a.filePath) {
+		// Package hashes still describe the old versions until the lock is refreshed
+		return "pipenv lock && pipenv sync"
+	}
+	return "pip install -r " + strings.Join(a.files(), " -r ")
+}
+
+// applyPatches rewrites each requirements file with patched versions
+func (a *RequirementsApp) applyPatches(
+	ctx context.Context, fileUpdates map[string]map[string]string, files []string,
+) error {
+	// Hold the locks from reading the files until they're written, so concurrent runs don't lose updates
+	unlock, err := common.LockFiles(ctx, files, a.options.LockTimeout)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	for _, file := range files {
+		a.logger.DebugContext(ctx, "Updating requirements file",
+			slog.String("file", file),
+			slog.Int("updates", len(fileUpdates[file])))
+
+		updatedContent, err := a.parser.Update(ctx, file, fileUpdates[file])
+		if err != nil {
+			return fmt.Errorf("failed to update %s: %w", file, err)
+		}
+
+		if !a.parser.Validate(updatedContent) {
+			return fmt.Errorf("updated content of %s is invalid", file)
+		}
+
+		if a.options.Backup {
+			backupPath, err := common.BackupFile(file)
+			if err != nil {
+				return fmt.Errorf("failed to back up %s: %w", file, err)
+			}
+			a.logger.InfoContext(ctx, "Backed up file before patching",
+				slog.String("file", file),
+				slog.String("backup", backupPath))
+		}
+
+		if err := os.WriteFile(file, []byte(updatedContent), 0644); err != nil {
+			return fmt.Errorf("failed to write %s: %w", file, err)
+		}
+	}
+
+	return nil
+}
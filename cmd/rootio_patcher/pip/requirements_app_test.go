@@ -0,0 +1,367 @@
+package pip
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"rootio_patcher/cmd/rootio_patcher/common"
+	"rootio_patcher/pkg/rootio"
+)
+
+func TestRequirementsApp_Run_FileNotFound(t *testing.T) {
+	ctx := context.Background()
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+
+	app := NewRequirementsAppWithServices(
+		"test-key",
+		"https://api.root.io",
+		"/nonexistent/requirements.txt",
+		true,
+		logger,
+		NewParser(),
+		&MockAPIClient{},
+	)
+
+	err := app.Run(ctx)
+	if err == nil {
+		t.Fatal("Expected error for nonexistent file, got nil")
+	}
+}
+
+func TestRequirementsApp_Run_OnlyPinnedPackagesAnalyzed(t *testing.T) {
+	ctx := context.Background()
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+
+	tmpDir := t.TempDir()
+	reqFile := filepath.Join(tmpDir, "requirements.txt")
+	content := "django==4.2.0\nrequests>=2.28\n"
+	if err := os.WriteFile(reqFile, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to create temp file: %v", err)
+	}
+
+	var analyzed []rootio.Package
+	mockAPIClient := &MockAPIClient{
+		AnalyzePackagesFunc: func(ctx context.Context, packages []rootio.Package) (*rootio.AnalyzePackagesResponse, error) {
+			analyzed = packages
+			return &rootio.AnalyzePackagesResponse{}, nil
+		},
+	}
+
+	app := NewRequirementsAppWithServices("test-key", "https://api.root.io", reqFile, true, logger, NewParser(), mockAPIClient)
+	if err := app.Run(ctx); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	if len(analyzed) != 1 || analyzed[0].Name != "django" {
+		t.Fatalf("Expected only django to be analyzed, got %v", analyzed)
+	}
+}
+
+func TestRequirementsApp_Run_APIError(t *testing.T) {
+	ctx := context.Background()
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+
+	tmpDir := t.TempDir()
+	reqFile := filepath.Join(tmpDir, "requirements.txt")
+	if err := os.WriteFile(reqFile, []byte("django==4.2.0\n"), 0644); err != nil {
+		t.Fatalf("Failed to create temp file: %v", err)
+	}
+
+	expectedError := errors.New("API error")
+	mockAPIClient := &MockAPIClient{
+		AnalyzePackagesFunc: func(ctx context.Context, packages []rootio.Package) (*rootio.AnalyzePackagesResponse, error) {
+			return nil, expectedError
+		},
+	}
+
+	app := NewRequirementsAppWithServices("test-key", "https://api.root.io", reqFile, true, logger, NewParser(), mockAPIClient)
+	err := app.Run(ctx)
+	if err == nil {
+		t.Fatal("Expected error, got nil")
+	}
+	if !errors.Is(err, expectedError) {
+		t.Fatalf("Expected error to wrap API error, got: %v", err)
+	}
+}
+
+func TestRequirementsApp_Run_DryRun(t *testing.T) {
+	ctx := context.Background()
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+
+	tmpDir := t.TempDir()
+	reqFile := filepath.Join(tmpDir, "requirements.txt")
+	content := "django==4.2.0\n"
+	if err := os.WriteFile(reqFile, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to create temp file: %v", err)
+	}
+
+	mockAPIClient := &MockAPIClient{
+		AnalyzePackagesFunc: func(ctx context.Context, packages []rootio.Package) (*rootio.AnalyzePackagesResponse, error) {
+			return &rootio.AnalyzePackagesResponse{
+				Patches: []rootio.PackagePatch{
+					{
+						PackageName: "django",
+						Version:     "4.2.0",
+						Patch:       rootio.PatchInfo{Name: "django", Version: "4.2.0.post1"},
+						CVEIDs:      []string{"CVE-2023-1234"},
+					},
+				},
+			}, nil
+		},
+	}
+
+	app := NewRequirementsAppWithServices("test-key", "https://api.root.io", reqFile, true, logger, NewParser(), mockAPIClient)
+	if err := app.Run(ctx); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	updatedContent, err := os.ReadFile(reqFile)
+	if err != nil {
+		t.Fatalf("Failed to read file: %v", err)
+	}
+	if string(updatedContent) != content {
+		t.Error("File should not be modified in dry-run mode")
+	}
+}
+
+func TestRequirementsApp_Run_ApplyPatchesToIncludedFile(t *testing.T) {
+	ctx := context.Background()
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+
+	tmpDir := t.TempDir()
+	reqFile := filepath.Join(tmpDir, "requirements.txt")
+	baseFile := filepath.Join(tmpDir, "base.txt")
+	if err := os.WriteFile(reqFile, []byte("-r base.txt\nflask==2.1.2\n"), 0644); err != nil {
+		t.Fatalf("Failed to create temp file: %v", err)
+	}
+	if err := os.WriteFile(baseFile, []byte("# base\ndjango==4.2.0  # web\n"), 0644); err != nil {
+		t.Fatalf("Failed to create temp file: %v", err)
+	}
+
+	mockAPIClient := &MockAPIClient{
+		AnalyzePackagesFunc: func(ctx context.Context, packages []rootio.Package) (*rootio.AnalyzePackagesResponse, error) {
+			return &rootio.AnalyzePackagesResponse{
+				Patches: []rootio.PackagePatch{
+					{
+						PackageName: "django",
+						Version:     "4.2.0",
+						Patch:       rootio.PatchInfo{Name: "django", Version: "4.2.0.post1"},
+					},
+				},
+			}, nil
+		},
+	}
+
+	app := NewRequirementsAppWithServices("test-key", "https://api.root.io", reqFile, false, logger, NewParser(), mockAPIClient)
+	if err := app.Run(ctx); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	baseContent, err := os.ReadFile(baseFile)
+	if err != nil {
+		t.Fatalf("Failed to read file: %v", err)
+	}
+	if string(baseContent) != "# base\ndjango==4.2.0.post1  # web\n" {
+		t.Errorf("Unexpected base.txt content: %q", string(baseContent))
+	}
+
+	mainContent, err := os.ReadFile(reqFile)
+	if err != nil {
+		t.Fatalf("Failed to read file: %v", err)
+	}
+	if string(mainContent) != "-r base.txt\nflask==2.1.2\n" {
+		t.Errorf("requirements.txt should be unchanged, got: %q", string(mainContent))
+	}
+}
+
+// copyIncludesFixture copies the requirements files of testdata/includes to a temporary directory
+func copyIncludesFixture(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	for _, name := range []string{"requirements.txt", "requirements-dev.txt", "requirements-docs.txt"} {
+		content, err := os.ReadFile(filepath.Join("testdata", "includes", name))
+		if err != nil {
+			t.Fatalf("Failed to read fixture: %v", err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, name), content, 0644); err != nil {
+			t.Fatalf("Failed to copy fixture: %v", err)
+		}
+	}
+	return dir
+}
+
+func TestRequirementsApp_Run_MultipleFiles(t *testing.T) {
+	ctx := context.Background()
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	dir := copyIncludesFixture(t)
+
+	var analyzed []rootio.Package
+	mockAPIClient := &MockAPIClient{
+		AnalyzePackagesFunc: func(ctx context.Context, packages []rootio.Package) (*rootio.AnalyzePackagesResponse, error) {
+			analyzed = packages
+			return &rootio.AnalyzePackagesResponse{
+				Patches: []rootio.PackagePatch{
+					{PackageName: "requests", Version: "2.28.0", Patch: rootio.PatchInfo{Name: "requests", Version: "2.31.0"}},
+					{PackageName: "pytest", Version: "7.2.0", Patch: rootio.PatchInfo{Name: "pytest", Version: "7.2.2"}},
+				},
+			}, nil
+		},
+	}
+
+	reqFile := filepath.Join(dir, "requirements.txt")
+	app := NewRequirementsAppWithServices("test-key", "https://api.root.io", reqFile, false, logger,
+		NewParser(), mockAPIClient,
+		common.WithAdditionalFiles(filepath.Join(dir, "requirements-docs.txt")),
+		common.WithOutput(io.Discard))
+	result, err := app.RunWithResult(ctx)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	// Pinned packages of the main file, its dev include and the additional file
+	if len(analyzed) != 5 {
+		t.Errorf("Expected 5 packages to be analyzed, got %+v", analyzed)
+	}
+	if result.PackagesFound != 5 {
+		t.Errorf("Expected 5 packages found, got %d", result.PackagesFound)
+	}
+
+	// Each patched version is written to the files that declare the package
+	expected := map[string]string{
+		"requirements.txt":      "# Production dependencies\ndjango==4.2.0\nrequests==2.31.0\n\n-r requirements-dev.txt\n",
+		"requirements-dev.txt":  "# Development and test dependencies\n-r requirements.txt\npytest==7.2.2\nblack>=23.0\n",
+		"requirements-docs.txt": "sphinx==5.3.0\nrequests==2.31.0\n",
+	}
+	for name, want := range expected {
+		content, err := os.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			t.Fatalf("Failed to read %s: %v", name, err)
+		}
+		if string(content) != want {
+			t.Errorf("Unexpected %s content: %q", name, string(content))
+		}
+	}
+}
+
+func TestRequirementsApp_Run_SkipDevIncludes(t *testing.T) {
+	ctx := context.Background()
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	dir := copyIncludesFixture(t)
+
+	var analyzed []rootio.Package
+	mockAPIClient := &MockAPIClient{
+		AnalyzePackagesFunc: func(ctx context.Context, packages []rootio.Package) (*rootio.AnalyzePackagesResponse, error) {
+			analyzed = packages
+			return &rootio.AnalyzePackagesResponse{}, nil
+		},
+	}
+
+	app := NewRequirementsAppWithServices("test-key", "https://api.root.io", filepath.Join(dir, "requirements.txt"), true, logger,
+		NewParser(), mockAPIClient, common.WithSkipDev(true), common.WithOutput(io.Discard))
+	result, err := app.RunWithResult(ctx)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	if result.DevSkipped != 2 {
+		t.Errorf("Expected the 2 packages of requirements-dev.txt to be skipped, got %d", result.DevSkipped)
+	}
+	for _, pkg := range analyzed {
+		if pkg.Name == "pytest" {
+			t.Errorf("Expected pytest to be left out with --skip-dev, got %+v", analyzed)
+		}
+	}
+}
+
+func TestRequirementsApp_Run_CVEFilter(t *testing.T) {
+	ctx := context.Background()
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	dir := copyIncludesFixture(t)
+
+	mockAPIClient := &MockAPIClient{
+		AnalyzePackagesFunc: func(ctx context.Context, packages []rootio.Package) (*rootio.AnalyzePackagesResponse, error) {
+			return &rootio.AnalyzePackagesResponse{
+				Patches: []rootio.PackagePatch{
+					{PackageName: "django", Version: "4.2.0", Patch: rootio.PatchInfo{Name: "django", Version: "4.2.2"},
+						CVEIDs: []string{"CVE-2023-31047", "CVE-2023-36053"}},
+					{PackageName: "requests", Version: "2.28.0", Patch: rootio.PatchInfo{Name: "requests", Version: "2.31.0"},
+						CVEIDs: []string{"CVE-2023-32681"}},
+				},
+			}, nil
+		},
+	}
+
+	var buf bytes.Buffer
+	reqFile := filepath.Join(dir, "requirements.txt")
+	app := NewRequirementsAppWithServices("test-key", "https://api.root.io", reqFile, false, logger,
+		NewParser(), mockAPIClient, common.WithCVEFilter([]string{"CVE-2023-32681"}), common.WithOutput(&buf))
+	result, err := app.RunWithResult(ctx)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	if len(result.Patches) != 1 || result.Patches[0].PackageName != "requests" {
+		t.Errorf("Expected only requests to be patched, got %+v", result.Patches)
+	}
+	if len(result.Skipped) != 1 || result.Skipped[0].PackageName != "django" {
+		t.Errorf("Expected django to be skipped, got %+v", result.Skipped)
+	}
+	if !strings.Contains(buf.String(), "CVE-2023-32681: requests 2.28.0") {
+		t.Errorf("Expected the matched package to be reported, got:\n%s", buf.String())
+	}
+
+	content, err := os.ReadFile(reqFile)
+	if err != nil {
+		t.Fatalf("Failed to read requirements.txt: %v", err)
+	}
+	if !strings.Contains(string(content), "django==4.2.0\nrequests==2.31.0\n") {
+		t.Errorf("Expected only requests to be updated, got:\n%s", content)
+	}
+}
+
+func TestRequirementsApp_Run_SkipsMissingTarget(t *testing.T) {
+	ctx := context.Background()
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	reqFile := filepath.Join(t.TempDir(), "requirements.txt")
+	content := "django==3.2.0\n"
+	if err := os.WriteFile(reqFile, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to create temp file: %v", err)
+	}
+
+	mockAPIClient := &MockAPIClient{
+		AnalyzePackagesFunc: func(ctx context.Context, packages []rootio.Package) (*rootio.AnalyzePackagesResponse, error) {
+			return &rootio.AnalyzePackagesResponse{
+				Patches: []rootio.PackagePatch{{PackageName: "django", Version: "3.2.0", Patch: rootio.PatchInfo{Name: "django"}}},
+			}, nil
+		},
+	}
+
+	app := NewRequirementsAppWithServices("test-key", "https://api.root.io", reqFile, false, logger,
+		NewParser(), mockAPIClient, common.WithOutput(io.Discard))
+	result, err := app.RunWithResult(ctx)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	if len(result.Patches) != 0 {
+		t.Errorf("Expected no patches, got %+v", result.Patches)
+	}
+	if len(result.Skipped) != 1 || result.Skipped[0].Reason != "the API returned no patched version for django 3.2.0" {
+		t.Errorf("Expected django to be skipped for its missing version, got %+v", result.Skipped)
+	}
+	updated, err := os.ReadFile(reqFile)
+	if err != nil {
+		t.Fatalf("Failed to read requirements.txt: %v", err)
+	}
+	if string(updated) != content {
+		t.Errorf("Expected requirements.txt to be unchanged, got %q", updated)
+	}
+}
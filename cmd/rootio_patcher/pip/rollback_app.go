@@ -0,0 +1,143 @@
+package pip
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"time"
+
+	"rootio_patcher/cmd/rootio_patcher/common"
+	"rootio_patcher/cmd/rootio_patcher/config"
+	"rootio_patcher/pkg/rootio"
+)
+
+// RollbackApp undoes pip patches recorded in the patch journal
+type RollbackApp struct {
+	journalPath string
+	dryRun      bool
+	logger      *slog.Logger
+	pipService  Service
+	out         io.Writer
+
+	result *common.RunResult
+}
+
+// NewRollbackApp creates a new pip rollback application instance
+func NewRollbackApp(
+	cfg *config.Config, pythonPath, journalPath string, dryRun bool, logger *slog.Logger, opts ...common.Option,
+) *RollbackApp {
+	options := common.NewOptions(opts...)
+	pipService := NewService(pythonPath, cfg.PKGURL, cfg.APIKey, false, logger, WithTarget(options.TargetDir))
+	return NewRollbackAppWithServices(journalPath, dryRun, logger, pipService, opts...)
+}
+
+// NewRollbackAppWithServices creates a new pip rollback application with injected services (for testing)
+func NewRollbackAppWithServices(
+	journalPath string, dryRun bool, logger *slog.Logger, pipService Service, opts ...common.Option,
+) *RollbackApp {
+	return &RollbackApp{
+		journalPath: journalPath,
+		dryRun:      dryRun,
+		logger:      logger,
+		pipService:  pipService,
+		out:         common.NewOptions(opts...).Output(),
+	}
+}
+
+// Result returns the structured result of the last run.
+// Each patch result describes a revert: from the patched package back to the original.
+func (a *RollbackApp) Result() *common.RunResult {
+	return a.result
+}
+
+// RunWithResult runs the rollback and returns its structured result
+func (a *RollbackApp) RunWithResult(ctx context.Context) (*common.RunResult, error) {
+	err := a.Run(ctx)
+	a.result.SetError(err)
+	return a.result, err
+}
+
+// Run reverts every applied patch in the journal that hasn't been rolled back yet
+func (a *RollbackApp) Run(ctx context.Context) error {
+	a.logger.DebugContext(ctx, "Starting pip rollback",
+		slog.String("journal", a.journalPath),
+		slog.Bool("dry_run", a.dryRun))
+	a.result = common.NewRunResult(common.EcosystemPyPI, a.journalPath, a.dryRun)
+
+	entries, err := ReadJournal(a.journalPath)
+	if err != nil {
+		return fmt.Errorf("failed to read journal %s: %w", a.journalPath, err)
+	}
+
+	pending := PendingRollbacks(entries)
+	if len(pending) == 0 {
+		fmt.Fprintf(a.out, "\nNothing to roll back - no applied patches recorded in %s\n", a.journalPath)
+		return nil
+	}
+
+	status := common.PatchStatusPending
+	if a.dryRun {
+		status = common.PatchStatusDryRun
+	}
+	for _, entry := range pending {
+		a.result.AddPatches([]rootio.PackagePatch{revertPatch(entry)}, false, status)
+	}
+
+	if a.dryRun {
+		fmt.Fprintln(a.out, "\n=== DRY-RUN MODE ===")
+		fmt.Fprintf(a.out, "The following patches recorded in %s would be rolled back:\n\n", a.journalPath)
+		for i, entry := range pending {
+			fmt.Fprintf(a.out, "%d. %s %s → %s %s\n", i+1,
+				entry.PatchedName, entry.PatchedVersion, entry.PackageName, entry.OriginalVersion)
+		}
+		fmt.Fprintln(a.out, "\nTo roll back these patches, run with --dry-run=false")
+		return nil
+	}
+
+	if err := a.pipService.CheckPip(ctx); err != nil {
+		return fmt.Errorf("failed to find pip: %w", err)
+	}
+
+	fmt.Fprintf(a.out, "\nRolling back %d patches...\n\n", len(pending))
+	for i, entry := range pending {
+		fmt.Fprintf(a.out, "[%d/%d] Restoring %s %s (replacing %s %s)...\n",
+			i+1, len(pending),
+			entry.PackageName, entry.OriginalVersion,
+			entry.PatchedName, entry.PatchedVersion)
+
+		if err := a.pipService.RevertPatch(ctx, entry); err != nil {
+			fmt.Fprintf(a.out, "✗ Rollback failed: %v\n", err)
+			a.result.SetPatchStatus(i, common.PatchStatusFailed, err)
+			for j := i + 1; j < len(pending); j++ {
+				a.result.SetPatchStatus(j, common.PatchStatusNotApplied, nil)
+			}
+			return fmt.Errorf("rollback failed: %w", err)
+		}
+		a.result.SetPatchStatus(i, common.PatchStatusApplied, nil)
+
+		entry.Action = JournalActionRolledBack
+		entry.Timestamp = time.Time{}
+		if err := AppendJournal(a.journalPath, entry); err != nil {
+			return fmt.Errorf("failed to record rollback of %s: %w", entry.PackageName, err)
+		}
+
+		fmt.Fprintf(a.out, "  ✓ Restored %s\n\n", entry.PackageName)
+	}
+
+	fmt.Fprintf(a.out, "\n✓ Successfully rolled back %d patches!\n", len(pending))
+
+	return nil
+}
+
+// revertPatch describes a journal entry as a patch from the patched package back to the original
+func revertPatch(entry JournalEntry) rootio.PackagePatch {
+	return rootio.PackagePatch{
+		PackageName: entry.PatchedName,
+		Version:     entry.PatchedVersion,
+		Patch: rootio.PatchInfo{
+			Name:    entry.PackageName,
+			Version: entry.OriginalVersion,
+		},
+	}
+}
@@ -0,0 +1,218 @@
+package pip
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"rootio_patcher/cmd/rootio_patcher/common"
+	"rootio_patcher/cmd/rootio_patcher/config"
+	"rootio_patcher/pkg/rootio"
+)
+
+func TestPipApp_Run_JournalRecordsOnlyAppliedPatches(t *testing.T) {
+	ctx := context.Background()
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+	journalPath := filepath.Join(t.TempDir(), "journal")
+
+	mockPipService := &MockPipService{
+		ListPackagesFunc: func(ctx context.Context) ([]common.InstalledPackage, error) {
+			return []common.InstalledPackage{
+				{Name: "django", Version: "4.0.0"},
+				{Name: "flask", Version: "2.0.0"},
+				{Name: "requests", Version: "2.6.0"},
+			}, nil
+		},
+		ApplyPatchFunc: func(ctx context.Context, patch rootio.PackagePatch) error {
+			// Simulate a failure midway through the run
+			if patch.PackageName == "flask" {
+				return errors.New("install failed")
+			}
+			return nil
+		},
+	}
+
+	mockAPIClient := &MockAPIClient{
+		AnalyzePackagesFunc: func(ctx context.Context, packages []rootio.Package) (*rootio.AnalyzePackagesResponse, error) {
+			return &rootio.AnalyzePackagesResponse{
+				Patches: []rootio.PackagePatch{
+					{PackageName: "django", Version: "4.0.0", PatchAlias: rootio.PatchInfo{Name: "rootio-django", Version: "4.0.1"}},
+					{PackageName: "flask", Version: "2.0.0", PatchAlias: rootio.PatchInfo{Name: "rootio-flask", Version: "2.0.1"}},
+					{PackageName: "requests", Version: "2.6.0", PatchAlias: rootio.PatchInfo{Name: "rootio-requests", Version: "2.6.1"}},
+				},
+			}, nil
+		},
+	}
+
+	mockReporter := common.NewReporter("https://pkg.root.io", logger)
+	cfg := &config.Config{}
+	app := NewAppWithServices(cfg, "python", false, true, logger, mockPipService, mockAPIClient, mockReporter,
+		common.WithJournal(journalPath))
+
+	if err := app.Run(ctx); err == nil {
+		t.Fatal("Expected error, got nil")
+	}
+
+	entries, err := ReadJournal(journalPath)
+	if err != nil {
+		t.Fatalf("Failed to read journal: %v", err)
+	}
+
+	if len(entries) != 1 {
+		t.Fatalf("Expected 1 journal entry, got %d: %+v", len(entries), entries)
+	}
+
+	entry := entries[0]
+	if entry.Action != JournalActionApplied || entry.PackageName != "django" || entry.OriginalVersion != "4.0.0" ||
+		entry.PatchedName != "rootio-django" || entry.PatchedVersion != "4.0.1" {
+		t.Errorf("Unexpected journal entry: %+v", entry)
+	}
+}
+
+func TestPipApp_Run_NoJournalByDefault(t *testing.T) {
+	ctx := context.Background()
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+
+	// Run from an empty directory so a stray default journal would be noticed
+	t.Chdir(t.TempDir())
+
+	mockPipService := &MockPipService{
+		ListPackagesFunc: func(ctx context.Context) ([]common.InstalledPackage, error) {
+			return []common.InstalledPackage{{Name: "django", Version: "4.0.0"}}, nil
+		},
+	}
+
+	mockAPIClient := &MockAPIClient{
+		AnalyzePackagesFunc: func(ctx context.Context, packages []rootio.Package) (*rootio.AnalyzePackagesResponse, error) {
+			return &rootio.AnalyzePackagesResponse{
+				Patches: []rootio.PackagePatch{
+					{PackageName: "django", Version: "4.0.0", PatchAlias: rootio.PatchInfo{Name: "rootio-django", Version: "4.0.1"}},
+				},
+			}, nil
+		},
+	}
+
+	mockReporter := common.NewReporter("https://pkg.root.io", logger)
+	cfg := &config.Config{}
+	app := NewAppWithServices(cfg, "python", false, true, logger, mockPipService, mockAPIClient, mockReporter)
+
+	if err := app.Run(ctx); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	if _, err := os.Stat(DefaultJournalPath); !os.IsNotExist(err) {
+		t.Errorf("Expected no journal to be written without WithJournal, got: %v", err)
+	}
+}
+
+func TestRollbackApp_Run(t *testing.T) {
+	ctx := context.Background()
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+	journalPath := filepath.Join(t.TempDir(), "journal")
+
+	for _, entry := range []JournalEntry{
+		{Action: JournalActionApplied, PackageName: "django", OriginalVersion: "4.0.0", PatchedName: "rootio-django", PatchedVersion: "4.0.1"},
+		{Action: JournalActionApplied, PackageName: "flask", OriginalVersion: "2.0.0", PatchedName: "rootio-flask", PatchedVersion: "2.0.1"},
+		{Action: JournalActionRolledBack, PackageName: "flask", OriginalVersion: "2.0.0", PatchedName: "rootio-flask", PatchedVersion: "2.0.1"},
+		{Action: JournalActionApplied, PackageName: "requests", OriginalVersion: "2.6.0", PatchedName: "rootio-requests", PatchedVersion: "2.6.1"},
+	} {
+		if err := AppendJournal(journalPath, entry); err != nil {
+			t.Fatalf("Failed to write journal: %v", err)
+		}
+	}
+
+	var reverted []string
+	mockPipService := &MockPipService{
+		RevertPatchFunc: func(ctx context.Context, entry JournalEntry) error {
+			reverted = append(reverted, entry.PackageName)
+			return nil
+		},
+	}
+
+	app := NewRollbackAppWithServices(journalPath, false, logger, mockPipService)
+	if err := app.Run(ctx); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	// Patches are reverted most recent first; flask was already rolled back
+	if len(reverted) != 2 || reverted[0] != "requests" || reverted[1] != "django" {
+		t.Fatalf("Expected requests then django to be reverted, got %v", reverted)
+	}
+
+	result := app.Result()
+	if result.Applied() != 2 {
+		t.Errorf("Expected 2 rollbacks applied, got %d", result.Applied())
+	}
+
+	// A second rollback has nothing left to do
+	entries, err := ReadJournal(journalPath)
+	if err != nil {
+		t.Fatalf("Failed to read journal: %v", err)
+	}
+	if pending := PendingRollbacks(entries); len(pending) != 0 {
+		t.Errorf("Expected no pending rollbacks, got %+v", pending)
+	}
+}
+
+func TestRollbackApp_Run_DryRun(t *testing.T) {
+	ctx := context.Background()
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+	journalPath := filepath.Join(t.TempDir(), "journal")
+
+	entry := JournalEntry{Action: JournalActionApplied, PackageName: "django", OriginalVersion: "4.0.0", PatchedName: "rootio-django", PatchedVersion: "4.0.1"}
+	if err := AppendJournal(journalPath, entry); err != nil {
+		t.Fatalf("Failed to write journal: %v", err)
+	}
+
+	mockPipService := &MockPipService{
+		RevertPatchFunc: func(ctx context.Context, entry JournalEntry) error {
+			t.Error("RevertPatch should not be called in dry-run mode")
+			return nil
+		},
+	}
+
+	app := NewRollbackAppWithServices(journalPath, true, logger, mockPipService)
+	if err := app.Run(ctx); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	if len(app.Result().Patches) != 1 || app.Result().Patches[0].Status != common.PatchStatusDryRun {
+		t.Errorf("Expected 1 dry-run rollback, got %+v", app.Result().Patches)
+	}
+}
+
+func TestRollbackApp_Run_RevertError(t *testing.T) {
+	ctx := context.Background()
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+	journalPath := filepath.Join(t.TempDir(), "journal")
+
+	for _, name := range []string{"django", "flask"} {
+		entry := JournalEntry{Action: JournalActionApplied, PackageName: name, OriginalVersion: "1.0.0", PatchedName: "rootio-" + name, PatchedVersion: "1.0.1"}
+		if err := AppendJournal(journalPath, entry); err != nil {
+			t.Fatalf("Failed to write journal: %v", err)
+		}
+	}
+
+	mockPipService := &MockPipService{
+		RevertPatchFunc: func(ctx context.Context, entry JournalEntry) error {
+			return errors.New("install failed")
+		},
+	}
+
+	app := NewRollbackAppWithServices(journalPath, false, logger, mockPipService)
+	if err := app.Run(ctx); err == nil {
+		t.Fatal("Expected error, got nil")
+	}
+
+	// Failed rollbacks stay pending so they can be retried
+	entries, err := ReadJournal(journalPath)
+	if err != nil {
+		t.Fatalf("Failed to read journal: %v", err)
+	}
+	if pending := PendingRollbacks(entries); len(pending) != 2 {
+		t.Errorf("Expected 2 pending rollbacks, got %d", len(pending))
+	}
+}
@@ -3,11 +3,15 @@ package pip
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log/slog"
 	"net/url"
+	"os"
 	"os/exec"
 	"runtime"
+	"slices"
+	"strings"
 
 	"rootio_patcher/cmd/rootio_patcher/common"
 	"rootio_patcher/pkg/rootio"
@@ -15,6 +19,9 @@ import (
 
 // Service defines the interface for pip operations
 type Service interface {
+	// CheckPip verifies the Python interpreter has pip available
+	CheckPip(ctx context.Context) error
+
 	// ListPackages lists all installed packages
 	ListPackages(ctx context.Context) ([]common.InstalledPackage, error)
 	
@@ -23,35 +30,100 @@ type Service interface {
 	
 	// ApplyPatchForPip applies a patch specifically for pip itself
 	ApplyPatchForPip(ctx context.Context, patch rootio.PackagePatch) error
+
+	// RevertPatch restores the original package recorded in a journal entry
+	RevertPatch(ctx context.Context, entry JournalEntry) error
+
+	// RestorePackage reinstalls a package version from the default index
+	RestorePackage(ctx context.Context, name, version string) error
+
+	// PackageDependencies returns the requirements of each installed package, as listed by pip show
+	PackageDependencies(ctx context.Context, names []string) (map[string][]string, error)
 }
 
+// ErrPackageRemoved is returned by ApplyPatch when the vulnerable package was uninstalled but the
+// patched one wasn't installed, leaving neither in the environment
+var ErrPackageRemoved = errors.New("package was uninstalled but the patch was not installed")
+
 // PipService implements Service for pip operations
 type PipService struct {
 	pythonPath string
 	pkgURL     string
 	apiKey     string
 	useAlias   bool
+	useNetrc   bool
 	logger     *slog.Logger
+
+	// installDeps lets pip resolve dependencies instead of passing --no-deps
+	installDeps bool
+
+	// target is a directory-based install (pip install --target) patched instead of the
+	// interpreter's environment
+	target string
+}
+
+// ServiceOption configures a PipService
+type ServiceOption func(*PipService)
+
+// WithNetrc passes the index credentials to pip in a temporary netrc file instead of the index URL,
+// so the API key doesn't show up in process listings or pip's output
+func WithNetrc(enabled bool) ServiceOption {
+	return func(s *PipService) {
+		s.useNetrc = enabled
+	}
+}
+
+// WithInstallDeps lets pip install the dependencies of patched packages from the Root.io index.
+// By default --no-deps is passed so patching never changes any other installed package.
+func WithInstallDeps(enabled bool) ServiceOption {
+	return func(s *PipService) {
+		s.installDeps = enabled
+	}
+}
+
+// WithTarget lists and patches the packages installed in dir with pip install --target (or a
+// venv's site-packages) instead of the interpreter's own environment
+func WithTarget(dir string) ServiceOption {
+	return func(s *PipService) {
+		s.target = dir
+	}
 }
 
 // NewService creates a new pip service
-func NewService(pythonPath, pkgURL, apiKey string, useAlias bool, logger *slog.Logger) *PipService {
-	return &PipService{
+func NewService(pythonPath, pkgURL, apiKey string, useAlias bool, logger *slog.Logger, opts ...ServiceOption) *PipService {
+	s := &PipService{
 		pythonPath: pythonPath,
 		pkgURL:     pkgURL,
 		apiKey:     apiKey,
 		useAlias:   useAlias,
 		logger:     logger,
 	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// CheckPip runs python -m pip --version to make sure pip is available
+func (s *PipService) CheckPip(ctx context.Context) error {
+	//nolint:gosec // Subprocess is safe - using fixed pip arguments, pythonPath from config
+	cmd := exec.CommandContext(ctx, s.pythonPath, "-m", "pip", "--version")
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("pip is not available for %s: %w (output: %s)",
+			s.pythonPath, err, s.redact(strings.TrimSpace(string(output))))
+	}
+	return nil
 }
 
 // ListPackages collects all installed packages using pip list
 func (s *PipService) ListPackages(ctx context.Context) ([]common.InstalledPackage, error) {
 	s.logger.DebugContext(ctx, "Using Python executable", slog.String("path", s.pythonPath))
 
-	// Run: python -m pip list --format=json
+	// Run: python -m pip list --format=json [--path <target>]
 	//nolint:gosec // Subprocess is safe - using fixed pip list arguments, pythonPath from config
-	cmd := exec.CommandContext(ctx, s.pythonPath, "-m", "pip", "list", "--format=json")
+	cmd := exec.CommandContext(ctx, s.pythonPath, s.listArgs()...)
+	// Verbose output adds each package's location, where its install record is kept
+	cmd.Env = append(cmd.Environ(), "PIP_VERBOSE=1")
 	output, err := cmd.Output()
 	if err != nil {
 		return nil, fmt.Errorf("failed to run pip list: %w", err)
@@ -62,26 +134,36 @@ func (s *PipService) ListPackages(ctx context.Context) ([]common.InstalledPackag
 	if err := json.Unmarshal(output, &packages); err != nil {
 		return nil, fmt.Errorf("failed to parse pip list output: %w", err)
 	}
+	markDirectURLs(packages)
 
 	return packages, nil
 }
 
 // ApplyPatch applies a single package patch (uninstall + install)
 func (s *PipService) ApplyPatch(ctx context.Context, patch rootio.PackagePatch) error {
-	// 1. Uninstall vulnerable package (original name)
+	// 1. Select patch based on useAlias flag, falling back to the direct patch without an alias
+	patchInfo := common.PatchTarget(patch, s.useAlias)
+
+	// Prepare the install before uninstalling, so a credentials failure leaves the package in place
+	packageSpec := fmt.Sprintf("%s==%s", patchInfo.Name, patchInfo.Version)
+	installCmd, cleanup, err := s.installCommand(ctx, packageSpec)
+	if err != nil {
+		return err
+	}
+	defer cleanup()
+
+	// 2. Uninstall vulnerable package (original name)
 	s.logger.DebugContext(ctx, "Uninstalling package", slog.String("package", patch.PackageName))
 	//nolint:gosec // Subprocess command is safe - using validated package names from our API
 	uninstallCmd := exec.CommandContext(ctx, s.pythonPath, "-m", "pip", "uninstall", "-y", patch.PackageName)
+	s.findInTarget(uninstallCmd)
 	if output, err := uninstallCmd.CombinedOutput(); err != nil {
-		return fmt.Errorf("uninstall failed: %w (output: %s)", err, string(output))
+		return fmt.Errorf("uninstall failed: %w (output: %s)", err, s.redact(string(output)))
 	}
 
-	// 2. Select patch based on useAlias flag
-	var patchInfo rootio.PatchInfo
-	if s.useAlias {
-		patchInfo = patch.PatchAlias
-	} else {
-		patchInfo = patch.Patch
+	// Interrupted between the two steps: the install would only fail on the cancelled context
+	if err := ctx.Err(); err != nil {
+		return fmt.Errorf("%w: %w", ErrPackageRemoved, err)
 	}
 
 	// 3. Install patched package
@@ -90,20 +172,8 @@ func (s *PipService) ApplyPatch(ctx context.Context, patch rootio.PackagePatch)
 		slog.String("version", patchInfo.Version),
 		slog.Bool("use_alias", s.useAlias))
 
-	indexURL := s.constructIndexURL()
-	packageSpec := fmt.Sprintf("%s==%s", patchInfo.Name, patchInfo.Version)
-
-	//nolint:gosec // Subprocess command is safe - using package names from our API
-	installCmd := exec.CommandContext(
-		ctx, s.pythonPath, "-m", "pip", "install",
-		"--no-deps",
-		"--no-cache-dir",
-		"--index-url", indexURL,
-		packageSpec,
-	)
-
 	if output, err := installCmd.CombinedOutput(); err != nil {
-		return fmt.Errorf("install failed: %w (output: %s)", err, string(output))
+		return fmt.Errorf("%w: install failed: %w (output: %s)", ErrPackageRemoved, err, s.redact(string(output)))
 	}
 
 	return nil
@@ -111,50 +181,223 @@ func (s *PipService) ApplyPatch(ctx context.Context, patch rootio.PackagePatch)
 
 // ApplyPatchForPip applies a patch for pip itself using upgrade
 func (s *PipService) ApplyPatchForPip(ctx context.Context, patch rootio.PackagePatch) error {
-	var patchInfo rootio.PatchInfo
-	if s.useAlias {
-		patchInfo = patch.PatchAlias
-	} else {
-		patchInfo = patch.Patch
-	}
+	patchInfo := common.PatchTarget(patch, s.useAlias)
 
 	s.logger.DebugContext(ctx, "Upgrading pip package",
 		slog.String("version", patchInfo.Version))
 
-	indexURL := s.constructIndexURL()
 	packageSpec := fmt.Sprintf("%s==%s", patchInfo.Name, patchInfo.Version)
+	installCmd, cleanup, err := s.installCommand(ctx, packageSpec, "--upgrade")
+	if err != nil {
+		return err
+	}
+	defer cleanup()
 
-	//nolint:gosec // Subprocess command is safe - using package names from our API
-	installCmd := exec.CommandContext(
-		ctx, s.pythonPath, "-m", "pip", "install",
-		"--no-deps",
-		"--no-cache-dir",
-		"--upgrade",
-		"--index-url", indexURL,
-		packageSpec,
-	)
+	if output, err := installCmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("pip upgrade failed: %w (output: %s)", err, s.redact(string(output)))
+	}
+
+	return nil
+}
+
+// RevertPatch uninstalls the patched package and reinstalls the original version from the default index
+func (s *PipService) RevertPatch(ctx context.Context, entry JournalEntry) error {
+	// pip was upgraded in place, so it is downgraded rather than uninstalled
+	if normalizeName(entry.PackageName) != "pip" {
+		s.logger.DebugContext(ctx, "Uninstalling patched package", slog.String("package", entry.PatchedName))
+		//nolint:gosec // Subprocess command is safe - using package names from the journal
+		uninstallCmd := exec.CommandContext(ctx, s.pythonPath, "-m", "pip", "uninstall", "-y", entry.PatchedName)
+		s.findInTarget(uninstallCmd)
+		if output, err := uninstallCmd.CombinedOutput(); err != nil {
+			return fmt.Errorf("uninstall failed: %w (output: %s)", err, s.redact(string(output)))
+		}
+	}
+
+	return s.RestorePackage(ctx, entry.PackageName, entry.OriginalVersion)
+}
+
+// RestorePackage installs name==version from the default index, without the Root.io credentials
+func (s *PipService) RestorePackage(ctx context.Context, name, version string) error {
+	s.logger.DebugContext(ctx, "Reinstalling original package",
+		slog.String("package", name),
+		slog.String("version", version))
+
+	packageSpec := fmt.Sprintf("%s==%s", name, version)
+
+	args := append([]string{"-m", "pip", "install", "--no-deps", "--no-cache-dir"}, s.targetArgs(nil)...)
+
+	//nolint:gosec // Subprocess command is safe - using package names from the journal or our API
+	installCmd := exec.CommandContext(ctx, s.pythonPath, append(args, packageSpec)...)
 
 	if output, err := installCmd.CombinedOutput(); err != nil {
-		return fmt.Errorf("pip upgrade failed: %w (output: %s)", err, string(output))
+		return fmt.Errorf("install failed: %w (output: %s)", err, s.redact(string(output)))
 	}
 
 	return nil
 }
 
-// constructIndexURL builds the authenticated PyPI index URL
-func (s *PipService) constructIndexURL() string {
+// PackageDependencies runs pip show for the packages and returns the requirements of each one
+// that is installed, keyed by its installed name
+func (s *PipService) PackageDependencies(ctx context.Context, names []string) (map[string][]string, error) {
+	args := append([]string{"-m", "pip", "show"}, names...)
+
+	//nolint:gosec // Subprocess command is safe - using validated package names from our API
+	cmd := exec.CommandContext(ctx, s.pythonPath, args...)
+	s.findInTarget(cmd)
+	output, err := cmd.Output()
+
+	// pip show fails if none of the packages are installed, but still lists the ones that are
+	if err != nil && len(output) == 0 {
+		return nil, fmt.Errorf("failed to run pip show: %w", err)
+	}
+
+	return parsePipShow(string(output)), nil
+}
+
+// parsePipShow parses the Name and Requires fields of pip show output
+func parsePipShow(output string) map[string][]string {
+	dependencies := make(map[string][]string)
+
+	var name string
+	for _, line := range strings.Split(output, "\n") {
+		key, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		value = strings.TrimSpace(value)
+
+		switch key {
+		case "Name":
+			name = value
+			dependencies[name] = nil
+		case "Requires":
+			if name == "" || value == "" {
+				continue
+			}
+			for _, requirement := range strings.Split(value, ",") {
+				dependencies[name] = append(dependencies[name], strings.TrimSpace(requirement))
+			}
+		}
+	}
+
+	return dependencies
+}
+
+// installCommand builds the pip install command for packageSpec from the Root.io index. In netrc
+// mode the credentials are written to a temporary netrc file that pip finds through $NETRC;
+// the returned cleanup removes it and must be called once the command has run.
+func (s *PipService) installCommand(ctx context.Context, packageSpec string, extraArgs ...string) (*exec.Cmd, func(), error) {
+	args := []string{"-m", "pip", "install"}
+	if !s.installDeps {
+		args = append(args, "--no-deps")
+	}
+	args = append(args, "--no-cache-dir")
+	args = append(args, extraArgs...)
+	args = append(args, s.targetArgs(extraArgs)...)
+	args = append(args, "--index-url", s.constructIndexURL(!s.useNetrc), packageSpec)
+
+	//nolint:gosec // Subprocess command is safe - using package names from our API
+	cmd := exec.CommandContext(ctx, s.pythonPath, args...)
+	if !s.useNetrc {
+		return cmd, func() {}, nil
+	}
+
+	netrcPath, err := s.writeNetrc()
+	if err != nil {
+		return nil, nil, err
+	}
+	cmd.Env = append(cmd.Environ(), "NETRC="+netrcPath)
+	return cmd, func() { os.Remove(netrcPath) }, nil
+}
+
+// listArgs returns the pip list arguments, limited to the target directory when one is set
+func (s *PipService) listArgs() []string {
+	args := []string{"-m", "pip", "list", "--format=json"}
+	if s.target != "" {
+		args = append(args, "--path", s.target)
+	}
+	return args
+}
+
+// targetArgs returns the pip install arguments installing into the target directory. pip
+// keeps a package already in the target unless --upgrade is given, so it's added when
+// extraArgs don't have it.
+func (s *PipService) targetArgs(extraArgs []string) []string {
+	if s.target == "" {
+		return nil
+	}
+	args := []string{"--target", s.target}
+	if !slices.Contains(extraArgs, "--upgrade") {
+		args = append(args, "--upgrade")
+	}
+	return args
+}
+
+// findInTarget puts the target directory on cmd's PYTHONPATH, so pip uninstall and pip show,
+// which have no --path option, find the packages installed there
+func (s *PipService) findInTarget(cmd *exec.Cmd) {
+	if s.target == "" {
+		return
+	}
+	pythonPath := s.target
+	if existing := os.Getenv("PYTHONPATH"); existing != "" {
+		pythonPath += string(os.PathListSeparator) + existing
+	}
+	cmd.Env = append(cmd.Environ(), "PYTHONPATH="+pythonPath)
+}
+
+// writeNetrc writes the index credentials to a temporary netrc file readable only by the current user
+func (s *PipService) writeNetrc() (string, error) {
+	parsedURL, err := url.Parse(s.pkgURL)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse package URL: %w", err)
+	}
+
+	file, err := os.CreateTemp("", "rootio-netrc-*")
+	if err != nil {
+		return "", fmt.Errorf("failed to create netrc file: %w", err)
+	}
+
+	// Don't leave the credentials behind if writing fails
+	if err := file.Chmod(0600); err != nil {
+		file.Close()
+		os.Remove(file.Name())
+		return "", fmt.Errorf("failed to restrict netrc file permissions: %w", err)
+	}
+	if _, err := fmt.Fprintf(file, "machine %s login root password %s\n", parsedURL.Hostname(), s.apiKey); err != nil {
+		file.Close()
+		os.Remove(file.Name())
+		return "", fmt.Errorf("failed to write netrc file: %w", err)
+	}
+	if err := file.Close(); err != nil {
+		os.Remove(file.Name())
+		return "", fmt.Errorf("failed to write netrc file: %w", err)
+	}
+
+	return file.Name(), nil
+}
+
+// constructIndexURL builds the PyPI index URL, with the credentials embedded when withCredentials is set
+func (s *PipService) constructIndexURL(withCredentials bool) string {
 	parsedURL, err := url.Parse(s.pkgURL)
 	if err != nil {
-		s.logger.Warn("Failed to parse package URL, using as-is", slog.String("url", s.pkgURL))
+		s.logger.Warn("Failed to parse package URL, using as-is", slog.String("url", s.redact(s.pkgURL)))
 		return s.pkgURL + "/pypi/simple/"
 	}
 
-	parsedURL.User = url.UserPassword("root", s.apiKey)
+	if withCredentials {
+		parsedURL.User = url.UserPassword("root", s.apiKey)
+	}
 	parsedURL.Path = "/pypi/simple/"
 
 	return parsedURL.String()
 }
 
+// redact hides the API key and any URL credentials in command output before it is logged or returned
+func (s *PipService) redact(text string) string {
+	return common.Redact(text, s.apiKey)
+}
+
 // GetArch returns the system architecture
 func GetArch() string {
 	return runtime.GOARCH
@@ -0,0 +1,361 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+
+	"rootio_patcher/cmd/rootio_patcher/common"
+)
+
+const (
+	// outputSARIF selects SARIF output for code scanning tools
+	outputSARIF = "sarif"
+
+	sarifVersion = "2.1.0"
+	sarifSchema  = "https://json.schemastore.org/sarif-2.1.0.json"
+
+	// sarifDefaultRule is the rule of patches the API listed no CVEs for
+	sarifDefaultRule = "vulnerable-dependency"
+
+	// sarifVersionSearchLines is how many lines after a package name are searched for its version,
+	// which is on a separate line in pom.xml and package-lock.json
+	sarifVersionSearchLines = 5
+)
+
+// sarifLog is a SARIF 2.1.0 document
+type sarifLog struct {
+	Schema  string     `json:"$schema"`
+	Version string     `json:"version"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool        sarifTool         `json:"tool"`
+	Results     []sarifResult     `json:"results"`
+	Invocations []sarifInvocation `json:"invocations"`
+}
+
+type sarifTool struct {
+	Driver sarifDriver `json:"driver"`
+}
+
+type sarifDriver struct {
+	Name           string      `json:"name"`
+	Version        string      `json:"version,omitempty"`
+	InformationURI string      `json:"informationUri"`
+	Rules          []sarifRule `json:"rules"`
+}
+
+type sarifRule struct {
+	ID               string         `json:"id"`
+	ShortDescription sarifMessage   `json:"shortDescription"`
+	HelpURI          string         `json:"helpUri,omitempty"`
+	Properties       map[string]any `json:"properties,omitempty"`
+}
+
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+type sarifResult struct {
+	RuleID     string          `json:"ruleId"`
+	Kind       string          `json:"kind"`
+	Level      string          `json:"level"`
+	Message    sarifMessage    `json:"message"`
+	Locations  []sarifLocation `json:"locations,omitempty"`
+	Fixes      []sarifFix      `json:"fixes,omitempty"`
+	Properties map[string]any  `json:"properties"`
+}
+
+type sarifLocation struct {
+	PhysicalLocation sarifPhysicalLocation `json:"physicalLocation"`
+}
+
+type sarifPhysicalLocation struct {
+	ArtifactLocation sarifArtifactLocation `json:"artifactLocation"`
+	Region           *sarifRegion          `json:"region,omitempty"`
+}
+
+type sarifArtifactLocation struct {
+	URI       string `json:"uri"`
+	URIBaseID string `json:"uriBaseId,omitempty"`
+}
+
+type sarifRegion struct {
+	StartLine   int `json:"startLine"`
+	StartColumn int `json:"startColumn,omitempty"`
+	EndColumn   int `json:"endColumn,omitempty"`
+}
+
+type sarifFix struct {
+	Description     sarifMessage          `json:"description"`
+	ArtifactChanges []sarifArtifactChange `json:"artifactChanges"`
+}
+
+type sarifArtifactChange struct {
+	ArtifactLocation sarifArtifactLocation `json:"artifactLocation"`
+	Replacements     []sarifReplacement    `json:"replacements"`
+}
+
+type sarifReplacement struct {
+	DeletedRegion   sarifRegion          `json:"deletedRegion"`
+	InsertedContent sarifArtifactContent `json:"insertedContent"`
+}
+
+type sarifArtifactContent struct {
+	Text string `json:"text"`
+}
+
+type sarifInvocation struct {
+	ExecutionSuccessful        bool                `json:"executionSuccessful"`
+	ToolExecutionNotifications []sarifNotification `json:"toolExecutionNotifications,omitempty"`
+}
+
+type sarifNotification struct {
+	Level   string       `json:"level"`
+	Message sarifMessage `json:"message"`
+}
+
+// writeSARIFResults writes the results of a run as a SARIF 2.1.0 document with one result per
+// available patch. Applied patches are reported as passing, so code scanning closes their alerts.
+func writeSARIFResults(w io.Writer, results []*common.RunResult, runErr error) error {
+	run := sarifRun{
+		Tool: sarifTool{Driver: sarifDriver{
+			Name:           "rootio_patcher",
+			Version:        version,
+			InformationURI: "https://root.io",
+		}},
+		Results:     []sarifResult{},
+		Invocations: []sarifInvocation{{ExecutionSuccessful: runErr == nil}},
+	}
+	if runErr != nil {
+		run.Invocations[0].ToolExecutionNotifications = []sarifNotification{
+			{Level: "error", Message: sarifMessage{Text: runErr.Error()}},
+		}
+	}
+
+	rules := make(map[string]sarifRule)
+	for _, result := range results {
+		if result == nil {
+			continue
+		}
+		lines := readLines(result.File)
+		for _, patch := range result.Patches {
+			run.Results = append(run.Results, newSARIFResult(result, patch, lines))
+			addSARIFRules(rules, patch)
+		}
+	}
+
+	run.Tool.Driver.Rules = make([]sarifRule, 0, len(rules))
+	for _, rule := range rules {
+		run.Tool.Driver.Rules = append(run.Tool.Driver.Rules, rule)
+	}
+	sort.Slice(run.Tool.Driver.Rules, func(i, j int) bool {
+		return run.Tool.Driver.Rules[i].ID < run.Tool.Driver.Rules[j].ID
+	})
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(sarifLog{Schema: sarifSchema, Version: sarifVersion, Runs: []sarifRun{run}})
+}
+
+// newSARIFResult describes one available patch, located at the package in its dependency file
+func newSARIFResult(result *common.RunResult, patch common.PatchResult, lines []string) sarifResult {
+	target := patch.PatchedVersion
+	if patch.PatchedName != "" && patch.PatchedName != patch.PackageName {
+		target = patch.PatchedName + "@" + patch.PatchedVersion
+	}
+
+	cves := "no CVE IDs"
+	if len(patch.CVEIDs) > 0 {
+		cves = strings.Join(patch.CVEIDs, ", ")
+	}
+
+	sr := sarifResult{
+		RuleID: sarifRuleID(patch),
+		Kind:   "fail",
+		Level:  sarifLevel(patch.Severity),
+		Message: sarifMessage{Text: fmt.Sprintf("%s %s is vulnerable (%s). Root.io patch: %s",
+			patch.PackageName, patch.CurrentVersion, cves, target)},
+		Properties: map[string]any{
+			"ecosystem":      result.Ecosystem,
+			"package":        patch.PackageName,
+			"currentVersion": patch.CurrentVersion,
+			"fixedVersion":   target,
+			"cves":           patch.CVEIDs,
+			"status":         patch.Status,
+		},
+	}
+	if patch.Status == common.PatchStatusApplied {
+		sr.Kind, sr.Level = "pass", "none"
+	}
+
+	if result.File == "" {
+		return sr
+	}
+	artifact := sarifArtifact(result.File)
+	line, versionRegion := locatePackage(lines, result.Ecosystem, patch.PackageName, patch.CurrentVersion)
+	location := sarifLocation{PhysicalLocation: sarifPhysicalLocation{ArtifactLocation: artifact}}
+	if line > 0 {
+		location.PhysicalLocation.Region = &sarifRegion{StartLine: line}
+	}
+	sr.Locations = []sarifLocation{location}
+
+	// Suggest replacing the version text only where it sits next to the package and is a plain bump
+	if versionRegion != nil && sr.Kind == "fail" && patch.PatchedName == patch.PackageName {
+		sr.Fixes = []sarifFix{{
+			Description: sarifMessage{Text: fmt.Sprintf("Upgrade %s to %s", patch.PackageName, patch.PatchedVersion)},
+			ArtifactChanges: []sarifArtifactChange{{
+				ArtifactLocation: artifact,
+				Replacements: []sarifReplacement{{
+					DeletedRegion:   *versionRegion,
+					InsertedContent: sarifArtifactContent{Text: patch.PatchedVersion},
+				}},
+			}},
+		}}
+	}
+	return sr
+}
+
+// addSARIFRules adds a rule for each CVE a patch fixes
+func addSARIFRules(rules map[string]sarifRule, patch common.PatchResult) {
+	if len(patch.CVEIDs) == 0 {
+		rules[sarifDefaultRule] = sarifRule{
+			ID:               sarifDefaultRule,
+			ShortDescription: sarifMessage{Text: "Vulnerable dependency with a Root.io patch"},
+			Properties:       map[string]any{"tags": []string{"security", "vulnerability"}},
+		}
+		return
+	}
+
+	details := make(map[string]string)
+	scores := make(map[string]float64)
+	for _, cve := range patch.CVEs {
+		details[cve.ID] = cve.Title
+		scores[cve.ID] = cve.CVSSScore
+	}
+
+	for _, id := range patch.CVEIDs {
+		if _, ok := rules[id]; ok {
+			continue
+		}
+		rule := sarifRule{
+			ID:               id,
+			ShortDescription: sarifMessage{Text: id},
+			Properties:       map[string]any{"tags": []string{"security", "vulnerability"}},
+		}
+		if details[id] != "" {
+			rule.ShortDescription.Text = id + ": " + details[id]
+		}
+		if strings.HasPrefix(id, "CVE-") {
+			rule.HelpURI = "https://nvd.nist.gov/vuln/detail/" + id
+		}
+		// GitHub code scanning ranks alerts by this score
+		if scores[id] > 0 {
+			rule.Properties["security-severity"] = fmt.Sprintf("%.1f", scores[id])
+		}
+		rules[id] = rule
+	}
+}
+
+// sarifRuleID returns the rule of a patch: its first CVE in sorted order
+func sarifRuleID(patch common.PatchResult) string {
+	if len(patch.CVEIDs) == 0 {
+		return sarifDefaultRule
+	}
+	ids := append([]string(nil), patch.CVEIDs...)
+	sort.Strings(ids)
+	return ids[0]
+}
+
+// sarifLevel maps a severity to a SARIF result level
+func sarifLevel(severity string) string {
+	switch strings.ToLower(severity) {
+	case "critical", "high":
+		return "error"
+	case "medium":
+		return "warning"
+	default:
+		return "note"
+	}
+}
+
+// sarifArtifact returns the location of a dependency file, relative to the source root when possible
+func sarifArtifact(file string) sarifArtifactLocation {
+	if filepath.IsAbs(file) {
+		return sarifArtifactLocation{URI: "file://" + filepath.ToSlash(file)}
+	}
+	return sarifArtifactLocation{URI: filepath.ToSlash(filepath.Clean(file)), URIBaseID: "%SRCROOT%"}
+}
+
+// readLines returns the lines of a dependency file, or nil if it can't be read
+func readLines(file string) []string {
+	if file == "" {
+		return nil
+	}
+	content, err := os.ReadFile(file)
+	if err != nil {
+		return nil
+	}
+	return strings.Split(string(content), "\n")
+}
+
+// locatePackage returns the 1-based line declaring a package, or 0 when it isn't found, and the
+// region of its current version when the version follows within a few lines
+func locatePackage(lines []string, ecosystem common.Ecosystem, name, currentVersion string) (int, *sarifRegion) {
+	matches := func(line string) bool { return containsName(line, name) }
+	switch ecosystem {
+	case common.EcosystemMaven:
+		// pom.xml lists the artifactId on its own
+		if _, artifactID, ok := strings.Cut(name, ":"); ok {
+			matches = func(line string) bool { return containsName(line, artifactID) }
+		}
+	case common.EcosystemNpm:
+		// Prefer the package's own entry in package-lock.json over packages that require it
+		entry := `node_modules/` + name + `"`
+		if slices.ContainsFunc(lines, func(line string) bool { return strings.Contains(line, entry) }) {
+			matches = func(line string) bool { return strings.Contains(line, entry) }
+		}
+	}
+
+	for i, line := range lines {
+		if !matches(line) {
+			continue
+		}
+		for j := i; j < len(lines) && j <= i+sarifVersionSearchLines && currentVersion != ""; j++ {
+			if col := strings.Index(lines[j], currentVersion); col >= 0 {
+				return i + 1, &sarifRegion{StartLine: j + 1, StartColumn: col + 1, EndColumn: col + len(currentVersion) + 1}
+			}
+		}
+		return i + 1, nil
+	}
+	return 0, nil
+}
+
+// containsName reports whether line mentions a package name as a whole word, ignoring case
+func containsName(line, name string) bool {
+	line, name = strings.ToLower(line), strings.ToLower(name)
+	for start := 0; ; {
+		idx := strings.Index(line[start:], name)
+		if idx < 0 {
+			return false
+		}
+		idx += start
+		end := idx + len(name)
+		if (idx == 0 || !isNameChar(line[idx-1])) && (end == len(line) || !isNameChar(line[end])) {
+			return true
+		}
+		start = idx + 1
+	}
+}
+
+// isNameChar reports whether b can be part of a package name
+func isNameChar(b byte) bool {
+	return b >= 'a' && b <= 'z' || b >= '0' && b <= '9' || b == '-' || b == '_' || b == '.'
+}
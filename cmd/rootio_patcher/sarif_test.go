@@ -0,0 +1,237 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"rootio_patcher/cmd/rootio_patcher/common"
+	"rootio_patcher/pkg/rootio"
+)
+
+// validateSchema checks value against the JSON schema keywords used by the SARIF schema in
+// testdata: $ref, type, enum, required, properties, additionalProperties, items, minItems,
+// minimum and anyOf. It returns one message per violation.
+func validateSchema(root, schema map[string]any, value any, path string) []string {
+	if ref, ok := schema["$ref"].(string); ok {
+		name := strings.TrimPrefix(ref, "#/definitions/")
+		return validateSchema(root, root["definitions"].(map[string]any)[name].(map[string]any), value, path)
+	}
+
+	var errs []string
+	if types, ok := schema["type"]; ok {
+		allowed := []any{types}
+		if list, ok := types.([]any); ok {
+			allowed = list
+		}
+		if !slices.ContainsFunc(allowed, func(t any) bool { return schemaType(value, t.(string)) }) {
+			return append(errs, fmt.Sprintf("%s: expected type %v, got %T", path, types, value))
+		}
+	}
+	if enum, ok := schema["enum"].([]any); ok && !slices.Contains(enum, value) {
+		errs = append(errs, fmt.Sprintf("%s: %v is not one of %v", path, value, enum))
+	}
+	if minimum, ok := schema["minimum"].(float64); ok {
+		if n, ok := value.(float64); ok && n < minimum {
+			errs = append(errs, fmt.Sprintf("%s: %v is below the minimum %v", path, n, minimum))
+		}
+	}
+	if anyOf, ok := schema["anyOf"].([]any); ok {
+		if !slices.ContainsFunc(anyOf, func(s any) bool {
+			return len(validateSchema(root, s.(map[string]any), value, path)) == 0
+		}) {
+			errs = append(errs, fmt.Sprintf("%s: matches none of anyOf", path))
+		}
+	}
+
+	switch v := value.(type) {
+	case map[string]any:
+		properties, _ := schema["properties"].(map[string]any)
+		for _, name := range schemaStrings(schema["required"]) {
+			if _, ok := v[name]; !ok {
+				errs = append(errs, fmt.Sprintf("%s: missing required property %q", path, name))
+			}
+		}
+		for name, child := range v {
+			if propSchema, ok := properties[name].(map[string]any); ok {
+				errs = append(errs, validateSchema(root, propSchema, child, path+"."+name)...)
+			} else if schema["additionalProperties"] == false {
+				errs = append(errs, fmt.Sprintf("%s: unexpected property %q", path, name))
+			}
+		}
+	case []any:
+		if minItems, ok := schema["minItems"].(float64); ok && float64(len(v)) < minItems {
+			errs = append(errs, fmt.Sprintf("%s: expected at least %v items", path, minItems))
+		}
+		if items, ok := schema["items"].(map[string]any); ok {
+			for i, item := range v {
+				errs = append(errs, validateSchema(root, items, item, fmt.Sprintf("%s[%d]", path, i))...)
+			}
+		}
+	}
+	return errs
+}
+
+// schemaType reports whether a decoded JSON value has the given JSON schema type
+func schemaType(value any, t string) bool {
+	switch t {
+	case "object":
+		_, ok := value.(map[string]any)
+		return ok
+	case "array":
+		_, ok := value.([]any)
+		return ok
+	case "string":
+		_, ok := value.(string)
+		return ok
+	case "boolean":
+		_, ok := value.(bool)
+		return ok
+	case "integer":
+		n, ok := value.(float64)
+		return ok && n == float64(int64(n))
+	case "null":
+		return value == nil
+	}
+	return false
+}
+
+// schemaStrings converts a decoded JSON array of strings
+func schemaStrings(value any) []string {
+	var out []string
+	list, _ := value.([]any)
+	for _, item := range list {
+		out = append(out, item.(string))
+	}
+	return out
+}
+
+func TestWriteSARIFResults(t *testing.T) {
+	schemaContent, err := os.ReadFile(filepath.Join("testdata", "sarif-schema-2.1.0.json"))
+	if err != nil {
+		t.Fatalf("Failed to read SARIF schema: %v", err)
+	}
+	var schema map[string]any
+	if err := json.Unmarshal(schemaContent, &schema); err != nil {
+		t.Fatalf("Failed to decode SARIF schema: %v", err)
+	}
+
+	t.Chdir(t.TempDir())
+	if err := os.WriteFile("requirements.txt", []byte("django==4.0.0\nflask==2.0.0\n"), 0644); err != nil {
+		t.Fatalf("Failed to write requirements.txt: %v", err)
+	}
+	pom := "<project>\n  <dependencies>\n    <dependency>\n      <groupId>org.apache.logging.log4j</groupId>\n" +
+		"      <artifactId>log4j-core</artifactId>\n      <version>2.14.1</version>\n    </dependency>\n  </dependencies>\n</project>\n"
+	if err := os.WriteFile("pom.xml", []byte(pom), 0644); err != nil {
+		t.Fatalf("Failed to write pom.xml: %v", err)
+	}
+
+	pipResult := common.NewRunResult(common.EcosystemPyPI, "requirements.txt", false)
+	pipResult.AddPatches([]rootio.PackagePatch{
+		{
+			PackageName: "django", Version: "4.0.0", Severity: "high",
+			Patch:  rootio.PatchInfo{Name: "django", Version: "4.0.1"},
+			CVEIDs: []string{"CVE-2023-1234", "CVE-2022-0001"},
+			CVEs:   []rootio.CVE{{ID: "CVE-2023-1234", CVSSScore: 7.5, Title: "SQL injection"}, {ID: "CVE-2022-0001"}},
+		},
+		{
+			PackageName: "flask", Version: "2.0.0",
+			Patch: rootio.PatchInfo{Name: "flask", Version: "2.0.1"},
+		},
+	}, false, common.PatchStatusPending)
+	pipResult.SetPatchStatus(0, common.PatchStatusFailed, errors.New("write failed"))
+	pipResult.SetPatchStatus(1, common.PatchStatusApplied, nil)
+
+	mavenResult := common.NewRunResult(common.EcosystemMaven, "pom.xml", true)
+	mavenResult.AddPatches([]rootio.PackagePatch{{
+		PackageName: "org.apache.logging.log4j:log4j-core", Version: "2.14.1", Severity: "critical",
+		Patch:  rootio.PatchInfo{Name: "org.apache.logging.log4j:log4j-core", Version: "2.17.1"},
+		CVEIDs: []string{"CVE-2021-44228"},
+	}}, false, common.PatchStatusDryRun)
+
+	var buf bytes.Buffer
+	if err := writeSARIFResults(&buf, []*common.RunResult{pipResult, mavenResult}, errors.New("patch failed")); err != nil {
+		t.Fatalf("writeSARIFResults failed: %v", err)
+	}
+
+	var document map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &document); err != nil {
+		t.Fatalf("Output is not valid JSON: %v\n%s", err, buf.String())
+	}
+	for _, violation := range validateSchema(schema, schema, document, "$") {
+		t.Errorf("SARIF schema violation: %s", violation)
+	}
+
+	// The validator must reject what the schema forbids, or the check above proves nothing
+	invalid := map[string]any{"version": "2.1.0", "runs": []any{map[string]any{
+		"tool":    map[string]any{"driver": map[string]any{"name": "rootio_patcher"}},
+		"results": []any{map[string]any{"message": map[string]any{"text": "x"}, "level": "fatal"}},
+	}}}
+	if violations := validateSchema(schema, schema, invalid, "$"); len(violations) == 0 {
+		t.Error("Expected an invalid result level to violate the SARIF schema")
+	}
+
+	var log sarifLog
+	if err := json.Unmarshal(buf.Bytes(), &log); err != nil {
+		t.Fatalf("Failed to decode SARIF output: %v", err)
+	}
+	run := log.Runs[0]
+
+	var ruleIDs []string
+	for _, rule := range run.Tool.Driver.Rules {
+		ruleIDs = append(ruleIDs, rule.ID)
+	}
+	if expected := []string{"CVE-2021-44228", "CVE-2022-0001", "CVE-2023-1234", sarifDefaultRule}; !slices.Equal(ruleIDs, expected) {
+		t.Errorf("Expected rules %v, got %v", expected, ruleIDs)
+	}
+	if rule := run.Tool.Driver.Rules[2]; rule.Properties["security-severity"] != "7.5" ||
+		rule.ShortDescription.Text != "CVE-2023-1234: SQL injection" {
+		t.Errorf("Unexpected rule for CVE-2023-1234: %+v", rule)
+	}
+
+	if len(run.Results) != 3 {
+		t.Fatalf("Expected 3 results, got %d", len(run.Results))
+	}
+
+	django := run.Results[0]
+	if django.RuleID != "CVE-2022-0001" || django.Kind != "fail" || django.Level != "error" {
+		t.Errorf("Unexpected django result: %+v", django)
+	}
+	location := django.Locations[0].PhysicalLocation
+	if location.ArtifactLocation.URI != "requirements.txt" || location.Region == nil || location.Region.StartLine != 1 {
+		t.Errorf("Expected django located on line 1 of requirements.txt, got %+v", location)
+	}
+	if len(django.Fixes) != 1 {
+		t.Fatalf("Expected a fix for django, got %+v", django.Fixes)
+	}
+	replacement := django.Fixes[0].ArtifactChanges[0].Replacements[0]
+	if replacement.DeletedRegion != (sarifRegion{StartLine: 1, StartColumn: 9, EndColumn: 14}) ||
+		replacement.InsertedContent.Text != "4.0.1" {
+		t.Errorf("Unexpected django fix: %+v", replacement)
+	}
+
+	// Applied patches pass, so code scanning closes their alerts
+	flask := run.Results[1]
+	if flask.RuleID != sarifDefaultRule || flask.Kind != "pass" || flask.Level != "none" || len(flask.Fixes) != 0 {
+		t.Errorf("Unexpected flask result: %+v", flask)
+	}
+
+	log4j := run.Results[2]
+	if region := log4j.Locations[0].PhysicalLocation.Region; region == nil || region.StartLine != 5 {
+		t.Errorf("Expected log4j-core located at its artifactId on line 5, got %+v", region)
+	}
+	if region := log4j.Fixes[0].ArtifactChanges[0].Replacements[0].DeletedRegion; region.StartLine != 6 {
+		t.Errorf("Expected the log4j-core fix to replace its version on line 6, got %+v", region)
+	}
+
+	if invocation := run.Invocations[0]; invocation.ExecutionSuccessful ||
+		invocation.ToolExecutionNotifications[0].Message.Text != "patch failed" {
+		t.Errorf("Expected the failed run to be reported, got %+v", invocation)
+	}
+}
@@ -0,0 +1,182 @@
+package sbom
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+
+	"rootio_patcher/cmd/rootio_patcher/common"
+	"rootio_patcher/pkg/rootio"
+)
+
+// ecosystems is the order SBOM packages are analyzed and reported in
+var ecosystems = []common.Ecosystem{
+	common.EcosystemNpm,
+	common.EcosystemPyPI,
+	common.EcosystemMaven,
+	common.EcosystemGo,
+	common.EcosystemRubyGems,
+	common.EcosystemNuGet,
+	common.EcosystemDebian,
+}
+
+// remediateCommands are the rootio_patcher commands that patch each ecosystem's packages
+var remediateCommands = map[common.Ecosystem]string{
+	common.EcosystemNpm:      "npm remediate",
+	common.EcosystemPyPI:     "pip remediate",
+	common.EcosystemMaven:    "maven remediate",
+	common.EcosystemGo:       "go remediate",
+	common.EcosystemRubyGems: "gem remediate",
+	common.EcosystemNuGet:    "nuget remediate",
+	common.EcosystemDebian:   "apt remediate",
+}
+
+// ClientFactory creates the API client that analyzes an ecosystem's packages
+type ClientFactory func(ecosystem common.Ecosystem) common.APIClient
+
+// App analyzes the packages listed in an SBOM. An SBOM describes a build rather than files
+// that can be patched, so it only reports the available patches.
+type App struct {
+	path      string
+	logger    *slog.Logger
+	newClient ClientFactory
+	options   common.Options
+	out       io.Writer
+
+	results []*common.RunResult
+}
+
+// NewApp creates a new SBOM analysis app that sends each ecosystem's packages to its remediate endpoint
+func NewApp(apiKey, apiURL, path string, logger *slog.Logger, opts ...common.Option) *App {
+	newClient := func(ecosystem common.Ecosystem) common.APIClient {
+		return common.NewAPIClient(ecosystem, apiURL, apiKey, logger, opts...)
+	}
+	return NewAppWithServices(path, logger, newClient, opts...)
+}
+
+// NewAppWithServices creates a new SBOM analysis app with injected services (for testing)
+func NewAppWithServices(path string, logger *slog.Logger, newClient ClientFactory, opts ...common.Option) *App {
+	options := common.NewOptions(opts...)
+	return &App{
+		path:      path,
+		logger:    logger,
+		newClient: newClient,
+		options:   options,
+		out:       options.Output(),
+	}
+}
+
+// Results returns the structured result of each analyzed ecosystem from the last run
+func (a *App) Results() []*common.RunResult {
+	return a.results
+}
+
+// Run analyzes the SBOM's packages, one request per ecosystem, and reports the available patches.
+// An ecosystem that fails to analyze doesn't stop the others; its error is recorded on its result.
+func (a *App) Run(ctx context.Context) error {
+	a.logger.DebugContext(ctx, "Starting SBOM analysis", slog.String("file", a.path))
+	a.results = nil
+
+	packages, ignored, err := Parse(a.path)
+	if err != nil {
+		return fmt.Errorf("failed to parse %s: %w", a.path, err)
+	}
+	a.logger.DebugContext(ctx, "Parsed SBOM",
+		slog.Int("packages", len(packages)),
+		slog.Int("ignored", len(ignored)))
+	a.options.Progress.Printf("Found %d packages in %s", len(packages), a.path)
+	if len(ignored) > 0 {
+		fmt.Fprintf(a.out, "\nIgnored %d components whose purl is malformed, has no version or isn't in a supported ecosystem\n", len(ignored))
+	}
+
+	byEcosystem := make(map[common.Ecosystem][]common.PackageInfo)
+	for _, pkg := range packages {
+		byEcosystem[pkg.Ecosystem] = append(byEcosystem[pkg.Ecosystem], pkg)
+	}
+	if len(byEcosystem) == 0 {
+		fmt.Fprintf(a.out, "\nNo packages found in %s\n", a.path)
+		return nil
+	}
+
+	var failed []common.Ecosystem
+	for _, ecosystem := range ecosystems {
+		packages, ok := byEcosystem[ecosystem]
+		if !ok {
+			continue
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		result := common.NewRunResult(ecosystem, a.path, true)
+		a.results = append(a.results, result)
+		if err := a.analyze(ctx, ecosystem, packages, result); err != nil {
+			a.logger.ErrorContext(ctx, "Failed to analyze SBOM packages",
+				slog.String("ecosystem", string(ecosystem)),
+				slog.String("error", err.Error()))
+			result.SetError(err)
+			failed = append(failed, ecosystem)
+		}
+	}
+
+	if len(failed) > 0 {
+		return fmt.Errorf("%d of %d ecosystems failed: %v", len(failed), len(a.results), failed)
+	}
+	return nil
+}
+
+// analyze sends one ecosystem's packages to its remediate endpoint and reports the patches
+func (a *App) analyze(ctx context.Context, ecosystem common.Ecosystem, packages []common.PackageInfo, result *common.RunResult) error {
+	fmt.Fprintf(a.out, "\n=== %s (%d packages) ===\n", ecosystem, len(packages))
+	result.PackagesFound = len(packages)
+
+	// Leave components with the excluded scope out
+	if a.options.SkipDev {
+		packages, result.DevSkipped = common.FilterDev(packages)
+		if result.DevSkipped > 0 {
+			fmt.Fprintf(a.out, "\nSkipped %d dev dependencies (--skip-dev)\n", result.DevSkipped)
+		}
+		if len(packages) == 0 {
+			return nil
+		}
+	}
+
+	sdkPackages := make([]rootio.Package, len(packages))
+	for i, pkg := range packages {
+		sdkPackages[i] = pkg.SDKPackage()
+	}
+
+	a.options.Progress.Printf("Analyzing %d %s packages for vulnerabilities...", len(sdkPackages), ecosystem)
+	response, err := a.newClient(ecosystem).AnalyzePackages(ctx, sdkPackages)
+	if err != nil {
+		return fmt.Errorf("failed to analyze packages: %w", err)
+	}
+	a.logger.DebugContext(ctx, "Vulnerability analysis complete",
+		slog.String("ecosystem", string(ecosystem)),
+		slog.Int("patches_available", len(response.Patches)),
+		slog.Int("packages_skipped", len(response.Skipped)))
+
+	// Root.io aliases are the default for npm and pip, as in their remediate commands
+	useAlias := a.options.Aliased(ecosystem == common.EcosystemNpm || ecosystem == common.EcosystemPyPI)
+
+	// Drop invalid names, patches without a version to install, patches left out by
+	// --min-severity, --only, --exclude and --cve, and patches that aren't upgrades
+	patches, filterSkipped := common.FilterPatches(ecosystem, response.Patches, useAlias, a.options)
+	response.Patches = patches
+	response.Skipped = append(response.Skipped, filterSkipped...)
+	common.WriteCVEMatches(a.out, response.Patches, a.options.CVEs)
+	result.AddSkipped(response.Skipped)
+	common.WriteSkipped(a.out, response.Skipped)
+
+	if len(response.Patches) == 0 {
+		fmt.Fprintln(a.out, "\nNo patches needed - all packages are up to date!")
+		return nil
+	}
+
+	result.AddPatches(response.Patches, useAlias, common.PatchStatusDryRun)
+	common.WritePatchTable(a.out, response.Patches, useAlias, common.TerminalWidth())
+	fmt.Fprintf(a.out, "\nTo apply these patches, run: rootio_patcher %s --dry-run=false\n", remediateCommands[ecosystem])
+	fmt.Fprintln(a.out, "in the project the SBOM was generated from")
+	return nil
+}
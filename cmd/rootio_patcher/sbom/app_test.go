@@ -0,0 +1,131 @@
+package sbom
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"rootio_patcher/cmd/rootio_patcher/common"
+	"rootio_patcher/pkg/rootio"
+)
+
+// recordingClients returns a client factory recording the packages analyzed per ecosystem.
+// Each ecosystem's client responds with its entry in responses.
+func recordingClients(
+	analyzed map[common.Ecosystem][]rootio.Package, responses map[common.Ecosystem]*rootio.AnalyzePackagesResponse,
+) ClientFactory {
+	return func(ecosystem common.Ecosystem) common.APIClient {
+		return &MockAPIClient{
+			AnalyzePackagesFunc: func(ctx context.Context, packages []rootio.Package) (*rootio.AnalyzePackagesResponse, error) {
+				analyzed[ecosystem] = packages
+				if response, ok := responses[ecosystem]; ok {
+					return response, nil
+				}
+				return nil, errors.New("API error")
+			},
+		}
+	}
+}
+
+func TestSBOMApp_Run_AnalyzesEachEcosystem(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	path := filepath.Join("testdata", "cyclonedx.json")
+
+	analyzed := make(map[common.Ecosystem][]rootio.Package)
+	responses := map[common.Ecosystem]*rootio.AnalyzePackagesResponse{
+		common.EcosystemNpm: {Patches: []rootio.PackagePatch{
+			{PackageName: "express", Version: "4.17.1", Patch: rootio.PatchInfo{Name: "express", Version: "4.17.3"}},
+		}},
+		common.EcosystemMaven: {Patches: []rootio.PackagePatch{
+			{
+				PackageName: "com.fasterxml.jackson.core:jackson-databind", Version: "2.13.0",
+				Patch: rootio.PatchInfo{Name: "com.fasterxml.jackson.core:jackson-databind", Version: "2.13.4.2"},
+			},
+		}},
+	}
+
+	app := NewAppWithServices(path, logger, recordingClients(analyzed, responses))
+	var out bytes.Buffer
+	app.out = &out
+	if err := app.Run(context.Background()); err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+
+	names := func(packages []rootio.Package) []string {
+		var names []string
+		for _, pkg := range packages {
+			names = append(names, pkg.Name+"@"+pkg.Version)
+		}
+		return names
+	}
+	if got := names(analyzed[common.EcosystemNpm]); !reflect.DeepEqual(got, []string{"express@4.17.1", "@babel/traverse@7.20.0"}) {
+		t.Errorf("Expected the npm components to be analyzed on the npm endpoint, got %v", got)
+	}
+	if got := names(analyzed[common.EcosystemMaven]); !reflect.DeepEqual(got, []string{
+		"com.fasterxml.jackson.core:jackson-databind@2.13.0", "com.fasterxml.jackson.core:jackson-core@2.13.0",
+	}) {
+		t.Errorf("Expected the maven components to be analyzed on the maven endpoint, got %v", got)
+	}
+	if len(analyzed) != 2 {
+		t.Errorf("Expected only npm and maven to be analyzed, got %d ecosystems", len(analyzed))
+	}
+
+	results := app.Results()
+	if len(results) != 2 || results[0].Ecosystem != common.EcosystemNpm || results[1].Ecosystem != common.EcosystemMaven {
+		t.Fatalf("Expected npm and maven results, got %+v", results)
+	}
+	for _, result := range results {
+		if result.File != path || !result.DryRun || result.PackagesFound != 2 {
+			t.Errorf("Expected a dry-run result for 2 packages in %s, got %+v", path, result)
+		}
+		if len(result.Patches) != 1 || result.Patches[0].Status != common.PatchStatusDryRun {
+			t.Errorf("Expected one dry-run patch for %s, got %+v", result.Ecosystem, result.Patches)
+		}
+	}
+
+	output := out.String()
+	for _, want := range []string{"Ignored 1 components", "rootio_patcher npm remediate", "rootio_patcher maven remediate"} {
+		if !strings.Contains(output, want) {
+			t.Errorf("Expected output to contain %q, got:\n%s", want, output)
+		}
+	}
+}
+
+func TestSBOMApp_Run_EcosystemError(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	// npm has no response and fails; maven is still analyzed
+	analyzed := make(map[common.Ecosystem][]rootio.Package)
+	responses := map[common.Ecosystem]*rootio.AnalyzePackagesResponse{
+		common.EcosystemMaven: {},
+	}
+
+	app := NewAppWithServices(filepath.Join("testdata", "cyclonedx.json"), logger, recordingClients(analyzed, responses),
+		common.WithSkipDev(true))
+	app.out = io.Discard
+	if err := app.Run(context.Background()); err == nil {
+		t.Fatal("Expected an error when an ecosystem fails to analyze")
+	}
+
+	if len(analyzed[common.EcosystemMaven]) != 2 {
+		t.Errorf("Expected maven to be analyzed after npm failed, got %v", analyzed[common.EcosystemMaven])
+	}
+	// --skip-dev leaves the excluded @babel/traverse out
+	if len(analyzed[common.EcosystemNpm]) != 1 {
+		t.Errorf("Expected the excluded npm component to be skipped, got %v", analyzed[common.EcosystemNpm])
+	}
+
+	results := app.Results()
+	if len(results) != 2 || results[0].Error == "" || results[1].Error != "" {
+		t.Errorf("Expected the error on the npm result only, got %+v", results)
+	}
+	if results[0].DevSkipped != 1 {
+		t.Errorf("Expected 1 dev component skipped, got %d", results[0].DevSkipped)
+	}
+}
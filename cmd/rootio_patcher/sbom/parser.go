@@ -0,0 +1,207 @@
+package sbom
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"slices"
+
+	"rootio_patcher/cmd/rootio_patcher/common"
+)
+
+// cycloneDXDocument is the subset of a CycloneDX JSON BOM that is read
+type cycloneDXDocument struct {
+	BOMFormat string `json:"bomFormat"`
+	Metadata  struct {
+		Component *cycloneDXComponent `json:"component"`
+	} `json:"metadata"`
+	Components   []cycloneDXComponent  `json:"components"`
+	Dependencies []cycloneDXDependency `json:"dependencies"`
+}
+
+// cycloneDXComponent is a component of a CycloneDX BOM, with any components nested in it
+type cycloneDXComponent struct {
+	BOMRef     string               `json:"bom-ref"`
+	PURL       string               `json:"purl"`
+	Scope      string               `json:"scope"`
+	Components []cycloneDXComponent `json:"components"`
+}
+
+// cycloneDXDependency is an entry of a CycloneDX dependency graph
+type cycloneDXDependency struct {
+	Ref       string   `json:"ref"`
+	DependsOn []string `json:"dependsOn"`
+}
+
+// spdxDocument is the subset of an SPDX JSON document that is read
+type spdxDocument struct {
+	SPDXVersion   string             `json:"spdxVersion"`
+	Packages      []spdxPackage      `json:"packages"`
+	Relationships []spdxRelationship `json:"relationships"`
+}
+
+// spdxPackage is a package of an SPDX document
+type spdxPackage struct {
+	SPDXID       string `json:"SPDXID"`
+	ExternalRefs []struct {
+		ReferenceType    string `json:"referenceType"`
+		ReferenceLocator string `json:"referenceLocator"`
+	} `json:"externalRefs"`
+}
+
+// spdxRelationship relates two elements of an SPDX document
+type spdxRelationship struct {
+	Element          string `json:"spdxElementId"`
+	RelationshipType string `json:"relationshipType"`
+	Related          string `json:"relatedSpdxElement"`
+}
+
+// Parse reads a CycloneDX or SPDX JSON SBOM and returns its components that have a purl in a
+// supported ecosystem, along with the purls that were ignored
+func Parse(path string) ([]common.PackageInfo, []string, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read SBOM: %w", err)
+	}
+
+	var format struct {
+		BOMFormat   string `json:"bomFormat"`
+		SPDXVersion string `json:"spdxVersion"`
+	}
+	if err := json.Unmarshal(content, &format); err != nil {
+		return nil, nil, fmt.Errorf("failed to parse SBOM: %w", err)
+	}
+
+	switch {
+	case format.BOMFormat == "CycloneDX":
+		var doc cycloneDXDocument
+		if err := json.Unmarshal(content, &doc); err != nil {
+			return nil, nil, fmt.Errorf("failed to parse CycloneDX SBOM: %w", err)
+		}
+		packages, ignored := parseCycloneDX(doc)
+		return packages, ignored, nil
+	case format.SPDXVersion != "":
+		var doc spdxDocument
+		if err := json.Unmarshal(content, &doc); err != nil {
+			return nil, nil, fmt.Errorf("failed to parse SPDX SBOM: %w", err)
+		}
+		packages, ignored := parseSPDX(doc)
+		return packages, ignored, nil
+	default:
+		return nil, nil, fmt.Errorf("unsupported SBOM format: expected CycloneDX or SPDX JSON")
+	}
+}
+
+// parseCycloneDX returns the packages of a CycloneDX BOM. Components the described project
+// depends on are direct; components with the excluded scope are treated as dev dependencies.
+func parseCycloneDX(doc cycloneDXDocument) ([]common.PackageInfo, []string) {
+	var direct []string
+	if root := doc.Metadata.Component; root != nil && root.BOMRef != "" {
+		for _, dep := range doc.Dependencies {
+			if dep.Ref == root.BOMRef {
+				direct = dep.DependsOn
+			}
+		}
+	}
+
+	var components []cycloneDXComponent
+	var walk func([]cycloneDXComponent)
+	walk = func(list []cycloneDXComponent) {
+		for _, c := range list {
+			components = append(components, c)
+			walk(c.Components)
+		}
+	}
+	walk(doc.Components)
+
+	b := newBuilder()
+	for _, c := range components {
+		b.add(c.PURL, slices.Contains(direct, c.BOMRef), c.Scope == "excluded")
+	}
+	return b.packages, b.ignored
+}
+
+// parseSPDX returns the packages of an SPDX document that have a purl external reference.
+// Packages the document's described packages depend on are direct.
+func parseSPDX(doc spdxDocument) ([]common.PackageInfo, []string) {
+	var described, direct []string
+	for _, rel := range doc.Relationships {
+		if rel.Element == "SPDXRef-DOCUMENT" && rel.RelationshipType == "DESCRIBES" {
+			described = append(described, rel.Related)
+		}
+	}
+	for _, rel := range doc.Relationships {
+		switch rel.RelationshipType {
+		case "DEPENDS_ON":
+			if slices.Contains(described, rel.Element) {
+				direct = append(direct, rel.Related)
+			}
+		case "DEPENDENCY_OF":
+			if slices.Contains(described, rel.Related) {
+				direct = append(direct, rel.Element)
+			}
+		}
+	}
+
+	b := newBuilder()
+	for _, pkg := range doc.Packages {
+		// The described packages are the project itself, not its dependencies
+		if slices.Contains(described, pkg.SPDXID) {
+			continue
+		}
+		for _, ref := range pkg.ExternalRefs {
+			if ref.ReferenceType != "purl" {
+				continue
+			}
+			b.add(ref.ReferenceLocator, slices.Contains(direct, pkg.SPDXID), false)
+		}
+	}
+	return b.packages, b.ignored
+}
+
+// builder collects the packages of an SBOM, once per ecosystem, name and version
+type builder struct {
+	packages []common.PackageInfo
+	ignored  []string
+	index    map[packageKey]int
+}
+
+// packageKey identifies a package across the listings of an SBOM
+type packageKey struct {
+	ecosystem     common.Ecosystem
+	name, version string
+}
+
+func newBuilder() *builder {
+	return &builder{index: make(map[packageKey]int)}
+}
+
+// add records the package identified by purl. Components without a purl are left out, and
+// malformed purls or purls without a supported ecosystem are recorded as ignored.
+func (b *builder) add(purl string, direct, dev bool) {
+	if purl == "" {
+		return
+	}
+	parsed, err := ParsePURL(purl)
+	if err != nil {
+		b.ignored = append(b.ignored, purl)
+		return
+	}
+	pkg, ok := parsed.Package()
+	if !ok {
+		b.ignored = append(b.ignored, purl)
+		return
+	}
+
+	// A package listed more than once is direct if any listing is, and dev only if all are
+	key := packageKey{pkg.Ecosystem, pkg.Name, pkg.Version}
+	if i, seen := b.index[key]; seen {
+		b.packages[i].Direct = b.packages[i].Direct || direct
+		b.packages[i].Dev = b.packages[i].Dev && dev
+		return
+	}
+	b.index[key] = len(b.packages)
+	pkg.Direct = direct
+	pkg.Dev = dev
+	b.packages = append(b.packages, pkg)
+}
@@ -0,0 +1,84 @@
+package sbom
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"rootio_patcher/cmd/rootio_patcher/common"
+)
+
+func TestParse_CycloneDX(t *testing.T) {
+	packages, ignored, err := Parse(filepath.Join("testdata", "cyclonedx.json"))
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+
+	expected := []common.PackageInfo{
+		{Name: "express", Version: "4.17.1", Ecosystem: common.EcosystemNpm, Direct: true},
+		{Name: "@babel/traverse", Version: "7.20.0", Ecosystem: common.EcosystemNpm, Dev: true},
+		{Name: "com.fasterxml.jackson.core:jackson-databind", Version: "2.13.0", Ecosystem: common.EcosystemMaven, Direct: true},
+		{Name: "com.fasterxml.jackson.core:jackson-core", Version: "2.13.0", Ecosystem: common.EcosystemMaven},
+	}
+	if !reflect.DeepEqual(packages, expected) {
+		t.Errorf("Expected packages %+v, got %+v", expected, packages)
+	}
+	if !reflect.DeepEqual(ignored, []string{"pkg:generic/openssl@3.0.2"}) {
+		t.Errorf("Expected the generic purl to be ignored, got %v", ignored)
+	}
+}
+
+func TestParse_SPDX(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "sbom.spdx.json")
+	content := `{
+  "spdxVersion": "SPDX-2.3",
+  "SPDXID": "SPDXRef-DOCUMENT",
+  "packages": [
+    {"SPDXID": "SPDXRef-app", "name": "app", "externalRefs": [
+      {"referenceCategory": "PACKAGE-MANAGER", "referenceType": "purl", "referenceLocator": "pkg:pypi/app@1.0.0"}
+    ]},
+    {"SPDXID": "SPDXRef-requests", "name": "requests", "externalRefs": [
+      {"referenceCategory": "SECURITY", "referenceType": "cpe23Type", "referenceLocator": "cpe:2.3:a:python:requests:2.25.0:*:*:*:*:*:*:*"},
+      {"referenceCategory": "PACKAGE-MANAGER", "referenceType": "purl", "referenceLocator": "pkg:pypi/requests@2.25.0"}
+    ]},
+    {"SPDXID": "SPDXRef-urllib3", "name": "urllib3", "externalRefs": [
+      {"referenceCategory": "PACKAGE-MANAGER", "referenceType": "purl", "referenceLocator": "pkg:pypi/urllib3@1.26.4"}
+    ]}
+  ],
+  "relationships": [
+    {"spdxElementId": "SPDXRef-DOCUMENT", "relationshipType": "DESCRIBES", "relatedSpdxElement": "SPDXRef-app"},
+    {"spdxElementId": "SPDXRef-app", "relationshipType": "DEPENDS_ON", "relatedSpdxElement": "SPDXRef-requests"},
+    {"spdxElementId": "SPDXRef-requests", "relationshipType": "DEPENDS_ON", "relatedSpdxElement": "SPDXRef-urllib3"}
+  ]
+}`
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write SBOM: %v", err)
+	}
+
+	packages, ignored, err := Parse(path)
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+
+	expected := []common.PackageInfo{
+		{Name: "requests", Version: "2.25.0", Ecosystem: common.EcosystemPyPI, Direct: true},
+		{Name: "urllib3", Version: "1.26.4", Ecosystem: common.EcosystemPyPI},
+	}
+	if !reflect.DeepEqual(packages, expected) {
+		t.Errorf("Expected packages %+v, got %+v", expected, packages)
+	}
+	if len(ignored) != 0 {
+		t.Errorf("Expected no ignored purls, got %v", ignored)
+	}
+}
+
+func TestParse_UnsupportedFormat(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "package.json")
+	if err := os.WriteFile(path, []byte(`{"name": "app"}`), 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+	if _, _, err := Parse(path); err == nil {
+		t.Error("Expected an error for a file that isn't an SBOM")
+	}
+}
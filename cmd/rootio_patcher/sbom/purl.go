@@ -0,0 +1,108 @@
+package sbom
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+
+	"rootio_patcher/cmd/rootio_patcher/common"
+)
+
+// PURL is a parsed package URL (pkg:type/namespace/name@version?qualifiers#subpath)
+type PURL struct {
+	Type      string
+	Namespace string
+	Name      string
+	Version   string
+}
+
+// ParsePURL parses a package URL, dropping its qualifiers and subpath
+func ParsePURL(s string) (PURL, error) {
+	rest, ok := strings.CutPrefix(s, "pkg:")
+	if !ok {
+		return PURL{}, fmt.Errorf("invalid purl %q: missing pkg: scheme", s)
+	}
+	rest, _, _ = strings.Cut(rest, "#")
+	rest, _, _ = strings.Cut(rest, "?")
+	rest = strings.Trim(rest, "/")
+
+	typ, path, ok := strings.Cut(rest, "/")
+	if !ok || typ == "" || path == "" {
+		return PURL{}, fmt.Errorf("invalid purl %q: missing type or name", s)
+	}
+
+	// The version follows the last @ of the name segment; scopes like @babel stay in the namespace
+	var version string
+	slash := strings.LastIndex(path, "/")
+	if at := strings.LastIndex(path, "@"); at > slash {
+		path, version = path[:at], path[at+1:]
+	}
+
+	var namespace, name string
+	if slash >= 0 {
+		namespace, name = path[:slash], path[slash+1:]
+	} else {
+		name = path
+	}
+
+	p := PURL{Type: strings.ToLower(typ)}
+	for _, field := range []struct {
+		dst *string
+		src string
+	}{{&p.Namespace, namespace}, {&p.Name, name}, {&p.Version, version}} {
+		decoded, err := url.PathUnescape(field.src)
+		if err != nil {
+			return PURL{}, fmt.Errorf("invalid purl %q: %w", s, err)
+		}
+		*field.dst = decoded
+	}
+	if p.Name == "" {
+		return PURL{}, fmt.Errorf("invalid purl %q: missing name", s)
+	}
+	return p, nil
+}
+
+// Package maps the purl to the ecosystem and package name the Root.io API uses. ok is false
+// for purl types without a remediate endpoint, and for purls without a version.
+func (p PURL) Package() (pkg common.PackageInfo, ok bool) {
+	if p.Version == "" {
+		return common.PackageInfo{}, false
+	}
+
+	var ecosystem common.Ecosystem
+	name := p.Name
+	switch p.Type {
+	case "npm":
+		ecosystem = common.EcosystemNpm
+		if p.Namespace != "" {
+			name = p.Namespace + "/" + p.Name
+		}
+	case "maven":
+		if p.Namespace == "" {
+			return common.PackageInfo{}, false
+		}
+		ecosystem = common.EcosystemMaven
+		name = p.Namespace + ":" + p.Name
+	case "golang":
+		ecosystem = common.EcosystemGo
+		if p.Namespace != "" {
+			name = p.Namespace + "/" + p.Name
+		}
+	case "pypi":
+		ecosystem = common.EcosystemPyPI
+	case "gem":
+		ecosystem = common.EcosystemRubyGems
+	case "nuget":
+		ecosystem = common.EcosystemNuGet
+	case "deb":
+		ecosystem = common.EcosystemDebian
+	default:
+		return common.PackageInfo{}, false
+	}
+
+	return common.PackageInfo{
+		Name:      name,
+		Version:   p.Version,
+		Ecosystem: ecosystem,
+	}, true
+}
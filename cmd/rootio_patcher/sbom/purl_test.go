@@ -0,0 +1,61 @@
+package sbom
+
+import (
+	"testing"
+
+	"rootio_patcher/cmd/rootio_patcher/common"
+)
+
+func TestParsePURL(t *testing.T) {
+	tests := []struct {
+		purl      string
+		ecosystem common.Ecosystem
+		name      string
+		version   string
+	}{
+		{"pkg:npm/lodash@4.17.20", common.EcosystemNpm, "lodash", "4.17.20"},
+		{"pkg:npm/%40babel/core@7.0.0", common.EcosystemNpm, "@babel/core", "7.0.0"},
+		{"pkg:npm/@babel/core@7.0.0", common.EcosystemNpm, "@babel/core", "7.0.0"},
+		{"pkg:maven/org.apache.logging.log4j/log4j-core@2.14.1?type=jar", common.EcosystemMaven, "org.apache.logging.log4j:log4j-core", "2.14.1"},
+		{"pkg:pypi/requests@2.25.0", common.EcosystemPyPI, "requests", "2.25.0"},
+		{"pkg:golang/golang.org/x/net@v0.7.0#http2", common.EcosystemGo, "golang.org/x/net", "v0.7.0"},
+		{"pkg:gem/rack@2.2.4", common.EcosystemRubyGems, "rack", "2.2.4"},
+		{"pkg:nuget/Newtonsoft.Json@12.0.1", common.EcosystemNuGet, "Newtonsoft.Json", "12.0.1"},
+		{"pkg:deb/debian/openssl@1.1.1n-0%2Bdeb11u4?arch=amd64", common.EcosystemDebian, "openssl", "1.1.1n-0+deb11u4"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.purl, func(t *testing.T) {
+			purl, err := ParsePURL(tt.purl)
+			if err != nil {
+				t.Fatalf("ParsePURL failed: %v", err)
+			}
+			pkg, ok := purl.Package()
+			if !ok {
+				t.Fatalf("Expected %s to map to a package", tt.purl)
+			}
+			if pkg.Ecosystem != tt.ecosystem || pkg.Name != tt.name || pkg.Version != tt.version {
+				t.Errorf("Expected %s %s@%s, got %s %s@%s",
+					tt.ecosystem, tt.name, tt.version, pkg.Ecosystem, pkg.Name, pkg.Version)
+			}
+		})
+	}
+}
+
+func TestParsePURL_Unsupported(t *testing.T) {
+	for _, s := range []string{"npm/lodash@4.17.20", "pkg:npm", "pkg:npm/%zz@1.0.0"} {
+		if _, err := ParsePURL(s); err == nil {
+			t.Errorf("Expected %q to be rejected", s)
+		}
+	}
+
+	for _, s := range []string{"pkg:generic/openssl@3.0.2", "pkg:npm/lodash", "pkg:maven/log4j-core@2.14.1"} {
+		purl, err := ParsePURL(s)
+		if err != nil {
+			t.Fatalf("ParsePURL(%q) failed: %v", s, err)
+		}
+		if _, ok := purl.Package(); ok {
+			t.Errorf("Expected %q not to map to a package", s)
+		}
+	}
+}
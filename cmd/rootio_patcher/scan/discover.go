@@ -0,0 +1,179 @@
+package scan
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"rootio_patcher/cmd/rootio_patcher/common"
+)
+
+// defaultIgnoredDirs are never walked: installed dependencies, build output and VCS metadata
+var defaultIgnoredDirs = map[string]bool{
+	".git":         true,
+	".venv":        true,
+	"venv":         true,
+	"node_modules": true,
+	"vendor":       true,
+	"__pycache__":  true,
+	"target":       true,
+	"build":        true,
+}
+
+// secondaryFiles are handled through another file: pyproject.toml is updated alongside
+// poetry.lock, Pipfile alongside Pipfile.lock, and Directory.Packages.props through the
+// .NET projects whose versions it manages
+var secondaryFiles = map[string]bool{
+	"pyproject.toml":           true,
+	"Pipfile":                  true,
+	"Directory.Packages.props": true,
+}
+
+// Target is a dependency file found by Discover
+type Target struct {
+	Path      string
+	Ecosystem common.Ecosystem
+}
+
+// Discover walks root and returns every dependency file one of the parsers can handle,
+// sorted by path. Directories and files matching the root .gitignore or the extra
+// gitignore-style ignore patterns are skipped.
+func Discover(root string, parsers []common.Parser, ignore []string) ([]Target, error) {
+	gitignore, err := readIgnoreFile(filepath.Join(root, ".gitignore"))
+	if err != nil {
+		return nil, err
+	}
+	matcher := newIgnoreMatcher(append(gitignore, ignore...))
+
+	var targets []Target
+	err = filepath.WalkDir(root, func(filePath string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		rel, err := filepath.Rel(root, filePath)
+		if err != nil {
+			return err
+		}
+		if rel == "." {
+			return nil
+		}
+		rel = filepath.ToSlash(rel)
+
+		if entry.IsDir() {
+			if defaultIgnoredDirs[entry.Name()] || matcher.matches(rel, true) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		if secondaryFiles[entry.Name()] || matcher.matches(rel, false) {
+			return nil
+		}
+
+		for _, parser := range parsers {
+			if parser.CanHandle(entry.Name()) {
+				targets = append(targets, Target{Path: filePath, Ecosystem: parser.Ecosystem()})
+				break
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to walk %s: %w", root, err)
+	}
+
+	sort.Slice(targets, func(i, j int) bool { return targets[i].Path < targets[j].Path })
+	return targets, nil
+}
+
+// readIgnoreFile reads gitignore patterns, skipping blank lines and comments. A missing file has none.
+func readIgnoreFile(filePath string) ([]string, error) {
+	file, err := os.Open(filePath)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %s: %w", filePath, err)
+	}
+	defer file.Close()
+
+	var patterns []string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		patterns = append(patterns, line)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", filePath, err)
+	}
+
+	return patterns, nil
+}
+
+// ignorePattern is a single parsed gitignore pattern
+type ignorePattern struct {
+	pattern  string
+	negated  bool
+	dirOnly  bool
+	anchored bool
+}
+
+// ignoreMatcher applies gitignore patterns in order; the last matching pattern wins
+type ignoreMatcher struct {
+	patterns []ignorePattern
+}
+
+// newIgnoreMatcher parses gitignore-style patterns
+func newIgnoreMatcher(patterns []string) *ignoreMatcher {
+	matcher := &ignoreMatcher{}
+	for _, raw := range patterns {
+		p := ignorePattern{pattern: strings.TrimSpace(raw)}
+		if rest, ok := strings.CutPrefix(p.pattern, "!"); ok {
+			p.negated, p.pattern = true, rest
+		}
+		if rest, ok := strings.CutSuffix(p.pattern, "/"); ok {
+			p.dirOnly, p.pattern = true, rest
+		}
+		// "**/" matches at any depth, which is already the default for patterns without a slash
+		p.pattern = strings.TrimPrefix(p.pattern, "**/")
+		// A leading or inner slash anchors the pattern to the root
+		if rest, ok := strings.CutPrefix(p.pattern, "/"); ok {
+			p.anchored, p.pattern = true, rest
+		} else if strings.Contains(p.pattern, "/") {
+			p.anchored = true
+		}
+		if p.pattern != "" {
+			matcher.patterns = append(matcher.patterns, p)
+		}
+	}
+	return matcher
+}
+
+// matches reports whether the slash-separated path relative to the root is ignored
+func (m *ignoreMatcher) matches(rel string, isDir bool) bool {
+	ignored := false
+	for _, p := range m.patterns {
+		if p.dirOnly && !isDir {
+			continue
+		}
+
+		name := path.Base(rel)
+		if p.anchored {
+			name = rel
+		}
+		if matched, err := path.Match(p.pattern, name); err == nil && matched {
+			ignored = !p.negated
+		}
+	}
+	return ignored
+}
@@ -0,0 +1,140 @@
+package scan
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"rootio_patcher/cmd/rootio_patcher/common"
+	"rootio_patcher/cmd/rootio_patcher/gem"
+	"rootio_patcher/cmd/rootio_patcher/gomod"
+	"rootio_patcher/cmd/rootio_patcher/maven"
+	"rootio_patcher/cmd/rootio_patcher/npm"
+	"rootio_patcher/cmd/rootio_patcher/nuget"
+	"rootio_patcher/cmd/rootio_patcher/pip"
+)
+
+// writeRepo creates the given files (slash-separated paths) under a temp directory and returns it
+func writeRepo(t *testing.T, files map[string]string) string {
+	t.Helper()
+
+	root := t.TempDir()
+	for name, content := range files {
+		path := filepath.Join(root, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("Failed to create directory: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+	}
+	return root
+}
+
+func allParsers() []common.Parser {
+	return []common.Parser{
+		npm.NewParser(),
+		maven.NewParser(),
+		maven.NewGradleParser(),
+		gomod.NewParser(),
+		gem.NewParser(),
+		nuget.NewParser(),
+		pip.NewParser(),
+		pip.NewPoetryParser(),
+		pip.NewPipenvParser(),
+	}
+}
+
+// relativeTargets converts targets to "ecosystem:relative/path" strings for comparison
+func relativeTargets(t *testing.T, root string, targets []Target) []string {
+	t.Helper()
+
+	var result []string
+	for _, target := range targets {
+		rel, err := filepath.Rel(root, target.Path)
+		if err != nil {
+			t.Fatalf("Failed to relativize %s: %v", target.Path, err)
+		}
+		result = append(result, string(target.Ecosystem)+":"+filepath.ToSlash(rel))
+	}
+	return result
+}
+
+func TestDiscover(t *testing.T) {
+	root := writeRepo(t, map[string]string{
+		"package-lock.json":                  "{}",
+		"package.json":                       "{}",
+		"services/api/pom.xml":               "<project/>",
+		"services/worker/build.gradle.kts":   "",
+		"services/gateway/go.mod":            "module example.com/gateway",
+		"services/gateway/go.sum":            "",
+		"web/Gemfile.lock":                   "GEM",
+		"web/Gemfile":                        "",
+		"vendor/example.com/dep/go.mod":      "module example.com/dep",
+		"tools/requirements-dev.txt":         "",
+		"ml/poetry.lock":                     "",
+		"ml/pyproject.toml":                  "",
+		"web/Pipfile.lock":                   "{}",
+		"web/Pipfile":                        "",
+		"dotnet/Directory.Packages.props":    "<Project/>",
+		"dotnet/src/Api/Api.csproj":          "<Project/>",
+		"node_modules/left-pad/package.json": "{}",
+		"node_modules/left-pad/yarn.lock":    "",
+		".venv/lib/requirements.txt":         "",
+		"services/api/target/pom.xml":        "<project/>",
+		"README.md":                          "",
+	})
+
+	targets, err := Discover(root, allParsers(), nil)
+	if err != nil {
+		t.Fatalf("Discover failed: %v", err)
+	}
+
+	expected := []string{
+		"nuget:dotnet/src/Api/Api.csproj",
+		"pypi:ml/poetry.lock",
+		"npm:package-lock.json",
+		"maven:services/api/pom.xml",
+		"go:services/gateway/go.mod",
+		"maven:services/worker/build.gradle.kts",
+		"pypi:tools/requirements-dev.txt",
+		"rubygems:web/Gemfile.lock",
+		"pypi:web/Pipfile.lock",
+	}
+	if got := relativeTargets(t, root, targets); !reflect.DeepEqual(got, expected) {
+		t.Errorf("Expected %v, got %v", expected, got)
+	}
+}
+
+func TestDiscover_Ignore(t *testing.T) {
+	root := writeRepo(t, map[string]string{
+		".gitignore":                     "# generated\nfixtures/\n*.gradle\n!keep/build.gradle\n",
+		"pom.xml":                        "<project/>",
+		"fixtures/pom.xml":               "<project/>",
+		"app/build.gradle":               "",
+		"keep/build.gradle":              "",
+		"examples/demo/requirements.txt": "",
+		"examples/requirements.txt":      "",
+	})
+
+	targets, err := Discover(root, allParsers(), []string{"/examples/demo"})
+	if err != nil {
+		t.Fatalf("Discover failed: %v", err)
+	}
+
+	expected := []string{
+		"pypi:examples/requirements.txt",
+		"maven:keep/build.gradle",
+		"maven:pom.xml",
+	}
+	if got := relativeTargets(t, root, targets); !reflect.DeepEqual(got, expected) {
+		t.Errorf("Expected %v, got %v", expected, got)
+	}
+}
+
+func TestDiscover_MissingRoot(t *testing.T) {
+	if _, err := Discover(filepath.Join(t.TempDir(), "missing"), allParsers(), nil); err == nil {
+		t.Fatal("Expected error for a missing root directory")
+	}
+}
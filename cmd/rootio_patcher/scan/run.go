@@ -0,0 +1,100 @@
+package scan
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+
+	"rootio_patcher/cmd/rootio_patcher/common"
+)
+
+// AppFactory creates the App that remediates target, writing its report to out
+type AppFactory func(target Target, out io.Writer) common.Runner
+
+// Run remediates targets with the Apps newApp creates. Files of one ecosystem are remediated
+// in order, and up to jobs ecosystems at once. The reports of concurrent Apps are buffered
+// and written to out ordered by ecosystem name, so the output doesn't depend on which finishes
+// first. A failed file doesn't stop the others: the results, ordered like the output, include
+// every file that ran, and the returned error joins their errors. Files not yet started when
+// ctx is cancelled are left out.
+func Run(ctx context.Context, targets []Target, jobs int, out io.Writer, newApp AppFactory) ([]FileResult, error) {
+	groups := groupByEcosystem(targets)
+	results := make([][]FileResult, len(groups))
+
+	if jobs <= 1 {
+		// Write straight to out, so a confirmation prompt follows the report it asks about
+		for i, group := range groups {
+			results[i] = runFiles(ctx, group, out, newApp)
+		}
+	} else {
+		outputs := make([]bytes.Buffer, len(groups))
+		slots := make(chan struct{}, jobs)
+		var wg sync.WaitGroup
+		for i, group := range groups {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				slots <- struct{}{}
+				defer func() { <-slots }()
+				results[i] = runFiles(ctx, group, &outputs[i], newApp)
+			}()
+		}
+		wg.Wait()
+
+		for i := range outputs {
+			if _, err := outputs[i].WriteTo(out); err != nil {
+				return nil, fmt.Errorf("failed to write report: %w", err)
+			}
+		}
+	}
+
+	var all []FileResult
+	var errs []error
+	for _, group := range results {
+		for _, fr := range group {
+			all = append(all, fr)
+			if fr.Err != nil {
+				errs = append(errs, fmt.Errorf("%s: %w", fr.Target.Path, fr.Err))
+			}
+		}
+	}
+	return all, errors.Join(errs...)
+}
+
+// runFiles remediates the files of one ecosystem in order
+func runFiles(ctx context.Context, targets []Target, out io.Writer, newApp AppFactory) []FileResult {
+	var results []FileResult
+	for _, target := range targets {
+		if ctx.Err() != nil {
+			break
+		}
+		fmt.Fprintf(out, "\n=== %s (%s) ===\n", target.Path, target.Ecosystem)
+		result, err := newApp(target, out).RunWithResult(ctx)
+		results = append(results, FileResult{Target: target, Result: result, Err: err})
+	}
+	return results
+}
+
+// groupByEcosystem splits targets by ecosystem, ordered by ecosystem name, keeping the order
+// of each ecosystem's files
+func groupByEcosystem(targets []Target) [][]Target {
+	byEcosystem := make(map[common.Ecosystem][]Target)
+	var ecosystems []common.Ecosystem
+	for _, target := range targets {
+		if _, ok := byEcosystem[target.Ecosystem]; !ok {
+			ecosystems = append(ecosystems, target.Ecosystem)
+		}
+		byEcosystem[target.Ecosystem] = append(byEcosystem[target.Ecosystem], target)
+	}
+	sort.Slice(ecosystems, func(i, j int) bool { return ecosystems[i] < ecosystems[j] })
+
+	groups := make([][]Target, len(ecosystems))
+	for i, ecosystem := range ecosystems {
+		groups[i] = byEcosystem[ecosystem]
+	}
+	return groups
+}
@@ -0,0 +1,119 @@
+package scan
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"rootio_patcher/cmd/rootio_patcher/common"
+)
+
+// mockApp is a common.Runner that runs a function in place of remediation
+type mockApp struct {
+	target Target
+	out    io.Writer
+	run    func(out io.Writer) error
+	result *common.RunResult
+}
+
+func (m *mockApp) Run(ctx context.Context) error {
+	_, err := m.RunWithResult(ctx)
+	return err
+}
+
+func (m *mockApp) RunWithResult(ctx context.Context) (*common.RunResult, error) {
+	m.result = common.NewRunResult(m.target.Ecosystem, m.target.Path, true)
+	err := m.run(m.out)
+	return m.result, err
+}
+
+func (m *mockApp) Result() *common.RunResult {
+	return m.result
+}
+
+func TestRun_ConcurrentEcosystems(t *testing.T) {
+	targets := []Target{
+		{Path: "api/requirements.txt", Ecosystem: common.EcosystemPyPI},
+		{Path: "web/package-lock.json", Ecosystem: common.EcosystemNpm},
+	}
+
+	// npm waits for pip, so the run only finishes when both ecosystems run at once
+	pipDone := make(chan struct{})
+	newApp := func(target Target, out io.Writer) common.Runner {
+		app := &mockApp{target: target, out: out}
+		switch target.Ecosystem {
+		case common.EcosystemNpm:
+			app.run = func(out io.Writer) error {
+				select {
+				case <-pipDone:
+				case <-time.After(5 * time.Second):
+					return errors.New("pip never ran")
+				}
+				fmt.Fprintln(out, "npm report")
+				return nil
+			}
+		default:
+			app.run = func(out io.Writer) error {
+				defer close(pipDone)
+				fmt.Fprintln(out, "pip report")
+				return errors.New("parse error")
+			}
+		}
+		return app
+	}
+
+	var buf bytes.Buffer
+	results, err := Run(context.Background(), targets, 2, &buf, newApp)
+
+	if err == nil || !strings.Contains(err.Error(), "api/requirements.txt: parse error") {
+		t.Errorf("Expected the pip error to be returned, got %v", err)
+	}
+	if len(results) != 2 {
+		t.Fatalf("Expected 2 results, got %+v", results)
+	}
+	if results[0].Target.Ecosystem != common.EcosystemNpm || results[0].Err != nil || results[0].Result == nil {
+		t.Errorf("Expected npm to succeed first, got %+v", results[0])
+	}
+	if results[1].Target.Ecosystem != common.EcosystemPyPI || results[1].Err == nil || results[1].Result == nil {
+		t.Errorf("Expected pip to fail second, got %+v", results[1])
+	}
+
+	// pip finished first, but the output is ordered by ecosystem name
+	want := "\n=== web/package-lock.json (npm) ===\nnpm report\n" +
+		"\n=== api/requirements.txt (pypi) ===\npip report\n"
+	if buf.String() != want {
+		t.Errorf("Expected output:\n%s\ngot:\n%s", want, buf.String())
+	}
+}
+
+func TestRun_Sequential(t *testing.T) {
+	targets := []Target{
+		{Path: "b/go.mod", Ecosystem: common.EcosystemGo},
+		{Path: "a/pom.xml", Ecosystem: common.EcosystemMaven},
+		{Path: "c/go.mod", Ecosystem: common.EcosystemGo},
+	}
+
+	var order []string
+	newApp := func(target Target, out io.Writer) common.Runner {
+		return &mockApp{target: target, out: out, run: func(io.Writer) error {
+			order = append(order, target.Path)
+			return nil
+		}}
+	}
+
+	results, err := Run(context.Background(), targets, 1, io.Discard, newApp)
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if len(results) != 3 {
+		t.Errorf("Expected 3 results, got %+v", results)
+	}
+	if got := strings.Join(order, " "); got != "b/go.mod c/go.mod a/pom.xml" {
+		t.Errorf("Expected files to run by ecosystem, got %s", got)
+	}
+}
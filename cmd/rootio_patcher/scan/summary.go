@@ -0,0 +1,68 @@
+package scan
+
+import (
+	"fmt"
+	"io"
+	"text/tabwriter"
+
+	"rootio_patcher/cmd/rootio_patcher/common"
+)
+
+// FileResult is the outcome of remediating one discovered file
+type FileResult struct {
+	Target Target
+	Result *common.RunResult
+	Err    error
+}
+
+// WriteSummary writes a table of results per file followed by totals per ecosystem
+func WriteSummary(w io.Writer, results []FileResult) {
+	fmt.Fprintln(w, "\n=== Scan Summary ===")
+	if len(results) == 0 {
+		fmt.Fprintln(w, "No dependency files found")
+		return
+	}
+
+	type totals struct {
+		files, packages, patches, applied, failed, skipped int
+	}
+	byEcosystem := make(map[common.Ecosystem]*totals)
+	var ecosystems []common.Ecosystem
+
+	table := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(table, "ECOSYSTEM\tFILE\tPACKAGES\tPATCHES\tAPPLIED\tSKIPPED\tSTATUS")
+	for _, fr := range results {
+		t, ok := byEcosystem[fr.Target.Ecosystem]
+		if !ok {
+			t = &totals{}
+			byEcosystem[fr.Target.Ecosystem] = t
+			ecosystems = append(ecosystems, fr.Target.Ecosystem)
+		}
+		t.files++
+
+		result := fr.Result
+		if result == nil {
+			result = common.NewRunResult(fr.Target.Ecosystem, fr.Target.Path, false)
+		}
+		t.packages += result.PackagesFound
+		t.patches += len(result.Patches)
+		t.applied += result.Applied()
+		t.failed += result.Failed()
+		t.skipped += len(result.Skipped)
+
+		status := "ok"
+		if fr.Err != nil {
+			status = "error: " + fr.Err.Error()
+		}
+		fmt.Fprintf(table, "%s\t%s\t%d\t%d\t%d\t%d\t%s\n", fr.Target.Ecosystem, fr.Target.Path,
+			result.PackagesFound, len(result.Patches), result.Applied(), len(result.Skipped), status)
+	}
+	table.Flush()
+
+	fmt.Fprintln(w)
+	for _, ecosystem := range ecosystems {
+		t := byEcosystem[ecosystem]
+		fmt.Fprintf(w, "%s: %d files, %d packages, %d patches available, %d applied, %d failed, %d skipped\n",
+			ecosystem, t.files, t.packages, t.patches, t.applied, t.failed, t.skipped)
+	}
+}
@@ -0,0 +1,48 @@
+package scan
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+
+	"rootio_patcher/cmd/rootio_patcher/common"
+	"rootio_patcher/pkg/rootio"
+)
+
+func TestWriteSummary(t *testing.T) {
+	npmResult := common.NewRunResult(common.EcosystemNpm, "package-lock.json", false)
+	npmResult.PackagesFound = 12
+	npmResult.AddPatches([]rootio.PackagePatch{
+		{PackageName: "express", Version: "4.17.1", PatchAlias: rootio.PatchInfo{Name: "@rootio/express", Version: "4.17.3"}},
+	}, true, common.PatchStatusApplied)
+
+	results := []FileResult{
+		{Target: Target{Path: "package-lock.json", Ecosystem: common.EcosystemNpm}, Result: npmResult},
+		{Target: Target{Path: "api/pom.xml", Ecosystem: common.EcosystemMaven}, Err: errors.New("parse error")},
+	}
+
+	var buf bytes.Buffer
+	WriteSummary(&buf, results)
+	output := buf.String()
+
+	for _, expected := range []string{
+		"=== Scan Summary ===",
+		"npm: 1 files, 12 packages, 1 patches available, 1 applied, 0 failed, 0 skipped",
+		"maven: 1 files, 0 packages, 0 patches available, 0 applied, 0 failed, 0 skipped",
+		"error: parse error",
+	} {
+		if !strings.Contains(output, expected) {
+			t.Errorf("Expected summary to contain %q, got:\n%s", expected, output)
+		}
+	}
+}
+
+func TestWriteSummary_Empty(t *testing.T) {
+	var buf bytes.Buffer
+	WriteSummary(&buf, nil)
+
+	if !strings.Contains(buf.String(), "No dependency files found") {
+		t.Errorf("Expected empty summary message, got:\n%s", buf.String())
+	}
+}
@@ -5,4 +5,7 @@ go 1.24.0
 require (
 	github.com/alecthomas/kong v1.13.0
 	github.com/caarlos0/env/v11 v11.3.1
+	golang.org/x/sync v0.17.0
 )
+
+require gopkg.in/yaml.v3 v3.0.1
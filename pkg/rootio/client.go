@@ -6,7 +6,51 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"log/slog"
+	"math/rand/v2"
 	"net/http"
+	"strings"
+	"time"
+
+	"golang.org/x/sync/errgroup"
+)
+
+const (
+	// DefaultMaxAttempts is the default number of attempts for API requests
+	DefaultMaxAttempts = 3
+
+	// DefaultRetryBaseDelay is the default delay before the first retry
+	DefaultRetryBaseDelay = 500 * time.Millisecond
+
+	// DefaultTimeout is the default timeout for a single API request
+	DefaultTimeout = 30 * time.Second
+
+	// DefaultBatchSize is the default number of packages sent per analysis request
+	DefaultBatchSize = 500
+
+	// DefaultConcurrency is the default number of analysis batches sent in parallel
+	DefaultConcurrency = 4
+
+	// DefaultEcosystem is the ecosystem analyzed when no hint is given
+	DefaultEcosystem = "pypi"
+
+	// DefaultRemediatePath is the path template of the remediate endpoint
+	DefaultRemediatePath = "/v3/remediate/" + EcosystemPlaceholder
+
+	// EcosystemPlaceholder is replaced with the client's ecosystem in remediate path templates
+	EcosystemPlaceholder = "{ecosystem}"
+)
+
+// Ways to send the API key, selected with WithAuth
+const (
+	// AuthBasic sends the API key as the Basic-Auth username with an empty password (the default)
+	AuthBasic = "basic"
+
+	// AuthBearer sends the API key as a bearer token in the Authorization header
+	AuthBearer = "bearer"
+
+	// AuthHeader sends the API key as the value of a custom header, such as X-API-Key
+	AuthHeader = "header"
 )
 
 // Client is the Root.io API client
@@ -14,21 +58,221 @@ type Client struct {
 	baseURL    string
 	apiKey     string
 	httpClient *http.Client
+	transport  *http.Transport
+
+	maxAttempts    int
+	retryBaseDelay time.Duration
+	// sleep waits out a backoff delay; tests replace it to retry without real delays
+	sleep func(ctx context.Context, d time.Duration) error
+
+	batchSize   int
+	concurrency int
+
+	ecosystem     string
+	remediatePath string
+
+	// authScheme is how the API key is sent (AuthBasic, AuthBearer or AuthHeader), and
+	// authHeader the header carrying it with AuthHeader
+	authScheme string
+	authHeader string
+
+	// logger records requests and responses at debug level
+	logger *slog.Logger
+}
+
+// Option configures a Client
+type Option func(*Client)
+
+// WithRetry sets the maximum number of attempts and the base backoff delay.
+// A maxAttempts value of 1 or less disables retries.
+func WithRetry(maxAttempts int, baseDelay time.Duration) Option {
+	return func(c *Client) {
+		if maxAttempts < 1 {
+			maxAttempts = 1
+		}
+		c.maxAttempts = maxAttempts
+		c.retryBaseDelay = baseDelay
+	}
+}
+
+// WithSleep replaces how the client waits between retries. sleep must return ctx.Err() if ctx
+// is cancelled first. Tests use it to record backoff delays without sleeping.
+func WithSleep(sleep func(ctx context.Context, d time.Duration) error) Option {
+	return func(c *Client) {
+		if sleep != nil {
+			c.sleep = sleep
+		}
+	}
+}
+
+// WithTimeout sets the timeout for a single API request attempt.
+// The caller's context still applies; whichever expires first cancels the request.
+func WithTimeout(timeout time.Duration) Option {
+	return func(c *Client) {
+		c.httpClient.Timeout = timeout
+	}
+}
+
+// WithBatchSize sets the maximum number of packages per analysis request.
+// A size of 0 or less sends all packages in a single request.
+func WithBatchSize(size int) Option {
+	return func(c *Client) {
+		c.batchSize = size
+	}
+}
+
+// WithConcurrency sets how many analysis batches are sent in parallel.
+// A value of 1 or less sends batches sequentially.
+func WithConcurrency(workers int) Option {
+	return func(c *Client) {
+		if workers < 1 {
+			workers = 1
+		}
+		c.concurrency = workers
+	}
+}
+
+// WithEcosystem selects the ecosystem the remediate endpoint analyzes packages for (e.g. "debian")
+func WithEcosystem(ecosystem string) Option {
+	return func(c *Client) {
+		c.ecosystem = ecosystem
+	}
+}
+
+// WithRemediatePath overrides the path template of the remediate endpoint, e.g. for a staging
+// backend. EcosystemPlaceholder in the template is replaced with the ecosystem. An empty template
+// keeps DefaultRemediatePath.
+func WithRemediatePath(template string) Option {
+	return func(c *Client) {
+		if template != "" {
+			c.remediatePath = template
+		}
+	}
+}
+
+// WithAuth selects how the API key is sent to deployments that don't accept Basic auth.
+// header names the header carrying the key with AuthHeader and is ignored otherwise. An empty
+// scheme keeps AuthBasic.
+func WithAuth(scheme, header string) Option {
+	return func(c *Client) {
+		if scheme != "" {
+			c.authScheme = scheme
+			c.authHeader = header
+		}
+	}
+}
+
+// WithLogger logs each request's URL and package count, and each response's status and patch
+// count, at debug level. The raw bodies are logged too when debug logging is enabled, with the
+// API key redacted.
+func WithLogger(logger *slog.Logger) Option {
+	return func(c *Client) {
+		if logger != nil {
+			c.logger = logger
+		}
+	}
 }
 
 // NewClient creates a new Root.io API client
-func NewClient(baseURL, apiKey string) *Client {
-	return &Client{
-		baseURL:    baseURL,
-		apiKey:     apiKey,
-		httpClient: &http.Client{},
+func NewClient(baseURL, apiKey string, opts ...Option) *Client {
+	transport := newTransport()
+	c := &Client{
+		baseURL:        baseURL,
+		apiKey:         apiKey,
+		httpClient:     &http.Client{Timeout: DefaultTimeout, Transport: transport},
+		transport:      transport,
+		maxAttempts:    DefaultMaxAttempts,
+		retryBaseDelay: DefaultRetryBaseDelay,
+		sleep:          sleepContext,
+		batchSize:      DefaultBatchSize,
+		concurrency:    DefaultConcurrency,
+		ecosystem:      DefaultEcosystem,
+		remediatePath:  DefaultRemediatePath,
+		authScheme:     AuthBasic,
+		logger:         slog.New(slog.DiscardHandler),
+	}
+
+	for _, opt := range opts {
+		opt(c)
 	}
+
+	return c
 }
 
-// AnalyzePackages sends packages to the backend for vulnerability analysis
+// AnalyzePackages sends packages to the backend for vulnerability analysis.
+// Large inputs are split into batches that are analyzed concurrently; results are
+// merged in batch order so the response doesn't depend on completion order.
 func (c *Client) AnalyzePackages(
 	ctx context.Context, packages []Package,
 ) (*AnalyzePackagesResponse, error) {
+	batches := splitBatches(packages, c.batchSize)
+	if len(batches) == 1 {
+		return c.analyzeBatch(ctx, batches[0])
+	}
+
+	responses := make([]*AnalyzePackagesResponse, len(batches))
+	if err := c.runBatches(ctx, len(batches), func(ctx context.Context, i int) error {
+		response, err := c.analyzeBatch(ctx, batches[i])
+		if err != nil {
+			return fmt.Errorf("batch %d of %d: %w", i+1, len(batches), err)
+		}
+		responses[i] = response
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+
+	merged := &AnalyzePackagesResponse{}
+	for _, response := range responses {
+		merged.Patches = append(merged.Patches, response.Patches...)
+		merged.Skipped = append(merged.Skipped, response.Skipped...)
+	}
+
+	return merged, nil
+}
+
+// runBatches calls fn for each batch index with at most c.concurrency calls in flight.
+// The first error cancels the context passed to the remaining calls and is returned.
+func (c *Client) runBatches(ctx context.Context, n int, fn func(ctx context.Context, i int) error) error {
+	g, batchCtx := errgroup.WithContext(ctx)
+	g.SetLimit(c.concurrency)
+
+	for i := 0; i < n; i++ {
+		if batchCtx.Err() != nil {
+			break
+		}
+		g.Go(func() error {
+			return fn(batchCtx, i)
+		})
+	}
+	if err := g.Wait(); err != nil {
+		return err
+	}
+
+	return context.Cause(ctx)
+}
+
+// splitBatches splits packages into batches of at most size packages
+func splitBatches(packages []Package, size int) [][]Package {
+	if size <= 0 || len(packages) <= size {
+		return [][]Package{packages}
+	}
+
+	var batches [][]Package
+	for start := 0; start < len(packages); start += size {
+		end := min(start+size, len(packages))
+		batches = append(batches, packages[start:end])
+	}
+	return batches
+}
+
+// RemediateURL returns the URL of the remediate endpoint for the client's ecosystem
+func (c *Client) RemediateURL() string {
+	return c.baseURL + strings.ReplaceAll(c.remediatePath, EcosystemPlaceholder, c.ecosystem)
+}
+
+// analyzeBatch analyzes a single batch of packages, retrying transient failures
+func (c *Client) analyzeBatch(ctx context.Context, packages []Package) (*AnalyzePackagesResponse, error) {
 	request := AnalyzePackagesRequest{
 		Packages: packages,
 	}
@@ -38,30 +282,159 @@ func (c *Client) AnalyzePackages(
 		return nil, fmt.Errorf("failed to marshal request: %w", err)
 	}
 
-	url := fmt.Sprintf("%s/v3/remediate/pypi", c.baseURL)
+	url := c.RemediateURL()
+	c.logger.DebugContext(ctx, "Sending analysis request",
+		slog.String("url", url),
+		slog.Int("packages", len(packages)))
+	c.logBody(ctx, "Analysis request body", body)
+
+	var lastErr error
+	for attempt := 1; attempt <= c.maxAttempts; attempt++ {
+		if attempt > 1 {
+			if err := c.sleep(ctx, c.backoff(attempt-1)); err != nil {
+				return nil, fmt.Errorf("request cancelled after %d attempts: %w", attempt-1, err)
+			}
+		}
+
+		response, retryable, err := c.doAnalyze(ctx, url, body)
+		if err == nil {
+			return response, nil
+		}
+		if !retryable {
+			return nil, err
+		}
+		lastErr = err
+	}
+
+	return nil, fmt.Errorf("request failed after %d attempts: %w", c.maxAttempts, lastErr)
+}
+
+// doAnalyze performs a single analysis request and reports whether a failure is retryable
+func (c *Client) doAnalyze(ctx context.Context, url string, body []byte) (*AnalyzePackagesResponse, bool, error) {
 	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
 	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %w", err)
+		return nil, false, fmt.Errorf("failed to create request: %w", err)
 	}
 
 	req.Header.Set("Content-Type", "application/json")
-	req.SetBasicAuth(c.apiKey, "")
+	c.authorize(req)
 
 	resp, err := c.httpClient.Do(req)
 	if err != nil {
-		return nil, fmt.Errorf("failed to execute request: %w", err)
+		// Connection errors are transient unless the caller gave up
+		return nil, ctx.Err() == nil, fmt.Errorf("failed to execute request: %w", err)
 	}
 	defer resp.Body.Close()
 
+	bodyBytes, err := io.ReadAll(resp.Body)
+	c.logger.DebugContext(ctx, "Received analysis response",
+		slog.String("url", url),
+		slog.Int("status", resp.StatusCode))
+	c.logBody(ctx, "Analysis response body", bodyBytes)
+	if err != nil {
+		return nil, ctx.Err() == nil, fmt.Errorf("failed to read response: %w", err)
+	}
+
 	if resp.StatusCode != http.StatusOK {
-		bodyBytes, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("API returned status %d: %s", resp.StatusCode, string(bodyBytes))
+		return nil, isRetryableStatus(resp.StatusCode),
+			&APIError{StatusCode: resp.StatusCode, Body: string(bodyBytes)}
 	}
 
 	var response AnalyzePackagesResponse
-	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
-		return nil, fmt.Errorf("failed to decode response: %w", err)
+	if err := json.Unmarshal(bodyBytes, &response); err != nil {
+		return nil, false, fmt.Errorf("failed to decode response: %w", err)
+	}
+	for i := range response.Patches {
+		normalizeCVEs(&response.Patches[i])
+	}
+	c.logger.DebugContext(ctx, "Decoded analysis response",
+		slog.Int("patches", len(response.Patches)),
+		slog.Int("skipped", len(response.Skipped)))
+
+	return &response, false, nil
+}
+
+// authorize adds the API key to a request as the client's auth scheme requires
+func (c *Client) authorize(req *http.Request) {
+	switch c.authScheme {
+	case AuthBearer:
+		req.Header.Set("Authorization", "Bearer "+c.apiKey)
+	case AuthHeader:
+		req.Header.Set(c.authHeader, c.apiKey)
+	default:
+		req.SetBasicAuth(c.apiKey, "")
+	}
+}
+
+// logBody logs a raw request or response body with the API key redacted. Bodies can be large,
+// so they're only converted when debug logging is enabled.
+func (c *Client) logBody(ctx context.Context, msg string, body []byte) {
+	if !c.logger.Enabled(ctx, slog.LevelDebug) {
+		return
+	}
+	text := string(body)
+	if c.apiKey != "" {
+		text = strings.ReplaceAll(text, c.apiKey, "***")
+	}
+	c.logger.DebugContext(ctx, msg, slog.String("body", text))
+}
+
+// normalizeCVEs keeps CVEIDs and CVEs consistent: IDs without details get an entry in CVEs,
+// and CVEIDs is filled from CVEs when the API only sent details
+func normalizeCVEs(patch *PackagePatch) {
+	detailed := make(map[string]bool, len(patch.CVEs))
+	for _, cve := range patch.CVEs {
+		detailed[cve.ID] = true
 	}
 
-	return &response, nil
+	listed := make(map[string]bool, len(patch.CVEIDs))
+	for _, id := range patch.CVEIDs {
+		listed[id] = true
+		if !detailed[id] {
+			patch.CVEs = append(patch.CVEs, CVE{ID: id})
+			detailed[id] = true
+		}
+	}
+	for _, cve := range patch.CVEs {
+		if !listed[cve.ID] {
+			patch.CVEIDs = append(patch.CVEIDs, cve.ID)
+			listed[cve.ID] = true
+		}
+	}
+}
+
+// isRetryableStatus reports whether a response status indicates a transient failure
+func isRetryableStatus(status int) bool {
+	switch status {
+	case http.StatusTooManyRequests,
+		http.StatusInternalServerError,
+		http.StatusBadGateway,
+		http.StatusServiceUnavailable,
+		http.StatusGatewayTimeout:
+		return true
+	default:
+		return false
+	}
+}
+
+// backoff returns the delay before the given retry: exponential growth plus up to 50% jitter
+func (c *Client) backoff(retry int) time.Duration {
+	delay := c.retryBaseDelay << (retry - 1)
+	if delay <= 0 {
+		return 0
+	}
+	return delay + rand.N(delay/2+1)
+}
+
+// sleepContext sleeps for the given duration or until ctx is cancelled
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
 }
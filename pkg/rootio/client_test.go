@@ -0,0 +1,652 @@
+package rootio
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestClient_AnalyzePackages_Success(t *testing.T) {
+	ctx := context.Background()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v3/remediate/pypi" {
+			t.Errorf("Unexpected path: %s", r.URL.Path)
+		}
+		user, _, ok := r.BasicAuth()
+		if !ok || user != "test-key" {
+			t.Errorf("Expected basic auth with API key, got %q", user)
+		}
+
+		_ = json.NewEncoder(w).Encode(AnalyzePackagesResponse{
+			Patches: []PackagePatch{{PackageName: "django", Version: "4.0.0"}},
+		})
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL, "test-key")
+	response, err := client.AnalyzePackages(ctx, []Package{{Name: "django", Version: "4.0.0"}})
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if len(response.Patches) != 1 {
+		t.Fatalf("Expected 1 patch, got %d", len(response.Patches))
+	}
+}
+
+func TestClient_AnalyzePackages_RetriesTransientErrors(t *testing.T) {
+	ctx := context.Background()
+
+	var attempts atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if attempts.Add(1) < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(AnalyzePackagesResponse{})
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL, "test-key", WithRetry(3, time.Millisecond))
+	if _, err := client.AnalyzePackages(ctx, nil); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if attempts.Load() != 3 {
+		t.Errorf("Expected 3 attempts, got %d", attempts.Load())
+	}
+}
+
+func TestClient_AnalyzePackages_BackoffWithoutRealDelay(t *testing.T) {
+	ctx := context.Background()
+
+	var attempts atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if attempts.Add(1) <= 3 {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(AnalyzePackagesResponse{})
+	}))
+	defer server.Close()
+
+	// An hour-long base delay would hang the test if the client slept for real
+	var delays []time.Duration
+	sleep := func(ctx context.Context, d time.Duration) error {
+		delays = append(delays, d)
+		return nil
+	}
+
+	start := time.Now()
+	client := NewClient(server.URL, "test-key", WithRetry(4, time.Hour), WithSleep(sleep))
+	if _, err := client.AnalyzePackages(ctx, nil); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if attempts.Load() != 4 {
+		t.Errorf("Expected 4 attempts (3 retries), got %d", attempts.Load())
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("Expected retries without real delays, took %v", elapsed)
+	}
+
+	// Each delay doubles the base, plus up to 50% jitter
+	if len(delays) != 3 {
+		t.Fatalf("Expected 3 backoff delays, got %v", delays)
+	}
+	for i, d := range delays {
+		base := time.Hour << i
+		if d < base || d > base+base/2 {
+			t.Errorf("Expected retry %d to wait between %v and %v, got %v", i+1, base, base+base/2, d)
+		}
+	}
+}
+
+func TestClient_AnalyzePackages_SleepCancelled(t *testing.T) {
+	var attempts atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts.Add(1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	sleep := func(ctx context.Context, d time.Duration) error {
+		return context.Canceled
+	}
+	client := NewClient(server.URL, "test-key", WithRetry(3, time.Hour), WithSleep(sleep))
+	_, err := client.AnalyzePackages(context.Background(), nil)
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected the sleep's error, got: %v", err)
+	}
+	if attempts.Load() != 1 {
+		t.Errorf("Expected no retry after a cancelled sleep, got %d attempts", attempts.Load())
+	}
+}
+
+func TestClient_AnalyzePackages_GivesUpAfterMaxAttempts(t *testing.T) {
+	ctx := context.Background()
+
+	var attempts atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts.Add(1)
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL, "test-key", WithRetry(3, time.Millisecond))
+	_, err := client.AnalyzePackages(ctx, nil)
+	if err == nil {
+		t.Fatal("Expected error, got nil")
+	}
+	if !strings.Contains(err.Error(), "after 3 attempts") {
+		t.Errorf("Expected error to mention attempt count, got: %v", err)
+	}
+	if attempts.Load() != 3 {
+		t.Errorf("Expected 3 attempts, got %d", attempts.Load())
+	}
+}
+
+func TestClient_AnalyzePackages_NoRetryOnClientError(t *testing.T) {
+	ctx := context.Background()
+
+	var attempts atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts.Add(1)
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL, "test-key", WithRetry(3, time.Millisecond))
+	if _, err := client.AnalyzePackages(ctx, nil); err == nil {
+		t.Fatal("Expected error, got nil")
+	}
+	if attempts.Load() != 1 {
+		t.Errorf("Expected 1 attempt, got %d", attempts.Load())
+	}
+}
+
+func TestClient_AnalyzePackages_RetryDisabled(t *testing.T) {
+	ctx := context.Background()
+
+	var attempts atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts.Add(1)
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL, "test-key", WithRetry(1, time.Millisecond))
+	if _, err := client.AnalyzePackages(ctx, nil); err == nil {
+		t.Fatal("Expected error, got nil")
+	}
+	if attempts.Load() != 1 {
+		t.Errorf("Expected 1 attempt, got %d", attempts.Load())
+	}
+}
+
+func TestClient_AnalyzePackages_CancelDuringBackoff(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		cancel()
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL, "test-key", WithRetry(3, time.Hour))
+	_, err := client.AnalyzePackages(ctx, nil)
+	if err == nil {
+		t.Fatal("Expected error, got nil")
+	}
+	if ctx.Err() == nil {
+		t.Fatal("Expected context to be cancelled")
+	}
+}
+
+func TestClient_AnalyzePackages_Timeout(t *testing.T) {
+	ctx := context.Background()
+
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Deliberately slow handler; released once the test is done
+		select {
+		case <-release:
+		case <-time.After(5 * time.Second):
+		}
+	}))
+	defer server.Close()
+	defer close(release)
+
+	client := NewClient(server.URL, "test-key", WithTimeout(50*time.Millisecond), WithRetry(1, 0))
+
+	start := time.Now()
+	_, err := client.AnalyzePackages(ctx, nil)
+	elapsed := time.Since(start)
+
+	if err == nil {
+		t.Fatal("Expected timeout error, got nil")
+	}
+	var netErr net.Error
+	if !errors.As(err, &netErr) || !netErr.Timeout() {
+		t.Errorf("Expected timeout error, got: %v", err)
+	}
+	if elapsed > 2*time.Second {
+		t.Errorf("Expected client to time out quickly, took %v", elapsed)
+	}
+}
+
+// newBatchServer echoes each requested package back as a patch after the given latency
+func newBatchServer(t testing.TB, latency func(packages []Package) time.Duration, inFlight, maxInFlight *atomic.Int32) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var request AnalyzePackagesRequest
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			t.Errorf("Failed to decode request: %v", err)
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		if inFlight != nil {
+			current := inFlight.Add(1)
+			defer inFlight.Add(-1)
+			for {
+				peak := maxInFlight.Load()
+				if current <= peak || maxInFlight.CompareAndSwap(peak, current) {
+					break
+				}
+			}
+		}
+
+		time.Sleep(latency(request.Packages))
+
+		var response AnalyzePackagesResponse
+		for _, pkg := range request.Packages {
+			response.Patches = append(response.Patches, PackagePatch{PackageName: pkg.Name, Version: pkg.Version})
+		}
+		_ = json.NewEncoder(w).Encode(response)
+	}))
+}
+
+// syntheticPackages returns n distinct packages
+func syntheticPackages(n int) []Package {
+	packages := make([]Package, n)
+	for i := range packages {
+		packages[i] = Package{Name: fmt.Sprintf("package-%04d", i), Version: "1.0.0"}
+	}
+	return packages
+}
+
+func TestClient_AnalyzePackages_ConcurrentBatchesMergeInOrder(t *testing.T) {
+	ctx := context.Background()
+
+	var inFlight, maxInFlight atomic.Int32
+	server := newBatchServer(t, func(packages []Package) time.Duration {
+		// Earlier batches finish last, so completion order is reversed
+		if packages[0].Name < "package-0050" {
+			return 30 * time.Millisecond
+		}
+		return time.Millisecond
+	}, &inFlight, &maxInFlight)
+	defer server.Close()
+
+	client := NewClient(server.URL, "test-key", WithBatchSize(10), WithConcurrency(3))
+	packages := syntheticPackages(95)
+
+	response, err := client.AnalyzePackages(ctx, packages)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	if len(response.Patches) != len(packages) {
+		t.Fatalf("Expected %d patches, got %d", len(packages), len(response.Patches))
+	}
+	for i, patch := range response.Patches {
+		if patch.PackageName != packages[i].Name {
+			t.Fatalf("Expected patch %d to be %s, got %s", i, packages[i].Name, patch.PackageName)
+		}
+	}
+
+	if peak := maxInFlight.Load(); peak > 3 {
+		t.Errorf("Expected at most 3 concurrent requests, got %d", peak)
+	}
+}
+
+func TestClient_AnalyzePackages_BatchErrorCancelsRemaining(t *testing.T) {
+	ctx := context.Background()
+
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if requests.Add(1) == 1 {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		select {
+		case <-r.Context().Done():
+		case <-time.After(5 * time.Second):
+		}
+		_ = json.NewEncoder(w).Encode(AnalyzePackagesResponse{})
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL, "test-key", WithBatchSize(1), WithConcurrency(1))
+
+	start := time.Now()
+	_, err := client.AnalyzePackages(ctx, syntheticPackages(5))
+	if err == nil || !strings.Contains(err.Error(), "status 401") {
+		t.Fatalf("Expected 401 error, got: %v", err)
+	}
+	if !strings.Contains(err.Error(), "batch 1 of 5") {
+		t.Errorf("Expected error to name the failing batch, got: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("Expected remaining batches to be cancelled, took %v", elapsed)
+	}
+	if got := requests.Load(); got != 1 {
+		t.Errorf("Expected no batches after the failure with concurrency 1, got %d requests", got)
+	}
+}
+
+func TestSplitBatches(t *testing.T) {
+	packages := syntheticPackages(7)
+
+	tests := []struct {
+		size     int
+		expected []int
+	}{
+		{0, []int{7}},
+		{10, []int{7}},
+		{7, []int{7}},
+		{3, []int{3, 3, 1}},
+	}
+
+	for _, tt := range tests {
+		batches := splitBatches(packages, tt.size)
+		if len(batches) != len(tt.expected) {
+			t.Errorf("size %d: expected %d batches, got %d", tt.size, len(tt.expected), len(batches))
+			continue
+		}
+		for i, batch := range batches {
+			if len(batch) != tt.expected[i] {
+				t.Errorf("size %d: expected batch %d to have %d packages, got %d", tt.size, i, tt.expected[i], len(batch))
+			}
+		}
+	}
+}
+
+// BenchmarkClient_AnalyzePackages compares sequential and concurrent analysis of 2000 packages
+// against a server with fixed per-request latency. Run with: go test -bench AnalyzePackages ./pkg/rootio
+func BenchmarkClient_AnalyzePackages(b *testing.B) {
+	ctx := context.Background()
+	packages := syntheticPackages(2000)
+
+	server := newBatchServer(b, func([]Package) time.Duration { return 20 * time.Millisecond }, nil, nil)
+	defer server.Close()
+
+	sequential := NewClient(server.URL, "test-key", WithBatchSize(100), WithConcurrency(1))
+	concurrent := NewClient(server.URL, "test-key", WithBatchSize(100), WithConcurrency(DefaultConcurrency))
+
+	var sequentialTime, concurrentTime time.Duration
+	for b.Loop() {
+		start := time.Now()
+		if _, err := sequential.AnalyzePackages(ctx, packages); err != nil {
+			b.Fatal(err)
+		}
+		sequentialTime += time.Since(start)
+
+		start = time.Now()
+		if _, err := concurrent.AnalyzePackages(ctx, packages); err != nil {
+			b.Fatal(err)
+		}
+		concurrentTime += time.Since(start)
+	}
+
+	b.ReportMetric(float64(sequentialTime.Milliseconds())/float64(b.N), "sequential-ms/op")
+	b.ReportMetric(float64(concurrentTime.Milliseconds())/float64(b.N), "concurrent-ms/op")
+	b.ReportMetric(float64(sequentialTime)/float64(concurrentTime), "speedup")
+}
+
+func TestClient_AnalyzePackages_DecodesCVEDetails(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"patches": [
+			{"package_name": "log4j-core", "version": "2.14.1",
+			 "cve_ids": ["CVE-2021-44228", "CVE-2021-45046"],
+			 "cves": [{"id": "CVE-2021-44228", "severity": "critical", "cvss_score": 10.0, "title": "Log4Shell"}]},
+			{"package_name": "django", "version": "4.0.0",
+			 "cves": [{"id": "CVE-2023-1234", "severity": "high"}]}
+		]}`)
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL, "test-key")
+	response, err := client.AnalyzePackages(context.Background(), []Package{{Name: "log4j-core", Version: "2.14.1"}})
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	log4j := response.Patches[0]
+	if len(log4j.CVEs) != 2 {
+		t.Fatalf("Expected 2 CVEs, got %+v", log4j.CVEs)
+	}
+	if cve := log4j.CVEs[0]; cve.ID != "CVE-2021-44228" || cve.Severity != "critical" || cve.CVSSScore != 10 || cve.Title != "Log4Shell" {
+		t.Errorf("Unexpected CVE details: %+v", cve)
+	}
+	if log4j.CVEs[1].ID != "CVE-2021-45046" {
+		t.Errorf("Expected CVE listed only by ID to get an entry, got %+v", log4j.CVEs[1])
+	}
+
+	django := response.Patches[1]
+	if len(django.CVEIDs) != 1 || django.CVEIDs[0] != "CVE-2023-1234" {
+		t.Errorf("Expected CVE IDs filled from details, got %v", django.CVEIDs)
+	}
+}
+
+func TestClient_AnalyzePackages_EcosystemHint(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v3/remediate/debian" {
+			t.Errorf("Unexpected path: %s", r.URL.Path)
+		}
+		_ = json.NewEncoder(w).Encode(AnalyzePackagesResponse{})
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL, "test-key", WithEcosystem("debian"))
+	if _, err := client.AnalyzePackages(context.Background(), []Package{{Name: "openssl", Version: "3.0.11-1~deb12u1"}}); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+}
+
+func TestClient_AnalyzePackages_EndpointPerEcosystem(t *testing.T) {
+	tests := []struct {
+		name string
+		opts []Option
+		path string
+	}{
+		{"default", nil, "/v3/remediate/pypi"},
+		{"pypi", []Option{WithEcosystem("pypi")}, "/v3/remediate/pypi"},
+		{"npm", []Option{WithEcosystem("npm")}, "/v3/remediate/npm"},
+		{"maven", []Option{WithEcosystem("maven")}, "/v3/remediate/maven"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var gotPath string
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				gotPath = r.URL.Path
+				_ = json.NewEncoder(w).Encode(AnalyzePackagesResponse{})
+			}))
+			defer server.Close()
+
+			client := NewClient(server.URL, "test-key", tt.opts...)
+			if _, err := client.AnalyzePackages(context.Background(), []Package{{Name: "pkg", Version: "1.0.0"}}); err != nil {
+				t.Fatalf("Expected no error, got: %v", err)
+			}
+			if gotPath != tt.path {
+				t.Errorf("Expected request to %s, got %s", tt.path, gotPath)
+			}
+		})
+	}
+}
+
+func TestClient_AnalyzePackages_RemediatePath(t *testing.T) {
+	tests := []struct {
+		name     string
+		template string
+		path     string
+	}{
+		{"default", "", "/v3/remediate/npm"},
+		{"templated", "/staging/v4/{ecosystem}/remediate", "/staging/v4/npm/remediate"},
+		{"without placeholder", "/analyze", "/analyze"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var gotPath string
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				gotPath = r.URL.Path
+				_ = json.NewEncoder(w).Encode(AnalyzePackagesResponse{})
+			}))
+			defer server.Close()
+
+			client := NewClient(server.URL, "test-key", WithEcosystem("npm"), WithRemediatePath(tt.template))
+			if _, err := client.AnalyzePackages(context.Background(), []Package{{Name: "pkg", Version: "1.0.0"}}); err != nil {
+				t.Fatalf("Expected no error, got: %v", err)
+			}
+			if gotPath != tt.path {
+				t.Errorf("Expected request to %s, got %s", tt.path, gotPath)
+			}
+			if want := server.URL + tt.path; client.RemediateURL() != want {
+				t.Errorf("Expected RemediateURL %s, got %s", want, client.RemediateURL())
+			}
+		})
+	}
+}
+
+func TestClient_AnalyzePackages_Auth(t *testing.T) {
+	tests := []struct {
+		name           string
+		options        []Option
+		wantBasicUser  string
+		wantAuthHeader string
+		wantCustom     string
+	}{
+		{name: "basic by default", wantBasicUser: "test-key"},
+		{name: "basic", options: []Option{WithAuth(AuthBasic, "")}, wantBasicUser: "test-key"},
+		{name: "bearer", options: []Option{WithAuth(AuthBearer, "")}, wantAuthHeader: "Bearer test-key"},
+		{name: "custom header", options: []Option{WithAuth(AuthHeader, "X-API-Key")}, wantCustom: "test-key"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var header http.Header
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				header = r.Header.Clone()
+				_ = json.NewEncoder(w).Encode(AnalyzePackagesResponse{})
+			}))
+			defer server.Close()
+
+			client := NewClient(server.URL, "test-key", tt.options...)
+			if _, err := client.AnalyzePackages(context.Background(), []Package{{Name: "pkg", Version: "1.0.0"}}); err != nil {
+				t.Fatalf("Expected no error, got: %v", err)
+			}
+
+			req := &http.Request{Header: header}
+			user, _, ok := req.BasicAuth()
+			if tt.wantBasicUser != "" && (!ok || user != tt.wantBasicUser) {
+				t.Errorf("Expected basic auth with user %q, got %q", tt.wantBasicUser, header.Get("Authorization"))
+			}
+			if tt.wantAuthHeader != "" && header.Get("Authorization") != tt.wantAuthHeader {
+				t.Errorf("Expected Authorization %q, got %q", tt.wantAuthHeader, header.Get("Authorization"))
+			}
+			if tt.wantCustom != "" {
+				if header.Get("X-API-Key") != tt.wantCustom {
+					t.Errorf("Expected X-API-Key %q, got %q", tt.wantCustom, header.Get("X-API-Key"))
+				}
+				if header.Get("Authorization") != "" {
+					t.Errorf("Expected no Authorization header, got %q", header.Get("Authorization"))
+				}
+			}
+		})
+	}
+}
+
+func TestClient_AnalyzePackages_SendsPackageMetadata(t *testing.T) {
+	var body map[string][]map[string]any
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Errorf("Failed to decode request: %v", err)
+		}
+		_ = json.NewEncoder(w).Encode(AnalyzePackagesResponse{})
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL, "test-key", WithEcosystem("npm"))
+	packages := []Package{
+		{Name: "lodash", Version: "4.17.20", Ecosystem: "npm", Direct: true},
+		{Name: "jest", Version: "29.0.0", Ecosystem: "npm", Direct: true, Dev: true},
+		{Name: "minimist", Version: "1.2.5"},
+	}
+	if _, err := client.AnalyzePackages(context.Background(), packages); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	expected := []map[string]any{
+		{"name": "lodash", "version": "4.17.20", "ecosystem": "npm", "direct": true},
+		{"name": "jest", "version": "29.0.0", "ecosystem": "npm", "direct": true, "dev": true},
+		{"name": "minimist", "version": "1.2.5"},
+	}
+	if !reflect.DeepEqual(body["packages"], expected) {
+		t.Errorf("Request packages = %v, want %v", body["packages"], expected)
+	}
+}
+
+func TestClient_AnalyzePackages_DebugLogging(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// A response echoing the key must not leak it into the log
+		_, _ = w.Write([]byte(`{"patches": [], "skipped": [{"package_name": "secret-key-123", "reason": "test"}]}`))
+	}))
+	defer server.Close()
+
+	var logs strings.Builder
+	logger := slog.New(slog.NewTextHandler(&logs, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	client := NewClient(server.URL, "secret-key-123", WithRetry(1, 0), WithLogger(logger))
+
+	packages := []Package{{Name: "requests", Version: "2.25.0"}, {Name: "urllib3", Version: "1.26.4"}}
+	if _, err := client.AnalyzePackages(context.Background(), packages); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	output := logs.String()
+	for _, want := range []string{"packages=2", "status=200", "patches=0", "skipped=1", "/v3/remediate/pypi", "urllib3"} {
+		if !strings.Contains(output, want) {
+			t.Errorf("Expected %q in the log, got:\n%s", want, output)
+		}
+	}
+	if strings.Contains(output, "secret-key-123") {
+		t.Errorf("Expected the API key to be redacted, got:\n%s", output)
+	}
+}
+
+func TestClient_AnalyzePackages_BodiesOnlyLoggedAtDebug(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"patches": []}`))
+	}))
+	defer server.Close()
+
+	var logs strings.Builder
+	logger := slog.New(slog.NewTextHandler(&logs, &slog.HandlerOptions{Level: slog.LevelInfo}))
+	client := NewClient(server.URL, "test-key", WithRetry(1, 0), WithLogger(logger))
+
+	if _, err := client.AnalyzePackages(context.Background(), []Package{{Name: "requests", Version: "2.25.0"}}); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if logs.Len() != 0 {
+		t.Errorf("Expected nothing logged above debug level, got:\n%s", logs.String())
+	}
+}
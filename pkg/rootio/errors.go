@@ -0,0 +1,57 @@
+package rootio
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+// Sentinel errors for the classes of API failures, matched with errors.Is on errors returned
+// by the client
+var (
+	// ErrBadRequest means the API rejected the request as malformed (400, 422)
+	ErrBadRequest = errors.New("bad request")
+
+	// ErrUnauthorized means the API key is missing, invalid or expired (401)
+	ErrUnauthorized = errors.New("unauthorized")
+
+	// ErrForbidden means the API key isn't allowed to use the remediation API (403)
+	ErrForbidden = errors.New("forbidden")
+
+	// ErrRateLimited means too many requests were sent (429)
+	ErrRateLimited = errors.New("rate limited")
+
+	// ErrServer means the API failed to handle the request (5xx)
+	ErrServer = errors.New("server error")
+)
+
+// APIError is returned when the API answers with a status other than 200 OK. It unwraps to
+// the sentinel error of its status class, if any.
+type APIError struct {
+	StatusCode int
+	Body       string
+}
+
+// Error describes the status and the response body
+func (e *APIError) Error() string {
+	return fmt.Sprintf("API returned status %d: %s", e.StatusCode, e.Body)
+}
+
+// Unwrap returns the sentinel error for the status class, so errors.Is(err, ErrUnauthorized)
+// matches a 401
+func (e *APIError) Unwrap() error {
+	switch {
+	case e.StatusCode == http.StatusBadRequest, e.StatusCode == http.StatusUnprocessableEntity:
+		return ErrBadRequest
+	case e.StatusCode == http.StatusUnauthorized:
+		return ErrUnauthorized
+	case e.StatusCode == http.StatusForbidden:
+		return ErrForbidden
+	case e.StatusCode == http.StatusTooManyRequests:
+		return ErrRateLimited
+	case e.StatusCode >= 500 && e.StatusCode <= 599:
+		return ErrServer
+	default:
+		return nil
+	}
+}
@@ -0,0 +1,66 @@
+package rootio
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestClient_AnalyzePackages_TypedErrors(t *testing.T) {
+	tests := []struct {
+		status   int
+		sentinel error
+	}{
+		{http.StatusBadRequest, ErrBadRequest},
+		{http.StatusUnprocessableEntity, ErrBadRequest},
+		{http.StatusUnauthorized, ErrUnauthorized},
+		{http.StatusForbidden, ErrForbidden},
+		{http.StatusTooManyRequests, ErrRateLimited},
+		{http.StatusInternalServerError, ErrServer},
+		{http.StatusServiceUnavailable, ErrServer},
+		{http.StatusTeapot, nil},
+	}
+	sentinels := []error{ErrBadRequest, ErrUnauthorized, ErrForbidden, ErrRateLimited, ErrServer}
+
+	for _, tt := range tests {
+		t.Run(http.StatusText(tt.status), func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				http.Error(w, "details", tt.status)
+			}))
+			defer server.Close()
+
+			client := NewClient(server.URL, "test-key", WithRetry(1, time.Millisecond))
+			_, err := client.AnalyzePackages(context.Background(), nil)
+
+			var apiErr *APIError
+			if !errors.As(err, &apiErr) {
+				t.Fatalf("Expected an *APIError, got %T: %v", err, err)
+			}
+			if apiErr.StatusCode != tt.status || apiErr.Body != "details\n" {
+				t.Errorf("Expected status %d with the response body, got %d %q", tt.status, apiErr.StatusCode, apiErr.Body)
+			}
+
+			for _, sentinel := range sentinels {
+				if got := errors.Is(err, sentinel); got != (sentinel == tt.sentinel) {
+					t.Errorf("errors.Is(err, %v) = %v for status %d", sentinel, got, tt.status)
+				}
+			}
+		})
+	}
+}
+
+func TestClient_AnalyzePackages_TypedErrorAfterRetries(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL, "test-key", WithRetry(2, time.Millisecond))
+	_, err := client.AnalyzePackages(context.Background(), nil)
+	if !errors.Is(err, ErrRateLimited) {
+		t.Errorf("Expected ErrRateLimited through the retry error, got: %v", err)
+	}
+}
@@ -0,0 +1,78 @@
+package rootio
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net/http"
+	"os"
+	"time"
+)
+
+const (
+	// DefaultMaxIdleConns is the default number of idle connections kept across all hosts
+	DefaultMaxIdleConns = 100
+
+	// DefaultMaxIdleConnsPerHost is the default number of idle connections kept to the API host.
+	// It's well above DefaultConcurrency, so concurrent batches reuse connections instead of
+	// closing them when the net/http default of 2 is exceeded.
+	DefaultMaxIdleConnsPerHost = 16
+
+	// DefaultIdleConnTimeout is how long an idle connection is kept open for reuse
+	DefaultIdleConnTimeout = 90 * time.Second
+)
+
+// newTransport returns an HTTP transport that routes requests through the proxies named in
+// HTTPS_PROXY, HTTP_PROXY and NO_PROXY (and their lowercase forms), and keeps connections
+// open for reuse across batches
+func newTransport() *http.Transport {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = http.ProxyFromEnvironment
+	transport.MaxIdleConns = DefaultMaxIdleConns
+	transport.MaxIdleConnsPerHost = DefaultMaxIdleConnsPerHost
+	transport.IdleConnTimeout = DefaultIdleConnTimeout
+	return transport
+}
+
+// WithConnectionPool sets how many idle connections are kept in total and per host, and how long
+// they stay open for reuse. A value of 0 or less keeps the default for that setting.
+func WithConnectionPool(maxIdleConns, maxIdleConnsPerHost int, idleConnTimeout time.Duration) Option {
+	return func(c *Client) {
+		if maxIdleConns > 0 {
+			c.transport.MaxIdleConns = maxIdleConns
+		}
+		if maxIdleConnsPerHost > 0 {
+			c.transport.MaxIdleConnsPerHost = maxIdleConnsPerHost
+		}
+		if idleConnTimeout > 0 {
+			c.transport.IdleConnTimeout = idleConnTimeout
+		}
+	}
+}
+
+// WithTLSConfig sets the TLS configuration used to connect to the API,
+// e.g. to trust the CA of a TLS-intercepting proxy (see LoadTLSConfig)
+func WithTLSConfig(tlsConfig *tls.Config) Option {
+	return func(c *Client) {
+		c.transport.TLSClientConfig = tlsConfig
+	}
+}
+
+// LoadTLSConfig returns a TLS configuration that trusts the system roots plus the
+// PEM-encoded certificates in caCertPath
+func LoadTLSConfig(caCertPath string) (*tls.Config, error) {
+	pem, err := os.ReadFile(caCertPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read CA certificate %s: %w", caCertPath, err)
+	}
+
+	pool, err := x509.SystemCertPool()
+	if err != nil || pool == nil {
+		pool = x509.NewCertPool()
+	}
+	if !pool.AppendCertsFromPEM(pem) {
+		return nil, fmt.Errorf("no PEM certificates found in %s", caCertPath)
+	}
+
+	return &tls.Config{RootCAs: pool, MinVersion: tls.VersionTLS12}, nil
+}
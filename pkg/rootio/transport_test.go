@@ -0,0 +1,188 @@
+package rootio
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"encoding/pem"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// writeCACert writes the certificate of a TLS test server to a PEM file and returns its path
+func writeCACert(t *testing.T, server *httptest.Server) string {
+	t.Helper()
+
+	caPath := filepath.Join(t.TempDir(), "ca.pem")
+	block := &pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw}
+	if err := os.WriteFile(caPath, pem.EncodeToMemory(block), 0644); err != nil {
+		t.Fatalf("Failed to write CA certificate: %v", err)
+	}
+	return caPath
+}
+
+func TestClient_WithTLSConfig_TrustsCustomCA(t *testing.T) {
+	ctx := context.Background()
+
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(AnalyzePackagesResponse{
+			Patches: []PackagePatch{{PackageName: "django", Version: "4.0.0"}},
+		})
+	}))
+	defer server.Close()
+
+	// Without the custom CA the server's self-signed certificate is rejected
+	untrusted := NewClient(server.URL, "test-key", WithRetry(1, 0))
+	if _, err := untrusted.AnalyzePackages(ctx, []Package{{Name: "django", Version: "4.0.0"}}); err == nil {
+		t.Fatal("Expected certificate error without the custom CA")
+	}
+
+	tlsConfig, err := LoadTLSConfig(writeCACert(t, server))
+	if err != nil {
+		t.Fatalf("LoadTLSConfig failed: %v", err)
+	}
+
+	client := NewClient(server.URL, "test-key", WithTLSConfig(tlsConfig))
+	response, err := client.AnalyzePackages(ctx, []Package{{Name: "django", Version: "4.0.0"}})
+	if err != nil {
+		t.Fatalf("Expected custom CA to be trusted, got: %v", err)
+	}
+	if len(response.Patches) != 1 {
+		t.Fatalf("Expected 1 patch, got %d", len(response.Patches))
+	}
+}
+
+func TestLoadTLSConfig_Errors(t *testing.T) {
+	if _, err := LoadTLSConfig(filepath.Join(t.TempDir(), "missing.pem")); err == nil {
+		t.Error("Expected error for a missing CA file")
+	}
+
+	invalid := filepath.Join(t.TempDir(), "invalid.pem")
+	if err := os.WriteFile(invalid, []byte("not a certificate"), 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+	if _, err := LoadTLSConfig(invalid); err == nil || !strings.Contains(err.Error(), "no PEM certificates") {
+		t.Errorf("Expected no PEM certificates error, got: %v", err)
+	}
+}
+
+func TestNewClient_UsesProxyFromEnvironment(t *testing.T) {
+	client := NewClient("https://api.root.io", "test-key")
+	if client.transport.Proxy == nil {
+		t.Fatal("Expected transport to honor proxy environment variables")
+	}
+	if client.httpClient.Transport != client.transport {
+		t.Error("Expected HTTP client to use the configured transport")
+	}
+}
+
+func TestNewClient_ConnectionPoolDefaults(t *testing.T) {
+	client := NewClient("https://api.root.io", "test-key")
+	if client.transport.MaxIdleConns != DefaultMaxIdleConns {
+		t.Errorf("Expected MaxIdleConns %d, got %d", DefaultMaxIdleConns, client.transport.MaxIdleConns)
+	}
+	if client.transport.MaxIdleConnsPerHost != DefaultMaxIdleConnsPerHost {
+		t.Errorf("Expected MaxIdleConnsPerHost %d, got %d", DefaultMaxIdleConnsPerHost, client.transport.MaxIdleConnsPerHost)
+	}
+	if client.transport.IdleConnTimeout != DefaultIdleConnTimeout {
+		t.Errorf("Expected IdleConnTimeout %v, got %v", DefaultIdleConnTimeout, client.transport.IdleConnTimeout)
+	}
+}
+
+func TestWithConnectionPool(t *testing.T) {
+	client := NewClient("https://api.root.io", "test-key", WithConnectionPool(10, 8, time.Minute))
+	if client.transport.MaxIdleConns != 10 || client.transport.MaxIdleConnsPerHost != 8 ||
+		client.transport.IdleConnTimeout != time.Minute {
+		t.Errorf("Unexpected connection pool: %d, %d, %v",
+			client.transport.MaxIdleConns, client.transport.MaxIdleConnsPerHost, client.transport.IdleConnTimeout)
+	}
+
+	// Zero values keep the defaults
+	client = NewClient("https://api.root.io", "test-key", WithConnectionPool(0, 0, 0))
+	if client.transport.MaxIdleConns != DefaultMaxIdleConns || client.transport.MaxIdleConnsPerHost != DefaultMaxIdleConnsPerHost ||
+		client.transport.IdleConnTimeout != DefaultIdleConnTimeout {
+		t.Errorf("Expected defaults to be kept, got: %d, %d, %v",
+			client.transport.MaxIdleConns, client.transport.MaxIdleConnsPerHost, client.transport.IdleConnTimeout)
+	}
+}
+
+// newConnCountingServer returns a TLS test server and a counter of the connections opened to it
+func newConnCountingServer() (*httptest.Server, *atomic.Int32) {
+	var conns atomic.Int32
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(AnalyzePackagesResponse{})
+	}))
+	server.Config.ConnState = func(_ net.Conn, state http.ConnState) {
+		if state == http.StateNew {
+			conns.Add(1)
+		}
+	}
+	server.StartTLS()
+	return server, &conns
+}
+
+// newTestTLSConfig trusts the certificate of a TLS test server
+func newTestTLSConfig(server *httptest.Server) *tls.Config {
+	return server.Client().Transport.(*http.Transport).TLSClientConfig
+}
+
+func TestClient_ReusesConnectionsAcrossCalls(t *testing.T) {
+	ctx := context.Background()
+	server, conns := newConnCountingServer()
+	defer server.Close()
+
+	client := NewClient(server.URL, "test-key", WithTLSConfig(newTestTLSConfig(server)))
+	for range 20 {
+		if _, err := client.AnalyzePackages(ctx, []Package{{Name: "django", Version: "4.0.0"}}); err != nil {
+			t.Fatalf("AnalyzePackages failed: %v", err)
+		}
+	}
+
+	if got := conns.Load(); got != 1 {
+		t.Errorf("Expected 20 sequential calls to share 1 connection, got %d", got)
+	}
+}
+
+// BenchmarkClient_ConnectionReuse compares 100 sequential calls over reused connections against
+// calls that open a new TLS connection each time. Run with: go test -bench ConnectionReuse ./pkg/rootio
+func BenchmarkClient_ConnectionReuse(b *testing.B) {
+	ctx := context.Background()
+	packages := []Package{{Name: "django", Version: "4.0.0"}}
+
+	server, _ := newConnCountingServer()
+	defer server.Close()
+
+	reused := NewClient(server.URL, "test-key", WithTLSConfig(newTestTLSConfig(server)))
+	fresh := NewClient(server.URL, "test-key", WithTLSConfig(newTestTLSConfig(server)))
+	fresh.transport.DisableKeepAlives = true
+
+	var reusedTime, freshTime time.Duration
+	for b.Loop() {
+		start := time.Now()
+		for range 100 {
+			if _, err := reused.AnalyzePackages(ctx, packages); err != nil {
+				b.Fatal(err)
+			}
+		}
+		reusedTime += time.Since(start)
+
+		start = time.Now()
+		for range 100 {
+			if _, err := fresh.AnalyzePackages(ctx, packages); err != nil {
+				b.Fatal(err)
+			}
+		}
+		freshTime += time.Since(start)
+	}
+
+	b.ReportMetric(float64(reusedTime.Milliseconds())/float64(b.N), "reused-ms/op")
+	b.ReportMetric(float64(freshTime.Milliseconds())/float64(b.N), "fresh-ms/op")
+	b.ReportMetric(float64(freshTime)/float64(reusedTime), "speedup")
+}
@@ -1,9 +1,12 @@
 package rootio
 
-// Package represents a Python package with its version
+// Package represents a package with its version and, when known, its place in the project
 type Package struct {
-	Name    string `json:"name"`
-	Version string `json:"version"`
+	Name      string `json:"name"`
+	Version   string `json:"version"`
+	Ecosystem string `json:"ecosystem,omitempty"` // npm, pypi, maven or debian
+	Direct    bool   `json:"direct,omitempty"`    // Declared by the project rather than pulled in transitively
+	Dev       bool   `json:"dev,omitempty"`       // Only needed for development or tests
 }
 
 // AnalyzePackagesRequest is the request for analyzing packages for vulnerabilities
@@ -17,6 +20,14 @@ type PatchInfo struct {
 	Version string `json:"version"` // Package version
 }
 
+// CVE describes a vulnerability fixed by a patch
+type CVE struct {
+	ID        string  `json:"id"`
+	Severity  string  `json:"severity,omitempty"`   // none, low, medium, high or critical
+	CVSSScore float64 `json:"cvss_score,omitempty"` // CVSS base score, 0 if unknown
+	Title     string  `json:"title,omitempty"`      // Short summary
+}
+
 // PackagePatch represents a package that needs to be patched
 type PackagePatch struct {
 	PackageName string    `json:"package_name"` // Currently installed package name
@@ -24,6 +35,8 @@ type PackagePatch struct {
 	Patch       PatchInfo `json:"patch"`        // Patch details
 	PatchAlias  PatchInfo `json:"patch_alias"`  // Root.io aliased package details
 	CVEIDs      []string  `json:"cve_ids"`      // Fixed CVEs
+	CVEs        []CVE     `json:"cves"`         // Details of the fixed CVEs
+	Severity    string    `json:"severity"`     // Highest severity among fixed CVEs
 }
 
 // SkippedPackage represents a package that was skipped during analysis